@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	chaptersLanguage string
+	markThrough      string
+)
+
+var progressChaptersCmd = &cobra.Command{
+	Use:   "chapters <manga-id>",
+	Short: "List a manga's chapters with your read marks",
+	Long: `List the chapters of a manga from the server's chapter catalogue, showing
+which ones you have read and how many times.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := fmt.Sprintf("/users/chapters/%s?language=%s", url.PathEscape(args[0]), url.QueryEscape(chaptersLanguage))
+		body, err := libraryRequest("GET", path, nil, "get chapters")
+		if err != nil {
+			return err
+		}
+
+		var progress struct {
+			CurrentChapter int `json:"current_chapter"`
+			ReadChapters   int `json:"read_chapters"`
+			TotalChapters  int `json:"total_chapters"`
+			Chapters       []struct {
+				ID              string `json:"id"`
+				Number          string `json:"number"`
+				Volume          string `json:"volume"`
+				Title           string `json:"title"`
+				ScanlationGroup string `json:"scanlation_group"`
+				Read            bool   `json:"read"`
+				ReadCount       int    `json:"read_count"`
+			} `json:"chapters"`
+		}
+		if err := json.Unmarshal(body, &progress); err != nil {
+			printError("Failed to parse response")
+			return err
+		}
+
+		if len(progress.Chapters) == 0 {
+			fmt.Printf("No %s chapters found for this manga.\n", chaptersLanguage)
+			return nil
+		}
+
+		fmt.Printf("\nRead %d of %d chapters (current chapter: %d)\n\n",
+			progress.ReadChapters, progress.TotalChapters, progress.CurrentChapter)
+		fmt.Printf("%-4s %-8s %-6s %-36s %-20s %s\n", "", "Chapter", "Vol", "Title", "Group", "ID")
+		for _, ch := range progress.Chapters {
+			mark := ""
+			switch {
+			case ch.ReadCount > 1:
+				mark = fmt.Sprintf("✓x%d", ch.ReadCount)
+			case ch.Read:
+				mark = "✓"
+			}
+			number := ch.Number
+			if number == "" {
+				number = "extra"
+			}
+			fmt.Printf("%-4s %-8s %-6s %-36s %-20s %s\n", mark, number, ch.Volume,
+				truncateString(ch.Title, 36), truncateString(ch.ScanlationGroup, 20), ch.ID)
+		}
+		fmt.Println()
+		return nil
+	},
+}
+
+var progressMarkCmd = &cobra.Command{
+	Use:   "mark <manga-id> [chapter-id...]",
+	Short: "Mark chapters as read",
+	Long: `Mark chapters as read by ID, or every chapter up to a number with --through.
+Your library's current chapter moves forward to the highest chapter read.
+
+Examples:
+  mangahub progress mark 13 --through 42
+  mangahub progress mark 13 <chapter-id> <chapter-id>`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 && markThrough == "" {
+			return fmt.Errorf("give chapter IDs or --through <number>")
+		}
+
+		payload, _ := json.Marshal(map[string]interface{}{
+			"chapter_ids": args[1:],
+			"through":     markThrough,
+			"language":    chaptersLanguage,
+		})
+		body, err := libraryRequest("POST", "/users/chapters/"+url.PathEscape(args[0])+"/read", payload, "mark chapters")
+		if err != nil {
+			return err
+		}
+
+		var result struct {
+			Marked         int `json:"marked"`
+			CurrentChapter int `json:"current_chapter"`
+		}
+		json.Unmarshal(body, &result)
+		printSuccess(fmt.Sprintf("Marked %d chapter(s) read (current chapter: %d)", result.Marked, result.CurrentChapter))
+		return nil
+	},
+}
+
+var progressUnmarkCmd = &cobra.Command{
+	Use:   "unmark <manga-id> <chapter-id>",
+	Short: "Remove a chapter's read mark",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := fmt.Sprintf("/users/chapters/%s/read/%s", url.PathEscape(args[0]), url.PathEscape(args[1]))
+		body, err := libraryRequest("DELETE", path, nil, "unmark chapter")
+		if err != nil {
+			return err
+		}
+
+		var result struct {
+			CurrentChapter int `json:"current_chapter"`
+		}
+		json.Unmarshal(body, &result)
+		printSuccess(fmt.Sprintf("Chapter unmarked (current chapter: %d)", result.CurrentChapter))
+		return nil
+	},
+}
+
+func init() {
+	progressChaptersCmd.Flags().StringVar(&chaptersLanguage, "language", "en", "Chapter language (e.g., en, ja, es)")
+	progressMarkCmd.Flags().StringVar(&chaptersLanguage, "language", "en", "Chapter language used with --through")
+	progressMarkCmd.Flags().StringVar(&markThrough, "through", "", "Mark every chapter up to and including this number")
+
+	progressCmd.AddCommand(progressChaptersCmd)
+	progressCmd.AddCommand(progressMarkCmd)
+	progressCmd.AddCommand(progressUnmarkCmd)
+}
@@ -46,6 +46,8 @@ var chatRoom string
 var chatMangaID string
 var chatRoomDisplayName string // For displaying manga titles instead of IDs
 var historyLimit int
+var historyBefore string
+var historySearch string
 
 func init() {
 	chatCmd.AddCommand(chatJoinCmd)
@@ -60,6 +62,9 @@ func init() {
 
 	chatHistoryCmd.Flags().StringVar(&chatMangaID, "manga-id", "", "Get history for manga chat")
 	chatHistoryCmd.Flags().IntVar(&historyLimit, "limit", 20, "Number of messages to retrieve")
+	chatHistoryCmd.Flags().StringVarP(&chatRoom, "room", "r", "global", "Room to read")
+	chatHistoryCmd.Flags().StringVar(&historyBefore, "before", "", "Show messages older than this message ID")
+	chatHistoryCmd.Flags().StringVar(&historySearch, "search", "", "Search messages in all your rooms and DMs")
 }
 
 func runChatJoin(cmd *cobra.Command, args []string) {
@@ -272,6 +277,11 @@ func runChatHistory(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	if historySearch != "" {
+		searchChatMessages(historySearch, historyLimit)
+		return
+	}
+
 	// Handle manga-specific chat
 	room := chatRoom
 	if chatMangaID != "" {
@@ -301,6 +311,9 @@ func runChatHistory(cmd *cobra.Command, args []string) {
 
 	// Send history command with requested limit
 	cmdStr := fmt.Sprintf("/history %d", historyLimit)
+	if historyBefore != "" {
+		cmdStr += " before " + historyBefore
+	}
 	message := map[string]interface{}{
 		"type":    "command",
 		"command": cmdStr,
@@ -326,11 +339,20 @@ func runChatHistory(cmd *cobra.Command, args []string) {
 			var response map[string]interface{}
 			if err := json.Unmarshal(msg, &response); err == nil {
 				msgType, _ := response["type"].(string)
+				if msgType == "error" {
+					printError(fmt.Sprintf("%v", response["content"]))
+					done <- true
+					return
+				}
 				if msgType == "history" {
 					metadata, _ := response["metadata"].(map[string]interface{})
 					messages, _ := metadata["messages"].([]interface{})
 
-					fmt.Printf("\nChat History (last %d messages):\n", historyLimit)
+					if historyBefore != "" {
+						fmt.Printf("\nChat History (%d messages before %s):\n", historyLimit, historyBefore)
+					} else {
+						fmt.Printf("\nChat History (last %d messages):\n", historyLimit)
+					}
 					fmt.Println("─────────────────────────────────────────────────────────────")
 
 					if len(messages) == 0 {
@@ -338,16 +360,15 @@ func runChatHistory(cmd *cobra.Command, args []string) {
 					} else {
 						for _, m := range messages {
 							if msgMap, ok := m.(map[string]interface{}); ok {
-								timestamp, _ := msgMap["timestamp"].(string)
-								fromUser, _ := msgMap["from"].(string)
-								msgContent, _ := msgMap["content"].(string)
-
-								t, _ := time.Parse(time.RFC3339, timestamp)
-								fmt.Printf("[%s] %s: %s\n", t.Format("15:04"), fromUser, msgContent)
+								printStoredMessage(msgMap, cfg.User.Username)
 							}
 						}
 					}
 					fmt.Println("─────────────────────────────────────────────────────────────")
+					if len(messages) == historyLimit {
+						oldest, _ := messages[0].(map[string]interface{})["id"].(string)
+						fmt.Printf("Older messages: mangahub chat history -r %s --before %s\n", room, oldest)
+					}
 					done <- true
 					return
 				}
@@ -374,6 +395,9 @@ func handleIncomingMessage(msg map[string]interface{}, username, currentRoom str
 	if room == "" {
 		room = "global"
 	}
+	if handleMessageUpdate(msg, username) {
+		return
+	}
 
 	switch msgType {
 	case "welcome":
@@ -419,9 +443,29 @@ func handleIncomingMessage(msg map[string]interface{}, username, currentRoom str
 	case "text":
 		timestamp, _ := msg["timestamp"].(string)
 		t, _ := time.Parse(time.RFC3339, timestamp)
+		metadata, _ := msg["metadata"].(map[string]interface{})
+		id, _ := msg["id"].(string)
+		rememberMessage(id, from, username)
+		if direct, _ := metadata["direct"].(bool); direct {
+			// Private messages are shown on both ends, including our own echo
+			to, _ := metadata["to"].(string)
+			fmt.Println()
+			printReplyContext(metadata["reply_to"])
+			fmt.Printf("[%s] [DM] %s → %s: %s  #%s\n", t.Format("15:04"), from, to, content, shortMessageID(id))
+			fmt.Printf("%s> ", username)
+			break
+		}
+		revealed, _ := metadata["revealed"].(bool)
+		if spoiler, _ := metadata["spoiler"].(bool); spoiler {
+			content = spoilerNotice(metadata["chapter"], id)
+		} else {
+			content += chapterTag(metadata["chapter"])
+		}
 		// Skip server echo of our own message since we already locally echoed it
-		if from != username {
-			fmt.Printf("\n[%s] %s: %s\n", t.Format("15:04"), from, content)
+		if from != username || revealed {
+			fmt.Println()
+			printReplyContext(metadata["reply_to"])
+			fmt.Printf("[%s] %s: %s  #%s\n", t.Format("15:04"), from, content, shortMessageID(id))
 			fmt.Printf("%s> ", username)
 		}
 
@@ -446,21 +490,29 @@ func handleIncomingMessage(msg map[string]interface{}, username, currentRoom str
 		metadata, _ := msg["metadata"].(map[string]interface{})
 		messages, _ := metadata["messages"].([]interface{})
 
-		fmt.Println("\n\nChat History:")
+		if with, ok := metadata["with"].(string); ok {
+			fmt.Printf("\n\nDirect messages with %s:\n", with)
+			if len(messages) == 0 {
+				fmt.Println("No messages yet.")
+			}
+		} else {
+			fmt.Println("\n\nChat History:")
+		}
 		for _, m := range messages {
 			if msgMap, ok := m.(map[string]interface{}); ok {
-				timestamp, _ := msgMap["timestamp"].(string)
-				fromUser, _ := msgMap["from"].(string)
-				msgContent, _ := msgMap["content"].(string)
-
-				t, _ := time.Parse(time.RFC3339, timestamp)
-				fmt.Printf("[%s] %s: %s\n", t.Format("15:04"), fromUser, msgContent)
+				printStoredMessage(msgMap, username)
 			}
 		}
 		fmt.Println()
 		// Reprint prompt after history display
 		fmt.Printf("%s> ", username)
 
+	case "chat_mention", "chat_keyword":
+		printChatAlert(msg, username, currentRoom)
+
+	case "presence_update":
+		printPresenceUpdate(msg, username)
+
 	case "system":
 		fmt.Printf("\n%s\n", content)
 
@@ -477,7 +529,11 @@ func handleIncomingMessage(msg map[string]interface{}, username, currentRoom str
 						memberCount, _ := roomMap["member_count"].(float64)
 						createdAt, _ := roomMap["created_at"].(string)
 
-						fmt.Printf("📁 %s\n", name)
+						unread, _ := roomMap["unread_count"].(float64)
+						fmt.Printf("📁 %s%s\n", name, unreadBadge(int(unread)))
+						if topic, ok := roomMap["topic"].(string); ok {
+							fmt.Printf("   %s\n", topic)
+						}
 						fmt.Printf("   Type: %s | Members: %d | Created: %s\n",
 							roomType, int(memberCount), createdAt)
 
@@ -489,6 +545,31 @@ func handleIncomingMessage(msg map[string]interface{}, username, currentRoom str
 				}
 				fmt.Println("─────────────────────────────────────────────────────")
 				fmt.Println("Use: mangahub chat join -r \"<room-name>\" to join a room")
+			} else if dms, ok := metadata["dms"].([]interface{}); ok {
+				if len(dms) == 0 {
+					fmt.Println("No direct messages yet. Start one with /pm <user> <msg>")
+				}
+				for _, d := range dms {
+					if dm, ok := d.(map[string]interface{}); ok {
+						other, _ := dm["other_username"].(string)
+						unread, _ := dm["unread_count"].(float64)
+						fmt.Printf("💬 %s%s\n", other, unreadBadge(int(unread)))
+						if last, ok := dm["last_message"].(map[string]interface{}); ok {
+							sender, _ := last["sender_username"].(string)
+							text, _ := last["content"].(string)
+							fmt.Printf("   %s: %s\n", sender, truncateString(text, 60))
+						}
+					}
+				}
+				fmt.Println("Use: /dm <user> to read a conversation")
+			} else if results, ok := metadata["results"].([]interface{}); ok {
+				printSearchResults(results)
+			} else if alerts, ok := metadata["alerts"].([]interface{}); ok {
+				printAlerts(alerts)
+			} else if keywords, ok := metadata["keywords"].([]interface{}); ok {
+				printKeywords(keywords)
+			} else if reason, ok := metadata["reason"].(string); ok {
+				printRateLimit(reason, metadata)
 			} else if roomID, ok := metadata["room_id"].(string); ok {
 				// Room creation confirmation
 				roomName, _ := metadata["room_name"].(string)
@@ -520,6 +601,9 @@ func handleChatCommand(conn *websocket.Conn, input, username, currentRoom string
 	}
 
 	command := parts[0]
+	if handleMessageCommand(conn, parts, username, currentRoom) {
+		return false
+	}
 
 	switch command {
 	case "/help":
@@ -530,9 +614,32 @@ func handleChatCommand(conn *websocket.Conn, input, username, currentRoom string
 		fmt.Println("  /users            - List online users")
 		fmt.Println("  /quit             - Leave chat")
 		fmt.Println("  /pm <user> <msg>  - Private message")
+		fmt.Println("  /dms              - List your direct messages")
+		fmt.Println("  /dm <user>        - Show messages with a user")
 		fmt.Println("  /manga <id>       - Switch to manga chat")
-		fmt.Println("  /history          - Show recent history")
+		fmt.Println("  /history [n] [before <#id>] - Show recent history, or older messages")
+		fmt.Println("  /search <words>   - Search your rooms and DMs")
 		fmt.Println("  /status           - Connection status")
+		fmt.Println("\nAlerts (@username in a message alerts that user):")
+		fmt.Println("  /mentions [n]     - Messages that mentioned you or matched a keyword")
+		fmt.Println("  /alert [*] <word> - Alert me when a message here (* = anywhere) says it")
+		fmt.Println("  /unalert [*] <word> - Stop a keyword alert")
+		fmt.Println("  /alerts           - List your keyword alerts")
+		fmt.Println("\nMessages (use the #id shown after a message, or 'last' for your latest):")
+		fmt.Println("  /reply <#id> <msg>      - Reply to a message")
+		fmt.Println("  /edit <#id|last> <msg>  - Edit your message")
+		fmt.Println("  /delete <#id|last>      - Delete your message (moderators: any)")
+		fmt.Println("  /react <#id> <emoji>    - Add or remove a reaction")
+		fmt.Println("  /reveal <#id>           - Show a spoiler hidden until you reach its chapter")
+		fmt.Println("\nModeration (room moderators and owners):")
+		fmt.Println("  /topic [text]     - Show or set the room topic (/topic clear)")
+		fmt.Println("  /kick <user>      - Remove a user from the room")
+		fmt.Println("  /ban <user> [duration] [reason]  - Ban, e.g. /ban bob 7d spam")
+		fmt.Println("  /mute <user> [duration] [reason] - Mute, e.g. /mute bob 10m")
+		fmt.Println("  /unban, /unmute <user>")
+		fmt.Println("  /slowmode <secs>  - Limit how often members post (0 = off)")
+		fmt.Println("  /promote, /demote <user> - Make or unmake a moderator (owner)")
+		fmt.Println("  /delete-room      - Delete this room (owner)")
 		fmt.Println()
 		return false
 
@@ -557,7 +664,33 @@ func handleChatCommand(conn *websocket.Conn, input, username, currentRoom string
 		return false
 
 	case "/history":
-		sendCommand(conn, "history", currentRoom)
+		// /history [limit] [before|after <#id>]
+		for i := 1; i < len(parts)-1; i++ {
+			if parts[i] == "before" || parts[i] == "after" {
+				parts[i+1] = resolveMessageRef(parts[i+1])
+			}
+		}
+		sendCommand(conn, "history "+strings.Join(parts[1:], " "), currentRoom)
+		return false
+
+	case "/search":
+		if len(parts) < 2 {
+			fmt.Println("Usage: /search <words>")
+			return false
+		}
+		sendCommand(conn, input, currentRoom)
+		return false
+
+	case "/mentions", "/alerts", "/alert", "/unalert":
+		sendCommand(conn, input, currentRoom)
+		return false
+
+	case "/reveal":
+		if len(parts) < 2 {
+			fmt.Println("Usage: /reveal <#id>")
+			return false
+		}
+		sendCommand(conn, "reveal "+resolveMessageRef(parts[1]), currentRoom)
 		return false
 
 	case "/status":
@@ -574,6 +707,22 @@ func handleChatCommand(conn *websocket.Conn, input, username, currentRoom string
 		sendPrivateMessage(conn, toUser, message, currentRoom)
 		return false
 
+	case "/dms":
+		sendCommand(conn, "dms", currentRoom)
+		return false
+
+	case "/topic", "/kick", "/ban", "/unban", "/mute", "/unmute", "/promote", "/demote", "/slowmode", "/delete-room":
+		sendCommand(conn, input, currentRoom)
+		return false
+
+	case "/dm":
+		if len(parts) < 2 {
+			fmt.Println("Usage: /dm <username> [limit]")
+			return false
+		}
+		sendCommand(conn, strings.Join(parts, " "), currentRoom)
+		return false
+
 	case "/manga":
 		if len(parts) < 2 {
 			fmt.Println("Usage: /manga <manga-id>")
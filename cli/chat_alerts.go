@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var mentionsLimit int
+
+var chatMentionsCmd = &cobra.Command{
+	Use:   "mentions",
+	Short: "Show messages that mentioned you or matched your keyword alerts",
+	Long: `List the latest room messages that @mentioned you or contained one of
+your keyword alerts, newest first.
+
+Set up keyword alerts inside chat with /alert <keyword> (this room) or
+/alert * <keyword> (every room). Run 'mangahub notify subscribe' or
+'mangahub sync monitor' to be told about new ones as they happen.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		body, err := libraryRequest("GET", fmt.Sprintf("/users/mentions?limit=%d", mentionsLimit), nil, "load mentions")
+		if err != nil {
+			return
+		}
+		var response struct {
+			Alerts []interface{} `json:"alerts"`
+		}
+		json.Unmarshal(body, &response)
+
+		fmt.Println("Mentions and alerts:")
+		fmt.Println("─────────────────────────────────────────────────────────────")
+		printAlerts(response.Alerts)
+		fmt.Println("─────────────────────────────────────────────────────────────")
+	},
+}
+
+// printChatAlert shows a chat_mention or chat_keyword event pushed while
+// chatting. Alerts from the room on screen are skipped; the message itself
+// is already there.
+func printChatAlert(msg map[string]interface{}, username, currentRoom string) {
+	data, _ := msg["data"].(map[string]interface{})
+	room, _ := data["room"].(string)
+	if room == currentRoom {
+		return
+	}
+	from, _ := data["from"].(string)
+	content, _ := data["content"].(string)
+	if chapter, ok := data["chapter"]; ok {
+		id, _ := data["message_id"].(string)
+		content = spoilerNotice(chapter, id)
+	}
+	what := "mentioned you"
+	if keyword, ok := data["keyword"].(string); ok {
+		what = fmt.Sprintf("said %q", keyword)
+	}
+	fmt.Printf("\n🔔 %s %s in #%s: %s\n", from, what, room, truncateString(content, 80))
+	fmt.Printf("%s> ", username)
+}
+
+// printAlerts lists stored alerts from /mentions or GET /users/mentions
+func printAlerts(alerts []interface{}) {
+	if len(alerts) == 0 {
+		fmt.Println("No mentions yet.")
+		return
+	}
+	for _, a := range alerts {
+		m, ok := a.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		from, _ := m["from"].(string)
+		room, _ := m["room"].(string)
+		content, _ := m["content"].(string)
+		createdAt, _ := m["created_at"].(string)
+		t, _ := time.Parse(time.RFC3339, createdAt)
+		why := "@"
+		if keyword, ok := m["keyword"].(string); ok {
+			why = fmt.Sprintf("%q", keyword)
+		}
+		fmt.Printf("[%s] #%s %s: %s  (%s)\n", t.Local().Format("Jan 02 15:04"), room, from, truncateString(content, 80), why)
+	}
+}
+
+// printKeywords lists the keywords from /alerts
+func printKeywords(keywords []interface{}) {
+	if len(keywords) == 0 {
+		fmt.Println("No keyword alerts. Add one with /alert <keyword>")
+		return
+	}
+	for _, k := range keywords {
+		m, ok := k.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		keyword, _ := m["keyword"].(string)
+		where := "every room"
+		if room, ok := m["room"].(string); ok && room != "" {
+			where = "#" + room
+		}
+		fmt.Printf("🔔 %q in %s\n", keyword, where)
+	}
+}
+
+func init() {
+	chatMentionsCmd.Flags().IntVar(&mentionsLimit, "limit", 20, "Number of alerts to show")
+	chatCmd.AddCommand(chatMentionsCmd)
+}
@@ -0,0 +1,227 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Messages are shown with a short #id; these map them back to the full IDs
+// the server needs for /reply, /edit, /delete and /react
+var (
+	chatIDsMu        sync.Mutex
+	chatMessageIDs   = map[string]string{}
+	lastOwnMessageID string
+)
+
+func shortMessageID(id string) string {
+	if len(id) > 6 {
+		return id[:6]
+	}
+	return id
+}
+
+func rememberMessage(id, from, username string) {
+	if id == "" {
+		return
+	}
+	chatIDsMu.Lock()
+	defer chatIDsMu.Unlock()
+	chatMessageIDs[shortMessageID(id)] = id
+	if from == username {
+		lastOwnMessageID = id
+	}
+}
+
+// resolveMessageRef turns "#abc123", "abc123" or "last" (your latest
+// message) into a full message ID
+func resolveMessageRef(ref string) string {
+	chatIDsMu.Lock()
+	defer chatIDsMu.Unlock()
+	if ref == "last" {
+		return lastOwnMessageID
+	}
+	ref = strings.TrimPrefix(ref, "#")
+	if full, ok := chatMessageIDs[ref]; ok {
+		return full
+	}
+	return ref
+}
+
+// formatReactions renders counts like "👍 2  ❤️ 1", most popular first
+func formatReactions(raw interface{}) string {
+	counts, _ := raw.(map[string]interface{})
+	emojis := make([]string, 0, len(counts))
+	for e := range counts {
+		emojis = append(emojis, e)
+	}
+	sort.Slice(emojis, func(i, j int) bool {
+		ci, _ := counts[emojis[i]].(float64)
+		cj, _ := counts[emojis[j]].(float64)
+		if ci != cj {
+			return ci > cj
+		}
+		return emojis[i] < emojis[j]
+	})
+	parts := make([]string, 0, len(emojis))
+	for _, e := range emojis {
+		n, _ := counts[e].(float64)
+		parts = append(parts, fmt.Sprintf("%s %d", e, int(n)))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// printReplyContext shows the message a reply answers, above the reply
+func printReplyContext(raw interface{}) {
+	reply, ok := raw.(map[string]interface{})
+	if !ok {
+		return
+	}
+	from, _ := reply["from"].(string)
+	content, _ := reply["content"].(string)
+	if deleted, _ := reply["deleted"].(bool); deleted {
+		content = "[deleted]"
+	} else if chapter, ok := reply["chapter"].(float64); ok {
+		content = fmt.Sprintf("[spoiler: chapter %d]", int(chapter))
+	}
+	id, _ := reply["id"].(string)
+	fmt.Printf("  ↪ %s: %s (#%s)\n", from, content, shortMessageID(id))
+}
+
+// printStoredMessage renders one history entry with its edit, delete,
+// reply and reaction details
+func printStoredMessage(m map[string]interface{}, username string) {
+	id, _ := m["id"].(string)
+	from, _ := m["from"].(string)
+	content, _ := m["content"].(string)
+	timestamp, _ := m["timestamp"].(string)
+	t, _ := time.Parse(time.RFC3339, timestamp)
+	rememberMessage(id, from, username)
+
+	if replyTo, _ := m["reply_to"].(string); replyTo != "" {
+		fmt.Printf("  ↪ reply to #%s\n", shortMessageID(replyTo))
+	}
+	switch {
+	case m["deleted"] == true:
+		content = "[message deleted]"
+	case m["spoiler"] == true:
+		content = spoilerNotice(m["chapter"], id)
+	case m["edited_at"] != nil:
+		content += " (edited)"
+	}
+	if m["spoiler"] != true && m["deleted"] != true {
+		content += chapterTag(m["chapter"])
+	}
+	fmt.Printf("[%s] %s: %s  #%s\n", t.Format("15:04"), from, content, shortMessageID(id))
+	if reactions := formatReactions(m["reactions"]); reactions != "" {
+		fmt.Printf("         %s\n", reactions)
+	}
+}
+
+// handleMessageUpdate renders edits, deletes and reactions. It reports
+// whether msg was one of them.
+func handleMessageUpdate(msg map[string]interface{}, username string) bool {
+	msgType, _ := msg["type"].(string)
+	from, _ := msg["from"].(string)
+	content, _ := msg["content"].(string)
+	metadata, _ := msg["metadata"].(map[string]interface{})
+	messageID, _ := metadata["message_id"].(string)
+	ref := "#" + shortMessageID(messageID)
+	now := time.Now().Format("15:04")
+
+	switch msgType {
+	case "edit":
+		if spoiler, _ := metadata["spoiler"].(bool); spoiler {
+			content = spoilerNotice(metadata["chapter"], messageID)
+		}
+		fmt.Printf("\n[%s] ✎ %s edited %s: %s\n", now, from, ref, content)
+	case "delete":
+		author, _ := metadata["author"].(string)
+		if author != "" && author != from {
+			fmt.Printf("\n[%s] 🗑 %s removed a message by %s (%s)\n", now, from, author, ref)
+		} else {
+			fmt.Printf("\n[%s] 🗑 %s deleted %s\n", now, from, ref)
+		}
+	case "reaction":
+		verb := "reacted"
+		if added, _ := metadata["added"].(bool); !added {
+			verb = "took back"
+		}
+		fmt.Printf("\n[%s] %s %s %s on %s", now, from, verb, content, ref)
+		if reactions := formatReactions(metadata["reactions"]); reactions != "" {
+			fmt.Printf("  (%s)", reactions)
+		}
+		fmt.Println()
+	case "read":
+		// Room receipts would flood the screen; only DMs show them
+		if direct, _ := metadata["direct"].(bool); !direct || from == username {
+			return true
+		}
+		fmt.Printf("\n[%s] ✓ %s read up to %s\n", now, from, ref)
+	default:
+		return false
+	}
+	fmt.Printf("%s> ", username)
+	return true
+}
+
+// unreadBadge renders " (3 unread)", or nothing when everything is read
+func unreadBadge(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%d unread)", n)
+}
+
+// sendMessageAction sends an edit, delete, reaction or reply
+func sendMessageAction(conn *websocket.Conn, message map[string]interface{}) {
+	data, _ := json.Marshal(message)
+	conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// handleMessageCommand runs /reply, /edit, /delete and /react. It reports
+// whether input was one of them.
+func handleMessageCommand(conn *websocket.Conn, parts []string, username, currentRoom string) bool {
+	usage := map[string]string{
+		"/reply":  "Usage: /reply <#id> <message>",
+		"/edit":   "Usage: /edit <#id|last> <new text>",
+		"/delete": "Usage: /delete <#id|last>",
+		"/react":  "Usage: /react <#id|last> <emoji>",
+	}
+	command := parts[0]
+	if _, ok := usage[command]; !ok {
+		return false
+	}
+	minArgs := 3
+	if command == "/delete" {
+		minArgs = 2
+	}
+	if len(parts) < minArgs {
+		fmt.Println(usage[command])
+		return true
+	}
+	id := resolveMessageRef(parts[1])
+	if id == "" {
+		fmt.Println("You haven't sent a message yet")
+		return true
+	}
+	text := strings.Join(parts[2:], " ")
+
+	switch command {
+	case "/reply":
+		sendMessageAction(conn, map[string]interface{}{"type": "reply", "reply_to": id, "content": text, "room": currentRoom})
+		fmt.Printf("[%s] %s: %s\n", time.Now().Format("15:04"), username, text)
+	case "/edit":
+		sendMessageAction(conn, map[string]interface{}{"type": "edit", "message_id": id, "content": text, "room": currentRoom})
+	case "/delete":
+		sendMessageAction(conn, map[string]interface{}{"type": "delete", "message_id": id, "room": currentRoom})
+	case "/react":
+		sendMessageAction(conn, map[string]interface{}{"type": "reaction", "message_id": id, "emoji": parts[2], "room": currentRoom})
+	}
+	return true
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+)
+
+// rateLimitReasons explains the server's rate limiter reasons
+var rateLimitReasons = map[string]string{
+	"user_rate": "you are sending messages too fast",
+	"ip_rate":   "too many messages from your network",
+	"room_rate": "this room is busy, try again shortly",
+	"flood":     "too many messages in a row",
+	"duplicate": "you already said that",
+	"muted":     "you are muted",
+}
+
+// printRateLimit explains why a message was refused and what it cost
+func printRateLimit(reason string, metadata map[string]interface{}) {
+	explanation, ok := rateLimitReasons[reason]
+	if !ok {
+		explanation = reason
+	}
+	fmt.Printf("⏳ Not sent: %s\n", explanation)
+
+	switch metadata["penalty"] {
+	case "warn":
+		fmt.Println("   Warning: keep it up and you will be muted")
+	case "mute":
+		if until, err := time.Parse(time.RFC3339Nano, fmt.Sprint(metadata["until"])); err == nil {
+			fmt.Printf("   Muted until %s\n", until.Local().Format("15:04:05"))
+		}
+	case "disconnect":
+		fmt.Println("   You have been disconnected for abuse")
+	}
+}
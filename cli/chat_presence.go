@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var chatPresenceCmd = &cobra.Command{
+	Use:   "presence [username...]",
+	Short: "Show who is online, idle, away or offline",
+	Long: `Show the presence of the given users, or by default of everyone you share
+a custom room or direct conversation with.
+
+Presence counts every way a user is connected: chat, sync sessions, UDP
+notifications and gRPC. Connected users turn idle and then away when
+they stop doing anything. Offline users show when they were last seen.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path := "/presence"
+		if len(args) > 0 {
+			path += "?users=" + url.QueryEscape(strings.Join(args, ","))
+		}
+		body, err := libraryRequest("GET", path, nil, "load presence")
+		if err != nil {
+			return
+		}
+		var response struct {
+			Presence []map[string]interface{} `json:"presence"`
+		}
+		json.Unmarshal(body, &response)
+
+		fmt.Println("Presence:")
+		fmt.Println("─────────────────────────────────────────────────────────────")
+		if len(response.Presence) == 0 {
+			fmt.Println("Nobody to show yet. Join a room or send a direct message first.")
+		}
+		for _, p := range response.Presence {
+			printPresence(p)
+		}
+		fmt.Println("─────────────────────────────────────────────────────────────")
+	},
+}
+
+var presenceIcons = map[string]string{
+	"online":  "🟢",
+	"idle":    "🟡",
+	"away":    "🟠",
+	"offline": "⚫",
+}
+
+// printPresence shows one entry from GET /presence
+func printPresence(p map[string]interface{}) {
+	username, _ := p["username"].(string)
+	if username == "" {
+		username, _ = p["user_id"].(string)
+	}
+	status, _ := p["status"].(string)
+	line := fmt.Sprintf("%s %-20s %s", presenceIcons[status], username, status)
+	if status == "offline" {
+		if lastSeen, ok := p["last_seen"].(string); ok {
+			t, _ := time.Parse(time.RFC3339, lastSeen)
+			line += ", last seen " + t.Local().Format("Jan 02 15:04")
+		}
+	} else if protocols, ok := p["protocols"].([]interface{}); ok && len(protocols) > 0 {
+		via := make([]string, 0, len(protocols))
+		for _, v := range protocols {
+			if s, ok := v.(string); ok {
+				via = append(via, s)
+			}
+		}
+		line += " via " + strings.Join(via, ", ")
+	}
+	fmt.Println(line)
+}
+
+// printPresenceUpdate shows a contact's presence_update event pushed while chatting
+func printPresenceUpdate(msg map[string]interface{}, username string) {
+	data, _ := msg["data"].(map[string]interface{})
+	who, _ := data["username"].(string)
+	status, _ := data["status"].(string)
+	fmt.Printf("\n%s %s is now %s\n", presenceIcons[status], who, status)
+	fmt.Printf("%s> ", username)
+}
+
+func init() {
+	chatCmd.AddCommand(chatPresenceCmd)
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// searchChatMessages runs `mangahub chat history --search` against the API
+func searchChatMessages(query string, limit int) {
+	path := fmt.Sprintf("/conversations/search?q=%s&limit=%d", url.QueryEscape(query), limit)
+	body, err := libraryRequest("GET", path, nil, "search messages")
+	if err != nil {
+		return
+	}
+	var response struct {
+		Results []interface{} `json:"results"`
+	}
+	json.Unmarshal(body, &response)
+
+	fmt.Printf("Messages matching %q:\n", query)
+	fmt.Println("─────────────────────────────────────────────────────────────")
+	printSearchResults(response.Results)
+	fmt.Println("─────────────────────────────────────────────────────────────")
+}
+
+// printSearchResults shows search hits with the room or DM they came from
+func printSearchResults(results []interface{}) {
+	if len(results) == 0 {
+		fmt.Println("No messages found.")
+		return
+	}
+	for _, r := range results {
+		m, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, _ := m["id"].(string)
+		from, _ := m["sender_username"].(string)
+		content, _ := m["content"].(string)
+		name, _ := m["conversation_name"].(string)
+		where := "#" + name
+		if kind, _ := m["conversation_type"].(string); kind == "direct" {
+			where = "DM"
+		}
+		createdAt, _ := m["created_at"].(string)
+		t, _ := time.Parse(time.RFC3339, createdAt)
+		fmt.Printf("[%s] %s %s: %s  (%s)\n", t.Local().Format("Jan 02 15:04"), where, from, truncateString(content, 80), id)
+	}
+}
@@ -0,0 +1,22 @@
+package cli
+
+import (
+	"fmt"
+)
+
+// In manga rooms the server collapses messages from readers further ahead;
+// these render the placeholder and the chapter tag on visible ones
+
+// spoilerNotice stands in for a message hidden until the reader reaches chapter
+func spoilerNotice(chapter interface{}, id string) string {
+	ch, _ := chapter.(float64)
+	return fmt.Sprintf("🙈 [spoiler: chapter %d — /reveal #%s]", int(ch), shortMessageID(id))
+}
+
+// chapterTag marks a message with the chapter its sender had reached
+func chapterTag(chapter interface{}) string {
+	if ch, _ := chapter.(float64); ch > 0 {
+		return fmt.Sprintf(" (ch. %d)", int(ch))
+	}
+	return ""
+}
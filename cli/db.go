@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/joho/godotenv"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dbPath          string
+	dbRollbackSteps int
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the server database schema",
+	Long: `Apply, inspect and roll back the versioned schema migrations of the
+server database. The servers apply pending migrations on startup as well.`,
+}
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply pending migrations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openServerDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		applied, err := database.Migrate(db)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			printInfo("Database is already up to date")
+			return nil
+		}
+		printSuccess(fmt.Sprintf("Applied %d migration(s)", len(applied)))
+		return nil
+	},
+}
+
+var dbStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show applied and pending migrations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openServerDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		states, err := database.MigrationStatus(db)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Database: %s\n\n", dbPath)
+		fmt.Printf("%-8s %-28s %-10s %s\n", "Version", "Name", "Status", "Applied At")
+		pending := 0
+		for _, s := range states {
+			status, appliedAt := "pending", "-"
+			if s.Applied {
+				status = "applied"
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			} else {
+				pending++
+			}
+			fmt.Printf("%04d     %-28s %-10s %s\n", s.Version, s.Name, status, appliedAt)
+		}
+		fmt.Printf("\n%d migration(s), %d pending\n", len(states), pending)
+		return nil
+	},
+}
+
+var dbRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Roll back the most recent migrations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openServerDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		reverted, err := database.Rollback(db, dbRollbackSteps)
+		if err != nil {
+			return err
+		}
+		for _, m := range reverted {
+			printInfo(fmt.Sprintf("Rolled back %04d_%s", m.Version, m.Name))
+		}
+		printSuccess(fmt.Sprintf("Rolled back %d migration(s)", len(reverted)))
+		return nil
+	},
+}
+
+// openServerDB opens the database named by --path, DB_PATH or the servers' default
+func openServerDB() (*sql.DB, error) {
+	if dbPath == "" {
+		godotenv.Load()
+		dbPath = os.Getenv("DB_PATH")
+	}
+	if dbPath == "" {
+		dbPath = "./data/mangahub.db"
+	}
+	return database.Open(dbPath)
+}
+
+func init() {
+	dbCmd.PersistentFlags().StringVar(&dbPath, "path", "", "database file (defaults to DB_PATH or ./data/mangahub.db)")
+	dbRollbackCmd.Flags().IntVar(&dbRollbackSteps, "steps", 1, "number of migrations to roll back")
+
+	dbCmd.AddCommand(dbMigrateCmd)
+	dbCmd.AddCommand(dbStatusCmd)
+	dbCmd.AddCommand(dbRollbackCmd)
+}
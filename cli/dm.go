@@ -0,0 +1,266 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/cli/config"
+	"github.com/gorilla/websocket"
+	"github.com/spf13/cobra"
+)
+
+var dmLimit int
+
+var chatDMCmd = &cobra.Command{
+	Use:   "dm [user] [message...]",
+	Short: "Private messages with another user",
+	Long: `Without arguments, list your direct message conversations.
+
+With a user (username or ID), show your conversation with them and stay
+connected: every line you type goes to them only. Add a message after the
+user to send just that and exit.`,
+	Run: runChatDM,
+}
+
+type dmMessage struct {
+	From      string    `json:"from"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func runChatDM(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		listDirectConversations()
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		printError(fmt.Sprintf("Failed to load config: %v", err))
+		return
+	}
+	if cfg.User.Token == "" {
+		printError("Not authenticated. Run 'mangahub auth login' first")
+		return
+	}
+
+	other := args[0]
+	if len(args) > 1 {
+		sendDirectMessage(cfg, other, strings.Join(args[1:], " "))
+		return
+	}
+
+	body, err := libraryRequest("GET", fmt.Sprintf("/users/dms/%s?limit=%d", url.PathEscape(other), dmLimit), nil, "load messages")
+	if err != nil {
+		return
+	}
+	var history struct {
+		With struct {
+			Username string `json:"username"`
+		} `json:"with"`
+		Messages []dmMessage `json:"messages"`
+	}
+	json.Unmarshal(body, &history)
+	other = history.With.Username
+
+	fmt.Printf("Direct messages with %s\n", other)
+	fmt.Println("─────────────────────────────────────────────────────────────")
+	if len(history.Messages) == 0 {
+		fmt.Println("No messages yet. Say hi!")
+	}
+	for _, m := range history.Messages {
+		fmt.Printf("[%s] %s: %s\n", m.Timestamp.Local().Format("Jan 02 15:04"), m.From, m.Content)
+	}
+	fmt.Println("─────────────────────────────────────────────────────────────")
+	fmt.Println("Type a message and press Enter. /quit to leave.")
+
+	conn, err := dialChatServer(cfg)
+	if err != nil {
+		printError(fmt.Sprintf("Failed to connect: %v", err))
+		return
+	}
+	defer conn.Close()
+
+	// The reader goroutine sends read receipts while the input loop sends
+	// messages, and a websocket only takes one writer at a time
+	var writeMu sync.Mutex
+	send := func(message map[string]string) {
+		data, _ := json.Marshal(message)
+		writeMu.Lock()
+		conn.WriteMessage(websocket.TextMessage, data)
+		writeMu.Unlock()
+	}
+	send(map[string]string{"type": "read", "to": other})
+
+	username := cfg.User.Username
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg struct {
+				ID       string                 `json:"id"`
+				Type     string                 `json:"type"`
+				From     string                 `json:"from"`
+				Content  string                 `json:"content"`
+				Metadata map[string]interface{} `json:"metadata"`
+			}
+			if json.Unmarshal(data, &msg) != nil {
+				continue
+			}
+			switch {
+			case msg.Type == "error":
+				fmt.Printf("\n%s\n%s> ", msg.Content, username)
+			case msg.Type == "text" && msg.Metadata["direct"] == true && msg.From != username:
+				// DMs from anyone else are still worth knowing about
+				prefix := "[" + time.Now().Format("15:04") + "] "
+				if msg.From != other {
+					prefix += "[DM] "
+				} else {
+					send(map[string]string{"type": "read", "message_id": msg.ID})
+				}
+				fmt.Printf("\n%s%s: %s\n%s> ", prefix, msg.From, msg.Content, username)
+			case msg.Type == "read" && msg.From == other:
+				fmt.Printf("\n✓ Seen by %s\n%s> ", other, username)
+			}
+		}
+	}()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	inputChan := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			inputChan <- scanner.Text()
+		}
+	}()
+
+	fmt.Printf("%s> ", username)
+	for {
+		select {
+		case <-done:
+			printError("Disconnected from chat server")
+			return
+		case <-interrupt:
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		case input := <-inputChan:
+			input = strings.TrimSpace(input)
+			if input == "/quit" || input == "/exit" {
+				return
+			}
+			if input != "" {
+				send(map[string]string{"type": "text", "to": other, "content": input})
+				fmt.Printf("[%s] %s: %s\n", time.Now().Format("15:04"), username, input)
+			}
+			fmt.Printf("%s> ", username)
+		}
+	}
+}
+
+// sendDirectMessage sends one message and waits for the server to confirm it
+func sendDirectMessage(cfg *config.Config, to, content string) {
+	conn, err := dialChatServer(cfg)
+	if err != nil {
+		printError(fmt.Sprintf("Failed to connect: %v", err))
+		return
+	}
+	defer conn.Close()
+
+	data, _ := json.Marshal(map[string]string{"type": "text", "to": to, "content": content})
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		printError(fmt.Sprintf("Failed to send message: %v", err))
+		return
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, reply, err := conn.ReadMessage()
+		if err != nil {
+			printError("No confirmation from chat server")
+			return
+		}
+		var msg struct {
+			Type     string                 `json:"type"`
+			From     string                 `json:"from"`
+			Content  string                 `json:"content"`
+			Metadata map[string]interface{} `json:"metadata"`
+		}
+		if json.Unmarshal(reply, &msg) != nil {
+			continue
+		}
+		if msg.Type == "error" {
+			printError(msg.Content)
+			return
+		}
+		if msg.Type == "text" && msg.Metadata["direct"] == true && msg.From == cfg.User.Username {
+			printSuccess(fmt.Sprintf("Message sent to %v", msg.Metadata["to"]))
+			return
+		}
+	}
+}
+
+func listDirectConversations() {
+	body, err := libraryRequest("GET", "/users/dms", nil, "load direct messages")
+	if err != nil {
+		return
+	}
+	var inbox struct {
+		Conversations []struct {
+			OtherUsername string `json:"other_username"`
+			UnreadCount   int    `json:"unread_count"`
+			LastMessage   *struct {
+				SenderUsername string    `json:"sender_username"`
+				Content        string    `json:"content"`
+				CreatedAt      time.Time `json:"created_at"`
+			} `json:"last_message"`
+		} `json:"conversations"`
+	}
+	json.Unmarshal(body, &inbox)
+
+	if len(inbox.Conversations) == 0 {
+		fmt.Println("No direct messages yet.")
+		fmt.Println("Start one with: mangahub chat dm <user> <message>")
+		return
+	}
+	fmt.Printf("Direct Messages (%d):\n", len(inbox.Conversations))
+	fmt.Println("─────────────────────────────────────────────────────────────")
+	for _, conv := range inbox.Conversations {
+		fmt.Printf("💬 %s%s\n", conv.OtherUsername, unreadBadge(conv.UnreadCount))
+		if m := conv.LastMessage; m != nil {
+			fmt.Printf("   [%s] %s: %s\n", m.CreatedAt.Local().Format("Jan 02 15:04"), m.SenderUsername, truncateString(m.Content, 60))
+		}
+	}
+	fmt.Println("─────────────────────────────────────────────────────────────")
+	fmt.Println("Open one with: mangahub chat dm <user>")
+}
+
+func dialChatServer(cfg *config.Config) (*websocket.Conn, error) {
+	wsPort := getEnvOrDefault("WEBSOCKET_PORT", fmt.Sprintf("%d", cfg.Server.WebSocketPort))
+	wsHost := getEnvOrDefault("WEBSOCKET_HOST", cfg.Server.Host)
+	if wsHost == "" {
+		wsHost = "localhost"
+		if detectedIP := detectServerIP(wsPort); detectedIP != "" {
+			wsHost = detectedIP
+		}
+	}
+	wsURL := fmt.Sprintf("ws://%s:%s/ws/chat?token=%s", wsHost, wsPort, url.QueryEscape(cfg.User.Token))
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	return conn, err
+}
+
+func init() {
+	chatDMCmd.Flags().IntVar(&dmLimit, "limit", 20, "Number of earlier messages to show")
+	chatCmd.AddCommand(chatDMCmd)
+}
@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/cli/config"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/download"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/manga"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	downloadRange    string
+	downloadMangaID  string
+	downloadLanguage string
+	downloadGroup    string
+	downloadOutput   string
+	downloadWorkers  int
+	downloadMarkRead bool
+)
+
+var mangaDownloadCmd = &cobra.Command{
+	Use:   "download <chapter-id | manga-id --range 1-20>",
+	Short: "Download chapters as CBZ archives for offline reading",
+	Long: `Download chapters from MangaDex into CBZ archives with ComicInfo.xml metadata,
+readable in most comic readers. Pages are fetched in parallel, and running the
+same command again resumes an interrupted download.
+
+Examples:
+  mangahub manga download 13 --range 1-20
+  mangahub manga download 13 --range 100 --group "TCB Scans" --mark-read
+  mangahub manga download <chapter-id> --manga-id 13`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mangaID := downloadMangaID
+		if downloadRange != "" {
+			mangaID = args[0]
+		}
+		if downloadMarkRead && mangaID == "" {
+			return fmt.Errorf("--mark-read needs the manga ID (--manga-id)")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		var series *models.Manga
+		var chapters []models.Chapter
+		if mangaID != "" {
+			serverURL, err := config.GetServerURL()
+			if err != nil {
+				printError("Configuration not initialized")
+				fmt.Println("Run: mangahub init")
+				return err
+			}
+			series = &models.Manga{}
+			if err := getServerJSON(serverURL+"/manga/"+url.PathEscape(mangaID), series); err != nil {
+				printError(fmt.Sprintf("Failed to get manga: %v", err))
+				return err
+			}
+			var catalogue struct {
+				Chapters []models.Chapter `json:"chapters"`
+			}
+			path := fmt.Sprintf("/manga/%s/chapters?language=%s", url.PathEscape(mangaID), url.QueryEscape(downloadLanguage))
+			if err := getServerJSON(serverURL+path, &catalogue); err != nil {
+				printError(fmt.Sprintf("Failed to get chapters: %v", err))
+				return err
+			}
+			chapters = catalogue.Chapters
+		}
+
+		source := manga.NewMangaDexSource()
+
+		var targets []models.Chapter
+		if downloadRange != "" {
+			from, to, err := download.ParseRange(downloadRange)
+			if err != nil {
+				return err
+			}
+			targets = download.Select(chapters, from, to, downloadGroup)
+			if len(targets) == 0 {
+				printError(fmt.Sprintf("No %s chapters in range %s", downloadLanguage, downloadRange))
+				return fmt.Errorf("no chapters to download")
+			}
+		} else {
+			target, err := findChapter(ctx, source, chapters, args[0], mangaID)
+			if err != nil {
+				printError(fmt.Sprintf("Failed to get chapter: %v", err))
+				return err
+			}
+			targets = []models.Chapter{*target}
+		}
+
+		downloader := download.NewDownloader(source, downloadWorkers)
+		var done []string
+		for i, ch := range targets {
+			label := "Ch." + ch.Number
+			if ch.Number == "" {
+				label = ch.ID
+			}
+			fmt.Printf("[%d/%d] %s ", i+1, len(targets), label)
+
+			result, err := downloader.Chapter(ctx, downloadOutput, series, ch)
+			if err != nil {
+				fmt.Println()
+				printError(fmt.Sprintf("Failed to download %s: %v", label, err))
+				if len(done) > 0 {
+					fmt.Println("Run the same command again to resume.")
+				}
+				markDownloaded(mangaID, done)
+				return err
+			}
+			if result.Skipped {
+				fmt.Printf("already downloaded: %s\n", result.Path)
+			} else {
+				fmt.Printf("%d pages, %.1f MB", result.Pages, float64(result.Bytes)/(1<<20))
+				if result.Resumed > 0 {
+					fmt.Printf(" (%d resumed)", result.Resumed)
+				}
+				fmt.Printf(" → %s\n", result.Path)
+			}
+			done = append(done, ch.ID)
+		}
+
+		printSuccess(fmt.Sprintf("Downloaded %d chapter(s) to %s", len(done), downloadOutput))
+		markDownloaded(mangaID, done)
+		return nil
+	},
+}
+
+// findChapter looks the chapter up in the server's catalogue, falling back
+// to MangaDex when it is not there (or no manga was given)
+func findChapter(ctx context.Context, source *manga.MangaDexSource, chapters []models.Chapter, chapterID, mangaID string) (*models.Chapter, error) {
+	for _, ch := range chapters {
+		if ch.ID == chapterID {
+			return &ch, nil
+		}
+	}
+
+	found, err := source.GetChapter(ctx, chapterID)
+	if err != nil {
+		return nil, err
+	}
+	ch := &models.Chapter{
+		ID:              found.ID,
+		MangaID:         mangaID,
+		Number:          found.Chapter,
+		Volume:          found.Volume,
+		Title:           found.Title,
+		Language:        found.Language,
+		ScanlationGroup: found.Group,
+		Pages:           found.Pages,
+	}
+	if t, err := time.Parse(time.RFC3339, found.PublishAt); err == nil {
+		ch.PublishedAt = &t
+	}
+	return ch, nil
+}
+
+// markDownloaded marks the downloaded chapters read when --mark-read is set
+func markDownloaded(mangaID string, chapterIDs []string) {
+	if !downloadMarkRead || len(chapterIDs) == 0 {
+		return
+	}
+	payload, _ := json.Marshal(map[string]interface{}{"chapter_ids": chapterIDs})
+	body, err := libraryRequest("POST", "/users/chapters/"+url.PathEscape(mangaID)+"/read", payload, "mark chapters read")
+	if err != nil {
+		return
+	}
+	var result struct {
+		CurrentChapter int `json:"current_chapter"`
+	}
+	json.Unmarshal(body, &result)
+	printSuccess(fmt.Sprintf("Marked %d chapter(s) read (current chapter: %d)", len(chapterIDs), result.CurrentChapter))
+}
+
+// getServerJSON decodes a public API response into v
+func getServerJSON(endpoint string, v interface{}) error {
+	res, err := http.Get(endpoint)
+	if err != nil {
+		return fmt.Errorf("server connection error")
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK {
+		var errResp map[string]string
+		json.Unmarshal(body, &errResp)
+		return fmt.Errorf("%s", errResp["error"])
+	}
+	return json.Unmarshal(body, v)
+}
+
+func init() {
+	mangaDownloadCmd.Flags().StringVar(&downloadRange, "range", "", "Chapter numbers to download from the manga, e.g. 1-20 (the argument is then a manga ID)")
+	mangaDownloadCmd.Flags().StringVar(&downloadMangaID, "manga-id", "", "Manga the chapter belongs to, for metadata and --mark-read")
+	mangaDownloadCmd.Flags().StringVar(&downloadLanguage, "language", "en", "Chapter language (e.g., en, ja, es)")
+	mangaDownloadCmd.Flags().StringVar(&downloadGroup, "group", "", "Preferred scanlation group when a chapter has several releases")
+	mangaDownloadCmd.Flags().StringVarP(&downloadOutput, "output", "o", "downloads", "Directory to write the CBZ files to")
+	mangaDownloadCmd.Flags().IntVar(&downloadWorkers, "workers", download.DefaultWorkers, fmt.Sprintf("Pages to fetch in parallel (max %d)", download.MaxWorkers))
+	mangaDownloadCmd.Flags().BoolVar(&downloadMarkRead, "mark-read", false, "Mark the downloaded chapters read in your progress")
+
+	mangaCmd.AddCommand(mangaDownloadCmd)
+}
@@ -67,13 +67,10 @@ var exportLibraryCmd = &cobra.Command{
 
 		body, _ := io.ReadAll(resp.Body)
 
-		var library []struct {
-			MangaID    string `json:"manga_id"`
-			Title      string `json:"title"`
-			Status     string `json:"status"`
-			IsFavorite bool   `json:"is_favorite"`
+		library, err := decodeLibrary(body)
+		if err != nil {
+			return fmt.Errorf("failed to parse library: %w", err)
 		}
-		json.Unmarshal(body, &library)
 
 		// Format output
 		var outputData []byte
@@ -83,9 +80,9 @@ var exportLibraryCmd = &cobra.Command{
 		case "csv":
 			var buf bytes.Buffer
 			w := csv.NewWriter(&buf)
-			w.Write([]string{"MangaID", "Title", "Status", "IsFavorite"})
+			w.Write([]string{"MangaID", "Title", "Status", "IsFavorite", "Tags"})
 			for _, item := range library {
-				w.Write([]string{item.MangaID, item.Title, item.Status, fmt.Sprintf("%v", item.IsFavorite)})
+				w.Write([]string{item.MangaID, item.Title, item.Status, fmt.Sprintf("%v", item.IsFavorite), strings.Join(item.Tags, ";")})
 			}
 			w.Flush()
 			outputData = buf.Bytes()
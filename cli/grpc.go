@@ -7,10 +7,12 @@ import (
 	"os"
 	"time"
 
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
 	pb "github.com/binhbb2204/Manga-Hub-Group13/proto/manga"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
 var (
@@ -141,10 +143,19 @@ func getGrpcClient() (*grpc.ClientConn, pb.MangaServiceClient) {
 	// For localhost connection
 	target := "localhost" + port
 
-	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(target,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(withRequestID))
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
 
 	return conn, pb.NewMangaServiceClient(conn)
 }
+
+// withRequestID tags every call with a fresh request ID so it can be traced
+// through the server logs
+func withRequestID(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", logger.NewRequestID())
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
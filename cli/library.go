@@ -6,17 +6,65 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/binhbb2204/Manga-Hub-Group13/cli/config"
 	"github.com/spf13/cobra"
 )
 
 var (
-	mangaID      string
-	mangaStatus  string
-	favoriteFlag bool
+	mangaID       string
+	mangaStatus   string
+	favoriteFlag  bool
+	libraryTags   []string
+	filterTag     string
+	favoritesOnly bool
+	unfavorite    bool
 )
 
+// libraryEntry is one row of the library, flattened from the status buckets
+// GET /users/library returns
+type libraryEntry struct {
+	MangaID        string   `json:"manga_id"`
+	Title          string   `json:"title"`
+	Status         string   `json:"status"`
+	CurrentChapter int      `json:"current_chapter"`
+	IsFavorite     bool     `json:"is_favorite"`
+	Tags           []string `json:"tags"`
+}
+
+func decodeLibrary(body []byte) ([]libraryEntry, error) {
+	var buckets map[string][]struct {
+		Manga struct {
+			ID    string `json:"id"`
+			Title string `json:"title"`
+		} `json:"manga"`
+		CurrentChapter int      `json:"current_chapter"`
+		Status         string   `json:"status"`
+		IsFavorite     bool     `json:"is_favorite"`
+		Tags           []string `json:"tags"`
+	}
+	if err := json.Unmarshal(body, &buckets); err != nil {
+		return nil, err
+	}
+
+	entries := []libraryEntry{}
+	for _, bucket := range []string{"reading", "plan_to_read", "completed"} {
+		for _, mp := range buckets[bucket] {
+			entries = append(entries, libraryEntry{
+				MangaID:        mp.Manga.ID,
+				Title:          mp.Manga.Title,
+				Status:         mp.Status,
+				CurrentChapter: mp.CurrentChapter,
+				IsFavorite:     mp.IsFavorite,
+				Tags:           mp.Tags,
+			})
+		}
+	}
+	return entries, nil
+}
+
 var libraryCmd = &cobra.Command{
 	Use:   "library",
 	Short: "Manage your manga library",
@@ -69,6 +117,7 @@ var libraryAddCmd = &cobra.Command{
 			"manga_id":    mangaID,
 			"status":      mangaStatus,
 			"is_favorite": favoriteFlag,
+			"tags":        libraryTags,
 		}
 		jsonData, _ := json.Marshal(reqBody)
 
@@ -99,6 +148,9 @@ var libraryAddCmd = &cobra.Command{
 		if favoriteFlag {
 			fmt.Println("Marked as favorite: Yes")
 		}
+		if len(libraryTags) > 0 {
+			fmt.Printf("Tags: %s\n", strings.Join(libraryTags, ", "))
+		}
 		fmt.Println("\nNext steps:")
 		fmt.Println("  View library: mangahub library list")
 		fmt.Println("  Update progress: mangahub progress update --manga-id", mangaID, "--chapter <chapter>")
@@ -110,7 +162,9 @@ var libraryAddCmd = &cobra.Command{
 var libraryListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "View your manga library",
-	Long:  `View all manga in your personal library.`,
+	Long: `View all manga in your personal library.
+
+Filter by a tag with --tag, or show only favorites with --favorites.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := config.Load()
 		if err != nil {
@@ -130,7 +184,19 @@ var libraryListCmd = &cobra.Command{
 			return err
 		}
 
-		req, _ := http.NewRequest("GET", serverURL+"/users/library", nil)
+		query := url.Values{}
+		if filterTag != "" {
+			query.Set("tag", filterTag)
+		}
+		if favoritesOnly {
+			query.Set("favorite", "true")
+		}
+		endpoint := serverURL + "/users/library"
+		if len(query) > 0 {
+			endpoint += "?" + query.Encode()
+		}
+
+		req, _ := http.NewRequest("GET", endpoint, nil)
 		req.Header.Set("Authorization", "Bearer "+cfg.User.Token)
 
 		client := &http.Client{}
@@ -150,14 +216,16 @@ var libraryListCmd = &cobra.Command{
 			return fmt.Errorf("failed to get library")
 		}
 
-		var library []struct {
-			MangaID    string `json:"manga_id"`
-			Title      string `json:"title"`
-			Status     string `json:"status"`
-			IsFavorite bool   `json:"is_favorite"`
+		library, err := decodeLibrary(body)
+		if err != nil {
+			printError("Failed to parse response")
+			return err
 		}
-		json.Unmarshal(body, &library)
 
+		if len(library) == 0 && (filterTag != "" || favoritesOnly) {
+			fmt.Println("No manga in your library match that filter")
+			return nil
+		}
 		if len(library) == 0 {
 			fmt.Println("Your library is empty")
 			fmt.Println("\nAdd manga to library:")
@@ -171,6 +239,12 @@ var libraryListCmd = &cobra.Command{
 			fmt.Printf("%d. %s\n", i+1, item.Title)
 			fmt.Printf("   ID: %s\n", item.MangaID)
 			fmt.Printf("   Status: %s\n", item.Status)
+			if item.CurrentChapter > 0 {
+				fmt.Printf("   Chapter: %d\n", item.CurrentChapter)
+			}
+			if len(item.Tags) > 0 {
+				fmt.Printf("   Tags: %s\n", strings.Join(item.Tags, ", "))
+			}
 			if item.IsFavorite {
 				fmt.Println("   ⭐ Favorite")
 			}
@@ -181,12 +255,116 @@ var libraryListCmd = &cobra.Command{
 	},
 }
 
+var libraryFavoriteCmd = &cobra.Command{
+	Use:   "favorite <manga-id>",
+	Short: "Mark a library entry as a favorite",
+	Long:  `Mark a manga in your library as a favorite, or unmark it with --remove.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonData, _ := json.Marshal(map[string]bool{"favorite": !unfavorite})
+		if _, err := libraryRequest("PUT", "/users/library/"+url.PathEscape(args[0])+"/favorite", jsonData, "update favorite"); err != nil {
+			return err
+		}
+
+		if unfavorite {
+			printSuccess("Removed from favorites")
+		} else {
+			printSuccess("Marked as favorite")
+		}
+		return nil
+	},
+}
+
+var libraryTagCmd = &cobra.Command{
+	Use:   "tag <manga-id> [tags...]",
+	Short: "Set the tags on a library entry",
+	Long: `Replace the tags on a manga in your library. Tags are free-form and
+case-insensitive; run with no tags to clear them.
+
+Examples:
+  mangahub library tag one-piece favorite-arcs reread
+  mangahub library list --tag reread`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonData, _ := json.Marshal(map[string][]string{"tags": args[1:]})
+		body, err := libraryRequest("PUT", "/users/library/"+url.PathEscape(args[0])+"/tags", jsonData, "update tags")
+		if err != nil {
+			return err
+		}
+
+		var resp struct {
+			Tags []string `json:"tags"`
+		}
+		json.Unmarshal(body, &resp)
+		if len(resp.Tags) == 0 {
+			printSuccess("Tags cleared")
+		} else {
+			printSuccess(fmt.Sprintf("Tags: %s", strings.Join(resp.Tags, ", ")))
+		}
+		return nil
+	},
+}
+
+// libraryRequest sends an authenticated request and returns the response body,
+// printing the server's error as "Failed to <action>: ..." on failure
+func libraryRequest(method, path string, payload []byte, action string) ([]byte, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		printError("Configuration not initialized")
+		fmt.Println("Run: mangahub init")
+		return nil, err
+	}
+
+	if cfg.User.Token == "" {
+		printError("Not logged in")
+		fmt.Println("Run: mangahub auth login --username <username>")
+		return nil, fmt.Errorf("authentication required")
+	}
+
+	serverURL, err := config.GetServerURL()
+	if err != nil {
+		return nil, err
+	}
+
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewBuffer(payload)
+	}
+	req, _ := http.NewRequest(method, serverURL+path, reqBody)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+cfg.User.Token)
+
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		printError(fmt.Sprintf("Failed to %s: Server connection error", action))
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errResp map[string]string
+		json.Unmarshal(body, &errResp)
+		printError(fmt.Sprintf("Failed to %s: %s", action, errResp["error"]))
+		return nil, fmt.Errorf("failed to %s", action)
+	}
+	return body, nil
+}
+
 func init() {
 	libraryAddCmd.Flags().StringVar(&mangaID, "manga-id", "", "Manga ID to add")
 	libraryAddCmd.Flags().StringVar(&mangaStatus, "status", "plan_to_read", "Reading status (reading, completed, on_hold, dropped, plan_to_read)")
 	libraryAddCmd.Flags().BoolVar(&favoriteFlag, "favorite", false, "Mark as favorite")
+	libraryAddCmd.Flags().StringSliceVar(&libraryTags, "tag", nil, "Tag the entry (repeatable or comma-separated)")
 	libraryAddCmd.MarkFlagRequired("manga-id")
 
+	libraryListCmd.Flags().StringVar(&filterTag, "tag", "", "Only show entries with this tag")
+	libraryListCmd.Flags().BoolVar(&favoritesOnly, "favorites", false, "Only show favorites")
+
+	libraryFavoriteCmd.Flags().BoolVar(&unfavorite, "remove", false, "Remove from favorites instead")
+
 	libraryCmd.AddCommand(libraryAddCmd)
 	libraryCmd.AddCommand(libraryListCmd)
+	libraryCmd.AddCommand(libraryFavoriteCmd)
+	libraryCmd.AddCommand(libraryTagCmd)
 }
@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	listDescription string
+	listPublic      bool
+	listPosition    int
+)
+
+type userList struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	IsPublic    bool   `json:"is_public"`
+	ItemCount   int    `json:"item_count"`
+	Items       []struct {
+		Position int `json:"position"`
+		Manga    struct {
+			ID     string `json:"id"`
+			Title  string `json:"title"`
+			Author string `json:"author"`
+		} `json:"manga"`
+	} `json:"items"`
+}
+
+func (l userList) visibility() string {
+	if l.IsPublic {
+		return "public"
+	}
+	return "private"
+}
+
+var listsCmd = &cobra.Command{
+	Use:   "lists",
+	Short: "Manage your custom manga lists",
+	Long: `Custom lists are ordered collections of manga, separate from your reading
+status. Lists are private unless created with --public; anyone with the ID of
+a public list can view it.
+
+Examples:
+  mangahub library lists
+  mangahub library lists create "Top 10 Seinen" --public
+  mangahub library lists add <list-id> berserk
+  mangahub library lists move <list-id> berserk --position 1
+  mangahub library lists show <list-id>`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		body, err := libraryRequest("GET", "/users/lists", nil, "get lists")
+		if err != nil {
+			return err
+		}
+
+		var resp struct {
+			Lists []userList `json:"lists"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			printError("Failed to parse response")
+			return err
+		}
+
+		if len(resp.Lists) == 0 {
+			fmt.Println("You have no lists yet")
+			fmt.Println("\nCreate one:")
+			fmt.Println("  mangahub library lists create \"Favorites of 2024\"")
+			return nil
+		}
+
+		fmt.Printf("Your Lists (%d):\n\n", len(resp.Lists))
+		for _, l := range resp.Lists {
+			fmt.Printf("%s  (%d manga, %s)\n", l.Name, l.ItemCount, l.visibility())
+			fmt.Printf("   ID: %s\n", l.ID)
+			if l.Description != "" {
+				fmt.Printf("   %s\n", truncateString(l.Description, 70))
+			}
+			fmt.Println()
+		}
+		return nil
+	},
+}
+
+var listsCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a list",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonData, _ := json.Marshal(map[string]interface{}{
+			"name":        args[0],
+			"description": listDescription,
+			"is_public":   listPublic,
+		})
+		body, err := libraryRequest("POST", "/users/lists", jsonData, "create list")
+		if err != nil {
+			return err
+		}
+
+		var list userList
+		json.Unmarshal(body, &list)
+		printSuccess(fmt.Sprintf("Created %s list %q", list.visibility(), list.Name))
+		fmt.Printf("List ID: %s\n", list.ID)
+		fmt.Println("\nAdd manga:")
+		fmt.Printf("  mangahub library lists add %s <manga-id>\n", list.ID)
+		return nil
+	},
+}
+
+var listsShowCmd = &cobra.Command{
+	Use:   "show <list-id>",
+	Short: "Show a list and its manga in order",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		body, err := libraryRequest("GET", "/users/lists/"+url.PathEscape(args[0]), nil, "get list")
+		if err != nil {
+			return err
+		}
+
+		var list userList
+		if err := json.Unmarshal(body, &list); err != nil {
+			printError("Failed to parse response")
+			return err
+		}
+
+		fmt.Printf("\n%s  (%s, by %s)\n", list.Name, list.visibility(), list.Username)
+		if list.Description != "" {
+			fmt.Println(wrapText(list.Description, 75))
+		}
+		fmt.Println("─────────────────────────────────────────────────────────────────────────")
+		if len(list.Items) == 0 {
+			fmt.Println("This list is empty")
+			return nil
+		}
+		for _, item := range list.Items {
+			fmt.Printf("%3d. %s\n", item.Position, item.Manga.Title)
+			fmt.Printf("     ID: %s", item.Manga.ID)
+			if item.Manga.Author != "" {
+				fmt.Printf(" · %s", item.Manga.Author)
+			}
+			fmt.Println()
+		}
+		fmt.Println()
+		return nil
+	},
+}
+
+var listsAddCmd = &cobra.Command{
+	Use:   "add <list-id> <manga-id>",
+	Short: "Add a manga to a list",
+	Long:  `Add a manga to a list, at the end or at --position (1 is the top).`,
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonData, _ := json.Marshal(map[string]interface{}{"manga_id": args[1], "position": listPosition})
+		body, err := libraryRequest("POST", "/users/lists/"+url.PathEscape(args[0])+"/items", jsonData, "add to list")
+		if err != nil {
+			return err
+		}
+
+		var list userList
+		json.Unmarshal(body, &list)
+		printSuccess(fmt.Sprintf("Added %s to %q (%d manga)", args[1], list.Name, list.ItemCount))
+		return nil
+	},
+}
+
+var listsMoveCmd = &cobra.Command{
+	Use:   "move <list-id> <manga-id>",
+	Short: "Move a manga to another position in a list",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if listPosition < 1 {
+			return fmt.Errorf("position is required (--position, 1 is the top)")
+		}
+
+		jsonData, _ := json.Marshal(map[string]int{"position": listPosition})
+		path := "/users/lists/" + url.PathEscape(args[0]) + "/items/" + url.PathEscape(args[1])
+		if _, err := libraryRequest("PUT", path, jsonData, "move manga"); err != nil {
+			return err
+		}
+
+		printSuccess(fmt.Sprintf("Moved %s to position %d", args[1], listPosition))
+		return nil
+	},
+}
+
+var listsRemoveCmd = &cobra.Command{
+	Use:   "remove <list-id> <manga-id>",
+	Short: "Remove a manga from a list",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := "/users/lists/" + url.PathEscape(args[0]) + "/items/" + url.PathEscape(args[1])
+		if _, err := libraryRequest("DELETE", path, nil, "remove manga"); err != nil {
+			return err
+		}
+
+		printSuccess(fmt.Sprintf("Removed %s from the list", args[1]))
+		return nil
+	},
+}
+
+var listsDeleteCmd = &cobra.Command{
+	Use:   "delete <list-id>",
+	Short: "Delete a list",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := libraryRequest("DELETE", "/users/lists/"+url.PathEscape(args[0]), nil, "delete list"); err != nil {
+			return err
+		}
+
+		printSuccess("List deleted")
+		return nil
+	},
+}
+
+func init() {
+	listsCreateCmd.Flags().StringVar(&listDescription, "description", "", "Short description of the list")
+	listsCreateCmd.Flags().BoolVar(&listPublic, "public", false, "Let others view the list")
+	listsAddCmd.Flags().IntVar(&listPosition, "position", 0, "Insert at this position instead of the end")
+	listsMoveCmd.Flags().IntVar(&listPosition, "position", 0, "New position (1 is the top)")
+
+	listsCmd.AddCommand(listsCreateCmd)
+	listsCmd.AddCommand(listsShowCmd)
+	listsCmd.AddCommand(listsAddCmd)
+	listsCmd.AddCommand(listsMoveCmd)
+	listsCmd.AddCommand(listsRemoveCmd)
+	listsCmd.AddCommand(listsDeleteCmd)
+	libraryCmd.AddCommand(listsCmd)
+}
@@ -1092,7 +1092,7 @@ var mangaReadCmd = &cobra.Command{
 		fmt.Println("─────────────────────────────────────────────────────────────────────────")
 		fmt.Println("\nYou can:")
 		fmt.Println("  - Copy these URLs and open in browser")
-		fmt.Println("  - Download images using wget/curl")
+		fmt.Printf("  - Download it as a CBZ: mangahub manga download %s\n", chapterID)
 		fmt.Printf("  - Read online: https://mangadex.org/chapter/%s\n", chapterID)
 
 		return nil
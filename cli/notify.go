@@ -47,7 +47,7 @@ var notifySubscribeCmd = &cobra.Command{
 
 		types := eventTypes
 		if len(types) == 0 {
-			types = []string{"progress_update", "library_update", "chapter_release"}
+			types = []string{"progress_update", "library_update", "chapter_release", "chat_mention", "chat_keyword"}
 		}
 
 		serverAddr := net.JoinHostPort(cfg.Server.Host, fmt.Sprintf("%d", cfg.Server.UDPPort))
@@ -162,6 +162,15 @@ var notifySubscribeCmd = &cobra.Command{
 					if delta, ok := data["delta"].(float64); ok {
 						fmt.Printf("  +%0.f new chapters!\n", delta)
 					}
+					if from, ok := data["from"].(string); ok {
+						fmt.Printf("  From: %s in #%v\n", from, data["room"])
+					}
+					if keyword, ok := data["keyword"].(string); ok {
+						fmt.Printf("  Keyword: %s\n", keyword)
+					}
+					if content, ok := data["content"].(string); ok {
+						fmt.Printf("  Message: %s\n", content)
+					}
 				}
 			}
 		}
@@ -347,7 +356,7 @@ func init() {
 	notifyCmd.AddCommand(notifyPreferencesCmd)
 	notifyCmd.AddCommand(notifyTestCmd)
 
-	notifySubscribeCmd.Flags().StringSliceVar(&eventTypes, "events", []string{}, "event types to subscribe to (progress_update, library_update)")
+	notifySubscribeCmd.Flags().StringSliceVar(&eventTypes, "events", []string{}, "event types to subscribe to (progress_update, library_update, chat_mention, chat_keyword)")
 
 	// Preferences flags
 	enableNotifications = notifyPreferencesCmd.Flags().Bool("enable", false, "Enable notifications")
@@ -0,0 +1,354 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/cli/config"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/reader"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	readProtocol string
+	readMangaID  string
+	readNoSync   bool
+)
+
+var readCmd = &cobra.Command{
+	Use:   "read <file.cbz | directory>...",
+	Short: "Read downloaded chapters in the terminal",
+	Long: `Page through CBZ archives (see 'mangahub manga download') without leaving the
+terminal. Pages are drawn with the kitty or sixel image protocol when the
+terminal supports one, otherwise they open in your system image viewer.
+
+When you reach the last page of a chapter, your progress is synced over the
+TCP sync server like any other device.
+
+Keys:
+  → l j space   next page          ← h k backspace   previous page
+  g / G         first / last page  ] / [             next / previous chapter
+  o             open page in viewer  q               quit`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths, err := expandCBZPaths(args)
+		if err != nil {
+			return err
+		}
+
+		var books []*reader.Book
+		defer func() {
+			for _, b := range books {
+				b.Close()
+			}
+		}()
+		for _, p := range paths {
+			b, err := reader.Open(p)
+			if err != nil {
+				printError(fmt.Sprintf("Failed to open %s: %v", p, err))
+				return err
+			}
+			books = append(books, b)
+		}
+
+		protocol := reader.DetectProtocol(os.Getenv)
+		if readProtocol != "" {
+			if protocol, err = reader.ParseProtocol(readProtocol); err != nil {
+				return err
+			}
+		}
+
+		fd := int(os.Stdin.Fd())
+		if !term.IsTerminal(fd) {
+			return fmt.Errorf("read needs an interactive terminal")
+		}
+		return runReader(fd, reader.NewPosition(books), protocol)
+	},
+}
+
+type syncResult struct {
+	book    *reader.Book
+	message string
+}
+
+func runReader(fd int, pos *reader.Position, protocol reader.Protocol) error {
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to switch terminal to raw mode: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	// Alternate screen with the cursor hidden, like a pager
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	tmpDir, err := os.MkdirTemp("", "mangahub-read-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	keys := make(chan reader.Key)
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- reader.ParseKey(buf[:n])
+		}
+	}()
+
+	synced := make(map[*reader.Book]bool)
+	syncs := make(chan syncResult, 1)
+	status := ""
+	moved := true
+
+	for {
+		book := pos.Current()
+		if pos.AtEnd() && !synced[book] && !readNoSync {
+			synced[book] = true
+			status = "Syncing progress..."
+			go func() { syncs <- syncResult{book: book, message: syncFinishedChapter(book)} }()
+		}
+		renderPage(fd, pos, protocol, tmpDir, status, moved)
+		moved = false
+
+		select {
+		case res := <-syncs:
+			status = res.book.Title() + ": " + res.message
+		case key, ok := <-keys:
+			if !ok || key == reader.KeyQuit {
+				if protocol == reader.ProtocolKitty {
+					reader.ClearKitty(os.Stdout)
+				}
+				return nil
+			}
+			if key == reader.KeyOpen {
+				data, name, err := book.Page(pos.Page)
+				if err == nil {
+					err = openInViewer(tmpDir, data, name)
+				}
+				if err != nil {
+					status = "Failed to open viewer: " + err.Error()
+				}
+				continue
+			}
+			if pos.Apply(key) {
+				status = ""
+				moved = true
+			}
+		}
+	}
+}
+
+// renderPage draws the current page above a one-line status bar. In viewer
+// mode the page is only handed to the viewer when it changed.
+func renderPage(fd int, pos *reader.Position, protocol reader.Protocol, tmpDir, status string, moved bool) {
+	cols, rows, err := term.GetSize(fd)
+	if err != nil || rows < 2 {
+		cols, rows = 80, 24
+	}
+
+	book := pos.Current()
+	fmt.Print("\x1b[2J\x1b[H")
+	if protocol == reader.ProtocolKitty {
+		reader.ClearKitty(os.Stdout)
+	}
+
+	data, name, err := book.Page(pos.Page)
+	if err == nil {
+		switch protocol {
+		case reader.ProtocolKitty:
+			err = reader.EncodeKitty(os.Stdout, data, cols, rows-1)
+		case reader.ProtocolSixel:
+			err = reader.EncodeSixel(os.Stdout, data, cols, rows-1)
+		default:
+			if moved {
+				err = openInViewer(tmpDir, data, name)
+			}
+			if err == nil {
+				fmt.Print("\r\n  Page opened in your image viewer.\r\n")
+			}
+		}
+	}
+	if err != nil {
+		fmt.Printf("\r\n  Can't show this page here: %v\r\n  Press o to open it in your image viewer.\r\n", err)
+	}
+
+	line := fmt.Sprintf(" %s  page %d/%d", book.Title(), pos.Page+1, book.Len())
+	if len(pos.Books) > 1 {
+		line += fmt.Sprintf("  (chapter %d of %d)", pos.Book+1, len(pos.Books))
+	}
+	if status != "" {
+		line += "  " + status
+	} else {
+		line += "  ←/→ page  [/] chapter  o viewer  q quit"
+	}
+	fmt.Printf("\x1b[%d;1H\x1b[7m%-*s\x1b[0m", rows, cols, truncateString(line, cols))
+}
+
+// syncFinishedChapter reports a finished chapter as sync_progress over a
+// short TCP sync session. Progress already past the chapter is left alone,
+// so re-reading an old chapter does not move it back.
+func syncFinishedChapter(book *reader.Book) string {
+	mangaID, _ := book.Info.MangaHubIDs()
+	if readMangaID != "" {
+		mangaID = readMangaID
+	}
+	if mangaID == "" {
+		return "not synced (no manga ID in the archive; use --manga-id)"
+	}
+	chapterNum, ok := book.ChapterNumber()
+	if !ok {
+		return "not synced (chapter has no number)"
+	}
+
+	cfg, err := config.Load()
+	if err != nil || cfg.User.Token == "" {
+		return "not synced (log in with 'mangahub auth login')"
+	}
+
+	serverAddr := net.JoinHostPort(cfg.Server.Host, fmt.Sprintf("%d", cfg.Server.TCPPort))
+	conn, err := net.DialTimeout("tcp", serverAddr, 5*time.Second)
+	if err != nil {
+		return "sync failed: sync server unreachable"
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	r := bufio.NewReader(conn)
+	if _, err := tcpRequest(conn, r, "auth", map[string]string{"token": cfg.User.Token}, "success"); err != nil {
+		return "sync failed: " + err.Error()
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "My Device"
+	}
+	connect := map[string]string{"device_type": "cli", "device_name": hostname + " (reader)"}
+	if _, err := tcpRequest(conn, r, "connect", connect, "connected"); err != nil {
+		return "sync failed: " + err.Error()
+	}
+	defer tcpSend(conn, "disconnect", map[string]string{})
+
+	if payload, err := tcpRequest(conn, r, "get_progress", map[string]string{"manga_id": mangaID}, "progress"); err == nil {
+		var current struct {
+			CurrentChapter int `json:"current_chapter"`
+		}
+		json.Unmarshal(payload, &current)
+		if current.CurrentChapter >= chapterNum {
+			return fmt.Sprintf("finished (progress already at chapter %d)", current.CurrentChapter)
+		}
+	}
+
+	sync := map[string]interface{}{"manga_id": mangaID, "current_chapter": chapterNum}
+	if _, err := tcpRequest(conn, r, "sync_progress", sync, "success"); err != nil {
+		return "sync failed: " + err.Error()
+	}
+	return fmt.Sprintf("✓ progress synced to chapter %d", chapterNum)
+}
+
+func tcpSend(conn net.Conn, msgType string, payload interface{}) error {
+	data, _ := json.Marshal(map[string]interface{}{"type": msgType, "payload": payload})
+	_, err := conn.Write(append(data, '\n'))
+	return err
+}
+
+// tcpRequest sends a message and waits for the reply of type want, skipping
+// broadcasts such as sync_update that arrive in between
+func tcpRequest(conn net.Conn, r *bufio.Reader, msgType string, payload interface{}, want string) (json.RawMessage, error) {
+	if err := tcpSend(conn, msgType, payload); err != nil {
+		return nil, fmt.Errorf("connection lost")
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("no reply from sync server")
+		}
+		var msg struct {
+			Type    string          `json:"type"`
+			Payload json.RawMessage `json:"payload"`
+		}
+		if json.Unmarshal([]byte(line), &msg) != nil {
+			continue
+		}
+		switch msg.Type {
+		case want:
+			return msg.Payload, nil
+		case "error":
+			var e struct {
+				Message string `json:"message"`
+			}
+			json.Unmarshal(msg.Payload, &e)
+			return nil, fmt.Errorf("%s", e.Message)
+		}
+	}
+}
+
+// openInViewer hands a page to the system image viewer
+func openInViewer(dir string, data []byte, name string) error {
+	file := filepath.Join(dir, fmt.Sprintf("%d-%s", time.Now().UnixNano(), name))
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return err
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", file)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", file)
+	default:
+		cmd = exec.Command("xdg-open", file)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// expandCBZPaths replaces directories with the CBZ files inside them
+func expandCBZPaths(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".cbz") {
+				paths = append(paths, filepath.Join(arg, e.Name()))
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no CBZ files found")
+	}
+	return paths, nil
+}
+
+func init() {
+	readCmd.Flags().StringVar(&readProtocol, "protocol", "", "Image protocol: kitty, sixel or viewer (detected from the terminal by default)")
+	readCmd.Flags().StringVar(&readMangaID, "manga-id", "", "Manga to sync progress to, for archives not downloaded by MangaHub")
+	readCmd.Flags().BoolVar(&readNoSync, "no-sync", false, "Don't sync progress when a chapter is finished")
+}
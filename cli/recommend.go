@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/cli/config"
+	"github.com/spf13/cobra"
+)
+
+var recommendLimit int
+
+var mangaRecommendCmd = &cobra.Command{
+	Use:   "recommend",
+	Short: "Suggest manga to read next",
+	Long: `Show manga picked for you from your library and ratings, with the reason
+for each suggestion. Rating more manga improves the results.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			printError("Configuration not initialized")
+			fmt.Println("Run: mangahub init")
+			return err
+		}
+
+		if cfg.User.Token == "" {
+			printError("Not logged in")
+			fmt.Println("Run: mangahub auth login --username <username>")
+			return fmt.Errorf("authentication required")
+		}
+
+		serverURL, err := config.GetServerURL()
+		if err != nil {
+			return err
+		}
+
+		req, _ := http.NewRequest("GET", fmt.Sprintf("%s/users/recommendations?limit=%d", serverURL, recommendLimit), nil)
+		req.Header.Set("Authorization", "Bearer "+cfg.User.Token)
+
+		resp, err := (&http.Client{}).Do(req)
+		if err != nil {
+			printError("Failed to get recommendations: Server connection error")
+			fmt.Println("Check server status: mangahub server status")
+			return err
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			var errResp map[string]string
+			json.Unmarshal(body, &errResp)
+			printError(fmt.Sprintf("Failed to get recommendations: %s", errResp["error"]))
+			return fmt.Errorf("failed to get recommendations")
+		}
+
+		var response struct {
+			Recommendations []struct {
+				Manga struct {
+					ID     string   `json:"id"`
+					Title  string   `json:"title"`
+					Author string   `json:"author"`
+					Genres []string `json:"genres"`
+				} `json:"manga"`
+				Score   float64  `json:"score"`
+				Reasons []string `json:"reasons"`
+			} `json:"recommendations"`
+			ComputedAt *string `json:"computed_at"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			printError("Failed to parse response")
+			return err
+		}
+
+		if len(response.Recommendations) == 0 {
+			if response.ComputedAt == nil {
+				fmt.Println("Recommendations are still being computed, try again in a moment")
+				return nil
+			}
+			fmt.Println("No recommendations yet")
+			fmt.Println("\nRate or finish a few manga in your library first:")
+			fmt.Println("  mangahub progress update --manga-id <id> --chapter <chapter>")
+			return nil
+		}
+
+		fmt.Printf("\nRecommended for you (%d)\n", len(response.Recommendations))
+		fmt.Println("─────────────────────────────────────────────────────────────────────────")
+		for i, r := range response.Recommendations {
+			fmt.Printf("%2d. %s", i+1, r.Manga.Title)
+			if r.Manga.Author != "" {
+				fmt.Printf(" by %s", r.Manga.Author)
+			}
+			fmt.Printf("  [%s]\n", r.Manga.ID)
+			for _, reason := range r.Reasons {
+				fmt.Printf("    - %s\n", reason)
+			}
+		}
+		fmt.Println("─────────────────────────────────────────────────────────────────────────")
+		fmt.Println("\nAdd one to your library: mangahub library add --manga-id <id>")
+
+		return nil
+	},
+}
+
+func init() {
+	mangaRecommendCmd.Flags().IntVar(&recommendLimit, "limit", 10, "Maximum number of recommendations")
+
+	mangaCmd.AddCommand(mangaRecommendCmd)
+}
@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/cli/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reviewsSort         string
+	reviewsLimit        int
+	reviewsWrite        string
+	reviewsSpoiler      bool
+	reviewsShowSpoilers bool
+	reviewsHelpful      string
+)
+
+type reviewItem struct {
+	ID           string   `json:"id"`
+	Username     string   `json:"username"`
+	Rating       *float64 `json:"rating"`
+	Body         string   `json:"body"`
+	Spoiler      bool     `json:"spoiler"`
+	HelpfulCount int      `json:"helpful_count"`
+	CreatedAt    string   `json:"created_at"`
+	EditedAt     *string  `json:"edited_at"`
+}
+
+var mangaReviewsCmd = &cobra.Command{
+	Use:   "reviews <manga-id>",
+	Short: "Read or write reviews for a manga",
+	Long: `List reader reviews for a manga, write your own with --write, or vote a
+review helpful with --helpful <review-id>. Spoiler reviews are masked unless
+--show-spoilers is set.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mangaID := args[0]
+
+		serverURL, err := config.GetServerURL()
+		if err != nil {
+			printError("Configuration not initialized")
+			fmt.Println("Run: mangahub init")
+			return err
+		}
+
+		if reviewsWrite != "" || reviewsHelpful != "" {
+			return writeReview(serverURL, mangaID)
+		}
+
+		query := url.Values{}
+		query.Set("sort", reviewsSort)
+		query.Set("limit", fmt.Sprintf("%d", reviewsLimit))
+
+		res, err := http.Get(fmt.Sprintf("%s/manga/%s/reviews?%s", serverURL, url.PathEscape(mangaID), query.Encode()))
+		if err != nil {
+			printError("Failed to get reviews: Server connection error")
+			fmt.Println("Check server status: mangahub server status")
+			return err
+		}
+		defer res.Body.Close()
+
+		body, _ := io.ReadAll(res.Body)
+		if res.StatusCode != http.StatusOK {
+			var errRes map[string]string
+			json.Unmarshal(body, &errRes)
+			printError(fmt.Sprintf("Failed to get reviews: %s", errRes["error"]))
+			return fmt.Errorf("failed to get reviews")
+		}
+
+		var response struct {
+			Reviews []reviewItem `json:"reviews"`
+			Total   int          `json:"total"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			printError("Failed to parse response")
+			return err
+		}
+
+		if len(response.Reviews) == 0 {
+			fmt.Println("No reviews yet")
+			fmt.Printf("\nBe the first: mangahub manga reviews %s --write \"your review\"\n", mangaID)
+			return nil
+		}
+
+		fmt.Printf("\nReviews for %s (%d of %d, sorted by %s)\n", mangaID, len(response.Reviews), response.Total, reviewsSort)
+		fmt.Println("─────────────────────────────────────────────────────────────────────────")
+		for _, r := range response.Reviews {
+			rating := "unrated"
+			if r.Rating != nil {
+				rating = strings.Repeat("★", int(*r.Rating)) + strings.Repeat("☆", 5-int(*r.Rating))
+			}
+			edited := ""
+			if r.EditedAt != nil {
+				edited = " (edited)"
+			}
+			fmt.Printf("%s  %s  %d found this helpful%s\n", r.Username, rating, r.HelpfulCount, edited)
+
+			if r.Spoiler && !reviewsShowSpoilers {
+				fmt.Println("  [spoiler hidden - use --show-spoilers to read]")
+			} else {
+				fmt.Println(indent(wrapText(r.Body, 70), "  "))
+			}
+			fmt.Printf("  id: %s\n", r.ID)
+			fmt.Println("─────────────────────────────────────────────────────────────────────────")
+		}
+
+		return nil
+	},
+}
+
+// writeReview posts --write as a review or --helpful as a vote for the current user
+func writeReview(serverURL, mangaID string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		printError("Configuration not initialized")
+		fmt.Println("Run: mangahub init")
+		return err
+	}
+	if cfg.User.Token == "" {
+		printError("Not logged in")
+		fmt.Println("Run: mangahub auth login --username <username>")
+		return fmt.Errorf("authentication required")
+	}
+
+	endpoint := fmt.Sprintf("%s/manga/%s/reviews", serverURL, url.PathEscape(mangaID))
+	var payload []byte
+	if reviewsHelpful != "" {
+		endpoint += "/" + url.PathEscape(reviewsHelpful) + "/helpful"
+	} else {
+		payload, _ = json.Marshal(map[string]interface{}{"body": reviewsWrite, "spoiler": reviewsSpoiler})
+	}
+
+	req, _ := http.NewRequest("POST", endpoint, bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+cfg.User.Token)
+
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		printError("Failed to submit: Server connection error")
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		var errResp map[string]string
+		json.Unmarshal(body, &errResp)
+		printError(fmt.Sprintf("Failed to submit: %s", errResp["error"]))
+		return fmt.Errorf("failed to submit review")
+	}
+
+	if reviewsHelpful != "" {
+		printSuccess("Marked review as helpful")
+		return nil
+	}
+	printSuccess("Review posted!")
+	fmt.Printf("\nSee it with: mangahub manga reviews %s\n", mangaID)
+	return nil
+}
+
+func indent(text, prefix string) string {
+	return prefix + strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
+
+func init() {
+	mangaReviewsCmd.Flags().StringVar(&reviewsSort, "sort", "recent", "Sort order (recent, helpful)")
+	mangaReviewsCmd.Flags().IntVar(&reviewsLimit, "limit", 10, "Maximum number of reviews to show")
+	mangaReviewsCmd.Flags().StringVar(&reviewsWrite, "write", "", "Post a review with this text")
+	mangaReviewsCmd.Flags().BoolVar(&reviewsSpoiler, "spoiler", false, "Mark the review you are writing as a spoiler")
+	mangaReviewsCmd.Flags().BoolVar(&reviewsShowSpoilers, "show-spoilers", false, "Show the text of spoiler reviews")
+	mangaReviewsCmd.Flags().StringVar(&reviewsHelpful, "helpful", "", "Vote the review with this ID helpful")
+
+	mangaCmd.AddCommand(mangaReviewsCmd)
+}
@@ -43,6 +43,9 @@ func init() {
 	rootCmd.AddCommand(importCmd)
 	rootCmd.AddCommand(updateCmd)
 	rootCmd.AddCommand(grpcCmd)
+	rootCmd.AddCommand(dbCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(readCmd)
 
 	libraryCmd.AddCommand(libraryBatchUpdateCmd)
 
@@ -0,0 +1,206 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/cli/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsFormat string
+	statsWeeks  int
+)
+
+type readingStats struct {
+	TotalManga         int            `json:"total_manga"`
+	ByStatus           map[string]int `json:"by_status"`
+	CompletionRate     float64        `json:"completion_rate"`
+	TotalChaptersRead  int            `json:"total_chapters_read"`
+	AverageRating      float64        `json:"average_rating"`
+	RatedCount         int            `json:"rated_count"`
+	RatingDistribution map[string]int `json:"rating_distribution"`
+	Genres             []struct {
+		Genre string `json:"genre"`
+		Count int    `json:"count"`
+	} `json:"genres"`
+	ChaptersPerWeek []struct {
+		WeekStart string `json:"week_start"`
+		Chapters  int    `json:"chapters"`
+	} `json:"chapters_per_week"`
+	CurrentStreakDays int  `json:"current_streak_days"`
+	LongestStreakDays int  `json:"longest_streak_days"`
+	HistoryAvailable  bool `json:"history_available"`
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show your reading statistics",
+	Long: `Show chapters read per week, genre breakdown, completion rate, ratings and
+reading streaks. Use --format json for scripting.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if statsFormat != "text" && statsFormat != "json" {
+			return fmt.Errorf("invalid format: %s (use: text, json)", statsFormat)
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			printError("Configuration not initialized")
+			fmt.Println("Run: mangahub init")
+			return err
+		}
+
+		if cfg.User.Token == "" {
+			printError("Not logged in")
+			fmt.Println("Run: mangahub auth login --username <username>")
+			return fmt.Errorf("authentication required")
+		}
+
+		serverURL, err := config.GetServerURL()
+		if err != nil {
+			return err
+		}
+
+		req, _ := http.NewRequest("GET", fmt.Sprintf("%s/users/stats?weeks=%d", serverURL, statsWeeks), nil)
+		req.Header.Set("Authorization", "Bearer "+cfg.User.Token)
+
+		resp, err := (&http.Client{}).Do(req)
+		if err != nil {
+			printError("Failed to get stats: Server connection error")
+			fmt.Println("Check server status: mangahub server status")
+			return err
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			var errResp map[string]string
+			json.Unmarshal(body, &errResp)
+			printError(fmt.Sprintf("Failed to get stats: %s", errResp["error"]))
+			return fmt.Errorf("failed to get stats")
+		}
+
+		if statsFormat == "json" {
+			var out bytes.Buffer
+			json.Indent(&out, body, "", "  ")
+			fmt.Println(out.String())
+			return nil
+		}
+
+		var stats readingStats
+		if err := json.Unmarshal(body, &stats); err != nil {
+			printError("Failed to parse response")
+			return err
+		}
+		printReadingStats(stats)
+		return nil
+	},
+}
+
+func printReadingStats(s readingStats) {
+	fmt.Println("\nReading Stats")
+	fmt.Println("─────────────────────────────────────────────────────────────────────────")
+	fmt.Printf("Library      %d manga (reading %d · completed %d · plan to read %d)\n",
+		s.TotalManga, s.ByStatus["reading"], s.ByStatus["completed"], s.ByStatus["plan_to_read"])
+	fmt.Printf("Completion   %s %.0f%%\n", bar(int(s.CompletionRate*100), 100, 20), s.CompletionRate*100)
+	fmt.Printf("Chapters     %d read\n", s.TotalChaptersRead)
+	if s.RatedCount > 0 {
+		fmt.Printf("Rating       %.1f★ average over %d rated\n", s.AverageRating, s.RatedCount)
+	} else {
+		fmt.Println("Rating       no ratings yet")
+	}
+	fmt.Printf("Streak       %d day(s), longest %d\n", s.CurrentStreakDays, s.LongestStreakDays)
+
+	if len(s.ChaptersPerWeek) > 0 {
+		values := make([]int, len(s.ChaptersPerWeek))
+		total, best := 0, 0
+		for i, w := range s.ChaptersPerWeek {
+			values[i] = w.Chapters
+			total += w.Chapters
+			if w.Chapters > s.ChaptersPerWeek[best].Chapters {
+				best = i
+			}
+		}
+		fmt.Printf("\nChapters per week (last %d weeks, from %s)\n", len(values), s.ChaptersPerWeek[0].WeekStart)
+		fmt.Printf("  %s  total %d", sparkline(values), total)
+		if total > 0 {
+			fmt.Printf(", best %d (week of %s)", s.ChaptersPerWeek[best].Chapters, s.ChaptersPerWeek[best].WeekStart)
+		}
+		fmt.Println()
+		if !s.HistoryAvailable {
+			fmt.Println("  (estimated from your library; weekly history builds up as you read)")
+		}
+	}
+
+	if len(s.Genres) > 0 {
+		fmt.Println("\nGenres")
+		genres := s.Genres
+		if len(genres) > 8 {
+			genres = genres[:8]
+		}
+		for _, g := range genres {
+			fmt.Printf("  %-16s %s %d\n", truncateString(g.Genre, 16), bar(g.Count, genres[0].Count, 24), g.Count)
+		}
+	}
+
+	if s.RatedCount > 0 {
+		fmt.Println("\nRatings")
+		maxCount := 0
+		for _, n := range s.RatingDistribution {
+			if n > maxCount {
+				maxCount = n
+			}
+		}
+		for stars := 5; stars >= 1; stars-- {
+			n := s.RatingDistribution[fmt.Sprintf("%d", stars)]
+			fmt.Printf("  %d★ %s %d\n", stars, bar(n, maxCount, 24), n)
+		}
+	}
+	fmt.Println()
+}
+
+// sparkline draws values as a row of block characters scaled to the maximum
+func sparkline(values []int) string {
+	levels := []rune("▁▂▃▄▅▆▇█")
+	maxValue := 0
+	for _, v := range values {
+		if v > maxValue {
+			maxValue = v
+		}
+	}
+
+	var sb strings.Builder
+	for _, v := range values {
+		if maxValue == 0 || v <= 0 {
+			sb.WriteRune(levels[0])
+			continue
+		}
+		sb.WriteRune(levels[(v*(len(levels)-1)+maxValue-1)/maxValue])
+	}
+	return sb.String()
+}
+
+// bar draws value as a horizontal bar of width cells, relative to maxValue
+func bar(value, maxValue, width int) string {
+	filled := 0
+	if maxValue > 0 {
+		filled = value * width / maxValue
+	}
+	if filled > width {
+		filled = width
+	}
+	if value > 0 && filled == 0 {
+		filled = 1
+	}
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+func init() {
+	statsCmd.Flags().StringVar(&statsFormat, "format", "text", "Output format (text, json)")
+	statsCmd.Flags().IntVar(&statsWeeks, "weeks", 12, "Number of weeks in the chapters-per-week series (max 52)")
+}
@@ -746,15 +746,25 @@ func displayEvent(jsonMsg string) {
 		return
 	}
 
-	msgType, ok := msg["type"].(string)
-	if !ok || msgType != "update_event" {
-		return
-	}
-
+	msgType, _ := msg["type"].(string)
 	payload, ok := msg["payload"].(map[string]interface{})
 	if !ok {
 		return
 	}
+	if msgType == "chat_mention" || msgType == "chat_keyword" {
+		displayChatAlert(msgType, payload)
+		return
+	}
+	if msgType == "presence_update" {
+		who, _ := payload["username"].(string)
+		status, _ := payload["status"].(string)
+		timestamp, _ := payload["timestamp"].(string)
+		fmt.Printf("[%s] %s %s is now %s\n", formatTimestamp(timestamp), presenceIcons[status], who, status)
+		return
+	}
+	if msgType != "update_event" {
+		return
+	}
 
 	timestamp, _ := payload["timestamp"].(string)
 	direction, _ := payload["direction"].(string)
@@ -778,6 +788,21 @@ func displayEvent(jsonMsg string) {
 	}
 }
 
+// displayChatAlert prints a chat mention or keyword alert forwarded by the server
+func displayChatAlert(msgType string, payload map[string]interface{}) {
+	timestamp, _ := payload["timestamp"].(string)
+	room, _ := payload["room"].(string)
+	from, _ := payload["from"].(string)
+	content, _ := payload["content"].(string)
+
+	what := "mentioned you"
+	if msgType == "chat_keyword" {
+		keyword, _ := payload["keyword"].(string)
+		what = fmt.Sprintf("said %q", keyword)
+	}
+	fmt.Printf("[%s] 🔔 %s %s in #%s: %s\n", formatTimestamp(timestamp), from, what, room, content)
+}
+
 func formatTimestamp(timestamp string) string {
 	t, err := time.Parse(time.RFC3339, timestamp)
 	if err != nil {
@@ -7,9 +7,18 @@ import (
 
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/auth"
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/chapter"
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/health"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/lists"
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/manga"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/presence"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/recommend"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/review"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/sse"
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/user"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/webhook"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/websocket"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/config"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/discovery"
@@ -45,10 +54,10 @@ func main() {
 	}
 	defer database.Close()
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "your-secret-key-change-this-in-production"
-		log.Warn("using_default_jwt_secret", "message", "Set JWT_SECRET environment variable in production!")
+	jwtSecret, err := config.LoadJWTSecret()
+	if err != nil {
+		log.Error("jwt_secret_missing", "error", err.Error())
+		os.Exit(1)
 	}
 
 	frontendURL := os.Getenv("FRONTEND_URL")
@@ -63,11 +72,37 @@ func main() {
 		log.Info("using_frontend_url", "url", frontendURL)
 	}
 
+	eventLog := bridge.NewEventLog(database.DB, logger.WithContext("component", "event_log"), bridge.RetentionPolicyFromEnv())
+	eventLog.Start()
+	defer eventLog.Stop()
+
+	// The unified bridge carries HTTP-side events to in-process subscribers such as webhooks
+	eventBridge := bridge.NewUnifiedBridge(logger.GetLogger())
+	eventBridge.SetEventLog(eventLog)
+	eventBridge.Start()
+	defer eventBridge.Stop()
+
 	apiBridge := bridge.NewBridge(logger.GetLogger())
+	apiBridge.SetEventLog(eventLog)
+	apiBridge.SetUnifiedBridge(eventBridge)
 	apiBridge.Start()
 	defer apiBridge.Stop()
 	log.Info("tcp_http_bridge_started")
 
+	webhookStore := webhook.NewStore(database.DB)
+	webhookDispatcher := webhook.NewDispatcher(webhookStore, logger.WithContext("component", "webhooks"), webhook.DefaultDispatcherConfig())
+	webhookDispatcher.Start()
+	defer webhookDispatcher.Stop()
+	eventBridge.Subscribe(webhookDispatcher.HandleEvent)
+
+	recommendEngine := recommend.NewEngine(repository.Default(), logger.WithContext("component", "recommendations"), recommend.ConfigFromEnv())
+	recommendEngine.Start()
+	defer recommendEngine.Stop()
+
+	// Nothing connects to the API server itself; presence comes from what
+	// the protocol servers stored
+	presenceService := presence.NewService(repository.Default(), logger.WithContext("component", "presence"), presence.ConfigFromEnv())
+
 	servicesConfig := config.LoadServicesConfig()
 	broadcaster := discovery.NewBroadcaster(localIP, map[string]string{
 		"api":       servicesConfig.API.URL(),
@@ -83,7 +118,17 @@ func main() {
 	authHandler := auth.NewHandler(jwtSecret)
 	gin.SetMode(gin.ReleaseMode)
 	mangaHandler := manga.NewHandler()
+	mangaHandler.SetBroker(sse.NewBroker(sse.Config{JWTSecret: jwtSecret}))
+	mangaHandler.SetBridge(eventBridge)
+	webhookHandler := webhook.NewHandler(webhookStore)
+	reviewHandler := review.NewHandler(review.NewStore(database.DB))
+	listHandler := lists.NewHandler(lists.NewStore(database.DB))
+	inboxHandler := websocket.NewInboxHandler(database.DB)
+	chapterHandler := chapter.NewHandler(chapter.NewCatalog(chapter.NewStore(database.DB)))
+	recommendHandler := recommend.NewHandler(recommendEngine)
+	presenceHandler := presence.NewHandler(presenceService, repository.Default())
 	userHandler := user.NewHandler(apiBridge)
+	chapterHandler.SetProgressSaver(userHandler)
 	healthHandler := health.NewHandler(apiBridge)
 	metricsHandler := metrics.NewHandler()
 
@@ -120,10 +165,12 @@ func main() {
 	}
 
 	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "Cache-Control"}
-	config.ExposeHeaders = []string{"Content-Length", "Content-Type"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "Cache-Control", "X-Request-ID"}
+	config.ExposeHeaders = []string{"Content-Length", "Content-Type", "X-Request-ID"}
 	config.AllowCredentials = true
 	router.Use(cors.New(config))
+	router.Use(logger.GinMiddleware())
+	router.Use(metrics.GinMiddleware())
 
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok", "local_ip": os.Getenv("LOCAL_IP")})
@@ -134,7 +181,8 @@ func main() {
 	})
 	router.GET("/healthz", healthHandler.Healthz)
 	router.GET("/readyz", healthHandler.Readyz)
-	router.GET("/metrics", metricsHandler.Metrics)
+	router.GET("/metrics", metricsHandler.Prometheus)
+	router.GET("/metrics/json", metricsHandler.Metrics)
 
 	// SSE notifications endpoint
 	router.GET("/events", mangaHandler.GetBroker().ServeSSE)
@@ -150,6 +198,7 @@ func main() {
 			c.JSON(400, gin.H{"error": "Invalid payload"})
 			return
 		}
+		log.ForContext(c).Debug("internal_notify_received", "user_id", payload.UserID, "event_type", payload.EventType)
 		if payload.UserID != "" {
 			mangaHandler.GetBroker().BroadcastToUser(payload.UserID, payload.EventType, payload.Message, payload.Data)
 		} else {
@@ -192,12 +241,25 @@ func main() {
 		mangaGroup.GET("/chapter/:chapterId/pages", mangaHandler.GetChapterPages)
 		// Generic ID route must be last
 		mangaGroup.GET("/:id", mangaHandler.GetMangaByID)
+		// Reviews and chapter discussion
+		mangaGroup.GET("/:id/reviews", reviewHandler.ListReviews)
+		mangaGroup.GET("/:id/chapters", chapterHandler.ListChapters)
+		mangaGroup.GET("/:id/reviews/:review_id/history", reviewHandler.GetReviewHistory)
+		mangaGroup.GET("/:id/chapters/:chapter/comments", reviewHandler.ListComments)
 		// Protected routes
 		protected := mangaGroup.Group("")
 		protected.Use(auth.AuthMiddleware(jwtSecret))
 		{
 			protected.POST("", mangaHandler.CreateManga)
 			protected.POST("/:id/refresh", mangaHandler.RefreshManga)
+			protected.POST("/:id/reviews", reviewHandler.CreateReview)
+			protected.PUT("/:id/reviews/:review_id", reviewHandler.UpdateReview)
+			protected.DELETE("/:id/reviews/:review_id", reviewHandler.DeleteReview)
+			protected.POST("/:id/reviews/:review_id/helpful", reviewHandler.MarkHelpful)
+			protected.DELETE("/:id/reviews/:review_id/helpful", reviewHandler.UnmarkHelpful)
+			protected.POST("/:id/chapters/:chapter/comments", reviewHandler.CreateComment)
+			protected.PUT("/:id/chapters/:chapter/comments/:comment_id", reviewHandler.UpdateComment)
+			protected.DELETE("/:id/chapters/:chapter/comments/:comment_id", reviewHandler.DeleteComment)
 		}
 
 		// Admin-only routes
@@ -206,6 +268,8 @@ func main() {
 		admin.Use(auth.AdminMiddleware())
 		{
 			admin.POST("/refresh-all", mangaHandler.RefreshAllManga)
+			admin.PUT("/:id/reviews/:review_id/moderation", reviewHandler.ModerateReview)
+			admin.PUT("/:id/chapters/:chapter/comments/:comment_id/moderation", reviewHandler.ModerateComment)
 		}
 	}
 
@@ -223,6 +287,56 @@ func main() {
 		userGroup.GET("/progress/:manga_id", userHandler.GetProgress)         // Get progress for specific manga
 		userGroup.PUT("/progress", userHandler.UpdateProgress)                // Update reading progress
 		userGroup.DELETE("/library/:manga_id", userHandler.RemoveFromLibrary) // Remove from library
+		userGroup.GET("/recommendations", recommendHandler.GetRecommendations)
+		userGroup.GET("/stats", userHandler.GetStats)
+		userGroup.PUT("/library/:manga_id/favorite", userHandler.SetFavorite)
+		userGroup.PUT("/library/:manga_id/tags", userHandler.SetTags)
+		userGroup.GET("/lists", listHandler.GetLists)
+		userGroup.POST("/lists", listHandler.CreateList)
+		userGroup.GET("/lists/:list_id", listHandler.GetList)
+		userGroup.PUT("/lists/:list_id", listHandler.UpdateList)
+		userGroup.DELETE("/lists/:list_id", listHandler.DeleteList)
+		userGroup.POST("/lists/:list_id/items", listHandler.AddItem)
+		userGroup.PUT("/lists/:list_id/items/:manga_id", listHandler.MoveItem)
+		userGroup.DELETE("/lists/:list_id/items/:manga_id", listHandler.RemoveItem)
+		userGroup.GET("/chapters/:manga_id", chapterHandler.GetReadState)
+		userGroup.POST("/chapters/:manga_id/read", chapterHandler.MarkRead)
+		userGroup.DELETE("/chapters/:manga_id/read/:chapter_id", chapterHandler.UnmarkRead)
+		userGroup.GET("/dms", inboxHandler.ListConversations)
+		userGroup.GET("/dms/:user", inboxHandler.GetConversation)
+		userGroup.GET("/conversations", inboxHandler.ListMemberConversations)
+		userGroup.GET("/mentions", inboxHandler.ListMentions)
+	}
+
+	// Chat history and search (protected)
+	conversationGroup := router.Group("/conversations")
+	conversationGroup.Use(func(c *gin.Context) {
+		c.Set("authHandler", authHandler)
+		auth.AuthMiddleware(jwtSecret)(c)
+	})
+	{
+		conversationGroup.GET("/search", inboxHandler.SearchMessages)
+		conversationGroup.GET("/:id/messages", inboxHandler.ListMessages)
+	}
+
+	router.GET("/presence", func(c *gin.Context) {
+		c.Set("authHandler", authHandler)
+		auth.AuthMiddleware(jwtSecret)(c)
+	}, presenceHandler.GetPresence)
+
+	// Webhook routes (protected)
+	webhookGroup := router.Group("/webhooks")
+	webhookGroup.Use(func(c *gin.Context) {
+		c.Set("authHandler", authHandler)
+		auth.AuthMiddleware(jwtSecret)(c)
+	})
+	{
+		webhookGroup.POST("", webhookHandler.CreateWebhook)
+		webhookGroup.GET("", webhookHandler.ListWebhooks)
+		webhookGroup.DELETE("/:id", webhookHandler.DeleteWebhook)
+		webhookGroup.GET("/:id/deliveries", webhookHandler.GetDeliveries)
+		webhookGroup.GET("/:id/dead-letters", webhookHandler.GetDeadLetters)
+		webhookGroup.GET("/dead-letters", auth.AdminMiddleware(), webhookHandler.ListAllDeadLetters)
 	}
 
 	// Debug routes (protected)
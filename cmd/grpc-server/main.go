@@ -6,11 +6,9 @@ import (
 	"os"
 
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/grpc"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/config"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
-	pb "github.com/binhbb2204/Manga-Hub-Group13/proto/manga"
 	"github.com/joho/godotenv"
-	googlegrpc "google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
 )
 
 func main() {
@@ -40,13 +38,17 @@ func main() {
 		log.Fatalf("failed to listen: %v", err)
 	}
 
-	s := googlegrpc.NewServer()
-	mangaServer := grpc.NewServer(database.DB)
-	pb.RegisterMangaServiceServer(s, mangaServer)
-	reflection.Register(s)
+	jwtSecret, err := config.LoadJWTSecret()
+	if err != nil {
+		log.Fatalf("failed to load JWT secret: %v", err)
+	}
+
+	server := grpc.NewStandalone(database.DB, jwtSecret)
+	server.Start()
+	defer server.Stop()
 
 	log.Printf("gRPC server listening at %v", lis.Addr())
-	if err := s.Serve(lis); err != nil {
+	if err := server.GRPC.Serve(lis); err != nil {
 		log.Fatalf("failed to serve: %v", err)
 	}
 }
@@ -7,6 +7,8 @@ import (
 	"syscall"
 
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/presence"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/ratelimit"
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/tcp"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
@@ -46,11 +48,27 @@ func main() {
 	localIP := utils.GetLocalIP()
 	log.Info("local_ip_detected", "ip", localIP)
 
+	eventLog := bridge.NewEventLog(database.DB, logger.WithContext("component", "event_log"), bridge.RetentionPolicyFromEnv())
+	eventLog.Start()
+	defer eventLog.Stop()
+
+	// The TCP bridge publishes through the unified one, so it keeps the event log
+	unifiedBridge := bridge.NewUnifiedBridge(logger.WithContext("component", "unified_bridge"))
+	unifiedBridge.SetEventLog(eventLog)
+	presenceService := presence.ForBridge(unifiedBridge)
+	presenceService.Start()
+	defer presenceService.Stop()
+	unifiedBridge.Start()
+	defer unifiedBridge.Stop()
+
 	tcpBridge := bridge.NewBridge(logger.WithContext("component", "bridge"))
+	tcpBridge.SetEventLog(eventLog)
+	tcpBridge.SetUnifiedBridge(unifiedBridge)
 	tcpBridge.Start()
 	defer tcpBridge.Stop()
 
 	server := tcp.NewServer(port, tcpBridge)
+	server.SetLimiter(ratelimit.New(ratelimit.ConfigFromEnv()))
 	if err := server.Start(); err != nil {
 		log.Error("failed_to_start_tcp_server", "error", err.Error())
 		os.Exit(1)
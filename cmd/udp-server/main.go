@@ -7,7 +7,12 @@ import (
 	"syscall"
 
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/presence"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/ratelimit"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/sse"
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/udp"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/config"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
 	"github.com/gin-contrib/cors"
@@ -28,6 +33,17 @@ func main() {
 	log := logger.GetLogger().WithContext("component", "udp_main")
 	log.Info("starting_udp_server", "version", "1.0.0")
 
+	dbPath := os.Getenv("DB_PATH")
+	if dbPath == "" {
+		dbPath = "./data/mangahub.db"
+	}
+
+	if err := database.InitDatabase(dbPath); err != nil {
+		log.Error("failed_to_initialize_database", "error", err.Error(), "path", dbPath)
+		os.Exit(1)
+	}
+	defer database.Close()
+
 	port := os.Getenv("UDP_PORT")
 	if port == "" {
 		port = "9091"
@@ -47,7 +63,24 @@ func main() {
 	udpBridge.Start()
 	defer udpBridge.Stop()
 
+	unifiedBridge := bridge.NewUnifiedBridge(logger.WithContext("component", "unified_bridge"))
+	presenceService := presence.ForBridge(unifiedBridge)
+	presenceService.Start()
+	defer presenceService.Stop()
+	unifiedBridge.Start()
+	defer unifiedBridge.Stop()
+
+	jwtSecret, err := config.LoadJWTSecret()
+	if err != nil {
+		log.Error("jwt_secret_missing", "error", err.Error())
+		os.Exit(1)
+	}
+
 	server := udp.NewServer(port)
+	server.SetBridge(unifiedBridge)
+	server.SetNotificationSecret(jwtSecret)
+	server.SetSSEBroker(sse.NewBroker(sse.Config{JWTSecret: jwtSecret}))
+	server.SetLimiter(ratelimit.New(ratelimit.ConfigFromEnv()))
 	if err := server.Start(); err != nil {
 		log.Error("failed_to_start_udp_server",
 			"error", err.Error(),
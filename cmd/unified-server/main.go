@@ -11,13 +11,23 @@ import (
 
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/auth"
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/chapter"
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/grpc"
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/health"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/lists"
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/manga"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/presence"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/ratelimit"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/recommend"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/review"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/sse"
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/tcp"
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/udp"
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/user"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/webhook"
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/websocket"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/config"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/metrics"
@@ -31,9 +41,14 @@ type ServerOrchestrator struct {
 	logger       *logger.Logger
 	bridge       *bridge.UnifiedBridge
 	oldBridge    *bridge.Bridge
+	eventLog     *bridge.EventLog
+	webhooks     *webhook.Dispatcher
+	recommender  *recommend.Engine
+	presence     *presence.Service
 	tcpServer    *tcp.Server
 	udpServer    *udp.Server
 	wsServer     *websocket.Server
+	sseBroker    *sse.Broker
 	grpcServer   *grpc.Server
 	grpcListener *grpc_server.Server
 	httpRouter   *gin.Engine
@@ -61,14 +76,32 @@ type Config struct {
 func NewServerOrchestrator(db *sql.DB, cfg *Config) *ServerOrchestrator {
 	log := logger.GetLogger()
 
+	eventLog := bridge.NewEventLog(db, log.WithContext("component", "event_log"), bridge.RetentionPolicyFromEnv())
 	unifiedBridge := bridge.NewUnifiedBridge(log)
+	unifiedBridge.SetEventLog(eventLog)
+
+	webhooks := webhook.NewDispatcher(webhook.NewStore(db), log.WithContext("component", "webhooks"), webhook.DefaultDispatcherConfig())
+	unifiedBridge.Subscribe(webhooks.HandleEvent)
+
+	recommender := recommend.NewEngine(repository.NewSQLiteStore(db), log.WithContext("component", "recommendations"), recommend.ConfigFromEnv())
+
+	// Every protocol reports its connections to the bridge, which passes
+	// them on to the presence service
+	presenceService := presence.NewService(repository.NewSQLiteStore(db), log.WithContext("component", "presence"), presence.ConfigFromEnv())
+	presenceService.SetBridge(unifiedBridge)
+	unifiedBridge.SetPresenceTracker(presenceService)
 
 	return &ServerOrchestrator{
-		logger:   log,
-		bridge:   unifiedBridge,
-		db:       db,
-		config:   cfg,
-		stopChan: make(chan os.Signal, 1),
+		logger:      log,
+		bridge:      unifiedBridge,
+		eventLog:    eventLog,
+		webhooks:    webhooks,
+		recommender: recommender,
+		presence:    presenceService,
+		db:          db,
+		config:      cfg,
+		stopChan:    make(chan os.Signal, 1),
+		sseBroker:   sse.NewBroker(sse.Config{JWTSecret: cfg.JWTSecret}),
 	}
 }
 
@@ -81,12 +114,23 @@ func (o *ServerOrchestrator) initializeServers() error {
 
 		// Create old bridge wrapper for compatibility with existing user handler
 		o.oldBridge = bridge.NewBridge(o.logger)
+		o.oldBridge.SetUnifiedBridge(o.bridge)
 		o.oldBridge.Start()
 
 		// Initialize handlers
 		authHandler := auth.NewHandler(o.config.JWTSecret)
 		mangaHandler := manga.NewHandler()
+		mangaHandler.SetBroker(o.sseBroker)
+		mangaHandler.SetBridge(o.bridge)
+		webhookHandler := webhook.NewHandler(webhook.NewStore(o.db))
+		reviewHandler := review.NewHandler(review.NewStore(o.db))
+		listHandler := lists.NewHandler(lists.NewStore(o.db))
+		inboxHandler := websocket.NewInboxHandler(o.db)
+		chapterHandler := chapter.NewHandler(chapter.NewCatalog(chapter.NewStore(o.db)))
+		recommendHandler := recommend.NewHandler(o.recommender)
+		presenceHandler := presence.NewHandler(o.presence, repository.NewSQLiteStore(o.db))
 		userHandler := user.NewHandler(o.oldBridge)
+		chapterHandler.SetProgressSaver(userHandler)
 		healthHandler := health.NewHandler(o.oldBridge)
 		metricsHandler := metrics.NewHandler()
 
@@ -97,10 +141,12 @@ func (o *ServerOrchestrator) initializeServers() error {
 		config := cors.DefaultConfig()
 		config.AllowOrigins = []string{o.config.FrontendURL}
 		config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-		config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
-		config.ExposeHeaders = []string{"Content-Length"}
+		config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID"}
+		config.ExposeHeaders = []string{"Content-Length", "X-Request-ID"}
 		config.AllowCredentials = true
 		router.Use(cors.New(config))
+		router.Use(logger.GinMiddleware())
+		router.Use(metrics.GinMiddleware())
 
 		// Health routes
 		router.GET("/health", func(c *gin.Context) {
@@ -108,7 +154,9 @@ func (o *ServerOrchestrator) initializeServers() error {
 		})
 		router.GET("/healthz", healthHandler.Healthz)
 		router.GET("/readyz", healthHandler.Readyz)
-		router.GET("/metrics", metricsHandler.Metrics)
+		router.GET("/metrics", metricsHandler.Prometheus)
+		router.GET("/metrics/json", metricsHandler.Metrics)
+		router.GET("/events", o.sseBroker.ServeSSE)
 
 		// Auth routes
 		authGroup := router.Group("/auth")
@@ -144,11 +192,31 @@ func (o *ServerOrchestrator) initializeServers() error {
 			mangaGroup.GET("/chapters/:mangadexId", mangaHandler.GetChapters)
 			mangaGroup.GET("/chapter/:chapterId/pages", mangaHandler.GetChapterPages)
 			mangaGroup.GET("/:id", mangaHandler.GetMangaByID)
+			mangaGroup.GET("/:id/reviews", reviewHandler.ListReviews)
+			mangaGroup.GET("/:id/chapters", chapterHandler.ListChapters)
+			mangaGroup.GET("/:id/reviews/:review_id/history", reviewHandler.GetReviewHistory)
+			mangaGroup.GET("/:id/chapters/:chapter/comments", reviewHandler.ListComments)
 
 			protected := mangaGroup.Group("")
 			protected.Use(auth.AuthMiddleware(o.config.JWTSecret))
 			{
 				protected.POST("", mangaHandler.CreateManga)
+				protected.POST("/:id/reviews", reviewHandler.CreateReview)
+				protected.PUT("/:id/reviews/:review_id", reviewHandler.UpdateReview)
+				protected.DELETE("/:id/reviews/:review_id", reviewHandler.DeleteReview)
+				protected.POST("/:id/reviews/:review_id/helpful", reviewHandler.MarkHelpful)
+				protected.DELETE("/:id/reviews/:review_id/helpful", reviewHandler.UnmarkHelpful)
+				protected.POST("/:id/chapters/:chapter/comments", reviewHandler.CreateComment)
+				protected.PUT("/:id/chapters/:chapter/comments/:comment_id", reviewHandler.UpdateComment)
+				protected.DELETE("/:id/chapters/:chapter/comments/:comment_id", reviewHandler.DeleteComment)
+			}
+
+			admin := mangaGroup.Group("")
+			admin.Use(auth.AuthMiddleware(o.config.JWTSecret))
+			admin.Use(auth.AdminMiddleware())
+			{
+				admin.PUT("/:id/reviews/:review_id/moderation", reviewHandler.ModerateReview)
+				admin.PUT("/:id/chapters/:chapter/comments/:comment_id/moderation", reviewHandler.ModerateComment)
 			}
 		}
 
@@ -165,11 +233,64 @@ func (o *ServerOrchestrator) initializeServers() error {
 			userGroup.GET("/progress/:manga_id", userHandler.GetProgress)
 			userGroup.PUT("/progress", userHandler.UpdateProgress)
 			userGroup.DELETE("/library/:manga_id", userHandler.RemoveFromLibrary)
+			userGroup.GET("/recommendations", recommendHandler.GetRecommendations)
+			userGroup.GET("/stats", userHandler.GetStats)
+			userGroup.PUT("/library/:manga_id/favorite", userHandler.SetFavorite)
+			userGroup.PUT("/library/:manga_id/tags", userHandler.SetTags)
+			userGroup.GET("/lists", listHandler.GetLists)
+			userGroup.POST("/lists", listHandler.CreateList)
+			userGroup.GET("/lists/:list_id", listHandler.GetList)
+			userGroup.PUT("/lists/:list_id", listHandler.UpdateList)
+			userGroup.DELETE("/lists/:list_id", listHandler.DeleteList)
+			userGroup.POST("/lists/:list_id/items", listHandler.AddItem)
+			userGroup.PUT("/lists/:list_id/items/:manga_id", listHandler.MoveItem)
+			userGroup.DELETE("/lists/:list_id/items/:manga_id", listHandler.RemoveItem)
+			userGroup.GET("/chapters/:manga_id", chapterHandler.GetReadState)
+			userGroup.POST("/chapters/:manga_id/read", chapterHandler.MarkRead)
+			userGroup.DELETE("/chapters/:manga_id/read/:chapter_id", chapterHandler.UnmarkRead)
+			userGroup.GET("/dms", inboxHandler.ListConversations)
+			userGroup.GET("/dms/:user", inboxHandler.GetConversation)
+			userGroup.GET("/conversations", inboxHandler.ListMemberConversations)
+			userGroup.GET("/mentions", inboxHandler.ListMentions)
+		}
+
+		// Chat history and search (protected)
+		conversationGroup := router.Group("/conversations")
+		conversationGroup.Use(func(c *gin.Context) {
+			c.Set("authHandler", authHandler)
+			auth.AuthMiddleware(o.config.JWTSecret)(c)
+		})
+		{
+			conversationGroup.GET("/search", inboxHandler.SearchMessages)
+			conversationGroup.GET("/:id/messages", inboxHandler.ListMessages)
+		}
+
+		router.GET("/presence", func(c *gin.Context) {
+			c.Set("authHandler", authHandler)
+			auth.AuthMiddleware(o.config.JWTSecret)(c)
+		}, presenceHandler.GetPresence)
+
+		// Webhook routes (all protected)
+		webhookGroup := router.Group("/webhooks")
+		webhookGroup.Use(func(c *gin.Context) {
+			c.Set("authHandler", authHandler)
+			auth.AuthMiddleware(o.config.JWTSecret)(c)
+		})
+		{
+			webhookGroup.POST("", webhookHandler.CreateWebhook)
+			webhookGroup.GET("", webhookHandler.ListWebhooks)
+			webhookGroup.DELETE("/:id", webhookHandler.DeleteWebhook)
+			webhookGroup.GET("/:id/deliveries", webhookHandler.GetDeliveries)
+			webhookGroup.GET("/:id/dead-letters", webhookHandler.GetDeadLetters)
+			webhookGroup.GET("/dead-letters", auth.AdminMiddleware(), webhookHandler.ListAllDeadLetters)
 		}
 
 		o.httpRouter = router
 	}
 
+	// One limiter for every chat protocol, so its limits are configured once
+	limiter := ratelimit.New(ratelimit.ConfigFromEnv())
+
 	if o.config.EnableTCP {
 		o.logger.Info("initializing_tcp_server", "port", o.config.TCPPort)
 
@@ -177,23 +298,32 @@ func (o *ServerOrchestrator) initializeServers() error {
 		// If API didn't initialize oldBridge, create and start it here.
 		if o.oldBridge == nil {
 			o.oldBridge = bridge.NewBridge(o.logger)
+			o.oldBridge.SetUnifiedBridge(o.bridge)
 			o.oldBridge.Start()
 		}
 
 		// Wire TCP server to the same bridge used by HTTP handlers
 		o.tcpServer = tcp.NewServer(o.config.TCPPort, o.oldBridge)
+		o.tcpServer.SetLimiter(limiter)
+		// Chat alerts start on the unified bridge; pass them on to TCP clients
+		o.bridge.Subscribe(o.oldBridge.ForwardChatAlert)
 	}
 
 	if o.config.EnableUDP {
 		o.logger.Info("initializing_udp_server", "port", o.config.UDPPort)
 		o.udpServer = udp.NewServer(o.config.UDPPort)
+		o.udpServer.SetSSEBroker(o.sseBroker)
 		o.udpServer.SetBridge(o.bridge)
+		o.udpServer.SetNotificationSecret(o.config.JWTSecret)
+		o.udpServer.SetLimiter(limiter)
 	}
 
 	if o.config.EnableWS {
 		o.logger.Info("initializing_websocket_server", "port", o.config.WebSocketPort)
-		o.wsServer = websocket.NewServer(o.db, o.config.JWTSecret)
+		o.wsServer = websocket.NewServerWithBackplane(o.db, o.config.JWTSecret, websocket.BackplaneFromEnv(o.db))
 		o.wsServer.SetBridge(o.bridge)
+		o.wsServer.SetPresence(o.presence)
+		o.wsServer.SetLimiter(limiter)
 	}
 
 	if o.config.EnableGRPC {
@@ -211,6 +341,10 @@ func (o *ServerOrchestrator) Start() error {
 		return fmt.Errorf("failed to initialize servers: %w", err)
 	}
 
+	o.eventLog.Start()
+	o.webhooks.Start()
+	o.recommender.Start()
+	o.presence.Start()
 	o.bridge.Start()
 	o.logger.Info("unified_bridge_started")
 
@@ -315,6 +449,11 @@ func (o *ServerOrchestrator) Shutdown() {
 			o.udpServer.Stop()
 		}
 
+		if o.wsServer != nil {
+			o.logger.Info("leaving_chat_backplane")
+			o.wsServer.Close()
+		}
+
 		if o.grpcListener != nil {
 			o.logger.Info("stopping_grpc_server")
 			o.grpcListener.GracefulStop()
@@ -325,6 +464,23 @@ func (o *ServerOrchestrator) Shutdown() {
 			o.bridge.Stop()
 		}
 
+		if o.webhooks != nil {
+			o.logger.Info("stopping_webhook_dispatcher")
+			o.webhooks.Stop()
+		}
+
+		if o.recommender != nil {
+			o.recommender.Stop()
+		}
+
+		if o.presence != nil {
+			o.presence.Stop()
+		}
+
+		if o.eventLog != nil {
+			o.eventLog.Stop()
+		}
+
 		close(done)
 	}()
 
@@ -353,10 +509,10 @@ func main() {
 	}
 	defer database.Close()
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "your-secret-key-change-this-in-production"
-		log.Warn("using_default_jwt_secret")
+	jwtSecret, err := config.LoadJWTSecret()
+	if err != nil {
+		log.Error("jwt_secret_missing", "error", err.Error())
+		os.Exit(1)
 	}
 
 	config := &Config{
@@ -5,6 +5,9 @@ import (
 	"log"
 	"os"
 
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/presence"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/ratelimit"
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/websocket"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/config"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
@@ -40,7 +43,21 @@ func main() {
 		log.Fatal("JWT_SECRET not set")
 	}
 
-	wsServer := websocket.NewServer(database.DB, jwtSecret)
+	// CHAT_BACKPLANE=sqlite lets several chat servers share one database
+	backplane := websocket.BackplaneFromEnv(database.DB)
+	defer backplane.Close()
+	wsServer := websocket.NewServerWithBackplane(database.DB, jwtSecret, backplane)
+	defer wsServer.Close()
+	wsServer.SetLimiter(ratelimit.New(ratelimit.ConfigFromEnv()))
+
+	unifiedBridge := bridge.NewUnifiedBridge(logger.WithContext("component", "unified_bridge"))
+	presenceService := presence.ForBridge(unifiedBridge)
+	presenceService.Start()
+	defer presenceService.Stop()
+	unifiedBridge.Start()
+	defer unifiedBridge.Stop()
+	wsServer.SetBridge(unifiedBridge)
+	wsServer.SetPresence(presenceService)
 
 	port := os.Getenv("WEBSOCKET_PORT")
 	if port == "" {
@@ -1,7 +1,7 @@
 package auth
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -9,7 +9,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
 	"github.com/gin-gonic/gin"
@@ -79,15 +79,6 @@ func (h *Handler) Register(c *gin.Context) {
 		return
 	}
 
-	userID, err := utils.GenerateID(16)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Server error",
-			"details": "Failed to generate user ID",
-		})
-		return
-	}
-
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -97,19 +88,18 @@ func (h *Handler) Register(c *gin.Context) {
 		return
 	}
 
-	query := `INSERT INTO users (id, username, email, password_hash) VALUES (?, ?, ?, ?)`
-	_, err = database.DB.Exec(query, userID, req.Username, req.Email, hashedPassword)
-	if err != nil {
+	user := &models.User{Username: req.Username, Email: req.Email, PasswordHash: hashedPassword}
+	if err := repository.Default().Users.Create(c.Request.Context(), user); err != nil {
 		// Log full DB error to help debugging unique constraint or schema issues
 		log.Printf("Insert user error: %v", err)
-		if strings.Contains(err.Error(), "UNIQUE constraint failed: users.username") {
+		if errors.Is(err, repository.ErrUsernameTaken) {
 			c.JSON(http.StatusConflict, gin.H{
 				"error":   "Username already exists",
 				"details": "This username is already taken. Please choose another one",
 			})
 			return
 		}
-		if strings.Contains(err.Error(), "UNIQUE constraint failed: users.email") {
+		if errors.Is(err, repository.ErrEmailTaken) {
 			c.JSON(http.StatusConflict, gin.H{
 				"error":   "Email already registered",
 				"details": "This email is already associated with an account",
@@ -123,11 +113,7 @@ func (h *Handler) Register(c *gin.Context) {
 		return
 	}
 
-	var createdAt time.Time
-	var role string
-	_ = database.DB.QueryRow(`SELECT created_at, role FROM users WHERE id = ?`, userID).Scan(&createdAt, &role)
-
-	token, err := utils.GenerateJWT(userID, req.Username, role, h.JWTSecret)
+	token, err := utils.GenerateJWT(user.ID, user.Username, user.Role, h.JWTSecret)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Server error",
@@ -138,11 +124,11 @@ func (h *Handler) Register(c *gin.Context) {
 
 	c.JSON(http.StatusCreated, models.AuthResponse{
 		Token:     token,
-		UserID:    userID,
-		Username:  req.Username,
-		Email:     req.Email,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
 		ExpiresAt: time.Now().Add(24 * time.Hour),
-		CreatedAt: createdAt,
+		CreatedAt: user.CreatedAt,
 	})
 }
 
@@ -173,17 +159,16 @@ func (h *Handler) Login(c *gin.Context) {
 	}
 
 	//Query user from database
-	var user models.User
+	users := repository.Default().Users
+	var user *models.User
 	var err error
 	if req.Username != "" {
-		err = database.DB.QueryRow(`SELECT id, username, email, password_hash, role, created_at FROM users WHERE username = ?`, req.Username).
-			Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Role, &user.CreatedAt)
+		user, err = users.GetByUsername(c.Request.Context(), req.Username)
 	} else {
-		err = database.DB.QueryRow(`SELECT id, username, email, password_hash, role, created_at FROM users WHERE email = ?`, req.Email).
-			Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Role, &user.CreatedAt)
+		user, err = users.GetByEmail(c.Request.Context(), req.Email)
 	}
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, repository.ErrNotFound) {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error":   "Authentication failed",
 				"details": "Username or email not found",
@@ -272,9 +257,10 @@ func (h *Handler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	var hash string
-	if err := database.DB.QueryRow(`SELECT password_hash FROM users WHERE id = ?`, userID).Scan(&hash); err != nil {
-		if err == sql.ErrNoRows {
+	users := repository.Default().Users
+	user, err := users.GetByID(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "Account not found",
 				"details": "User account does not exist",
@@ -288,7 +274,7 @@ func (h *Handler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	if err := utils.CheckPassword(hash, req.CurrentPassword); err != nil {
+	if err := utils.CheckPassword(user.PasswordHash, req.CurrentPassword); err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":   "Change password failed",
 			"details": "Current password is incorrect",
@@ -305,7 +291,7 @@ func (h *Handler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	if _, err := database.DB.Exec(`UPDATE users SET password_hash = ? WHERE id = ?`, newHash, userID); err != nil {
+	if err := users.UpdatePassword(c.Request.Context(), userID, newHash); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Server error",
 			"details": "Failed to update password in database",
@@ -354,9 +340,10 @@ func (h *Handler) UpdateEmail(c *gin.Context) {
 	}
 
 	// Get current user info
-	var currentEmail string
-	if err := database.DB.QueryRow(`SELECT email FROM users WHERE id = ?`, userID).Scan(&currentEmail); err != nil {
-		if err == sql.ErrNoRows {
+	users := repository.Default().Users
+	user, err := users.GetByID(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "Account not found",
 				"details": "User account does not exist",
@@ -371,7 +358,7 @@ func (h *Handler) UpdateEmail(c *gin.Context) {
 	}
 
 	// Check if new email is same as current
-	if req.NewEmail == currentEmail {
+	if req.NewEmail == user.Email {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Email unchanged",
 			"details": "New email is the same as current email",
@@ -379,26 +366,15 @@ func (h *Handler) UpdateEmail(c *gin.Context) {
 		return
 	}
 
-	// Check if new email already exists
-	var existingEmail string
-	err := database.DB.QueryRow(`SELECT email FROM users WHERE email = ? AND id != ?`, req.NewEmail, userID).Scan(&existingEmail)
-	if err == nil {
-		c.JSON(http.StatusConflict, gin.H{
-			"error":   "Email already registered",
-			"details": "This email is already associated with another account",
-		})
-		return
-	}
-	if err != sql.ErrNoRows {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Server error",
-			"details": "Database error occurred",
-		})
-		return
-	}
-
-	// Update email
-	if _, err := database.DB.Exec(`UPDATE users SET email = ? WHERE id = ?`, req.NewEmail, userID); err != nil {
+	// Update email, rejecting one that belongs to another account
+	if err := users.UpdateEmail(c.Request.Context(), userID, req.NewEmail); err != nil {
+		if errors.Is(err, repository.ErrEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Email already registered",
+				"details": "This email is already associated with another account",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Server error",
 			"details": "Failed to update email in database",
@@ -451,9 +427,10 @@ func (h *Handler) UpdateUsername(c *gin.Context) {
 	}
 
 	// Get current user info
-	var currentUsername string
-	if err := database.DB.QueryRow(`SELECT username FROM users WHERE id = ?`, userID).Scan(&currentUsername); err != nil {
-		if err == sql.ErrNoRows {
+	users := repository.Default().Users
+	user, err := users.GetByID(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "Account not found",
 				"details": "User account does not exist",
@@ -468,7 +445,7 @@ func (h *Handler) UpdateUsername(c *gin.Context) {
 	}
 
 	// Check if new username is same as current
-	if req.NewUsername == currentUsername {
+	if req.NewUsername == user.Username {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Username unchanged",
 			"details": "New username is the same as current username",
@@ -476,26 +453,15 @@ func (h *Handler) UpdateUsername(c *gin.Context) {
 		return
 	}
 
-	// Check if new username already exists
-	var existingUsername string
-	err := database.DB.QueryRow(`SELECT username FROM users WHERE username = ? AND id != ?`, req.NewUsername, userID).Scan(&existingUsername)
-	if err == nil {
-		c.JSON(http.StatusConflict, gin.H{
-			"error":   "Username already exists",
-			"details": "This username is already taken. Please choose another one",
-		})
-		return
-	}
-	if err != sql.ErrNoRows {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Server error",
-			"details": "Database error occurred",
-		})
-		return
-	}
-
-	// Update username
-	if _, err := database.DB.Exec(`UPDATE users SET username = ? WHERE id = ?`, req.NewUsername, userID); err != nil {
+	// Update username, rejecting one that belongs to another account
+	if err := users.UpdateUsername(c.Request.Context(), userID, req.NewUsername); err != nil {
+		if errors.Is(err, repository.ErrUsernameTaken) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Username already exists",
+				"details": "This username is already taken. Please choose another one",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Server error",
 			"details": "Failed to update username in database",
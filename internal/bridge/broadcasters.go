@@ -20,6 +20,15 @@ type UDPBroadcaster interface {
 	GetSubscriberCount(userID string) int
 }
 
+// PresenceTracker hears about every connection a user opens or closes and
+// everything they do over it, whichever protocol carries it. connID only
+// has to be unique among the user's open connections.
+type PresenceTracker interface {
+	Connected(userID, connID string, protocol ProtocolType)
+	Disconnected(userID, connID string)
+	Active(userID string, protocol ProtocolType)
+}
+
 type EventMetadata struct {
 	RequestID   string    `json:"request_id,omitempty"`
 	Priority    int       `json:"priority"`
@@ -47,10 +56,15 @@ const (
 	EventHealthCheck        EventType = "health_check"
 	EventMetricsUpdate      EventType = "metrics_update"
 	EventChapterRelease     EventType = "chapter_release"
+	EventMangaCreated       EventType = "manga_created"
+	EventChatMention        EventType = "chat_mention"
+	EventChatKeyword        EventType = "chat_keyword"
+	EventPresenceUpdate     EventType = "presence_update"
 )
 
 type UnifiedEvent struct {
 	ID          string                 `json:"id"`
+	Offset      int64                  `json:"offset,omitempty"`
 	Type        EventType              `json:"type"`
 	UserID      string                 `json:"user_id"`
 	SourceProto ProtocolType           `json:"source_protocol"`
@@ -0,0 +1,218 @@
+package bridge
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/config"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+)
+
+var ErrEventLogDisabled = errors.New("event log is not configured")
+
+const (
+	DefaultEventLogMaxAge     = 7 * 24 * time.Hour
+	DefaultEventLogMaxPerUser = 1000
+	DefaultReplayLimit        = 500
+	eventLogPruneInterval     = 10 * time.Minute
+)
+
+// RetentionPolicy controls how long events stay in the log. Events are
+// removed once they are older than MaxAge or once a user has more than
+// MaxPerUser newer events. A zero value disables that rule.
+type RetentionPolicy struct {
+	MaxAge     time.Duration
+	MaxPerUser int
+}
+
+func DefaultRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{
+		MaxAge:     DefaultEventLogMaxAge,
+		MaxPerUser: DefaultEventLogMaxPerUser,
+	}
+}
+
+// RetentionPolicyFromEnv reads EVENT_LOG_RETENTION_HOURS and
+// EVENT_LOG_MAX_PER_USER, falling back to the defaults.
+func RetentionPolicyFromEnv() RetentionPolicy {
+	defaults := DefaultRetentionPolicy()
+	return RetentionPolicy{
+		MaxAge:     time.Duration(config.GetEnvInt("EVENT_LOG_RETENTION_HOURS", int(defaults.MaxAge/time.Hour))) * time.Hour,
+		MaxPerUser: config.GetEnvInt("EVENT_LOG_MAX_PER_USER", defaults.MaxPerUser),
+	}
+}
+
+// EventLog is an append-only, SQLite-backed log of unified events. Every
+// appended event receives a monotonically increasing offset that clients
+// can use to resume after a disconnect.
+type EventLog struct {
+	db        *sql.DB
+	logger    *logger.Logger
+	retention RetentionPolicy
+	stopChan  chan struct{}
+	stopOnce  sync.Once
+}
+
+func NewEventLog(db *sql.DB, log *logger.Logger, retention RetentionPolicy) *EventLog {
+	return &EventLog{
+		db:        db,
+		logger:    log,
+		retention: retention,
+		stopChan:  make(chan struct{}),
+	}
+}
+
+func (el *EventLog) Start() {
+	el.logger.Info("event_log_started",
+		"max_age", el.retention.MaxAge.String(),
+		"max_per_user", el.retention.MaxPerUser)
+	go el.pruneLoop()
+}
+
+func (el *EventLog) Stop() {
+	el.stopOnce.Do(func() {
+		close(el.stopChan)
+	})
+}
+
+// Append persists the event and returns the offset assigned to it.
+func (el *EventLog) Append(event UnifiedEvent) (int64, error) {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal event data: %w", err)
+	}
+	metadata, err := json.Marshal(event.Metadata)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal event metadata: %w", err)
+	}
+
+	ts := event.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	res, err := el.db.Exec(`INSERT INTO event_log (event_id, user_id, type, source_protocol, data, metadata, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		event.ID, event.UserID, string(event.Type), string(event.SourceProto), string(data), string(metadata), ts.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("failed to append event: %w", err)
+	}
+
+	offset, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read event offset: %w", err)
+	}
+	return offset, nil
+}
+
+// EventsAfter returns the events for userID with an offset greater than
+// afterOffset, oldest first. Broadcast events (empty user ID) are included.
+func (el *EventLog) EventsAfter(userID string, afterOffset int64, limit int) ([]UnifiedEvent, error) {
+	if limit <= 0 || limit > DefaultReplayLimit {
+		limit = DefaultReplayLimit
+	}
+
+	rows, err := el.db.Query(`SELECT seq, event_id, user_id, type, source_protocol, data, metadata, created_at
+		FROM event_log
+		WHERE seq > ? AND (user_id = ? OR user_id = '')
+		ORDER BY seq ASC
+		LIMIT ?`, afterOffset, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query event log: %w", err)
+	}
+	defer rows.Close()
+
+	events := []UnifiedEvent{}
+	for rows.Next() {
+		var (
+			event             UnifiedEvent
+			eventType, source string
+			data, metadata    sql.NullString
+			createdAt         int64
+		)
+		if err := rows.Scan(&event.Offset, &event.ID, &event.UserID, &eventType, &source, &data, &metadata, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		event.Type = EventType(eventType)
+		event.SourceProto = ProtocolType(source)
+		event.Timestamp = time.UnixMilli(createdAt)
+		if data.Valid && data.String != "" {
+			if err := json.Unmarshal([]byte(data.String), &event.Data); err != nil {
+				el.logger.Warn("event_log_bad_data", "offset", event.Offset, "error", err.Error())
+			}
+		}
+		if metadata.Valid && metadata.String != "" {
+			if err := json.Unmarshal([]byte(metadata.String), &event.Metadata); err != nil {
+				el.logger.Warn("event_log_bad_metadata", "offset", event.Offset, "error", err.Error())
+			}
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// LatestOffset returns the highest offset in the log, or 0 when it is empty.
+func (el *EventLog) LatestOffset() (int64, error) {
+	var offset sql.NullInt64
+	if err := el.db.QueryRow(`SELECT MAX(seq) FROM event_log`).Scan(&offset); err != nil {
+		return 0, fmt.Errorf("failed to read latest offset: %w", err)
+	}
+	return offset.Int64, nil
+}
+
+// Prune applies the retention policy and returns the number of removed events.
+func (el *EventLog) Prune() (int64, error) {
+	var removed int64
+
+	if el.retention.MaxAge > 0 {
+		cutoff := time.Now().Add(-el.retention.MaxAge).UnixMilli()
+		res, err := el.db.Exec(`DELETE FROM event_log WHERE created_at < ?`, cutoff)
+		if err != nil {
+			return removed, fmt.Errorf("failed to prune expired events: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		removed += n
+	}
+
+	if el.retention.MaxPerUser > 0 {
+		res, err := el.db.Exec(`DELETE FROM event_log WHERE seq IN (
+			SELECT seq FROM (
+				SELECT seq, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY seq DESC) AS rn
+				FROM event_log
+			) WHERE rn > ?
+		)`, el.retention.MaxPerUser)
+		if err != nil {
+			return removed, fmt.Errorf("failed to prune excess events: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		removed += n
+	}
+
+	return removed, nil
+}
+
+func (el *EventLog) pruneLoop() {
+	ticker := time.NewTicker(eventLogPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			removed, err := el.Prune()
+			if err != nil {
+				el.logger.Warn("event_log_prune_failed", "error", err.Error())
+				continue
+			}
+			if removed > 0 {
+				el.logger.Debug("event_log_pruned", "removed", removed)
+			}
+		case <-el.stopChan:
+			el.logger.Info("event_log_stopped")
+			return
+		}
+	}
+}
@@ -13,6 +13,7 @@ const (
 type Event struct {
 	Type      EventType              `json:"type"`
 	UserID    string                 `json:"user_id"`
+	Offset    int64                  `json:"offset,omitempty"` // position in the event log, for resume
 	Data      map[string]interface{} `json:"data"`
 	Timestamp time.Time              `json:"timestamp"`
 }
@@ -24,10 +25,12 @@ type ProgressUpdateEvent struct {
 	ChapterID    int       `json:"chapter_id"`
 	Status       string    `json:"status"`
 	LastReadDate time.Time `json:"last_read_date"`
+	RequestID    string    `json:"request_id,omitempty"`
 }
 
 type LibraryUpdateEvent struct {
-	UserID  string `json:"user_id"`
-	MangaID string `json:"manga_id"`
-	Action  string `json:"action"`
+	UserID    string `json:"user_id"`
+	MangaID   string `json:"manga_id"`
+	Action    string `json:"action"`
+	RequestID string `json:"request_id,omitempty"`
 }
@@ -22,6 +22,7 @@ type OldUDPBroadcaster interface {
 type BroadcastEvent struct {
 	UserID    string
 	EventType string
+	Offset    int64
 	Data      interface{}
 }
 
@@ -30,6 +31,8 @@ type Bridge struct {
 	clients        map[string][]*TCPClient
 	udpBroadcaster OldUDPBroadcaster
 	sessionManager SessionManager
+	eventLog       *EventLog
+	unified        *UnifiedBridge
 	clientsLock    sync.RWMutex
 	eventChan      chan Event
 	stopChan       chan struct{}
@@ -81,9 +84,64 @@ func (b *Bridge) SetSessionManager(sm SessionManager) {
 	b.logger.Info("session_manager_set")
 }
 
-func (b *Bridge) RegisterTCPClient(conn net.Conn, userID string) {
+func (b *Bridge) SetEventLog(el *EventLog) {
+	b.clientsLock.Lock()
+	defer b.clientsLock.Unlock()
+	b.eventLog = el
+	b.logger.Info("event_log_set")
+}
+
+// SetUnifiedBridge publishes progress and library notifications to the
+// unified bridge subscribers. Events are then logged by the unified bridge.
+func (b *Bridge) SetUnifiedBridge(ub *UnifiedBridge) {
 	b.clientsLock.Lock()
 	defer b.clientsLock.Unlock()
+	b.unified = ub
+	b.logger.Info("unified_bridge_set")
+}
+
+// ReplayEvents returns the logged events for userID after the given offset.
+func (b *Bridge) ReplayEvents(userID string, afterOffset int64, limit int) ([]UnifiedEvent, error) {
+	b.clientsLock.RLock()
+	el := b.eventLog
+	unified := b.unified
+	b.clientsLock.RUnlock()
+
+	if el == nil && unified != nil {
+		return unified.ReplayEvents(userID, afterOffset, limit)
+	}
+	if el == nil {
+		return nil, ErrEventLogDisabled
+	}
+	return el.EventsAfter(userID, afterOffset, limit)
+}
+
+// recordEvent logs the event and returns its offset, or 0 when it was not logged
+func (b *Bridge) recordEvent(eventType EventType, userID, requestID string, data map[string]interface{}) int64 {
+	b.clientsLock.RLock()
+	el := b.eventLog
+	unified := b.unified
+	b.clientsLock.RUnlock()
+
+	event := NewUnifiedEvent(eventType, userID, ProtocolHTTP, data)
+	event.Metadata.RequestID = requestID
+	if unified != nil {
+		return unified.PublishEvent(event)
+	}
+	if el == nil {
+		return 0
+	}
+
+	offset, err := el.Append(event)
+	if err != nil {
+		b.logger.WithRequestID(requestID).Error("event_log_append_failed", "type", eventType, "user_id", userID, "error", err.Error())
+		return 0
+	}
+	return offset
+}
+
+func (b *Bridge) RegisterTCPClient(conn net.Conn, userID string) {
+	b.clientsLock.Lock()
 
 	client := &TCPClient{
 		Conn:   conn,
@@ -96,11 +154,16 @@ func (b *Bridge) RegisterTCPClient(conn net.Conn, userID string) {
 		"client_addr", conn.RemoteAddr().String(),
 		"total_clients", len(b.clients[userID]),
 	)
+	unified := b.unified
+	b.clientsLock.Unlock()
+
+	if unified != nil {
+		unified.MarkConnected(userID, tcpConnID(conn), ProtocolTCP)
+	}
 }
 
 func (b *Bridge) UnregisterTCPClient(conn net.Conn, userID string) {
 	b.clientsLock.Lock()
-	defer b.clientsLock.Unlock()
 
 	clients := b.clients[userID]
 	for i, client := range clients {
@@ -119,6 +182,28 @@ func (b *Bridge) UnregisterTCPClient(conn net.Conn, userID string) {
 		"client_addr", conn.RemoteAddr().String(),
 		"remaining_clients", len(b.clients[userID]),
 	)
+	unified := b.unified
+	b.clientsLock.Unlock()
+
+	if unified != nil {
+		unified.MarkDisconnected(userID, tcpConnID(conn))
+	}
+}
+
+// MarkActive records that a TCP client's user did something other than
+// keep the connection alive
+func (b *Bridge) MarkActive(userID string) {
+	b.clientsLock.RLock()
+	unified := b.unified
+	b.clientsLock.RUnlock()
+
+	if unified != nil {
+		unified.MarkActive(userID, ProtocolTCP)
+	}
+}
+
+func tcpConnID(conn net.Conn) string {
+	return "tcp/" + conn.RemoteAddr().String()
 }
 
 func (b *Bridge) NotifyProgressUpdate(event ProgressUpdateEvent) {
@@ -129,24 +214,29 @@ func (b *Bridge) NotifyProgressUpdate(event ProgressUpdateEvent) {
 		"last_read_date": event.LastReadDate,
 	}
 
+	offset := b.recordEvent(EventProgressUpdate, event.UserID, event.RequestID, data)
+
 	b.eventChan <- Event{
 		Type:      EventTypeProgressUpdate,
 		UserID:    event.UserID,
+		Offset:    offset,
 		Data:      data,
 		Timestamp: event.LastReadDate,
 	}
+	metrics.SetBridgeQueueDepth("tcp_http", len(b.eventChan))
 
-	b.logger.Debug("progress_update_queued",
+	b.logger.WithRequestID(event.RequestID).Debug("progress_update_queued",
 		"user_id", event.UserID,
 		"manga_id", event.MangaID,
 		"chapter_id", event.ChapterID,
 	)
 
-	b.broadcastUpdateEvent(event.UserID, "updated", event.MangaTitle, event.ChapterID, "outgoing")
+	b.broadcastUpdateEvent(event.UserID, "updated", event.MangaTitle, event.ChapterID, "outgoing", offset)
 
 	if b.udpBroadcaster != nil {
 		b.udpBroadcaster.BroadcastToUser(event.UserID, BroadcastEvent{
 			EventType: "progress_update",
+			Offset:    offset,
 			Data:      data,
 		})
 	}
@@ -158,29 +248,34 @@ func (b *Bridge) NotifyLibraryUpdate(event LibraryUpdateEvent) {
 		"action":   event.Action,
 	}
 
+	offset := b.recordEvent(EventLibraryUpdate, event.UserID, event.RequestID, data)
+
 	b.eventChan <- Event{
 		Type:   EventTypeLibraryUpdate,
 		UserID: event.UserID,
+		Offset: offset,
 		Data:   data,
 	}
+	metrics.SetBridgeQueueDepth("tcp_http", len(b.eventChan))
 
-	b.logger.Debug("library_update_queued",
+	b.logger.WithRequestID(event.RequestID).Debug("library_update_queued",
 		"user_id", event.UserID,
 		"manga_id", event.MangaID,
 		"action", event.Action,
 	)
 
-	b.broadcastUpdateEvent(event.UserID, event.Action, event.MangaID, 0, "outgoing")
+	b.broadcastUpdateEvent(event.UserID, event.Action, event.MangaID, 0, "outgoing", offset)
 
 	if b.udpBroadcaster != nil {
 		b.udpBroadcaster.BroadcastToUser(event.UserID, BroadcastEvent{
 			EventType: "library_update",
+			Offset:    offset,
 			Data:      data,
 		})
 	}
 }
 
-func (b *Bridge) broadcastUpdateEvent(userID, action, mangaTitle string, chapter int, direction string) {
+func (b *Bridge) broadcastUpdateEvent(userID, action, mangaTitle string, chapter int, direction string, offset int64) {
 	if b.sessionManager == nil {
 		return
 	}
@@ -213,17 +308,21 @@ func (b *Bridge) broadcastUpdateEvent(userID, action, mangaTitle string, chapter
 			continue
 		}
 
+		payload := map[string]interface{}{
+			"timestamp":   generateTimestamp(),
+			"direction":   direction,
+			"action":      action,
+			"manga_title": mangaTitle,
+			"chapter":     chapter,
+			"device_type": session.GetDeviceType(),
+			"device_name": session.GetDeviceName(),
+		}
+		if offset > 0 {
+			payload["offset"] = offset
+		}
 		updateEvent := map[string]interface{}{
-			"type": "update_event",
-			"payload": map[string]interface{}{
-				"timestamp":   generateTimestamp(),
-				"direction":   direction,
-				"action":      action,
-				"manga_title": mangaTitle,
-				"chapter":     chapter,
-				"device_type": session.GetDeviceType(),
-				"device_name": session.GetDeviceName(),
-			},
+			"type":    "update_event",
+			"payload": payload,
 		}
 
 		messageBytes, err := json.Marshal(updateEvent)
@@ -293,6 +392,48 @@ func (b *Bridge) BroadcastToUser(userID string, event Event) {
 	)
 }
 
+// ForwardChatAlert writes chat mentions, keyword alerts and contacts'
+// presence changes to the user's TCP clients, so `sync monitor` shows them
+// next to sync updates. It is meant to be subscribed to the unified bridge
+// and ignores every other event.
+func (b *Bridge) ForwardChatAlert(event UnifiedEvent) error {
+	if event.Type != EventChatMention && event.Type != EventChatKeyword && event.Type != EventPresenceUpdate {
+		return nil
+	}
+
+	b.clientsLock.RLock()
+	clients := b.clients[event.UserID]
+	b.clientsLock.RUnlock()
+	if len(clients) == 0 {
+		return nil
+	}
+
+	payload := map[string]interface{}{"timestamp": event.Timestamp.Format(time.RFC3339)}
+	for k, v := range event.Data {
+		payload[k] = v
+	}
+	if event.Offset > 0 {
+		payload["offset"] = event.Offset
+	}
+	messageBytes, err := json.Marshal(map[string]interface{}{"type": string(event.Type), "payload": payload})
+	if err != nil {
+		return err
+	}
+
+	message := append(messageBytes, '\n')
+	for _, client := range clients {
+		if _, err := client.Conn.Write(message); err != nil {
+			b.logger.Warn("failed_to_send_chat_alert",
+				"user_id", event.UserID,
+				"error", err.Error())
+			metrics.IncrementBroadcastFails()
+		} else {
+			metrics.IncrementBroadcasts()
+		}
+	}
+	return nil
+}
+
 func (b *Bridge) GetActiveUserCount() int {
 	b.clientsLock.RLock()
 	defer b.clientsLock.RUnlock()
@@ -314,6 +455,7 @@ func (b *Bridge) processEvents() {
 	for {
 		select {
 		case event := <-b.eventChan:
+			metrics.SetBridgeQueueDepth("tcp_http", len(b.eventChan))
 			b.BroadcastToUser(event.UserID, event)
 		case <-b.stopChan:
 			b.logger.Info("bridge_stopped")
@@ -1,6 +1,8 @@
 package bridge_test
 
 import (
+	"bufio"
+	"encoding/json"
 	"net"
 	"testing"
 	"time"
@@ -157,3 +159,39 @@ func TestBridgeNotifications(t *testing.T) {
 	br.NotifyLibraryUpdate(libraryEvent)
 	time.Sleep(100 * time.Millisecond)
 }
+
+func TestForwardChatAlert(t *testing.T) {
+	logger.Init(logger.INFO, false, nil)
+	br := bridge.NewBridge(logger.GetLogger())
+
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	br.RegisterTCPClient(server, "user1")
+
+	// Other events are left to the paths that already deliver them
+	if err := br.ForwardChatAlert(bridge.NewUnifiedEvent(bridge.EventProgressUpdate, "user1", bridge.ProtocolHTTP, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	event := bridge.NewUnifiedEvent(bridge.EventChatKeyword, "user1", bridge.ProtocolWebSocket, map[string]interface{}{
+		"room": "one-piece", "from": "bob", "content": "Luffy is back", "keyword": "luffy",
+	})
+	go br.ForwardChatAlert(event)
+
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := bufio.NewReader(client).ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("Failed to read alert: %v", err)
+	}
+	var msg struct {
+		Type    string                 `json:"type"`
+		Payload map[string]interface{} `json:"payload"`
+	}
+	if err := json.Unmarshal(line, &msg); err != nil {
+		t.Fatalf("Failed to decode %q: %v", line, err)
+	}
+	if msg.Type != "chat_keyword" || msg.Payload["keyword"] != "luffy" || msg.Payload["room"] != "one-piece" || msg.Payload["timestamp"] == nil {
+		t.Errorf("unexpected alert %s", line)
+	}
+}
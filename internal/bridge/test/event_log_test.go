@@ -0,0 +1,241 @@
+package bridge_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+)
+
+func setupEventLog(t *testing.T, retention bridge.RetentionPolicy) *bridge.EventLog {
+	if err := database.InitDatabase(t.TempDir() + "/events.db"); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	log := logger.New(logger.DEBUG, false, os.Stdout)
+	return bridge.NewEventLog(database.DB, log, retention)
+}
+
+func TestEventLogOffsetsIncrease(t *testing.T) {
+	el := setupEventLog(t, bridge.DefaultRetentionPolicy())
+
+	var last int64
+	for i := 0; i < 5; i++ {
+		event := bridge.NewUnifiedEvent(bridge.EventProgressUpdate, "user1", bridge.ProtocolTCP, map[string]interface{}{"chapter": i})
+		offset, err := el.Append(event)
+		if err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+		if offset <= last {
+			t.Fatalf("expected offset > %d, got %d", last, offset)
+		}
+		last = offset
+	}
+
+	latest, err := el.LatestOffset()
+	if err != nil {
+		t.Fatalf("latest offset failed: %v", err)
+	}
+	if latest != last {
+		t.Errorf("expected latest offset %d, got %d", last, latest)
+	}
+}
+
+func TestEventLogEventsAfter(t *testing.T) {
+	el := setupEventLog(t, bridge.DefaultRetentionPolicy())
+
+	first, _ := el.Append(bridge.NewUnifiedEvent(bridge.EventProgressUpdate, "user1", bridge.ProtocolTCP, map[string]interface{}{"chapter": 1}))
+	el.Append(bridge.NewUnifiedEvent(bridge.EventProgressUpdate, "user2", bridge.ProtocolTCP, map[string]interface{}{"chapter": 2}))
+	el.Append(bridge.NewUnifiedEvent(bridge.EventLibraryUpdate, "user1", bridge.ProtocolWebSocket, map[string]interface{}{"action": "added"}))
+	el.Append(bridge.NewUnifiedEvent(bridge.EventChapterRelease, "", bridge.ProtocolUDP, map[string]interface{}{"manga_id": "m1"}))
+
+	events, err := el.EventsAfter("user1", first, 0)
+	if err != nil {
+		t.Fatalf("events after failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].Type != bridge.EventLibraryUpdate || events[0].Data["action"] != "added" {
+		t.Errorf("unexpected first event: %+v", events[0])
+	}
+	if events[1].Type != bridge.EventChapterRelease {
+		t.Errorf("expected broadcast event to be replayed, got %s", events[1].Type)
+	}
+	if events[0].Offset >= events[1].Offset {
+		t.Errorf("expected events ordered by offset")
+	}
+}
+
+func TestEventLogPruneMaxPerUser(t *testing.T) {
+	el := setupEventLog(t, bridge.RetentionPolicy{MaxPerUser: 2})
+
+	for i := 0; i < 5; i++ {
+		el.Append(bridge.NewUnifiedEvent(bridge.EventProgressUpdate, "user1", bridge.ProtocolTCP, map[string]interface{}{"chapter": i}))
+	}
+
+	removed, err := el.Prune()
+	if err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	if removed != 3 {
+		t.Errorf("expected 3 events removed, got %d", removed)
+	}
+
+	events, _ := el.EventsAfter("user1", 0, 0)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events kept, got %d", len(events))
+	}
+	if events[1].Data["chapter"] != float64(4) {
+		t.Errorf("expected newest events kept, got %v", events[1].Data["chapter"])
+	}
+}
+
+func TestEventLogPruneMaxAge(t *testing.T) {
+	el := setupEventLog(t, bridge.RetentionPolicy{MaxAge: time.Hour})
+
+	old := bridge.NewUnifiedEvent(bridge.EventProgressUpdate, "user1", bridge.ProtocolTCP, nil)
+	old.Timestamp = time.Now().Add(-2 * time.Hour)
+	el.Append(old)
+	el.Append(bridge.NewUnifiedEvent(bridge.EventProgressUpdate, "user1", bridge.ProtocolTCP, nil))
+
+	removed, err := el.Prune()
+	if err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 expired event removed, got %d", removed)
+	}
+}
+
+func TestUnifiedBridgeReplayEvents(t *testing.T) {
+	el := setupEventLog(t, bridge.DefaultRetentionPolicy())
+
+	log := logger.New(logger.DEBUG, false, os.Stdout)
+	ub := bridge.NewUnifiedBridge(log)
+
+	if _, err := ub.ReplayEvents("user1", 0, 0); err != bridge.ErrEventLogDisabled {
+		t.Errorf("expected ErrEventLogDisabled, got %v", err)
+	}
+
+	ub.SetEventLog(el)
+
+	// Bridge is not started, so the queue holds events; the log must still have them
+	for i := 0; i < 3; i++ {
+		ub.BroadcastEvent(bridge.NewUnifiedEvent(bridge.EventProgressUpdate, "user1", bridge.ProtocolGRPC, map[string]interface{}{"chapter": i}))
+	}
+
+	events, err := ub.ReplayEvents("user1", 0, 0)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected 3 replayed events, got %d", len(events))
+	}
+
+	events, _ = ub.ReplayEvents("user1", events[1].Offset, 0)
+	if len(events) != 1 {
+		t.Errorf("expected 1 event after second offset, got %d", len(events))
+	}
+}
+
+func TestBridgeRecordsNotifications(t *testing.T) {
+	el := setupEventLog(t, bridge.DefaultRetentionPolicy())
+
+	log := logger.New(logger.DEBUG, false, os.Stdout)
+	b := bridge.NewBridge(log)
+	b.SetEventLog(el)
+	b.Start()
+	defer b.Stop()
+
+	b.NotifyProgressUpdate(bridge.ProgressUpdateEvent{UserID: "user1", MangaID: "m1", ChapterID: 10, Status: "reading", LastReadDate: time.Now()})
+	b.NotifyLibraryUpdate(bridge.LibraryUpdateEvent{UserID: "user1", MangaID: "m1", Action: "added"})
+
+	events, err := b.ReplayEvents("user1", 0, 0)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].Type != bridge.EventProgressUpdate || events[1].Type != bridge.EventLibraryUpdate {
+		t.Errorf("unexpected event types: %s, %s", events[0].Type, events[1].Type)
+	}
+}
+
+func TestBridgePropagatesRequestID(t *testing.T) {
+	el := setupEventLog(t, bridge.DefaultRetentionPolicy())
+
+	log := logger.New(logger.DEBUG, false, os.Stdout)
+	ub := bridge.NewUnifiedBridge(log)
+	ub.SetEventLog(el)
+
+	received := make(chan bridge.UnifiedEvent, 1)
+	ub.Subscribe(func(event bridge.UnifiedEvent) error {
+		received <- event
+		return nil
+	})
+
+	b := bridge.NewBridge(log)
+	b.SetUnifiedBridge(ub)
+	b.Start()
+	defer b.Stop()
+
+	b.NotifyProgressUpdate(bridge.ProgressUpdateEvent{UserID: "user1", MangaID: "m1", ChapterID: 3, LastReadDate: time.Now(), RequestID: "req-abc"})
+
+	select {
+	case event := <-received:
+		if event.Metadata.RequestID != "req-abc" {
+			t.Errorf("expected subscriber to see request ID req-abc, got %q", event.Metadata.RequestID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscriber was not notified")
+	}
+
+	events, err := b.ReplayEvents("user1", 0, 0)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("expected 1 replayed event, got %d (%v)", len(events), err)
+	}
+	if events[0].Metadata.RequestID != "req-abc" {
+		t.Errorf("expected replayed request ID req-abc, got %q", events[0].Metadata.RequestID)
+	}
+}
+
+func TestTCPPushesCarryTheirOffset(t *testing.T) {
+	el := setupEventLog(t, bridge.DefaultRetentionPolicy())
+
+	log := logger.New(logger.DEBUG, false, os.Stdout)
+	b := bridge.NewBridge(log)
+	b.SetEventLog(el)
+	b.Start()
+	defer b.Stop()
+
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	b.RegisterTCPClient(server, "user1")
+
+	b.NotifyProgressUpdate(bridge.ProgressUpdateEvent{UserID: "user1", MangaID: "m1", ChapterID: 4, LastReadDate: time.Now()})
+
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := bufio.NewReader(client).ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("no event pushed: %v", err)
+	}
+	var pushed bridge.Event
+	if err := json.Unmarshal(line, &pushed); err != nil {
+		t.Fatalf("failed to decode pushed event: %v", err)
+	}
+
+	events, _ := b.ReplayEvents("user1", 0, 0)
+	if len(events) != 1 || pushed.Offset != events[0].Offset {
+		t.Errorf("pushed offset = %d, want the logged offset of %+v", pushed.Offset, events)
+	}
+}
@@ -17,6 +17,7 @@ const (
 	ProtocolUDP       ProtocolType = "udp"
 	ProtocolWebSocket ProtocolType = "websocket"
 	ProtocolGRPC      ProtocolType = "grpc"
+	ProtocolHTTP      ProtocolType = "http"
 )
 
 type ProtocolClient struct {
@@ -38,6 +39,9 @@ type UnifiedBridge struct {
 	wsBroadcaster   WebSocketBroadcaster
 	udpBroadcaster  UDPBroadcaster
 	sessionManager  SessionManager
+	presence        PresenceTracker
+	eventLog        *EventLog
+	subscribers     []EventHandler
 	clientsLock     sync.RWMutex
 	eventChan       chan UnifiedEvent
 	stopChan        chan struct{}
@@ -96,9 +100,67 @@ func (ub *UnifiedBridge) SetSessionManager(sm SessionManager) {
 	ub.logger.Info("session_manager_set")
 }
 
-func (ub *UnifiedBridge) RegisterProtocolClient(conn interface{}, userID string, protocol ProtocolType) string {
+func (ub *UnifiedBridge) SetEventLog(el *EventLog) {
+	ub.clientsLock.Lock()
+	defer ub.clientsLock.Unlock()
+	ub.eventLog = el
+	ub.logger.Info("event_log_set")
+}
+
+func (ub *UnifiedBridge) GetEventLog() *EventLog {
+	ub.clientsLock.RLock()
+	defer ub.clientsLock.RUnlock()
+	return ub.eventLog
+}
+
+// SetPresenceTracker reports connections registered with the bridge, and
+// those the protocol servers mark themselves, to the tracker
+func (ub *UnifiedBridge) SetPresenceTracker(t PresenceTracker) {
 	ub.clientsLock.Lock()
 	defer ub.clientsLock.Unlock()
+	ub.presence = t
+	ub.logger.Info("presence_tracker_set")
+}
+
+func (ub *UnifiedBridge) presenceTracker() PresenceTracker {
+	ub.clientsLock.RLock()
+	defer ub.clientsLock.RUnlock()
+	return ub.presence
+}
+
+// MarkConnected tells the presence tracker about a connection the bridge
+// does not route events to itself, such as a TCP session or UDP subscriber
+func (ub *UnifiedBridge) MarkConnected(userID, connID string, protocol ProtocolType) {
+	if t := ub.presenceTracker(); t != nil && userID != "" {
+		t.Connected(userID, connID, protocol)
+	}
+}
+
+func (ub *UnifiedBridge) MarkDisconnected(userID, connID string) {
+	if t := ub.presenceTracker(); t != nil && userID != "" {
+		t.Disconnected(userID, connID)
+	}
+}
+
+// MarkActive records that the user did something, as opposed to a client
+// keeping its connection alive
+func (ub *UnifiedBridge) MarkActive(userID string, protocol ProtocolType) {
+	if t := ub.presenceTracker(); t != nil && userID != "" {
+		t.Active(userID, protocol)
+	}
+}
+
+// Subscribe registers an in-process consumer that receives every event
+// routed through the bridge, e.g. the webhook dispatcher.
+func (ub *UnifiedBridge) Subscribe(handler EventHandler) {
+	ub.clientsLock.Lock()
+	defer ub.clientsLock.Unlock()
+	ub.subscribers = append(ub.subscribers, handler)
+	ub.logger.Info("bridge_subscriber_added", "subscribers", len(ub.subscribers))
+}
+
+func (ub *UnifiedBridge) RegisterProtocolClient(conn interface{}, userID string, protocol ProtocolType) string {
+	ub.clientsLock.Lock()
 
 	clientID := generateClientID(userID, protocol)
 	client := &ProtocolClient{
@@ -119,13 +181,32 @@ func (ub *UnifiedBridge) RegisterProtocolClient(conn interface{}, userID string,
 		"client_id", clientID,
 		"total_clients", len(ub.clients[userID]),
 	)
+	ub.clientsLock.Unlock()
 
+	ub.MarkConnected(userID, clientID, protocol)
 	return clientID
 }
 
+// TouchProtocolClient records activity on a registered client
+func (ub *UnifiedBridge) TouchProtocolClient(clientID string, userID string) {
+	ub.clientsLock.Lock()
+	var protocol ProtocolType
+	for _, client := range ub.clients[userID] {
+		if client.ID == clientID {
+			client.LastActivity = time.Now()
+			protocol = client.Type
+			break
+		}
+	}
+	ub.clientsLock.Unlock()
+
+	if protocol != "" {
+		ub.MarkActive(userID, protocol)
+	}
+}
+
 func (ub *UnifiedBridge) UnregisterProtocolClient(clientID string, userID string) {
 	ub.clientsLock.Lock()
-	defer ub.clientsLock.Unlock()
 
 	clients := ub.clients[userID]
 	for i, client := range clients {
@@ -145,21 +226,92 @@ func (ub *UnifiedBridge) UnregisterProtocolClient(clientID string, userID string
 		"client_id", clientID,
 		"remaining_clients", len(ub.clients[userID]),
 	)
+	ub.clientsLock.Unlock()
+
+	ub.MarkDisconnected(userID, clientID)
+}
+
+// BroadcastEvent logs the event and queues it for every protocol. It
+// returns the event's offset, or 0 when it was not logged.
+func (ub *UnifiedBridge) BroadcastEvent(event UnifiedEvent) int64 {
+	// Persist before queueing so a full channel or an offline client can
+	// still catch up through ReplayEvents.
+	if el := ub.GetEventLog(); el != nil && event.Offset == 0 {
+		offset, err := el.Append(event)
+		if err != nil {
+			ub.logger.WithRequestID(event.Metadata.RequestID).Error("event_log_append_failed", "type", event.Type, "user_id", event.UserID, "error", err.Error())
+		} else {
+			event.Offset = offset
+		}
+	}
+
+	ub.queue(event)
+	return event.Offset
 }
 
-func (ub *UnifiedBridge) BroadcastEvent(event UnifiedEvent) {
+// BroadcastEphemeral queues the event for every protocol without logging
+// it. It is for state that is stale by the time anyone could replay it,
+// such as presence, which would otherwise flood the event log.
+func (ub *UnifiedBridge) BroadcastEphemeral(event UnifiedEvent) {
+	ub.queue(event)
+}
+
+func (ub *UnifiedBridge) queue(event UnifiedEvent) {
 	select {
 	case ub.eventChan <- event:
-		ub.logger.Debug("event_queued", "type", event.Type, "user_id", event.UserID)
+		metrics.SetBridgeQueueDepth("unified", len(ub.eventChan))
+		ub.logger.WithRequestID(event.Metadata.RequestID).Debug("event_queued", "type", event.Type, "user_id", event.UserID, "offset", event.Offset)
 	default:
-		ub.logger.Warn("event_channel_full", "type", event.Type, "user_id", event.UserID)
+		ub.logger.WithRequestID(event.Metadata.RequestID).Warn("event_channel_full", "type", event.Type, "user_id", event.UserID, "offset", event.Offset)
+	}
+}
+
+// PublishEvent logs the event and hands it to subscribers without fanning it
+// out to protocol clients. It is meant for events that were already delivered
+// to clients through another path, such as the TCP/HTTP bridge or SSE. It
+// returns the event's offset, or 0 when it was not logged.
+func (ub *UnifiedBridge) PublishEvent(event UnifiedEvent) int64 {
+	if el := ub.GetEventLog(); el != nil && event.Offset == 0 {
+		offset, err := el.Append(event)
+		if err != nil {
+			ub.logger.WithRequestID(event.Metadata.RequestID).Error("event_log_append_failed", "type", event.Type, "user_id", event.UserID, "error", err.Error())
+		} else {
+			event.Offset = offset
+		}
 	}
+	ub.logger.WithRequestID(event.Metadata.RequestID).Debug("event_published", "type", event.Type, "user_id", event.UserID, "offset", event.Offset)
+	ub.notifySubscribers(event)
+	return event.Offset
+}
+
+func (ub *UnifiedBridge) notifySubscribers(event UnifiedEvent) {
+	ub.clientsLock.RLock()
+	subscribers := ub.subscribers
+	ub.clientsLock.RUnlock()
+
+	for _, handler := range subscribers {
+		go func(h EventHandler) {
+			if err := h(event); err != nil {
+				ub.logger.WithRequestID(event.Metadata.RequestID).Warn("bridge_subscriber_error", "event_id", event.ID, "type", event.Type, "error", err.Error())
+			}
+		}(handler)
+	}
+}
+
+// ReplayEvents returns the logged events for userID after the given offset.
+func (ub *UnifiedBridge) ReplayEvents(userID string, afterOffset int64, limit int) ([]UnifiedEvent, error) {
+	el := ub.GetEventLog()
+	if el == nil {
+		return nil, ErrEventLogDisabled
+	}
+	return el.EventsAfter(userID, afterOffset, limit)
 }
 
 func (ub *UnifiedBridge) processEvents() {
 	for {
 		select {
 		case event := <-ub.eventChan:
+			metrics.SetBridgeQueueDepth("unified", len(ub.eventChan))
 			ub.routeEvent(event)
 		case <-ub.stopChan:
 			ub.logger.Info("unified_bridge_stopped")
@@ -191,6 +343,8 @@ func (ub *UnifiedBridge) routeEvent(event UnifiedEvent) {
 	if udpBroadcaster != nil {
 		go udpBroadcaster.BroadcastUnifiedEvent(event.UserID, event)
 	}
+
+	ub.notifySubscribers(event)
 }
 
 func (ub *UnifiedBridge) sendToClient(client *ProtocolClient, event UnifiedEvent) {
@@ -279,5 +433,5 @@ func (ub *UnifiedBridge) GetProtocolStats() map[ProtocolType]int {
 }
 
 func generateClientID(userID string, protocol ProtocolType) string {
-	return userID + "_" + string(protocol) + "_" + time.Now().Format("20060102150405")
+	return userID + "_" + string(protocol) + "_" + time.Now().Format("20060102150405.000000000")
 }
@@ -0,0 +1,72 @@
+package chapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/manga"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+// maxCatalogChapters caps how much of a MangaDex feed one refresh stores
+const maxCatalogChapters = 5000
+
+var ErrNoMangaDexID = errors.New("manga has no MangaDex mapping")
+
+// Source fetches a manga's chapters from MangaDex
+type Source interface {
+	GetAllChapters(ctx context.Context, mangaDexID, language string, maxChapters int) ([]manga.Chapter, error)
+}
+
+// Catalog fills the local chapter store from MangaDex
+type Catalog struct {
+	store   *Store
+	source  Source
+	resolve func(mangaID string) string
+}
+
+// NewCatalog uses MangaDex, mapping local (MAL) manga IDs with the legacy mapping API
+func NewCatalog(store *Store) *Catalog {
+	return NewCatalogWithSource(store, manga.NewMangaDexSource(), manga.FetchMangaDexID)
+}
+
+// NewCatalogWithSource uses a custom source and ID mapping (for testing)
+func NewCatalogWithSource(store *Store, source Source, resolve func(mangaID string) string) *Catalog {
+	return &Catalog{store: store, source: source, resolve: resolve}
+}
+
+// Refresh fetches a manga's chapters in language and stores them, returning
+// how many were new
+func (c *Catalog) Refresh(ctx context.Context, mangaID, language string) (int, error) {
+	mangaDexID := c.resolve(mangaID)
+	if mangaDexID == "" {
+		return 0, ErrNoMangaDexID
+	}
+
+	fetched, err := c.source.GetAllChapters(ctx, mangaDexID, language, maxCatalogChapters)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch chapters: %w", err)
+	}
+
+	chapters := make([]models.Chapter, 0, len(fetched))
+	for _, f := range fetched {
+		ch := models.Chapter{
+			ID:              f.ID,
+			MangaID:         mangaID,
+			Number:          strings.TrimSpace(f.Chapter),
+			Volume:          strings.TrimSpace(f.Volume),
+			Title:           strings.TrimSpace(f.Title),
+			Language:        f.Language,
+			ScanlationGroup: f.Group,
+			Pages:           f.Pages,
+		}
+		if t, err := time.Parse(time.RFC3339, f.PublishAt); err == nil {
+			ch.PublishedAt = &t
+		}
+		chapters = append(chapters, ch)
+	}
+	return c.store.SaveChapters(mangaID, chapters)
+}
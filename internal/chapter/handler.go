@@ -0,0 +1,299 @@
+package chapter
+
+import (
+	"errors"
+	"math"
+	"net/http"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the chapter catalogue under /manga/:id/chapters and the
+// current user's read marks under /users/chapters/:manga_id
+type Handler struct {
+	catalog  *Catalog
+	progress ProgressSaver
+}
+
+// ProgressSaver applies a progress update and tells connected clients about
+// it; the library handler's SaveProgress is the one servers wire in
+type ProgressSaver interface {
+	SaveProgress(c *gin.Context, userID string, req models.UpdateProgressRequest) (*models.UserProgress, error)
+}
+
+func NewHandler(catalog *Catalog) *Handler {
+	return &Handler{catalog: catalog}
+}
+
+// SetProgressSaver routes current_chapter changes from read marks through s.
+// Without one they are only stored, and no client hears about them.
+func (h *Handler) SetProgressSaver(s ProgressSaver) {
+	h.progress = s
+}
+
+// ListChapters returns a manga's chapters from the local catalogue, fetching
+// them from MangaDex the first time or with ?refresh=true (?language=, default en)
+func (h *Handler) ListChapters(c *gin.Context) {
+	mangaID := c.Param("id")
+	language := c.DefaultQuery("language", "en")
+
+	exists, err := repository.Default().Manga.Exists(c.Request.Context(), mangaID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Manga not found"})
+		return
+	}
+
+	chapters, err := h.catalog.store.Chapters(mangaID, language)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load chapters"})
+		return
+	}
+
+	added := 0
+	if len(chapters) == 0 || c.Query("refresh") == "true" {
+		added, err = h.catalog.Refresh(c.Request.Context(), mangaID, language)
+		if err != nil && len(chapters) == 0 {
+			if errors.Is(err, ErrNoMangaDexID) {
+				c.JSON(http.StatusBadGateway, gin.H{"error": "failed to map to MangaDex ID"})
+				return
+			}
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch chapters from MangaDex"})
+			return
+		}
+		if err == nil {
+			if chapters, err = h.catalog.store.Chapters(mangaID, language); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load chapters"})
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"manga_id": mangaID,
+		"language": language,
+		"total":    len(chapters),
+		"added":    added,
+		"chapters": chapters,
+	})
+}
+
+// GetReadState returns the current user's read marks for a manga's chapters
+func (h *Handler) GetReadState(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	mangaID := c.Param("manga_id")
+	language := c.DefaultQuery("language", "en")
+
+	exists, err := repository.Default().Manga.Exists(c.Request.Context(), mangaID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Manga not found"})
+		return
+	}
+
+	states, err := h.catalog.store.ReadState(userID, mangaID, language)
+	if err == nil && len(states) == 0 {
+		// Nothing cached yet; a failed fetch just leaves the list empty
+		if _, refreshErr := h.catalog.Refresh(c.Request.Context(), mangaID, language); refreshErr == nil {
+			states, err = h.catalog.store.ReadState(userID, mangaID, language)
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load read marks"})
+		return
+	}
+
+	progress := models.ChapterProgress{
+		MangaID:       mangaID,
+		TotalChapters: len(states),
+		Chapters:      states,
+	}
+	for _, s := range states {
+		if s.Read {
+			progress.ReadChapters++
+		}
+	}
+	if p, err := repository.Default().Progress.Get(c.Request.Context(), userID, mangaID); err == nil {
+		progress.CurrentChapter = p.CurrentChapter
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
+
+// MarkRead marks chapters read by ID and/or everything up to a chapter number,
+// then moves the library's current_chapter forward to match
+func (h *Handler) MarkRead(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.MarkChaptersReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.ChapterIDs) == 0 && req.Through == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "chapter_ids or through is required"})
+		return
+	}
+	if req.Language == "" {
+		req.Language = "en"
+	}
+
+	mangaID := c.Param("manga_id")
+	var marks []models.Chapter
+	for _, id := range req.ChapterIDs {
+		ch, err := h.catalog.store.Get(id)
+		if err != nil && !errors.Is(err, ErrChapterNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load chapter"})
+			return
+		}
+		if err != nil || ch.MangaID != mangaID {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Chapter not found: " + id})
+			return
+		}
+		marks = append(marks, *ch)
+	}
+
+	if req.Through != "" {
+		through, ok := SortKey(req.Through)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "through must be a chapter number"})
+			return
+		}
+		chapters, err := h.catalog.store.Chapters(mangaID, req.Language)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load chapters"})
+			return
+		}
+		for _, ch := range chapters {
+			if key, ok := SortKey(ch.Number); ok && key <= through {
+				marks = append(marks, ch)
+			}
+		}
+	}
+
+	if len(marks) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No matching chapters; list the manga's chapters first to load them"})
+		return
+	}
+	if err := h.catalog.store.MarkRead(userID, marks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark chapters read"})
+		return
+	}
+
+	current, err := h.syncProgress(c, userID, mangaID, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Chapters marked but progress update failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"manga_id": mangaID, "marked": len(marks), "current_chapter": current})
+}
+
+// UnmarkRead removes a read mark. If it was the current chapter, the
+// library's current_chapter steps back to the highest chapter still read.
+func (h *Handler) UnmarkRead(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	mangaID := c.Param("manga_id")
+	ch, err := h.catalog.store.Get(c.Param("chapter_id"))
+	if err != nil || ch.MangaID != mangaID {
+		if err == nil || errors.Is(err, ErrChapterNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Chapter not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load chapter"})
+		return
+	}
+
+	if err := h.catalog.store.UnmarkRead(userID, ch.ID); err != nil {
+		if errors.Is(err, ErrNotRead) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Chapter not marked as read"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unmark chapter"})
+		return
+	}
+
+	current, err := h.syncProgress(c, userID, mangaID, ch)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Chapter unmarked but progress update failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"manga_id": mangaID, "chapter_id": ch.ID, "current_chapter": current})
+}
+
+// syncProgress derives current_chapter from the read marks. Progress saved
+// the old way (a bare chapter number) is kept: marks only move it forward,
+// except that unmarking the current chapter steps back to the highest one
+// still read. Manga not yet in the library are added as reading.
+func (h *Handler) syncProgress(c *gin.Context, userID, mangaID string, unmarked *models.Chapter) (int, error) {
+	ctx := c.Request.Context()
+	highest, err := h.catalog.store.HighestRead(userID, mangaID)
+	if err != nil {
+		return 0, err
+	}
+
+	progressRepo := repository.Default().Progress
+	current, inLibrary := 0, true
+	p, err := progressRepo.Get(ctx, userID, mangaID)
+	switch {
+	case errors.Is(err, repository.ErrNotInLibrary):
+		inLibrary = false
+	case err != nil:
+		return 0, err
+	default:
+		current = p.CurrentChapter
+	}
+
+	target := current
+	if highest > current {
+		target = highest
+	}
+	if unmarked != nil {
+		if key, ok := SortKey(unmarked.Number); ok && int(math.Floor(key)) == current && highest < current {
+			target = highest
+		}
+	}
+
+	if target == current {
+		return current, nil
+	}
+	if !inLibrary {
+		if _, err := progressRepo.AddToLibrary(ctx, userID, mangaID, "reading"); err != nil {
+			return 0, err
+		}
+	}
+	req := models.UpdateProgressRequest{MangaID: mangaID, CurrentChapter: &target}
+	var updated *models.UserProgress
+	if h.progress != nil {
+		updated, err = h.progress.SaveProgress(c, userID, req)
+	} else {
+		updated, err = progressRepo.Update(ctx, userID, req)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return updated.CurrentChapter, nil
+}
@@ -0,0 +1,241 @@
+package chapter
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+var (
+	ErrChapterNotFound = errors.New("chapter not found")
+	ErrNotRead         = errors.New("chapter not marked as read")
+)
+
+// Store persists the chapter catalogue and each user's read marks
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// SortKey is the numeric value of a chapter number, false for oneshots and
+// extras that have none
+func SortKey(number string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	return v, true
+}
+
+func sortKeyValue(number string) interface{} {
+	if v, ok := SortKey(number); ok {
+		return v
+	}
+	return nil
+}
+
+const chapterColumns = `c.id, c.manga_id, c.number, c.volume, c.title, c.language, c.scanlation_group, c.pages, c.published_at`
+
+// chapterOrder puts numbered chapters first, then extras by publish date
+const chapterOrder = ` ORDER BY c.sort_key IS NULL, c.sort_key, c.published_at, c.id`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanChapter(row rowScanner, extra ...interface{}) (*models.Chapter, error) {
+	var ch models.Chapter
+	var published sql.NullTime
+	dest := append([]interface{}{&ch.ID, &ch.MangaID, &ch.Number, &ch.Volume, &ch.Title, &ch.Language,
+		&ch.ScanlationGroup, &ch.Pages, &published}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if published.Valid {
+		t := published.Time
+		ch.PublishedAt = &t
+	}
+	return &ch, nil
+}
+
+// SaveChapters upserts a manga's chapters and returns how many were new
+func (s *Store) SaveChapters(mangaID string, chapters []models.Chapter) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM chapters WHERE manga_id = ?`, mangaID).Scan(&before); err != nil {
+		return 0, fmt.Errorf("failed to count chapters: %w", err)
+	}
+
+	now := time.Now()
+	for _, ch := range chapters {
+		var published interface{}
+		if ch.PublishedAt != nil {
+			published = *ch.PublishedAt
+		}
+		_, err := tx.Exec(`INSERT INTO chapters (id, manga_id, number, sort_key, volume, title, language, scanlation_group, pages, published_at, fetched_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+			    number = excluded.number,
+			    sort_key = excluded.sort_key,
+			    volume = excluded.volume,
+			    title = excluded.title,
+			    language = excluded.language,
+			    scanlation_group = excluded.scanlation_group,
+			    pages = excluded.pages,
+			    published_at = excluded.published_at,
+			    fetched_at = excluded.fetched_at`,
+			ch.ID, mangaID, ch.Number, sortKeyValue(ch.Number), ch.Volume, ch.Title, ch.Language,
+			ch.ScanlationGroup, ch.Pages, published, now)
+		if err != nil {
+			return 0, fmt.Errorf("failed to save chapter %s: %w", ch.ID, err)
+		}
+	}
+
+	var after int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM chapters WHERE manga_id = ?`, mangaID).Scan(&after); err != nil {
+		return 0, fmt.Errorf("failed to count chapters: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit chapters: %w", err)
+	}
+	return after - before, nil
+}
+
+// Chapters returns a manga's chapters in reading order; an empty language means all
+func (s *Store) Chapters(mangaID, language string) ([]models.Chapter, error) {
+	query := `SELECT ` + chapterColumns + ` FROM chapters c WHERE c.manga_id = ?`
+	args := []interface{}{mangaID}
+	if language != "" {
+		query += ` AND c.language = ?`
+		args = append(args, language)
+	}
+
+	rows, err := s.db.Query(query+chapterOrder, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chapters: %w", err)
+	}
+	defer rows.Close()
+
+	chapters := []models.Chapter{}
+	for rows.Next() {
+		ch, err := scanChapter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan chapter: %w", err)
+		}
+		chapters = append(chapters, *ch)
+	}
+	return chapters, rows.Err()
+}
+
+func (s *Store) Get(id string) (*models.Chapter, error) {
+	ch, err := scanChapter(s.db.QueryRow(`SELECT `+chapterColumns+` FROM chapters c WHERE c.id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrChapterNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chapter: %w", err)
+	}
+	return ch, nil
+}
+
+// MarkRead records a read of each chapter; marking a chapter again counts a re-read
+func (s *Store) MarkRead(userID string, chapters []models.Chapter) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for _, ch := range chapters {
+		_, err := tx.Exec(`INSERT INTO chapter_reads (user_id, chapter_id, manga_id, read_count, first_read_at, last_read_at)
+			VALUES (?, ?, ?, 1, ?, ?)
+			ON CONFLICT(user_id, chapter_id) DO UPDATE SET
+			    read_count = read_count + 1,
+			    last_read_at = excluded.last_read_at`,
+			userID, ch.ID, ch.MangaID, now, now)
+		if err != nil {
+			return fmt.Errorf("failed to mark chapter %s: %w", ch.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit read marks: %w", err)
+	}
+	return nil
+}
+
+// UnmarkRead removes a user's read mark from a chapter
+func (s *Store) UnmarkRead(userID, chapterID string) error {
+	res, err := s.db.Exec(`DELETE FROM chapter_reads WHERE user_id = ? AND chapter_id = ?`, userID, chapterID)
+	if err != nil {
+		return fmt.Errorf("failed to unmark chapter: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotRead
+	}
+	return nil
+}
+
+// ReadState returns a manga's chapters with the user's read marks
+func (s *Store) ReadState(userID, mangaID, language string) ([]models.ChapterReadState, error) {
+	query := `SELECT ` + chapterColumns + `, COALESCE(r.read_count, 0), r.last_read_at
+		FROM chapters c
+		LEFT JOIN chapter_reads r ON r.chapter_id = c.id AND r.user_id = ?
+		WHERE c.manga_id = ?`
+	args := []interface{}{userID, mangaID}
+	if language != "" {
+		query += ` AND c.language = ?`
+		args = append(args, language)
+	}
+
+	rows, err := s.db.Query(query+chapterOrder, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list read marks: %w", err)
+	}
+	defer rows.Close()
+
+	states := []models.ChapterReadState{}
+	for rows.Next() {
+		var state models.ChapterReadState
+		var lastRead sql.NullTime
+		ch, err := scanChapter(rows, &state.ReadCount, &lastRead)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan read mark: %w", err)
+		}
+		state.Chapter = *ch
+		state.Read = state.ReadCount > 0
+		if lastRead.Valid {
+			t := lastRead.Time
+			state.LastReadAt = &t
+		}
+		states = append(states, state)
+	}
+	return states, rows.Err()
+}
+
+// HighestRead returns the whole part of the highest numbered chapter the
+// user has marked read, or 0 when none are
+func (s *Store) HighestRead(userID, mangaID string) (int, error) {
+	var highest sql.NullFloat64
+	err := s.db.QueryRow(`SELECT MAX(c.sort_key) FROM chapter_reads r
+		JOIN chapters c ON c.id = r.chapter_id
+		WHERE r.user_id = ? AND r.manga_id = ?`, userID, mangaID).Scan(&highest)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find highest read chapter: %w", err)
+	}
+	return int(math.Floor(highest.Float64)), nil
+}
@@ -0,0 +1,207 @@
+package chapter_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/chapter"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/manga"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+type fakeSource struct {
+	chapters []manga.Chapter
+	calls    int
+}
+
+func (f *fakeSource) GetAllChapters(ctx context.Context, mangaDexID, language string, maxChapters int) ([]manga.Chapter, error) {
+	f.calls++
+	var out []manga.Chapter
+	for _, ch := range f.chapters {
+		if ch.Language == language {
+			out = append(out, ch)
+		}
+	}
+	return out, nil
+}
+
+// recordingSaver stores progress like the library handler and remembers
+// each chapter it was asked to announce
+type recordingSaver struct {
+	saved []int
+}
+
+func (r *recordingSaver) SaveProgress(c *gin.Context, userID string, req models.UpdateProgressRequest) (*models.UserProgress, error) {
+	r.saved = append(r.saved, *req.CurrentChapter)
+	return repository.Default().Progress.Update(c.Request.Context(), userID, req)
+}
+
+func setup(t *testing.T) (*chapter.Store, *fakeSource) {
+	if err := database.InitDatabase(t.TempDir() + "/chapters.db"); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	_, err := database.DB.Exec(`INSERT INTO users (id, username, email, password_hash)
+		VALUES ('user-1', 'reader', 'reader@example.com', 'hash')`)
+	if err != nil {
+		t.Fatalf("failed to insert user: %v", err)
+	}
+	if _, err := database.DB.Exec(`INSERT INTO manga (id, title, author) VALUES ('m1', 'Berserk', 'Kentaro Miura')`); err != nil {
+		t.Fatalf("failed to insert manga: %v", err)
+	}
+
+	source := &fakeSource{chapters: []manga.Chapter{
+		{ID: "c10", Chapter: "10", Language: "en", Group: "Band of the Hawk", PublishAt: "2024-01-10T00:00:00+00:00"},
+		{ID: "c2", Chapter: "2", Volume: "1", Language: "en"},
+		{ID: "c1", Chapter: "1", Volume: "1", Title: "The Black Swordsman", Language: "en"},
+		{ID: "c2.5", Chapter: "2.5", Language: "en"},
+		{ID: "extra", Chapter: "", Title: "Oneshot", Language: "en"},
+		{ID: "c1-es", Chapter: "1", Language: "es"},
+	}}
+	return chapter.NewStore(database.DB), source
+}
+
+func TestCatalogOrderAndRefresh(t *testing.T) {
+	store, source := setup(t)
+	catalog := chapter.NewCatalogWithSource(store, source, func(string) string { return "md-1" })
+
+	added, err := catalog.Refresh(context.Background(), "m1", "en")
+	if err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if added != 5 {
+		t.Errorf("first refresh added %d chapters, want 5", added)
+	}
+	if added, _ := catalog.Refresh(context.Background(), "m1", "en"); added != 0 {
+		t.Errorf("second refresh added %d chapters, want 0", added)
+	}
+
+	chapters, err := store.Chapters("m1", "en")
+	if err != nil {
+		t.Fatalf("failed to list chapters: %v", err)
+	}
+	want := []string{"c1", "c2", "c2.5", "c10", "extra"}
+	if len(chapters) != len(want) {
+		t.Fatalf("got %d chapters, want %d", len(chapters), len(want))
+	}
+	for i, id := range want {
+		if chapters[i].ID != id {
+			t.Errorf("chapter %d = %s, want %s", i, chapters[i].ID, id)
+		}
+	}
+	if chapters[3].ScanlationGroup != "Band of the Hawk" || chapters[3].PublishedAt == nil {
+		t.Errorf("chapter 10 lost its group or publish date: %+v", chapters[3])
+	}
+
+	missing := chapter.NewCatalogWithSource(store, source, func(string) string { return "" })
+	if _, err := missing.Refresh(context.Background(), "m1", "en"); err != chapter.ErrNoMangaDexID {
+		t.Errorf("refresh without mapping error = %v, want ErrNoMangaDexID", err)
+	}
+}
+
+func TestReadMarksDriveCurrentChapter(t *testing.T) {
+	store, source := setup(t)
+	catalog := chapter.NewCatalogWithSource(store, source, func(string) string { return "md-1" })
+	repository.SetDefault(nil)
+
+	gin.SetMode(gin.TestMode)
+	h := chapter.NewHandler(catalog)
+	saver := &recordingSaver{}
+	h.SetProgressSaver(saver)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", "user-1") })
+	router.GET("/manga/:id/chapters", h.ListChapters)
+	router.GET("/users/chapters/:manga_id", h.GetReadState)
+	router.POST("/users/chapters/:manga_id/read", h.MarkRead)
+	router.DELETE("/users/chapters/:manga_id/read/:chapter_id", h.UnmarkRead)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	current := func() int {
+		p, err := repository.Default().Progress.Get(context.Background(), "user-1", "m1")
+		if err != nil {
+			t.Fatalf("manga not in library: %v", err)
+		}
+		return p.CurrentChapter
+	}
+
+	if w := do("GET", "/manga/m1/chapters", ""); w.Code != http.StatusOK {
+		t.Fatalf("list chapters status = %d (%s)", w.Code, w.Body.String())
+	}
+	if w := do("GET", "/manga/m1/chapters", ""); w.Code != http.StatusOK || source.calls != 1 {
+		t.Errorf("cached list status = %d after %d fetches, want one fetch", w.Code, source.calls)
+	}
+	if w := do("GET", "/manga/missing/chapters", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown manga status = %d, want 404", w.Code)
+	}
+	if w := do("GET", "/users/chapters/missing", ""); w.Code != http.StatusNotFound || source.calls != 1 {
+		t.Errorf("read state of unknown manga status = %d after %d fetches, want 404 without a fetch", w.Code, source.calls)
+	}
+
+	if w := do("POST", "/users/chapters/m1/read", `{"through": "2.5"}`); w.Code != http.StatusOK {
+		t.Fatalf("mark through status = %d (%s)", w.Code, w.Body.String())
+	}
+	if got := current(); got != 2 {
+		t.Errorf("current_chapter after marking through 2.5 = %d, want 2", got)
+	}
+
+	// Progress saved as a bare number is kept when marks are behind it
+	chapterNum := 5
+	repository.Default().Progress.Update(context.Background(), "user-1", models.UpdateProgressRequest{MangaID: "m1", CurrentChapter: &chapterNum})
+	do("POST", "/users/chapters/m1/read", `{"chapter_ids": ["c1"]}`)
+	if got := current(); got != 5 {
+		t.Errorf("re-reading chapter 1 moved current_chapter to %d, want 5", got)
+	}
+
+	if w := do("POST", "/users/chapters/m1/read", `{"chapter_ids": ["c10"]}`); w.Code != http.StatusOK {
+		t.Fatalf("mark chapter status = %d (%s)", w.Code, w.Body.String())
+	}
+	if got := current(); got != 10 {
+		t.Errorf("current_chapter after reading chapter 10 = %d, want 10", got)
+	}
+
+	if w := do("DELETE", "/users/chapters/m1/read/c10", ""); w.Code != http.StatusOK {
+		t.Fatalf("unmark status = %d (%s)", w.Code, w.Body.String())
+	}
+	if got := current(); got != 2 {
+		t.Errorf("current_chapter after unmarking chapter 10 = %d, want 2", got)
+	}
+	if w := do("DELETE", "/users/chapters/m1/read/c10", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unmarking an unread chapter status = %d, want 404", w.Code)
+	}
+	if w := do("POST", "/users/chapters/m1/read", `{"chapter_ids": ["c1-es", "nope"]}`); w.Code != http.StatusNotFound {
+		t.Errorf("marking an unknown chapter status = %d, want 404", w.Code)
+	}
+
+	var progress models.ChapterProgress
+	w := do("GET", "/users/chapters/m1", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &progress); err != nil {
+		t.Fatalf("failed to decode read state: %v", err)
+	}
+	if progress.ReadChapters != 3 || progress.TotalChapters != 5 || progress.CurrentChapter != 2 {
+		t.Errorf("read state = %d/%d at chapter %d, want 3/5 at chapter 2",
+			progress.ReadChapters, progress.TotalChapters, progress.CurrentChapter)
+	}
+	if progress.Chapters[0].ReadCount != 2 {
+		t.Errorf("chapter 1 read count = %d, want 2", progress.Chapters[0].ReadCount)
+	}
+
+	// Every move went out through the saver, so clients heard about it
+	if got := fmt.Sprint(saver.saved); got != "[2 10 2]" {
+		t.Errorf("announced chapters = %s, want [2 10 2]", got)
+	}
+}
@@ -0,0 +1,159 @@
+package download
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+// ComicInfo is the ComicRack metadata file most comic readers look for
+type ComicInfo struct {
+	XMLName         xml.Name `xml:"ComicInfo"`
+	Title           string   `xml:"Title,omitempty"`
+	Series          string   `xml:"Series,omitempty"`
+	Number          string   `xml:"Number,omitempty"`
+	Volume          int      `xml:"Volume,omitempty"`
+	Summary         string   `xml:"Summary,omitempty"`
+	Year            int      `xml:"Year,omitempty"`
+	Month           int      `xml:"Month,omitempty"`
+	Day             int      `xml:"Day,omitempty"`
+	Writer          string   `xml:"Writer,omitempty"`
+	Genre           string   `xml:"Genre,omitempty"`
+	Web             string   `xml:"Web,omitempty"`
+	PageCount       int      `xml:"PageCount"`
+	LanguageISO     string   `xml:"LanguageISO,omitempty"`
+	ScanInformation string   `xml:"ScanInformation,omitempty"`
+	Notes           string   `xml:"Notes,omitempty"`
+	Manga           string   `xml:"Manga"`
+}
+
+var notesIDPattern = regexp.MustCompile(`\[(Manga|Chapter) ID ([^\]]+)\]`)
+
+// MangaHubIDs reads back the manga and chapter IDs NewComicInfo records in
+// Notes, the way ComicTagger tags its source IDs
+func (c ComicInfo) MangaHubIDs() (mangaID, chapterID string) {
+	for _, m := range notesIDPattern.FindAllStringSubmatch(c.Notes, -1) {
+		if m[1] == "Manga" {
+			mangaID = m[2]
+		} else {
+			chapterID = m[2]
+		}
+	}
+	return mangaID, chapterID
+}
+
+func NewComicInfo(m *models.Manga, ch models.Chapter, pages int) ComicInfo {
+	info := ComicInfo{
+		Title:           ch.Title,
+		Series:          m.Title,
+		Number:          ch.Number,
+		Summary:         m.Description,
+		Writer:          m.Author,
+		Genre:           strings.Join(m.Genres, ", "),
+		Web:             "https://mangadex.org/chapter/" + ch.ID,
+		PageCount:       pages,
+		LanguageISO:     ch.Language,
+		ScanInformation: ch.ScanlationGroup,
+		Notes:           "Downloaded with MangaHub [Chapter ID " + ch.ID + "]",
+		Manga:           "YesAndRightToLeft",
+	}
+	if m.ID != "" {
+		info.Notes = "Downloaded with MangaHub [Manga ID " + m.ID + "] [Chapter ID " + ch.ID + "]"
+	}
+	if v, err := strconv.Atoi(ch.Volume); err == nil {
+		info.Volume = v
+	}
+	if ch.PublishedAt != nil {
+		info.Year, info.Month, info.Day = ch.PublishedAt.Year(), int(ch.PublishedAt.Month()), ch.PublishedAt.Day()
+	}
+	return info
+}
+
+// ArchiveName is "<Series> - Vol.<v> Ch.<n>.cbz", falling back to the
+// chapter title or ID for oneshots and extras
+func ArchiveName(m *models.Manga, ch models.Chapter) string {
+	var parts []string
+	if m.Title != "" {
+		parts = append(parts, m.Title, "-")
+	}
+	if ch.Volume != "" {
+		parts = append(parts, "Vol."+ch.Volume)
+	}
+	switch {
+	case ch.Number != "":
+		parts = append(parts, "Ch."+ch.Number)
+	case ch.Title != "":
+		parts = append(parts, ch.Title)
+	default:
+		parts = append(parts, ch.ID)
+	}
+	return sanitizeFilename(strings.Join(parts, " ")) + ".cbz"
+}
+
+func sanitizeFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?*`, r) || r < 32 {
+			return '_'
+		}
+		return r
+	}, name)
+	return strings.TrimRight(strings.TrimSpace(name), ".")
+}
+
+// writeCBZ packs ComicInfo.xml and the pages, in order, into a zip written
+// next to the archive and renamed into place when complete
+func writeCBZ(archive string, info ComicInfo, pages []string) error {
+	tmp := archive + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+
+	err = func() error {
+		zw := zip.NewWriter(f)
+		w, err := zw.Create("ComicInfo.xml")
+		if err != nil {
+			return err
+		}
+		io.WriteString(w, xml.Header)
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		if err := enc.Encode(info); err != nil {
+			return err
+		}
+
+		for _, page := range pages {
+			// Images are already compressed, so store them as they are
+			w, err := zw.CreateHeader(&zip.FileHeader{Name: filepath.Base(page), Method: zip.Store})
+			if err != nil {
+				return err
+			}
+			src, err := os.Open(page)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(w, src)
+			src.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return zw.Close()
+	}()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return os.Rename(tmp, archive)
+}
@@ -0,0 +1,270 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/manga"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+const (
+	DefaultWorkers = 4
+	// MaxWorkers keeps us polite towards MangaDex@Home nodes
+	MaxWorkers   = 8
+	pageAttempts = 3
+)
+
+var ErrNoPages = errors.New("chapter has no pages available")
+
+// PageSource resolves a chapter's page URLs (MangaDex at-home)
+type PageSource interface {
+	GetChapterPages(ctx context.Context, chapterID string) (*manga.ChapterPages, error)
+}
+
+// Downloader fetches chapter pages into a staging directory and packs them
+// into a CBZ archive. Pages already on disk are kept, and a partly written
+// page is continued with a Range request, so an interrupted download resumes.
+type Downloader struct {
+	source  PageSource
+	client  *http.Client
+	workers int
+}
+
+func NewDownloader(source PageSource, workers int) *Downloader {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if workers > MaxWorkers {
+		workers = MaxWorkers
+	}
+	return &Downloader{
+		source:  source,
+		client:  &http.Client{Timeout: 60 * time.Second},
+		workers: workers,
+	}
+}
+
+// Result describes one downloaded chapter
+type Result struct {
+	Path    string `json:"path"`
+	Pages   int    `json:"pages"`
+	Resumed int    `json:"resumed"` // pages that were already on disk
+	Bytes   int64  `json:"bytes"`
+	Skipped bool   `json:"skipped"` // the archive already existed
+}
+
+// Chapter downloads one chapter into dir as a CBZ with ComicInfo.xml built
+// from m and ch. m may be nil when the series is unknown.
+func (d *Downloader) Chapter(ctx context.Context, dir string, m *models.Manga, ch models.Chapter) (*Result, error) {
+	if m == nil {
+		m = &models.Manga{}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	archive := filepath.Join(dir, ArchiveName(m, ch))
+	if info, err := os.Stat(archive); err == nil && info.Size() > 0 {
+		return &Result{Path: archive, Skipped: true}, nil
+	}
+
+	pages, err := d.source.GetChapterPages(ctx, ch.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chapter pages: %w", err)
+	}
+	if len(pages.Data) == 0 {
+		return nil, ErrNoPages
+	}
+
+	staging := filepath.Join(dir, "."+ch.ID+".part")
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	files := make([]string, len(pages.Data))
+	for i, name := range pages.Data {
+		files[i] = filepath.Join(staging, fmt.Sprintf("%03d%s", i+1, path.Ext(name)))
+	}
+
+	result := &Result{Path: archive, Pages: len(files)}
+	if err := d.fetchAll(ctx, pages, files, result); err != nil {
+		return nil, err
+	}
+
+	if err := writeCBZ(archive, NewComicInfo(m, ch, len(files)), files); err != nil {
+		return nil, err
+	}
+	os.RemoveAll(staging)
+	return result, nil
+}
+
+// fetchAll downloads the pages with a bounded worker pool, stopping at the
+// first page that still fails after retrying
+func (d *Downloader) fetchAll(ctx context.Context, pages *manga.ChapterPages, files []string, result *Result) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+
+	for w := 0; w < d.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				resumed, n, err := d.fetchPage(ctx, pages.GetPageURL(i), files[i])
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = fmt.Errorf("page %d: %w", i+1, err)
+					cancel()
+				}
+				if resumed {
+					result.Resumed++
+				}
+				result.Bytes += n
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for i := range files {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// fetchPage makes sure dest holds the complete page, retrying transient
+// failures. It reports whether the page was already there and its size.
+func (d *Downloader) fetchPage(ctx context.Context, url, dest string) (bool, int64, error) {
+	if info, err := os.Stat(dest); err == nil {
+		return true, info.Size(), nil
+	}
+
+	var err error
+	for attempt := 1; attempt <= pageAttempts; attempt++ {
+		var n int64
+		if n, err = d.fetchOnce(ctx, url, dest); err == nil {
+			return false, n, nil
+		}
+		if ctx.Err() != nil {
+			return false, 0, ctx.Err()
+		}
+		if attempt == pageAttempts {
+			break
+		}
+		backoff := time.NewTimer(time.Duration(attempt) * 500 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			backoff.Stop()
+			return false, 0, ctx.Err()
+		case <-backoff.C:
+		}
+	}
+	return false, 0, err
+}
+
+// fetchOnce continues dest+".part" from where it stopped and renames it to
+// dest once its size matches what the server announced
+func (d *Downloader) fetchOnce(ctx context.Context, url, dest string) (int64, error) {
+	partial := dest + ".part"
+	var offset int64
+	if info, err := os.Stat(partial); err == nil {
+		offset = info.Size()
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	req.Header.Set("User-Agent", "MangaHub/1.0")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	expected := res.ContentLength
+	switch res.StatusCode {
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+		expected = contentRangeTotal(res.Header.Get("Content-Range"))
+	case http.StatusOK:
+		// The server ignored the range; start over
+		flags |= os.O_TRUNC
+		offset = 0
+	case http.StatusRequestedRangeNotSatisfiable:
+		os.Remove(partial)
+		return 0, fmt.Errorf("partial page no longer matches, restarting")
+	default:
+		return 0, fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	f, err := os.OpenFile(partial, flags, 0644)
+	if err != nil {
+		return 0, err
+	}
+	n, copyErr := io.Copy(f, res.Body)
+	if err := f.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	if copyErr != nil {
+		return 0, copyErr
+	}
+
+	size := offset + n
+	if size == 0 {
+		os.Remove(partial)
+		return 0, fmt.Errorf("empty page")
+	}
+	if expected >= 0 && size != expected {
+		if size > expected {
+			os.Remove(partial)
+		}
+		return 0, fmt.Errorf("got %d bytes, want %d", size, expected)
+	}
+	if err := os.Rename(partial, dest); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// contentRangeTotal reads the full size from "bytes 100-199/200", or -1
+func contentRangeTotal(header string) int64 {
+	i := strings.LastIndex(header, "/")
+	if i < 0 {
+		return -1
+	}
+	total, err := strconv.ParseInt(header[i+1:], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return total
+}
@@ -0,0 +1,50 @@
+package download
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/chapter"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+// ParseRange reads "1-20", "10.5-12" or a single chapter "7"
+func ParseRange(s string) (float64, float64, error) {
+	from, to, found := strings.Cut(strings.TrimSpace(s), "-")
+	if !found {
+		to = from
+	}
+	lo, ok := chapter.SortKey(from)
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid range %q: use a chapter number or <from>-<to>", s)
+	}
+	hi, ok := chapter.SortKey(to)
+	if !ok || hi < lo {
+		return 0, 0, fmt.Errorf("invalid range %q: use a chapter number or <from>-<to>", s)
+	}
+	return lo, hi, nil
+}
+
+// Select picks one release per chapter number within [from, to], taking
+// chapters in catalogue order. With group set, that group's release wins
+// when there is one.
+func Select(chapters []models.Chapter, from, to float64, group string) []models.Chapter {
+	var picked []models.Chapter
+	index := make(map[float64]int)
+	for _, ch := range chapters {
+		key, ok := chapter.SortKey(ch.Number)
+		if !ok || key < from || key > to {
+			continue
+		}
+		i, seen := index[key]
+		if !seen {
+			index[key] = len(picked)
+			picked = append(picked, ch)
+			continue
+		}
+		if group != "" && !strings.EqualFold(picked[i].ScanlationGroup, group) && strings.EqualFold(ch.ScanlationGroup, group) {
+			picked[i] = ch
+		}
+	}
+	return picked
+}
@@ -0,0 +1,212 @@
+package download_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/download"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/manga"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+// atHome imitates MangaDex@Home: /at-home/server/:id lists the pages and
+// /data/:hash/:file serves them, honouring Range requests
+type atHome struct {
+	srv   *httptest.Server
+	pages map[string][]byte
+
+	mu       sync.Mutex
+	failing  map[string]bool // always 500
+	truncate map[string]bool // cut off the first response halfway
+	ranges   []string
+}
+
+func newAtHome(t *testing.T, n int) *atHome {
+	a := &atHome{pages: map[string][]byte{}, failing: map[string]bool{}, truncate: map[string]bool{}}
+	var names []string
+	for i := 1; i <= n; i++ {
+		name := fmt.Sprintf("%d-abc.png", i)
+		names = append(names, name)
+		a.pages[name] = bytes.Repeat([]byte{byte(i)}, 1000*i)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/at-home/server/", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"baseUrl": a.srv.URL,
+			"chapter": map[string]interface{}{"hash": "hash", "data": names},
+		})
+	})
+	mux.HandleFunc("/data/hash/", func(w http.ResponseWriter, r *http.Request) {
+		name := filepath.Base(r.URL.Path)
+		a.mu.Lock()
+		failing, truncate := a.failing[name], a.truncate[name]
+		delete(a.truncate, name)
+		if rng := r.Header.Get("Range"); rng != "" {
+			a.ranges = append(a.ranges, name+" "+rng)
+		}
+		a.mu.Unlock()
+
+		data := a.pages[name]
+		switch {
+		case failing:
+			http.Error(w, "node unavailable", http.StatusInternalServerError)
+		case truncate:
+			w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+			w.Write(data[:len(data)/2])
+		default:
+			http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+		}
+	})
+	a.srv = httptest.NewServer(mux)
+	t.Cleanup(a.srv.Close)
+	return a
+}
+
+func (a *atHome) source() *manga.MangaDexSource {
+	return &manga.MangaDexSource{BaseURL: a.srv.URL, Client: a.srv.Client()}
+}
+
+func TestChapterCBZ(t *testing.T) {
+	home := newAtHome(t, 5)
+	home.truncate["2-abc.png"] = true
+	dir := t.TempDir()
+
+	published := time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)
+	series := &models.Manga{ID: "2", Title: "Berserk", Author: "Kentaro Miura", Genres: []string{"Action", "Fantasy"}}
+	ch := models.Chapter{ID: "chap-1", Number: "12.5", Volume: "3", Title: "Guts", Language: "en",
+		ScanlationGroup: "Band of the Hawk", PublishedAt: &published}
+
+	result, err := download.NewDownloader(home.source(), 3).Chapter(context.Background(), dir, series, ch)
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if want := filepath.Join(dir, "Berserk - Vol.3 Ch.12.5.cbz"); result.Path != want {
+		t.Errorf("archive path = %s, want %s", result.Path, want)
+	}
+	if len(home.ranges) != 1 || !strings.HasPrefix(home.ranges[0], "2-abc.png bytes=1000-") {
+		t.Errorf("truncated page was not continued with a range request: %v", home.ranges)
+	}
+
+	zr, err := zip.OpenReader(result.Path)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer zr.Close()
+	if len(zr.File) != 6 || zr.File[0].Name != "ComicInfo.xml" {
+		t.Fatalf("archive has %d entries starting with %q, want ComicInfo.xml and 5 pages", len(zr.File), zr.File[0].Name)
+	}
+
+	var info download.ComicInfo
+	rc, _ := zr.File[0].Open()
+	if err := xml.NewDecoder(rc).Decode(&info); err != nil {
+		t.Fatalf("failed to decode ComicInfo.xml: %v", err)
+	}
+	rc.Close()
+	if info.Series != "Berserk" || info.Number != "12.5" || info.Volume != 3 || info.PageCount != 5 ||
+		info.Writer != "Kentaro Miura" || info.Year != 2024 || info.ScanInformation != "Band of the Hawk" {
+		t.Errorf("unexpected ComicInfo: %+v", info)
+	}
+
+	for i, f := range zr.File[1:] {
+		if want := fmt.Sprintf("%03d.png", i+1); f.Name != want {
+			t.Errorf("entry %d = %s, want %s", i+1, f.Name, want)
+		}
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if !bytes.Equal(data, home.pages[fmt.Sprintf("%d-abc.png", i+1)]) {
+			t.Errorf("page %d content does not match", i+1)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".chap-1.part")); !os.IsNotExist(err) {
+		t.Errorf("staging directory left behind: %v", err)
+	}
+	again, err := download.NewDownloader(home.source(), 3).Chapter(context.Background(), dir, series, ch)
+	if err != nil || !again.Skipped {
+		t.Errorf("second download = %+v, %v; want skipped", again, err)
+	}
+}
+
+func TestChapterResumesAfterFailure(t *testing.T) {
+	home := newAtHome(t, 4)
+	home.failing["3-abc.png"] = true
+	dir := t.TempDir()
+	ch := models.Chapter{ID: "chap-2", Number: "7"}
+
+	// One worker keeps the order predictable: pages 1 and 2 land before 3 fails
+	if _, err := download.NewDownloader(home.source(), 1).Chapter(context.Background(), dir, nil, ch); err == nil {
+		t.Fatal("download with a failing page succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Ch.7.cbz")); !os.IsNotExist(err) {
+		t.Errorf("archive written despite the failure: %v", err)
+	}
+
+	home.mu.Lock()
+	delete(home.failing, "3-abc.png")
+	home.mu.Unlock()
+
+	result, err := download.NewDownloader(home.source(), 2).Chapter(context.Background(), dir, nil, ch)
+	if err != nil {
+		t.Fatalf("resumed download failed: %v", err)
+	}
+	if result.Resumed != 2 || result.Pages != 4 {
+		t.Errorf("resumed %d of %d pages, want 2 of 4", result.Resumed, result.Pages)
+	}
+}
+
+func TestChapterStopsRetryingWhenCancelled(t *testing.T) {
+	home := newAtHome(t, 1)
+	home.failing["1-abc.png"] = true
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := download.NewDownloader(home.source(), 1).Chapter(ctx, t.TempDir(), nil, models.Chapter{ID: "chap-3", Number: "1"})
+	if err == nil {
+		t.Fatal("download with a failing page succeeded")
+	}
+	if waited := time.Since(start); waited > 400*time.Millisecond {
+		t.Errorf("download took %v to notice the cancellation, want it to cut the backoff short", waited)
+	}
+}
+
+func TestSelectRange(t *testing.T) {
+	from, to, err := download.ParseRange("2-3")
+	if err != nil {
+		t.Fatalf("failed to parse range: %v", err)
+	}
+	if _, _, err := download.ParseRange("5-1"); err == nil {
+		t.Error("backwards range accepted")
+	}
+
+	chapters := []models.Chapter{
+		{ID: "a1", Number: "1"},
+		{ID: "a2", Number: "2", ScanlationGroup: "Alpha"},
+		{ID: "b2", Number: "2", ScanlationGroup: "Beta"},
+		{ID: "a2.5", Number: "2.5"},
+		{ID: "a3", Number: "3"},
+		{ID: "extra", Number: ""},
+	}
+	var ids []string
+	for _, ch := range download.Select(chapters, from, to, "beta") {
+		ids = append(ids, ch.ID)
+	}
+	if got := strings.Join(ids, ","); got != "b2,a2.5,a3" {
+		t.Errorf("selected %s, want b2,a2.5,a3", got)
+	}
+}
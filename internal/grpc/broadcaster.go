@@ -7,10 +7,15 @@ import (
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
 )
 
+// streamBuffer is how many events a slow stream may fall behind by before
+// newer ones are dropped; the client can fetch those again by offset
+const streamBuffer = 64
+
 type StreamConnection struct {
 	UserID string
 	Stream interface{}
 	Active bool
+	events chan bridge.UnifiedEvent
 }
 
 type GRPCBroadcaster struct {
@@ -32,38 +37,56 @@ func (gb *GRPCBroadcaster) SetBridge(b *bridge.UnifiedBridge) {
 	gb.logger.Info("grpc_broadcaster_bridge_set")
 }
 
-func (gb *GRPCBroadcaster) RegisterStream(streamID string, userID string, stream interface{}) {
+// RegisterStream adds a stream and returns the channel its user's events
+// arrive on
+func (gb *GRPCBroadcaster) RegisterStream(streamID string, userID string, stream interface{}) <-chan bridge.UnifiedEvent {
 	gb.mu.Lock()
-	defer gb.mu.Unlock()
 
 	conn := &StreamConnection{
 		UserID: userID,
 		Stream: stream,
 		Active: true,
+		events: make(chan bridge.UnifiedEvent, streamBuffer),
 	}
 
 	gb.streams[streamID] = append(gb.streams[streamID], conn)
 	gb.logger.Info("grpc_stream_registered", "stream_id", streamID, "user_id", userID)
+	br := gb.bridge
+	gb.mu.Unlock()
+
+	if br != nil {
+		br.MarkConnected(userID, streamID, bridge.ProtocolGRPC)
+	}
+	return conn.events
 }
 
 func (gb *GRPCBroadcaster) UnregisterStream(streamID string) {
 	gb.mu.Lock()
-	defer gb.mu.Unlock()
-
+	conns := gb.streams[streamID]
 	delete(gb.streams, streamID)
 	gb.logger.Info("grpc_stream_unregistered", "stream_id", streamID)
+	br := gb.bridge
+	gb.mu.Unlock()
+
+	if br != nil {
+		for _, conn := range conns {
+			br.MarkDisconnected(conn.UserID, streamID)
+		}
+	}
 }
 
+// BroadcastToUser queues the event on the user's streams, or on every
+// stream when userID is empty
 func (gb *GRPCBroadcaster) BroadcastToUser(userID string, event bridge.UnifiedEvent) {
 	gb.mu.RLock()
 	defer gb.mu.RUnlock()
 
 	count := 0
-	for _, connections := range gb.streams {
+	for streamID, connections := range gb.streams {
 		for _, conn := range connections {
-			if conn.UserID == userID && conn.Active {
+			if (userID == "" || conn.UserID == userID) && conn.Active {
 				count++
-				gb.logger.Debug("grpc_event_queued", "user_id", userID, "event_type", event.Type)
+				gb.queue(streamID, conn, event)
 			}
 		}
 	}
@@ -73,20 +96,39 @@ func (gb *GRPCBroadcaster) BroadcastToUser(userID string, event bridge.UnifiedEv
 	}
 }
 
+// HandleEvent passes every event routed through the bridge on to the
+// streams it belongs to; it is meant for UnifiedBridge.Subscribe
+func (gb *GRPCBroadcaster) HandleEvent(event bridge.UnifiedEvent) error {
+	gb.BroadcastToUser(event.UserID, event)
+	return nil
+}
+
 func (gb *GRPCBroadcaster) SendToStream(streamID string, event bridge.UnifiedEvent) error {
 	gb.mu.RLock()
+	defer gb.mu.RUnlock()
 	connections, ok := gb.streams[streamID]
-	gb.mu.RUnlock()
 
 	if !ok || len(connections) == 0 {
 		gb.logger.Warn("grpc_stream_not_found", "stream_id", streamID)
 		return nil
 	}
 
-	gb.logger.Debug("grpc_event_sent", "stream_id", streamID, "event_type", event.Type)
+	for _, conn := range connections {
+		gb.queue(streamID, conn, event)
+	}
 	return nil
 }
 
+// queue hands the event to a stream without waiting on it. The caller holds gb.mu.
+func (gb *GRPCBroadcaster) queue(streamID string, conn *StreamConnection, event bridge.UnifiedEvent) {
+	select {
+	case conn.events <- event:
+		gb.logger.Debug("grpc_event_queued", "stream_id", streamID, "event_type", event.Type, "offset", event.Offset)
+	default:
+		gb.logger.Warn("grpc_stream_lagging", "stream_id", streamID, "event_type", event.Type, "offset", event.Offset)
+	}
+}
+
 func (gb *GRPCBroadcaster) GetActiveStreams(userID string) []string {
 	gb.mu.RLock()
 	defer gb.mu.RUnlock()
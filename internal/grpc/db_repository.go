@@ -4,19 +4,22 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
 	"github.com/google/uuid"
 )
 
 type DBRepository struct {
-	db *sql.DB
+	db    *sql.DB
+	store *repository.Store
 }
 
 func NewDBRepository(db *sql.DB) *DBRepository {
-	return &DBRepository{db: db}
+	return &DBRepository{db: db, store: repository.NewSQLiteStore(db)}
 }
 
 func (r *DBRepository) AddManga(ctx context.Context, manga *models.Manga) (*models.Manga, error) {
@@ -77,43 +80,17 @@ func (r *DBRepository) GetMangaByID(ctx context.Context, id string) (*models.Man
 		return nil, fmt.Errorf("id is required")
 	}
 
-	query := `
-		SELECT
-			id, title, author, genres, status,
-			total_chapters, description, cover_url, media_type
-		FROM manga
-		WHERE id = ?
-	`
-
-	var manga models.Manga
-	var genresJSON string
-
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&manga.ID,
-		&manga.Title,
-		&manga.Author,
-		&genresJSON,
-		&manga.Status,
-		&manga.TotalChapters,
-		&manga.Description,
-		&manga.CoverURL,
-		&manga.MediaType,
-	)
-
-	if err == sql.ErrNoRows {
+	manga, err := r.store.Manga.Get(ctx, id)
+	if errors.Is(err, repository.ErrMangaNotFound) {
 		return nil, fmt.Errorf("manga not found: %s", id)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("query manga: %w", err)
 	}
-
-	if genresJSON != "" {
-		if err := json.Unmarshal([]byte(genresJSON), &manga.Genres); err != nil {
-			manga.Genres = []string{}
-		}
+	if manga.Genres == nil {
+		manga.Genres = []string{}
 	}
-
-	return &manga, nil
+	return manga, nil
 }
 
 func (r *DBRepository) SearchManga(ctx context.Context, filter *SearchFilter) ([]*models.Manga, int32, error) {
@@ -240,17 +217,9 @@ func (r *DBRepository) UpdateMangaProgress(ctx context.Context, userID, mangaID
 		return fmt.Errorf("userID and mangaID are required")
 	}
 
-	query := `
-		INSERT INTO user_progress (
-			user_id, manga_id, current_chapter, updated_at
-		)
-		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
-		ON CONFLICT(user_id, manga_id) DO UPDATE SET
-			current_chapter = excluded.current_chapter,
-			updated_at = CURRENT_TIMESTAMP
-	`
-
-	if _, err := r.db.ExecContext(ctx, query, userID, mangaID, chapter); err != nil {
+	// Same rules as the HTTP and TCP sync paths: the manga must exist and
+	// the stored status is kept
+	if _, err := r.store.Progress.Sync(ctx, userID, mangaID, int(chapter), ""); err != nil {
 		return fmt.Errorf("update progress: %w", err)
 	}
 
@@ -0,0 +1,114 @@
+package grpc
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/metrics"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+	googlegrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDMetadataKey carries the request ID in gRPC metadata
+const RequestIDMetadataKey = "x-request-id"
+
+// AuthMetadataKey carries "Bearer <jwt>" in gRPC metadata, as the
+// Authorization header does over HTTP
+const AuthMetadataKey = "authorization"
+
+type userIDKey struct{}
+
+// UserIDFromContext returns the user the auth interceptors authenticated
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userIDKey{}).(string)
+	return userID, ok && userID != ""
+}
+
+// RequestIDInterceptor reads the request ID from incoming metadata, or
+// generates one, stores it on the context and echoes it as a response header.
+func RequestIDInterceptor() googlegrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *googlegrpc.UnaryServerInfo, handler googlegrpc.UnaryHandler) (interface{}, error) {
+		requestID := ""
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(RequestIDMetadataKey); len(values) > 0 {
+				requestID = values[0]
+			}
+		}
+		if requestID == "" {
+			requestID = logger.NewRequestID()
+		}
+
+		googlegrpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, requestID))
+		return handler(logger.ContextWithRequestID(ctx, requestID), req)
+	}
+}
+
+// MetricsInterceptor counts unary calls by method name and records their
+// latency and status code.
+func MetricsInterceptor() googlegrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *googlegrpc.UnaryServerInfo, handler googlegrpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		method := path.Base(info.FullMethod)
+		metrics.RecordProtocolMessage("grpc", method)
+		metrics.ObserveGRPCRequest(method, status.Code(err).String(), time.Since(start))
+		return resp, err
+	}
+}
+
+// AuthInterceptor validates the bearer token in the call's metadata and puts
+// its user on the context. Calls without a token go through anonymously;
+// handlers that need a user check UserIDFromContext.
+func AuthInterceptor(jwtSecret string) googlegrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *googlegrpc.UnaryServerInfo, handler googlegrpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, jwtSecret)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamInterceptor is AuthInterceptor for streaming calls
+func AuthStreamInterceptor(jwtSecret string) googlegrpc.StreamServerInterceptor {
+	return func(srv interface{}, ss googlegrpc.ServerStream, info *googlegrpc.StreamServerInfo, handler googlegrpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), jwtSecret)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authenticate(ctx context.Context, jwtSecret string) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get(AuthMetadataKey)) == 0 {
+		return ctx, nil
+	}
+
+	token, found := strings.CutPrefix(md.Get(AuthMetadataKey)[0], "Bearer ")
+	if !found || token == "" {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata format")
+	}
+	claims, err := utils.ValidateJWT(token, jwtSecret)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+	return context.WithValue(ctx, userIDKey{}, claims.UserID), nil
+}
+
+// contextStream swaps the context a ServerStream hands its handler
+type contextStream struct {
+	googlegrpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -3,8 +3,10 @@ package grpc
 import (
 	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
 	pb "github.com/binhbb2204/Manga-Hub-Group13/proto/manga"
@@ -47,9 +49,12 @@ func NewServerWithRepository(repo MangaRepository, db *sql.DB) *Server {
 	}
 }
 
+// SetBridge reports streams to the bridge and feeds them every event it
+// routes or publishes
 func (s *Server) SetBridge(b *bridge.UnifiedBridge) {
 	s.bridge = b
 	s.broadcaster.SetBridge(b)
+	b.Subscribe(s.broadcaster.HandleEvent)
 }
 
 func (s *Server) AddManga(ctx context.Context, req *pb.AddMangaRequest) (*pb.AddMangaResponse, error) {
@@ -160,6 +165,12 @@ func (s *Server) UpdateProgress(ctx context.Context, req *pb.ProgressRequest) (*
 	}
 
 	if err := s.repository.UpdateMangaProgress(ctx, req.UserId, req.MangaId, req.Chapter); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrMangaNotFound):
+			return nil, status.Errorf(codes.NotFound, "manga not found: %s", req.MangaId)
+		case errors.Is(err, repository.ErrInvalidChapter):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		return nil, status.Errorf(codes.Internal, "failed to update progress: %v", err)
 	}
 
@@ -173,7 +184,9 @@ func (s *Server) UpdateProgress(ctx context.Context, req *pb.ProgressRequest) (*
 				"chapter":  req.Chapter,
 			},
 		)
+		event.Metadata.RequestID = logger.RequestIDFromContext(ctx)
 		s.bridge.BroadcastEvent(event)
+		s.bridge.MarkActive(req.UserId, bridge.ProtocolGRPC)
 	}
 
 	return &pb.ProgressResponse{
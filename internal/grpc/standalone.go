@@ -0,0 +1,60 @@
+package grpc
+
+import (
+	"database/sql"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/presence"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	pb "github.com/binhbb2204/Manga-Hub-Group13/proto/manga"
+	googlegrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// Standalone is the gRPC service as cmd/grpc-server runs it on its own. Its
+// unified bridge logs events to the shared event log, so streams can resume
+// from an offset, and reports streams to presence.
+type Standalone struct {
+	GRPC     *googlegrpc.Server
+	Bridge   *bridge.UnifiedBridge
+	eventLog *bridge.EventLog
+	presence *presence.Service
+}
+
+func NewStandalone(db *sql.DB, jwtSecret string) *Standalone {
+	eventLog := bridge.NewEventLog(db, logger.WithContext("component", "event_log"), bridge.RetentionPolicyFromEnv())
+	unifiedBridge := bridge.NewUnifiedBridge(logger.WithContext("component", "unified_bridge"))
+	unifiedBridge.SetEventLog(eventLog)
+
+	presenceService := presence.ForBridge(unifiedBridge)
+
+	s := googlegrpc.NewServer(
+		googlegrpc.ChainUnaryInterceptor(RequestIDInterceptor(), MetricsInterceptor(), AuthInterceptor(jwtSecret)),
+		googlegrpc.ChainStreamInterceptor(AuthStreamInterceptor(jwtSecret)),
+	)
+	mangaServer := NewServer(db)
+	mangaServer.SetBridge(unifiedBridge)
+	pb.RegisterMangaServiceServer(s, mangaServer)
+	reflection.Register(s)
+
+	return &Standalone{
+		GRPC:     s,
+		Bridge:   unifiedBridge,
+		eventLog: eventLog,
+		presence: presenceService,
+	}
+}
+
+// Start starts the event log, presence and bridge; serve GRPC after it
+func (s *Standalone) Start() {
+	s.eventLog.Start()
+	s.presence.Start()
+	s.Bridge.Start()
+}
+
+func (s *Standalone) Stop() {
+	s.GRPC.Stop()
+	s.Bridge.Stop()
+	s.presence.Stop()
+	s.eventLog.Stop()
+}
@@ -0,0 +1,106 @@
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+	pb "github.com/binhbb2204/Manga-Hub-Group13/proto/manga"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StreamEvents sends the authenticated user's events as the bridge sees
+// them. user_id may be left out; if set it must name that same user. With
+// a positive after_offset it first replays what the event log holds after
+// it, so a client that keeps the last offset it received misses nothing
+// across reconnects.
+func (s *Server) StreamEvents(req *pb.StreamEventsRequest, stream pb.MangaService_StreamEventsServer) error {
+	userID, ok := UserIDFromContext(stream.Context())
+	if !ok {
+		return status.Error(codes.Unauthenticated, "a bearer token is required to stream events")
+	}
+	if req.UserId != "" && req.UserId != userID {
+		return status.Error(codes.PermissionDenied, "user_id does not match the authenticated user")
+	}
+	if req.AfterOffset < 0 {
+		return status.Error(codes.InvalidArgument, "after_offset must not be negative")
+	}
+	if req.AfterOffset > 0 && s.bridge == nil {
+		return status.Error(codes.FailedPrecondition, "event replay is not available")
+	}
+
+	id, err := utils.GenerateID(16)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to open stream: %v", err)
+	}
+	streamID := "grpc/" + id
+
+	// Registered before replaying so nothing published meanwhile is lost;
+	// live events the replay already sent are skipped below
+	live := s.broadcaster.RegisterStream(streamID, userID, stream)
+	defer s.broadcaster.UnregisterStream(streamID)
+
+	replayed := int64(0)
+	if req.AfterOffset > 0 {
+		if replayed, err = s.replayEvents(stream, userID, req.AfterOffset); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-live:
+			if event.Offset > 0 && event.Offset <= replayed {
+				continue
+			}
+			if err := stream.Send(toProtoEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// replayEvents sends the logged events after afterOffset a page at a time
+// and returns the offset of the last one sent
+func (s *Server) replayEvents(stream pb.MangaService_StreamEventsServer, userID string, afterOffset int64) (int64, error) {
+	last := afterOffset
+	for {
+		events, err := s.bridge.ReplayEvents(userID, last, bridge.DefaultReplayLimit)
+		if errors.Is(err, bridge.ErrEventLogDisabled) {
+			return 0, status.Error(codes.FailedPrecondition, "event replay is not available")
+		}
+		if err != nil {
+			logger.GetLogger().Error("grpc_event_replay_failed", "user_id", userID, "error", err.Error())
+			return 0, status.Errorf(codes.Internal, "failed to replay events: %v", err)
+		}
+		for _, event := range events {
+			if err := stream.Send(toProtoEvent(event)); err != nil {
+				return 0, err
+			}
+			last = event.Offset
+		}
+		if len(events) < bridge.DefaultReplayLimit {
+			logger.GetLogger().Info("grpc_events_replayed", "user_id", userID, "after_offset", afterOffset, "last_offset", last)
+			return last, nil
+		}
+	}
+}
+
+func toProtoEvent(event bridge.UnifiedEvent) *pb.Event {
+	data, _ := json.Marshal(event.Data)
+	return &pb.Event{
+		Offset:         event.Offset,
+		Id:             event.ID,
+		Type:           string(event.Type),
+		UserId:         event.UserID,
+		SourceProtocol: string(event.SourceProto),
+		Timestamp:      event.Timestamp.Format(time.RFC3339),
+		DataJson:       string(data),
+	}
+}
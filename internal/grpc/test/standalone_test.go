@@ -0,0 +1,72 @@
+package test
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	mangagrpc "github.com/binhbb2204/Manga-Hub-Group13/internal/grpc"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	pb "github.com/binhbb2204/Manga-Hub-Group13/proto/manga"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// The standalone binary shares the event log with the other servers, so a
+// client can resume from an offset another server handed out
+func TestStandaloneResumesFromSharedEventLog(t *testing.T) {
+	if err := database.InitDatabase(t.TempDir() + "/standalone.db"); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	log := logger.New(logger.DEBUG, false, os.Stdout)
+	apiLog := bridge.NewEventLog(database.DB, log, bridge.DefaultRetentionPolicy())
+	event := func(chapter int) bridge.UnifiedEvent {
+		return bridge.NewUnifiedEvent(bridge.EventProgressUpdate, "user1", bridge.ProtocolHTTP, map[string]interface{}{"chapter": chapter})
+	}
+	seen, _ := apiLog.Append(event(1))
+	missed, _ := apiLog.Append(event(2))
+
+	server := mangagrpc.NewStandalone(database.DB, testSecret)
+	server.Start()
+	lis := bufconn.Listen(1 << 20)
+	go server.GRPC.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := pb.NewMangaServiceClient(conn).StreamEvents(asUser(t, ctx, "user1"), &pb.StreamEventsRequest{AfterOffset: seen})
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	if first.Offset != missed || first.DataJson != `{"chapter":2}` {
+		t.Errorf("replayed %+v, want offset %d with chapter 2", first, missed)
+	}
+
+	live := server.Bridge.PublishEvent(event(3))
+	next, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("no live event: %v", err)
+	}
+	if next.Offset != live || live <= missed {
+		t.Errorf("live event at offset %d, want %d after %d", next.Offset, live, missed)
+	}
+}
@@ -0,0 +1,148 @@
+package test
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	mangagrpc "github.com/binhbb2204/Manga-Hub-Group13/internal/grpc"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+	pb "github.com/binhbb2204/Manga-Hub-Group13/proto/manga"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testSecret = "grpc-stream-secret"
+
+// startServer serves a manga server wired to ub over an in-memory listener
+func startServer(t *testing.T, ub *bridge.UnifiedBridge) pb.MangaServiceClient {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(mangagrpc.AuthInterceptor(testSecret)),
+		grpc.ChainStreamInterceptor(mangagrpc.AuthStreamInterceptor(testSecret)),
+	)
+	server := mangagrpc.NewServer(database.DB)
+	if ub != nil {
+		server.SetBridge(ub)
+	}
+	pb.RegisterMangaServiceServer(srv, server)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewMangaServiceClient(conn)
+}
+
+// asUser adds userID's bearer token to the outgoing metadata
+func asUser(t *testing.T, ctx context.Context, userID string) context.Context {
+	token, err := utils.GenerateJWT(userID, userID, "user", testSecret)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return metadata.AppendToOutgoingContext(ctx, mangagrpc.AuthMetadataKey, "Bearer "+token)
+}
+
+func TestStreamEventsReplaysThenFollows(t *testing.T) {
+	if err := database.InitDatabase(t.TempDir() + "/stream.db"); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	log := logger.New(logger.DEBUG, false, os.Stdout)
+	ub := bridge.NewUnifiedBridge(log)
+	ub.SetEventLog(bridge.NewEventLog(database.DB, log, bridge.DefaultRetentionPolicy()))
+	ub.Start()
+	defer ub.Stop()
+	client := startServer(t, ub)
+
+	publish := func(userID string, chapter int) int64 {
+		return ub.PublishEvent(bridge.NewUnifiedEvent(bridge.EventProgressUpdate, userID, bridge.ProtocolHTTP, map[string]interface{}{"chapter": chapter}))
+	}
+	seen := publish("user1", 1)
+	missed := publish("user1", 2)
+	publish("user2", 3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.StreamEvents(asUser(t, ctx, "user1"), &pb.StreamEventsRequest{AfterOffset: seen})
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("no replayed event: %v", err)
+	}
+	if first.Offset != missed || first.DataJson != `{"chapter":2}` {
+		t.Errorf("replayed %+v, want offset %d with chapter 2", first, missed)
+	}
+
+	// The stream registers before replaying, so this reaches it live
+	live := publish("user1", 4)
+	next, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("no live event: %v", err)
+	}
+	if next.Offset != live || next.UserId != "user1" {
+		t.Errorf("live event %+v, want offset %d for user1", next, live)
+	}
+}
+
+func TestStreamEventsNeedsAnEventLogToReplay(t *testing.T) {
+	client := startServer(t, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.StreamEvents(asUser(t, ctx, "user1"), &pb.StreamEventsRequest{UserId: "user1", AfterOffset: 7})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("resuming without an event log = %v, want FailedPrecondition", err)
+	}
+}
+
+func TestStreamEventsAuthenticatesTheUser(t *testing.T) {
+	client := startServer(t, nil)
+
+	tests := []struct {
+		name string
+		ctx  func(context.Context) context.Context
+		req  *pb.StreamEventsRequest
+		want codes.Code
+	}{
+		{"no token", func(ctx context.Context) context.Context { return ctx }, &pb.StreamEventsRequest{UserId: "user1"}, codes.Unauthenticated},
+		{"bad token", func(ctx context.Context) context.Context {
+			return metadata.AppendToOutgoingContext(ctx, mangagrpc.AuthMetadataKey, "Bearer nope")
+		}, &pb.StreamEventsRequest{UserId: "user1"}, codes.Unauthenticated},
+		{"someone else's user_id", func(ctx context.Context) context.Context { return asUser(t, ctx, "user2") }, &pb.StreamEventsRequest{UserId: "user1"}, codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			stream, err := client.StreamEvents(tt.ctx(ctx), tt.req)
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			if status.Code(err) != tt.want {
+				t.Errorf("StreamEvents = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package lists
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxNameLength        = 100
+	maxDescriptionLength = 500
+)
+
+// Handler exposes the current user's lists under /users/lists. Public lists
+// can be read by anyone who has their ID; only the owner can change a list.
+type Handler struct {
+	store *Store
+}
+
+func NewHandler(store *Store) *Handler {
+	return &Handler{store: store}
+}
+
+// GetLists returns the current user's lists
+func (h *Handler) GetLists(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	lists, err := h.store.ListsFor(userID, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load lists"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"lists": lists, "count": len(lists)})
+}
+
+// CreateList creates a list owned by the current user
+func (h *Handler) CreateList(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.CreateListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	req.Description = strings.TrimSpace(req.Description)
+	if !validName(c, req.Name) || !validDescription(c, req.Description) {
+		return
+	}
+
+	list, err := h.store.CreateList(userID, req)
+	if err != nil {
+		if errors.Is(err, ErrNameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": "You already have a list with that name"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create list"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, list)
+}
+
+// GetList returns a list with its items, if it is public or owned by the current user
+func (h *Handler) GetList(c *gin.Context) {
+	list, ok := h.visibleList(c)
+	if !ok {
+		return
+	}
+
+	items, err := h.store.Items(list.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load list items"})
+		return
+	}
+	list.Items = items
+
+	c.JSON(http.StatusOK, list)
+}
+
+// UpdateList renames a list or changes its description or visibility
+func (h *Handler) UpdateList(c *gin.Context) {
+	list, ok := h.ownList(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if !validName(c, name) {
+			return
+		}
+		req.Name = &name
+	}
+	if req.Description != nil {
+		description := strings.TrimSpace(*req.Description)
+		if !validDescription(c, description) {
+			return
+		}
+		req.Description = &description
+	}
+
+	updated, err := h.store.UpdateList(list.ID, req)
+	if err != nil {
+		if errors.Is(err, ErrNameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": "You already have a list with that name"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteList removes a list and its items
+func (h *Handler) DeleteList(c *gin.Context) {
+	list, ok := h.ownList(c)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteList(list.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "List deleted"})
+}
+
+// AddItem adds a manga to a list, at the end unless a position is given
+func (h *Handler) AddItem(c *gin.Context) {
+	list, ok := h.ownList(c)
+	if !ok {
+		return
+	}
+
+	var req models.AddListItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := h.store.AddItem(list.ID, req.MangaID, req.Position)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrMangaNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Manga not found"})
+		case errors.Is(err, ErrAlreadyInList):
+			c.JSON(http.StatusConflict, gin.H{"error": "Manga already in list"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add manga to list"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, updated)
+}
+
+// MoveItem moves a manga to a new position in a list
+func (h *Handler) MoveItem(c *gin.Context) {
+	list, ok := h.ownList(c)
+	if !ok {
+		return
+	}
+
+	var req models.MoveListItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.store.MoveItem(list.ID, c.Param("manga_id"), req.Position); err != nil {
+		h.itemError(c, err, "Failed to move manga")
+		return
+	}
+
+	items, err := h.store.Items(list.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load list items"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "count": len(items)})
+}
+
+// RemoveItem removes a manga from a list
+func (h *Handler) RemoveItem(c *gin.Context) {
+	list, ok := h.ownList(c)
+	if !ok {
+		return
+	}
+
+	if err := h.store.RemoveItem(list.ID, c.Param("manga_id")); err != nil {
+		h.itemError(c, err, "Failed to remove manga")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Manga removed from list"})
+}
+
+func (h *Handler) itemError(c *gin.Context, err error, message string) {
+	if errors.Is(err, ErrItemNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Manga not in list"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
+
+// visibleList loads the :list_id list if the current user may read it.
+// Private lists of other users are reported as missing.
+func (h *Handler) visibleList(c *gin.Context) (*models.UserList, bool) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	list, err := h.store.GetList(c.Param("list_id"))
+	if err != nil {
+		if errors.Is(err, ErrListNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load list"})
+		return nil, false
+	}
+	if !list.IsPublic && list.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return nil, false
+	}
+	return list, true
+}
+
+// ownList is visibleList for changes, which only the owner may make
+func (h *Handler) ownList(c *gin.Context) (*models.UserList, bool) {
+	list, ok := h.visibleList(c)
+	if ok && list.UserID != c.GetString("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can change this list"})
+		return nil, false
+	}
+	return list, ok
+}
+
+func validName(c *gin.Context, name string) bool {
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must not be empty"})
+		return false
+	}
+	if len([]rune(name)) > maxNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be at most " + strconv.Itoa(maxNameLength) + " characters"})
+		return false
+	}
+	return true
+}
+
+func validDescription(c *gin.Context, description string) bool {
+	if len([]rune(description)) > maxDescriptionLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "description must be at most " + strconv.Itoa(maxDescriptionLength) + " characters"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,296 @@
+package lists
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+)
+
+var (
+	ErrListNotFound  = errors.New("list not found")
+	ErrItemNotFound  = errors.New("manga not in list")
+	ErrMangaNotFound = errors.New("manga not found")
+	ErrNameTaken     = errors.New("you already have a list with that name")
+	ErrAlreadyInList = errors.New("manga already in list")
+)
+
+// Store persists user lists and their ordered items. Item positions are
+// 1-based and kept contiguous: inserting, removing or moving an item shifts
+// the items after it.
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+const listColumns = `l.id, l.user_id, COALESCE(u.username, ''), l.name, l.description, l.is_public,
+	(SELECT COUNT(*) FROM user_list_items i WHERE i.list_id = l.id) AS item_count, l.created_at, l.updated_at`
+
+const listFrom = `FROM user_lists l LEFT JOIN users u ON u.id = l.user_id`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanList(row rowScanner) (*models.UserList, error) {
+	var l models.UserList
+	if err := row.Scan(&l.ID, &l.UserID, &l.Username, &l.Name, &l.Description, &l.IsPublic,
+		&l.ItemCount, &l.CreatedAt, &l.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (s *Store) CreateList(userID string, req models.CreateListRequest) (*models.UserList, error) {
+	id, err := utils.GenerateID(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate list id: %w", err)
+	}
+
+	now := time.Now()
+	_, err = s.db.Exec(`INSERT INTO user_lists (id, user_id, name, description, is_public, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, id, userID, req.Name, req.Description, req.IsPublic, now, now)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrNameTaken
+		}
+		return nil, fmt.Errorf("failed to create list: %w", err)
+	}
+	return s.GetList(id)
+}
+
+func (s *Store) GetList(id string) (*models.UserList, error) {
+	l, err := scanList(s.db.QueryRow(`SELECT `+listColumns+` `+listFrom+` WHERE l.id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrListNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load list: %w", err)
+	}
+	return l, nil
+}
+
+// ListsFor returns a user's lists by name, only the public ones unless includePrivate
+func (s *Store) ListsFor(userID string, includePrivate bool) ([]models.UserList, error) {
+	where := ` WHERE l.user_id = ?`
+	if !includePrivate {
+		where += ` AND l.is_public = 1`
+	}
+
+	rows, err := s.db.Query(`SELECT `+listColumns+` `+listFrom+where+` ORDER BY l.name COLLATE NOCASE`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lists: %w", err)
+	}
+	defer rows.Close()
+
+	lists := []models.UserList{}
+	for rows.Next() {
+		l, err := scanList(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan list: %w", err)
+		}
+		lists = append(lists, *l)
+	}
+	return lists, rows.Err()
+}
+
+func (s *Store) UpdateList(id string, req models.UpdateListRequest) (*models.UserList, error) {
+	query := `UPDATE user_lists SET updated_at = ?`
+	args := []interface{}{time.Now()}
+	if req.Name != nil {
+		query += `, name = ?`
+		args = append(args, *req.Name)
+	}
+	if req.Description != nil {
+		query += `, description = ?`
+		args = append(args, *req.Description)
+	}
+	if req.IsPublic != nil {
+		query += `, is_public = ?`
+		args = append(args, *req.IsPublic)
+	}
+	query += ` WHERE id = ?`
+	args = append(args, id)
+
+	res, err := s.db.Exec(query, args...)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrNameTaken
+		}
+		return nil, fmt.Errorf("failed to update list: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrListNotFound
+	}
+	return s.GetList(id)
+}
+
+func (s *Store) DeleteList(id string) error {
+	res, err := s.db.Exec(`DELETE FROM user_lists WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete list: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrListNotFound
+	}
+	return nil
+}
+
+// Items returns a list's manga in order
+func (s *Store) Items(listID string) ([]models.ListItem, error) {
+	rows, err := s.db.Query(`SELECT i.position, i.added_at, m.id, m.title, COALESCE(m.author, ''), COALESCE(m.genres, ''),
+		COALESCE(m.status, ''), COALESCE(m.total_chapters, 0), COALESCE(m.cover_url, ''), COALESCE(m.media_type, '')
+		FROM user_list_items i
+		JOIN manga m ON m.id = i.manga_id
+		WHERE i.list_id = ?
+		ORDER BY i.position`, listID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.ListItem{}
+	for rows.Next() {
+		var item models.ListItem
+		var genres string
+		m := &item.Manga
+		if err := rows.Scan(&item.Position, &item.AddedAt, &m.ID, &m.Title, &m.Author, &genres,
+			&m.Status, &m.TotalChapters, &m.CoverURL, &m.MediaType); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		if genres != "" {
+			json.Unmarshal([]byte(genres), &m.Genres)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// AddItem inserts a manga at position, appending when position is 0 or past the end
+func (s *Store) AddItem(listID, mangaID string, position int) (*models.UserList, error) {
+	var exists bool
+	if err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM manga WHERE id = ?)`, mangaID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check manga: %w", err)
+	}
+	if !exists {
+		return nil, ErrMangaNotFound
+	}
+
+	err := s.withItems(listID, func(tx *sql.Tx, count int) error {
+		if position <= 0 || position > count+1 {
+			position = count + 1
+		}
+		if _, err := tx.Exec(`UPDATE user_list_items SET position = position + 1
+			WHERE list_id = ? AND position >= ?`, listID, position); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO user_list_items (list_id, manga_id, position, added_at) VALUES (?, ?, ?, ?)`,
+			listID, mangaID, position, time.Now())
+		if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrAlreadyInList
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetList(listID)
+}
+
+func (s *Store) RemoveItem(listID, mangaID string) error {
+	return s.withItems(listID, func(tx *sql.Tx, count int) error {
+		position, err := itemPosition(tx, listID, mangaID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM user_list_items WHERE list_id = ? AND manga_id = ?`, listID, mangaID); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE user_list_items SET position = position - 1
+			WHERE list_id = ? AND position > ?`, listID, position)
+		return err
+	})
+}
+
+// MoveItem moves a manga to position, clamped to the list's length
+func (s *Store) MoveItem(listID, mangaID string, position int) error {
+	return s.withItems(listID, func(tx *sql.Tx, count int) error {
+		from, err := itemPosition(tx, listID, mangaID)
+		if err != nil {
+			return err
+		}
+		if position < 1 {
+			position = 1
+		}
+		if position > count {
+			position = count
+		}
+
+		switch {
+		case position < from:
+			_, err = tx.Exec(`UPDATE user_list_items SET position = position + 1
+				WHERE list_id = ? AND position >= ? AND position < ?`, listID, position, from)
+		case position > from:
+			_, err = tx.Exec(`UPDATE user_list_items SET position = position - 1
+				WHERE list_id = ? AND position > ? AND position <= ?`, listID, from, position)
+		default:
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE user_list_items SET position = ? WHERE list_id = ? AND manga_id = ?`,
+			position, listID, mangaID)
+		return err
+	})
+}
+
+// withItems runs fn in a transaction with the list's current item count and
+// bumps the list's update time if fn succeeds
+func (s *Store) withItems(listID string, fn func(tx *sql.Tx, count int) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE user_lists SET updated_at = ? WHERE id = ?`, time.Now(), listID)
+	if err != nil {
+		return fmt.Errorf("failed to update list: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrListNotFound
+	}
+
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM user_list_items WHERE list_id = ?`, listID).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count items: %w", err)
+	}
+	if err := fn(tx, count); err != nil {
+		if errors.Is(err, ErrItemNotFound) || errors.Is(err, ErrAlreadyInList) {
+			return err
+		}
+		return fmt.Errorf("failed to update items: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit items: %w", err)
+	}
+	return nil
+}
+
+func itemPosition(tx *sql.Tx, listID, mangaID string) (int, error) {
+	var position int
+	err := tx.QueryRow(`SELECT position FROM user_list_items WHERE list_id = ? AND manga_id = ?`, listID, mangaID).Scan(&position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrItemNotFound
+	}
+	return position, err
+}
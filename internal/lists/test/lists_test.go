@@ -0,0 +1,208 @@
+package lists_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/lists"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+func setupStore(t *testing.T) *lists.Store {
+	if err := database.InitDatabase(t.TempDir() + "/lists.db"); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	_, err := database.DB.Exec(`INSERT INTO users (id, username, email, password_hash)
+		VALUES ('user-1', 'reader', 'reader@example.com', 'hash'),
+		       ('user-2', 'other', 'other@example.com', 'hash')`)
+	if err != nil {
+		t.Fatalf("failed to insert users: %v", err)
+	}
+	_, err = database.DB.Exec(`INSERT INTO manga (id, title, author) VALUES
+		('m1', 'Berserk', 'Kentaro Miura'), ('m2', 'Vagabond', 'Takehiko Inoue'),
+		('m3', 'Monster', 'Naoki Urasawa'), ('m4', 'Pluto', 'Naoki Urasawa')`)
+	if err != nil {
+		t.Fatalf("failed to insert manga: %v", err)
+	}
+	return lists.NewStore(database.DB)
+}
+
+func order(t *testing.T, store *lists.Store, listID string) []string {
+	items, err := store.Items(listID)
+	if err != nil {
+		t.Fatalf("failed to load items: %v", err)
+	}
+	ids := make([]string, len(items))
+	for i, item := range items {
+		if item.Position != i+1 {
+			t.Errorf("item %s has position %d, want %d", item.Manga.ID, item.Position, i+1)
+		}
+		ids[i] = item.Manga.ID
+	}
+	return ids
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestListItemsStayOrdered(t *testing.T) {
+	store := setupStore(t)
+	list, err := store.CreateList("user-1", models.CreateListRequest{Name: "Seinen"})
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	if _, err := store.CreateList("user-1", models.CreateListRequest{Name: "Seinen"}); err != lists.ErrNameTaken {
+		t.Errorf("expected ErrNameTaken, got %v", err)
+	}
+
+	for _, id := range []string{"m1", "m2", "m3"} {
+		if _, err := store.AddItem(list.ID, id, 0); err != nil {
+			t.Fatalf("add %s: %v", id, err)
+		}
+	}
+	updated, err := store.AddItem(list.ID, "m4", 1)
+	if err != nil || updated.ItemCount != 4 {
+		t.Fatalf("insert at top: %+v (err %v)", updated, err)
+	}
+	if got := order(t, store, list.ID); !equal(got, []string{"m4", "m1", "m2", "m3"}) {
+		t.Fatalf("after insert: %v", got)
+	}
+	if _, err := store.AddItem(list.ID, "m1", 0); err != lists.ErrAlreadyInList {
+		t.Errorf("expected ErrAlreadyInList, got %v", err)
+	}
+	if got := order(t, store, list.ID); len(got) != 4 {
+		t.Errorf("failed insert changed the list: %v", got)
+	}
+
+	if err := store.MoveItem(list.ID, "m4", 3); err != nil {
+		t.Fatalf("move down: %v", err)
+	}
+	if got := order(t, store, list.ID); !equal(got, []string{"m1", "m2", "m4", "m3"}) {
+		t.Errorf("after moving down: %v", got)
+	}
+	if err := store.MoveItem(list.ID, "m3", 1); err != nil {
+		t.Fatalf("move up: %v", err)
+	}
+	if got := order(t, store, list.ID); !equal(got, []string{"m3", "m1", "m2", "m4"}) {
+		t.Errorf("after moving up: %v", got)
+	}
+	if err := store.MoveItem(list.ID, "m3", 99); err != nil {
+		t.Fatalf("move past end: %v", err)
+	}
+	if got := order(t, store, list.ID); !equal(got, []string{"m1", "m2", "m4", "m3"}) {
+		t.Errorf("after moving past the end: %v", got)
+	}
+
+	if err := store.RemoveItem(list.ID, "m2"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if got := order(t, store, list.ID); !equal(got, []string{"m1", "m4", "m3"}) {
+		t.Errorf("after remove: %v", got)
+	}
+	if err := store.RemoveItem(list.ID, "m2"); err != lists.ErrItemNotFound {
+		t.Errorf("expected ErrItemNotFound, got %v", err)
+	}
+
+	if err := store.DeleteList(list.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	var remaining int
+	database.DB.QueryRow(`SELECT COUNT(*) FROM user_list_items WHERE list_id = ?`, list.ID).Scan(&remaining)
+	if remaining != 0 {
+		t.Errorf("expected items deleted with the list, %d left", remaining)
+	}
+}
+
+func do(t *testing.T, router *gin.Engine, method, path, user, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-User", user)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestListVisibilityAndOwnership(t *testing.T) {
+	store := setupStore(t)
+	gin.SetMode(gin.TestMode)
+	h := lists.NewHandler(store)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", c.GetHeader("X-Test-User")) })
+	users := router.Group("/users")
+	users.GET("/lists", h.GetLists)
+	users.POST("/lists", h.CreateList)
+	users.GET("/lists/:list_id", h.GetList)
+	users.PUT("/lists/:list_id", h.UpdateList)
+	users.POST("/lists/:list_id/items", h.AddItem)
+	users.PUT("/lists/:list_id/items/:manga_id", h.MoveItem)
+
+	if w := do(t, router, "POST", "/users/lists", "user-1", `{"name": "   "}`); w.Code != http.StatusBadRequest {
+		t.Errorf("blank name status = %d, want 400", w.Code)
+	}
+	w := do(t, router, "POST", "/users/lists", "user-1", `{"name": " Favorites ", "description": "best of"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d (%s)", w.Code, w.Body.String())
+	}
+	var list models.UserList
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if list.Name != "Favorites" || list.IsPublic || list.Username != "reader" {
+		t.Errorf("unexpected list %+v", list)
+	}
+
+	path := "/users/lists/" + list.ID
+	if w := do(t, router, "POST", path+"/items", "user-1", `{"manga_id": "m1"}`); w.Code != http.StatusCreated {
+		t.Fatalf("add status = %d (%s)", w.Code, w.Body.String())
+	}
+	if w := do(t, router, "POST", path+"/items", "user-1", `{"manga_id": "missing"}`); w.Code != http.StatusNotFound {
+		t.Errorf("unknown manga status = %d, want 404", w.Code)
+	}
+
+	// Private lists are invisible to others, public ones read-only
+	if w := do(t, router, "GET", path, "user-2", ""); w.Code != http.StatusNotFound {
+		t.Errorf("private list status for other user = %d, want 404", w.Code)
+	}
+	if w := do(t, router, "PUT", path, "user-1", `{"is_public": true}`); w.Code != http.StatusOK {
+		t.Fatalf("make public status = %d (%s)", w.Code, w.Body.String())
+	}
+	w = do(t, router, "GET", path, "user-2", "")
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || !list.IsPublic || len(list.Items) != 1 || list.Items[0].Manga.Title != "Berserk" {
+		t.Errorf("public list for other user: %d %+v", w.Code, list)
+	}
+	if w := do(t, router, "POST", path+"/items", "user-2", `{"manga_id": "m2"}`); w.Code != http.StatusForbidden {
+		t.Errorf("other user add status = %d, want 403", w.Code)
+	}
+	if w := do(t, router, "PUT", path+"/items/m2", "user-1", `{"position": 1}`); w.Code != http.StatusNotFound {
+		t.Errorf("move of missing item status = %d, want 404", w.Code)
+	}
+
+	var mine struct {
+		Lists []models.UserList `json:"lists"`
+		Count int               `json:"count"`
+	}
+	json.Unmarshal(do(t, router, "GET", "/users/lists", "user-1", "").Body.Bytes(), &mine)
+	if mine.Count != 1 || mine.Lists[0].ItemCount != 1 {
+		t.Errorf("unexpected lists %+v", mine)
+	}
+	json.Unmarshal(do(t, router, "GET", "/users/lists", "user-2", "").Body.Bytes(), &mine)
+	if mine.Count != 0 {
+		t.Errorf("other user should have no lists, got %+v", mine)
+	}
+}
@@ -0,0 +1,254 @@
+package manga
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/metrics"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+// AniListSource searches AniList's public GraphQL API. No key is needed.
+type AniListSource struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewAniListSource() *AniListSource {
+	return &AniListSource{
+		BaseURL: "https://graphql.anilist.co",
+		Client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: metrics.InstrumentTransport("anilist", http.DefaultTransport),
+		},
+	}
+}
+
+const aniListMediaFields = `id idMal format status chapters volumes genres averageScore
+	description(asHtml: false)
+	title { romaji english native }
+	coverImage { large medium }
+	startDate { year month day }
+	endDate { year month day }
+	staff(perPage: 4) { edges { role node { name { full } } } }`
+
+type aniListDate struct {
+	Year  int `json:"year"`
+	Month int `json:"month"`
+	Day   int `json:"day"`
+}
+
+type aniListMedia struct {
+	ID           int      `json:"id"`
+	IDMal        int      `json:"idMal"`
+	Format       string   `json:"format"`
+	Status       string   `json:"status"`
+	Chapters     int      `json:"chapters"`
+	Volumes      int      `json:"volumes"`
+	Genres       []string `json:"genres"`
+	AverageScore int      `json:"averageScore"`
+	Description  string   `json:"description"`
+	Title        struct {
+		Romaji  string `json:"romaji"`
+		English string `json:"english"`
+		Native  string `json:"native"`
+	} `json:"title"`
+	CoverImage struct {
+		Large  string `json:"large"`
+		Medium string `json:"medium"`
+	} `json:"coverImage"`
+	StartDate aniListDate `json:"startDate"`
+	EndDate   aniListDate `json:"endDate"`
+	Staff     struct {
+		Edges []struct {
+			Role string `json:"role"`
+			Node struct {
+				Name struct {
+					Full string `json:"full"`
+				} `json:"name"`
+			} `json:"node"`
+		} `json:"edges"`
+	} `json:"staff"`
+}
+
+func (a *AniListSource) Search(ctx context.Context, query string, limit, offset int) ([]models.Manga, error) {
+	// AniList pages rather than offsets, at most 50 per page. An offset that
+	// is not a multiple of the page size falls inside a page, so the window
+	// can span two of them.
+	if limit <= 0 || limit > 50 {
+		limit = 50
+	}
+	page, skip := offset/limit+1, offset%limit
+
+	var media []aniListMedia
+	for len(media) < skip+limit {
+		batch, err := a.searchPage(ctx, query, page, limit)
+		if err != nil {
+			return nil, err
+		}
+		media = append(media, batch...)
+		if len(batch) < limit {
+			break
+		}
+		page++
+	}
+	if skip > len(media) {
+		skip = len(media)
+	}
+	media = media[skip:]
+	if len(media) > limit {
+		media = media[:limit]
+	}
+
+	out := make([]models.Manga, 0, len(media))
+	for _, m := range media {
+		out = append(out, convertAniListToManga(m))
+	}
+	return out, nil
+}
+
+func (a *AniListSource) searchPage(ctx context.Context, query string, page, perPage int) ([]aniListMedia, error) {
+	gql := `query ($search: String, $page: Int, $perPage: Int) {
+		Page(page: $page, perPage: $perPage) { media(search: $search, type: MANGA) { ` + aniListMediaFields + ` } }
+	}`
+	vars := map[string]interface{}{"search": query, "page": page, "perPage": perPage}
+
+	var r struct {
+		Page struct {
+			Media []aniListMedia `json:"media"`
+		} `json:"Page"`
+	}
+	if err := a.query(ctx, gql, vars, &r); err != nil {
+		return nil, err
+	}
+	return r.Page.Media, nil
+}
+
+func (a *AniListSource) GetMangaByID(ctx context.Context, id string) (*models.Manga, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(id, "anilist:"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid AniList ID %q", id)
+	}
+	gql := `query ($id: Int) { Media(id: $id, type: MANGA) { ` + aniListMediaFields + ` } }`
+
+	var r struct {
+		Media aniListMedia `json:"Media"`
+	}
+	if err := a.query(ctx, gql, map[string]interface{}{"id": n}, &r); err != nil {
+		return nil, err
+	}
+	manga := convertAniListToManga(r.Media)
+	return &manga, nil
+}
+
+func (a *AniListSource) query(ctx context.Context, gql string, vars map[string]interface{}, out interface{}) error {
+	body, _ := json.Marshal(map[string]interface{}{"query": gql, "variables": vars})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, a.BaseURL, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "MangaHub/1.0")
+
+	res, err := a.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("AniList API request failed: %s", res.Status)
+	}
+
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&envelope); err != nil {
+		return err
+	}
+	if len(envelope.Errors) > 0 {
+		return fmt.Errorf("AniList API error: %s", envelope.Errors[0].Message)
+	}
+	return json.Unmarshal(envelope.Data, out)
+}
+
+func convertAniListToManga(m aniListMedia) models.Manga {
+	title := m.Title.English
+	if title == "" {
+		title = m.Title.Romaji
+	}
+
+	author := ""
+	authors := []map[string]interface{}{}
+	for _, e := range m.Staff.Edges {
+		authors = append(authors, map[string]interface{}{"name": e.Node.Name.Full, "role": e.Role})
+		if author == "" && strings.HasPrefix(e.Role, "Story") {
+			author = e.Node.Name.Full
+		}
+	}
+	if author == "" && len(m.Staff.Edges) > 0 {
+		author = m.Staff.Edges[0].Node.Name.Full
+	}
+
+	status := strings.ToLower(m.Status)
+	switch m.Status {
+	case "FINISHED":
+		status = "completed"
+	case "RELEASING":
+		status = "ongoing"
+	case "NOT_YET_RELEASED":
+		status = "upcoming"
+	}
+
+	coverURL := m.CoverImage.Large
+	if coverURL == "" {
+		coverURL = m.CoverImage.Medium
+	}
+
+	externalIDs := map[string]string{"anilist": strconv.Itoa(m.ID)}
+	if m.IDMal > 0 {
+		externalIDs["mal"] = strconv.Itoa(m.IDMal)
+	}
+
+	return models.Manga{
+		ID:            "anilist:" + strconv.Itoa(m.ID),
+		ExternalIDs:   externalIDs,
+		Title:         title,
+		Author:        author,
+		Genres:        append([]string{}, m.Genres...),
+		Status:        status,
+		TotalChapters: m.Chapters,
+		Description:   m.Description,
+		CoverURL:      coverURL,
+		AlternativeTitles: map[string]interface{}{
+			"en": m.Title.English,
+			"ja": m.Title.Native,
+		},
+		StartDate:  m.StartDate.String(),
+		EndDate:    m.EndDate.String(),
+		Mean:       float64(m.AverageScore) / 10,
+		MediaType:  strings.ToLower(m.Format),
+		NumVolumes: m.Volumes,
+		Authors:    authors,
+	}
+}
+
+// String formats the date like MAL does, dropping unknown parts
+func (d aniListDate) String() string {
+	switch {
+	case d.Year == 0:
+		return ""
+	case d.Month == 0:
+		return fmt.Sprintf("%04d", d.Year)
+	case d.Day == 0:
+		return fmt.Sprintf("%04d-%02d", d.Year, d.Month)
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
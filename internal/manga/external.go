@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/metrics"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
 )
 
@@ -37,7 +38,7 @@ func NewMangaDexSource() *MangaDexSource {
 		ClientID: strings.TrimSpace(os.Getenv("MANGADEX_CLIENT_ID")),
 		Client: &http.Client{
 			Timeout: 10 * time.Second,
-			Transport: &http.Transport{
+			Transport: metrics.InstrumentTransport("mangadex", &http.Transport{
 				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
 					// Use custom DNS resolver (Google DNS) to bypass hosts file
 					dialer := &net.Dialer{
@@ -51,7 +52,7 @@ func NewMangaDexSource() *MangaDexSource {
 					}
 					return dialer.DialContext(ctx, "tcp4", addr)
 				},
-			},
+			}),
 		},
 	}
 }
@@ -67,6 +68,7 @@ type mangaDexSearchResponse struct {
 			Status        string              `json:"status"`
 			Year          int                 `json:"year"`
 			ContentRating string              `json:"contentRating"`
+			Links         map[string]string   `json:"links"`
 			Tags          []struct {
 				Attributes struct {
 					Name map[string]string `json:"name"`
@@ -154,6 +156,7 @@ func (m *MangaDexSource) GetMangaByID(ctx context.Context, id string) (*models.M
 				Status        string              `json:"status"`
 				Year          int                 `json:"year"`
 				ContentRating string              `json:"contentRating"`
+				Links         map[string]string   `json:"links"`
 				Tags          []struct {
 					Attributes struct {
 						Name map[string]string `json:"name"`
@@ -191,6 +194,7 @@ func convertMangaDexToManga(data interface{}) models.Manga {
 			Status        string              `json:"status"`
 			Year          int                 `json:"year"`
 			ContentRating string              `json:"contentRating"`
+			Links         map[string]string   `json:"links"`
 			Tags          []struct {
 				Attributes struct {
 					Name map[string]string `json:"name"`
@@ -281,9 +285,18 @@ func convertMangaDexToManga(data interface{}) models.Manga {
 		}
 	}
 
+	// MangaDex links other trackers by their site IDs: mal, al (AniList), kt (Kitsu)
+	externalIDs := map[string]string{"mangadex": id}
+	for key, name := range map[string]string{"mal": "mal", "al": "anilist", "kt": "kitsu"} {
+		if v := d.Attributes.Links[key]; v != "" && isNumericID(v) {
+			externalIDs[name] = v
+		}
+	}
+
 	return models.Manga{
 		ID:                id,
 		MangaDexID:        mangaDexID,
+		ExternalIDs:       externalIDs,
 		Title:             title,
 		Author:            author,
 		Genres:            genres,
@@ -303,9 +316,14 @@ type Chapter struct {
 	Pages      int    `json:"pages"`
 	Volume     string `json:"volume"`
 	Language   string `json:"language"`
+	Group      string `json:"group"`
+	PublishAt  string `json:"publishAt"`
 	ReadableAt string `json:"readableAt"`
 }
 
+// maxChapterFeedPage is the largest page the MangaDex feed returns
+const maxChapterFeedPage = 500
+
 // GetChapters fetches chapters for a manga from MangaDex
 func (m *MangaDexSource) GetChapters(ctx context.Context, mangaDexID string, language string, limit int) ([]Chapter, error) {
 	if limit <= 0 {
@@ -314,6 +332,29 @@ func (m *MangaDexSource) GetChapters(ctx context.Context, mangaDexID string, lan
 	if limit > 200 {
 		limit = 200
 	}
+	chapters, _, err := m.getChapterPage(ctx, mangaDexID, language, limit, 0)
+	return chapters, err
+}
+
+// GetAllChapters pages through the whole MangaDex feed for a manga, up to maxChapters
+func (m *MangaDexSource) GetAllChapters(ctx context.Context, mangaDexID string, language string, maxChapters int) ([]Chapter, error) {
+	var all []Chapter
+	for offset := 0; offset < maxChapters; offset += maxChapterFeedPage {
+		page, total, err := m.getChapterPage(ctx, mangaDexID, language, maxChapterFeedPage, offset)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if offset+maxChapterFeedPage >= total {
+			break
+		}
+	}
+	return all, nil
+}
+
+// getChapterPage returns one page of the feed and the feed's total size. The
+// total counts chapters hosted elsewhere, which are left out of the page.
+func (m *MangaDexSource) getChapterPage(ctx context.Context, mangaDexID, language string, limit, offset int) ([]Chapter, int, error) {
 	if language == "" {
 		language = "en"
 	}
@@ -322,7 +363,9 @@ func (m *MangaDexSource) GetChapters(ctx context.Context, mangaDexID string, lan
 	qs := u.Query()
 	qs.Add("translatedLanguage[]", language)
 	qs.Set("limit", fmt.Sprintf("%d", limit))
+	qs.Set("offset", fmt.Sprintf("%d", offset))
 	qs.Set("order[chapter]", "asc")
+	qs.Add("includes[]", "scanlation_group")
 	// Allow all ratings so mature titles (e.g., Berserk marked as erotica) are not filtered out
 	qs.Add("contentRating[]", "safe")
 	qs.Add("contentRating[]", "suggestive")
@@ -335,12 +378,12 @@ func (m *MangaDexSource) GetChapters(ctx context.Context, mangaDexID string, lan
 
 	res, err := m.Client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("MangaDex API request failed: %s", res.Status)
+		return nil, 0, fmt.Errorf("MangaDex API request failed: %s", res.Status)
 	}
 
 	var response struct {
@@ -353,19 +396,34 @@ func (m *MangaDexSource) GetChapters(ctx context.Context, mangaDexID string, lan
 				Pages          int    `json:"pages"`
 				Volume         string `json:"volume"`
 				TranslatedLang string `json:"translatedLanguage"`
+				PublishAt      string `json:"publishAt"`
 				ReadableAt     string `json:"readableAt"`
 			} `json:"attributes"`
+			Relationships []struct {
+				Type       string `json:"type"`
+				Attributes struct {
+					Name string `json:"name"`
+				} `json:"attributes"`
+			} `json:"relationships"`
 		} `json:"data"`
+		Total int `json:"total"`
 	}
 
 	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	chapters := make([]Chapter, 0, len(response.Data))
 	for _, d := range response.Data {
 		// Only include chapters that have actual pages available
 		if d.Attributes.Pages > 0 {
+			group := ""
+			for _, rel := range d.Relationships {
+				if rel.Type == "scanlation_group" && rel.Attributes.Name != "" {
+					group = rel.Attributes.Name
+					break
+				}
+			}
 			chapters = append(chapters, Chapter{
 				ID:         d.ID,
 				Chapter:    d.Attributes.Chapter,
@@ -373,12 +431,77 @@ func (m *MangaDexSource) GetChapters(ctx context.Context, mangaDexID string, lan
 				Pages:      d.Attributes.Pages,
 				Volume:     d.Attributes.Volume,
 				Language:   d.Attributes.TranslatedLang,
+				Group:      group,
+				PublishAt:  d.Attributes.PublishAt,
 				ReadableAt: d.Attributes.ReadableAt,
 			})
 		}
 	}
 
-	return chapters, nil
+	return chapters, response.Total, nil
+}
+
+// GetChapter fetches a single chapter's details from MangaDex
+func (m *MangaDexSource) GetChapter(ctx context.Context, chapterID string) (*Chapter, error) {
+	u, _ := url.Parse(fmt.Sprintf("%s/chapter/%s", m.BaseURL, chapterID))
+	qs := u.Query()
+	qs.Add("includes[]", "scanlation_group")
+	u.RawQuery = qs.Encode()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	req.Header.Set("User-Agent", "MangaHub/1.0")
+
+	res, err := m.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("MangaDex API request failed: %s", res.Status)
+	}
+
+	var response struct {
+		Data struct {
+			ID         string `json:"id"`
+			Attributes struct {
+				Chapter        string `json:"chapter"`
+				Title          string `json:"title"`
+				Pages          int    `json:"pages"`
+				Volume         string `json:"volume"`
+				TranslatedLang string `json:"translatedLanguage"`
+				PublishAt      string `json:"publishAt"`
+			} `json:"attributes"`
+			Relationships []struct {
+				Type       string `json:"type"`
+				Attributes struct {
+					Name string `json:"name"`
+				} `json:"attributes"`
+			} `json:"relationships"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+
+	d := response.Data
+	ch := &Chapter{
+		ID:        d.ID,
+		Chapter:   d.Attributes.Chapter,
+		Title:     d.Attributes.Title,
+		Pages:     d.Attributes.Pages,
+		Volume:    d.Attributes.Volume,
+		Language:  d.Attributes.TranslatedLang,
+		PublishAt: d.Attributes.PublishAt,
+	}
+	for _, rel := range d.Relationships {
+		if rel.Type == "scanlation_group" && rel.Attributes.Name != "" {
+			ch.Group = rel.Attributes.Name
+			break
+		}
+	}
+	return ch, nil
 }
 
 // ChapterPages represents the page URLs for a chapter
@@ -436,7 +559,10 @@ func NewMALSource() *MALSource {
 	return &MALSource{
 		BaseURL:  "https://api.myanimelist.net/v2",
 		ClientID: strings.TrimSpace(os.Getenv("MAL_CLIENT_ID")),
-		Client:   &http.Client{Timeout: 10 * time.Second},
+		Client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: metrics.InstrumentTransport("mal", http.DefaultTransport),
+		},
 	}
 }
 
@@ -699,6 +825,7 @@ func convertMALDetailToManga(r malMangaDetailRes) models.Manga {
 		}
 	}
 	manga.MangaDexID = fetchMangaDexIDFromCandidates(candidates)
+	manga.ExternalIDs = malExternalIDs(manga)
 
 	return manga
 }
@@ -771,10 +898,19 @@ func convertMALToManga(id int, title string, mainPicture interface{}, altTitles
 
 	// Fetch MangaDex ID using primary title
 	manga.MangaDexID = fetchMangaDexIDFromCandidates([]string{title})
+	manga.ExternalIDs = malExternalIDs(manga)
 
 	return manga
 }
 
+func malExternalIDs(m models.Manga) map[string]string {
+	ids := map[string]string{"mal": m.ID}
+	if m.MangaDexID != "" {
+		ids["mangadex"] = m.MangaDexID
+	}
+	return ids
+}
+
 func fetchMangaDexIDFromCandidates(candidates []string) string {
 	// Normalize and dedupe candidates
 	seen := map[string]struct{}{}
@@ -911,10 +1047,12 @@ func levenshtein(a, b string) int {
 	return prev[len(b)]
 }
 
+// NewExternalSourceFromEnv returns the registry of sources enabled in the
+// config file, failing when none of them can search or look manga up
 func NewExternalSourceFromEnv() (ExternalSource, error) {
-	clientID := strings.TrimSpace(os.Getenv("MAL_CLIENT_ID"))
-	if clientID == "" {
-		return nil, fmt.Errorf("MAL_CLIENT_ID is required in environment")
+	registry := DefaultRegistry()
+	if !registry.Supports(CapabilitySearch) && !registry.Supports(CapabilityDetails) {
+		return nil, fmt.Errorf("no manga source enabled (MAL needs MAL_CLIENT_ID in environment)")
 	}
-	return NewMALSource(), nil
+	return registry, nil
 }
@@ -2,8 +2,8 @@ package manga
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -14,7 +14,11 @@ import (
 	"sync"
 	"time"
 
-	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/sse"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/udp"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/config"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
 	"github.com/gin-gonic/gin"
@@ -22,7 +26,9 @@ import (
 
 type Handler struct {
 	externalSource ExternalSource
-	broker         *NotificationBroker
+	sources        *Registry
+	broker         *sse.Broker
+	bridge         *bridge.UnifiedBridge
 }
 
 // Helper function to parse query parameters as integers
@@ -140,21 +146,60 @@ type RankingList struct {
 }
 
 func NewHandler() *Handler {
-	source, err := NewExternalSourceFromEnv()
-	broker := NewBroker()
-	if err != nil {
-		return &Handler{
-			broker: broker,
+	h := &Handler{sources: DefaultRegistry()}
+	if source, err := NewExternalSourceFromEnv(); err == nil {
+		h.externalSource = source
+	}
+	return h
+}
+
+// rankingClientID is the MAL client ID when MAL is enabled, as rankings
+// only come from MAL
+func (h *Handler) rankingClientID() string {
+	if h.sources == nil {
+		return ""
+	}
+	for _, p := range h.sources.Providers(CapabilityRankings) {
+		if mal, ok := p.Source.(*MALSource); ok {
+			return mal.ClientID
+		}
+	}
+	return ""
+}
+
+// chapterSource is the enabled source chapters and pages are read from
+func (h *Handler) chapterSource() *MangaDexSource {
+	if h.sources == nil {
+		return nil
+	}
+	for _, p := range h.sources.Providers(CapabilityChapters) {
+		if mangadex, ok := p.Source.(*MangaDexSource); ok {
+			return mangadex
 		}
 	}
-	return &Handler{
-		externalSource: source,
-		broker:         broker,
+	return nil
+}
+
+// SetBroker sets the SSE broker used for manga notifications
+func (h *Handler) SetBroker(broker *sse.Broker) {
+	h.broker = broker
+}
+
+// SetBridge publishes manga notifications to unified bridge subscribers
+func (h *Handler) SetBridge(br *bridge.UnifiedBridge) {
+	h.bridge = br
+}
+
+// publishEvent hands a global manga event to bridge subscribers such as webhooks
+func (h *Handler) publishEvent(eventType bridge.EventType, data map[string]interface{}) {
+	if h.bridge == nil {
+		return
 	}
+	h.bridge.PublishEvent(bridge.NewUnifiedEvent(eventType, "", bridge.ProtocolHTTP, data))
 }
 
 // GetBroker returns the notification broker
-func (h *Handler) GetBroker() *NotificationBroker {
+func (h *Handler) GetBroker() *sse.Broker {
 	return h.broker
 }
 
@@ -225,109 +270,19 @@ func (h *Handler) SearchManga(c *gin.Context) {
 		page = 1
 	}
 
-	// Get total count first
-	countQuery := `SELECT COUNT(*) FROM manga WHERE 1=1`
-	countArgs := []interface{}{}
-
-	if req.Title != "" {
-		countQuery += ` AND title LIKE ?`
-		countArgs = append(countArgs, "%"+req.Title+"%")
-	}
-
-	if req.Author != "" {
-		countQuery += ` AND author LIKE ?`
-		countArgs = append(countArgs, "%"+req.Author+"%")
-	}
-
-	if req.Status != "" {
-		countQuery += ` AND status = ?`
-		countArgs = append(countArgs, req.Status)
-	}
-
-	for _, g := range genreFilters {
-		countQuery += ` AND genres LIKE ?`
-		countArgs = append(countArgs, "%"+g+"%")
-	}
-
-	if normalizedType != "" {
-		countQuery += ` AND media_type = ?`
-		countArgs = append(countArgs, normalizedType)
-	}
-
-	var total int
-	err := database.DB.QueryRow(countQuery, countArgs...).Scan(&total)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
-		return
-	}
-
-	// Calculate offset for the requested page
-	offset := (page - 1) * limit
-
-	query := `SELECT id, title, author, genres, status, total_chapters, description, cover_url, media_type FROM manga WHERE 1=1`
-	args := []interface{}{}
-
-	if req.Title != "" {
-		query += ` AND title LIKE ?`
-		args = append(args, "%"+req.Title+"%")
-	}
-
-	if req.Author != "" {
-		query += ` AND author LIKE ?`
-		args = append(args, "%"+req.Author+"%")
-	}
-
-	if req.Status != "" {
-		query += ` AND status = ?`
-		args = append(args, req.Status)
-	}
-
-	for _, g := range genreFilters {
-		query += ` AND genres LIKE ?`
-		args = append(args, "%"+g+"%")
-	}
-
-	if normalizedType != "" {
-		query += ` AND media_type = ?`
-		args = append(args, normalizedType)
-	}
-
-	query += ` LIMIT ? OFFSET ?`
-	args = append(args, limit, offset)
-
-	rows, err := database.DB.Query(query, args...)
+	mangas, total, err := repository.Default().Manga.Search(c.Request.Context(), repository.MangaFilter{
+		Title:     req.Title,
+		Author:    req.Author,
+		Status:    req.Status,
+		Genres:    genreFilters,
+		MediaType: normalizedType,
+		Limit:     limit,
+		Offset:    (page - 1) * limit,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
 	}
-	defer rows.Close()
-
-	var mangas []models.Manga
-	for rows.Next() {
-		var manga models.Manga
-		var genresJSON string
-
-		err := rows.Scan(
-			&manga.ID,
-			&manga.Title,
-			&manga.Author,
-			&genresJSON,
-			&manga.Status,
-			&manga.TotalChapters,
-			&manga.Description,
-			&manga.CoverURL,
-			&manga.MediaType,
-		)
-		if err != nil {
-			continue
-		}
-
-		if genresJSON != "" {
-			json.Unmarshal([]byte(genresJSON), &manga.Genres)
-		}
-
-		mangas = append(mangas, manga)
-	}
 
 	pagination := calculatePagination(page, limit, total)
 
@@ -386,9 +341,9 @@ func (h *Handler) SearchExternal(c *gin.Context) {
 
 	// If no query provided but type is provided, fall back to ranking-by-type search
 	if query == "" {
-		clientID := os.Getenv("MAL_CLIENT_ID")
+		clientID := h.rankingClientID()
 		if clientID == "" {
-			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MAL API not configured"})
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No ranking source enabled (MAL API not configured)"})
 			return
 		}
 
@@ -634,9 +589,8 @@ func (h *Handler) GetMangaInfo(c *gin.Context) {
 	}
 
 	// First check if manga exists in local database
-	var exists bool
-	checkQuery := `SELECT EXISTS(SELECT 1 FROM manga WHERE id = ?)`
-	err := database.DB.QueryRow(checkQuery, mangaID).Scan(&exists)
+	mangaRepo := repository.Default().Manga
+	exists, err := mangaRepo.Exists(c.Request.Context(), mangaID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check manga existence"})
 		return
@@ -647,59 +601,22 @@ func (h *Handler) GetMangaInfo(c *gin.Context) {
 		return
 	}
 
-	// Decide source: UUID-style goes to MangaDex, numeric goes to MAL
-	isUUID := func(id string) bool {
-		return len(id) == 36 && strings.Count(id, "-") == 4
-	}
-
+	// The registry routes the ID to its source: numeric to MAL, UUIDs to
+	// MangaDex, prefixed IDs ("anilist:30013") to the named source
 	ctx := context.Background()
 
-	var manga *models.Manga
-
-	if isUUID(mangaID) {
-		mangadex := NewMangaDexSource()
-		manga, err = mangadex.GetMangaByID(ctx, mangaID)
-		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-	} else {
-		// Fallback to MAL (numeric IDs)
-		for _, ch := range mangaID {
-			if ch < '0' || ch > '9' {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Manga ID must be numeric or a MangaDex UUID"})
-				return
-			}
-		}
-
-		manga, err = h.externalSource.GetMangaByID(ctx, mangaID)
-		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
+	manga, err := h.externalSource.GetMangaByID(ctx, mangaID)
+	if errors.Is(err, ErrUnknownSourceID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Manga ID does not belong to an enabled source"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
 	}
 
 	// Add local rating statistics to external manga data
-	ratingQuery := `
-		SELECT 
-			ROUND(AVG(user_rating), 1) as average_rating,
-			COUNT(*) as total_ratings,
-			COUNT(CASE WHEN ROUND(user_rating) = 5 THEN 1 END) as rating_5,
-			COUNT(CASE WHEN ROUND(user_rating) = 4 THEN 1 END) as rating_4,
-			COUNT(CASE WHEN ROUND(user_rating) = 3 THEN 1 END) as rating_3,
-			COUNT(CASE WHEN ROUND(user_rating) = 2 THEN 1 END) as rating_2,
-			COUNT(CASE WHEN ROUND(user_rating) = 1 THEN 1 END) as rating_1
-		FROM user_progress
-		WHERE manga_id = ? AND user_rating IS NOT NULL
-	`
-
-	var avgRating sql.NullFloat64
-	var totalCount int
-	var r5, r4, r3, r2, r1 int
-
-	err = database.DB.QueryRow(ratingQuery, mangaID).Scan(
-		&avgRating, &totalCount, &r5, &r4, &r3, &r2, &r1,
-	)
+	stats, err := mangaRepo.RatingStats(c.Request.Context(), mangaID)
 
 	// Build response with external manga data + local rating stats
 	response := make(map[string]interface{})
@@ -709,18 +626,8 @@ func (h *Handler) GetMangaInfo(c *gin.Context) {
 	json.Unmarshal(mangaJSON, &response)
 
 	// Add rating_stats if we have ratings
-	if err == nil && totalCount > 0 {
-		response["rating_stats"] = gin.H{
-			"average":     avgRating.Float64,
-			"total_count": totalCount,
-			"distribution": gin.H{
-				"5": r5,
-				"4": r4,
-				"3": r3,
-				"2": r2,
-				"1": r1,
-			},
-		}
+	if err == nil && stats.TotalCount > 0 {
+		response["rating_stats"] = stats
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -729,23 +636,10 @@ func (h *Handler) GetMangaInfo(c *gin.Context) {
 func (h *Handler) GetMangaByID(c *gin.Context) {
 	mangaID := c.Param("id")
 
-	var manga models.Manga
-	var genresJSON string
-
-	query := `SELECT id, title, author, genres, status, total_chapters, description, cover_url FROM manga WHERE id = ?`
-	err := database.DB.QueryRow(query, mangaID).Scan(
-		&manga.ID,
-		&manga.Title,
-		&manga.Author,
-		&genresJSON,
-		&manga.Status,
-		&manga.TotalChapters,
-		&manga.Description,
-		&manga.CoverURL,
-	)
-
+	mangaRepo := repository.Default().Manga
+	manga, err := mangaRepo.Get(c.Request.Context(), mangaID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, repository.ErrMangaNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Manga not found"})
 			return
 		}
@@ -753,32 +647,8 @@ func (h *Handler) GetMangaByID(c *gin.Context) {
 		return
 	}
 
-	// Parse genres JSON
-	if genresJSON != "" {
-		json.Unmarshal([]byte(genresJSON), &manga.Genres)
-	}
-
 	// Get rating statistics
-	ratingQuery := `
-		SELECT 
-			ROUND(AVG(user_rating), 1) as average_rating,
-			COUNT(*) as total_ratings,
-			COUNT(CASE WHEN ROUND(user_rating) = 5 THEN 1 END) as rating_5,
-			COUNT(CASE WHEN ROUND(user_rating) = 4 THEN 1 END) as rating_4,
-			COUNT(CASE WHEN ROUND(user_rating) = 3 THEN 1 END) as rating_3,
-			COUNT(CASE WHEN ROUND(user_rating) = 2 THEN 1 END) as rating_2,
-			COUNT(CASE WHEN ROUND(user_rating) = 1 THEN 1 END) as rating_1
-		FROM user_progress
-		WHERE manga_id = ? AND user_rating IS NOT NULL
-	`
-
-	var avgRating sql.NullFloat64
-	var totalCount int
-	var r5, r4, r3, r2, r1 int
-
-	err = database.DB.QueryRow(ratingQuery, mangaID).Scan(
-		&avgRating, &totalCount, &r5, &r4, &r3, &r2, &r1,
-	)
+	stats, err := mangaRepo.RatingStats(c.Request.Context(), mangaID)
 
 	// Build response with rating stats
 	response := gin.H{
@@ -793,18 +663,8 @@ func (h *Handler) GetMangaByID(c *gin.Context) {
 	}
 
 	// Only include rating_stats if there are ratings
-	if err == nil && totalCount > 0 {
-		response["rating_stats"] = gin.H{
-			"average":     avgRating.Float64,
-			"total_count": totalCount,
-			"distribution": gin.H{
-				"5": r5,
-				"4": r4,
-				"3": r3,
-				"2": r2,
-				"1": r1,
-			},
-		}
+	if err == nil && stats.TotalCount > 0 {
+		response["rating_stats"] = stats
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -823,28 +683,8 @@ func (h *Handler) CreateManga(c *gin.Context) {
 		return
 	}
 
-	genresJSON, err := json.Marshal(manga.Genres)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize genres"})
-		return
-	}
-
-	query := `INSERT INTO manga (id, title, author, genres, status, total_chapters, description, cover_url) 
-	              VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err = database.DB.Exec(
-		query,
-		manga.ID,
-		manga.Title,
-		manga.Author,
-		string(genresJSON),
-		manga.Status,
-		manga.TotalChapters,
-		manga.Description,
-		manga.CoverURL,
-	)
-
-	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+	if err := repository.Default().Manga.Create(c.Request.Context(), &manga); err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
 			c.JSON(http.StatusConflict, gin.H{"error": "Manga with this ID already exists"})
 			return
 		}
@@ -861,45 +701,23 @@ func (h *Handler) CreateManga(c *gin.Context) {
 			"cover":  manga.CoverURL,
 		})
 	}
+	h.publishEvent(bridge.EventMangaCreated, map[string]interface{}{
+		"id":     manga.ID,
+		"title":  manga.Title,
+		"author": manga.Author,
+		"cover":  manga.CoverURL,
+	})
 
 	c.JSON(http.StatusCreated, manga)
 }
 
 // GetAllManga retrieves all manga (for testing purposes)
 func (h *Handler) GetAllManga(c *gin.Context) {
-	query := `SELECT id, title, author, genres, status, total_chapters, description, cover_url FROM manga`
-	rows, err := database.DB.Query(query)
+	mangas, err := repository.Default().Manga.List(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
 	}
-	defer rows.Close()
-
-	var mangas []models.Manga
-	for rows.Next() {
-		var manga models.Manga
-		var genresJSON string
-
-		err := rows.Scan(
-			&manga.ID,
-			&manga.Title,
-			&manga.Author,
-			&genresJSON,
-			&manga.Status,
-			&manga.TotalChapters,
-			&manga.Description,
-			&manga.CoverURL,
-		)
-		if err != nil {
-			continue
-		}
-
-		if genresJSON != "" {
-			json.Unmarshal([]byte(genresJSON), &manga.Genres)
-		}
-
-		mangas = append(mangas, manga)
-	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"mangas": mangas,
@@ -917,13 +735,13 @@ func (h *Handler) RefreshManga(c *gin.Context) {
 	}
 
 	// Load current manga from DB
-	var title string
-	var oldTotal int
-	err := database.DB.QueryRow(`SELECT title, total_chapters FROM manga WHERE id = ?`, mangaID).Scan(&title, &oldTotal)
+	mangaRepo := repository.Default().Manga
+	current, err := mangaRepo.Get(c.Request.Context(), mangaID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "manga not found"})
 		return
 	}
+	title, oldTotal := current.Title, current.TotalChapters
 
 	// Map MAL ID -> MangaDex ID and fetch aggregate chapter count
 	mangadexID := FetchMangaDexID(mangaID)
@@ -940,8 +758,7 @@ func (h *Handler) RefreshManga(c *gin.Context) {
 	// Update DB if increased
 	delta := 0
 	if newTotal > oldTotal {
-		_, updErr := database.DB.Exec(`UPDATE manga SET total_chapters = ? WHERE id = ?`, newTotal, mangaID)
-		if updErr != nil {
+		if updErr := mangaRepo.UpdateTotalChapters(c.Request.Context(), mangaID, newTotal); updErr != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update database"})
 			return
 		}
@@ -957,6 +774,13 @@ func (h *Handler) RefreshManga(c *gin.Context) {
 				"new_chapters": delta,
 			})
 		}
+		h.publishEvent(bridge.EventChapterRelease, map[string]interface{}{
+			"manga_id":     mangaID,
+			"title":        title,
+			"old_total":    oldTotal,
+			"new_total":    newTotal,
+			"new_chapters": delta,
+		})
 
 		// Forward a UDP chapter_release notification to UDP server (global broadcast)
 		// Build payload
@@ -994,43 +818,21 @@ func forwardChapterReleaseToUDP(udpAddr string, data map[string]interface{}) err
 	}
 	defer conn.Close()
 
-	msg := map[string]interface{}{
-		"type":       "notification",
-		"event_type": "chapter_release",
-		"user_id":    "",
-		"data":       data,
-		"timestamp":  time.Now().Format(time.RFC3339),
-	}
-	bytes, _ := json.Marshal(msg)
+	secret, _ := config.LoadJWTSecret()
 	conn.SetWriteDeadline(time.Now().Add(2 * time.Second))
-	_, err = conn.Write(bytes)
+	_, err = conn.Write(udp.CreateSignedNotificationMessage(secret, "", "chapter_release", "", data))
 	return err
 }
 
 // RefreshAllManga updates total_chapters for ALL manga in the database
 func (h *Handler) RefreshAllManga(c *gin.Context) {
 	// Get all manga from DB
-	rows, err := database.DB.Query(`SELECT id, title, total_chapters FROM manga`)
+	mangaRepo := repository.Default().Manga
+	allManga, err := mangaRepo.List(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query manga"})
 		return
 	}
-	defer rows.Close()
-
-	type MangaRow struct {
-		ID       string
-		Title    string
-		OldTotal int
-	}
-
-	var allManga []MangaRow
-	for rows.Next() {
-		var m MangaRow
-		if err := rows.Scan(&m.ID, &m.Title, &m.OldTotal); err != nil {
-			continue
-		}
-		allManga = append(allManga, m)
-	}
 
 	totalProcessed := 0
 	totalUpdated := 0
@@ -1057,21 +859,20 @@ func (h *Handler) RefreshAllManga(c *gin.Context) {
 		}
 
 		// Update if increased
-		if newTotal > manga.OldTotal {
-			_, err := database.DB.Exec(`UPDATE manga SET total_chapters = ? WHERE id = ?`, newTotal, manga.ID)
-			if err != nil {
+		if newTotal > manga.TotalChapters {
+			if err := mangaRepo.UpdateTotalChapters(c.Request.Context(), manga.ID, newTotal); err != nil {
 				totalFailed++
 				continue
 			}
 
-			delta := newTotal - manga.OldTotal
+			delta := newTotal - manga.TotalChapters
 			totalUpdated++
 
 			// Broadcast UDP notification
 			data := map[string]interface{}{
 				"manga_id":  manga.ID,
 				"title":     manga.Title,
-				"old_total": manga.OldTotal,
+				"old_total": manga.TotalChapters,
 				"new_total": newTotal,
 				"delta":     delta,
 			}
@@ -1080,7 +881,7 @@ func (h *Handler) RefreshAllManga(c *gin.Context) {
 			updates = append(updates, map[string]interface{}{
 				"manga_id": manga.ID,
 				"title":    manga.Title,
-				"old":      manga.OldTotal,
+				"old":      manga.TotalChapters,
 				"new":      newTotal,
 				"delta":    delta,
 			})
@@ -1155,9 +956,9 @@ func (h *Handler) fetchRanking(clientID, rankingType string, limit int) ([]Ranki
 }
 
 func (h *Handler) GetFeaturedManga(c *gin.Context) {
-	clientID := os.Getenv("MAL_CLIENT_ID")
+	clientID := h.rankingClientID()
 	if clientID == "" {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "MAL API not configured"})
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No ranking source enabled (MAL API not configured)"})
 		return
 	}
 
@@ -1241,7 +1042,11 @@ func (h *Handler) GetChapters(c *gin.Context) {
 		limit = 200
 	}
 
-	mangadex := NewMangaDexSource()
+	mangadex := h.chapterSource()
+	if mangadex == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No chapter source enabled"})
+		return
+	}
 	ctx := context.Background()
 
 	chapters, err := mangadex.GetChapters(ctx, mangaDexID, language, limit)
@@ -1267,7 +1072,11 @@ func (h *Handler) GetChapterPages(c *gin.Context) {
 		return
 	}
 
-	mangadex := NewMangaDexSource()
+	mangadex := h.chapterSource()
+	if mangadex == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No chapter source enabled"})
+		return
+	}
 	ctx := context.Background()
 
 	pages, err := mangadex.GetChapterPages(ctx, chapterID)
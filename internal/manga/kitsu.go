@@ -0,0 +1,215 @@
+package manga
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/metrics"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+// KitsuSource reads Kitsu's public JSON:API. No key is needed.
+type KitsuSource struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewKitsuSource() *KitsuSource {
+	return &KitsuSource{
+		BaseURL: "https://kitsu.io/api/edge",
+		Client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: metrics.InstrumentTransport("kitsu", http.DefaultTransport),
+		},
+	}
+}
+
+// kitsuMappingSites maps Kitsu's externalSite values to our source names
+var kitsuMappingSites = map[string]string{
+	"myanimelist/manga": "mal",
+	"anilist/manga":     "anilist",
+	"mangadex":          "mangadex",
+}
+
+type kitsuManga struct {
+	ID         string `json:"id"`
+	Attributes struct {
+		CanonicalTitle string            `json:"canonicalTitle"`
+		Titles         map[string]string `json:"titles"`
+		Synopsis       string            `json:"synopsis"`
+		Status         string            `json:"status"`
+		Subtype        string            `json:"subtype"`
+		ChapterCount   int               `json:"chapterCount"`
+		VolumeCount    int               `json:"volumeCount"`
+		AverageRating  string            `json:"averageRating"`
+		StartDate      string            `json:"startDate"`
+		EndDate        string            `json:"endDate"`
+		PosterImage    struct {
+			Large  string `json:"large"`
+			Medium string `json:"medium"`
+		} `json:"posterImage"`
+	} `json:"attributes"`
+	Relationships map[string]struct {
+		Data json.RawMessage `json:"data"`
+	} `json:"relationships"`
+}
+
+type kitsuIncluded struct {
+	Type       string `json:"type"`
+	ID         string `json:"id"`
+	Attributes struct {
+		ExternalSite string `json:"externalSite"`
+		ExternalID   string `json:"externalId"`
+		Title        string `json:"title"`
+	} `json:"attributes"`
+}
+
+func (k *KitsuSource) Search(ctx context.Context, query string, limit, offset int) ([]models.Manga, error) {
+	// Kitsu caps pages at 20
+	if limit <= 0 || limit > 20 {
+		limit = 20
+	}
+	qs := url.Values{}
+	qs.Set("filter[text]", query)
+	qs.Set("page[limit]", strconv.Itoa(limit))
+	qs.Set("page[offset]", strconv.Itoa(offset))
+	qs.Set("include", "mappings,categories")
+
+	var r struct {
+		Data     []kitsuManga    `json:"data"`
+		Included []kitsuIncluded `json:"included"`
+	}
+	if err := k.get(ctx, "/manga?"+qs.Encode(), &r); err != nil {
+		return nil, err
+	}
+
+	out := make([]models.Manga, 0, len(r.Data))
+	for _, d := range r.Data {
+		out = append(out, convertKitsuToManga(d, r.Included))
+	}
+	return out, nil
+}
+
+func (k *KitsuSource) GetMangaByID(ctx context.Context, id string) (*models.Manga, error) {
+	id = strings.TrimPrefix(id, "kitsu:")
+	if !isNumericID(id) {
+		return nil, fmt.Errorf("invalid Kitsu ID %q", id)
+	}
+
+	var r struct {
+		Data     kitsuManga      `json:"data"`
+		Included []kitsuIncluded `json:"included"`
+	}
+	if err := k.get(ctx, "/manga/"+id+"?include=mappings,categories", &r); err != nil {
+		return nil, err
+	}
+	manga := convertKitsuToManga(r.Data, r.Included)
+	return &manga, nil
+}
+
+func (k *KitsuSource) get(ctx context.Context, path string, out interface{}) error {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, k.BaseURL+path, nil)
+	req.Header.Set("Accept", "application/vnd.api+json")
+	req.Header.Set("User-Agent", "MangaHub/1.0")
+
+	res, err := k.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("Kitsu API request failed: %s", res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+func convertKitsuToManga(d kitsuManga, included []kitsuIncluded) models.Manga {
+	byKey := make(map[string]kitsuIncluded, len(included))
+	for _, inc := range included {
+		byKey[inc.Type+"/"+inc.ID] = inc
+	}
+	related := func(name string) []kitsuIncluded {
+		var refs []struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		}
+		json.Unmarshal(d.Relationships[name].Data, &refs)
+		out := make([]kitsuIncluded, 0, len(refs))
+		for _, ref := range refs {
+			if inc, ok := byKey[ref.Type+"/"+ref.ID]; ok {
+				out = append(out, inc)
+			}
+		}
+		return out
+	}
+
+	externalIDs := map[string]string{"kitsu": d.ID}
+	for _, m := range related("mappings") {
+		if name, ok := kitsuMappingSites[m.Attributes.ExternalSite]; ok && m.Attributes.ExternalID != "" {
+			externalIDs[name] = m.Attributes.ExternalID
+		}
+	}
+	genres := []string{}
+	for _, c := range related("categories") {
+		genres = append(genres, c.Attributes.Title)
+	}
+
+	a := d.Attributes
+	title := a.Titles["en"]
+	if title == "" {
+		title = a.CanonicalTitle
+	}
+
+	status := a.Status
+	switch a.Status {
+	case "finished":
+		status = "completed"
+	case "current":
+		status = "ongoing"
+	case "tba", "unreleased":
+		status = "upcoming"
+	}
+
+	mediaType := a.Subtype
+	if mediaType == "oneshot" {
+		mediaType = "one_shot"
+	}
+
+	coverURL := a.PosterImage.Large
+	if coverURL == "" {
+		coverURL = a.PosterImage.Medium
+	}
+
+	// averageRating is a percentage as a string, e.g. "82.51"
+	mean := 0.0
+	if r, err := strconv.ParseFloat(a.AverageRating, 64); err == nil {
+		mean = r / 10
+	}
+
+	return models.Manga{
+		ID:            "kitsu:" + d.ID,
+		ExternalIDs:   externalIDs,
+		Title:         title,
+		Genres:        genres,
+		Status:        status,
+		TotalChapters: a.ChapterCount,
+		Description:   a.Synopsis,
+		CoverURL:      coverURL,
+		AlternativeTitles: map[string]interface{}{
+			"en": a.Titles["en"],
+			"ja": a.Titles["ja_jp"],
+		},
+		StartDate:  a.StartDate,
+		EndDate:    a.EndDate,
+		Mean:       mean,
+		MediaType:  mediaType,
+		NumVolumes: a.VolumeCount,
+	}
+}
@@ -0,0 +1,108 @@
+package manga
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+// LocalSource serves manga from a JSON file holding an array in the same
+// shape the API returns. The file is re-read on each call, so it can be
+// edited while the server runs.
+type LocalSource struct {
+	Path string
+}
+
+func NewLocalSource(path string) *LocalSource {
+	return &LocalSource{Path: path}
+}
+
+func (l *LocalSource) load() ([]models.Manga, error) {
+	data, err := os.ReadFile(l.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local manga file: %w", err)
+	}
+	var mangas []models.Manga
+	if err := json.Unmarshal(data, &mangas); err != nil {
+		return nil, fmt.Errorf("failed to parse local manga file: %w", err)
+	}
+
+	for i := range mangas {
+		m := &mangas[i]
+		bare := strings.TrimPrefix(m.ID, "local:")
+		m.ID = "local:" + bare
+		ids := map[string]string{"local": bare}
+		for k, v := range m.ExternalIDs {
+			ids[k] = v
+		}
+		if m.MangaDexID != "" {
+			ids["mangadex"] = m.MangaDexID
+		}
+		m.ExternalIDs = ids
+	}
+	return mangas, nil
+}
+
+// Search matches the query against titles and alternative titles
+func (l *LocalSource) Search(ctx context.Context, query string, limit, offset int) ([]models.Manga, error) {
+	mangas, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+
+	q := strings.ToLower(strings.TrimSpace(query))
+	var matches []models.Manga
+	for _, m := range mangas {
+		if q == "" || localTitleMatches(m, q) {
+			matches = append(matches, m)
+		}
+	}
+
+	if offset >= len(matches) {
+		return []models.Manga{}, nil
+	}
+	matches = matches[offset:]
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+func (l *LocalSource) GetMangaByID(ctx context.Context, id string) (*models.Manga, error) {
+	mangas, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	id = "local:" + strings.TrimPrefix(id, "local:")
+	for i := range mangas {
+		if mangas[i].ID == id {
+			return &mangas[i], nil
+		}
+	}
+	return nil, fmt.Errorf("manga %s not found in local file", id)
+}
+
+func localTitleMatches(m models.Manga, q string) bool {
+	if strings.Contains(strings.ToLower(m.Title), q) {
+		return true
+	}
+	for _, v := range m.AlternativeTitles {
+		switch t := v.(type) {
+		case string:
+			if strings.Contains(strings.ToLower(t), q) {
+				return true
+			}
+		case []interface{}:
+			for _, s := range t {
+				if str, ok := s.(string); ok && strings.Contains(strings.ToLower(str), q) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,286 @@
+package manga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+// Capability is something a provider can be asked to do
+type Capability string
+
+const (
+	CapabilitySearch   Capability = "search"
+	CapabilityDetails  Capability = "details"
+	CapabilityChapters Capability = "chapters"
+	CapabilityRankings Capability = "rankings"
+)
+
+var ErrUnknownSourceID = errors.New("no enabled source recognises this manga ID")
+
+// Provider is an ExternalSource registered under a name. IDs from sources
+// other than MAL and MangaDex carry the name as a prefix ("anilist:30013").
+type Provider struct {
+	Name         string
+	Priority     int // lower is preferred when results are merged
+	Capabilities []Capability
+	Source       ExternalSource
+	// OwnsID claims unprefixed IDs, e.g. MAL's numeric ones
+	OwnsID func(id string) bool
+}
+
+func (p Provider) Can(c Capability) bool {
+	for _, have := range p.Capabilities {
+		if have == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry fans ExternalSource calls out to every enabled provider
+type Registry struct {
+	providers []Provider
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a provider, replacing any with the same name
+func (r *Registry) Register(p Provider) {
+	for i, existing := range r.providers {
+		if existing.Name == p.Name {
+			r.providers = append(r.providers[:i], r.providers[i+1:]...)
+			break
+		}
+	}
+	r.providers = append(r.providers, p)
+	sort.SliceStable(r.providers, func(i, j int) bool {
+		return r.providers[i].Priority < r.providers[j].Priority
+	})
+}
+
+// Providers lists the providers with a capability, most preferred first
+func (r *Registry) Providers(c Capability) []Provider {
+	var out []Provider
+	for _, p := range r.providers {
+		if p.Can(c) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func (r *Registry) Provider(name string) (Provider, bool) {
+	for _, p := range r.providers {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Provider{}, false
+}
+
+func (r *Registry) Supports(c Capability) bool {
+	return len(r.Providers(c)) > 0
+}
+
+// Search queries every search provider at once and merges their results,
+// then pages through the merged list. Each provider is asked for everything
+// up to the end of the page, since merging decides which of its rows land
+// on it. It only fails when every provider does.
+func (r *Registry) Search(ctx context.Context, query string, limit, offset int) ([]models.Manga, error) {
+	providers := r.Providers(CapabilitySearch)
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("no search source enabled")
+	}
+
+	results := make([][]models.Manga, len(providers))
+	errs := make([]error, len(providers))
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func(i int, p Provider) {
+			defer wg.Done()
+			results[i], errs[i] = searchUpTo(ctx, p, query, limit+offset)
+		}(i, p)
+	}
+	wg.Wait()
+
+	var firstErr error
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			log.Printf("manga source %s: search failed: %v", providers[i].Name, err)
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if failed == len(providers) {
+		return nil, firstErr
+	}
+
+	merged := mergeResults(results...)
+	if offset >= len(merged) {
+		return []models.Manga{}, nil
+	}
+	merged = merged[offset:]
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged, nil
+}
+
+// searchUpTo collects the first n results from a provider. Sources cap how
+// many rows one request returns (AniList at 50, Kitsu at 20), so it keeps
+// asking from where the last page ended until it has n or the source runs
+// dry. A failure after the first page keeps what was already fetched.
+func searchUpTo(ctx context.Context, p Provider, query string, n int) ([]models.Manga, error) {
+	var out []models.Manga
+	for len(out) < n {
+		page, err := p.Source.Search(ctx, query, n-len(out), len(out))
+		if err != nil {
+			if len(out) == 0 {
+				return nil, err
+			}
+			log.Printf("manga source %s: search stopped after %d results: %v", p.Name, len(out), err)
+			break
+		}
+		if len(page) == 0 {
+			break
+		}
+		out = append(out, page...)
+	}
+	return out, nil
+}
+
+// GetMangaByID asks the provider the ID belongs to: the one named by its
+// prefix (which is stripped), or else the first details provider that owns
+// bare IDs like it
+func (r *Registry) GetMangaByID(ctx context.Context, id string) (*models.Manga, error) {
+	if name, rest, ok := strings.Cut(id, ":"); ok {
+		if p, found := r.Provider(name); found && p.Can(CapabilityDetails) {
+			return p.Source.GetMangaByID(ctx, rest)
+		}
+	}
+	for _, p := range r.Providers(CapabilityDetails) {
+		if p.OwnsID != nil && p.OwnsID(id) {
+			return p.Source.GetMangaByID(ctx, id)
+		}
+	}
+	return nil, ErrUnknownSourceID
+}
+
+// Dedupe merges manga that share an external ID, keeping the first copy
+// and filling its blanks from the later ones
+func Dedupe(mangas []models.Manga) []models.Manga {
+	return mergeResults(mangas)
+}
+
+func mergeResults(lists ...[]models.Manga) []models.Manga {
+	var out []models.Manga
+	index := make(map[string]int)
+	for _, list := range lists {
+		for _, m := range list {
+			keys := externalKeys(m)
+			at := -1
+			for _, k := range keys {
+				if i, ok := index[k]; ok {
+					at = i
+					break
+				}
+			}
+			if at < 0 {
+				at = len(out)
+				out = append(out, m)
+			} else {
+				fillBlanks(&out[at], m)
+			}
+			for _, k := range externalKeys(out[at]) {
+				if _, ok := index[k]; !ok {
+					index[k] = at
+				}
+			}
+		}
+	}
+	return out
+}
+
+func externalKeys(m models.Manga) []string {
+	keys := make([]string, 0, len(m.ExternalIDs)+1)
+	for source, id := range m.ExternalIDs {
+		if id != "" {
+			keys = append(keys, source+":"+id)
+		}
+	}
+	if m.MangaDexID != "" {
+		keys = append(keys, "mangadex:"+m.MangaDexID)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fillBlanks copies into dst what src knows and dst does not
+func fillBlanks(dst *models.Manga, src models.Manga) {
+	if dst.Author == "" {
+		dst.Author = src.Author
+	}
+	if len(dst.Genres) == 0 {
+		dst.Genres = src.Genres
+	}
+	if dst.Status == "" {
+		dst.Status = src.Status
+	}
+	if dst.TotalChapters == 0 {
+		dst.TotalChapters = src.TotalChapters
+	}
+	if dst.Description == "" {
+		dst.Description = src.Description
+	}
+	if dst.CoverURL == "" {
+		dst.CoverURL = src.CoverURL
+	}
+	if dst.MangaDexID == "" {
+		dst.MangaDexID = src.MangaDexID
+	}
+	if dst.MediaType == "" {
+		dst.MediaType = src.MediaType
+	}
+	if dst.Mean == 0 {
+		dst.Mean = src.Mean
+	}
+	if len(src.ExternalIDs) > 0 {
+		ids := make(map[string]string, len(dst.ExternalIDs)+len(src.ExternalIDs))
+		for k, v := range src.ExternalIDs {
+			ids[k] = v
+		}
+		for k, v := range dst.ExternalIDs {
+			ids[k] = v
+		}
+		dst.ExternalIDs = ids
+	}
+}
+
+func isNumericID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, ch := range id {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isMangaDexID(id string) bool {
+	return len(id) == 36 && strings.Count(id, "-") == 4
+}
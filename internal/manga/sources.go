@@ -0,0 +1,133 @@
+package manga
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SourceConfig is one entry of the sources section in config.yaml
+type SourceConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Priority int    `yaml:"priority"`
+	Path     string `yaml:"path,omitempty"` // local source only
+}
+
+// SourcesConfig maps a source name (mal, mangadex, anilist, kitsu, local)
+// to its settings
+type SourcesConfig map[string]SourceConfig
+
+// DefaultSourcesConfig keeps MAL first and MangaDex behind it, the sources
+// the server has always used. The others are opt-in.
+func DefaultSourcesConfig() SourcesConfig {
+	return SourcesConfig{
+		"mal":      {Enabled: true, Priority: 10},
+		"mangadex": {Enabled: true, Priority: 20},
+		"anilist":  {Enabled: false, Priority: 30},
+		"kitsu":    {Enabled: false, Priority: 40},
+		"local":    {Enabled: false, Priority: 50, Path: "./data/local-manga.json"},
+	}
+}
+
+// LoadSourcesConfig reads the sources section of a config file over the
+// defaults. A missing file just means the defaults.
+func LoadSourcesConfig(path string) (SourcesConfig, error) {
+	cfg := DefaultSourcesConfig()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var file struct {
+		Sources map[string]yaml.Node `yaml:"sources"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return cfg, fmt.Errorf("failed to parse config: %w", err)
+	}
+	for name, node := range file.Sources {
+		name = strings.ToLower(name)
+		entry := cfg[name] // fields left out of the file keep their defaults
+		if err := node.Decode(&entry); err != nil {
+			return cfg, fmt.Errorf("failed to parse source %s: %w", name, err)
+		}
+		cfg[name] = entry
+	}
+	return cfg, nil
+}
+
+// NewRegistryFromConfig registers every enabled source. MAL is skipped with
+// a warning when MAL_CLIENT_ID is not set.
+func NewRegistryFromConfig(cfg SourcesConfig) (*Registry, error) {
+	r := NewRegistry()
+	for name, sc := range cfg {
+		if !sc.Enabled {
+			continue
+		}
+		p := Provider{Name: name, Priority: sc.Priority}
+		switch name {
+		case "mal":
+			mal := NewMALSource()
+			if mal.ClientID == "" {
+				log.Printf("manga source mal: MAL_CLIENT_ID is not set, source disabled")
+				continue
+			}
+			p.Source = mal
+			p.Capabilities = []Capability{CapabilitySearch, CapabilityDetails, CapabilityRankings}
+			p.OwnsID = isNumericID
+		case "mangadex":
+			p.Source = NewMangaDexSource()
+			p.Capabilities = []Capability{CapabilitySearch, CapabilityDetails, CapabilityChapters}
+			p.OwnsID = isMangaDexID
+		case "anilist":
+			p.Source = NewAniListSource()
+			p.Capabilities = []Capability{CapabilitySearch, CapabilityDetails}
+		case "kitsu":
+			p.Source = NewKitsuSource()
+			p.Capabilities = []Capability{CapabilitySearch, CapabilityDetails}
+		case "local":
+			if sc.Path == "" {
+				return nil, fmt.Errorf("source local needs a path")
+			}
+			p.Source = NewLocalSource(sc.Path)
+			p.Capabilities = []Capability{CapabilitySearch, CapabilityDetails}
+		default:
+			return nil, fmt.Errorf("unknown manga source %q", name)
+		}
+		r.Register(p)
+	}
+	return r, nil
+}
+
+var (
+	defaultRegistry     *Registry
+	defaultRegistryOnce sync.Once
+)
+
+// DefaultRegistry is built once from the file named by MANGAHUB_CONFIG
+// (config.yaml by default), falling back to the default sources if the
+// file is broken
+func DefaultRegistry() *Registry {
+	defaultRegistryOnce.Do(func() {
+		path := os.Getenv("MANGAHUB_CONFIG")
+		if path == "" {
+			path = "config.yaml"
+		}
+		cfg, err := LoadSourcesConfig(path)
+		if err == nil {
+			defaultRegistry, err = NewRegistryFromConfig(cfg)
+		}
+		if err != nil {
+			log.Printf("manga sources: %v; using the default sources", err)
+			defaultRegistry, _ = NewRegistryFromConfig(DefaultSourcesConfig())
+		}
+	})
+	return defaultRegistry
+}
@@ -0,0 +1,311 @@
+package manga_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/manga"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+// fakeSource pages through fixed results and records the IDs it is asked for.
+// A non-zero perPage caps each page the way real sources do.
+type fakeSource struct {
+	results []models.Manga
+	err     error
+	perPage int
+	asked   []string
+}
+
+func (f *fakeSource) Search(ctx context.Context, query string, limit, offset int) ([]models.Manga, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if offset > len(f.results) {
+		offset = len(f.results)
+	}
+	if f.perPage > 0 && limit > f.perPage {
+		limit = f.perPage
+	}
+	page := f.results[offset:]
+	if len(page) > limit {
+		page = page[:limit]
+	}
+	return page, nil
+}
+
+func (f *fakeSource) GetMangaByID(ctx context.Context, id string) (*models.Manga, error) {
+	f.asked = append(f.asked, id)
+	return &models.Manga{ID: id}, nil
+}
+
+func TestRegistryMergesByExternalID(t *testing.T) {
+	mal := &fakeSource{results: []models.Manga{
+		{ID: "13", Title: "One Piece", ExternalIDs: map[string]string{"mal": "13"}},
+		{ID: "2", Title: "Berserk", ExternalIDs: map[string]string{"mal": "2"}},
+	}}
+	anilist := &fakeSource{results: []models.Manga{
+		{ID: "anilist:30013", Title: "ONE PIECE", Description: "Pirates", TotalChapters: 1100,
+			ExternalIDs: map[string]string{"anilist": "30013", "mal": "13"}},
+	}}
+	kitsu := &fakeSource{results: []models.Manga{
+		{ID: "kitsu:38", Title: "One Piece (Kitsu)", ExternalIDs: map[string]string{"kitsu": "38", "anilist": "30013"}},
+		{ID: "kitsu:1", Title: "Only on Kitsu", ExternalIDs: map[string]string{"kitsu": "1"}},
+	}}
+	broken := &fakeSource{err: errors.New("down")}
+
+	r := manga.NewRegistry()
+	r.Register(manga.Provider{Name: "kitsu", Priority: 30, Source: kitsu, Capabilities: []manga.Capability{manga.CapabilitySearch}})
+	r.Register(manga.Provider{Name: "mal", Priority: 10, Source: mal, Capabilities: []manga.Capability{manga.CapabilitySearch}})
+	r.Register(manga.Provider{Name: "anilist", Priority: 20, Source: anilist, Capabilities: []manga.Capability{manga.CapabilitySearch}})
+	r.Register(manga.Provider{Name: "broken", Priority: 5, Source: broken, Capabilities: []manga.Capability{manga.CapabilitySearch}})
+
+	got, err := r.Search(context.Background(), "one piece", 20, 0)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	var ids []string
+	for _, m := range got {
+		ids = append(ids, m.ID)
+	}
+	if strings.Join(ids, ",") != "13,2,kitsu:1" {
+		t.Fatalf("merged ids = %v, want 13,2,kitsu:1", ids)
+	}
+
+	top := got[0]
+	if top.Title != "One Piece" {
+		t.Errorf("title = %q, want the MAL one", top.Title)
+	}
+	if top.Description != "Pirates" || top.TotalChapters != 1100 {
+		t.Errorf("blanks not filled from AniList: %+v", top)
+	}
+	if top.ExternalIDs["kitsu"] != "38" || top.ExternalIDs["anilist"] != "30013" {
+		t.Errorf("external ids = %v", top.ExternalIDs)
+	}
+
+	broken.err, mal.err, anilist.err, kitsu.err = errors.New("a"), errors.New("b"), errors.New("c"), errors.New("d")
+	if _, err := r.Search(context.Background(), "x", 20, 0); err == nil {
+		t.Error("search succeeded with every source down")
+	}
+}
+
+func TestRegistryPagesMergedResults(t *testing.T) {
+	numbered := func(source string, n int) []models.Manga {
+		var out []models.Manga
+		for i := 1; i <= n; i++ {
+			id := fmt.Sprintf("%s:%d", source, i)
+			out = append(out, models.Manga{ID: id, ExternalIDs: map[string]string{source: id}})
+		}
+		return out
+	}
+	r := manga.NewRegistry()
+	r.Register(manga.Provider{Name: "mal", Priority: 10, Source: &fakeSource{results: numbered("mal", 3), perPage: 2}, Capabilities: []manga.Capability{manga.CapabilitySearch}})
+	r.Register(manga.Provider{Name: "kitsu", Priority: 20, Source: &fakeSource{results: numbered("kitsu", 3), perPage: 1}, Capabilities: []manga.Capability{manga.CapabilitySearch}})
+
+	var seen []string
+	for offset := 0; offset < 8; offset += 2 {
+		page, err := r.Search(context.Background(), "x", 2, offset)
+		if err != nil {
+			t.Fatalf("search at offset %d: %v", offset, err)
+		}
+		if len(page) > 2 {
+			t.Fatalf("page at offset %d has %d results, want at most 2", offset, len(page))
+		}
+		for _, m := range page {
+			seen = append(seen, m.ID)
+		}
+	}
+	if got := strings.Join(seen, ","); got != "mal:1,mal:2,mal:3,kitsu:1,kitsu:2,kitsu:3" {
+		t.Errorf("paging skipped or repeated results: %s", got)
+	}
+}
+
+func TestRegistryRoutesIDs(t *testing.T) {
+	mal, mangadex, anilist := &fakeSource{}, &fakeSource{}, &fakeSource{}
+	details := []manga.Capability{manga.CapabilityDetails}
+
+	r := manga.NewRegistry()
+	r.Register(manga.Provider{Name: "mal", Priority: 10, Source: mal, Capabilities: details,
+		OwnsID: func(id string) bool { return strings.Trim(id, "0123456789") == "" }})
+	r.Register(manga.Provider{Name: "mangadex", Priority: 20, Source: mangadex, Capabilities: details,
+		OwnsID: func(id string) bool { return len(id) == 36 }})
+	r.Register(manga.Provider{Name: "anilist", Priority: 30, Source: anilist, Capabilities: details})
+
+	ctx := context.Background()
+	uuid := "a1c7c817-4e59-43b7-9365-09675a149a6f"
+	for _, id := range []string{"13", uuid, "anilist:30013", "mal:2"} {
+		if _, err := r.GetMangaByID(ctx, id); err != nil {
+			t.Fatalf("GetMangaByID(%s) failed: %v", id, err)
+		}
+	}
+	if strings.Join(mal.asked, ",") != "13,2" || len(mangadex.asked) != 1 || anilist.asked[0] != "30013" {
+		t.Errorf("routed mal=%v mangadex=%v anilist=%v", mal.asked, mangadex.asked, anilist.asked)
+	}
+	if _, err := r.GetMangaByID(ctx, "kitsu:38"); !errors.Is(err, manga.ErrUnknownSourceID) {
+		t.Errorf("disabled source: err = %v, want ErrUnknownSourceID", err)
+	}
+}
+
+func TestLoadSourcesConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := manga.LoadSourcesConfig(filepath.Join(dir, "missing.yaml"))
+	if err != nil || !cfg["mangadex"].Enabled || cfg["anilist"].Enabled {
+		t.Fatalf("missing file: %v, %+v", err, cfg)
+	}
+
+	file := filepath.Join(dir, "config.yaml")
+	os.WriteFile(file, []byte("server:\n  host: localhost\nsources:\n  mangadex:\n    enabled: false\n  anilist:\n    enabled: true\n    priority: 1\n  kitsu:\n    enabled: true\n"), 0644)
+	cfg, err = manga.LoadSourcesConfig(file)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg["mangadex"].Enabled || !cfg["anilist"].Enabled || cfg["anilist"].Priority != 1 {
+		t.Errorf("overrides not applied: %+v", cfg)
+	}
+	if cfg["kitsu"].Priority != 40 {
+		t.Errorf("kitsu priority = %d, want the default 40", cfg["kitsu"].Priority)
+	}
+
+	cfg["mal"] = manga.SourceConfig{Enabled: false}
+	r, err := manga.NewRegistryFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to build registry: %v", err)
+	}
+	search := r.Providers(manga.CapabilitySearch)
+	if len(search) != 2 || search[0].Name != "anilist" || r.Supports(manga.CapabilityChapters) {
+		t.Errorf("unexpected providers: %+v", search)
+	}
+
+	if _, err := manga.NewRegistryFromConfig(manga.SourcesConfig{"goodreads": {Enabled: true}}); err == nil {
+		t.Error("unknown source accepted")
+	}
+}
+
+func TestAniListSource(t *testing.T) {
+	var gotQuery map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &gotQuery)
+		io.WriteString(w, `{"data":{"Page":{"media":[{"id":30013,"idMal":13,"format":"MANGA","status":"RELEASING",
+			"chapters":0,"genres":["Action"],"averageScore":88,"description":"Pirates",
+			"title":{"romaji":"ONE PIECE","english":"One Piece","native":"ワンピース"},
+			"coverImage":{"large":"https://img/large.jpg"},"startDate":{"year":1997,"month":7,"day":22},
+			"staff":{"edges":[{"role":"Story & Art","node":{"name":{"full":"Eiichiro Oda"}}}]}}]}}}`)
+	}))
+	defer server.Close()
+
+	src := &manga.AniListSource{BaseURL: server.URL, Client: server.Client()}
+	got, err := src.Search(context.Background(), "one piece", 100, 100)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if vars := gotQuery["variables"].(map[string]interface{}); vars["page"] != 3.0 || vars["perPage"] != 50.0 {
+		t.Errorf("paging variables = %v, want page 3 of 50", vars)
+	}
+	m := got[0]
+	if m.ID != "anilist:30013" || m.ExternalIDs["mal"] != "13" || m.Title != "One Piece" {
+		t.Errorf("ids/title wrong: %+v", m)
+	}
+	if m.Status != "ongoing" || m.Author != "Eiichiro Oda" || m.Mean != 8.8 || m.StartDate != "1997-07-22" {
+		t.Errorf("fields wrong: %+v", m)
+	}
+}
+
+func TestAniListSourceUnalignedOffset(t *testing.T) {
+	// 120 results in AniList order; the stub serves whatever page is asked for
+	var pages []float64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var q struct {
+			Variables struct {
+				Page    float64 `json:"page"`
+				PerPage float64 `json:"perPage"`
+			} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&q)
+		pages = append(pages, q.Variables.Page)
+		var media []string
+		start := int(q.Variables.Page-1) * int(q.Variables.PerPage)
+		for id := start + 1; id <= start+int(q.Variables.PerPage) && id <= 120; id++ {
+			media = append(media, fmt.Sprintf(`{"id":%d}`, id))
+		}
+		fmt.Fprintf(w, `{"data":{"Page":{"media":[%s]}}}`, strings.Join(media, ","))
+	}))
+	defer server.Close()
+
+	src := &manga.AniListSource{BaseURL: server.URL, Client: server.Client()}
+	got, err := src.Search(context.Background(), "one piece", 20, 30)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(got) != 20 || got[0].ID != "anilist:31" || got[19].ID != "anilist:50" {
+		t.Fatalf("got %d results from %s, want anilist:31..anilist:50", len(got), got[0].ID)
+	}
+	if len(pages) != 2 || pages[0] != 2 || pages[1] != 3 {
+		t.Errorf("fetched pages %v, want [2 3]", pages)
+	}
+
+	// Near the end the window is cut short rather than padded
+	pages = nil
+	got, err = src.Search(context.Background(), "one piece", 20, 110)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(got) != 10 || got[0].ID != "anilist:111" {
+		t.Errorf("got %d results from %s, want anilist:111..anilist:120", len(got), got[0].ID)
+	}
+}
+
+func TestKitsuSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/manga/38" || r.URL.Query().Get("include") != "mappings,categories" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, `{"data":{"id":"38","attributes":{"canonicalTitle":"One Piece","titles":{"ja_jp":"ワンピース"},
+			"status":"current","subtype":"manga","averageRating":"85.2","posterImage":{"large":"https://img/p.jpg"}},
+			"relationships":{"mappings":{"data":[{"type":"mappings","id":"9"}]},"categories":{"data":[{"type":"categories","id":"1"}]}}},
+			"included":[{"type":"mappings","id":"9","attributes":{"externalSite":"myanimelist/manga","externalId":"13"}},
+			{"type":"categories","id":"1","attributes":{"title":"Adventure"}}]}`)
+	}))
+	defer server.Close()
+
+	src := &manga.KitsuSource{BaseURL: server.URL, Client: server.Client()}
+	m, err := src.GetMangaByID(context.Background(), "kitsu:38")
+	if err != nil {
+		t.Fatalf("lookup failed: %v", err)
+	}
+	if m.ID != "kitsu:38" || m.ExternalIDs["mal"] != "13" || m.Status != "ongoing" {
+		t.Errorf("unexpected manga: %+v", m)
+	}
+	if len(m.Genres) != 1 || m.Genres[0] != "Adventure" || m.Mean != 8.52 {
+		t.Errorf("genres/mean wrong: %v %v", m.Genres, m.Mean)
+	}
+}
+
+func TestLocalSource(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "local.json")
+	os.WriteFile(file, []byte(`[{"id":"op","title":"One Piece","external_ids":{"mal":"13"}},
+		{"id":"local:ber","title":"Berserk","alternative_titles":{"synonyms":["Kenpuu Denki"]}}]`), 0644)
+
+	src := manga.NewLocalSource(file)
+	got, err := src.Search(context.Background(), "kenpuu", 10, 0)
+	if err != nil || len(got) != 1 || got[0].ID != "local:ber" {
+		t.Fatalf("search = %+v, %v", got, err)
+	}
+	m, err := src.GetMangaByID(context.Background(), "op")
+	if err != nil {
+		t.Fatalf("lookup failed: %v", err)
+	}
+	if m.ID != "local:op" || m.ExternalIDs["mal"] != "13" || m.ExternalIDs["local"] != "op" {
+		t.Errorf("unexpected manga: %+v", m)
+	}
+}
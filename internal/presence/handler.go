@@ -0,0 +1,76 @@
+package presence
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// maxQueryUsers caps how many users one presence query may name
+const maxQueryUsers = 100
+
+// Handler answers presence queries
+type Handler struct {
+	service *Service
+	store   *repository.Store
+}
+
+func NewHandler(service *Service, store *repository.Store) *Handler {
+	return &Handler{service: service, store: store}
+}
+
+// GetPresence returns the presence of the users named in ?users= (a
+// comma-separated list of usernames), or by default of everyone the current
+// user shares a custom room or direct conversation with
+func (h *Handler) GetPresence(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	names := []string{}
+	for _, name := range strings.Split(c.Query("users"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) > maxQueryUsers {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many users"})
+		return
+	}
+
+	if len(names) == 0 {
+		presence, err := h.service.Contacts(ctx, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load presence"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"presence": presence, "count": len(presence)})
+		return
+	}
+
+	ids := make([]string, 0, len(names))
+	for _, name := range names {
+		u, err := h.store.Users.GetByUsername(ctx, name)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found: " + name})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load presence"})
+			return
+		}
+		ids = append(ids, u.ID)
+	}
+	presence, err := h.service.Get(ctx, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load presence"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"presence": presence, "count": len(presence)})
+}
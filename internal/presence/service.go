@@ -0,0 +1,381 @@
+package presence
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/config"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+// Config controls when a connected user stops counting as online and how
+// often presence is re-evaluated and written back to the store
+type Config struct {
+	IdleAfter time.Duration // no activity for this long: idle
+	AwayAfter time.Duration // no activity for this long: away
+	Interval  time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		IdleAfter: 5 * time.Minute,
+		AwayAfter: 15 * time.Minute,
+		Interval:  30 * time.Second,
+	}
+}
+
+// ConfigFromEnv reads PRESENCE_IDLE_MINUTES, PRESENCE_AWAY_MINUTES and
+// PRESENCE_INTERVAL_SECONDS, falling back to the defaults.
+func ConfigFromEnv() Config {
+	defaults := DefaultConfig()
+	return Config{
+		IdleAfter: time.Duration(config.GetEnvInt("PRESENCE_IDLE_MINUTES", int(defaults.IdleAfter/time.Minute))) * time.Minute,
+		AwayAfter: time.Duration(config.GetEnvInt("PRESENCE_AWAY_MINUTES", int(defaults.AwayAfter/time.Minute))) * time.Minute,
+		Interval:  time.Duration(config.GetEnvInt("PRESENCE_INTERVAL_SECONDS", int(defaults.Interval/time.Second))) * time.Second,
+	}
+}
+
+// userState is what this server knows about a user it has seen
+type userState struct {
+	conns      map[string]bridge.ProtocolType // connection ID -> protocol
+	status     string
+	lastActive time.Time
+	lastSeen   time.Time
+	via        bridge.ProtocolType // protocol of the latest connection or activity
+}
+
+// change is a presence snapshot waiting to be stored and, when the status
+// moved, pushed out
+type change struct {
+	presence models.UserPresence
+	previous string
+	via      bridge.ProtocolType
+	notify   bool
+}
+
+// Service follows every connection a user has open over WebSocket, TCP,
+// UDP and gRPC, derives online/idle/away/offline from their last activity
+// and keeps the result in the store so last-seen survives restarts and
+// other servers can answer presence queries. Status changes are pushed to
+// the user's contacts through the bridge and to Subscribe callbacks.
+//
+// It implements bridge.PresenceTracker. Servers that share a database each
+// report the users connected to them; a user connected to two servers is
+// stored as whatever the last of them saw.
+type Service struct {
+	store  *repository.Store
+	logger *logger.Logger
+	config Config
+
+	mu          sync.Mutex
+	users       map[string]*userState
+	bridge      *bridge.UnifiedBridge
+	subscribers []func(p models.UserPresence, previous string)
+
+	changes  chan change
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+func NewService(store *repository.Store, log *logger.Logger, cfg Config) *Service {
+	defaults := DefaultConfig()
+	if cfg.IdleAfter <= 0 {
+		cfg.IdleAfter = defaults.IdleAfter
+	}
+	if cfg.AwayAfter <= cfg.IdleAfter {
+		cfg.AwayAfter = cfg.IdleAfter + defaults.AwayAfter - defaults.IdleAfter
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaults.Interval
+	}
+	return &Service{
+		store:    store,
+		logger:   log,
+		config:   cfg,
+		users:    make(map[string]*userState),
+		changes:  make(chan change, 256),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// ForBridge returns a service over the default store, configured from the
+// environment, that tracks the bridge's connections and pushes changes
+// through it. Each standalone protocol server gives its unified bridge one,
+// so the API server's /presence sees the users connected to it.
+func ForBridge(b *bridge.UnifiedBridge) *Service {
+	s := NewService(repository.Default(), logger.WithContext("component", "presence"), ConfigFromEnv())
+	s.SetBridge(b)
+	b.SetPresenceTracker(s)
+	return s
+}
+
+// SetBridge pushes status changes to each user's contacts as presence_update events
+func (s *Service) SetBridge(b *bridge.UnifiedBridge) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bridge = b
+}
+
+// Subscribe calls fn with every status change and the status it replaced
+func (s *Service) Subscribe(fn func(p models.UserPresence, previous string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, fn)
+}
+
+// Start stores and pushes changes in the background and re-evaluates
+// everyone every Interval
+func (s *Service) Start() {
+	s.logger.Info("presence_service_started",
+		"idle_after", s.config.IdleAfter.String(),
+		"away_after", s.config.AwayAfter.String(),
+		"interval", s.config.Interval.String())
+	go s.run()
+}
+
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopChan)
+	})
+}
+
+func (s *Service) Connected(userID, connID string, protocol bridge.ProtocolType) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.state(userID)
+	if len(st.conns) == 0 {
+		st.lastActive = now
+	}
+	st.conns[connID] = protocol
+	st.via = protocol
+	s.update(userID, st, now, false)
+}
+
+func (s *Service) Disconnected(userID, connID string) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.users[userID]
+	if !ok {
+		return
+	}
+	if _, ok := st.conns[connID]; !ok {
+		return
+	}
+	delete(st.conns, connID)
+	s.update(userID, st, now, false)
+}
+
+// Active records activity. It also refreshes last-seen for users with no
+// open connection, e.g. a single gRPC call.
+func (s *Service) Active(userID string, protocol bridge.ProtocolType) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.state(userID)
+	st.lastActive = now
+	st.via = protocol
+	s.update(userID, st, now, len(st.conns) == 0)
+}
+
+// Sweep moves connected users to idle or away as their activity ages,
+// refreshes their stored last-seen and forgets users who went offline
+func (s *Service) Sweep() {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for userID, st := range s.users {
+		if len(st.conns) == 0 && st.status == repository.PresenceOffline {
+			delete(s.users, userID)
+			continue
+		}
+		s.update(userID, st, now, true)
+	}
+}
+
+// Get returns the presence of each user, in the order given. Users this
+// server is not following come from the store; a stored status that has not
+// been refreshed for a few intervals belonged to a server that went away
+// and reads as offline.
+func (s *Service) Get(ctx context.Context, userIDs []string) ([]models.UserPresence, error) {
+	stored, err := s.store.Presence.Get(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]models.UserPresence, 0, len(userIDs))
+	for _, id := range userIDs {
+		p, ok := stored[id]
+		if st, live := s.users[id]; live && st.status != "" {
+			username := p.Username
+			p = s.snapshot(id, st, now)
+			p.Username = username
+		} else if !ok {
+			p = models.UserPresence{UserID: id, Status: repository.PresenceOffline}
+		} else if p.Status != repository.PresenceOffline && (p.LastSeen == nil || now.Sub(*p.LastSeen) > 3*s.config.Interval) {
+			p.Status = repository.PresenceOffline
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// Contacts returns the presence of everyone sharing a custom room or a
+// direct conversation with the user
+func (s *Service) Contacts(ctx context.Context, userID string) ([]models.UserPresence, error) {
+	ids, err := s.store.Conversations.Contacts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, ids)
+}
+
+// state returns the user's state, creating it. The caller holds s.mu.
+func (s *Service) state(userID string) *userState {
+	st, ok := s.users[userID]
+	if !ok {
+		st = &userState{conns: make(map[string]bridge.ProtocolType)}
+		s.users[userID] = st
+	}
+	return st
+}
+
+// update re-derives the user's status and queues a change when it moved,
+// or a plain save when store is set. The caller holds s.mu.
+func (s *Service) update(userID string, st *userState, now time.Time, store bool) {
+	status := s.statusOf(st, now)
+	previous := st.status
+	if previous == "" {
+		previous = repository.PresenceOffline
+	}
+	moved := status != st.status
+	st.status = status
+	st.lastSeen = now
+	if !moved && !store {
+		return
+	}
+
+	c := change{presence: s.snapshot(userID, st, now), previous: previous, via: st.via, notify: moved && status != previous}
+	select {
+	case s.changes <- c:
+	default:
+		s.logger.Warn("presence_queue_full", "user_id", userID, "status", status)
+	}
+}
+
+func (s *Service) statusOf(st *userState, now time.Time) string {
+	if len(st.conns) == 0 {
+		return repository.PresenceOffline
+	}
+	switch quiet := now.Sub(st.lastActive); {
+	case quiet < s.config.IdleAfter:
+		return repository.PresenceOnline
+	case quiet < s.config.AwayAfter:
+		return repository.PresenceIdle
+	default:
+		return repository.PresenceAway
+	}
+}
+
+// snapshot describes the user as of now. The caller holds s.mu.
+func (s *Service) snapshot(userID string, st *userState, now time.Time) models.UserPresence {
+	lastSeen := st.lastSeen
+	if len(st.conns) > 0 {
+		lastSeen = now
+	}
+	p := models.UserPresence{UserID: userID, Status: st.status, LastSeen: &lastSeen}
+	if !st.lastActive.IsZero() {
+		lastActive := st.lastActive
+		p.LastActive = &lastActive
+	}
+	seen := map[bridge.ProtocolType]bool{}
+	for _, protocol := range st.conns {
+		if !seen[protocol] {
+			seen[protocol] = true
+			p.Protocols = append(p.Protocols, string(protocol))
+		}
+	}
+	sort.Strings(p.Protocols)
+	return p
+}
+
+func (s *Service) run() {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case c := <-s.changes:
+			s.apply(c)
+		case <-ticker.C:
+			s.Sweep()
+		case <-s.stopChan:
+			s.logger.Info("presence_service_stopped")
+			return
+		}
+	}
+}
+
+// apply stores a change and, when the status moved, tells subscribers and
+// the user's contacts
+func (s *Service) apply(c change) {
+	ctx := context.Background()
+	p := c.presence
+	if err := s.store.Presence.Save(ctx, &p); err != nil {
+		s.logger.Warn("presence_save_failed", "user_id", p.UserID, "error", err.Error())
+	}
+	if !c.notify {
+		return
+	}
+
+	if u, err := s.store.Users.GetByID(ctx, p.UserID); err == nil {
+		p.Username = u.Username
+	}
+	s.logger.Debug("presence_changed", "user_id", p.UserID, "status", p.Status, "previous", c.previous)
+
+	s.mu.Lock()
+	subscribers := s.subscribers
+	br := s.bridge
+	s.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(p, c.previous)
+	}
+	if br == nil {
+		return
+	}
+
+	contacts, err := s.store.Conversations.Contacts(ctx, p.UserID)
+	if err != nil {
+		s.logger.Warn("presence_contacts_failed", "user_id", p.UserID, "error", err.Error())
+		return
+	}
+	data := map[string]interface{}{
+		"user_id":   p.UserID,
+		"username":  p.Username,
+		"status":    p.Status,
+		"previous":  c.previous,
+		"protocols": p.Protocols,
+		"last_seen": p.LastSeen.Format(time.RFC3339),
+	}
+	if p.LastActive != nil {
+		data["last_active"] = p.LastActive.Format(time.RFC3339)
+	}
+	for _, contactID := range contacts {
+		br.BroadcastEphemeral(bridge.NewUnifiedEvent(bridge.EventPresenceUpdate, contactID, c.via, data))
+	}
+}
@@ -0,0 +1,241 @@
+package presence_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/presence"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+var quietLog = logger.New(logger.ERROR, false, os.Stdout)
+
+// newService returns a started service over a store holding alice and bob,
+// who share a custom room
+func newService(t *testing.T, cfg presence.Config) (*presence.Service, *repository.Store) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	for _, u := range []models.User{
+		{ID: "u1", Username: "alice", Email: "alice@example.com", PasswordHash: "hash"},
+		{ID: "u2", Username: "bob", Email: "bob@example.com", PasswordHash: "hash"},
+	} {
+		if err := store.Users.Create(ctx, &u); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	room := &models.Conversation{Name: "one-piece", Type: "custom"}
+	store.Conversations.Create(ctx, room)
+	store.Conversations.AddMember(ctx, room.ID, "u1", repository.RoleMember)
+	store.Conversations.AddMember(ctx, room.ID, "u2", repository.RoleMember)
+
+	svc := presence.NewService(store, quietLog, cfg)
+	svc.Start()
+	t.Cleanup(svc.Stop)
+	return svc, store
+}
+
+func statusOf(t *testing.T, svc *presence.Service, userID string) models.UserPresence {
+	t.Helper()
+	found, err := svc.Get(context.Background(), []string{userID})
+	if err != nil || len(found) != 1 {
+		t.Fatalf("Get = %+v, %v", found, err)
+	}
+	return found[0]
+}
+
+func TestStatusFollowsActivity(t *testing.T) {
+	svc, store := newService(t, presence.Config{IdleAfter: 40 * time.Millisecond, AwayAfter: 80 * time.Millisecond, Interval: time.Hour})
+	br := bridge.NewUnifiedBridge(quietLog)
+	br.SetPresenceTracker(svc)
+
+	if p := statusOf(t, svc, "u1"); p.Status != repository.PresenceOffline || p.LastSeen != nil {
+		t.Errorf("expected an unknown user to be offline, got %+v", p)
+	}
+
+	br.MarkConnected("u1", "tcp/1", bridge.ProtocolTCP)
+	br.MarkConnected("u1", "udp/1", bridge.ProtocolUDP)
+	if p := statusOf(t, svc, "u1"); p.Status != repository.PresenceOnline || len(p.Protocols) != 2 {
+		t.Errorf("expected online over two protocols, got %+v", p)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	svc.Sweep()
+	if p := statusOf(t, svc, "u1"); p.Status != repository.PresenceIdle {
+		t.Errorf("expected idle after a quiet spell, got %s", p.Status)
+	}
+	time.Sleep(40 * time.Millisecond)
+	svc.Sweep()
+	if p := statusOf(t, svc, "u1"); p.Status != repository.PresenceAway {
+		t.Errorf("expected away after a longer one, got %s", p.Status)
+	}
+
+	br.MarkActive("u1", bridge.ProtocolTCP)
+	if p := statusOf(t, svc, "u1"); p.Status != repository.PresenceOnline {
+		t.Errorf("expected activity to bring the user back online, got %s", p.Status)
+	}
+
+	br.MarkDisconnected("u1", "tcp/1")
+	if p := statusOf(t, svc, "u1"); p.Status != repository.PresenceOnline {
+		t.Errorf("expected the UDP subscription to keep the user online, got %s", p.Status)
+	}
+	br.MarkDisconnected("u1", "udp/1")
+	p := statusOf(t, svc, "u1")
+	if p.Status != repository.PresenceOffline || p.LastSeen == nil {
+		t.Fatalf("expected offline with a last-seen time, got %+v", p)
+	}
+
+	// Last-seen outlives the service
+	deadline := time.Now().Add(time.Second)
+	for {
+		stored, _ := store.Presence.Get(context.Background(), []string{"u1"})
+		if s, ok := stored["u1"]; ok && s.Status == repository.PresenceOffline {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("offline status never reached the store: %+v", stored)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	restarted := presence.NewService(store, quietLog, presence.DefaultConfig())
+	if again := statusOf(t, restarted, "u1"); again.Status != repository.PresenceOffline || again.Username != "alice" || again.LastSeen == nil {
+		t.Errorf("unexpected stored presence %+v", again)
+	}
+}
+
+func TestStaleStoredStatusReadsOffline(t *testing.T) {
+	svc, store := newService(t, presence.Config{Interval: time.Second})
+	old := time.Now().Add(-time.Hour)
+	store.Presence.Save(context.Background(), &models.UserPresence{UserID: "u2", Status: repository.PresenceOnline, LastSeen: &old})
+
+	if p := statusOf(t, svc, "u2"); p.Status != repository.PresenceOffline {
+		t.Errorf("expected an unrefreshed online status to read offline, got %s", p.Status)
+	}
+}
+
+func TestChangesReachContacts(t *testing.T) {
+	svc, _ := newService(t, presence.DefaultConfig())
+	br := bridge.NewUnifiedBridge(quietLog)
+	br.Start()
+	defer br.Stop()
+	br.SetPresenceTracker(svc)
+	svc.SetBridge(br)
+
+	events := make(chan bridge.UnifiedEvent, 10)
+	br.Subscribe(func(e bridge.UnifiedEvent) error {
+		if e.Type == bridge.EventPresenceUpdate {
+			events <- e
+		}
+		return nil
+	})
+	changes := make(chan string, 10)
+	svc.Subscribe(func(p models.UserPresence, previous string) {
+		changes <- p.Username + ":" + previous + "->" + p.Status
+	})
+
+	id := br.RegisterProtocolClient(nil, "u1", bridge.ProtocolWebSocket)
+	select {
+	case e := <-events:
+		if e.UserID != "u2" || e.Data["username"] != "alice" || e.Data["status"] != repository.PresenceOnline {
+			t.Errorf("unexpected presence event %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("contact was not told alice came online")
+	}
+	if got := <-changes; got != "alice:offline->online" {
+		t.Errorf("subscriber saw %q", got)
+	}
+
+	br.TouchProtocolClient(id, "u1")
+	br.UnregisterProtocolClient(id, "u1")
+	select {
+	case e := <-events:
+		if e.Data["status"] != repository.PresenceOffline || e.Data["previous"] != repository.PresenceOnline {
+			t.Errorf("unexpected presence event %+v", e.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("contact was not told alice left")
+	}
+}
+
+func TestPresenceHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc, store := newService(t, presence.DefaultConfig())
+	svc.Connected("u2", "ws/1", bridge.ProtocolWebSocket)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", "u1") })
+	router.GET("/presence", presence.NewHandler(svc, store).GetPresence)
+
+	get := func(path string) (int, []models.UserPresence) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var body struct {
+			Presence []models.UserPresence `json:"presence"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body.Presence
+	}
+
+	code, contacts := get("/presence")
+	if code != http.StatusOK || len(contacts) != 1 || contacts[0].UserID != "u2" || contacts[0].Status != repository.PresenceOnline {
+		t.Errorf("GET /presence = %d %+v", code, contacts)
+	}
+	code, named := get("/presence?users=alice,bob")
+	if code != http.StatusOK || len(named) != 2 || named[0].Status != repository.PresenceOffline || named[1].Status != repository.PresenceOnline {
+		t.Errorf("GET /presence?users= = %d %+v", code, named)
+	}
+	if code, _ := get("/presence?users=nobody"); code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown user, got %d", code)
+	}
+}
+
+// Presence goes stale at once, so pushing it must not grow the event log
+func TestChangesAreNotLogged(t *testing.T) {
+	if err := database.InitDatabase(t.TempDir() + "/presence.db"); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	svc, _ := newService(t, presence.DefaultConfig())
+	br := bridge.NewUnifiedBridge(quietLog)
+	eventLog := bridge.NewEventLog(database.DB, quietLog, bridge.DefaultRetentionPolicy())
+	br.SetEventLog(eventLog)
+	br.Start()
+	defer br.Stop()
+	br.SetPresenceTracker(svc)
+	svc.SetBridge(br)
+
+	events := make(chan bridge.UnifiedEvent, 10)
+	br.Subscribe(func(e bridge.UnifiedEvent) error {
+		if e.Type == bridge.EventPresenceUpdate {
+			events <- e
+		}
+		return nil
+	})
+
+	id := br.RegisterProtocolClient(nil, "u1", bridge.ProtocolWebSocket)
+	br.UnregisterProtocolClient(id, "u1")
+	for i := 0; i < 2; i++ {
+		select {
+		case e := <-events:
+			if e.Offset != 0 {
+				t.Errorf("presence event was logged at offset %d", e.Offset)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("contact was not told about alice")
+		}
+	}
+	if latest, err := eventLog.LatestOffset(); err != nil || latest != 0 {
+		t.Errorf("event log holds %d events (%v), want none", latest, err)
+	}
+}
@@ -0,0 +1,367 @@
+package ratelimit
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/config"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/metrics"
+)
+
+// Config sets the message rates servers accept and how abuse is punished.
+// A zero limit switches that check off.
+type Config struct {
+	Window  time.Duration // the rates below are counted over this window
+	PerUser int           // messages one user may send per window
+	PerRoom int           // messages one room may carry per window, from everyone
+	PerIP   int           // messages one address may send per window
+
+	FloodMessages     int // this many chat messages within FloodWindow is a flood
+	FloodWindow       time.Duration
+	DuplicateMessages int // the same text this many times within DuplicateWindow
+	DuplicateWindow   time.Duration
+
+	MuteAfter       int // strikes before a temporary mute; earlier ones are warnings
+	MuteFor         time.Duration
+	DisconnectAfter int           // strikes before the connection is dropped
+	StrikeExpiry    time.Duration // strikes are forgotten after this long without a new one
+
+	Words []string // masked in chat messages, ignoring case
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Window:            10 * time.Second,
+		PerUser:           20,
+		PerRoom:           100,
+		PerIP:             60,
+		FloodMessages:     5,
+		FloodWindow:       2 * time.Second,
+		DuplicateMessages: 3,
+		DuplicateWindow:   30 * time.Second,
+		MuteAfter:         3,
+		MuteFor:           time.Minute,
+		DisconnectAfter:   5,
+		StrikeExpiry:      10 * time.Minute,
+	}
+}
+
+// ConfigFromEnv reads RATELIMIT_WINDOW_SECONDS, RATELIMIT_PER_USER,
+// RATELIMIT_PER_ROOM, RATELIMIT_PER_IP, RATELIMIT_FLOOD_MESSAGES,
+// RATELIMIT_FLOOD_SECONDS, RATELIMIT_DUPLICATE_MESSAGES,
+// RATELIMIT_DUPLICATE_SECONDS, RATELIMIT_MUTE_AFTER, RATELIMIT_MUTE_SECONDS,
+// RATELIMIT_DISCONNECT_AFTER and RATELIMIT_STRIKE_MINUTES, falling back to
+// the defaults, and RATELIMIT_WORDS as a comma-separated list.
+func ConfigFromEnv() Config {
+	d := DefaultConfig()
+	seconds := func(key string, def time.Duration) time.Duration {
+		return time.Duration(config.GetEnvInt(key, int(def/time.Second))) * time.Second
+	}
+	cfg := Config{
+		Window:            seconds("RATELIMIT_WINDOW_SECONDS", d.Window),
+		PerUser:           config.GetEnvInt("RATELIMIT_PER_USER", d.PerUser),
+		PerRoom:           config.GetEnvInt("RATELIMIT_PER_ROOM", d.PerRoom),
+		PerIP:             config.GetEnvInt("RATELIMIT_PER_IP", d.PerIP),
+		FloodMessages:     config.GetEnvInt("RATELIMIT_FLOOD_MESSAGES", d.FloodMessages),
+		FloodWindow:       seconds("RATELIMIT_FLOOD_SECONDS", d.FloodWindow),
+		DuplicateMessages: config.GetEnvInt("RATELIMIT_DUPLICATE_MESSAGES", d.DuplicateMessages),
+		DuplicateWindow:   seconds("RATELIMIT_DUPLICATE_SECONDS", d.DuplicateWindow),
+		MuteAfter:         config.GetEnvInt("RATELIMIT_MUTE_AFTER", d.MuteAfter),
+		MuteFor:           seconds("RATELIMIT_MUTE_SECONDS", d.MuteFor),
+		DisconnectAfter:   config.GetEnvInt("RATELIMIT_DISCONNECT_AFTER", d.DisconnectAfter),
+		StrikeExpiry:      time.Duration(config.GetEnvInt("RATELIMIT_STRIKE_MINUTES", int(d.StrikeExpiry/time.Minute))) * time.Minute,
+	}
+	for _, w := range strings.Split(os.Getenv("RATELIMIT_WORDS"), ",") {
+		if w = strings.TrimSpace(w); w != "" {
+			cfg.Words = append(cfg.Words, w)
+		}
+	}
+	return cfg
+}
+
+// Penalty is what happens to whoever sent a refused message
+type Penalty int
+
+const (
+	None       Penalty = iota // refused, but not the sender's fault
+	Warn                      // refused and counted as a strike
+	Mute                      // refused, and nothing more is accepted until Decision.Until
+	Disconnect                // refused, and the connection should be dropped
+)
+
+func (p Penalty) String() string {
+	switch p {
+	case Warn:
+		return "warn"
+	case Mute:
+		return "mute"
+	case Disconnect:
+		return "disconnect"
+	}
+	return "none"
+}
+
+// Why a message was refused
+const (
+	ReasonUserRate  = "user_rate"
+	ReasonRoomRate  = "room_rate"
+	ReasonIPRate    = "ip_rate"
+	ReasonFlood     = "flood"
+	ReasonDuplicate = "duplicate"
+	ReasonMuted     = "muted"
+)
+
+// Message describes one incoming message. Scope names the server checking
+// it, so one Limiter can serve several servers without their counts mixing.
+// Room and Content are only set for chat messages; flood, duplicate and
+// word checks look at messages with Content.
+type Message struct {
+	Scope   string
+	UserID  string // empty before the sender has authenticated
+	IP      string
+	Room    string
+	Content string
+}
+
+// Decision is the verdict on a message
+type Decision struct {
+	Allowed bool
+	Penalty Penalty
+	Reason  string
+	Until   time.Time // when a mute ends
+	Content string    // the message's content with filtered words masked
+}
+
+// window counts messages in a fixed window
+type window struct {
+	start time.Time
+	n     int
+}
+
+type sent struct {
+	text string
+	at   time.Time
+}
+
+// offender is what the limiter remembers about one sender
+type offender struct {
+	recent     []time.Time // chat messages within FloodWindow
+	texts      []sent      // chat messages within DuplicateWindow
+	strikes    int
+	lastStrike time.Time
+	mutedUntil time.Time
+}
+
+// pruneEvery is how often Check forgets quiet senders and rooms
+const pruneEvery = time.Minute
+
+// Limiter enforces a Config. It is safe for concurrent use.
+type Limiter struct {
+	config Config
+	words  *regexp.Regexp
+
+	mu        sync.Mutex
+	windows   map[string]*window
+	offenders map[string]*offender
+	lastPrune time.Time
+}
+
+func New(cfg Config) *Limiter {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultConfig().Window
+	}
+	l := &Limiter{
+		config:    cfg,
+		windows:   make(map[string]*window),
+		offenders: make(map[string]*offender),
+		lastPrune: time.Now(),
+	}
+	if len(cfg.Words) > 0 {
+		quoted := make([]string, len(cfg.Words))
+		for i, w := range cfg.Words {
+			quoted[i] = regexp.QuoteMeta(w)
+		}
+		// Longest first, so "darned" wins over "darn" when both are listed.
+		// \b only knows ASCII word characters, so the boundary before a word
+		// is spelled out; the one after is checked in Filter, as RE2 has no
+		// lookahead and consuming it would hide back-to-back matches.
+		sort.SliceStable(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+		l.words = regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}_])(` + strings.Join(quoted, "|") + `)`)
+	}
+	return l
+}
+
+// Config returns the limits in force
+func (l *Limiter) Config() Config {
+	return l.config
+}
+
+// Filter masks filtered words in text with asterisks
+func (l *Limiter) Filter(text string) string {
+	if l.words == nil {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range l.words.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[2], m[3]
+		if next, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWordRune(next) {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(strings.Repeat("*", utf8.RuneCountInString(text[start:end])))
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// Check decides whether to accept a message. Going over the user or IP
+// rate, flooding and repeating yourself each earn the sender a strike:
+// warnings first, then a mute, then a disconnect. A full room refuses
+// messages without penalty.
+func (l *Limiter) Check(m Message) Decision {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastPrune) >= pruneEvery {
+		l.prune(now)
+	}
+
+	key := m.Scope + "/user:" + m.UserID
+	if m.UserID == "" {
+		key = m.Scope + "/ip:" + m.IP
+	}
+	o, ok := l.offenders[key]
+	if !ok {
+		o = &offender{}
+		l.offenders[key] = o
+	}
+	if now.Before(o.mutedUntil) {
+		return l.refuse(Decision{Penalty: Mute, Reason: ReasonMuted, Until: o.mutedUntil})
+	}
+
+	if reason := l.violation(m, o, now); reason != "" {
+		return l.refuse(l.strike(o, reason, now))
+	}
+	if m.Room != "" && !l.hit(m.Scope+"/room:"+m.Room, l.config.PerRoom, now) {
+		return l.refuse(Decision{Penalty: None, Reason: ReasonRoomRate})
+	}
+	return Decision{Allowed: true, Content: l.Filter(m.Content)}
+}
+
+// violation returns the first rule the message breaks, or ""
+func (l *Limiter) violation(m Message, o *offender, now time.Time) string {
+	if m.UserID != "" && !l.hit(m.Scope+"/user:"+m.UserID, l.config.PerUser, now) {
+		return ReasonUserRate
+	}
+	if m.IP != "" && !l.hit(m.Scope+"/ip:"+m.IP, l.config.PerIP, now) {
+		return ReasonIPRate
+	}
+	if m.Content == "" {
+		return ""
+	}
+
+	if l.config.FloodMessages > 0 {
+		o.recent = append(since(o.recent, now.Add(-l.config.FloodWindow)), now)
+		if len(o.recent) > l.config.FloodMessages {
+			return ReasonFlood
+		}
+	}
+	if l.config.DuplicateMessages > 0 {
+		cutoff := now.Add(-l.config.DuplicateWindow)
+		kept := o.texts[:0]
+		for _, s := range o.texts {
+			if s.at.After(cutoff) {
+				kept = append(kept, s)
+			}
+		}
+		text := strings.ToLower(strings.Join(strings.Fields(m.Content), " "))
+		o.texts = append(kept, sent{text: text, at: now})
+		repeats := 0
+		for _, s := range o.texts {
+			if s.text == text {
+				repeats++
+			}
+		}
+		if repeats >= l.config.DuplicateMessages {
+			return ReasonDuplicate
+		}
+	}
+	return ""
+}
+
+// hit counts a message against a limit and reports whether it fits
+func (l *Limiter) hit(key string, limit int, now time.Time) bool {
+	if limit <= 0 {
+		return true
+	}
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.config.Window {
+		w = &window{start: now}
+		l.windows[key] = w
+	}
+	w.n++
+	return w.n <= limit
+}
+
+// strike records a violation and picks the penalty for it
+func (l *Limiter) strike(o *offender, reason string, now time.Time) Decision {
+	if now.Sub(o.lastStrike) >= l.config.StrikeExpiry {
+		o.strikes = 0
+	}
+	o.strikes++
+	o.lastStrike = now
+
+	switch {
+	case l.config.DisconnectAfter > 0 && o.strikes >= l.config.DisconnectAfter:
+		return Decision{Penalty: Disconnect, Reason: reason}
+	case l.config.MuteAfter > 0 && o.strikes >= l.config.MuteAfter:
+		o.mutedUntil = now.Add(l.config.MuteFor)
+		return Decision{Penalty: Mute, Reason: reason, Until: o.mutedUntil}
+	}
+	return Decision{Penalty: Warn, Reason: reason}
+}
+
+func (l *Limiter) refuse(d Decision) Decision {
+	metrics.IncrementRateLimited()
+	return d
+}
+
+// prune forgets windows that have ended and senders with nothing left to
+// remember. The caller holds l.mu.
+func (l *Limiter) prune(now time.Time) {
+	l.lastPrune = now
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.config.Window {
+			delete(l.windows, key)
+		}
+	}
+	for key, o := range l.offenders {
+		quiet := len(since(o.recent, now.Add(-l.config.FloodWindow))) == 0 &&
+			(len(o.texts) == 0 || now.Sub(o.texts[len(o.texts)-1].at) >= l.config.DuplicateWindow)
+		forgiven := o.strikes == 0 || now.Sub(o.lastStrike) >= l.config.StrikeExpiry
+		if quiet && forgiven && !now.Before(o.mutedUntil) {
+			delete(l.offenders, key)
+		}
+	}
+}
+
+// since drops the times before cutoff from an ascending list
+func since(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	return times[i:]
+}
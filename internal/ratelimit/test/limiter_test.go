@@ -0,0 +1,156 @@
+package ratelimit_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/ratelimit"
+)
+
+// only returns a config with every check off, for tests to switch on one at a time
+func only() ratelimit.Config {
+	return ratelimit.Config{Window: time.Hour, StrikeExpiry: time.Hour}
+}
+
+func TestUserAndIPRates(t *testing.T) {
+	cfg := only()
+	cfg.Window = 50 * time.Millisecond
+	cfg.PerUser = 3
+	cfg.PerIP = 4
+	l := ratelimit.New(cfg)
+
+	for i := 0; i < 3; i++ {
+		if d := l.Check(ratelimit.Message{Scope: "tcp", UserID: "u1", IP: "10.0.0.1"}); !d.Allowed {
+			t.Fatalf("message %d refused: %+v", i, d)
+		}
+	}
+	if d := l.Check(ratelimit.Message{Scope: "tcp", UserID: "u1", IP: "10.0.0.1"}); d.Allowed || d.Reason != ratelimit.ReasonUserRate || d.Penalty != ratelimit.Warn {
+		t.Errorf("expected a warning for going over the user rate, got %+v", d)
+	}
+	if d := l.Check(ratelimit.Message{Scope: "udp", UserID: "u1", IP: "10.0.0.1"}); !d.Allowed {
+		t.Errorf("another scope should keep its own count, got %+v", d)
+	}
+
+	// Other users at the same address share its limit
+	if d := l.Check(ratelimit.Message{Scope: "tcp", UserID: "u2", IP: "10.0.0.1"}); !d.Allowed {
+		t.Errorf("u2 should fit in the address's limit, got %+v", d)
+	}
+	if d := l.Check(ratelimit.Message{Scope: "tcp", UserID: "u3", IP: "10.0.0.1"}); d.Allowed || d.Reason != ratelimit.ReasonIPRate {
+		t.Errorf("expected the address to be over its limit, got %+v", d)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if d := l.Check(ratelimit.Message{Scope: "tcp", UserID: "u1", IP: "10.0.0.1"}); !d.Allowed {
+		t.Errorf("expected a new window to start afresh, got %+v", d)
+	}
+}
+
+func TestPenaltiesEscalate(t *testing.T) {
+	cfg := only()
+	cfg.PerUser = 1
+	cfg.MuteAfter = 2
+	cfg.MuteFor = 50 * time.Millisecond
+	cfg.DisconnectAfter = 3
+	l := ratelimit.New(cfg)
+	msg := ratelimit.Message{Scope: "websocket", UserID: "u1"}
+
+	l.Check(msg)
+	if d := l.Check(msg); d.Penalty != ratelimit.Warn {
+		t.Errorf("first strike should warn, got %+v", d)
+	}
+	d := l.Check(msg)
+	if d.Penalty != ratelimit.Mute || d.Until.IsZero() {
+		t.Fatalf("second strike should mute, got %+v", d)
+	}
+	if d := l.Check(msg); d.Reason != ratelimit.ReasonMuted || d.Penalty != ratelimit.Mute {
+		t.Errorf("expected messages during the mute to be refused, got %+v", d)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if d := l.Check(msg); d.Penalty != ratelimit.Disconnect || d.Reason != ratelimit.ReasonUserRate {
+		t.Errorf("third strike should disconnect, got %+v", d)
+	}
+}
+
+func TestFloodAndDuplicates(t *testing.T) {
+	cfg := only()
+	cfg.FloodMessages = 3
+	cfg.FloodWindow = time.Second
+	cfg.DuplicateMessages = 2
+	cfg.DuplicateWindow = time.Second
+	l := ratelimit.New(cfg)
+
+	send := func(userID, content string) ratelimit.Decision {
+		return l.Check(ratelimit.Message{Scope: "websocket", UserID: userID, Room: "global", Content: content})
+	}
+	if d := send("u1", "Chapter 100!"); !d.Allowed {
+		t.Fatalf("first message refused: %+v", d)
+	}
+	if d := send("u1", "chapter   100!"); d.Allowed || d.Reason != ratelimit.ReasonDuplicate {
+		t.Errorf("expected a repeat to be caught whatever its case and spacing, got %+v", d)
+	}
+	if d := send("u2", "chapter 100!"); !d.Allowed {
+		t.Errorf("someone else saying the same thing is fine, got %+v", d)
+	}
+
+	for i := 0; i < 2; i++ {
+		send("u2", fmt.Sprintf("message %d", i))
+	}
+	if d := send("u2", "one too many"); d.Allowed || d.Reason != ratelimit.ReasonFlood {
+		t.Errorf("expected a flood, got %+v", d)
+	}
+	if d := l.Check(ratelimit.Message{Scope: "websocket", UserID: "u2"}); !d.Allowed {
+		t.Errorf("messages without content are not flood checked, got %+v", d)
+	}
+}
+
+func TestRoomRateHasNoPenalty(t *testing.T) {
+	cfg := only()
+	cfg.PerRoom = 2
+	cfg.MuteAfter = 1
+	l := ratelimit.New(cfg)
+
+	l.Check(ratelimit.Message{Scope: "websocket", UserID: "u1", Room: "one-piece", Content: "a"})
+	l.Check(ratelimit.Message{Scope: "websocket", UserID: "u2", Room: "one-piece", Content: "b"})
+	d := l.Check(ratelimit.Message{Scope: "websocket", UserID: "u3", Room: "one-piece", Content: "c"})
+	if d.Allowed || d.Reason != ratelimit.ReasonRoomRate || d.Penalty != ratelimit.None {
+		t.Errorf("expected a full room to refuse without penalty, got %+v", d)
+	}
+	if d := l.Check(ratelimit.Message{Scope: "websocket", UserID: "u3", Room: "naruto", Content: "c"}); !d.Allowed {
+		t.Errorf("other rooms are unaffected, got %+v", d)
+	}
+}
+
+func TestWordFilter(t *testing.T) {
+	cfg := only()
+	cfg.Words = []string{"darn", "spoil it"}
+	l := ratelimit.New(cfg)
+
+	d := l.Check(ratelimit.Message{Scope: "websocket", UserID: "u1", Content: "DARN, don't spoil it! darned"})
+	if !d.Allowed || d.Content != "****, don't ********! darned" {
+		t.Errorf("unexpected filtered content %q (%+v)", d.Content, d)
+	}
+	if got := l.Filter("nothing to see"); got != "nothing to see" {
+		t.Errorf("Filter changed clean text to %q", got)
+	}
+}
+
+func TestWordFilterOutsideASCII(t *testing.T) {
+	cfg := only()
+	cfg.Words = []string{"baka", "ばか", "tệ"}
+	l := ratelimit.New(cfg)
+
+	cases := map[string]string{
+		"bakaбака baka":     "bakaбака ****",
+		"ébaka baka, baka!": "ébaka ****, ****!",
+		"ばか":                "**",
+		"quá tệ":            "quá **",
+		"tệhại tệ_x":        "tệhại tệ_x",
+	}
+	for in, want := range cases {
+		if got := l.Filter(in); got != want {
+			t.Errorf("Filter(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package reader
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/chapter"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/download"
+)
+
+var ErrNoImages = errors.New("archive has no images")
+
+var imageExts = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true}
+
+// Book is an open CBZ archive with its pages in reading order
+type Book struct {
+	Path  string
+	Info  download.ComicInfo
+	zr    *zip.ReadCloser
+	pages []*zip.File
+}
+
+// Open reads a CBZ's page list and ComicInfo.xml, when it has one
+func Open(filename string) (*Book, error) {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	b := &Book{Path: filename, zr: zr}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || strings.HasPrefix(path.Base(f.Name), ".") {
+			continue
+		}
+		if strings.EqualFold(path.Base(f.Name), "ComicInfo.xml") {
+			if rc, err := f.Open(); err == nil {
+				xml.NewDecoder(rc).Decode(&b.Info)
+				rc.Close()
+			}
+			continue
+		}
+		if imageExts[strings.ToLower(path.Ext(f.Name))] {
+			b.pages = append(b.pages, f)
+		}
+	}
+	if len(b.pages) == 0 {
+		zr.Close()
+		return nil, ErrNoImages
+	}
+
+	sort.SliceStable(b.pages, func(i, j int) bool {
+		return naturalLess(b.pages[i].Name, b.pages[j].Name)
+	})
+	return b, nil
+}
+
+func (b *Book) Close() error {
+	return b.zr.Close()
+}
+
+func (b *Book) Len() int {
+	return len(b.pages)
+}
+
+// Page returns the raw image of page i (0-based) and its file name
+func (b *Book) Page(i int) ([]byte, string, error) {
+	if i < 0 || i >= len(b.pages) {
+		return nil, "", fmt.Errorf("page %d out of range", i+1)
+	}
+	rc, err := b.pages[i].Open()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read page %d: %w", i+1, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read page %d: %w", i+1, err)
+	}
+	return data, path.Base(b.pages[i].Name), nil
+}
+
+// Title is "<Series> Ch.<n>", falling back to the file name
+func (b *Book) Title() string {
+	title := b.Info.Series
+	if b.Info.Number != "" {
+		title = strings.TrimSpace(title + " Ch." + b.Info.Number)
+	}
+	if title == "" {
+		title = strings.TrimSuffix(path.Base(b.Path), path.Ext(b.Path))
+	}
+	return title
+}
+
+// ChapterNumber is the whole chapter number, as progress stores it
+func (b *Book) ChapterNumber() (int, bool) {
+	key, ok := chapter.SortKey(b.Info.Number)
+	if !ok {
+		return 0, false
+	}
+	return int(math.Floor(key)), true
+}
+
+// naturalLess orders names so that "2.png" comes before "10.png"
+func naturalLess(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	for a != "" && b != "" {
+		if unicode.IsDigit(rune(a[0])) && unicode.IsDigit(rune(b[0])) {
+			na, ra := leadingDigits(a)
+			nb, rb := leadingDigits(b)
+			ta, tb := strings.TrimLeft(na, "0"), strings.TrimLeft(nb, "0")
+			if len(ta) != len(tb) {
+				return len(ta) < len(tb)
+			}
+			if ta != tb {
+				return ta < tb
+			}
+			a, b = ra, rb
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func leadingDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i], s[i:]
+}
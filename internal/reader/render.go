@@ -0,0 +1,235 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// Protocol is how a page image reaches the screen
+type Protocol string
+
+const (
+	ProtocolKitty  Protocol = "kitty"
+	ProtocolSixel  Protocol = "sixel"
+	ProtocolViewer Protocol = "viewer" // open pages in the system image viewer
+)
+
+// Approximate terminal cell size, used to size sixel images
+const (
+	cellWidth  = 10
+	cellHeight = 20
+)
+
+// kittyChunk is the largest base64 payload kitty accepts per escape sequence
+const kittyChunk = 4096
+
+// ParseProtocol accepts "kitty", "sixel" or "viewer"
+func ParseProtocol(s string) (Protocol, error) {
+	switch p := Protocol(strings.ToLower(s)); p {
+	case ProtocolKitty, ProtocolSixel, ProtocolViewer:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown image protocol %q (use kitty, sixel or viewer)", s)
+}
+
+// DetectProtocol guesses the terminal's image support from its environment
+func DetectProtocol(getenv func(string) string) Protocol {
+	term := strings.ToLower(getenv("TERM"))
+	program := strings.ToLower(getenv("TERM_PROGRAM"))
+
+	switch {
+	case getenv("KITTY_WINDOW_ID") != "", strings.Contains(term, "kitty"),
+		program == "wezterm", program == "ghostty", strings.Contains(term, "ghostty"):
+		return ProtocolKitty
+	case strings.Contains(term, "sixel"), strings.Contains(term, "mlterm"), strings.Contains(term, "foot"),
+		strings.Contains(term, "contour"), program == "iterm.app", getenv("WT_SESSION") != "":
+		return ProtocolSixel
+	}
+	return ProtocolViewer
+}
+
+// EncodeKitty writes a PNG with the kitty graphics protocol, scaled by the
+// terminal to fit cols x rows cells with its aspect ratio kept
+func EncodeKitty(w io.Writer, data []byte, cols, rows int) error {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("unsupported image: %w", err)
+	}
+	if format != "png" {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("unsupported image: %w", err)
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+
+	// Give kitty one dimension and let it work out the other
+	size := fmt.Sprintf("r=%d", rows)
+	if cfg.Height > 0 && cfg.Width*rows*cellHeight > cfg.Height*cols*cellWidth {
+		size = fmt.Sprintf("c=%d", cols)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	bw := bufio.NewWriter(w)
+	for first := true; encoded != "" || first; first = false {
+		chunk := encoded
+		if len(chunk) > kittyChunk {
+			chunk = chunk[:kittyChunk]
+		}
+		encoded = encoded[len(chunk):]
+		more := 0
+		if encoded != "" {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(bw, "\x1b_Ga=T,f=100,q=2,%s,m=%d;%s\x1b\\", size, more, chunk)
+		} else {
+			fmt.Fprintf(bw, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	return bw.Flush()
+}
+
+// ClearKitty deletes every image kitty is showing
+func ClearKitty(w io.Writer) {
+	io.WriteString(w, "\x1b_Ga=d,q=2\x1b\\")
+}
+
+// sixelPalette is 16 greys, for the mostly black and white pages, plus the
+// web-safe colours
+var sixelPalette = func() color.Palette {
+	p := make(color.Palette, 0, 16+len(palette.WebSafe))
+	for i := 0; i < 16; i++ {
+		v := uint8(i * 17)
+		p = append(p, color.Gray{Y: v})
+	}
+	return append(p, palette.WebSafe...)
+}()
+
+// EncodeSixel writes the image as sixel graphics, scaled to fit cols x rows cells
+func EncodeSixel(w io.Writer, data []byte, cols, rows int) error {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("unsupported image: %w", err)
+	}
+	img := fit(src, cols*cellWidth, rows*cellHeight)
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	pal := image.NewPaletted(image.Rect(0, 0, width, height), sixelPalette)
+	draw.FloydSteinberg.Draw(pal, pal.Bounds(), img, bounds.Min)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "\x1bPq\"1;1;%d;%d", width, height)
+	for i, c := range sixelPalette {
+		r, g, b, _ := c.RGBA()
+		fmt.Fprintf(bw, "#%d;2;%d;%d;%d", i, r*100/0xffff, g*100/0xffff, b*100/0xffff)
+	}
+
+	used := make([]bool, len(sixelPalette))
+	for top := 0; top < height; top += 6 {
+		for i := range used {
+			used[i] = false
+		}
+		for y := top; y < top+6 && y < height; y++ {
+			for _, idx := range pal.Pix[y*pal.Stride : y*pal.Stride+width] {
+				used[idx] = true
+			}
+		}
+
+		for c, ok := range used {
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(bw, "#%d", c)
+			run, last := 0, byte(0)
+			for x := 0; x < width; x++ {
+				bits := byte(0)
+				for dy := 0; dy < 6 && top+dy < height; dy++ {
+					if int(pal.Pix[(top+dy)*pal.Stride+x]) == c {
+						bits |= 1 << dy
+					}
+				}
+				ch := 63 + bits
+				if ch == last || run == 0 {
+					run++
+					last = ch
+					continue
+				}
+				writeSixelRun(bw, run, last)
+				run, last = 1, ch
+			}
+			writeSixelRun(bw, run, last)
+			bw.WriteByte('$')
+		}
+		bw.WriteByte('-')
+	}
+	bw.WriteString("\x1b\\")
+	return bw.Flush()
+}
+
+func writeSixelRun(w *bufio.Writer, run int, ch byte) {
+	if run > 3 {
+		fmt.Fprintf(w, "!%d%c", run, ch)
+		return
+	}
+	for i := 0; i < run; i++ {
+		w.WriteByte(ch)
+	}
+}
+
+// fit shrinks img to fit within maxW x maxH, averaging the source pixels
+// each target pixel covers. Smaller images are returned as they are.
+func fit(img image.Image, maxW, maxH int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxW && h <= maxH || w == 0 || h == 0 {
+		return img
+	}
+	scale := float64(maxW) / float64(w)
+	if s := float64(maxH) / float64(h); s < scale {
+		scale = s
+	}
+	dw, dh := int(float64(w)*scale), int(float64(h)*scale)
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := b.Min.Y+y*h/dh, b.Min.Y+(y+1)*h/dh
+		for x := 0; x < dw; x++ {
+			x0, x1 := b.Min.X+x*w/dw, b.Min.X+(x+1)*w/dw
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa), n+1
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
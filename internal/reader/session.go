@@ -0,0 +1,112 @@
+package reader
+
+import (
+	"sort"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/chapter"
+)
+
+// Key is a reader command decoded from terminal input
+type Key int
+
+const (
+	KeyNone Key = iota
+	KeyNext
+	KeyPrev
+	KeyFirst
+	KeyLast
+	KeyNextChapter
+	KeyPrevChapter
+	KeyOpen
+	KeyQuit
+)
+
+// ParseKey decodes one read from a raw-mode terminal
+func ParseKey(b []byte) Key {
+	switch string(b) {
+	case "\x1b[C", "\x1b[B", "\x1b[6~", "l", "j", "n", " ", "\r":
+		return KeyNext
+	case "\x1b[D", "\x1b[A", "\x1b[5~", "h", "k", "p", "\x7f":
+		return KeyPrev
+	case "g", "\x1b[H":
+		return KeyFirst
+	case "G", "\x1b[F":
+		return KeyLast
+	case "N", "]":
+		return KeyNextChapter
+	case "P", "[":
+		return KeyPrevChapter
+	case "o":
+		return KeyOpen
+	case "q", "Q", "\x03", "\x1b":
+		return KeyQuit
+	}
+	return KeyNone
+}
+
+// Position is the current page across one or more chapters
+type Position struct {
+	Books []*Book
+	Book  int
+	Page  int
+}
+
+// NewPosition orders the books by series and chapter number
+func NewPosition(books []*Book) *Position {
+	sort.SliceStable(books, func(i, j int) bool {
+		a, b := books[i].Info, books[j].Info
+		if a.Series != b.Series {
+			return a.Series < b.Series
+		}
+		ka, okA := chapter.SortKey(a.Number)
+		kb, okB := chapter.SortKey(b.Number)
+		if okA && okB && ka != kb {
+			return ka < kb
+		}
+		return okA && !okB
+	})
+	return &Position{Books: books}
+}
+
+func (p *Position) Current() *Book {
+	return p.Books[p.Book]
+}
+
+// AtEnd reports whether the current page is the chapter's last
+func (p *Position) AtEnd() bool {
+	return p.Page == p.Current().Len()-1
+}
+
+// Apply moves according to key, reporting whether the page changed. Paging
+// past either end of a chapter continues into the neighbouring one.
+func (p *Position) Apply(key Key) bool {
+	book, page := p.Book, p.Page
+	switch key {
+	case KeyNext:
+		if p.Page < p.Current().Len()-1 {
+			p.Page++
+		} else if p.Book < len(p.Books)-1 {
+			p.Book, p.Page = p.Book+1, 0
+		}
+	case KeyPrev:
+		if p.Page > 0 {
+			p.Page--
+		} else if p.Book > 0 {
+			p.Book--
+			p.Page = p.Current().Len() - 1
+		}
+	case KeyFirst:
+		p.Page = 0
+	case KeyLast:
+		p.Page = p.Current().Len() - 1
+	case KeyNextChapter:
+		if p.Book < len(p.Books)-1 {
+			p.Book, p.Page = p.Book+1, 0
+		}
+	case KeyPrevChapter:
+		if p.Book > 0 {
+			p.Book, p.Page = p.Book-1, 0
+		}
+	}
+	return book != p.Book || page != p.Page
+}
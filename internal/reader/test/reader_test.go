@@ -0,0 +1,176 @@
+package reader_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/download"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/reader"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+func pngPage(t *testing.T, w, h int, shade uint8) []byte {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = shade
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode page: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// writeCBZ builds an archive whose pages are stored out of order
+func writeCBZ(t *testing.T, dir, number string, names []string) string {
+	file := filepath.Join(dir, "ch"+number+".cbz")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for i, name := range names {
+		w, _ := zw.Create(name)
+		w.Write(pngPage(t, 4, 4, uint8(i)))
+	}
+	info := download.NewComicInfo(&models.Manga{ID: "13", Title: "One Piece"}, models.Chapter{ID: "chap-" + number, Number: number}, len(names))
+	w, _ := zw.Create("ComicInfo.xml")
+	xml.NewEncoder(w).Encode(info)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+	return file
+}
+
+func TestOpenBook(t *testing.T) {
+	file := writeCBZ(t, t.TempDir(), "12.5", []string{"10.png", "2.png", "1.png", "notes.txt"})
+
+	book, err := reader.Open(file)
+	if err != nil {
+		t.Fatalf("failed to open book: %v", err)
+	}
+	defer book.Close()
+
+	if book.Len() != 3 {
+		t.Fatalf("book has %d pages, want 3", book.Len())
+	}
+	for i, want := range []string{"1.png", "2.png", "10.png"} {
+		if _, name, _ := book.Page(i); name != want {
+			t.Errorf("page %d = %s, want %s", i+1, name, want)
+		}
+	}
+	if title := book.Title(); title != "One Piece Ch.12.5" {
+		t.Errorf("title = %q", title)
+	}
+	if n, ok := book.ChapterNumber(); !ok || n != 12 {
+		t.Errorf("chapter number = %d, %v; want 12", n, ok)
+	}
+	if mangaID, chapterID := book.Info.MangaHubIDs(); mangaID != "13" || chapterID != "chap-12.5" {
+		t.Errorf("ids = %q, %q", mangaID, chapterID)
+	}
+}
+
+func TestPositionCrossesChapters(t *testing.T) {
+	dir := t.TempDir()
+	var books []*reader.Book
+	for _, n := range []string{"10", "2"} {
+		b, err := reader.Open(writeCBZ(t, dir, n, []string{"1.png", "2.png"}))
+		if err != nil {
+			t.Fatalf("failed to open book: %v", err)
+		}
+		defer b.Close()
+		books = append(books, b)
+	}
+
+	pos := reader.NewPosition(books)
+	if pos.Current().Info.Number != "2" {
+		t.Fatalf("first chapter = %s, want 2", pos.Current().Info.Number)
+	}
+	if pos.Apply(reader.KeyPrev) {
+		t.Error("moved before the first page")
+	}
+	pos.Apply(reader.ParseKey([]byte("\x1b[C")))
+	if !pos.AtEnd() {
+		t.Error("second page of a two-page chapter is not the end")
+	}
+	pos.Apply(reader.KeyNext)
+	if pos.Current().Info.Number != "10" || pos.Page != 0 {
+		t.Errorf("after paging on: chapter %s page %d, want chapter 10 page 0", pos.Current().Info.Number, pos.Page)
+	}
+	pos.Apply(reader.KeyPrev)
+	if pos.Current().Info.Number != "2" || !pos.AtEnd() {
+		t.Errorf("paging back did not return to the end of chapter 2")
+	}
+	if reader.ParseKey([]byte("q")) != reader.KeyQuit || reader.ParseKey([]byte("x")) != reader.KeyNone {
+		t.Error("unexpected key mapping")
+	}
+}
+
+func TestEncoders(t *testing.T) {
+	var sixel bytes.Buffer
+	if err := reader.EncodeSixel(&sixel, pngPage(t, 100, 50, 128), 5, 2); err != nil {
+		t.Fatalf("sixel encode failed: %v", err)
+	}
+	out := sixel.String()
+	if !strings.HasPrefix(out, "\x1bPq\"1;1;50;25") || !strings.HasSuffix(out, "\x1b\\") {
+		t.Errorf("sixel output is not a 50x25 image: %q...", out[:20])
+	}
+
+	// Noise keeps the PNG large enough to need several kitty chunks
+	img := image.NewRGBA(image.Rect(0, 0, 120, 120))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7919 % 251)
+	}
+	img.Set(0, 0, color.White)
+	var jpg bytes.Buffer
+	jpeg.Encode(&jpg, img, &jpeg.Options{Quality: 100})
+
+	var kitty bytes.Buffer
+	if err := reader.EncodeKitty(&kitty, jpg.Bytes(), 80, 24); err != nil {
+		t.Fatalf("kitty encode failed: %v", err)
+	}
+	chunks := strings.Split(strings.TrimSuffix(kitty.String(), "\x1b\\"), "\x1b\\")
+	if len(chunks) < 2 {
+		t.Fatalf("got %d kitty chunks, want several", len(chunks))
+	}
+	if !strings.HasPrefix(chunks[0], "\x1b_Ga=T,f=100,q=2,r=24,m=1;") {
+		t.Errorf("unexpected first chunk header: %q", chunks[0][:40])
+	}
+	if !strings.HasPrefix(chunks[len(chunks)-1], "\x1b_Gm=0;") {
+		t.Errorf("last chunk does not end the transfer: %q", chunks[len(chunks)-1][:10])
+	}
+}
+
+func TestDetectProtocol(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(k string) string { return vars[k] }
+	}
+	cases := []struct {
+		vars map[string]string
+		want reader.Protocol
+	}{
+		{map[string]string{"TERM": "xterm-kitty"}, reader.ProtocolKitty},
+		{map[string]string{"TERM_PROGRAM": "WezTerm"}, reader.ProtocolKitty},
+		{map[string]string{"TERM": "foot"}, reader.ProtocolSixel},
+		{map[string]string{"TERM": "xterm-256color"}, reader.ProtocolViewer},
+	}
+	for _, c := range cases {
+		if got := reader.DetectProtocol(env(c.vars)); got != c.want {
+			t.Errorf("DetectProtocol(%v) = %s, want %s", c.vars, got, c.want)
+		}
+	}
+	if _, err := reader.ParseProtocol("ascii"); err == nil {
+		t.Error("unknown protocol accepted")
+	}
+}
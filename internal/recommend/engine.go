@@ -0,0 +1,148 @@
+package recommend
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/config"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+// Config controls how often recommendations are recomputed and how content
+// and collaborative scores are blended
+type Config struct {
+	Interval      time.Duration
+	ContentWeight float64 // 0..1, the rest goes to collaborative filtering
+	PerUser       int     // recommendations kept per user
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Interval:      time.Hour,
+		ContentWeight: 0.4,
+		PerUser:       20,
+	}
+}
+
+// ConfigFromEnv reads RECOMMEND_INTERVAL_MINUTES, RECOMMEND_CONTENT_PERCENT
+// and RECOMMEND_PER_USER, falling back to the defaults.
+func ConfigFromEnv() Config {
+	defaults := DefaultConfig()
+	return Config{
+		Interval:      time.Duration(config.GetEnvInt("RECOMMEND_INTERVAL_MINUTES", int(defaults.Interval/time.Minute))) * time.Minute,
+		ContentWeight: float64(config.GetEnvInt("RECOMMEND_CONTENT_PERCENT", int(defaults.ContentWeight*100))) / 100,
+		PerUser:       config.GetEnvInt("RECOMMEND_PER_USER", defaults.PerUser),
+	}
+}
+
+// Engine periodically recomputes every user's recommendations from the
+// library and catalogue and serves them from memory
+type Engine struct {
+	store  *repository.Store
+	logger *logger.Logger
+	config Config
+
+	mu         sync.RWMutex
+	byUser     map[string][]models.Recommendation
+	computedAt time.Time
+
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+func NewEngine(store *repository.Store, log *logger.Logger, cfg Config) *Engine {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultConfig().Interval
+	}
+	if cfg.ContentWeight < 0 || cfg.ContentWeight > 1 {
+		cfg.ContentWeight = DefaultConfig().ContentWeight
+	}
+	return &Engine{
+		store:    store,
+		logger:   log,
+		config:   cfg,
+		byUser:   make(map[string][]models.Recommendation),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start computes recommendations in the background now and then every Interval
+func (e *Engine) Start() {
+	e.logger.Info("recommendation_engine_started",
+		"interval", e.config.Interval.String(),
+		"content_weight", e.config.ContentWeight)
+	go e.refreshLoop()
+}
+
+func (e *Engine) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stopChan)
+	})
+}
+
+// Refresh recomputes recommendations for every user
+func (e *Engine) Refresh(ctx context.Context) error {
+	started := time.Now()
+
+	entries, err := e.store.Progress.All(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load libraries: %w", err)
+	}
+	catalogue, err := e.store.Manga.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load manga: %w", err)
+	}
+
+	byUser := compute(entries, catalogue, e.config.ContentWeight, e.config.PerUser)
+
+	e.mu.Lock()
+	e.byUser = byUser
+	e.computedAt = time.Now()
+	e.mu.Unlock()
+
+	e.logger.Debug("recommendations_refreshed",
+		"users", len(byUser),
+		"manga", len(catalogue),
+		"duration_ms", time.Since(started).Milliseconds())
+	return nil
+}
+
+// For returns up to limit recommendations for the user and when they were
+// computed. The time is zero until the first refresh finishes.
+func (e *Engine) For(userID string, limit int) ([]models.Recommendation, time.Time) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	recs := e.byUser[userID]
+	if limit > 0 && len(recs) > limit {
+		recs = recs[:limit]
+	}
+	out := make([]models.Recommendation, len(recs))
+	copy(out, recs)
+	return out, e.computedAt
+}
+
+func (e *Engine) refreshLoop() {
+	if err := e.Refresh(context.Background()); err != nil {
+		e.logger.Warn("recommendation_refresh_failed", "error", err.Error())
+	}
+
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := e.Refresh(context.Background()); err != nil {
+				e.logger.Warn("recommendation_refresh_failed", "error", err.Error())
+			}
+		case <-e.stopChan:
+			e.logger.Info("recommendation_engine_stopped")
+			return
+		}
+	}
+}
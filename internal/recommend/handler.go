@@ -0,0 +1,39 @@
+package recommend
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the current user's recommendations
+type Handler struct {
+	engine *Engine
+}
+
+func NewHandler(engine *Engine) *Handler {
+	return &Handler{engine: engine}
+}
+
+// GetRecommendations returns what the current user might read next (?limit=, default 10)
+func (h *Handler) GetRecommendations(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+
+	recs, computedAt := h.engine.For(userID, limit)
+	resp := models.RecommendationsResponse{Recommendations: recs, Count: len(recs)}
+	if !computedAt.IsZero() {
+		resp.ComputedAt = &computedAt
+	}
+	c.JSON(http.StatusOK, resp)
+}
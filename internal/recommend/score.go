@@ -0,0 +1,244 @@
+package recommend
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+// similarityShrinkage damps item similarities backed by only a few co-raters
+const similarityShrinkage = 2.0
+
+// preference turns a library entry into a signed signal around a neutral 3★.
+// Unrated entries count mildly in favour if the user is reading or finished
+// them; plan_to_read carries no signal.
+func preference(p models.UserProgress) float64 {
+	if p.UserRating != nil {
+		return *p.UserRating - 3
+	}
+	switch p.Status {
+	case repository.StatusCompleted:
+		return 1
+	case repository.StatusReading:
+		return 0.5
+	}
+	return 0
+}
+
+// reasonFor explains a recommendation in terms of the entry it came from
+func reasonFor(p models.UserProgress, title string) string {
+	switch {
+	case p.UserRating != nil:
+		return fmt.Sprintf("because you rated %s %d★", title, int(math.Round(*p.UserRating)))
+	case p.Status == repository.StatusCompleted:
+		return "because you finished " + title
+	default:
+		return "because you're reading " + title
+	}
+}
+
+type candidate struct {
+	mangaID string
+	content float64
+	cf      float64
+	hasCF   bool
+	reasons []string
+}
+
+// compute scores every catalogue manga outside each user's library. Content
+// similarity compares the manga's genres and author with a profile built from
+// the user's preferences; collaborative similarity is the cosine between
+// manga over users who have both in their library. The two are blended with
+// contentWeight and the best perUser results are kept.
+func compute(entries []models.UserProgress, catalogue []models.Manga, contentWeight float64, perUser int) map[string][]models.Recommendation {
+	mangaByID := make(map[string]models.Manga, len(catalogue))
+	for _, m := range catalogue {
+		mangaByID[m.ID] = m
+	}
+
+	library := make(map[string]map[string]models.UserProgress)
+	prefs := make(map[string]map[string]float64)
+	for _, p := range entries {
+		if library[p.UserID] == nil {
+			library[p.UserID] = make(map[string]models.UserProgress)
+			prefs[p.UserID] = make(map[string]float64)
+		}
+		library[p.UserID][p.MangaID] = p
+		if pref := preference(p); pref != 0 {
+			prefs[p.UserID][p.MangaID] = pref
+		}
+	}
+
+	// Item-item cosine similarity over co-rating users
+	norms := make(map[string]float64)
+	dots := make(map[string]map[string]float64)
+	coRaters := make(map[string]map[string]int)
+	for _, userPrefs := range prefs {
+		for i, pi := range userPrefs {
+			norms[i] += pi * pi
+			for j, pj := range userPrefs {
+				if i == j {
+					continue
+				}
+				if dots[i] == nil {
+					dots[i] = make(map[string]float64)
+					coRaters[i] = make(map[string]int)
+				}
+				dots[i][j] += pi * pj
+				coRaters[i][j]++
+			}
+		}
+	}
+	similarity := func(i, j string) float64 {
+		dot, ok := dots[i][j]
+		if !ok || norms[i] == 0 || norms[j] == 0 {
+			return 0
+		}
+		n := float64(coRaters[i][j])
+		return dot / (math.Sqrt(norms[i]) * math.Sqrt(norms[j])) * n / (n + similarityShrinkage)
+	}
+
+	results := make(map[string][]models.Recommendation, len(prefs))
+	for userID, userPrefs := range prefs {
+		if len(userPrefs) == 0 {
+			continue
+		}
+		genreWeights, authorWeights, profileNorm := profile(userPrefs, mangaByID)
+
+		var candidates []candidate
+		for _, m := range catalogue {
+			if _, inLibrary := library[userID][m.ID]; inLibrary {
+				continue
+			}
+			c := candidate{mangaID: m.ID}
+
+			// Collaborative part: similarity-weighted average of the user's
+			// preferences, damped when the total similarity is small
+			var weighted, totalSim, best float64
+			var bestSource string
+			for i, pi := range userPrefs {
+				sim := similarity(i, m.ID)
+				if sim == 0 {
+					continue
+				}
+				weighted += sim * pi
+				totalSim += math.Abs(sim)
+				if sim*pi > best {
+					best, bestSource = sim*pi, i
+				}
+			}
+			if totalSim > 0 {
+				c.hasCF = true
+				c.cf = weighted / totalSim / 2 * totalSim / (totalSim + 1)
+				if bestSource != "" && c.cf > 0 {
+					c.reasons = append(c.reasons, reasonFor(library[userID][bestSource], titleOf(mangaByID, bestSource)))
+				}
+			}
+
+			// Content part: cosine between the user's profile and the manga's features
+			if profileNorm > 0 {
+				var dot float64
+				features := 0
+				var liked []string
+				for _, g := range m.Genres {
+					key := strings.ToLower(g)
+					features++
+					dot += genreWeights[key]
+					if genreWeights[key] > 0 {
+						liked = append(liked, g)
+					}
+				}
+				author := strings.ToLower(strings.TrimSpace(m.Author))
+				if author != "" {
+					features++
+					dot += authorWeights[author]
+				}
+				if features > 0 {
+					c.content = dot / (profileNorm * math.Sqrt(float64(features)))
+				}
+				if c.content > 0 {
+					sort.SliceStable(liked, func(a, b int) bool {
+						return genreWeights[strings.ToLower(liked[a])] > genreWeights[strings.ToLower(liked[b])]
+					})
+					if len(liked) > 2 {
+						liked = liked[:2]
+					}
+					if len(liked) > 0 {
+						c.reasons = append(c.reasons, "because you like "+strings.Join(liked, " and "))
+					}
+					if author != "" && authorWeights[author] > 0 {
+						c.reasons = append(c.reasons, "because you enjoy books by "+m.Author)
+					}
+				}
+			}
+
+			candidates = append(candidates, c)
+		}
+
+		recs := []models.Recommendation{}
+		for _, c := range candidates {
+			score := contentWeight * c.content
+			if c.hasCF {
+				score += (1 - contentWeight) * c.cf
+			}
+			if score <= 0 {
+				continue
+			}
+			recs = append(recs, models.Recommendation{
+				Manga:   mangaByID[c.mangaID],
+				Score:   math.Round(score*1000) / 1000,
+				Reasons: c.reasons,
+			})
+		}
+		sort.SliceStable(recs, func(a, b int) bool {
+			if recs[a].Score != recs[b].Score {
+				return recs[a].Score > recs[b].Score
+			}
+			return recs[a].Manga.Title < recs[b].Manga.Title
+		})
+		if perUser > 0 && len(recs) > perUser {
+			recs = recs[:perUser]
+		}
+		results[userID] = recs
+	}
+	return results
+}
+
+// profile sums the user's preferences per genre and author, keyed in lower
+// case, and returns the norm of the combined vector
+func profile(userPrefs map[string]float64, mangaByID map[string]models.Manga) (map[string]float64, map[string]float64, float64) {
+	genres := make(map[string]float64)
+	authors := make(map[string]float64)
+	for mangaID, pref := range userPrefs {
+		m, ok := mangaByID[mangaID]
+		if !ok {
+			continue
+		}
+		for _, g := range m.Genres {
+			genres[strings.ToLower(g)] += pref
+		}
+		if author := strings.ToLower(strings.TrimSpace(m.Author)); author != "" {
+			authors[author] += pref
+		}
+	}
+
+	var sum float64
+	for _, w := range genres {
+		sum += w * w
+	}
+	for _, w := range authors {
+		sum += w * w
+	}
+	return genres, authors, math.Sqrt(sum)
+}
+
+func titleOf(mangaByID map[string]models.Manga, id string) string {
+	if m, ok := mangaByID[id]; ok && m.Title != "" {
+		return m.Title
+	}
+	return id
+}
@@ -0,0 +1,165 @@
+package recommend_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/recommend"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+// newEngine seeds a memory store with a small catalogue and the given ratings
+// (user -> manga -> stars, 0 meaning completed without a rating)
+func newEngine(t *testing.T, ratings map[string]map[string]float64) *recommend.Engine {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+
+	for _, m := range []models.Manga{
+		{ID: "berserk", Title: "Berserk", Author: "Kentaro Miura", Genres: []string{"Action", "Dark Fantasy"}},
+		{ID: "claymore", Title: "Claymore", Author: "Norihiro Yagi", Genres: []string{"Action", "Dark Fantasy"}},
+		{ID: "vagabond", Title: "Vagabond", Author: "Takehiko Inoue", Genres: []string{"Action", "Historical"}},
+		{ID: "slamdunk", Title: "Slam Dunk", Author: "Takehiko Inoue", Genres: []string{"Sports"}},
+		{ID: "yotsuba", Title: "Yotsuba&!", Author: "Kiyohiko Azuma", Genres: []string{"Comedy", "Slice of Life"}},
+	} {
+		if err := store.Manga.Create(ctx, &m); err != nil {
+			t.Fatalf("create manga: %v", err)
+		}
+	}
+
+	for userID, byManga := range ratings {
+		for mangaID, stars := range byManga {
+			req := models.UpdateProgressRequest{MangaID: mangaID, Status: repository.StatusCompleted}
+			if stars > 0 {
+				req.UserRating = floatPtr(stars)
+			}
+			if _, err := store.Progress.AddToLibrary(ctx, userID, mangaID, ""); err != nil {
+				t.Fatalf("add to library: %v", err)
+			}
+			if _, err := store.Progress.Update(ctx, userID, req); err != nil {
+				t.Fatalf("update progress: %v", err)
+			}
+		}
+	}
+
+	engine := recommend.NewEngine(store, logger.New(logger.ERROR, false, os.Stdout), recommend.DefaultConfig())
+	if err := engine.Refresh(ctx); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	return engine
+}
+
+func ids(recs []models.Recommendation) []string {
+	out := make([]string, len(recs))
+	for i, r := range recs {
+		out[i] = r.Manga.ID
+	}
+	return out
+}
+
+func TestCollaborativeRecommendationExplainsSource(t *testing.T) {
+	engine := newEngine(t, map[string]map[string]float64{
+		"alice": {"yotsuba": 5, "slamdunk": 5},
+		"bob":   {"yotsuba": 5, "slamdunk": 4},
+		"carol": {"yotsuba": 5},
+	})
+
+	recs, computedAt := engine.For("carol", 10)
+	if computedAt.IsZero() {
+		t.Error("expected computed time after refresh")
+	}
+	if len(recs) == 0 || recs[0].Manga.ID != "slamdunk" {
+		t.Fatalf("expected Slam Dunk first for carol, got %v", ids(recs))
+	}
+	if !strings.Contains(strings.Join(recs[0].Reasons, "|"), "because you rated Yotsuba&! 5★") {
+		t.Errorf("expected a reason naming the co-rated manga, got %v", recs[0].Reasons)
+	}
+}
+
+func TestContentRecommendationFollowsGenresAndAuthors(t *testing.T) {
+	engine := newEngine(t, map[string]map[string]float64{
+		"dave": {"berserk": 5, "yotsuba": 1},
+	})
+
+	recs, _ := engine.For("dave", 10)
+	got := ids(recs)
+	if len(got) == 0 || got[0] != "claymore" {
+		t.Fatalf("expected Claymore first for a Berserk fan, got %v", got)
+	}
+	if !strings.Contains(strings.Join(recs[0].Reasons, "|"), "because you like") {
+		t.Errorf("expected a genre reason, got %v", recs[0].Reasons)
+	}
+	for _, id := range got {
+		if id == "berserk" || id == "yotsuba" {
+			t.Errorf("manga already in the library was recommended: %v", got)
+		}
+		if id == "slamdunk" {
+			t.Errorf("unrelated manga should not be recommended: %v", got)
+		}
+	}
+
+	if recs, _ := engine.For("dave", 1); len(recs) != 1 {
+		t.Errorf("expected limit to apply, got %d", len(recs))
+	}
+}
+
+func TestUnratedCompletedCountsAsInterest(t *testing.T) {
+	engine := newEngine(t, map[string]map[string]float64{
+		"erin": {"vagabond": 0},
+	})
+
+	recs, _ := engine.For("erin", 10)
+	found := false
+	for _, r := range recs {
+		if r.Manga.ID == "slamdunk" && strings.Contains(strings.Join(r.Reasons, "|"), "Takehiko Inoue") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected another Inoue manga with an author reason, got %+v", recs)
+	}
+
+	if recs, _ := engine.For("nobody", 10); len(recs) != 0 {
+		t.Errorf("expected nothing for a user without a library, got %v", ids(recs))
+	}
+}
+
+func TestGetRecommendationsHandler(t *testing.T) {
+	engine := newEngine(t, map[string]map[string]float64{
+		"dave": {"berserk": 5},
+	})
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+	})
+	router.GET("/users/recommendations", recommend.NewHandler(engine).GetRecommendations)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/recommendations", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status without user = %d, want 401", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/users/recommendations?limit=2", nil)
+	req.Header.Set("X-Test-User", "dave")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp models.RecommendationsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", w.Code, w.Body.String())
+	}
+	if resp.Count != 2 || resp.ComputedAt == nil || len(resp.Recommendations[0].Reasons) == 0 {
+		t.Errorf("unexpected recommendations %+v", resp)
+	}
+}
@@ -0,0 +1,1316 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/google/uuid"
+)
+
+// memoryData is the state shared by the in-memory repositories, so that
+// progress can see manga and messages can see usernames as they would
+// through SQL joins.
+type memoryData struct {
+	mu            sync.RWMutex
+	users         map[string]*models.User
+	manga         map[string]*models.Manga
+	progress      map[string]map[string]*models.UserProgress
+	conversations map[string]*models.Conversation
+	members       map[string]map[string]string
+	reads         map[string]*readMarker         // conversation/user
+	sanctions     map[string]models.RoomSanction // conversation/user/kind
+	messages      []models.ChatMessage
+	reactions     map[string]map[string]map[string]bool // message -> emoji -> users
+	direct        []models.ChatMessage
+	history       map[string][]models.ReadingEvent
+	tags          map[string]map[string][]string
+	alerts        []models.ChatAlert
+	keywords      []models.KeywordAlert
+	presence      map[string]models.UserPresence
+}
+
+// NewMemoryStore returns repositories that keep everything in memory, for tests
+func NewMemoryStore() *Store {
+	d := &memoryData{
+		users:         make(map[string]*models.User),
+		manga:         make(map[string]*models.Manga),
+		progress:      make(map[string]map[string]*models.UserProgress),
+		conversations: make(map[string]*models.Conversation),
+		members:       make(map[string]map[string]string),
+		reads:         make(map[string]*readMarker),
+		sanctions:     make(map[string]models.RoomSanction),
+		reactions:     make(map[string]map[string]map[string]bool),
+		history:       make(map[string][]models.ReadingEvent),
+		tags:          make(map[string]map[string][]string),
+		presence:      make(map[string]models.UserPresence),
+	}
+	now := time.Now()
+	d.conversations["global"] = &models.Conversation{ID: "global", Name: "global", Type: "global", CreatedAt: now, LastMessageAt: &now}
+
+	return &Store{
+		Users:         &memoryUsers{d},
+		Manga:         &memoryManga{d},
+		Progress:      &memoryProgress{d},
+		Conversations: &memoryConversations{d},
+		Messages:      &memoryMessages{d},
+		Alerts:        &memoryAlerts{d},
+		Presence:      &memoryPresence{d},
+	}
+}
+
+// ---- users ----
+
+type memoryUsers struct {
+	*memoryData
+}
+
+func (r *memoryUsers) Create(ctx context.Context, u *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Username == u.Username {
+			return ErrUsernameTaken
+		}
+		if u.Email != "" && existing.Email == u.Email {
+			return ErrEmailTaken
+		}
+	}
+	if u.ID == "" {
+		u.ID = uuid.New().String()
+	}
+	if u.Role == "" {
+		u.Role = RoleMember
+	}
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now().UTC()
+	}
+	stored := *u
+	r.users[u.ID] = &stored
+	return nil
+}
+
+func (r *memoryUsers) find(match func(*models.User) bool) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, u := range r.users {
+		if match(u) {
+			found := *u
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryUsers) GetByID(ctx context.Context, id string) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.ID == id })
+}
+
+func (r *memoryUsers) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.Username == username })
+}
+
+func (r *memoryUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.Email == email })
+}
+
+func (r *memoryUsers) update(id string, apply func(u *models.User) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	return apply(u)
+}
+
+func (r *memoryUsers) UpdatePassword(ctx context.Context, id, passwordHash string) error {
+	return r.update(id, func(u *models.User) error {
+		u.PasswordHash = passwordHash
+		return nil
+	})
+}
+
+func (r *memoryUsers) UpdateEmail(ctx context.Context, id, email string) error {
+	return r.update(id, func(u *models.User) error {
+		for _, other := range r.users {
+			if other.ID != id && other.Email == email {
+				return ErrEmailTaken
+			}
+		}
+		u.Email = email
+		return nil
+	})
+}
+
+func (r *memoryUsers) UpdateUsername(ctx context.Context, id, username string) error {
+	return r.update(id, func(u *models.User) error {
+		for _, other := range r.users {
+			if other.ID != id && other.Username == username {
+				return ErrUsernameTaken
+			}
+		}
+		u.Username = username
+		return nil
+	})
+}
+
+// ---- manga ----
+
+type memoryManga struct {
+	*memoryData
+}
+
+func (r *memoryManga) Get(ctx context.Context, id string) (*models.Manga, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m, ok := r.manga[id]
+	if !ok {
+		return nil, ErrMangaNotFound
+	}
+	found := *m
+	return &found, nil
+}
+
+func (r *memoryManga) Exists(ctx context.Context, id string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.manga[id]
+	return ok, nil
+}
+
+func (r *memoryManga) store(m *models.Manga) {
+	stored := *m
+	if stored.MediaType == "" {
+		stored.MediaType = "manga"
+	}
+	r.manga[m.ID] = &stored
+}
+
+func (r *memoryManga) Create(ctx context.Context, m *models.Manga) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.manga[m.ID]; ok {
+		return ErrAlreadyExists
+	}
+	r.store(m)
+	return nil
+}
+
+func (r *memoryManga) Upsert(ctx context.Context, m *models.Manga) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.store(m)
+	return nil
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func (f MangaFilter) matches(m *models.Manga) bool {
+	if f.Title != "" && !containsFold(m.Title, f.Title) {
+		return false
+	}
+	if f.Author != "" && !containsFold(m.Author, f.Author) {
+		return false
+	}
+	if f.Status != "" && m.Status != f.Status {
+		return false
+	}
+	if f.MediaType != "" && m.MediaType != f.MediaType {
+		return false
+	}
+	for _, g := range f.Genres {
+		if !containsFold(strings.Join(m.Genres, ","), g) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *memoryManga) Search(ctx context.Context, f MangaFilter) ([]models.Manga, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []models.Manga
+	for _, m := range r.manga {
+		if f.matches(m) {
+			matched = append(matched, *m)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ID < matched[j].ID })
+
+	total := len(matched)
+	if f.Offset >= total {
+		return nil, total, nil
+	}
+	matched = matched[f.Offset:]
+	if f.Limit > 0 && f.Limit < len(matched) {
+		matched = matched[:f.Limit]
+	}
+	return matched, total, nil
+}
+
+func (r *memoryManga) List(ctx context.Context) ([]models.Manga, error) {
+	mangas, _, err := r.Search(ctx, MangaFilter{})
+	return mangas, err
+}
+
+func (r *memoryManga) UpdateTotalChapters(ctx context.Context, id string, total int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.manga[id]
+	if !ok {
+		return ErrMangaNotFound
+	}
+	m.TotalChapters = total
+	return nil
+}
+
+func (r *memoryManga) RatingStats(ctx context.Context, id string) (*models.RatingStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := &models.RatingStats{Distribution: map[string]int{"5": 0, "4": 0, "3": 0, "2": 0, "1": 0}}
+	var sum float64
+	for _, entries := range r.progress {
+		p, ok := entries[id]
+		if !ok || p.UserRating == nil {
+			continue
+		}
+		sum += *p.UserRating
+		stats.TotalCount++
+		if rounded := int(*p.UserRating + 0.5); rounded >= 1 && rounded <= 5 {
+			stats.Distribution[strconv.Itoa(rounded)]++
+		}
+	}
+	if stats.TotalCount > 0 {
+		stats.Average = float64(int(sum/float64(stats.TotalCount)*10+0.5)) / 10
+	}
+	return stats, nil
+}
+
+// ---- progress ----
+
+type memoryProgress struct {
+	*memoryData
+}
+
+func (r *memoryProgress) Get(ctx context.Context, userID, mangaID string) (*models.UserProgress, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.get(userID, mangaID)
+}
+
+func (r *memoryProgress) get(userID, mangaID string) (*models.UserProgress, error) {
+	p, ok := r.progress[userID][mangaID]
+	if !ok {
+		return nil, ErrNotInLibrary
+	}
+	found := *p
+	return &found, nil
+}
+
+func (r *memoryProgress) withManga(p *models.UserProgress) models.MangaProgress {
+	mp := models.MangaProgress{
+		Manga:          models.Manga{ID: p.MangaID},
+		CurrentChapter: p.CurrentChapter,
+		Status:         p.Status,
+		UserRating:     p.UserRating,
+		IsFavorite:     p.IsFavorite,
+		Tags:           append([]string{}, r.tags[p.UserID][p.MangaID]...),
+		UpdatedAt:      p.UpdatedAt,
+	}
+	if m, ok := r.manga[p.MangaID]; ok {
+		mp.Manga = *m
+	}
+	return mp
+}
+
+func (r *memoryProgress) Library(ctx context.Context, userID string) ([]models.MangaProgress, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	library := []models.MangaProgress{}
+	for mangaID, p := range r.progress[userID] {
+		if _, ok := r.manga[mangaID]; ok {
+			library = append(library, r.withManga(p))
+		}
+	}
+	sort.SliceStable(library, func(i, j int) bool { return library[i].UpdatedAt.After(library[j].UpdatedAt) })
+	return library, nil
+}
+
+func (r *memoryProgress) All(ctx context.Context) ([]models.UserProgress, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := []models.UserProgress{}
+	for _, byManga := range r.progress {
+		for _, p := range byManga {
+			entries = append(entries, *p)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].UserID != entries[j].UserID {
+			return entries[i].UserID < entries[j].UserID
+		}
+		return entries[i].MangaID < entries[j].MangaID
+	})
+	return entries, nil
+}
+
+func (r *memoryProgress) Latest(ctx context.Context, userID string) (*models.MangaProgress, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *models.UserProgress
+	for _, p := range r.progress[userID] {
+		if latest == nil || p.UpdatedAt.After(latest.UpdatedAt) {
+			latest = p
+		}
+	}
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+	mp := r.withManga(latest)
+	return &mp, nil
+}
+
+func (r *memoryProgress) put(p *models.UserProgress) {
+	entries, ok := r.progress[p.UserID]
+	if !ok {
+		entries = make(map[string]*models.UserProgress)
+		r.progress[p.UserID] = entries
+	}
+	entries[p.MangaID] = p
+}
+
+func (r *memoryProgress) AddToLibrary(ctx context.Context, userID, mangaID, status string) (*models.UserProgress, error) {
+	if status == "" {
+		status = StatusPlanToRead
+	}
+	if err := ValidateStatus(status); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.manga[mangaID]; !ok {
+		return nil, ErrMangaNotFound
+	}
+	p, ok := r.progress[userID][mangaID]
+	if !ok {
+		p = &models.UserProgress{UserID: userID, MangaID: mangaID}
+		r.put(p)
+	}
+	p.Status = status
+	p.UpdatedAt = time.Now()
+	return r.get(userID, mangaID)
+}
+
+func (r *memoryProgress) Update(ctx context.Context, userID string, req models.UpdateProgressRequest) (*models.UserProgress, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.progress[userID][req.MangaID]
+	if !ok {
+		return nil, ErrNotInLibrary
+	}
+
+	updated := *p
+	if req.CurrentChapter != nil {
+		if *req.CurrentChapter < 0 {
+			return nil, ErrInvalidChapter
+		}
+		totalChapters := 0
+		if m, ok := r.manga[req.MangaID]; ok {
+			totalChapters = m.TotalChapters
+		}
+		updated.CurrentChapter = *req.CurrentChapter
+		updated.Status = StatusForChapter(*req.CurrentChapter, totalChapters)
+	} else if req.Status != "" {
+		if err := ValidateStatus(req.Status); err != nil {
+			return nil, err
+		}
+		updated.Status = req.Status
+	}
+	if req.UserRating != nil {
+		if err := ValidateRating(*req.UserRating); err != nil {
+			return nil, err
+		}
+		rating := *req.UserRating
+		updated.UserRating = &rating
+	}
+	updated.UpdatedAt = time.Now()
+
+	r.recordRead(userID, req.MangaID, p.CurrentChapter, updated.CurrentChapter)
+	*p = updated
+	return r.get(userID, req.MangaID)
+}
+
+func (r *memoryProgress) Sync(ctx context.Context, userID, mangaID string, chapter int, status string) (*models.MangaProgress, error) {
+	if chapter < 0 {
+		return nil, ErrInvalidChapter
+	}
+	if status != "" {
+		if err := ValidateStatus(status); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.manga[mangaID]; !ok {
+		return nil, ErrMangaNotFound
+	}
+	p, ok := r.progress[userID][mangaID]
+	if !ok {
+		p = &models.UserProgress{UserID: userID, MangaID: mangaID, Status: StatusReading}
+		r.put(p)
+	}
+	r.recordRead(userID, mangaID, p.CurrentChapter, chapter)
+	p.CurrentChapter = chapter
+	if status != "" {
+		p.Status = status
+	}
+	p.UpdatedAt = time.Now()
+
+	mp := r.withManga(p)
+	return &mp, nil
+}
+
+// recordRead must be called with the lock held
+func (r *memoryProgress) recordRead(userID, mangaID string, previous, chapter int) {
+	if chapter <= previous {
+		return
+	}
+	r.history[userID] = append(r.history[userID], models.ReadingEvent{
+		MangaID:      mangaID,
+		Chapter:      chapter,
+		ChaptersRead: chapter - previous,
+		ReadAt:       time.Now().UTC(),
+	})
+}
+
+func (r *memoryProgress) History(ctx context.Context, userID string, since time.Time) ([]models.ReadingEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := []models.ReadingEvent{}
+	for _, e := range r.history[userID] {
+		if !e.ReadAt.Before(since) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (r *memoryProgress) Remove(ctx context.Context, userID, mangaID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.progress[userID][mangaID]; !ok {
+		return ErrNotInLibrary
+	}
+	delete(r.progress[userID], mangaID)
+	delete(r.tags[userID], mangaID)
+	return nil
+}
+
+func (r *memoryProgress) SetFavorite(ctx context.Context, userID, mangaID string, favorite bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.progress[userID][mangaID]
+	if !ok {
+		return ErrNotInLibrary
+	}
+	p.IsFavorite = favorite
+	return nil
+}
+
+func (r *memoryProgress) SetTags(ctx context.Context, userID, mangaID string, tags []string) ([]string, error) {
+	normalized, err := NormalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.progress[userID][mangaID]; !ok {
+		return nil, ErrNotInLibrary
+	}
+	byManga, ok := r.tags[userID]
+	if !ok {
+		byManga = make(map[string][]string)
+		r.tags[userID] = byManga
+	}
+	byManga[mangaID] = normalized
+	return append([]string{}, normalized...), nil
+}
+
+// ---- conversations ----
+
+type memoryConversations struct {
+	*memoryData
+}
+
+func (r *memoryConversations) Get(ctx context.Context, id string) (*models.Conversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.conversations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	found := *c
+	return &found, nil
+}
+
+func (r *memoryConversations) GetByName(ctx context.Context, name string) (*models.Conversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, c := range r.conversations {
+		if c.Name == name {
+			found := *c
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryConversations) Create(ctx context.Context, conv *models.Conversation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.conversations {
+		if c.Name == conv.Name || c.ID == conv.ID {
+			return ErrAlreadyExists
+		}
+	}
+	if conv.ID == "" {
+		conv.ID = uuid.New().String()
+	}
+	now := time.Now()
+	conv.CreatedAt = now
+	conv.LastMessageAt = &now
+	stored := *conv
+	r.conversations[conv.ID] = &stored
+	return nil
+}
+
+func (r *memoryConversations) AddMember(ctx context.Context, conversationID, userID, role string) error {
+	if role == "" {
+		role = RoleMember
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	members, ok := r.members[conversationID]
+	if !ok {
+		members = make(map[string]string)
+		r.members[conversationID] = members
+	}
+	if _, ok := members[userID]; !ok {
+		members[userID] = role
+	}
+	return nil
+}
+
+func (r *memoryConversations) List(ctx context.Context) ([]models.ConversationSummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	summaries := []models.ConversationSummary{}
+	for _, c := range r.conversations {
+		if c.Type == "direct" {
+			continue
+		}
+		s := models.ConversationSummary{
+			Conversation: models.Conversation{ID: c.ID, Name: c.Name, Type: c.Type, CreatedAt: c.CreatedAt, Topic: c.Topic},
+			MemberCount:  len(r.members[c.ID]),
+		}
+		for _, m := range r.messages {
+			if m.ConversationID == c.ID && (s.LastMessageAt == nil || m.CreatedAt.After(*s.LastMessageAt)) {
+				at := m.CreatedAt
+				s.LastMessageAt = &at
+			}
+		}
+		summaries = append(summaries, s)
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		a, b := summaries[i].LastMessageAt, summaries[j].LastMessageAt
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return a.After(*b)
+	})
+	return summaries, nil
+}
+
+func (r *memoryConversations) GetOrCreateDirect(ctx context.Context, userA, userB string) (*models.Conversation, error) {
+	name := DirectConversationName(userA, userB)
+	conv, err := r.GetByName(ctx, name)
+	if err == ErrNotFound {
+		conv = &models.Conversation{Name: name, Type: "direct", CreatedBy: userA}
+		if err = r.Create(ctx, conv); err == ErrAlreadyExists {
+			conv, err = r.GetByName(ctx, name)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.AddMember(ctx, conv.ID, userA, RoleMember)
+	r.AddMember(ctx, conv.ID, userB, RoleMember)
+	return conv, nil
+}
+
+func (r *memoryConversations) ListDirect(ctx context.Context, userID string) ([]models.DirectConversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	inbox := []models.DirectConversation{}
+	for _, c := range r.conversations {
+		if c.Type != "direct" {
+			continue
+		}
+		if _, ok := r.members[c.ID][userID]; !ok {
+			continue
+		}
+		d := models.DirectConversation{Conversation: *c}
+		if marker, ok := r.reads[readKey(c.ID, userID)]; ok {
+			d.UnreadCount = marker.unread
+		}
+		for member := range r.members[c.ID] {
+			if member != userID {
+				d.OtherUserID = member
+				if u, ok := r.users[member]; ok {
+					d.OtherUsername = u.Username
+				}
+			}
+		}
+		for i := len(r.messages) - 1; i >= 0; i-- {
+			if m := r.messages[i]; m.ConversationID == c.ID {
+				if u, ok := r.users[m.SenderID]; ok {
+					m.SenderUsername = u.Username
+				}
+				d.LastMessage = &m
+				break
+			}
+		}
+		inbox = append(inbox, d)
+	}
+	sort.SliceStable(inbox, func(i, j int) bool {
+		a, b := inbox[i].LastMessageAt, inbox[j].LastMessageAt
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return a.After(*b)
+	})
+	return inbox, nil
+}
+
+// readMarker is a member's position in a conversation
+type readMarker struct {
+	lastRead string
+	unread   int
+}
+
+func readKey(conversationID, userID string) string {
+	return conversationID + "/" + userID
+}
+
+func (r *memoryConversations) ListForMember(ctx context.Context, userID string) ([]models.MemberConversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := []models.MemberConversation{}
+	for convID, members := range r.members {
+		role, ok := members[userID]
+		c, exists := r.conversations[convID]
+		if !ok || !exists {
+			continue
+		}
+		mc := models.MemberConversation{Conversation: *c, Role: role}
+		if marker, ok := r.reads[readKey(convID, userID)]; ok {
+			mc.UnreadCount = marker.unread
+			mc.LastReadMessageID = marker.lastRead
+		}
+		if c.Type == "direct" {
+			for member := range members {
+				if u, ok := r.users[member]; ok && member != userID {
+					mc.OtherUsername = u.Username
+				}
+			}
+		}
+		list = append(list, mc)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i].LastMessageAt, list[j].LastMessageAt
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return a.After(*b)
+	})
+	return list, nil
+}
+
+func (r *memoryConversations) IncrementUnread(ctx context.Context, conversationID string, except []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	skip := make(map[string]bool, len(except))
+	for _, id := range except {
+		skip[id] = true
+	}
+	for member := range r.members[conversationID] {
+		if skip[member] {
+			continue
+		}
+		key := readKey(conversationID, member)
+		if r.reads[key] == nil {
+			r.reads[key] = &readMarker{}
+		}
+		r.reads[key].unread++
+	}
+	return nil
+}
+
+func (r *memoryConversations) MarkRead(ctx context.Context, conversationID, userID, messageID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.members[conversationID][userID]; !ok {
+		return false, ErrNotFound
+	}
+	target, current := -1, -1
+	marker := r.reads[readKey(conversationID, userID)]
+	for i, m := range r.messages {
+		if m.ID == messageID && m.ConversationID == conversationID {
+			target = i
+		}
+		if marker != nil && m.ID == marker.lastRead {
+			current = i
+		}
+	}
+	if target < 0 {
+		return false, ErrNotFound
+	}
+	if current >= target {
+		return false, nil
+	}
+
+	unread := 0
+	for _, m := range r.messages[target+1:] {
+		if m.ConversationID == conversationID && m.SenderID != userID && m.DeletedAt == nil {
+			unread++
+		}
+	}
+	r.reads[readKey(conversationID, userID)] = &readMarker{lastRead: messageID, unread: unread}
+	return true, nil
+}
+
+func (r *memoryConversations) MemberRole(ctx context.Context, conversationID, userID string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	role, ok := r.members[conversationID][userID]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return role, nil
+}
+
+func (r *memoryConversations) SetMemberRole(ctx context.Context, conversationID, userID, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	members, ok := r.members[conversationID]
+	if !ok {
+		members = make(map[string]string)
+		r.members[conversationID] = members
+	}
+	members[userID] = role
+	return nil
+}
+
+func (r *memoryConversations) RemoveMember(ctx context.Context, conversationID, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.members[conversationID], userID)
+	delete(r.reads, readKey(conversationID, userID))
+	return nil
+}
+
+func (r *memoryConversations) SetTopic(ctx context.Context, conversationID, topic string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.conversations[conversationID]
+	if !ok {
+		return ErrNotFound
+	}
+	c.Topic = topic
+	return nil
+}
+
+func (r *memoryConversations) SetSlowMode(ctx context.Context, conversationID string, seconds int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.conversations[conversationID]
+	if !ok {
+		return ErrNotFound
+	}
+	c.SlowModeSeconds = seconds
+	return nil
+}
+
+func (r *memoryConversations) Delete(ctx context.Context, conversationID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.conversations[conversationID]; !ok {
+		return ErrNotFound
+	}
+	delete(r.conversations, conversationID)
+	for member := range r.members[conversationID] {
+		delete(r.reads, readKey(conversationID, member))
+	}
+	delete(r.members, conversationID)
+	for key, s := range r.sanctions {
+		if s.ConversationID == conversationID {
+			delete(r.sanctions, key)
+		}
+	}
+	kept := r.messages[:0]
+	for _, m := range r.messages {
+		if m.ConversationID != conversationID {
+			kept = append(kept, m)
+		} else {
+			delete(r.reactions, m.ID)
+		}
+	}
+	r.messages = kept
+	keptAlerts := r.alerts[:0]
+	for _, a := range r.alerts {
+		if a.ConversationID != conversationID {
+			keptAlerts = append(keptAlerts, a)
+		}
+	}
+	r.alerts = keptAlerts
+	keptKeywords := r.keywords[:0]
+	for _, k := range r.keywords {
+		if k.ConversationID != conversationID {
+			keptKeywords = append(keptKeywords, k)
+		}
+	}
+	r.keywords = keptKeywords
+	return nil
+}
+
+func sanctionKey(conversationID, userID, kind string) string {
+	return conversationID + "/" + userID + "/" + kind
+}
+
+func (r *memoryConversations) AddSanction(ctx context.Context, s *models.RoomSanction) error {
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sanctions[sanctionKey(s.ConversationID, s.UserID, s.Kind)] = *s
+	return nil
+}
+
+func (r *memoryConversations) RemoveSanction(ctx context.Context, conversationID, userID, kind string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := sanctionKey(conversationID, userID, kind)
+	if _, ok := r.sanctions[key]; !ok {
+		return ErrNotFound
+	}
+	delete(r.sanctions, key)
+	return nil
+}
+
+func (r *memoryConversations) ActiveSanction(ctx context.Context, conversationID, userID, kind string) (*models.RoomSanction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.sanctions[sanctionKey(conversationID, userID, kind)]
+	if !ok || (s.ExpiresAt != nil && !s.ExpiresAt.After(time.Now())) {
+		return nil, ErrNotFound
+	}
+	return &s, nil
+}
+
+func (r *memoryConversations) Contacts(ctx context.Context, userID string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := map[string]bool{}
+	contacts := []string{}
+	for convID, members := range r.members {
+		c, ok := r.conversations[convID]
+		if _, member := members[userID]; !ok || !member || (c.Type != "custom" && c.Type != "direct") {
+			continue
+		}
+		for other := range members {
+			if other != userID && !seen[other] {
+				seen[other] = true
+				contacts = append(contacts, other)
+			}
+		}
+	}
+	sort.Strings(contacts)
+	return contacts, nil
+}
+
+// ---- messages ----
+
+type memoryMessages struct {
+	*memoryData
+}
+
+func (d *memoryData) username(userID string) string {
+	if u, ok := d.users[userID]; ok {
+		return u.Username
+	}
+	return ""
+}
+
+func (r *memoryMessages) SaveToConversation(ctx context.Context, conversationID, senderID, content string) (*models.ChatMessage, error) {
+	return r.SaveReply(ctx, conversationID, senderID, content, "")
+}
+
+func (r *memoryMessages) SaveReply(ctx context.Context, conversationID, senderID, content, replyToID string) (*models.ChatMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	msg := models.ChatMessage{ID: uuid.New().String(), ConversationID: conversationID, SenderID: senderID, Content: content, CreatedAt: now, ReplyToID: replyToID}
+	r.messages = append(r.messages, msg)
+	if c, ok := r.conversations[conversationID]; ok {
+		c.LastMessageAt = &now
+	}
+	return &msg, nil
+}
+
+func (r *memoryMessages) SaveDirect(ctx context.Context, fromUserID, toUserID, content string) (*models.ChatMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	msg := models.ChatMessage{ID: uuid.New().String(), SenderID: fromUserID, RecipientID: toUserID, Content: content, CreatedAt: time.Now()}
+	r.direct = append(r.direct, msg)
+	return &msg, nil
+}
+
+func (r *memoryMessages) ConversationHistory(ctx context.Context, conversationID string, limit int) ([]models.ChatMessage, error) {
+	return r.ConversationPage(ctx, conversationID, MessagePage{Limit: limit})
+}
+
+func (r *memoryMessages) ConversationPage(ctx context.Context, conversationID string, page MessagePage) ([]models.ChatMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Messages are kept in the order they were sent
+	start, end := len(r.messages)-1, -1
+	if cursor := page.Before + page.After; cursor != "" {
+		at := r.find(cursor)
+		if at < 0 || r.messages[at].ConversationID != conversationID {
+			return nil, ErrNotFound
+		}
+		start = at - 1
+		if page.After != "" {
+			start, end = at+1, len(r.messages)
+		}
+	}
+
+	var messages []models.ChatMessage
+	step := -1
+	if end > start {
+		step = 1
+	}
+	for i := start; i != end && len(messages) < page.Limit; i += step {
+		if m := r.messages[i]; m.ConversationID == conversationID {
+			messages = append(messages, r.withDetails(m))
+		}
+	}
+	if step < 0 {
+		reverse(messages)
+	}
+	return messages, nil
+}
+
+func (r *memoryMessages) Search(ctx context.Context, userID, query string, limit int) ([]models.MessageSearchResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	terms := strings.Fields(query)
+	results := []models.MessageSearchResult{}
+	if len(terms) == 0 {
+		return results, nil
+	}
+	for i := len(r.messages) - 1; i >= 0 && len(results) < limit; i-- {
+		m := r.messages[i]
+		if _, member := r.members[m.ConversationID][userID]; !member || m.DeletedAt != nil {
+			continue
+		}
+		matched := true
+		for _, term := range terms {
+			if !containsFold(m.Content, term) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		res := models.MessageSearchResult{ChatMessage: r.withDetails(m)}
+		if c, ok := r.conversations[m.ConversationID]; ok {
+			res.ConversationName, res.ConversationType = c.Name, c.Type
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// withDetails fills in the sender's name and reaction counts. The caller
+// holds r.mu.
+func (r *memoryMessages) withDetails(m models.ChatMessage) models.ChatMessage {
+	m.SenderUsername = r.username(m.SenderID)
+	m.Reactions = nil
+	for emoji, users := range r.reactions[m.ID] {
+		if len(users) == 0 {
+			continue
+		}
+		if m.Reactions == nil {
+			m.Reactions = make(map[string]int)
+		}
+		m.Reactions[emoji] = len(users)
+	}
+	return m
+}
+
+// find returns the index of a conversation message, or -1. The caller holds r.mu.
+func (r *memoryMessages) find(id string) int {
+	for i := range r.messages {
+		if r.messages[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *memoryMessages) Get(ctx context.Context, id string) (*models.ChatMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	i := r.find(id)
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	msg := r.withDetails(r.messages[i])
+	return &msg, nil
+}
+
+func (r *memoryMessages) Edit(ctx context.Context, id, content string) (*models.ChatMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.find(id)
+	if i < 0 || r.messages[i].DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	now := time.Now()
+	r.messages[i].Content = content
+	r.messages[i].EditedAt = &now
+	msg := r.withDetails(r.messages[i])
+	return &msg, nil
+}
+
+func (r *memoryMessages) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.find(id)
+	if i < 0 || r.messages[i].DeletedAt != nil {
+		return ErrNotFound
+	}
+	now := time.Now()
+	r.messages[i].Content = ""
+	r.messages[i].DeletedAt = &now
+	delete(r.reactions, id)
+	return nil
+}
+
+func (r *memoryMessages) TagChapter(ctx context.Context, id string, chapter int) error {
+	if chapter < 0 {
+		return ErrInvalidChapter
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.find(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	r.messages[i].SpoilerChapter = chapter
+	return nil
+}
+
+func (r *memoryMessages) ToggleReaction(ctx context.Context, messageID, userID, emoji string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	byEmoji, ok := r.reactions[messageID]
+	if !ok {
+		byEmoji = make(map[string]map[string]bool)
+		r.reactions[messageID] = byEmoji
+	}
+	if byEmoji[emoji][userID] {
+		delete(byEmoji[emoji], userID)
+		return false, nil
+	}
+	if byEmoji[emoji] == nil {
+		byEmoji[emoji] = make(map[string]bool)
+	}
+	byEmoji[emoji][userID] = true
+	return true, nil
+}
+
+func (r *memoryMessages) DirectHistory(ctx context.Context, userID string, limit int) ([]models.ChatMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var messages []models.ChatMessage
+	for i := len(r.direct) - 1; i >= 0 && len(messages) < limit; i-- {
+		m := r.direct[i]
+		if m.SenderID == userID || m.RecipientID == userID || m.RecipientID == "" {
+			m.SenderUsername = r.username(m.SenderID)
+			messages = append(messages, m)
+		}
+	}
+	return messages, nil
+}
+
+// ---- alerts ----
+
+type memoryAlerts struct {
+	*memoryData
+}
+
+func (r *memoryAlerts) Record(ctx context.Context, alert *models.ChatAlert) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, a := range r.alerts {
+		if a.UserID == alert.UserID && a.MessageID == alert.MessageID {
+			return false, nil
+		}
+	}
+	if alert.CreatedAt.IsZero() {
+		alert.CreatedAt = time.Now()
+	}
+	alert.ID = int64(len(r.alerts) + 1)
+	r.alerts = append(r.alerts, *alert)
+	return true, nil
+}
+
+func (r *memoryAlerts) ListForUser(ctx context.Context, userID string, limit int) ([]models.ChatAlert, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	alerts := []models.ChatAlert{}
+	for i := len(r.alerts) - 1; i >= 0 && len(alerts) < limit; i-- {
+		a := r.alerts[i]
+		if a.UserID != userID {
+			continue
+		}
+		if conv, ok := r.conversations[a.ConversationID]; ok {
+			a.ConversationName = conv.Name
+		}
+		for _, m := range r.messages {
+			if m.ID == a.MessageID {
+				a.Content = m.Content
+				a.SpoilerChapter = m.SpoilerChapter
+				a.SenderUsername = r.username(m.SenderID)
+				break
+			}
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, nil
+}
+
+func (r *memoryAlerts) AddKeyword(ctx context.Context, userID, conversationID, keyword string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range r.keywords {
+		if k.UserID == userID && k.ConversationID == conversationID && k.Keyword == keyword {
+			return ErrAlreadyExists
+		}
+	}
+	r.keywords = append(r.keywords, models.KeywordAlert{UserID: userID, ConversationID: conversationID, Keyword: keyword, CreatedAt: time.Now()})
+	return nil
+}
+
+func (r *memoryAlerts) RemoveKeyword(ctx context.Context, userID, conversationID, keyword string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, k := range r.keywords {
+		if k.UserID == userID && k.ConversationID == conversationID && k.Keyword == keyword {
+			r.keywords = append(r.keywords[:i], r.keywords[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memoryAlerts) matchingKeywords(match func(models.KeywordAlert) bool) []models.KeywordAlert {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keywords := []models.KeywordAlert{}
+	for _, k := range r.keywords {
+		if !match(k) {
+			continue
+		}
+		if conv, ok := r.conversations[k.ConversationID]; ok {
+			k.ConversationName = conv.Name
+		}
+		keywords = append(keywords, k)
+	}
+	return keywords
+}
+
+func (r *memoryAlerts) Keywords(ctx context.Context, userID string) ([]models.KeywordAlert, error) {
+	return r.matchingKeywords(func(k models.KeywordAlert) bool { return k.UserID == userID }), nil
+}
+
+func (r *memoryAlerts) KeywordsForConversation(ctx context.Context, conversationID string) ([]models.KeywordAlert, error) {
+	return r.matchingKeywords(func(k models.KeywordAlert) bool {
+		return k.ConversationID == conversationID || k.ConversationID == ""
+	}), nil
+}
+
+// ---- presence ----
+
+type memoryPresence struct {
+	*memoryData
+}
+
+func (r *memoryPresence) Save(ctx context.Context, p *models.UserPresence) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *p
+	stored.Username, stored.Protocols = "", nil
+	if stored.LastSeen == nil {
+		now := time.Now()
+		stored.LastSeen = &now
+	}
+	if stored.LastActive == nil {
+		stored.LastActive = r.presence[p.UserID].LastActive
+	}
+	r.presence[p.UserID] = stored
+	return nil
+}
+
+func (r *memoryPresence) Get(ctx context.Context, userIDs []string) (map[string]models.UserPresence, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	found := make(map[string]models.UserPresence, len(userIDs))
+	for _, id := range userIDs {
+		if p, ok := r.presence[id]; ok {
+			p.Username = r.username(id)
+			found[id] = p
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+var (
+	ErrNotFound       = errors.New("not found")
+	ErrMangaNotFound  = errors.New("manga not found")
+	ErrNotInLibrary   = errors.New("manga not in library")
+	ErrAlreadyExists  = errors.New("already exists")
+	ErrUsernameTaken  = errors.New("username already taken")
+	ErrEmailTaken     = errors.New("email already registered")
+	ErrInvalidStatus  = errors.New("invalid reading status")
+	ErrInvalidRating  = errors.New("rating must be between 1 and 5")
+	ErrInvalidChapter = errors.New("chapter must not be negative")
+	ErrInvalidTag     = errors.New("invalid tag")
+	ErrInvalidKeyword = errors.New("invalid keyword")
+)
+
+// UserRepository stores accounts. Lookups return ErrNotFound for unknown users.
+type UserRepository interface {
+	// Create inserts u, filling in its role and creation time when unset
+	Create(ctx context.Context, u *models.User) error
+	GetByID(ctx context.Context, id string) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	UpdatePassword(ctx context.Context, id, passwordHash string) error
+	UpdateEmail(ctx context.Context, id, email string) error
+	UpdateUsername(ctx context.Context, id, username string) error
+}
+
+// MangaFilter narrows a manga search. Empty fields match everything.
+type MangaFilter struct {
+	Title     string
+	Author    string
+	Status    string
+	Genres    []string
+	MediaType string
+
+	Limit  int
+	Offset int
+}
+
+// MangaRepository stores the local manga catalogue. Get returns ErrMangaNotFound.
+type MangaRepository interface {
+	Get(ctx context.Context, id string) (*models.Manga, error)
+	Exists(ctx context.Context, id string) (bool, error)
+	// Create inserts m and returns ErrAlreadyExists if the ID is taken
+	Create(ctx context.Context, m *models.Manga) error
+	// Upsert inserts m or replaces the stored metadata
+	Upsert(ctx context.Context, m *models.Manga) error
+	// Search returns one page of matches and the total number of matches
+	Search(ctx context.Context, filter MangaFilter) ([]models.Manga, int, error)
+	List(ctx context.Context) ([]models.Manga, error)
+	UpdateTotalChapters(ctx context.Context, id string, total int) error
+	// RatingStats summarises user ratings; TotalCount is 0 when unrated
+	RatingStats(ctx context.Context, id string) (*models.RatingStats, error)
+}
+
+// ProgressRepository stores each user's library and reading progress and
+// enforces the status and rating rules every protocol shares.
+type ProgressRepository interface {
+	// Get returns ErrNotInLibrary when the manga isn't in the user's library
+	Get(ctx context.Context, userID, mangaID string) (*models.UserProgress, error)
+	// Library returns the user's entries with their tags, most recently updated first
+	Library(ctx context.Context, userID string) ([]models.MangaProgress, error)
+	// Latest returns the most recently updated entry or ErrNotFound
+	Latest(ctx context.Context, userID string) (*models.MangaProgress, error)
+	// AddToLibrary adds the manga, or resets its status if already present.
+	// An empty status means plan_to_read.
+	AddToLibrary(ctx context.Context, userID, mangaID, status string) (*models.UserProgress, error)
+	// Update applies a partial update to an existing entry. A chapter update
+	// derives the status from the manga's chapter count. Update and Sync
+	// record a reading event whenever the chapter moves forward.
+	Update(ctx context.Context, userID string, req models.UpdateProgressRequest) (*models.UserProgress, error)
+	// Sync records a chapter, adding the manga to the library if needed. An
+	// empty status keeps the stored one (reading for new entries).
+	Sync(ctx context.Context, userID, mangaID string, chapter int, status string) (*models.MangaProgress, error)
+	// Remove returns ErrNotInLibrary when there was nothing to remove
+	Remove(ctx context.Context, userID, mangaID string) error
+	// SetFavorite flags or unflags an entry without touching its update time
+	SetFavorite(ctx context.Context, userID, mangaID string, favorite bool) error
+	// SetTags replaces an entry's tags and returns them normalized (see NormalizeTags)
+	SetTags(ctx context.Context, userID, mangaID string, tags []string) ([]string, error)
+	// All returns every user's entries, for batch jobs such as recommendations
+	All(ctx context.Context) ([]models.UserProgress, error)
+	// History returns the user's reading events since the given time, oldest first
+	History(ctx context.Context, userID string, since time.Time) ([]models.ReadingEvent, error)
+}
+
+// ConversationRepository stores chat rooms and their membership
+type ConversationRepository interface {
+	Get(ctx context.Context, id string) (*models.Conversation, error)
+	GetByName(ctx context.Context, name string) (*models.Conversation, error)
+	// Create assigns an ID and returns ErrAlreadyExists if the name is taken
+	Create(ctx context.Context, conv *models.Conversation) error
+	// AddMember is a no-op if the user is already a member
+	AddMember(ctx context.Context, conversationID, userID, role string) error
+	// List summarises every room, most recently active first. Direct
+	// conversations are left out.
+	List(ctx context.Context) ([]models.ConversationSummary, error)
+	// GetOrCreateDirect returns the direct conversation between two users,
+	// creating it with both as members
+	GetOrCreateDirect(ctx context.Context, userA, userB string) (*models.Conversation, error)
+	// ListDirect returns the user's direct conversations, most recently active first
+	ListDirect(ctx context.Context, userID string) ([]models.DirectConversation, error)
+	// ListForMember returns the conversations the user belongs to with their
+	// unread counts, most recently active first
+	ListForMember(ctx context.Context, userID string) ([]models.MemberConversation, error)
+	// IncrementUnread counts a new message as unread for every member
+	// except the given users
+	IncrementUnread(ctx context.Context, conversationID string, except []string) error
+	// MarkRead moves the member's read marker forward to messageID and
+	// recounts what is still unread. It reports false when the marker was
+	// already there or past it, and ErrNotFound for non-members or messages
+	// from another conversation.
+	MarkRead(ctx context.Context, conversationID, userID, messageID string) (bool, error)
+	// MemberRole returns ErrNotFound if the user is not a member
+	MemberRole(ctx context.Context, conversationID, userID string) (string, error)
+	// SetMemberRole changes a member's role, adding them if needed
+	SetMemberRole(ctx context.Context, conversationID, userID, role string) error
+	RemoveMember(ctx context.Context, conversationID, userID string) error
+	SetTopic(ctx context.Context, conversationID, topic string) error
+	SetSlowMode(ctx context.Context, conversationID string, seconds int) error
+	// Delete removes a room with its messages, members and sanctions
+	Delete(ctx context.Context, conversationID string) error
+	// AddSanction bans or mutes a user, replacing any earlier sanction of the same kind
+	AddSanction(ctx context.Context, s *models.RoomSanction) error
+	// RemoveSanction returns ErrNotFound if there was nothing to lift
+	RemoveSanction(ctx context.Context, conversationID, userID, kind string) error
+	// ActiveSanction returns ErrNotFound unless the sanction is in force
+	ActiveSanction(ctx context.Context, conversationID, userID, kind string) (*models.RoomSanction, error)
+	// Contacts returns the IDs of everyone sharing a custom room or a direct
+	// conversation with the user
+	Contacts(ctx context.Context, userID string) ([]string, error)
+}
+
+// MessageRepository stores room messages and direct chat messages
+type MessageRepository interface {
+	// SaveToConversation stores a room message and bumps the room's activity time
+	SaveToConversation(ctx context.Context, conversationID, senderID, content string) (*models.ChatMessage, error)
+	// SaveReply is SaveToConversation for a message answering replyToID
+	SaveReply(ctx context.Context, conversationID, senderID, content, replyToID string) (*models.ChatMessage, error)
+	// Get returns a conversation message with its reactions
+	Get(ctx context.Context, id string) (*models.ChatMessage, error)
+	// Edit replaces a message's content and stamps EditedAt
+	Edit(ctx context.Context, id, content string) (*models.ChatMessage, error)
+	// Delete leaves an empty tombstone with DeletedAt set and drops its reactions
+	Delete(ctx context.Context, id string) error
+	// TagChapter records the chapter the sender had reached when posting a
+	// manga room message. It returns ErrNotFound for unknown messages.
+	TagChapter(ctx context.Context, id string, chapter int) error
+	// ToggleReaction adds the user's reaction, or takes it back if it was
+	// already there, and reports which it did
+	ToggleReaction(ctx context.Context, messageID, userID, emoji string) (bool, error)
+	// SaveDirect stores a chat message; an empty recipient means everyone
+	SaveDirect(ctx context.Context, fromUserID, toUserID, content string) (*models.ChatMessage, error)
+	// ConversationHistory returns the latest messages in chronological order,
+	// tombstones and reactions included
+	ConversationHistory(ctx context.Context, conversationID string, limit int) ([]models.ChatMessage, error)
+	// ConversationPage is ConversationHistory from a cursor. It returns
+	// ErrNotFound if the cursor message is not in the conversation.
+	ConversationPage(ctx context.Context, conversationID string, page MessagePage) ([]models.ChatMessage, error)
+	// Search finds messages containing every search term in conversations
+	// the user belongs to, newest first. Deleted messages never match.
+	Search(ctx context.Context, userID, query string, limit int) ([]models.MessageSearchResult, error)
+	// DirectHistory returns the latest chat messages visible to the user, newest first
+	DirectHistory(ctx context.Context, userID string, limit int) ([]models.ChatMessage, error)
+}
+
+// AlertRepository stores chat alerts and the keywords that raise them
+type AlertRepository interface {
+	// Record stores an alert, reporting false if the user was already
+	// alerted about that message
+	Record(ctx context.Context, alert *models.ChatAlert) (bool, error)
+	// ListForUser returns the user's latest alerts, newest first, with the
+	// message and room each one points at
+	ListForUser(ctx context.Context, userID string, limit int) ([]models.ChatAlert, error)
+	// AddKeyword watches for keyword in one room, or in every room when
+	// conversationID is empty. It returns ErrAlreadyExists for a duplicate.
+	AddKeyword(ctx context.Context, userID, conversationID, keyword string) error
+	// RemoveKeyword returns ErrNotFound if the keyword wasn't being watched there
+	RemoveKeyword(ctx context.Context, userID, conversationID, keyword string) error
+	// Keywords lists what the user is watching
+	Keywords(ctx context.Context, userID string) ([]models.KeywordAlert, error)
+	// KeywordsForConversation lists every keyword watched in the room,
+	// including ones watched in every room
+	KeywordsForConversation(ctx context.Context, conversationID string) ([]models.KeywordAlert, error)
+}
+
+// PresenceRepository keeps the latest presence each server reported for a user
+type PresenceRepository interface {
+	// Save replaces the user's stored presence
+	Save(ctx context.Context, p *models.UserPresence) error
+	// Get returns the stored presence of every listed user that has one,
+	// keyed by user ID and with usernames filled in
+	Get(ctx context.Context, userIDs []string) (map[string]models.UserPresence, error)
+}
+
+// MessagePage selects a window of a conversation's history. With Before set
+// it holds the messages just older than that message, with After the ones
+// just newer, and with neither the newest. Messages are always returned
+// oldest first.
+type MessagePage struct {
+	Before string
+	After  string
+	Limit  int
+}
+
+// DirectConversationName is the unique name of the direct conversation
+// between two users, the same whichever of them starts it
+func DirectConversationName(userA, userB string) string {
+	if userB < userA {
+		userA, userB = userB, userA
+	}
+	return "dm:" + userA + ":" + userB
+}
+
+// Store bundles the repositories a server needs
+type Store struct {
+	Users         UserRepository
+	Manga         MangaRepository
+	Progress      ProgressRepository
+	Conversations ConversationRepository
+	Messages      MessageRepository
+	Alerts        AlertRepository
+	Presence      PresenceRepository
+}
+
+var (
+	defaultMu    sync.RWMutex
+	defaultStore *Store
+)
+
+// SetDefault overrides the store returned by Default; nil restores the SQLite store
+func SetDefault(s *Store) {
+	defaultMu.Lock()
+	defaultStore = s
+	defaultMu.Unlock()
+}
+
+// Default returns the store set by SetDefault, or one backed by database.DB
+func Default() *Store {
+	defaultMu.RLock()
+	s := defaultStore
+	defaultMu.RUnlock()
+	if s != nil {
+		return s
+	}
+	return NewSQLiteStore(database.DB)
+}
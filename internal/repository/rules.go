@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	StatusReading    = "reading"
+	StatusCompleted  = "completed"
+	StatusPlanToRead = "plan_to_read"
+)
+
+const (
+	MaxTags      = 20
+	MaxTagLength = 32
+)
+
+const (
+	RoleOwner     = "owner"
+	RoleModerator = "moderator"
+	RoleMember    = "member"
+)
+
+const (
+	SanctionBan  = "ban"
+	SanctionMute = "mute"
+)
+
+// Why a chat alert was raised
+const (
+	AlertMention = "mention"
+	AlertKeyword = "keyword"
+)
+
+// Presence statuses, from most to least available
+const (
+	PresenceOnline  = "online"
+	PresenceIdle    = "idle"
+	PresenceAway    = "away"
+	PresenceOffline = "offline"
+)
+
+const (
+	MaxKeywordAlerts = 20
+	MaxKeywordLength = 64
+)
+
+// RoleRank orders room roles so permission checks can compare them.
+// Unknown roles rank as members.
+func RoleRank(role string) int {
+	switch role {
+	case RoleOwner:
+		return 3
+	case RoleModerator:
+		return 2
+	}
+	return 1
+}
+
+// ValidStatus reports whether s is a reading status
+func ValidStatus(s string) bool {
+	switch s {
+	case StatusReading, StatusCompleted, StatusPlanToRead:
+		return true
+	}
+	return false
+}
+
+// ValidateStatus returns an error wrapping ErrInvalidStatus for unknown statuses
+func ValidateStatus(s string) error {
+	if !ValidStatus(s) {
+		return fmt.Errorf("%w: %q", ErrInvalidStatus, s)
+	}
+	return nil
+}
+
+// ValidateRating checks a user rating is on the 1-5 star scale
+func ValidateRating(r float64) error {
+	if r < 1 || r > 5 {
+		return ErrInvalidRating
+	}
+	return nil
+}
+
+// StatusForChapter derives the reading status from the current chapter.
+// Chapter 0 means not started; reaching the last known chapter completes it.
+func StatusForChapter(chapter, totalChapters int) string {
+	switch {
+	case chapter == 0:
+		return StatusPlanToRead
+	case totalChapters > 0 && chapter >= totalChapters:
+		return StatusCompleted
+	default:
+		return StatusReading
+	}
+}
+
+// NormalizeTags trims, lower-cases, de-duplicates and sorts tags, rejecting
+// more than MaxTags or any longer than MaxTagLength
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool)
+	normalized := []string{}
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		if len([]rune(t)) > MaxTagLength {
+			return nil, fmt.Errorf("%w: %q is longer than %d characters", ErrInvalidTag, t, MaxTagLength)
+		}
+		seen[t] = true
+		normalized = append(normalized, t)
+	}
+	if len(normalized) > MaxTags {
+		return nil, fmt.Errorf("%w: at most %d tags per entry", ErrInvalidTag, MaxTags)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// NormalizeKeyword trims and lower-cases an alert keyword, rejecting empty
+// ones and any longer than MaxKeywordLength
+func NormalizeKeyword(keyword string) (string, error) {
+	keyword = strings.ToLower(strings.Join(strings.Fields(keyword), " "))
+	if keyword == "" {
+		return "", fmt.Errorf("%w: keyword is empty", ErrInvalidKeyword)
+	}
+	if len([]rune(keyword)) > MaxKeywordLength {
+		return "", fmt.Errorf("%w: longer than %d characters", ErrInvalidKeyword, MaxKeywordLength)
+	}
+	return keyword, nil
+}
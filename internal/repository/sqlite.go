@@ -0,0 +1,1614 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+)
+
+// chatTimeFormat is how conversation and message timestamps have always been
+// written; keeping it lets ORDER BY compare them as text.
+const chatTimeFormat = "2006-01-02 15:04:05"
+
+// NewSQLiteStore returns repositories backed by db
+func NewSQLiteStore(db *sql.DB) *Store {
+	return &Store{
+		Users:         &sqliteUsers{db: db},
+		Manga:         &sqliteManga{db: db},
+		Progress:      &sqliteProgress{db: db, manga: &sqliteManga{db: db}},
+		Conversations: &sqliteConversations{db: db},
+		Messages:      &sqliteMessages{db: db},
+		Alerts:        &sqliteAlerts{db: db},
+		Presence:      &sqlitePresence{db: db},
+	}
+}
+
+// nullTime scans timestamps stored either as time values or as text
+type nullTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	time.RFC3339Nano,
+	chatTimeFormat,
+	"2006-01-02T15:04:05",
+}
+
+func (t *nullTime) Scan(v interface{}) error {
+	switch x := v.(type) {
+	case nil:
+		t.Valid = false
+		return nil
+	case time.Time:
+		t.Time, t.Valid = x, true
+		return nil
+	case int64:
+		t.Time, t.Valid = time.Unix(x, 0), true
+		return nil
+	case []byte:
+		return t.parse(string(x))
+	case string:
+		return t.parse(x)
+	}
+	return fmt.Errorf("unsupported timestamp type %T", v)
+}
+
+func (t *nullTime) parse(s string) error {
+	for _, layout := range timeLayouts {
+		if parsed, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			t.Time, t.Valid = parsed, true
+			return nil
+		}
+	}
+	return fmt.Errorf("unrecognised timestamp %q", s)
+}
+
+func (t nullTime) ptr() *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	v := t.Time
+	return &v
+}
+
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func isUniqueViolation(err error, column string) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed: "+column)
+}
+
+// ---- users ----
+
+type sqliteUsers struct {
+	db *sql.DB
+}
+
+const userColumns = `id, username, COALESCE(email, ''), password_hash, COALESCE(role, 'member'), created_at`
+
+func (r *sqliteUsers) Create(ctx context.Context, u *models.User) error {
+	if u.ID == "" {
+		id, err := utils.GenerateID(16)
+		if err != nil {
+			return fmt.Errorf("failed to generate user id: %w", err)
+		}
+		u.ID = id
+	}
+	if u.Role == "" {
+		u.Role = RoleMember
+	}
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now().UTC()
+	}
+
+	_, err := r.db.ExecContext(ctx, `INSERT INTO users (id, username, email, password_hash, role, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`, u.ID, u.Username, nullable(u.Email), u.PasswordHash, u.Role, u.CreatedAt)
+	switch {
+	case isUniqueViolation(err, "users.username"):
+		return ErrUsernameTaken
+	case isUniqueViolation(err, "users.email"):
+		return ErrEmailTaken
+	case err != nil:
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+func (r *sqliteUsers) getBy(ctx context.Context, column, value string) (*models.User, error) {
+	var u models.User
+	var createdAt nullTime
+	err := r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE `+column+` = ?`, value).
+		Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.Role, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.CreatedAt = createdAt.Time
+	return &u, nil
+}
+
+func (r *sqliteUsers) GetByID(ctx context.Context, id string) (*models.User, error) {
+	return r.getBy(ctx, "id", id)
+}
+
+func (r *sqliteUsers) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.getBy(ctx, "username", username)
+}
+
+func (r *sqliteUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.getBy(ctx, "email", email)
+}
+
+func (r *sqliteUsers) update(ctx context.Context, column, id string, value interface{}) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE users SET `+column+` = ? WHERE id = ?`, value, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *sqliteUsers) UpdatePassword(ctx context.Context, id, passwordHash string) error {
+	return r.update(ctx, "password_hash", id, passwordHash)
+}
+
+func (r *sqliteUsers) UpdateEmail(ctx context.Context, id, email string) error {
+	err := r.update(ctx, "email", id, email)
+	if isUniqueViolation(err, "users.email") {
+		return ErrEmailTaken
+	}
+	return err
+}
+
+func (r *sqliteUsers) UpdateUsername(ctx context.Context, id, username string) error {
+	err := r.update(ctx, "username", id, username)
+	if isUniqueViolation(err, "users.username") {
+		return ErrUsernameTaken
+	}
+	return err
+}
+
+// ---- manga ----
+
+type sqliteManga struct {
+	db *sql.DB
+}
+
+const mangaColumns = `id, title, COALESCE(author, ''), COALESCE(genres, ''), COALESCE(status, ''),
+	COALESCE(total_chapters, 0), COALESCE(description, ''), COALESCE(cover_url, ''), COALESCE(media_type, '')`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanManga(row rowScanner, extra ...interface{}) (*models.Manga, error) {
+	var m models.Manga
+	var genresJSON string
+	dest := append([]interface{}{
+		&m.ID, &m.Title, &m.Author, &genresJSON, &m.Status,
+		&m.TotalChapters, &m.Description, &m.CoverURL, &m.MediaType,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if genresJSON != "" {
+		json.Unmarshal([]byte(genresJSON), &m.Genres)
+	}
+	return &m, nil
+}
+
+func (r *sqliteManga) Get(ctx context.Context, id string) (*models.Manga, error) {
+	m, err := scanManga(r.db.QueryRowContext(ctx, `SELECT `+mangaColumns+` FROM manga WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrMangaNotFound
+	}
+	return m, err
+}
+
+func (r *sqliteManga) Exists(ctx context.Context, id string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM manga WHERE id = ?)`, id).Scan(&exists)
+	return exists, err
+}
+
+func mangaArgs(m *models.Manga) ([]interface{}, error) {
+	genresJSON, err := json.Marshal(m.Genres)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize genres: %w", err)
+	}
+	mediaType := m.MediaType
+	if mediaType == "" {
+		mediaType = "manga"
+	}
+	return []interface{}{m.ID, m.Title, m.Author, string(genresJSON), m.Status,
+		m.TotalChapters, m.Description, m.CoverURL, mediaType}, nil
+}
+
+func (r *sqliteManga) Create(ctx context.Context, m *models.Manga) error {
+	args, err := mangaArgs(m)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO manga (id, title, author, genres, status, total_chapters, description, cover_url, media_type)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`, args...)
+	if isUniqueViolation(err, "manga.id") {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+func (r *sqliteManga) Upsert(ctx context.Context, m *models.Manga) error {
+	args, err := mangaArgs(m)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO manga (id, title, author, genres, status, total_chapters, description, cover_url, media_type)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+		    title = excluded.title,
+		    author = excluded.author,
+		    genres = excluded.genres,
+		    status = excluded.status,
+		    total_chapters = excluded.total_chapters,
+		    description = excluded.description,
+		    cover_url = excluded.cover_url,
+		    media_type = excluded.media_type`, args...)
+	return err
+}
+
+func (r *sqliteManga) Search(ctx context.Context, f MangaFilter) ([]models.Manga, int, error) {
+	where := ` WHERE 1=1`
+	args := []interface{}{}
+	if f.Title != "" {
+		where += ` AND title LIKE ?`
+		args = append(args, "%"+f.Title+"%")
+	}
+	if f.Author != "" {
+		where += ` AND author LIKE ?`
+		args = append(args, "%"+f.Author+"%")
+	}
+	if f.Status != "" {
+		where += ` AND status = ?`
+		args = append(args, f.Status)
+	}
+	for _, g := range f.Genres {
+		where += ` AND genres LIKE ?`
+		args = append(args, "%"+g+"%")
+	}
+	if f.MediaType != "" {
+		where += ` AND media_type = ?`
+		args = append(args, f.MediaType)
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM manga`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT `+mangaColumns+` FROM manga`+where+` LIMIT ? OFFSET ?`,
+		append(args, limit, f.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var mangas []models.Manga
+	for rows.Next() {
+		m, err := scanManga(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		mangas = append(mangas, *m)
+	}
+	return mangas, total, rows.Err()
+}
+
+func (r *sqliteManga) List(ctx context.Context) ([]models.Manga, error) {
+	mangas, _, err := r.Search(ctx, MangaFilter{})
+	return mangas, err
+}
+
+func (r *sqliteManga) UpdateTotalChapters(ctx context.Context, id string, total int) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE manga SET total_chapters = ? WHERE id = ?`, total, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrMangaNotFound
+	}
+	return nil
+}
+
+func (r *sqliteManga) RatingStats(ctx context.Context, id string) (*models.RatingStats, error) {
+	var avg sql.NullFloat64
+	var total, r5, r4, r3, r2, r1 int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			ROUND(AVG(user_rating), 1),
+			COUNT(*),
+			COUNT(CASE WHEN ROUND(user_rating) = 5 THEN 1 END),
+			COUNT(CASE WHEN ROUND(user_rating) = 4 THEN 1 END),
+			COUNT(CASE WHEN ROUND(user_rating) = 3 THEN 1 END),
+			COUNT(CASE WHEN ROUND(user_rating) = 2 THEN 1 END),
+			COUNT(CASE WHEN ROUND(user_rating) = 1 THEN 1 END)
+		FROM user_progress
+		WHERE manga_id = ? AND user_rating IS NOT NULL`, id).Scan(&avg, &total, &r5, &r4, &r3, &r2, &r1)
+	if err != nil {
+		return nil, err
+	}
+	return &models.RatingStats{
+		Average:      avg.Float64,
+		TotalCount:   total,
+		Distribution: map[string]int{"5": r5, "4": r4, "3": r3, "2": r2, "1": r1},
+	}, nil
+}
+
+// ---- progress ----
+
+type sqliteProgress struct {
+	db    *sql.DB
+	manga *sqliteManga
+}
+
+func (r *sqliteProgress) Get(ctx context.Context, userID, mangaID string) (*models.UserProgress, error) {
+	p := models.UserProgress{UserID: userID, MangaID: mangaID}
+	var rating sql.NullFloat64
+	var updatedAt nullTime
+	err := r.db.QueryRowContext(ctx, `SELECT current_chapter, status, user_rating, is_favorite, updated_at
+		FROM user_progress WHERE user_id = ? AND manga_id = ?`, userID, mangaID).
+		Scan(&p.CurrentChapter, &p.Status, &rating, &p.IsFavorite, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotInLibrary
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rating.Valid {
+		p.UserRating = &rating.Float64
+	}
+	p.UpdatedAt = updatedAt.Time
+	return &p, nil
+}
+
+// libraryColumns follow the manga ID in library queries
+const libraryColumns = `COALESCE(m.title, ''), COALESCE(m.author, ''), COALESCE(m.genres, ''), COALESCE(m.status, ''),
+	COALESCE(m.total_chapters, 0), COALESCE(m.description, ''), COALESCE(m.cover_url, ''), COALESCE(m.media_type, ''),
+	up.current_chapter, up.status, up.user_rating, up.is_favorite, up.updated_at`
+
+func scanMangaProgress(row rowScanner) (*models.MangaProgress, error) {
+	var mp models.MangaProgress
+	var rating sql.NullFloat64
+	var updatedAt nullTime
+	m, err := scanManga(row, &mp.CurrentChapter, &mp.Status, &rating, &mp.IsFavorite, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	mp.Manga = *m
+	if rating.Valid {
+		mp.UserRating = &rating.Float64
+	}
+	mp.UpdatedAt = updatedAt.Time
+	mp.Tags = []string{}
+	return &mp, nil
+}
+
+func (r *sqliteProgress) Library(ctx context.Context, userID string) ([]models.MangaProgress, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT m.id, `+libraryColumns+`
+		FROM user_progress up
+		JOIN manga m ON up.manga_id = m.id
+		WHERE up.user_id = ?
+		ORDER BY up.updated_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	library := []models.MangaProgress{}
+	for rows.Next() {
+		mp, err := scanMangaProgress(rows)
+		if err != nil {
+			return nil, err
+		}
+		library = append(library, *mp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tags, err := r.tags(ctx, userID, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range library {
+		if t, ok := tags[library[i].Manga.ID]; ok {
+			library[i].Tags = t
+		}
+	}
+	return library, nil
+}
+
+// tags returns the user's tags keyed by manga, for one manga or (with an
+// empty mangaID) the whole library
+func (r *sqliteProgress) tags(ctx context.Context, userID, mangaID string) (map[string][]string, error) {
+	query := `SELECT manga_id, tag FROM library_tags WHERE user_id = ?`
+	args := []interface{}{userID}
+	if mangaID != "" {
+		query += ` AND manga_id = ?`
+		args = append(args, mangaID)
+	}
+	rows, err := r.db.QueryContext(ctx, query+` ORDER BY manga_id, tag`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[string][]string)
+	for rows.Next() {
+		var id, tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return nil, err
+		}
+		tags[id] = append(tags[id], tag)
+	}
+	return tags, rows.Err()
+}
+
+// withTags fills in mp's tags
+func (r *sqliteProgress) withTags(ctx context.Context, userID string, mp *models.MangaProgress) (*models.MangaProgress, error) {
+	tags, err := r.tags(ctx, userID, mp.Manga.ID)
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := tags[mp.Manga.ID]; ok {
+		mp.Tags = t
+	}
+	return mp, nil
+}
+
+func (r *sqliteProgress) All(ctx context.Context) ([]models.UserProgress, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT user_id, manga_id, current_chapter, status, user_rating, is_favorite, updated_at
+		FROM user_progress ORDER BY user_id, manga_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.UserProgress{}
+	for rows.Next() {
+		var p models.UserProgress
+		var rating sql.NullFloat64
+		var updatedAt nullTime
+		if err := rows.Scan(&p.UserID, &p.MangaID, &p.CurrentChapter, &p.Status, &rating, &p.IsFavorite, &updatedAt); err != nil {
+			return nil, err
+		}
+		if rating.Valid {
+			p.UserRating = &rating.Float64
+		}
+		p.UpdatedAt = updatedAt.Time
+		entries = append(entries, p)
+	}
+	return entries, rows.Err()
+}
+
+func (r *sqliteProgress) Latest(ctx context.Context, userID string) (*models.MangaProgress, error) {
+	// up.manga_id so an entry whose manga row is missing still counts
+	row := r.db.QueryRowContext(ctx, `SELECT up.manga_id, `+libraryColumns+`
+		FROM user_progress up
+		LEFT JOIN manga m ON m.id = up.manga_id
+		WHERE up.user_id = ?
+		ORDER BY up.updated_at DESC
+		LIMIT 1`, userID)
+	mp, err := scanMangaProgress(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.withTags(ctx, userID, mp)
+}
+
+func (r *sqliteProgress) AddToLibrary(ctx context.Context, userID, mangaID, status string) (*models.UserProgress, error) {
+	if status == "" {
+		status = StatusPlanToRead
+	}
+	if err := ValidateStatus(status); err != nil {
+		return nil, err
+	}
+	if err := r.requireManga(ctx, mangaID); err != nil {
+		return nil, err
+	}
+
+	_, err := r.db.ExecContext(ctx, `INSERT INTO user_progress (user_id, manga_id, current_chapter, status, updated_at)
+		VALUES (?, ?, 0, ?, ?)
+		ON CONFLICT(user_id, manga_id) DO UPDATE SET status = excluded.status, updated_at = excluded.updated_at`,
+		userID, mangaID, status, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, userID, mangaID)
+}
+
+func (r *sqliteProgress) requireManga(ctx context.Context, mangaID string) error {
+	exists, err := r.manga.Exists(ctx, mangaID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrMangaNotFound
+	}
+	return nil
+}
+
+func (r *sqliteProgress) Update(ctx context.Context, userID string, req models.UpdateProgressRequest) (*models.UserProgress, error) {
+	previous, err := r.Get(ctx, userID, req.MangaID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE user_progress SET updated_at = ?`
+	args := []interface{}{time.Now()}
+
+	if req.CurrentChapter != nil {
+		if *req.CurrentChapter < 0 {
+			return nil, ErrInvalidChapter
+		}
+		var totalChapters int
+		r.db.QueryRowContext(ctx, `SELECT COALESCE(total_chapters, 0) FROM manga WHERE id = ?`, req.MangaID).Scan(&totalChapters)
+
+		query += `, current_chapter = ?, status = ?`
+		args = append(args, *req.CurrentChapter, StatusForChapter(*req.CurrentChapter, totalChapters))
+	} else if req.Status != "" {
+		if err := ValidateStatus(req.Status); err != nil {
+			return nil, err
+		}
+		query += `, status = ?`
+		args = append(args, req.Status)
+	}
+
+	if req.UserRating != nil {
+		if err := ValidateRating(*req.UserRating); err != nil {
+			return nil, err
+		}
+		query += `, user_rating = ?`
+		args = append(args, *req.UserRating)
+	}
+
+	query += ` WHERE user_id = ? AND manga_id = ?`
+	args = append(args, userID, req.MangaID)
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return nil, err
+	}
+	if req.CurrentChapter != nil {
+		if err := r.recordRead(ctx, userID, req.MangaID, previous.CurrentChapter, *req.CurrentChapter); err != nil {
+			return nil, err
+		}
+	}
+	return r.Get(ctx, userID, req.MangaID)
+}
+
+func (r *sqliteProgress) Sync(ctx context.Context, userID, mangaID string, chapter int, status string) (*models.MangaProgress, error) {
+	if chapter < 0 {
+		return nil, ErrInvalidChapter
+	}
+	if status != "" {
+		if err := ValidateStatus(status); err != nil {
+			return nil, err
+		}
+	}
+	m, err := r.manga.Get(ctx, mangaID)
+	if err != nil {
+		return nil, err
+	}
+
+	previousChapter := 0
+	if previous, err := r.Get(ctx, userID, mangaID); err == nil {
+		previousChapter = previous.CurrentChapter
+	}
+
+	insertStatus := status
+	if insertStatus == "" {
+		insertStatus = StatusReading
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO user_progress (user_id, manga_id, current_chapter, status, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, manga_id) DO UPDATE SET
+		    current_chapter = excluded.current_chapter,
+		    status = COALESCE(?, status),
+		    updated_at = excluded.updated_at`,
+		userID, mangaID, chapter, insertStatus, time.Now(), nullable(status))
+	if err != nil {
+		return nil, err
+	}
+	if err := r.recordRead(ctx, userID, mangaID, previousChapter, chapter); err != nil {
+		return nil, err
+	}
+
+	p, err := r.Get(ctx, userID, mangaID)
+	if err != nil {
+		return nil, err
+	}
+	return r.withTags(ctx, userID, &models.MangaProgress{
+		Manga:          *m,
+		CurrentChapter: p.CurrentChapter,
+		Status:         p.Status,
+		UserRating:     p.UserRating,
+		IsFavorite:     p.IsFavorite,
+		Tags:           []string{},
+		UpdatedAt:      p.UpdatedAt,
+	})
+}
+
+// recordRead logs the chapters read when progress moves from previous to chapter
+func (r *sqliteProgress) recordRead(ctx context.Context, userID, mangaID string, previous, chapter int) error {
+	if chapter <= previous {
+		return nil
+	}
+	_, err := r.db.ExecContext(ctx, `INSERT INTO reading_history (user_id, manga_id, chapter, chapters_read, read_at)
+		VALUES (?, ?, ?, ?, ?)`, userID, mangaID, chapter, chapter-previous, time.Now().UTC())
+	return err
+}
+
+func (r *sqliteProgress) History(ctx context.Context, userID string, since time.Time) ([]models.ReadingEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT manga_id, chapter, chapters_read, read_at
+		FROM reading_history WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Filtered here rather than in SQL: read_at is stored as text whose
+	// format depends on the driver, so it can't be compared reliably
+	events := []models.ReadingEvent{}
+	for rows.Next() {
+		var e models.ReadingEvent
+		var readAt nullTime
+		if err := rows.Scan(&e.MangaID, &e.Chapter, &e.ChaptersRead, &readAt); err != nil {
+			return nil, err
+		}
+		e.ReadAt = readAt.Time
+		if !e.ReadAt.Before(since) {
+			events = append(events, e)
+		}
+	}
+	return events, rows.Err()
+}
+
+func (r *sqliteProgress) Remove(ctx context.Context, userID, mangaID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM user_progress WHERE user_id = ? AND manga_id = ?`, userID, mangaID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotInLibrary
+	}
+	// Tags key on the user and manga rather than the library entry, so
+	// nothing cascades to them
+	if _, err := tx.ExecContext(ctx, `DELETE FROM library_tags WHERE user_id = ? AND manga_id = ?`, userID, mangaID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqliteProgress) SetFavorite(ctx context.Context, userID, mangaID string, favorite bool) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE user_progress SET is_favorite = ? WHERE user_id = ? AND manga_id = ?`,
+		favorite, userID, mangaID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotInLibrary
+	}
+	return nil
+}
+
+func (r *sqliteProgress) SetTags(ctx context.Context, userID, mangaID string, tags []string) ([]string, error) {
+	normalized, err := NormalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Get(ctx, userID, mangaID); err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM library_tags WHERE user_id = ? AND manga_id = ?`, userID, mangaID); err != nil {
+		return nil, err
+	}
+	for _, tag := range normalized {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO library_tags (user_id, manga_id, tag) VALUES (?, ?, ?)`,
+			userID, mangaID, tag); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// ---- conversations ----
+
+type sqliteConversations struct {
+	db *sql.DB
+}
+
+func (r *sqliteConversations) getBy(ctx context.Context, column, value string) (*models.Conversation, error) {
+	var c models.Conversation
+	var mangaID, createdBy, topic sql.NullString
+	var createdAt, lastMessageAt nullTime
+	err := r.db.QueryRowContext(ctx, `SELECT id, name, type, manga_id, created_by, created_at, last_message_at,
+		topic, slow_mode_seconds
+		FROM conversations WHERE `+column+` = ?`, value).
+		Scan(&c.ID, &c.Name, &c.Type, &mangaID, &createdBy, &createdAt, &lastMessageAt, &topic, &c.SlowModeSeconds)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.MangaID = mangaID.String
+	c.CreatedBy = createdBy.String
+	c.CreatedAt = createdAt.Time
+	c.LastMessageAt = lastMessageAt.ptr()
+	c.Topic = topic.String
+	return &c, nil
+}
+
+func (r *sqliteConversations) Get(ctx context.Context, id string) (*models.Conversation, error) {
+	return r.getBy(ctx, "id", id)
+}
+
+func (r *sqliteConversations) GetByName(ctx context.Context, name string) (*models.Conversation, error) {
+	return r.getBy(ctx, "name", name)
+}
+
+func (r *sqliteConversations) Create(ctx context.Context, conv *models.Conversation) error {
+	if conv.ID == "" {
+		id, err := utils.GenerateID(16)
+		if err != nil {
+			return fmt.Errorf("failed to generate conversation id: %w", err)
+		}
+		conv.ID = id
+	}
+	now := time.Now()
+	conv.CreatedAt = now
+	conv.LastMessageAt = &now
+
+	stamp := now.Format(chatTimeFormat)
+	_, err := r.db.ExecContext(ctx, `INSERT INTO conversations (id, name, type, manga_id, created_by, created_at, last_message_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		conv.ID, conv.Name, conv.Type, nullable(conv.MangaID), nullable(conv.CreatedBy), stamp, stamp)
+	if isUniqueViolation(err, "conversations.name") || isUniqueViolation(err, "conversations.id") {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+func (r *sqliteConversations) AddMember(ctx context.Context, conversationID, userID, role string) error {
+	if role == "" {
+		role = RoleMember
+	}
+	_, err := r.db.ExecContext(ctx, `INSERT OR IGNORE INTO user_conversation_history (user_id, conversation_id, role, joined_at, unread_count)
+		VALUES (?, ?, ?, ?, 0)`, userID, conversationID, role, time.Now().Format(chatTimeFormat))
+	return err
+}
+
+func (r *sqliteConversations) List(ctx context.Context) ([]models.ConversationSummary, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.id, c.name, c.type, c.created_at, COALESCE(c.topic, ''),
+		       (SELECT COUNT(*) FROM user_conversation_history uch WHERE uch.conversation_id = c.id),
+		       (SELECT MAX(m.created_at) FROM messages m WHERE m.conversation_id = c.id) AS last_message_at
+		FROM conversations c
+		WHERE c.type != 'direct'
+		ORDER BY last_message_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []models.ConversationSummary{}
+	for rows.Next() {
+		var s models.ConversationSummary
+		var createdAt, lastMessageAt nullTime
+		if err := rows.Scan(&s.ID, &s.Name, &s.Type, &createdAt, &s.Topic, &s.MemberCount, &lastMessageAt); err != nil {
+			return nil, err
+		}
+		s.CreatedAt = createdAt.Time
+		s.LastMessageAt = lastMessageAt.ptr()
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+func (r *sqliteConversations) GetOrCreateDirect(ctx context.Context, userA, userB string) (*models.Conversation, error) {
+	name := DirectConversationName(userA, userB)
+	conv, err := r.GetByName(ctx, name)
+	if errors.Is(err, ErrNotFound) {
+		conv = &models.Conversation{Name: name, Type: "direct", CreatedBy: userA}
+		err = r.Create(ctx, conv)
+		if errors.Is(err, ErrAlreadyExists) {
+			// The other participant started it at the same moment
+			conv, err = r.GetByName(ctx, name)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range []string{userA, userB} {
+		if err := r.AddMember(ctx, conv.ID, userID, RoleMember); err != nil {
+			return nil, err
+		}
+	}
+	return conv, nil
+}
+
+func (r *sqliteConversations) ListDirect(ctx context.Context, userID string) ([]models.DirectConversation, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.id, c.name, c.created_at, c.last_message_at, other.user_id, COALESCE(u.username, ''), me.unread_count,
+		       m.id, m.sender_id, COALESCE(su.username, ''), m.content, m.created_at
+		FROM conversations c
+		JOIN user_conversation_history me ON me.conversation_id = c.id AND me.user_id = ?
+		JOIN user_conversation_history other ON other.conversation_id = c.id AND other.user_id != ?
+		LEFT JOIN users u ON u.id = other.user_id
+		LEFT JOIN messages m ON m.id = (
+			SELECT id FROM messages WHERE conversation_id = c.id ORDER BY created_at DESC, seq DESC LIMIT 1)
+		LEFT JOIN users su ON su.id = m.sender_id
+		WHERE c.type = 'direct'
+		ORDER BY c.last_message_at DESC`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inbox := []models.DirectConversation{}
+	for rows.Next() {
+		d := models.DirectConversation{Conversation: models.Conversation{Type: "direct"}}
+		var createdAt, lastMessageAt, sentAt nullTime
+		var msgID, senderID, senderName, content sql.NullString
+		if err := rows.Scan(&d.ID, &d.Name, &createdAt, &lastMessageAt, &d.OtherUserID, &d.OtherUsername, &d.UnreadCount,
+			&msgID, &senderID, &senderName, &content, &sentAt); err != nil {
+			return nil, err
+		}
+		d.CreatedAt = createdAt.Time
+		d.LastMessageAt = lastMessageAt.ptr()
+		if msgID.Valid {
+			d.LastMessage = &models.ChatMessage{ID: msgID.String, ConversationID: d.ID, SenderID: senderID.String,
+				SenderUsername: senderName.String, Content: content.String, CreatedAt: sentAt.Time}
+		}
+		inbox = append(inbox, d)
+	}
+	return inbox, rows.Err()
+}
+
+func (r *sqliteConversations) ListForMember(ctx context.Context, userID string) ([]models.MemberConversation, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.id, c.name, c.type, c.created_at, c.last_message_at, COALESCE(c.topic, ''),
+		       COALESCE(me.role, ''), me.unread_count, COALESCE(me.last_read_message_id, ''),
+		       COALESCE((SELECT u.username FROM user_conversation_history other
+		                 JOIN users u ON u.id = other.user_id
+		                 WHERE other.conversation_id = c.id AND other.user_id != me.user_id AND c.type = 'direct'
+		                 LIMIT 1), '')
+		FROM user_conversation_history me
+		JOIN conversations c ON c.id = me.conversation_id
+		WHERE me.user_id = ?
+		ORDER BY c.last_message_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.MemberConversation{}
+	for rows.Next() {
+		var mc models.MemberConversation
+		var createdAt, lastMessageAt nullTime
+		if err := rows.Scan(&mc.ID, &mc.Name, &mc.Type, &createdAt, &lastMessageAt, &mc.Topic,
+			&mc.Role, &mc.UnreadCount, &mc.LastReadMessageID, &mc.OtherUsername); err != nil {
+			return nil, err
+		}
+		if mc.Role == "" {
+			mc.Role = RoleMember
+		}
+		mc.CreatedAt = createdAt.Time
+		mc.LastMessageAt = lastMessageAt.ptr()
+		list = append(list, mc)
+	}
+	return list, rows.Err()
+}
+
+func (r *sqliteConversations) IncrementUnread(ctx context.Context, conversationID string, except []string) error {
+	query := `UPDATE user_conversation_history SET unread_count = unread_count + 1 WHERE conversation_id = ?`
+	args := []interface{}{conversationID}
+	if len(except) > 0 {
+		query += ` AND user_id NOT IN (?` + strings.Repeat(", ?", len(except)-1) + `)`
+		for _, id := range except {
+			args = append(args, id)
+		}
+	}
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *sqliteConversations) MarkRead(ctx context.Context, conversationID, userID, messageID string) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var current sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT last_read_message_id FROM user_conversation_history
+		WHERE conversation_id = ? AND user_id = ?`, conversationID, userID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return false, ErrNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// seq follows insertion order, which is the order messages were sent
+	var target int64
+	err = tx.QueryRowContext(ctx, `SELECT seq FROM messages WHERE id = ? AND conversation_id = ?`,
+		messageID, conversationID).Scan(&target)
+	if err == sql.ErrNoRows {
+		return false, ErrNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+	if current.Valid {
+		var at int64
+		err := tx.QueryRowContext(ctx, `SELECT seq FROM messages WHERE id = ?`, current.String).Scan(&at)
+		if err == nil && at >= target {
+			return false, nil
+		}
+	}
+
+	var unread int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM messages
+		WHERE conversation_id = ? AND seq > ? AND sender_id != ? AND deleted_at IS NULL`,
+		conversationID, target, userID).Scan(&unread); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE user_conversation_history SET last_read_message_id = ?, unread_count = ?
+		WHERE conversation_id = ? AND user_id = ?`, messageID, unread, conversationID, userID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *sqliteConversations) MemberRole(ctx context.Context, conversationID, userID string) (string, error) {
+	var role sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT role FROM user_conversation_history WHERE conversation_id = ? AND user_id = ?`,
+		conversationID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if role.String == "" {
+		return RoleMember, nil
+	}
+	return role.String, nil
+}
+
+func (r *sqliteConversations) SetMemberRole(ctx context.Context, conversationID, userID, role string) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO user_conversation_history (user_id, conversation_id, role, joined_at, unread_count)
+		VALUES (?, ?, ?, ?, 0)
+		ON CONFLICT(user_id, conversation_id) DO UPDATE SET role = excluded.role`,
+		userID, conversationID, role, time.Now().Format(chatTimeFormat))
+	return err
+}
+
+func (r *sqliteConversations) RemoveMember(ctx context.Context, conversationID, userID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM user_conversation_history WHERE conversation_id = ? AND user_id = ?`,
+		conversationID, userID)
+	return err
+}
+
+func (r *sqliteConversations) SetTopic(ctx context.Context, conversationID, topic string) error {
+	return r.update(ctx, `UPDATE conversations SET topic = ? WHERE id = ?`, nullable(topic), conversationID)
+}
+
+func (r *sqliteConversations) SetSlowMode(ctx context.Context, conversationID string, seconds int) error {
+	return r.update(ctx, `UPDATE conversations SET slow_mode_seconds = ? WHERE id = ?`, seconds, conversationID)
+}
+
+// update runs a single-row UPDATE, returning ErrNotFound if nothing matched
+func (r *sqliteConversations) update(ctx context.Context, query string, args ...interface{}) error {
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *sqliteConversations) Delete(ctx context.Context, conversationID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Messages, members and sanctions cascade with the room, and reactions
+	// and alerts with its messages. Keyword alerts can't reference it since
+	// an empty conversation_id means every room.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM keyword_alerts WHERE conversation_id = ?`, conversationID); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM conversations WHERE id = ?`, conversationID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return tx.Commit()
+}
+
+func (r *sqliteConversations) AddSanction(ctx context.Context, s *models.RoomSanction) error {
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now()
+	}
+	var expiresAt interface{}
+	if s.ExpiresAt != nil {
+		expiresAt = s.ExpiresAt.Format(chatTimeFormat)
+	}
+	_, err := r.db.ExecContext(ctx, `INSERT OR REPLACE INTO conversation_sanctions
+		(conversation_id, user_id, kind, reason, created_by, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		s.ConversationID, s.UserID, s.Kind, nullable(s.Reason), nullable(s.CreatedBy), s.CreatedAt.Format(chatTimeFormat), expiresAt)
+	return err
+}
+
+func (r *sqliteConversations) RemoveSanction(ctx context.Context, conversationID, userID, kind string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM conversation_sanctions WHERE conversation_id = ? AND user_id = ? AND kind = ?`,
+		conversationID, userID, kind)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *sqliteConversations) ActiveSanction(ctx context.Context, conversationID, userID, kind string) (*models.RoomSanction, error) {
+	s := models.RoomSanction{ConversationID: conversationID, UserID: userID, Kind: kind}
+	var reason, createdBy sql.NullString
+	var createdAt, expiresAt nullTime
+	err := r.db.QueryRowContext(ctx, `SELECT reason, created_by, created_at, expires_at FROM conversation_sanctions
+		WHERE conversation_id = ? AND user_id = ? AND kind = ?`, conversationID, userID, kind).
+		Scan(&reason, &createdBy, &createdAt, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid && !expiresAt.Time.After(time.Now()) {
+		return nil, ErrNotFound
+	}
+	s.Reason = reason.String
+	s.CreatedBy = createdBy.String
+	s.CreatedAt = createdAt.Time
+	s.ExpiresAt = expiresAt.ptr()
+	return &s, nil
+}
+
+func (r *sqliteConversations) Contacts(ctx context.Context, userID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT other.user_id
+		FROM user_conversation_history me
+		JOIN conversations c ON c.id = me.conversation_id
+		JOIN user_conversation_history other ON other.conversation_id = me.conversation_id
+		WHERE me.user_id = ? AND other.user_id != me.user_id AND c.type IN ('custom', 'direct')
+		ORDER BY other.user_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contacts := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, id)
+	}
+	return contacts, rows.Err()
+}
+
+// ---- messages ----
+
+type sqliteMessages struct {
+	db *sql.DB
+}
+
+func (r *sqliteMessages) SaveToConversation(ctx context.Context, conversationID, senderID, content string) (*models.ChatMessage, error) {
+	return r.SaveReply(ctx, conversationID, senderID, content, "")
+}
+
+func (r *sqliteMessages) SaveReply(ctx context.Context, conversationID, senderID, content, replyToID string) (*models.ChatMessage, error) {
+	id, err := utils.GenerateID(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate message id: %w", err)
+	}
+	now := time.Now()
+	stamp := now.Format(chatTimeFormat)
+
+	if _, err := r.db.ExecContext(ctx, `INSERT INTO messages (id, conversation_id, sender_id, content, created_at, reply_to_id)
+		VALUES (?, ?, ?, ?, ?, ?)`, id, conversationID, senderID, content, stamp, nullable(replyToID)); err != nil {
+		return nil, err
+	}
+	r.db.ExecContext(ctx, `UPDATE conversations SET last_message_at = ? WHERE id = ?`, stamp, conversationID)
+
+	return &models.ChatMessage{ID: id, ConversationID: conversationID, SenderID: senderID, Content: content, CreatedAt: now, ReplyToID: replyToID}, nil
+}
+
+// messageColumns are read by scanMessage
+const messageColumns = `m.id, m.conversation_id, m.sender_id, COALESCE(u.username, ''), m.content, m.created_at,
+	m.reply_to_id, m.edited_at, m.deleted_at, m.spoiler_chapter`
+
+func scanMessage(scan func(...interface{}) error) (models.ChatMessage, error) {
+	var msg models.ChatMessage
+	var replyTo sql.NullString
+	var createdAt, editedAt, deletedAt nullTime
+	err := scan(&msg.ID, &msg.ConversationID, &msg.SenderID, &msg.SenderUsername, &msg.Content, &createdAt,
+		&replyTo, &editedAt, &deletedAt, &msg.SpoilerChapter)
+	msg.CreatedAt = createdAt.Time
+	msg.ReplyToID = replyTo.String
+	msg.EditedAt = editedAt.ptr()
+	msg.DeletedAt = deletedAt.ptr()
+	return msg, err
+}
+
+func (r *sqliteMessages) Get(ctx context.Context, id string) (*models.ChatMessage, error) {
+	msg, err := scanMessage(r.db.QueryRowContext(ctx, `SELECT `+messageColumns+`
+		FROM messages m LEFT JOIN users u ON m.sender_id = u.id
+		WHERE m.id = ?`, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	messages := []models.ChatMessage{msg}
+	if err := r.loadReactions(ctx, messages); err != nil {
+		return nil, err
+	}
+	return &messages[0], nil
+}
+
+func (r *sqliteMessages) Edit(ctx context.Context, id, content string) (*models.ChatMessage, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE messages SET content = ?, edited_at = ? WHERE id = ? AND deleted_at IS NULL`,
+		content, time.Now().Format(chatTimeFormat), id)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+	return r.Get(ctx, id)
+}
+
+func (r *sqliteMessages) Delete(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE messages SET content = '', deleted_at = ? WHERE id = ? AND deleted_at IS NULL`,
+		time.Now().Format(chatTimeFormat), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	_, err = r.db.ExecContext(ctx, `DELETE FROM message_reactions WHERE message_id = ?`, id)
+	return err
+}
+
+func (r *sqliteMessages) TagChapter(ctx context.Context, id string, chapter int) error {
+	if chapter < 0 {
+		return ErrInvalidChapter
+	}
+	res, err := r.db.ExecContext(ctx, `UPDATE messages SET spoiler_chapter = ? WHERE id = ?`, chapter, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *sqliteMessages) ToggleReaction(ctx context.Context, messageID, userID, emoji string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM message_reactions WHERE message_id = ? AND user_id = ? AND emoji = ?`,
+		messageID, userID, emoji)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return false, nil
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO message_reactions (message_id, user_id, emoji, created_at) VALUES (?, ?, ?, ?)`,
+		messageID, userID, emoji, time.Now().Format(chatTimeFormat))
+	return err == nil, err
+}
+
+// loadReactions fills in reaction counts for a page of messages
+func (r *sqliteMessages) loadReactions(ctx context.Context, messages []models.ChatMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	index := make(map[string]int, len(messages))
+	args := make([]interface{}, len(messages))
+	for i, m := range messages {
+		index[m.ID] = i
+		args[i] = m.ID
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT message_id, emoji, COUNT(*) FROM message_reactions
+		WHERE message_id IN (?`+strings.Repeat(", ?", len(messages)-1)+`)
+		GROUP BY message_id, emoji`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var messageID, emoji string
+		var count int
+		if err := rows.Scan(&messageID, &emoji, &count); err != nil {
+			return err
+		}
+		m := &messages[index[messageID]]
+		if m.Reactions == nil {
+			m.Reactions = make(map[string]int)
+		}
+		m.Reactions[emoji] = count
+	}
+	return rows.Err()
+}
+
+func (r *sqliteMessages) SaveDirect(ctx context.Context, fromUserID, toUserID, content string) (*models.ChatMessage, error) {
+	id, err := utils.GenerateID(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate message id: %w", err)
+	}
+	now := time.Now()
+	if _, err := r.db.ExecContext(ctx, `INSERT INTO chat_messages (id, from_user_id, to_user_id, content, created_at)
+		VALUES (?, ?, ?, ?, ?)`, id, fromUserID, nullable(toUserID), content, now); err != nil {
+		return nil, err
+	}
+	return &models.ChatMessage{ID: id, SenderID: fromUserID, RecipientID: toUserID, Content: content, CreatedAt: now}, nil
+}
+
+func (r *sqliteMessages) ConversationHistory(ctx context.Context, conversationID string, limit int) ([]models.ChatMessage, error) {
+	return r.ConversationPage(ctx, conversationID, MessagePage{Limit: limit})
+}
+
+func (r *sqliteMessages) ConversationPage(ctx context.Context, conversationID string, page MessagePage) ([]models.ChatMessage, error) {
+	query := `SELECT ` + messageColumns + `
+		FROM messages m
+		JOIN users u ON m.sender_id = u.id
+		WHERE m.conversation_id = ?`
+	args := []interface{}{conversationID}
+	order := ` ORDER BY m.created_at DESC, m.seq DESC`
+
+	if cursor := page.Before + page.After; cursor != "" {
+		var found int
+		err := r.db.QueryRowContext(ctx, `SELECT 1 FROM messages WHERE id = ? AND conversation_id = ?`,
+			cursor, conversationID).Scan(&found)
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		position := `(SELECT created_at, seq FROM messages WHERE id = ?)`
+		if page.Before != "" {
+			query += ` AND (m.created_at, m.seq) < ` + position
+		} else {
+			query += ` AND (m.created_at, m.seq) > ` + position
+			order = ` ORDER BY m.created_at ASC, m.seq ASC`
+		}
+		args = append(args, cursor)
+	}
+	rows, err := r.db.QueryContext(ctx, query+order+` LIMIT ?`, append(args, page.Limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []models.ChatMessage
+	for rows.Next() {
+		msg, err := scanMessage(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if page.Before != "" || page.After == "" {
+		reverse(messages)
+	}
+	if err := r.loadReactions(ctx, messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// ftsQuery turns free text into an FTS5 query where every word must appear,
+// as a word or the start of one. Quoting each word keeps punctuation in user
+// input from being read as query syntax.
+func ftsQuery(text string) string {
+	terms := strings.Fields(text)
+	for i, term := range terms {
+		terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+	}
+	return strings.Join(terms, " ")
+}
+
+func (r *sqliteMessages) Search(ctx context.Context, userID, query string, limit int) ([]models.MessageSearchResult, error) {
+	match := ftsQuery(query)
+	if match == "" {
+		return []models.MessageSearchResult{}, nil
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT `+messageColumns+`, c.name, c.type
+		FROM messages_fts f
+		JOIN messages m ON m.seq = f.rowid
+		JOIN conversations c ON c.id = m.conversation_id
+		JOIN user_conversation_history me ON me.conversation_id = m.conversation_id AND me.user_id = ?
+		LEFT JOIN users u ON m.sender_id = u.id
+		WHERE messages_fts MATCH ? AND m.deleted_at IS NULL
+		ORDER BY m.created_at DESC, m.seq DESC
+		LIMIT ?`, userID, match, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.MessageSearchResult{}
+	for rows.Next() {
+		var res models.MessageSearchResult
+		res.ChatMessage, err = scanMessage(func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &res.ConversationName, &res.ConversationType)...)
+		})
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
+func (r *sqliteMessages) DirectHistory(ctx context.Context, userID string, limit int) ([]models.ChatMessage, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT cm.id, cm.from_user_id, u.username, cm.to_user_id, cm.content, cm.created_at
+		FROM chat_messages cm
+		JOIN users u ON cm.from_user_id = u.id
+		WHERE cm.from_user_id = ? OR cm.to_user_id = ? OR cm.to_user_id IS NULL
+		ORDER BY cm.created_at DESC, cm.rowid DESC LIMIT ?`, userID, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []models.ChatMessage
+	for rows.Next() {
+		var msg models.ChatMessage
+		var to sql.NullString
+		var createdAt nullTime
+		if err := rows.Scan(&msg.ID, &msg.SenderID, &msg.SenderUsername, &to, &msg.Content, &createdAt); err != nil {
+			return nil, err
+		}
+		msg.RecipientID = to.String
+		msg.CreatedAt = createdAt.Time
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+func reverse(messages []models.ChatMessage) {
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+}
+
+// ---- alerts ----
+
+type sqliteAlerts struct {
+	db *sql.DB
+}
+
+func (r *sqliteAlerts) Record(ctx context.Context, alert *models.ChatAlert) (bool, error) {
+	if alert.CreatedAt.IsZero() {
+		alert.CreatedAt = time.Now()
+	}
+	res, err := r.db.ExecContext(ctx, `INSERT OR IGNORE INTO chat_alerts
+		(user_id, message_id, conversation_id, kind, keyword, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		alert.UserID, alert.MessageID, alert.ConversationID, alert.Kind, nullable(alert.Keyword), alert.CreatedAt.Format(chatTimeFormat))
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	alert.ID, _ = res.LastInsertId()
+	return true, nil
+}
+
+func (r *sqliteAlerts) ListForUser(ctx context.Context, userID string, limit int) ([]models.ChatAlert, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT a.id, a.user_id, a.message_id, a.conversation_id, COALESCE(c.name, ''), COALESCE(u.username, ''),
+		       COALESCE(m.content, ''), COALESCE(m.spoiler_chapter, 0), a.kind, COALESCE(a.keyword, ''), a.created_at
+		FROM chat_alerts a
+		LEFT JOIN messages m ON m.id = a.message_id
+		LEFT JOIN users u ON u.id = m.sender_id
+		LEFT JOIN conversations c ON c.id = a.conversation_id
+		WHERE a.user_id = ?
+		ORDER BY a.created_at DESC, a.id DESC
+		LIMIT ?`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []models.ChatAlert{}
+	for rows.Next() {
+		var a models.ChatAlert
+		var createdAt nullTime
+		if err := rows.Scan(&a.ID, &a.UserID, &a.MessageID, &a.ConversationID, &a.ConversationName, &a.SenderUsername,
+			&a.Content, &a.SpoilerChapter, &a.Kind, &a.Keyword, &createdAt); err != nil {
+			return nil, err
+		}
+		a.CreatedAt = createdAt.Time
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+func (r *sqliteAlerts) AddKeyword(ctx context.Context, userID, conversationID, keyword string) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO keyword_alerts (user_id, conversation_id, keyword, created_at) VALUES (?, ?, ?, ?)`,
+		userID, conversationID, keyword, time.Now().Format(chatTimeFormat))
+	if isUniqueViolation(err, "keyword_alerts") {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+func (r *sqliteAlerts) RemoveKeyword(ctx context.Context, userID, conversationID, keyword string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM keyword_alerts WHERE user_id = ? AND conversation_id = ? AND keyword = ?`,
+		userID, conversationID, keyword)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *sqliteAlerts) listKeywords(ctx context.Context, where string, arg string) ([]models.KeywordAlert, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT k.user_id, k.conversation_id, COALESCE(c.name, ''), k.keyword, k.created_at
+		FROM keyword_alerts k
+		LEFT JOIN conversations c ON c.id = k.conversation_id
+		WHERE `+where+`
+		ORDER BY k.created_at, k.keyword`, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keywords := []models.KeywordAlert{}
+	for rows.Next() {
+		var k models.KeywordAlert
+		var createdAt nullTime
+		if err := rows.Scan(&k.UserID, &k.ConversationID, &k.ConversationName, &k.Keyword, &createdAt); err != nil {
+			return nil, err
+		}
+		k.CreatedAt = createdAt.Time
+		keywords = append(keywords, k)
+	}
+	return keywords, rows.Err()
+}
+
+func (r *sqliteAlerts) Keywords(ctx context.Context, userID string) ([]models.KeywordAlert, error) {
+	return r.listKeywords(ctx, `k.user_id = ?`, userID)
+}
+
+func (r *sqliteAlerts) KeywordsForConversation(ctx context.Context, conversationID string) ([]models.KeywordAlert, error) {
+	return r.listKeywords(ctx, `k.conversation_id IN (?, '')`, conversationID)
+}
+
+// ---- presence ----
+
+type sqlitePresence struct {
+	db *sql.DB
+}
+
+func (r *sqlitePresence) Save(ctx context.Context, p *models.UserPresence) error {
+	lastSeen := time.Now()
+	if p.LastSeen != nil {
+		lastSeen = *p.LastSeen
+	}
+	var lastActive interface{}
+	if p.LastActive != nil {
+		lastActive = *p.LastActive
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO user_presence (user_id, status, last_active, last_seen) VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET status = excluded.status,
+			last_active = COALESCE(excluded.last_active, user_presence.last_active),
+			last_seen = excluded.last_seen`,
+		p.UserID, p.Status, lastActive, lastSeen)
+	return err
+}
+
+func (r *sqlitePresence) Get(ctx context.Context, userIDs []string) (map[string]models.UserPresence, error) {
+	found := make(map[string]models.UserPresence, len(userIDs))
+	if len(userIDs) == 0 {
+		return found, nil
+	}
+	args := make([]interface{}, len(userIDs))
+	for i, id := range userIDs {
+		args[i] = id
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT p.user_id, COALESCE(u.username, ''), p.status, p.last_active, p.last_seen
+		FROM user_presence p
+		LEFT JOIN users u ON u.id = p.user_id
+		WHERE p.user_id IN (?`+strings.Repeat(", ?", len(userIDs)-1)+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.UserPresence
+		var lastActive, lastSeen nullTime
+		if err := rows.Scan(&p.UserID, &p.Username, &p.Status, &lastActive, &lastSeen); err != nil {
+			return nil, err
+		}
+		p.LastActive = lastActive.ptr()
+		p.LastSeen = lastSeen.ptr()
+		found[p.UserID] = p
+	}
+	return found, rows.Err()
+}
@@ -0,0 +1,808 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+// stores returns one of each implementation so every test checks both behave the same
+func stores(t *testing.T) map[string]*repository.Store {
+	return map[string]*repository.Store{
+		"sqlite": repository.NewSQLiteStore(migratedDB(t)),
+		"memory": repository.NewMemoryStore(),
+	}
+}
+
+// migratedDB opens a fresh SQLite database, for tests that look at its tables
+func migratedDB(t *testing.T) *sql.DB {
+	db, err := database.Open(t.TempDir() + "/repo.db")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := database.Migrate(db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+func seed(t *testing.T, s *repository.Store) {
+	ctx := context.Background()
+	for _, u := range []models.User{
+		{ID: "u1", Username: "reader", Email: "reader@example.com", PasswordHash: "hash"},
+		{ID: "u2", Username: "other", Email: "other@example.com", PasswordHash: "hash"},
+	} {
+		if err := s.Users.Create(ctx, &u); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	for _, m := range []models.Manga{
+		{ID: "m1", Title: "Berserk", Author: "Miura", Genres: []string{"Action", "Dark Fantasy"}, Status: "ongoing", TotalChapters: 10},
+		{ID: "m2", Title: "Monster", Author: "Urasawa", Genres: []string{"Thriller"}, Status: "completed"},
+	} {
+		if err := s.Manga.Create(ctx, &m); err != nil {
+			t.Fatalf("create manga: %v", err)
+		}
+	}
+}
+
+func intPtr(v int) *int           { return &v }
+func floatPtr(v float64) *float64 { return &v }
+
+func TestUsers(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seed(t, s)
+
+			u, err := s.Users.GetByUsername(ctx, "reader")
+			if err != nil || u.ID != "u1" || u.Role != repository.RoleMember || u.CreatedAt.IsZero() {
+				t.Fatalf("unexpected user %+v (err %v)", u, err)
+			}
+			if _, err := s.Users.GetByEmail(ctx, "missing@example.com"); !errors.Is(err, repository.ErrNotFound) {
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+
+			dup := models.User{Username: "reader", Email: "new@example.com", PasswordHash: "x"}
+			if err := s.Users.Create(ctx, &dup); !errors.Is(err, repository.ErrUsernameTaken) {
+				t.Errorf("expected ErrUsernameTaken, got %v", err)
+			}
+			if err := s.Users.UpdateEmail(ctx, "u1", "other@example.com"); !errors.Is(err, repository.ErrEmailTaken) {
+				t.Errorf("expected ErrEmailTaken, got %v", err)
+			}
+			if err := s.Users.UpdateUsername(ctx, "u1", "renamed"); err != nil {
+				t.Fatalf("update username: %v", err)
+			}
+			if u, _ := s.Users.GetByID(ctx, "u1"); u.Username != "renamed" {
+				t.Errorf("username not updated: %+v", u)
+			}
+		})
+	}
+}
+
+func TestMangaSearch(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seed(t, s)
+
+			results, total, err := s.Manga.Search(ctx, repository.MangaFilter{Genres: []string{"fantasy"}, Limit: 10})
+			if err != nil || total != 1 || len(results) != 1 || results[0].ID != "m1" {
+				t.Fatalf("unexpected genre search: %+v total=%d err=%v", results, total, err)
+			}
+			if results[0].MediaType != "manga" {
+				t.Errorf("expected default media type, got %q", results[0].MediaType)
+			}
+
+			results, total, _ = s.Manga.Search(ctx, repository.MangaFilter{Limit: 1, Offset: 1})
+			if total != 2 || len(results) != 1 {
+				t.Errorf("expected second page of 1 with total 2, got %d/%d", len(results), total)
+			}
+
+			if err := s.Manga.Create(ctx, &models.Manga{ID: "m1", Title: "Dup"}); !errors.Is(err, repository.ErrAlreadyExists) {
+				t.Errorf("expected ErrAlreadyExists, got %v", err)
+			}
+		})
+	}
+}
+
+func TestProgressRules(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seed(t, s)
+
+			p, err := s.Progress.AddToLibrary(ctx, "u1", "m1", "")
+			if err != nil || p.Status != repository.StatusPlanToRead {
+				t.Fatalf("expected plan_to_read default, got %+v (err %v)", p, err)
+			}
+			if _, err := s.Progress.AddToLibrary(ctx, "u1", "m1", "dropped"); !errors.Is(err, repository.ErrInvalidStatus) {
+				t.Errorf("expected ErrInvalidStatus, got %v", err)
+			}
+			if _, err := s.Progress.AddToLibrary(ctx, "u1", "nope", ""); !errors.Is(err, repository.ErrMangaNotFound) {
+				t.Errorf("expected ErrMangaNotFound, got %v", err)
+			}
+
+			p, err = s.Progress.Update(ctx, "u1", models.UpdateProgressRequest{MangaID: "m1", CurrentChapter: intPtr(4), UserRating: floatPtr(4)})
+			if err != nil || p.Status != repository.StatusReading || p.CurrentChapter != 4 || p.UserRating == nil || *p.UserRating != 4 {
+				t.Fatalf("unexpected progress after update: %+v (err %v)", p, err)
+			}
+			p, _ = s.Progress.Update(ctx, "u1", models.UpdateProgressRequest{MangaID: "m1", CurrentChapter: intPtr(10)})
+			if p.Status != repository.StatusCompleted {
+				t.Errorf("reaching the last chapter should complete, got %q", p.Status)
+			}
+			if _, err := s.Progress.Update(ctx, "u1", models.UpdateProgressRequest{MangaID: "m1", UserRating: floatPtr(9)}); !errors.Is(err, repository.ErrInvalidRating) {
+				t.Errorf("expected ErrInvalidRating, got %v", err)
+			}
+			if _, err := s.Progress.Update(ctx, "u1", models.UpdateProgressRequest{MangaID: "m2", Status: "reading"}); !errors.Is(err, repository.ErrNotInLibrary) {
+				t.Errorf("expected ErrNotInLibrary, got %v", err)
+			}
+
+			stats, err := s.Manga.RatingStats(ctx, "m1")
+			if err != nil || stats.TotalCount != 1 || stats.Average != 4 || stats.Distribution["4"] != 1 {
+				t.Errorf("unexpected rating stats %+v (err %v)", stats, err)
+			}
+
+			s.Progress.AddToLibrary(ctx, "u2", "m2", "")
+			all, err := s.Progress.All(ctx)
+			if err != nil || len(all) != 2 || all[0].UserID != "u1" || all[1].MangaID != "m2" {
+				t.Errorf("unexpected entries from All: %+v (err %v)", all, err)
+			}
+		})
+	}
+}
+
+func TestProgressSyncKeepsStatus(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seed(t, s)
+
+			mp, err := s.Progress.Sync(ctx, "u1", "m2", 3, "")
+			if err != nil || mp.Status != repository.StatusReading || mp.Manga.Title != "Monster" {
+				t.Fatalf("unexpected sync result %+v (err %v)", mp, err)
+			}
+			s.Progress.Sync(ctx, "u1", "m2", 3, repository.StatusCompleted)
+			time.Sleep(10 * time.Millisecond)
+			mp, _ = s.Progress.Sync(ctx, "u1", "m2", 5, "")
+			if mp.Status != repository.StatusCompleted || mp.CurrentChapter != 5 {
+				t.Errorf("sync without status should keep it, got %+v", mp)
+			}
+			if _, err := s.Progress.Sync(ctx, "u1", "m2", -1, ""); !errors.Is(err, repository.ErrInvalidChapter) {
+				t.Errorf("expected ErrInvalidChapter, got %v", err)
+			}
+
+			s.Progress.AddToLibrary(ctx, "u1", "m1", "")
+			time.Sleep(10 * time.Millisecond)
+			s.Progress.Sync(ctx, "u1", "m2", 6, "")
+			latest, err := s.Progress.Latest(ctx, "u1")
+			if err != nil || latest.Manga.ID != "m2" {
+				t.Errorf("expected m2 as latest, got %+v (err %v)", latest, err)
+			}
+			library, _ := s.Progress.Library(ctx, "u1")
+			if len(library) != 2 || library[0].Manga.ID != "m2" {
+				t.Errorf("expected library newest first, got %+v", library)
+			}
+
+			history, err := s.Progress.History(ctx, "u1", time.Time{})
+			if err != nil || len(history) != 3 {
+				t.Fatalf("expected 3 reading events, got %+v (err %v)", history, err)
+			}
+			if history[0].ChaptersRead != 3 || history[1].ChaptersRead != 2 || history[2].Chapter != 6 || history[2].ChaptersRead != 1 {
+				t.Errorf("unexpected reading events %+v", history)
+			}
+			if recent, _ := s.Progress.History(ctx, "u1", time.Now().Add(time.Hour)); len(recent) != 0 {
+				t.Errorf("expected no events in the future, got %+v", recent)
+			}
+
+			if err := s.Progress.Remove(ctx, "u1", "m1"); err != nil {
+				t.Fatalf("remove: %v", err)
+			}
+			if err := s.Progress.Remove(ctx, "u1", "m1"); !errors.Is(err, repository.ErrNotInLibrary) {
+				t.Errorf("expected ErrNotInLibrary on second remove, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFavoritesAndTags(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seed(t, s)
+			s.Progress.AddToLibrary(ctx, "u1", "m1", "")
+			s.Progress.AddToLibrary(ctx, "u1", "m2", "")
+
+			if err := s.Progress.SetFavorite(ctx, "u1", "m1", true); err != nil {
+				t.Fatalf("set favorite: %v", err)
+			}
+			if err := s.Progress.SetFavorite(ctx, "u2", "m1", true); !errors.Is(err, repository.ErrNotInLibrary) {
+				t.Errorf("expected ErrNotInLibrary, got %v", err)
+			}
+
+			tags, err := s.Progress.SetTags(ctx, "u1", "m1", []string{" Reread ", "dark", "reread", ""})
+			if err != nil || len(tags) != 2 || tags[0] != "dark" || tags[1] != "reread" {
+				t.Fatalf("unexpected tags %v (err %v)", tags, err)
+			}
+			if _, err := s.Progress.SetTags(ctx, "u1", "m2", []string{"this tag is far too long to be accepted"}); !errors.Is(err, repository.ErrInvalidTag) {
+				t.Errorf("expected ErrInvalidTag, got %v", err)
+			}
+
+			library, _ := s.Progress.Library(ctx, "u1")
+			for _, mp := range library {
+				switch mp.Manga.ID {
+				case "m1":
+					if !mp.IsFavorite || len(mp.Tags) != 2 {
+						t.Errorf("unexpected m1 entry %+v", mp)
+					}
+				case "m2":
+					if mp.IsFavorite || mp.Tags == nil || len(mp.Tags) != 0 {
+						t.Errorf("unexpected m2 entry %+v", mp)
+					}
+				}
+			}
+			if p, _ := s.Progress.Get(ctx, "u1", "m1"); !p.IsFavorite {
+				t.Error("expected favorite on Get")
+			}
+
+			if tags, _ := s.Progress.SetTags(ctx, "u1", "m1", nil); len(tags) != 0 {
+				t.Errorf("expected tags cleared, got %v", tags)
+			}
+			s.Progress.SetTags(ctx, "u1", "m1", []string{"dark"})
+			s.Progress.Remove(ctx, "u1", "m1")
+			s.Progress.AddToLibrary(ctx, "u1", "m1", "")
+			if latest, _ := s.Progress.Latest(ctx, "u1"); latest.Manga.ID != "m1" || len(latest.Tags) != 0 || latest.IsFavorite {
+				t.Errorf("re-added entry kept old tags or favorite: %+v", latest)
+			}
+		})
+	}
+}
+
+func TestConversationsAndMessages(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seed(t, s)
+
+			global, err := s.Conversations.GetByName(ctx, "global")
+			if err != nil {
+				t.Fatalf("global conversation missing: %v", err)
+			}
+
+			room := &models.Conversation{Name: "readers", Type: "custom", CreatedBy: "u1"}
+			if err := s.Conversations.Create(ctx, room); err != nil || room.ID == "" {
+				t.Fatalf("create room: %v", err)
+			}
+			if err := s.Conversations.Create(ctx, &models.Conversation{Name: "readers", Type: "custom"}); !errors.Is(err, repository.ErrAlreadyExists) {
+				t.Errorf("expected ErrAlreadyExists, got %v", err)
+			}
+			s.Conversations.AddMember(ctx, room.ID, "u1", repository.RoleOwner)
+			s.Conversations.AddMember(ctx, room.ID, "u1", repository.RoleMember)
+			s.Conversations.AddMember(ctx, room.ID, "u2", "")
+
+			for _, content := range []string{"first", "second", "third"} {
+				if _, err := s.Messages.SaveToConversation(ctx, room.ID, "u1", content); err != nil {
+					t.Fatalf("save message: %v", err)
+				}
+			}
+			history, err := s.Messages.ConversationHistory(ctx, room.ID, 2)
+			if err != nil || len(history) != 2 {
+				t.Fatalf("expected 2 messages, got %d (err %v)", len(history), err)
+			}
+			if history[0].Content != "second" || history[1].Content != "third" || history[1].SenderUsername != "reader" {
+				t.Errorf("expected latest messages in chronological order, got %+v", history)
+			}
+
+			rooms, err := s.Conversations.List(ctx)
+			if err != nil || len(rooms) != 2 {
+				t.Fatalf("expected 2 rooms, got %d (err %v)", len(rooms), err)
+			}
+			if rooms[0].ID != room.ID || rooms[0].MemberCount != 2 || rooms[0].LastMessageAt == nil {
+				t.Errorf("expected active room first with 2 members, got %+v", rooms[0])
+			}
+			if rooms[1].ID != global.ID {
+				t.Errorf("expected global room second, got %+v", rooms[1])
+			}
+
+			s.Messages.SaveDirect(ctx, "u2", "u1", "hi")
+			s.Messages.SaveDirect(ctx, "u2", "", "everyone")
+			direct, _ := s.Messages.DirectHistory(ctx, "u1", 10)
+			if len(direct) != 2 || direct[1].RecipientID != "u1" || direct[0].SenderUsername != "other" {
+				t.Errorf("unexpected direct history %+v", direct)
+			}
+		})
+	}
+}
+
+func TestDirectConversations(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seed(t, s)
+
+			conv, err := s.Conversations.GetOrCreateDirect(ctx, "u1", "u2")
+			if err != nil || conv.Type != "direct" {
+				t.Fatalf("create direct conversation: %+v, %v", conv, err)
+			}
+			again, err := s.Conversations.GetOrCreateDirect(ctx, "u2", "u1")
+			if err != nil || again.ID != conv.ID {
+				t.Fatalf("expected the same conversation from either side, got %+v (err %v)", again, err)
+			}
+
+			s.Messages.SaveToConversation(ctx, conv.ID, "u1", "hello")
+			s.Messages.SaveToConversation(ctx, conv.ID, "u2", "hey")
+
+			rooms, _ := s.Conversations.List(ctx)
+			for _, r := range rooms {
+				if r.ID == conv.ID {
+					t.Errorf("direct conversation listed as a room")
+				}
+			}
+
+			inbox, err := s.Conversations.ListDirect(ctx, "u1")
+			if err != nil || len(inbox) != 1 {
+				t.Fatalf("expected 1 direct conversation, got %d (err %v)", len(inbox), err)
+			}
+			if inbox[0].OtherUsername != "other" || inbox[0].LastMessage == nil || inbox[0].LastMessage.Content != "hey" {
+				t.Errorf("unexpected inbox entry %+v", inbox[0])
+			}
+			if theirs, _ := s.Conversations.ListDirect(ctx, "u2"); len(theirs) != 1 || theirs[0].OtherUserID != "u1" {
+				t.Errorf("unexpected inbox for the other side %+v", theirs)
+			}
+		})
+	}
+}
+
+func TestRoomModeration(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seed(t, s)
+
+			room := &models.Conversation{Name: "club", Type: "custom", CreatedBy: "u1"}
+			if err := s.Conversations.Create(ctx, room); err != nil {
+				t.Fatalf("create room: %v", err)
+			}
+			s.Conversations.AddMember(ctx, room.ID, "u1", repository.RoleOwner)
+			if _, err := s.Conversations.MemberRole(ctx, room.ID, "u2"); !errors.Is(err, repository.ErrNotFound) {
+				t.Errorf("expected ErrNotFound for a non-member, got %v", err)
+			}
+			s.Conversations.SetMemberRole(ctx, room.ID, "u2", repository.RoleModerator)
+			if role, _ := s.Conversations.MemberRole(ctx, room.ID, "u2"); role != repository.RoleModerator {
+				t.Errorf("expected moderator, got %q", role)
+			}
+
+			s.Conversations.SetTopic(ctx, room.ID, "volume 3 spoilers")
+			s.Conversations.SetSlowMode(ctx, room.ID, 30)
+			if got, _ := s.Conversations.Get(ctx, room.ID); got.Topic != "volume 3 spoilers" || got.SlowModeSeconds != 30 {
+				t.Errorf("settings not saved: %+v", got)
+			}
+
+			past := time.Now().Add(-time.Minute)
+			s.Conversations.AddSanction(ctx, &models.RoomSanction{ConversationID: room.ID, UserID: "u2", Kind: repository.SanctionMute, ExpiresAt: &past})
+			if _, err := s.Conversations.ActiveSanction(ctx, room.ID, "u2", repository.SanctionMute); !errors.Is(err, repository.ErrNotFound) {
+				t.Errorf("expired mute still active: %v", err)
+			}
+			s.Conversations.AddSanction(ctx, &models.RoomSanction{ConversationID: room.ID, UserID: "u2", Kind: repository.SanctionBan, Reason: "spam"})
+			if ban, err := s.Conversations.ActiveSanction(ctx, room.ID, "u2", repository.SanctionBan); err != nil || ban.Reason != "spam" || ban.ExpiresAt != nil {
+				t.Errorf("expected a permanent ban, got %+v (err %v)", ban, err)
+			}
+			if err := s.Conversations.RemoveSanction(ctx, room.ID, "u2", repository.SanctionBan); err != nil {
+				t.Errorf("lift ban: %v", err)
+			}
+			if err := s.Conversations.RemoveSanction(ctx, room.ID, "u2", repository.SanctionBan); !errors.Is(err, repository.ErrNotFound) {
+				t.Errorf("expected ErrNotFound lifting twice, got %v", err)
+			}
+
+			s.Messages.SaveToConversation(ctx, room.ID, "u1", "bye")
+			if err := s.Conversations.Delete(ctx, room.ID); err != nil {
+				t.Fatalf("delete room: %v", err)
+			}
+			if _, err := s.Conversations.Get(ctx, room.ID); !errors.Is(err, repository.ErrNotFound) {
+				t.Errorf("room still there after delete: %v", err)
+			}
+			if history, _ := s.Messages.ConversationHistory(ctx, room.ID, 10); len(history) != 0 {
+				t.Errorf("messages survived room delete: %+v", history)
+			}
+		})
+	}
+}
+
+// Deleting a room relies on foreign keys to clear what hangs off its
+// messages, which only SQLite can show
+func TestDeletingRoomLeavesNoOrphans(t *testing.T) {
+	db := migratedDB(t)
+	s := repository.NewSQLiteStore(db)
+	ctx := context.Background()
+	seed(t, s)
+
+	room := &models.Conversation{Name: "club", Type: "custom", CreatedBy: "u1"}
+	if err := s.Conversations.Create(ctx, room); err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+	msg, _ := s.Messages.SaveToConversation(ctx, room.ID, "u1", "chapter 100 was wild")
+	s.Messages.ToggleReaction(ctx, msg.ID, "u2", "🔥")
+	s.Alerts.Record(ctx, &models.ChatAlert{UserID: "u2", MessageID: msg.ID, ConversationID: room.ID, Kind: repository.AlertKeyword, Keyword: "wild"})
+	s.Alerts.AddKeyword(ctx, "u2", room.ID, "wild")
+
+	if err := s.Conversations.Delete(ctx, room.ID); err != nil {
+		t.Fatalf("delete room: %v", err)
+	}
+	for _, table := range []string{"messages", "message_reactions", "chat_alerts", "keyword_alerts"} {
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err != nil || n != 0 {
+			t.Errorf("expected %s to be empty after deleting the room, got %d (%v)", table, n, err)
+		}
+	}
+}
+
+// VACUUM renumbers implicit rowids after deletes; search and read positions
+// must not depend on them
+func TestSearchSurvivesVacuum(t *testing.T) {
+	db := migratedDB(t)
+	s := repository.NewSQLiteStore(db)
+	ctx := context.Background()
+	seed(t, s)
+
+	first, _ := s.Messages.SaveToConversation(ctx, "global", "u1", "first")
+	second, _ := s.Messages.SaveToConversation(ctx, "global", "u2", "second")
+	third, _ := s.Messages.SaveToConversation(ctx, "global", "u2", "zoro got lost again")
+	s.Conversations.AddMember(ctx, "global", "u1", repository.RoleMember)
+	if _, err := db.Exec(`DELETE FROM messages WHERE id = ?`, first.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := db.Exec(`VACUUM`); err != nil {
+		t.Fatalf("vacuum: %v", err)
+	}
+
+	results, err := s.Messages.Search(ctx, "u1", "zoro", 10)
+	if err != nil || len(results) != 1 || results[0].ID != third.ID {
+		t.Fatalf("expected to find the third message after VACUUM, got %+v (%v)", results, err)
+	}
+	if moved, err := s.Conversations.MarkRead(ctx, "global", "u1", second.ID); err != nil || !moved {
+		t.Fatalf("MarkRead = %v, %v", moved, err)
+	}
+	if moved, _ := s.Conversations.MarkRead(ctx, "global", "u1", third.ID); !moved {
+		t.Error("expected the read position to move forward to a later message")
+	}
+}
+
+func TestMessageEditsAndReactions(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seed(t, s)
+			global, _ := s.Conversations.GetByName(ctx, "global")
+
+			first, _ := s.Messages.SaveToConversation(ctx, global.ID, "u1", "chapter 100 was great")
+			reply, err := s.Messages.SaveReply(ctx, global.ID, "u2", "agreed", first.ID)
+			if err != nil || reply.ReplyToID != first.ID {
+				t.Fatalf("save reply: %+v, %v", reply, err)
+			}
+
+			edited, err := s.Messages.Edit(ctx, first.ID, "chapter 101 was great")
+			if err != nil || edited.Content != "chapter 101 was great" || edited.EditedAt == nil {
+				t.Fatalf("edit: %+v, %v", edited, err)
+			}
+
+			if added, _ := s.Messages.ToggleReaction(ctx, first.ID, "u1", "👍"); !added {
+				t.Error("expected first reaction to be added")
+			}
+			s.Messages.ToggleReaction(ctx, first.ID, "u2", "👍")
+			s.Messages.ToggleReaction(ctx, first.ID, "u2", "🔥")
+			if added, _ := s.Messages.ToggleReaction(ctx, first.ID, "u2", "🔥"); added {
+				t.Error("expected repeated reaction to be taken back")
+			}
+			got, err := s.Messages.Get(ctx, first.ID)
+			if err != nil || got.Reactions["👍"] != 2 || len(got.Reactions) != 1 || got.SenderUsername != "reader" {
+				t.Errorf("unexpected message %+v (err %v)", got, err)
+			}
+
+			if err := s.Messages.Delete(ctx, first.ID); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+			if err := s.Messages.Delete(ctx, first.ID); !errors.Is(err, repository.ErrNotFound) {
+				t.Errorf("expected ErrNotFound deleting twice, got %v", err)
+			}
+			if _, err := s.Messages.Edit(ctx, first.ID, "back"); !errors.Is(err, repository.ErrNotFound) {
+				t.Errorf("expected deleted message to be uneditable, got %v", err)
+			}
+
+			history, _ := s.Messages.ConversationHistory(ctx, global.ID, 10)
+			if len(history) != 2 {
+				t.Fatalf("expected tombstone and reply in history, got %+v", history)
+			}
+			if history[0].DeletedAt == nil || history[0].Content != "" || history[0].Reactions != nil {
+				t.Errorf("expected an empty tombstone, got %+v", history[0])
+			}
+			if history[1].ReplyToID != first.ID {
+				t.Errorf("reply lost its parent: %+v", history[1])
+			}
+		})
+	}
+}
+
+func TestTagChapter(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seed(t, s)
+			global, _ := s.Conversations.GetByName(ctx, "global")
+
+			msg, _ := s.Messages.SaveToConversation(ctx, global.ID, "u1", "the ending of chapter 90")
+			if err := s.Messages.TagChapter(ctx, msg.ID, 90); err != nil {
+				t.Fatalf("tag chapter: %v", err)
+			}
+			if err := s.Messages.TagChapter(ctx, msg.ID, -1); !errors.Is(err, repository.ErrInvalidChapter) {
+				t.Errorf("expected ErrInvalidChapter, got %v", err)
+			}
+			if err := s.Messages.TagChapter(ctx, "missing", 1); !errors.Is(err, repository.ErrNotFound) {
+				t.Errorf("expected ErrNotFound for an unknown message, got %v", err)
+			}
+
+			if got, _ := s.Messages.Get(ctx, msg.ID); got == nil || got.SpoilerChapter != 90 {
+				t.Errorf("Get lost the chapter: %+v", got)
+			}
+			history, _ := s.Messages.ConversationHistory(ctx, global.ID, 10)
+			if len(history) != 1 || history[0].SpoilerChapter != 90 {
+				t.Errorf("history lost the chapter: %+v", history)
+			}
+		})
+	}
+}
+
+func TestValidStatus(t *testing.T) {
+	tests := []struct {
+		chapter, total int
+		want           string
+	}{
+		{0, 10, repository.StatusPlanToRead},
+		{3, 10, repository.StatusReading},
+		{10, 10, repository.StatusCompleted},
+		{50, 0, repository.StatusReading},
+	}
+	for _, tt := range tests {
+		if got := repository.StatusForChapter(tt.chapter, tt.total); got != tt.want {
+			t.Errorf("StatusForChapter(%d, %d) = %q, want %q", tt.chapter, tt.total, got, tt.want)
+		}
+	}
+	if repository.ValidStatus("dropped") || !repository.ValidStatus("reading") {
+		t.Error("ValidStatus accepted an unknown status or rejected a known one")
+	}
+}
+
+func TestUnreadCountsAndReadMarkers(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seed(t, s)
+			global, _ := s.Conversations.GetByName(ctx, "global")
+			s.Conversations.AddMember(ctx, global.ID, "u1", repository.RoleMember)
+			s.Conversations.AddMember(ctx, global.ID, "u2", repository.RoleMember)
+
+			var sent []string
+			for _, text := range []string{"one", "two", "three"} {
+				m, _ := s.Messages.SaveToConversation(ctx, global.ID, "u1", text)
+				s.Conversations.IncrementUnread(ctx, global.ID, []string{"u1"})
+				sent = append(sent, m.ID)
+			}
+
+			unread := func(userID string) int {
+				list, err := s.Conversations.ListForMember(ctx, userID)
+				if err != nil || len(list) != 1 {
+					t.Fatalf("list for %s: %+v, %v", userID, list, err)
+				}
+				return list[0].UnreadCount
+			}
+			if n := unread("u2"); n != 3 {
+				t.Errorf("expected 3 unread, got %d", n)
+			}
+			if n := unread("u1"); n != 0 {
+				t.Errorf("sender should have nothing unread, got %d", n)
+			}
+
+			if advanced, err := s.Conversations.MarkRead(ctx, global.ID, "u2", sent[1]); err != nil || !advanced {
+				t.Fatalf("mark read: %v, %v", advanced, err)
+			}
+			if n := unread("u2"); n != 1 {
+				t.Errorf("expected 1 unread after reading two, got %d", n)
+			}
+			if advanced, _ := s.Conversations.MarkRead(ctx, global.ID, "u2", sent[0]); advanced {
+				t.Error("read marker moved backwards")
+			}
+			if _, err := s.Conversations.MarkRead(ctx, global.ID, "u2", "missing"); !errors.Is(err, repository.ErrNotFound) {
+				t.Errorf("expected ErrNotFound for an unknown message, got %v", err)
+			}
+
+			dm, _ := s.Conversations.GetOrCreateDirect(ctx, "u1", "u2")
+			m, _ := s.Messages.SaveToConversation(ctx, dm.ID, "u1", "psst")
+			s.Conversations.IncrementUnread(ctx, dm.ID, []string{"u1"})
+			inbox, _ := s.Conversations.ListDirect(ctx, "u2")
+			if len(inbox) != 1 || inbox[0].UnreadCount != 1 {
+				t.Errorf("expected one unread DM, got %+v", inbox)
+			}
+			s.Conversations.MarkRead(ctx, dm.ID, "u2", m.ID)
+			list, _ := s.Conversations.ListForMember(ctx, "u2")
+			for _, mc := range list {
+				if mc.Type == "direct" && (mc.UnreadCount != 0 || mc.OtherUsername != "reader" || mc.LastReadMessageID != m.ID) {
+					t.Errorf("unexpected direct conversation %+v", mc)
+				}
+			}
+		})
+	}
+}
+
+func TestConversationPagingAndSearch(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seed(t, s)
+			global, _ := s.Conversations.GetByName(ctx, "global")
+			s.Conversations.AddMember(ctx, global.ID, "u1", repository.RoleMember)
+
+			var ids []string
+			for i := 1; i <= 5; i++ {
+				m, _ := s.Messages.SaveToConversation(ctx, global.ID, "u1", fmt.Sprintf("message %d about Luffy", i))
+				ids = append(ids, m.ID)
+			}
+
+			contents := func(msgs []models.ChatMessage) []string {
+				out := []string{}
+				for _, m := range msgs {
+					out = append(out, m.Content[:9])
+				}
+				return out
+			}
+			page, err := s.Messages.ConversationPage(ctx, global.ID, repository.MessagePage{Limit: 2})
+			if err != nil || !reflect.DeepEqual(contents(page), []string{"message 4", "message 5"}) {
+				t.Fatalf("newest page = %v, %v", contents(page), err)
+			}
+			page, _ = s.Messages.ConversationPage(ctx, global.ID, repository.MessagePage{Before: page[0].ID, Limit: 2})
+			if !reflect.DeepEqual(contents(page), []string{"message 2", "message 3"}) {
+				t.Errorf("page before message 4 = %v", contents(page))
+			}
+			page, _ = s.Messages.ConversationPage(ctx, global.ID, repository.MessagePage{After: ids[0], Limit: 2})
+			if !reflect.DeepEqual(contents(page), []string{"message 2", "message 3"}) {
+				t.Errorf("page after message 1 = %v", contents(page))
+			}
+			page, _ = s.Messages.ConversationPage(ctx, global.ID, repository.MessagePage{Before: ids[0], Limit: 2})
+			if len(page) != 0 {
+				t.Errorf("expected nothing before the first message, got %v", contents(page))
+			}
+			if _, err := s.Messages.ConversationPage(ctx, global.ID, repository.MessagePage{Before: "missing", Limit: 2}); !errors.Is(err, repository.ErrNotFound) {
+				t.Errorf("expected ErrNotFound for an unknown cursor, got %v", err)
+			}
+
+			s.Messages.Edit(ctx, ids[1], "message 2 about Zoro now")
+			s.Messages.Delete(ctx, ids[2])
+			results, err := s.Messages.Search(ctx, "u1", "luffy MESSAGE", 10)
+			if err != nil || len(results) != 3 {
+				t.Fatalf("search = %+v, %v", results, err)
+			}
+			if results[0].ID != ids[4] || results[0].ConversationName != "global" || results[0].SenderUsername != "reader" {
+				t.Errorf("unexpected first result %+v", results[0])
+			}
+			if results, _ := s.Messages.Search(ctx, "u1", "zoro", 10); len(results) != 1 || results[0].ID != ids[1] {
+				t.Errorf("edited message not found by its new text: %+v", results)
+			}
+			if results, _ := s.Messages.Search(ctx, "u2", "luffy", 10); len(results) != 0 {
+				t.Errorf("non-member found messages: %+v", results)
+			}
+		})
+	}
+}
+
+func TestChatAlerts(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seed(t, s)
+			global, _ := s.Conversations.GetByName(ctx, "global")
+			room := &models.Conversation{Name: "one-piece", Type: "custom"}
+			if err := s.Conversations.Create(ctx, room); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := s.Alerts.AddKeyword(ctx, "u1", room.ID, "luffy"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Alerts.AddKeyword(ctx, "u1", room.ID, "luffy"); !errors.Is(err, repository.ErrAlreadyExists) {
+				t.Errorf("expected ErrAlreadyExists for a duplicate keyword, got %v", err)
+			}
+			s.Alerts.AddKeyword(ctx, "u2", "", "zoro")
+			if kws, _ := s.Alerts.KeywordsForConversation(ctx, global.ID); len(kws) != 1 || kws[0].Keyword != "zoro" {
+				t.Errorf("global keywords = %+v, want only the every-room one", kws)
+			}
+			if kws, _ := s.Alerts.KeywordsForConversation(ctx, room.ID); len(kws) != 2 {
+				t.Errorf("expected 2 keywords in one-piece, got %+v", kws)
+			}
+			if kws, _ := s.Alerts.Keywords(ctx, "u1"); len(kws) != 1 || kws[0].ConversationName != "one-piece" {
+				t.Errorf("u1 keywords = %+v", kws)
+			}
+
+			msg, _ := s.Messages.SaveToConversation(ctx, room.ID, "u2", "hey @reader, Luffy is back")
+			alert := &models.ChatAlert{UserID: "u1", MessageID: msg.ID, ConversationID: room.ID, Kind: repository.AlertMention}
+			if ok, err := s.Alerts.Record(ctx, alert); err != nil || !ok {
+				t.Fatalf("Record = %v, %v", ok, err)
+			}
+			again := &models.ChatAlert{UserID: "u1", MessageID: msg.ID, ConversationID: room.ID, Kind: repository.AlertKeyword, Keyword: "luffy"}
+			if ok, _ := s.Alerts.Record(ctx, again); ok {
+				t.Error("expected one alert per user and message")
+			}
+			alerts, err := s.Alerts.ListForUser(ctx, "u1", 10)
+			if err != nil || len(alerts) != 1 {
+				t.Fatalf("ListForUser = %+v, %v", alerts, err)
+			}
+			if a := alerts[0]; a.SenderUsername != "other" || a.ConversationName != "one-piece" || a.Content != msg.Content || a.Kind != repository.AlertMention {
+				t.Errorf("unexpected alert %+v", a)
+			}
+
+			if err := s.Alerts.RemoveKeyword(ctx, "u1", room.ID, "luffy"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Alerts.RemoveKeyword(ctx, "u1", room.ID, "luffy"); !errors.Is(err, repository.ErrNotFound) {
+				t.Errorf("expected ErrNotFound removing a missing keyword, got %v", err)
+			}
+
+			s.Alerts.AddKeyword(ctx, "u1", room.ID, "nami")
+			s.Conversations.Delete(ctx, room.ID)
+			if alerts, _ := s.Alerts.ListForUser(ctx, "u1", 10); len(alerts) != 0 {
+				t.Errorf("expected alerts to go with the room, got %+v", alerts)
+			}
+			if kws, _ := s.Alerts.Keywords(ctx, "u1"); len(kws) != 0 {
+				t.Errorf("expected keywords to go with the room, got %+v", kws)
+			}
+		})
+	}
+}
+
+func TestPresence(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seed(t, s)
+
+			active := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+			if err := s.Presence.Save(ctx, &models.UserPresence{UserID: "u1", Status: repository.PresenceOnline, LastActive: &active}); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Presence.Save(ctx, &models.UserPresence{UserID: "u1", Status: repository.PresenceOffline}); err != nil {
+				t.Fatal(err)
+			}
+			found, err := s.Presence.Get(ctx, []string{"u1", "u2"})
+			if err != nil || len(found) != 1 {
+				t.Fatalf("Get = %+v, %v", found, err)
+			}
+			p := found["u1"]
+			if p.Status != repository.PresenceOffline || p.Username != "reader" || p.LastSeen == nil {
+				t.Errorf("unexpected presence %+v", p)
+			}
+			if p.LastActive == nil || !p.LastActive.Equal(active) {
+				t.Errorf("expected last_active to survive a save without one, got %v", p.LastActive)
+			}
+
+			room := &models.Conversation{Name: "one-piece", Type: "custom"}
+			s.Conversations.Create(ctx, room)
+			s.Conversations.AddMember(ctx, room.ID, "u1", repository.RoleMember)
+			global, _ := s.Conversations.GetByName(ctx, "global")
+			s.Conversations.AddMember(ctx, global.ID, "u1", repository.RoleMember)
+			s.Conversations.AddMember(ctx, global.ID, "u2", repository.RoleMember)
+			if contacts, _ := s.Conversations.Contacts(ctx, "u1"); len(contacts) != 0 {
+				t.Errorf("expected the global room not to make contacts, got %v", contacts)
+			}
+			s.Conversations.AddMember(ctx, room.ID, "u2", repository.RoleMember)
+			if contacts, err := s.Conversations.Contacts(ctx, "u1"); err != nil || !reflect.DeepEqual(contacts, []string{"u2"}) {
+				t.Errorf("Contacts = %v, %v", contacts, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package review
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxReviewLength  = 10000
+	maxCommentLength = 2000
+)
+
+// Handler exposes reviews under /manga/:id/reviews and chapter discussion
+// under /manga/:id/chapters/:chapter/comments
+type Handler struct {
+	store *Store
+}
+
+func NewHandler(store *Store) *Handler {
+	return &Handler{store: store}
+}
+
+// ListReviews returns a page of visible reviews (?sort=recent|helpful)
+func (h *Handler) ListReviews(c *gin.Context) {
+	opts := listOptions(c)
+	if opts.Sort != SortHelpful {
+		opts.Sort = SortRecent
+	}
+
+	reviews, total, err := h.store.ListReviews(c.Param("id"), opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reviews": reviews, "count": len(reviews), "total": total})
+}
+
+// CreateReview adds the current user's review; each user reviews a manga once
+func (h *Handler) CreateReview(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	req, ok := bindBody(c, maxReviewLength)
+	if !ok {
+		return
+	}
+
+	review, err := h.store.CreateReview(c.Param("id"), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrMangaNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Manga not found"})
+		case errors.Is(err, ErrAlreadyReviewed):
+			c.JSON(http.StatusConflict, gin.H{"error": "You have already reviewed this manga"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, review)
+}
+
+// UpdateReview edits the author's review, keeping the previous text in its history
+func (h *Handler) UpdateReview(c *gin.Context) {
+	review, ok := h.visibleReview(c)
+	if !ok {
+		return
+	}
+	if review.UserID != c.GetString("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can edit this review"})
+		return
+	}
+
+	req, ok := bindBody(c, maxReviewLength)
+	if !ok {
+		return
+	}
+
+	updated, err := h.store.UpdateReview(review.ID, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteReview removes a review; admins may remove anyone's
+func (h *Handler) DeleteReview(c *gin.Context) {
+	review, ok := h.review(c)
+	if !ok {
+		return
+	}
+	if review.UserID != c.GetString("user_id") && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author or an admin can delete this review"})
+		return
+	}
+
+	if err := h.store.DeleteReview(review.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Review deleted"})
+}
+
+// GetReviewHistory returns the current review and its earlier versions
+func (h *Handler) GetReviewHistory(c *gin.Context) {
+	review, ok := h.visibleReview(c)
+	if !ok {
+		return
+	}
+
+	revisions, err := h.store.Revisions(review.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load review history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"review": review, "revisions": revisions, "count": len(revisions)})
+}
+
+// MarkHelpful records the current user's helpful vote
+func (h *Handler) MarkHelpful(c *gin.Context) {
+	h.setHelpful(c, true)
+}
+
+// UnmarkHelpful withdraws the current user's helpful vote
+func (h *Handler) UnmarkHelpful(c *gin.Context) {
+	h.setHelpful(c, false)
+}
+
+func (h *Handler) setHelpful(c *gin.Context, helpful bool) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	review, ok := h.visibleReview(c)
+	if !ok {
+		return
+	}
+	if review.UserID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot vote on your own review"})
+		return
+	}
+
+	count, err := h.store.SetHelpful(review.ID, userID, helpful)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"review_id": review.ID, "helpful": helpful, "helpful_count": count})
+}
+
+// ModerateReview hides or restores a review (admin only)
+func (h *Handler) ModerateReview(c *gin.Context) {
+	review, ok := h.review(c)
+	if !ok {
+		return
+	}
+
+	var req models.ModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := h.store.ModerateReview(review.ID, c.GetString("user_id"), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to moderate review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// ListComments returns a page of a chapter's visible comments, oldest first
+// unless ?sort=recent
+func (h *Handler) ListComments(c *gin.Context) {
+	chapter, ok := chapterParam(c)
+	if !ok {
+		return
+	}
+
+	comments, total, err := h.store.ListComments(c.Param("id"), chapter, listOptions(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load comments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"comments": comments, "count": len(comments), "total": total})
+}
+
+// CreateComment posts to a chapter's discussion thread
+func (h *Handler) CreateComment(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	chapter, ok := chapterParam(c)
+	if !ok {
+		return
+	}
+
+	req, ok := bindBody(c, maxCommentLength)
+	if !ok {
+		return
+	}
+
+	comment, err := h.store.CreateComment(c.Param("id"), chapter, userID, req)
+	if err != nil {
+		if errors.Is(err, ErrMangaNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Manga not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// UpdateComment edits the author's comment
+func (h *Handler) UpdateComment(c *gin.Context) {
+	comment, ok := h.comment(c)
+	if !ok {
+		return
+	}
+	if comment.Hidden {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	if comment.UserID != c.GetString("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can edit this comment"})
+		return
+	}
+
+	req, ok := bindBody(c, maxCommentLength)
+	if !ok {
+		return
+	}
+
+	updated, err := h.store.UpdateComment(comment.ID, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteComment removes a comment; admins may remove anyone's
+func (h *Handler) DeleteComment(c *gin.Context) {
+	comment, ok := h.comment(c)
+	if !ok {
+		return
+	}
+	if comment.UserID != c.GetString("user_id") && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author or an admin can delete this comment"})
+		return
+	}
+
+	if err := h.store.DeleteComment(comment.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted"})
+}
+
+// ModerateComment hides or restores a comment (admin only)
+func (h *Handler) ModerateComment(c *gin.Context) {
+	comment, ok := h.comment(c)
+	if !ok {
+		return
+	}
+
+	var req models.ModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := h.store.ModerateComment(comment.ID, c.GetString("user_id"), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to moderate comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// review loads the :review_id review and checks it belongs to the :id manga
+func (h *Handler) review(c *gin.Context) (*models.Review, bool) {
+	review, err := h.store.GetReview(c.Param("review_id"))
+	if err != nil {
+		if errors.Is(err, ErrReviewNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load review"})
+		return nil, false
+	}
+	if review.MangaID != c.Param("id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return nil, false
+	}
+	return review, true
+}
+
+// visibleReview is review for actions that don't apply to hidden reviews
+func (h *Handler) visibleReview(c *gin.Context) (*models.Review, bool) {
+	review, ok := h.review(c)
+	if ok && review.Hidden && !isAdmin(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return nil, false
+	}
+	return review, ok
+}
+
+// comment loads the :comment_id comment and checks it belongs to the :id manga and :chapter
+func (h *Handler) comment(c *gin.Context) (*models.ChapterComment, bool) {
+	chapter, ok := chapterParam(c)
+	if !ok {
+		return nil, false
+	}
+
+	comment, err := h.store.GetComment(c.Param("comment_id"))
+	if err != nil {
+		if errors.Is(err, ErrCommentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load comment"})
+		return nil, false
+	}
+	if comment.MangaID != c.Param("id") || comment.Chapter != chapter {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return nil, false
+	}
+	return comment, true
+}
+
+func bindBody(c *gin.Context, maxLength int) (models.WriteReviewRequest, bool) {
+	var req models.WriteReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Body == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "body must not be empty"})
+		return req, false
+	}
+	if len([]rune(req.Body)) > maxLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "body must be at most " + strconv.Itoa(maxLength) + " characters"})
+		return req, false
+	}
+	return req, true
+}
+
+func chapterParam(c *gin.Context) (int, bool) {
+	chapter, err := strconv.Atoi(c.Param("chapter"))
+	if err != nil || chapter < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chapter number"})
+		return 0, false
+	}
+	return chapter, true
+}
+
+func listOptions(c *gin.Context) ListOptions {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	return ListOptions{Sort: c.Query("sort"), Limit: limit, Offset: offset}
+}
+
+func isAdmin(c *gin.Context) bool {
+	return c.GetString("role") == "admin"
+}
@@ -0,0 +1,377 @@
+package review
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+)
+
+var (
+	ErrReviewNotFound  = errors.New("review not found")
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrMangaNotFound   = errors.New("manga not found")
+	ErrAlreadyReviewed = errors.New("you have already reviewed this manga")
+)
+
+const (
+	SortRecent  = "recent"
+	SortHelpful = "helpful"
+)
+
+// ListOptions pages through reviews or comments. Hidden items are only
+// included for moderators.
+type ListOptions struct {
+	Sort          string
+	IncludeHidden bool
+	Limit         int
+	Offset        int
+}
+
+// Store persists reviews, their revisions and helpful votes, and chapter comments
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) mangaExists(mangaID string) error {
+	var exists bool
+	if err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM manga WHERE id = ?)`, mangaID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check manga: %w", err)
+	}
+	if !exists {
+		return ErrMangaNotFound
+	}
+	return nil
+}
+
+const reviewColumns = `r.id, r.manga_id, r.user_id, COALESCE(u.username, ''), up.user_rating, r.body, r.spoiler,
+	(SELECT COUNT(*) FROM review_votes v WHERE v.review_id = r.id) AS helpful_count,
+	r.hidden, COALESCE(r.hidden_by, ''), COALESCE(r.hidden_reason, ''), r.created_at, r.updated_at, r.edited_at`
+
+const reviewFrom = `FROM reviews r
+	LEFT JOIN users u ON u.id = r.user_id
+	LEFT JOIN user_progress up ON up.user_id = r.user_id AND up.manga_id = r.manga_id`
+
+func (s *Store) CreateReview(mangaID, userID string, req models.WriteReviewRequest) (*models.Review, error) {
+	if err := s.mangaExists(mangaID); err != nil {
+		return nil, err
+	}
+
+	id, err := utils.GenerateID(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate review id: %w", err)
+	}
+
+	now := time.Now()
+	_, err = s.db.Exec(`INSERT INTO reviews (id, manga_id, user_id, body, spoiler, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, id, mangaID, userID, req.Body, req.Spoiler, now, now)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrAlreadyReviewed
+		}
+		return nil, fmt.Errorf("failed to create review: %w", err)
+	}
+	return s.GetReview(id)
+}
+
+func (s *Store) GetReview(id string) (*models.Review, error) {
+	row := s.db.QueryRow(`SELECT `+reviewColumns+` `+reviewFrom+` WHERE r.id = ?`, id)
+	r, err := scanReview(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReviewNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load review: %w", err)
+	}
+	return r, nil
+}
+
+// ListReviews returns one page of a manga's reviews and the total number of reviews
+func (s *Store) ListReviews(mangaID string, opts ListOptions) ([]models.Review, int, error) {
+	where := ` WHERE r.manga_id = ?`
+	if !opts.IncludeHidden {
+		where += ` AND r.hidden = 0`
+	}
+
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM reviews r`+where, mangaID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count reviews: %w", err)
+	}
+
+	order := ` ORDER BY r.created_at DESC, r.rowid DESC`
+	if opts.Sort == SortHelpful {
+		order = ` ORDER BY helpful_count DESC, r.created_at DESC, r.rowid DESC`
+	}
+
+	rows, err := s.db.Query(`SELECT `+reviewColumns+` `+reviewFrom+where+order+` LIMIT ? OFFSET ?`,
+		mangaID, opts.Limit, opts.Offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list reviews: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := []models.Review{}
+	for rows.Next() {
+		r, err := scanReview(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan review: %w", err)
+		}
+		reviews = append(reviews, *r)
+	}
+	return reviews, total, rows.Err()
+}
+
+// UpdateReview saves the current text as a revision and replaces it
+func (s *Store) UpdateReview(id string, req models.WriteReviewRequest) (*models.Review, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO review_revisions (review_id, body, spoiler, created_at)
+		SELECT id, body, spoiler, COALESCE(edited_at, created_at) FROM reviews WHERE id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save revision: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrReviewNotFound
+	}
+
+	now := time.Now()
+	if _, err := tx.Exec(`UPDATE reviews SET body = ?, spoiler = ?, updated_at = ?, edited_at = ? WHERE id = ?`,
+		req.Body, req.Spoiler, now, now, id); err != nil {
+		return nil, fmt.Errorf("failed to update review: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit review: %w", err)
+	}
+	return s.GetReview(id)
+}
+
+func (s *Store) DeleteReview(id string) error {
+	res, err := s.db.Exec(`DELETE FROM reviews WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete review: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrReviewNotFound
+	}
+	return nil
+}
+
+// Revisions returns the earlier versions of a review, oldest first
+func (s *Store) Revisions(reviewID string) ([]models.ReviewRevision, error) {
+	rows, err := s.db.Query(`SELECT id, review_id, body, spoiler, created_at
+		FROM review_revisions WHERE review_id = ? ORDER BY id`, reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []models.ReviewRevision{}
+	for rows.Next() {
+		var rev models.ReviewRevision
+		if err := rows.Scan(&rev.ID, &rev.ReviewID, &rev.Body, &rev.Spoiler, &rev.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan revision: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+// SetHelpful records or withdraws a user's helpful vote and returns the new count
+func (s *Store) SetHelpful(reviewID, userID string, helpful bool) (int, error) {
+	var err error
+	if helpful {
+		_, err = s.db.Exec(`INSERT OR IGNORE INTO review_votes (review_id, user_id, created_at) VALUES (?, ?, ?)`,
+			reviewID, userID, time.Now())
+	} else {
+		_, err = s.db.Exec(`DELETE FROM review_votes WHERE review_id = ? AND user_id = ?`, reviewID, userID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to record vote: %w", err)
+	}
+
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM review_votes WHERE review_id = ?`, reviewID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count votes: %w", err)
+	}
+	return count, nil
+}
+
+func (s *Store) ModerateReview(id, moderatorID string, req models.ModerationRequest) (*models.Review, error) {
+	if err := s.moderate("reviews", id, moderatorID, req); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrReviewNotFound
+		}
+		return nil, err
+	}
+	return s.GetReview(id)
+}
+
+const commentColumns = `c.id, c.manga_id, c.chapter, c.user_id, COALESCE(u.username, ''), c.body, c.spoiler,
+	c.hidden, COALESCE(c.hidden_by, ''), COALESCE(c.hidden_reason, ''), c.created_at, c.updated_at, c.edited_at`
+
+const commentFrom = `FROM chapter_comments c LEFT JOIN users u ON u.id = c.user_id`
+
+func (s *Store) CreateComment(mangaID string, chapter int, userID string, req models.WriteReviewRequest) (*models.ChapterComment, error) {
+	if err := s.mangaExists(mangaID); err != nil {
+		return nil, err
+	}
+
+	id, err := utils.GenerateID(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate comment id: %w", err)
+	}
+
+	now := time.Now()
+	_, err = s.db.Exec(`INSERT INTO chapter_comments (id, manga_id, chapter, user_id, body, spoiler, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, id, mangaID, chapter, userID, req.Body, req.Spoiler, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+	return s.GetComment(id)
+}
+
+func (s *Store) GetComment(id string) (*models.ChapterComment, error) {
+	row := s.db.QueryRow(`SELECT `+commentColumns+` `+commentFrom+` WHERE c.id = ?`, id)
+	c, err := scanComment(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load comment: %w", err)
+	}
+	return c, nil
+}
+
+// ListComments returns one page of a chapter's discussion, oldest first, and the total
+func (s *Store) ListComments(mangaID string, chapter int, opts ListOptions) ([]models.ChapterComment, int, error) {
+	where := ` WHERE c.manga_id = ? AND c.chapter = ?`
+	if !opts.IncludeHidden {
+		where += ` AND c.hidden = 0`
+	}
+
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM chapter_comments c`+where, mangaID, chapter).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count comments: %w", err)
+	}
+
+	order := ` ORDER BY c.created_at, c.rowid`
+	if opts.Sort == SortRecent {
+		order = ` ORDER BY c.created_at DESC, c.rowid DESC`
+	}
+
+	rows, err := s.db.Query(`SELECT `+commentColumns+` `+commentFrom+where+order+` LIMIT ? OFFSET ?`,
+		mangaID, chapter, opts.Limit, opts.Offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []models.ChapterComment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, *c)
+	}
+	return comments, total, rows.Err()
+}
+
+func (s *Store) UpdateComment(id string, req models.WriteReviewRequest) (*models.ChapterComment, error) {
+	now := time.Now()
+	res, err := s.db.Exec(`UPDATE chapter_comments SET body = ?, spoiler = ?, updated_at = ?, edited_at = ? WHERE id = ?`,
+		req.Body, req.Spoiler, now, now, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update comment: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrCommentNotFound
+	}
+	return s.GetComment(id)
+}
+
+func (s *Store) DeleteComment(id string) error {
+	res, err := s.db.Exec(`DELETE FROM chapter_comments WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
+func (s *Store) ModerateComment(id, moderatorID string, req models.ModerationRequest) (*models.ChapterComment, error) {
+	if err := s.moderate("chapter_comments", id, moderatorID, req); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+	return s.GetComment(id)
+}
+
+// moderate hides or restores a row of table; restoring clears the moderator and reason
+func (s *Store) moderate(table, id, moderatorID string, req models.ModerationRequest) error {
+	var res sql.Result
+	var err error
+	if req.Hidden {
+		res, err = s.db.Exec(`UPDATE `+table+` SET hidden = 1, hidden_by = ?, hidden_reason = ? WHERE id = ?`,
+			moderatorID, req.Reason, id)
+	} else {
+		res, err = s.db.Exec(`UPDATE `+table+` SET hidden = 0, hidden_by = NULL, hidden_reason = NULL WHERE id = ?`, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to moderate: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanReview(row scanner) (*models.Review, error) {
+	var r models.Review
+	var rating sql.NullFloat64
+	var edited sql.NullTime
+	if err := row.Scan(&r.ID, &r.MangaID, &r.UserID, &r.Username, &rating, &r.Body, &r.Spoiler, &r.HelpfulCount,
+		&r.Hidden, &r.HiddenBy, &r.HiddenReason, &r.CreatedAt, &r.UpdatedAt, &edited); err != nil {
+		return nil, err
+	}
+	if rating.Valid {
+		r.Rating = &rating.Float64
+	}
+	if edited.Valid {
+		r.EditedAt = &edited.Time
+	}
+	return &r, nil
+}
+
+func scanComment(row scanner) (*models.ChapterComment, error) {
+	var c models.ChapterComment
+	var edited sql.NullTime
+	if err := row.Scan(&c.ID, &c.MangaID, &c.Chapter, &c.UserID, &c.Username, &c.Body, &c.Spoiler,
+		&c.Hidden, &c.HiddenBy, &c.HiddenReason, &c.CreatedAt, &c.UpdatedAt, &edited); err != nil {
+		return nil, err
+	}
+	if edited.Valid {
+		c.EditedAt = &edited.Time
+	}
+	return &c, nil
+}
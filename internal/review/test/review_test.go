@@ -0,0 +1,233 @@
+package review_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/review"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+func setupStore(t *testing.T) *review.Store {
+	if err := database.InitDatabase(t.TempDir() + "/reviews.db"); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	_, err := database.DB.Exec(`INSERT INTO users (id, username, email, password_hash, role)
+		VALUES ('user-1', 'reader', 'reader@example.com', 'hash', 'user'),
+		       ('user-2', 'critic', 'critic@example.com', 'hash', 'user'),
+		       ('admin-1', 'admin', 'admin@example.com', 'hash', 'admin')`)
+	if err != nil {
+		t.Fatalf("failed to insert users: %v", err)
+	}
+	_, err = database.DB.Exec(`INSERT INTO manga (id, title, total_chapters) VALUES ('m1', 'Berserk', 10)`)
+	if err != nil {
+		t.Fatalf("failed to insert manga: %v", err)
+	}
+	_, err = database.DB.Exec(`INSERT INTO user_progress (user_id, manga_id, current_chapter, status, user_rating)
+		VALUES ('user-1', 'm1', 10, 'completed', 5)`)
+	if err != nil {
+		t.Fatalf("failed to insert progress: %v", err)
+	}
+	return review.NewStore(database.DB)
+}
+
+// newRouter mounts the review routes the way the API server does, with the
+// caller's identity taken from test headers
+func newRouter(store *review.Store) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := review.NewHandler(store)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		c.Set("role", c.GetHeader("X-Test-Role"))
+	})
+	manga := router.Group("/manga")
+	manga.GET("/chapters/:mangadexId", func(c *gin.Context) {})
+	manga.GET("/:id", func(c *gin.Context) {})
+	manga.GET("/:id/reviews", h.ListReviews)
+	manga.POST("/:id/reviews", h.CreateReview)
+	manga.PUT("/:id/reviews/:review_id", h.UpdateReview)
+	manga.DELETE("/:id/reviews/:review_id", h.DeleteReview)
+	manga.GET("/:id/reviews/:review_id/history", h.GetReviewHistory)
+	manga.POST("/:id/reviews/:review_id/helpful", h.MarkHelpful)
+	manga.PUT("/:id/reviews/:review_id/moderation", h.ModerateReview)
+	manga.GET("/:id/chapters/:chapter/comments", h.ListComments)
+	manga.POST("/:id/chapters/:chapter/comments", h.CreateComment)
+	manga.PUT("/:id/chapters/:chapter/comments/:comment_id", h.UpdateComment)
+	manga.DELETE("/:id/chapters/:chapter/comments/:comment_id", h.DeleteComment)
+	return router
+}
+
+func do(t *testing.T, router *gin.Engine, method, path, user, role, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-User", user)
+	req.Header.Set("X-Test-Role", role)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestReviewLifecycle(t *testing.T) {
+	store := setupStore(t)
+	router := newRouter(store)
+
+	w := do(t, router, http.MethodPost, "/manga/m1/reviews", "user-1", "user", `{"body":"Griffith did nothing wrong","spoiler":true}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d (%s)", w.Code, w.Body.String())
+	}
+	var created models.Review
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if created.Username != "reader" || created.Rating == nil || *created.Rating != 5 || !created.Spoiler {
+		t.Errorf("unexpected review %+v", created)
+	}
+
+	if w := do(t, router, http.MethodPost, "/manga/m1/reviews", "user-1", "user", `{"body":"again"}`); w.Code != http.StatusConflict {
+		t.Errorf("duplicate review status = %d, want 409", w.Code)
+	}
+	if w := do(t, router, http.MethodPost, "/manga/nope/reviews", "user-1", "user", `{"body":"x"}`); w.Code != http.StatusNotFound {
+		t.Errorf("unknown manga status = %d, want 404", w.Code)
+	}
+
+	reviewPath := "/manga/m1/reviews/" + created.ID
+	if w := do(t, router, http.MethodPut, reviewPath, "user-2", "user", `{"body":"hijack"}`); w.Code != http.StatusForbidden {
+		t.Errorf("edit by other user status = %d, want 403", w.Code)
+	}
+	if w := do(t, router, http.MethodPut, reviewPath, "user-1", "user", `{"body":"A masterpiece"}`); w.Code != http.StatusOK {
+		t.Fatalf("edit status = %d (%s)", w.Code, w.Body.String())
+	}
+
+	w = do(t, router, http.MethodGet, reviewPath+"/history", "", "", "")
+	var history struct {
+		Review    models.Review           `json:"review"`
+		Revisions []models.ReviewRevision `json:"revisions"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &history)
+	if history.Review.Body != "A masterpiece" || history.Review.EditedAt == nil || history.Review.Spoiler {
+		t.Errorf("unexpected current review %+v", history.Review)
+	}
+	if len(history.Revisions) != 1 || history.Revisions[0].Body != "Griffith did nothing wrong" || !history.Revisions[0].Spoiler {
+		t.Errorf("expected the original text in history, got %+v", history.Revisions)
+	}
+
+	if w := do(t, router, http.MethodPost, reviewPath+"/helpful", "user-1", "user", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("self vote status = %d, want 400", w.Code)
+	}
+	for i := 0; i < 2; i++ {
+		w = do(t, router, http.MethodPost, reviewPath+"/helpful", "user-2", "user", "")
+	}
+	var vote struct {
+		HelpfulCount int `json:"helpful_count"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &vote)
+	if vote.HelpfulCount != 1 {
+		t.Errorf("repeated vote should count once, got %d", vote.HelpfulCount)
+	}
+
+	if w := do(t, router, http.MethodDelete, reviewPath, "admin-1", "admin", ""); w.Code != http.StatusOK {
+		t.Errorf("admin delete status = %d, want 200", w.Code)
+	}
+	if w := do(t, router, http.MethodGet, reviewPath+"/history", "", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("deleted review status = %d, want 404", w.Code)
+	}
+}
+
+func TestReviewSortingAndModeration(t *testing.T) {
+	store := setupStore(t)
+	router := newRouter(store)
+
+	first, err := store.CreateReview("m1", "user-1", models.WriteReviewRequest{Body: "first"})
+	if err != nil {
+		t.Fatalf("create review: %v", err)
+	}
+	second, _ := store.CreateReview("m1", "user-2", models.WriteReviewRequest{Body: "second"})
+	store.SetHelpful(first.ID, "user-2", true)
+	store.SetHelpful(first.ID, "admin-1", true)
+
+	list := func(query string) []models.Review {
+		w := do(t, router, http.MethodGet, "/manga/m1/reviews"+query, "", "", "")
+		var res struct {
+			Reviews []models.Review `json:"reviews"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return res.Reviews
+	}
+
+	if got := list(""); len(got) != 2 || got[0].ID != second.ID {
+		t.Errorf("expected newest first, got %+v", got)
+	}
+	if got := list("?sort=helpful"); len(got) != 2 || got[0].ID != first.ID || got[0].HelpfulCount != 2 {
+		t.Errorf("expected most helpful first, got %+v", got)
+	}
+
+	moderated, err := store.ModerateReview(second.ID, "admin-1", models.ModerationRequest{Hidden: true, Reason: "off-topic"})
+	if err != nil || !moderated.Hidden || moderated.HiddenBy != "admin-1" {
+		t.Fatalf("unexpected moderation result %+v (err %v)", moderated, err)
+	}
+	if got := list(""); len(got) != 1 || got[0].ID != first.ID {
+		t.Errorf("hidden review should not be listed, got %+v", got)
+	}
+	if w := do(t, router, http.MethodPost, "/manga/m1/reviews/"+second.ID+"/helpful", "user-1", "user", ""); w.Code != http.StatusNotFound {
+		t.Errorf("vote on hidden review status = %d, want 404", w.Code)
+	}
+
+	restored, _ := store.ModerateReview(second.ID, "admin-1", models.ModerationRequest{Hidden: false})
+	if restored.Hidden || restored.HiddenReason != "" {
+		t.Errorf("expected review restored, got %+v", restored)
+	}
+}
+
+func TestChapterComments(t *testing.T) {
+	store := setupStore(t)
+	router := newRouter(store)
+
+	for _, body := range []string{`{"body":"That ending!","spoiler":true}`, `{"body":"  "}`, `{"body":"Agreed"}`} {
+		do(t, router, http.MethodPost, "/manga/m1/chapters/3/comments", "user-1", "user", body)
+	}
+	if w := do(t, router, http.MethodPost, "/manga/m1/chapters/abc/comments", "user-1", "user", `{"body":"x"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid chapter status = %d, want 400", w.Code)
+	}
+
+	w := do(t, router, http.MethodGet, "/manga/m1/chapters/3/comments", "", "", "")
+	var res struct {
+		Comments []models.ChapterComment `json:"comments"`
+		Total    int                     `json:"total"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if res.Total != 2 || res.Comments[0].Body != "That ending!" || !res.Comments[0].Spoiler || res.Comments[1].Body != "Agreed" {
+		t.Fatalf("unexpected comments %+v", res)
+	}
+	if w := do(t, router, http.MethodGet, "/manga/m1/chapters/4/comments", "", "", ""); !bytes.Contains(w.Body.Bytes(), []byte(`"total":0`)) {
+		t.Errorf("expected other chapters to be empty, got %s", w.Body.String())
+	}
+
+	commentPath := "/manga/m1/chapters/3/comments/" + res.Comments[0].ID
+	if w := do(t, router, http.MethodPut, "/manga/m1/chapters/4/comments/"+res.Comments[0].ID, "user-1", "user", `{"body":"moved"}`); w.Code != http.StatusNotFound {
+		t.Errorf("edit via wrong chapter status = %d, want 404", w.Code)
+	}
+	if w := do(t, router, http.MethodPut, commentPath, "user-1", "user", `{"body":"That ending!!"}`); w.Code != http.StatusOK {
+		t.Errorf("edit status = %d (%s)", w.Code, w.Body.String())
+	}
+	if w := do(t, router, http.MethodDelete, commentPath, "user-2", "user", ""); w.Code != http.StatusForbidden {
+		t.Errorf("delete by other user status = %d, want 403", w.Code)
+	}
+
+	if _, err := store.ModerateComment(res.Comments[1].ID, "admin-1", models.ModerationRequest{Hidden: true}); err != nil {
+		t.Fatalf("moderate comment: %v", err)
+	}
+	comments, total, _ := store.ListComments("m1", 3, review.ListOptions{Limit: 10})
+	if total != 1 || len(comments) != 1 || comments[0].Body != "That ending!!" || comments[0].EditedAt == nil {
+		t.Errorf("unexpected visible comments %+v", comments)
+	}
+	if _, total, _ := store.ListComments("m1", 3, review.ListOptions{Limit: 10, IncludeHidden: true}); total != 2 {
+		t.Errorf("expected hidden comment for moderators, total %d", total)
+	}
+}
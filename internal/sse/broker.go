@@ -0,0 +1,313 @@
+package sse
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DefaultBufferSize        = 100
+	DefaultKeepAliveInterval = 15 * time.Second
+	clientChannelSize        = 32
+	// globalStream holds events broadcast to every client
+	globalStream = ""
+)
+
+// Config configures a Broker. JWTSecret is used to resolve the user behind
+// the optional token; without it every client is treated as anonymous.
+type Config struct {
+	JWTSecret         string
+	BufferSize        int
+	KeepAliveInterval time.Duration
+}
+
+// Event is the payload written to the stream. ID is also sent, behind the
+// broker's epoch, as the SSE id field so browsers can resume with
+// Last-Event-ID.
+type Event struct {
+	ID        uint64      `json:"id"`
+	Type      string      `json:"type"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data,omitempty"`
+	Timestamp int64       `json:"timestamp"`
+}
+
+type client struct {
+	userID  string
+	channel chan Event
+}
+
+// ringBuffer keeps the most recent events of a single stream
+type ringBuffer struct {
+	events []Event
+	start  int
+	size   int
+}
+
+func newRingBuffer(capacity int) *ringBuffer {
+	return &ringBuffer{events: make([]Event, capacity)}
+}
+
+func (r *ringBuffer) push(event Event) {
+	capacity := len(r.events)
+	if r.size < capacity {
+		r.events[(r.start+r.size)%capacity] = event
+		r.size++
+		return
+	}
+	r.events[r.start] = event
+	r.start = (r.start + 1) % capacity
+}
+
+func (r *ringBuffer) after(id uint64) []Event {
+	var out []Event
+	for i := 0; i < r.size; i++ {
+		event := r.events[(r.start+i)%len(r.events)]
+		if event.ID > id {
+			out = append(out, event)
+		}
+	}
+	return out
+}
+
+// Broker fans out notifications to Server-Sent Events clients and keeps a
+// per-user history so reconnecting clients can catch up.
+// IDs only count up within one process, so each boot gets its own epoch and
+// an ID from another epoch is treated as a client that missed everything.
+type Broker struct {
+	cfg     Config
+	epoch   string
+	clients map[string]*client
+	history map[string]*ringBuffer
+	nextID  uint64
+	mu      sync.RWMutex
+}
+
+func NewBroker(cfg Config) *Broker {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = DefaultBufferSize
+	}
+	if cfg.KeepAliveInterval <= 0 {
+		cfg.KeepAliveInterval = DefaultKeepAliveInterval
+	}
+	epoch, err := utils.GenerateID(8)
+	if err != nil {
+		epoch = strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return &Broker{
+		cfg:     cfg,
+		epoch:   epoch,
+		clients: make(map[string]*client),
+		history: make(map[string]*ringBuffer),
+	}
+}
+
+// ServeSSE handles a Server-Sent Events connection. The token may be passed
+// as a query parameter (EventSource cannot set headers) or a Bearer header.
+func (b *Broker) ServeSSE(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	userID := b.resolveUser(c)
+	lastEventID, resuming := b.parseLastEventID(c)
+
+	connectionID := fmt.Sprintf("%s_%d", c.ClientIP(), time.Now().UnixNano())
+	cl := &client{
+		userID:  userID,
+		channel: make(chan Event, clientChannelSize),
+	}
+
+	// Register before reading the history so nothing published in between is lost
+	b.mu.Lock()
+	b.clients[connectionID] = cl
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		delete(b.clients, connectionID)
+		b.mu.Unlock()
+	}()
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", 3000)
+	initial, _ := json.Marshal(Event{
+		Type:      "connected",
+		Message:   "Connected to MangaHub notifications",
+		Timestamp: time.Now().Unix(),
+	})
+	fmt.Fprintf(c.Writer, "data: %s\n\n", initial)
+
+	sent := lastEventID
+	if resuming {
+		for _, event := range b.Replay(userID, lastEventID) {
+			b.writeEvent(c, event)
+			sent = event.ID
+		}
+	}
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(b.cfg.KeepAliveInterval)
+	defer ticker.Stop()
+
+	notify := c.Request.Context().Done()
+	for {
+		select {
+		case <-notify:
+			return
+		case <-ticker.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			c.Writer.Flush()
+		case event := <-cl.channel:
+			if event.ID <= sent {
+				continue
+			}
+			b.writeEvent(c, event)
+			sent = event.ID
+			c.Writer.Flush()
+		}
+	}
+}
+
+// Broadcast sends a notification to every connected client
+func (b *Broker) Broadcast(eventType, message string, data interface{}) {
+	b.publish(globalStream, eventType, message, data)
+}
+
+// BroadcastToUser sends a notification to a specific user only
+func (b *Broker) BroadcastToUser(userID, eventType, message string, data interface{}) {
+	if userID == "" {
+		return
+	}
+	b.publish(userID, eventType, message, data)
+}
+
+// Replay returns the buffered events visible to userID with an ID greater
+// than lastEventID, oldest first.
+func (b *Broker) Replay(userID string, lastEventID uint64) []Event {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var events []Event
+	if buf, ok := b.history[globalStream]; ok {
+		events = append(events, buf.after(lastEventID)...)
+	}
+	if userID != "" {
+		if buf, ok := b.history[userID]; ok {
+			events = append(events, buf.after(lastEventID)...)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events
+}
+
+// Epoch identifies this broker's run of event IDs
+func (b *Broker) Epoch() string {
+	return b.epoch
+}
+
+// GetClientCount returns the number of connected clients
+func (b *Broker) GetClientCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.clients)
+}
+
+func (b *Broker) publish(stream, eventType, message string, data interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event := Event{
+		ID:        b.nextID,
+		Type:      eventType,
+		Message:   message,
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+	}
+
+	buf, ok := b.history[stream]
+	if !ok {
+		buf = newRingBuffer(b.cfg.BufferSize)
+		b.history[stream] = buf
+	}
+	buf.push(event)
+
+	for _, cl := range b.clients {
+		if stream != globalStream && cl.userID != stream {
+			continue
+		}
+		select {
+		case cl.channel <- event:
+		default:
+			// Client is lagging; it can recover the event with Last-Event-ID
+		}
+	}
+}
+
+func (b *Broker) resolveUser(c *gin.Context) string {
+	if b.cfg.JWTSecret == "" {
+		return ""
+	}
+
+	token := c.Query("token")
+	if token == "" {
+		token = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
+	if token == "" {
+		return ""
+	}
+
+	claims, err := utils.ValidateJWT(token, b.cfg.JWTSecret)
+	if err != nil {
+		return ""
+	}
+	return claims.UserID
+}
+
+// parseLastEventID reads "<epoch>-<id>" and reports whether the client is
+// resuming. An ID from another epoch, or one past the newest event, was
+// handed out before a restart: the client resumes from 0 and gets the whole
+// buffer rather than skipping everything up to a number this boot reuses.
+func (b *Broker) parseLastEventID(c *gin.Context) (uint64, bool) {
+	raw := strings.TrimSpace(c.GetHeader("Last-Event-ID"))
+	if raw == "" {
+		raw = strings.TrimSpace(c.Query("last_event_id"))
+	}
+	if raw == "" {
+		return 0, false
+	}
+
+	epoch, rawID, ok := strings.Cut(raw, "-")
+	if !ok || epoch != b.epoch {
+		return 0, true
+	}
+	id, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil {
+		return 0, true
+	}
+
+	b.mu.RLock()
+	newest := b.nextID
+	b.mu.RUnlock()
+	if id > newest {
+		return 0, true
+	}
+	return id, true
+}
+
+func (b *Broker) writeEvent(c *gin.Context, event Event) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Writer, "id: %s-%d\ndata: %s\n\n", b.epoch, event.ID, payload)
+}
@@ -0,0 +1,219 @@
+package sse_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/sse"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const testSecret = "sse-test-secret"
+
+type streamEvent struct {
+	ID   string
+	Data sse.Event
+}
+
+func startBroker(t *testing.T, cfg sse.Config) (*sse.Broker, *httptest.Server) {
+	gin.SetMode(gin.TestMode)
+	broker := sse.NewBroker(cfg)
+	router := gin.New()
+	router.GET("/events", broker.ServeSSE)
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+	return broker, ts
+}
+
+func openStream(t *testing.T, url string, headers map[string]string) (*bufio.Reader, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		cancel()
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return bufio.NewReader(resp.Body), cancel
+}
+
+// readEvent returns the next event carrying data, or ok=false on a keep-alive comment
+func readEvent(t *testing.T, r *bufio.Reader) (streamEvent, bool) {
+	var ev streamEvent
+	comment := false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream read failed: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if comment {
+				return ev, false
+			}
+			if ev.Data.Type != "" {
+				return ev, true
+			}
+		case strings.HasPrefix(line, ":"):
+			comment = true
+		case strings.HasPrefix(line, "id: "):
+			ev.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev.Data)
+		}
+	}
+}
+
+func waitForClients(t *testing.T, broker *sse.Broker, n int) {
+	deadline := time.Now().Add(2 * time.Second)
+	for broker.GetClientCount() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d clients, got %d", n, broker.GetClientCount())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBrokerAssignsEventIDs(t *testing.T) {
+	broker, ts := startBroker(t, sse.Config{JWTSecret: testSecret})
+	stream, cancel := openStream(t, ts.URL+"/events", nil)
+	defer cancel()
+
+	if ev, _ := readEvent(t, stream); ev.Data.Type != "connected" {
+		t.Fatalf("expected connected event, got %q", ev.Data.Type)
+	}
+	waitForClients(t, broker, 1)
+
+	broker.Broadcast("manga_created", "New manga added: One Piece", nil)
+	broker.Broadcast("chapter_release", "1 new chapter(s) for One Piece", nil)
+
+	first, _ := readEvent(t, stream)
+	second, _ := readEvent(t, stream)
+	if first.ID != broker.Epoch()+"-1" || second.ID != broker.Epoch()+"-2" {
+		t.Errorf("expected ids 1 and 2 in epoch %s, got %q and %q", broker.Epoch(), first.ID, second.ID)
+	}
+	if first.Data.Type != "manga_created" || second.Data.ID != 2 {
+		t.Errorf("unexpected events: %+v %+v", first.Data, second.Data)
+	}
+}
+
+func TestBrokerDeliversUserEventsOnlyToThatUser(t *testing.T) {
+	broker, ts := startBroker(t, sse.Config{JWTSecret: testSecret})
+	token, _ := utils.GenerateJWT("user-1", "reader", "user", testSecret)
+
+	userStream, cancelUser := openStream(t, ts.URL+"/events?token="+token, nil)
+	defer cancelUser()
+	anonStream, cancelAnon := openStream(t, ts.URL+"/events", nil)
+	defer cancelAnon()
+	readEvent(t, userStream)
+	readEvent(t, anonStream)
+	waitForClients(t, broker, 2)
+
+	broker.BroadcastToUser("user-1", "progress_update", "Progress updated: Chapter 5", nil)
+	broker.Broadcast("manga_created", "New manga added", nil)
+
+	if ev, _ := readEvent(t, userStream); ev.Data.Type != "progress_update" {
+		t.Errorf("expected user to receive progress_update, got %q", ev.Data.Type)
+	}
+	if ev, _ := readEvent(t, anonStream); ev.Data.Type != "manga_created" {
+		t.Errorf("expected anonymous client to skip user event, got %q", ev.Data.Type)
+	}
+}
+
+func TestBrokerReplaysAfterLastEventID(t *testing.T) {
+	broker, ts := startBroker(t, sse.Config{JWTSecret: testSecret})
+	token, _ := utils.GenerateJWT("user-1", "reader", "user", testSecret)
+
+	broker.Broadcast("manga_created", "first", nil)
+	broker.BroadcastToUser("user-1", "library_update", "second", nil)
+	broker.BroadcastToUser("user-2", "library_update", "other user", nil)
+	broker.Broadcast("chapter_release", "third", nil)
+
+	stream, cancel := openStream(t, ts.URL+"/events?token="+token, map[string]string{"Last-Event-ID": broker.Epoch() + "-1"})
+	defer cancel()
+	readEvent(t, stream)
+
+	second, _ := readEvent(t, stream)
+	third, _ := readEvent(t, stream)
+	if second.Data.Message != "second" || third.Data.Message != "third" {
+		t.Errorf("unexpected replay: %q, %q", second.Data.Message, third.Data.Message)
+	}
+	if third.ID != broker.Epoch()+"-4" {
+		t.Errorf("expected replayed event to keep id 4, got %q", third.ID)
+	}
+}
+
+// A client resuming across a restart holds an ID the new broker also hands
+// out; it must get the events it missed, not skip them.
+func TestBrokerResumesAcrossRestart(t *testing.T) {
+	before := sse.NewBroker(sse.Config{})
+	for i := 0; i < 5; i++ {
+		before.Broadcast("manga_created", "before restart", nil)
+	}
+	staleID := before.Epoch() + "-5"
+
+	tests := []struct {
+		name   string
+		lastID func(*sse.Broker) string
+	}{
+		{"other epoch", func(*sse.Broker) string { return staleID }},
+		{"bare number", func(*sse.Broker) string { return "5" }},
+		{"past newest", func(b *sse.Broker) string { return b.Epoch() + "-99" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker, ts := startBroker(t, sse.Config{JWTSecret: testSecret})
+			broker.Broadcast("manga_created", "missed", nil)
+
+			stream, cancel := openStream(t, ts.URL+"/events", map[string]string{"Last-Event-ID": tt.lastID(broker)})
+			defer cancel()
+			readEvent(t, stream)
+
+			if ev, _ := readEvent(t, stream); ev.Data.Message != "missed" {
+				t.Fatalf("expected the buffered event to be replayed, got %q", ev.Data.Message)
+			}
+			waitForClients(t, broker, 1)
+			broker.Broadcast("chapter_release", "live", nil)
+			if ev, _ := readEvent(t, stream); ev.Data.Message != "live" || ev.ID != broker.Epoch()+"-2" {
+				t.Errorf("expected live event 2, got %q (%s)", ev.Data.Message, ev.ID)
+			}
+		})
+	}
+}
+
+func TestBrokerRingBufferDropsOldest(t *testing.T) {
+	broker := sse.NewBroker(sse.Config{BufferSize: 3})
+	for i := 0; i < 5; i++ {
+		broker.Broadcast("manga_created", "event", nil)
+	}
+
+	events := broker.Replay("", 0)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 buffered events, got %d", len(events))
+	}
+	if events[0].ID != 3 || events[2].ID != 5 {
+		t.Errorf("expected ids 3..5, got %d..%d", events[0].ID, events[2].ID)
+	}
+}
+
+func TestBrokerSendsKeepAlive(t *testing.T) {
+	_, ts := startBroker(t, sse.Config{KeepAliveInterval: 50 * time.Millisecond})
+	stream, cancel := openStream(t, ts.URL+"/events", nil)
+	defer cancel()
+	readEvent(t, stream)
+
+	if _, ok := readEvent(t, stream); ok {
+		t.Error("expected a keep-alive comment")
+	}
+}
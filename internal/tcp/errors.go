@@ -28,6 +28,7 @@ const (
 	ErrProtocolUnknownType     ErrorCode = "PROTO-002"
 	ErrProtocolInvalidPayload  ErrorCode = "PROTO-003"
 	ErrProtocolMessageTooLarge ErrorCode = "PROTO-004"
+	ErrProtocolRateLimited     ErrorCode = "PROTO-005"
 
 	ErrAuthTokenMissing     ErrorCode = "AUTH-001"
 	ErrAuthTokenInvalid     ErrorCode = "AUTH-002"
@@ -125,6 +126,11 @@ func NewProtocolInvalidPayloadError(details string) *TCPError {
 		fmt.Sprintf("Invalid payload: %s", details), nil)
 }
 
+func NewProtocolRateLimitedError(reason, penalty string) *TCPError {
+	return NewTCPError(ProtocolError, ErrProtocolRateLimited,
+		fmt.Sprintf("Rate limit exceeded: %s (%s)", reason, penalty), nil)
+}
+
 func NewAuthTokenMissingError() *TCPError {
 	return NewTCPError(AuthenticationError, ErrAuthTokenMissing, "Token is required", nil)
 }
@@ -2,18 +2,23 @@ package tcp
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
+	"net"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
-	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/ratelimit"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/metrics"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
 )
 
-func HandleConnection(client *Client, manager *ClientManager, removeClient func(string), br *bridge.Bridge, sessionMgr *SessionManager, heartbeatMgr *HeartbeatManager) {
+func HandleConnection(client *Client, manager *ClientManager, removeClient func(string), br *bridge.Bridge, sessionMgr *SessionManager, heartbeatMgr *HeartbeatManager, limiter *ratelimit.Limiter) {
 	log := logger.WithFields(map[string]interface{}{
 		"client_id": client.ID,
 		"component": "tcp_handler",
@@ -30,6 +35,7 @@ func HandleConnection(client *Client, manager *ClientManager, removeClient func(
 	}()
 
 	log.Info("client_connected")
+	ip, _, _ := net.SplitHostPort(client.Conn.RemoteAddr().String())
 	reader := bufio.NewReader(client.Conn)
 	for {
 		line, err := reader.ReadString('\n')
@@ -52,6 +58,22 @@ func HandleConnection(client *Client, manager *ClientManager, removeClient func(
 			continue
 		}
 
+		// Keep-alives are exempt so a busy client isn't timed out
+		if limiter != nil && !isKeepAlive(msg.Type) {
+			d := limiter.Check(ratelimit.Message{Scope: "tcp", UserID: client.UserID, IP: ip})
+			if !d.Allowed {
+				log.Warn("message_rate_limited",
+					"user_id", client.UserID,
+					"reason", d.Reason,
+					"penalty", d.Penalty.String())
+				SendError(client, NewProtocolRateLimitedError(d.Reason, d.Penalty.String()))
+				if d.Penalty == ratelimit.Disconnect {
+					return
+				}
+				continue
+			}
+		}
+
 		// Increment messages received counter
 		if session, ok := sessionMgr.GetSessionByClientID(client.ID); ok {
 			sessionMgr.IncrementMessagesReceived(session.SessionID)
@@ -67,11 +89,24 @@ func HandleConnection(client *Client, manager *ClientManager, removeClient func(
 		if session, ok := sessionMgr.GetSessionByClientID(client.ID); ok {
 			sessionMgr.IncrementMessagesSent(session.SessionID)
 		}
+
+		// Pings and heartbeats only keep the connection open
+		if client.UserID != "" && br != nil && !isKeepAlive(msg.Type) {
+			br.MarkActive(client.UserID)
+		}
 	}
 }
 
+func isKeepAlive(msgType string) bool {
+	return msgType == "ping" || msgType == "heartbeat" || msgType == "status_request"
+}
+
 func routeMessage(client *Client, msg *Message, log *logger.Logger, br *bridge.Bridge, sessionMgr *SessionManager, heartbeatMgr *HeartbeatManager) error {
-	log = log.WithContext("message_type", msg.Type)
+	if msg.RequestID == "" {
+		msg.RequestID = logger.NewRequestID()
+	}
+	log = log.WithContext("message_type", msg.Type).WithRequestID(msg.RequestID)
+	metrics.RecordProtocolMessage("tcp", msg.Type)
 
 	switch msg.Type {
 	case "ping":
@@ -90,16 +125,18 @@ func routeMessage(client *Client, msg *Message, log *logger.Logger, br *bridge.B
 		return handleSubscribeUpdates(client, msg.Payload, log, sessionMgr)
 	case "unsubscribe_updates":
 		return handleUnsubscribeUpdates(client, log, sessionMgr)
+	case "resume":
+		return handleResume(client, msg.Payload, log, br)
 	case "sync_progress":
-		return handleSyncProgress(client, msg.Payload, log, br, sessionMgr)
+		return handleSyncProgress(client, msg.Payload, msg.RequestID, log, br, sessionMgr)
 	case "get_library":
 		return handleGetLibrary(client, msg.Payload, log)
 	case "get_progress":
 		return handleGetProgress(client, msg.Payload, log)
 	case "add_to_library":
-		return handleAddToLibrary(client, msg.Payload, log, br)
+		return handleAddToLibrary(client, msg.Payload, msg.RequestID, log, br)
 	case "remove_from_library":
-		return handleRemoveFromLibrary(client, msg.Payload, log, br)
+		return handleRemoveFromLibrary(client, msg.Payload, msg.RequestID, log, br)
 	default:
 		err := NewProtocolUnknownTypeError(msg.Type)
 		SendError(client, err)
@@ -158,7 +195,59 @@ func handleAuth(client *Client, payload json.RawMessage, log *logger.Logger, br
 	return nil
 }
 
-func handleSyncProgress(client *Client, payload json.RawMessage, log *logger.Logger, br *bridge.Bridge, sessionMgr *SessionManager) error {
+func handleResume(client *Client, payload json.RawMessage, log *logger.Logger, br *bridge.Bridge) error {
+	if !client.Authenticated {
+		authErr := NewAuthNotAuthenticatedError()
+		SendError(client, authErr)
+		return authErr
+	}
+
+	var req ResumePayload
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &req); err != nil {
+			protoErr := NewProtocolInvalidPayloadError("Invalid resume payload")
+			SendError(client, protoErr)
+			return protoErr
+		}
+	}
+
+	if req.AfterOffset < 0 {
+		protoErr := NewProtocolInvalidPayloadError("after_offset must not be negative")
+		SendError(client, protoErr)
+		return protoErr
+	}
+
+	if br == nil {
+		bizErr := NewTCPError(BusinessLogicError, "BIZ-999", "Event replay is not available", nil)
+		SendError(client, bizErr)
+		return bizErr
+	}
+
+	events, err := br.ReplayEvents(client.UserID, req.AfterOffset, req.Limit)
+	if err != nil {
+		dbErr := NewDatabaseQueryError(err)
+		log.Error("event_replay_failed", "user_id", client.UserID, "error", err.Error())
+		SendError(client, dbErr)
+		return dbErr
+	}
+
+	lastOffset := req.AfterOffset
+	if len(events) > 0 {
+		lastOffset = events[len(events)-1].Offset
+	}
+
+	log.Info("events_replayed",
+		"user_id", client.UserID,
+		"after_offset", req.AfterOffset,
+		"count", len(events))
+	client.Conn.Write(CreateDataMessage("resume", ResumeResponsePayload{
+		Events:     events,
+		LastOffset: lastOffset,
+	}))
+	return nil
+}
+
+func handleSyncProgress(client *Client, payload json.RawMessage, requestID string, log *logger.Logger, br *bridge.Bridge, sessionMgr *SessionManager) error {
 	if !client.Authenticated {
 		authErr := NewAuthNotAuthenticatedError()
 		SendError(client, authErr)
@@ -186,56 +275,24 @@ func handleSyncProgress(client *Client, payload json.RawMessage, log *logger.Log
 		return bizErr
 	}
 
-	validStatuses := map[string]bool{
-		"reading":      true,
-		"completed":    true,
-		"plan_to_read": true,
-	}
-	if syncPayload.Status != "" && !validStatuses[syncPayload.Status] {
-		bizErr := NewBizInvalidStatusError(syncPayload.Status)
-		SendError(client, bizErr)
-		return bizErr
-	}
-
-	var exists bool
-	var mangaTitle string
-	checkQuery := `SELECT EXISTS(SELECT 1 FROM manga WHERE id = ?), COALESCE((SELECT title FROM manga WHERE id = ?), '')`
-	err := database.DB.QueryRow(checkQuery, syncPayload.MangaID, syncPayload.MangaID).Scan(&exists, &mangaTitle)
-	if err != nil {
-		dbErr := NewDatabaseQueryError(err)
-		log.Error("database_error_checking_manga", "error", err.Error(), "manga_id", syncPayload.MangaID)
-		SendError(client, dbErr)
-		return dbErr
-	}
-	if !exists {
-		bizErr := NewBizMangaNotFoundError(syncPayload.MangaID)
-		SendError(client, bizErr)
-		return bizErr
-	}
-
-	now := time.Now()
-	query := `INSERT INTO user_progress (user_id, manga_id, current_chapter, status, updated_at)
-              VALUES (?, ?, ?, ?, ?)
-              ON CONFLICT(user_id, manga_id) DO UPDATE SET 
-              current_chapter = ?, 
-              status = COALESCE(?, status),
-              updated_at = ?`
-
-	status := syncPayload.Status
-	if status == "" {
-		status = "reading"
-	}
-
-	_, err = database.DB.Exec(query,
-		client.UserID, syncPayload.MangaID, syncPayload.CurrentChapter, status, now,
-		syncPayload.CurrentChapter, syncPayload.Status, now)
-
+	progress, err := repository.Default().Progress.Sync(context.Background(), client.UserID, syncPayload.MangaID, syncPayload.CurrentChapter, syncPayload.Status)
 	if err != nil {
-		dbErr := NewDatabaseQueryError(err)
-		log.Error("database_error_syncing_progress", "error", err.Error())
-		SendError(client, dbErr)
-		return dbErr
+		var tcpErr *TCPError
+		switch {
+		case errors.Is(err, repository.ErrInvalidStatus):
+			tcpErr = NewBizInvalidStatusError(syncPayload.Status)
+		case errors.Is(err, repository.ErrMangaNotFound):
+			tcpErr = NewBizMangaNotFoundError(syncPayload.MangaID)
+		default:
+			tcpErr = NewDatabaseQueryError(err)
+			log.Error("database_error_syncing_progress", "error", err.Error(), "manga_id", syncPayload.MangaID)
+		}
+		SendError(client, tcpErr)
+		return tcpErr
 	}
+	status := progress.Status
+	mangaTitle := progress.Manga.Title
+	now := progress.UpdatedAt
 
 	log.Info("progress_synced",
 		"manga_id", syncPayload.MangaID,
@@ -254,6 +311,7 @@ func handleSyncProgress(client *Client, payload json.RawMessage, log *logger.Log
 			ChapterID:    syncPayload.CurrentChapter,
 			Status:       status,
 			LastReadDate: now,
+			RequestID:    requestID,
 		})
 	}
 
@@ -273,23 +331,13 @@ func handleGetLibrary(client *Client, payload json.RawMessage, log *logger.Logge
 		"username": client.Username,
 	})
 
-	query := `
-        SELECT m.id, m.title, m.author, m.genres, m.status, m.total_chapters, m.description, m.cover_url,
-               up.current_chapter, up.status, up.updated_at
-        FROM user_progress up
-        JOIN manga m ON up.manga_id = m.id
-        WHERE up.user_id = ?
-        ORDER BY up.updated_at DESC
-    `
-
-	rows, err := database.DB.Query(query, client.UserID)
+	entries, err := repository.Default().Progress.Library(context.Background(), client.UserID)
 	if err != nil {
 		dbErr := NewDatabaseQueryError(err)
 		log.Error("database_error_fetching_library", "error", err.Error())
 		SendError(client, dbErr)
 		return dbErr
 	}
-	defer rows.Close()
 
 	type MangaProgress struct {
 		MangaID        string `json:"manga_id"`
@@ -305,44 +353,25 @@ func handleGetLibrary(client *Client, payload json.RawMessage, log *logger.Logge
 		UpdatedAt      string `json:"updated_at"`
 	}
 
-	library := []MangaProgress{}
-	rowCount := 0
-	for rows.Next() {
-		rowCount++
-		var mp MangaProgress
-		var genres, description, coverURL *string
-		err := rows.Scan(
-			&mp.MangaID,
-			&mp.Title,
-			&mp.Author,
-			&genres,
-			&mp.Status,
-			&mp.TotalChapters,
-			&description,
-			&coverURL,
-			&mp.CurrentChapter,
-			&mp.ReadStatus,
-			&mp.UpdatedAt,
-		)
-		if err != nil {
-			log.Warn("error_scanning_library_row", "error", err.Error())
-			continue
-		}
-		if genres != nil {
-			mp.Genres = *genres
-		}
-		if description != nil {
-			mp.Description = *description
-		}
-		if coverURL != nil {
-			mp.CoverURL = *coverURL
-		}
-		library = append(library, mp)
+	library := make([]MangaProgress, 0, len(entries))
+	for _, e := range entries {
+		genres, _ := json.Marshal(e.Manga.Genres)
+		library = append(library, MangaProgress{
+			MangaID:        e.Manga.ID,
+			Title:          e.Manga.Title,
+			Author:         e.Manga.Author,
+			Genres:         string(genres),
+			Status:         e.Manga.Status,
+			TotalChapters:  e.Manga.TotalChapters,
+			Description:    e.Manga.Description,
+			CoverURL:       e.Manga.CoverURL,
+			CurrentChapter: e.CurrentChapter,
+			ReadStatus:     e.Status,
+			UpdatedAt:      e.UpdatedAt.Format(time.RFC3339Nano),
+		})
 	}
 
-	log.Info("library_fetched",
-		"item_count", len(library),
-		"rows_scanned", rowCount)
+	log.Info("library_fetched", "item_count", len(library))
 	client.Conn.Write(CreateDataMessage("library", library))
 	return nil
 }
@@ -372,14 +401,7 @@ func handleGetProgress(client *Client, payload json.RawMessage, log *logger.Logg
 		return bizErr
 	}
 
-	var progress struct {
-		CurrentChapter int    `json:"current_chapter"`
-		Status         string `json:"status"`
-		UpdatedAt      string `json:"updated_at"`
-	}
-
-	query := `SELECT current_chapter, status, updated_at FROM user_progress WHERE user_id = ? AND manga_id = ?`
-	err := database.DB.QueryRow(query, client.UserID, req.MangaID).Scan(&progress.CurrentChapter, &progress.Status, &progress.UpdatedAt)
+	progress, err := repository.Default().Progress.Get(context.Background(), client.UserID, req.MangaID)
 	if err != nil {
 		dbErr := NewDatabaseNotFoundError()
 		log.Info("progress_not_found", "manga_id", req.MangaID)
@@ -388,11 +410,15 @@ func handleGetProgress(client *Client, payload json.RawMessage, log *logger.Logg
 	}
 
 	log.Debug("progress_retrieved", "manga_id", req.MangaID)
-	client.Conn.Write(CreateDataMessage("progress", progress))
+	client.Conn.Write(CreateDataMessage("progress", map[string]interface{}{
+		"current_chapter": progress.CurrentChapter,
+		"status":          progress.Status,
+		"updated_at":      progress.UpdatedAt.Format(time.RFC3339Nano),
+	}))
 	return nil
 }
 
-func handleAddToLibrary(client *Client, payload json.RawMessage, log *logger.Logger, br *bridge.Bridge) error {
+func handleAddToLibrary(client *Client, payload json.RawMessage, requestID string, log *logger.Logger, br *bridge.Bridge) error {
 	if !client.Authenticated {
 		authErr := NewAuthNotAuthenticatedError()
 		SendError(client, authErr)
@@ -417,50 +443,31 @@ func handleAddToLibrary(client *Client, payload json.RawMessage, log *logger.Log
 		return bizErr
 	}
 
-	validStatuses := map[string]bool{
-		"reading":      true,
-		"completed":    true,
-		"plan_to_read": true,
-	}
-	status := req.Status
-	if status == "" {
-		status = "plan_to_read"
-	}
-	if !validStatuses[status] {
-		bizErr := NewBizInvalidStatusError(status)
-		SendError(client, bizErr)
-		return bizErr
-	}
-
-	var exists bool
-	checkQuery := `SELECT EXISTS(SELECT 1 FROM manga WHERE id = ?)`
-	err := database.DB.QueryRow(checkQuery, req.MangaID).Scan(&exists)
-	if err != nil || !exists {
-		bizErr := NewBizMangaNotFoundError(req.MangaID)
-		SendError(client, bizErr)
-		return bizErr
-	}
-
-	now := time.Now()
-	query := `INSERT INTO user_progress (user_id, manga_id, current_chapter, status, updated_at)
-              VALUES (?, ?, 0, ?, ?)
-              ON CONFLICT(user_id, manga_id) DO UPDATE SET status = ?, updated_at = ?`
-
-	_, err = database.DB.Exec(query, client.UserID, req.MangaID, status, now, status, now)
+	progress, err := repository.Default().Progress.AddToLibrary(context.Background(), client.UserID, req.MangaID, req.Status)
 	if err != nil {
-		dbErr := NewDatabaseQueryError(err)
-		log.Error("database_error_adding_to_library", "error", err.Error(), "manga_id", req.MangaID)
-		SendError(client, dbErr)
-		return dbErr
+		var tcpErr *TCPError
+		switch {
+		case errors.Is(err, repository.ErrInvalidStatus):
+			tcpErr = NewBizInvalidStatusError(req.Status)
+		case errors.Is(err, repository.ErrMangaNotFound):
+			tcpErr = NewBizMangaNotFoundError(req.MangaID)
+		default:
+			tcpErr = NewDatabaseQueryError(err)
+			log.Error("database_error_adding_to_library", "error", err.Error(), "manga_id", req.MangaID)
+		}
+		SendError(client, tcpErr)
+		return tcpErr
 	}
+	status := progress.Status
 
 	log.Info("manga_added_to_library", "manga_id", req.MangaID, "status", status)
 
 	if br != nil {
 		br.NotifyLibraryUpdate(bridge.LibraryUpdateEvent{
-			UserID:  client.UserID,
-			MangaID: req.MangaID,
-			Action:  "added",
+			UserID:    client.UserID,
+			MangaID:   req.MangaID,
+			Action:    "added",
+			RequestID: requestID,
 		})
 	}
 
@@ -468,7 +475,7 @@ func handleAddToLibrary(client *Client, payload json.RawMessage, log *logger.Log
 	return nil
 }
 
-func handleRemoveFromLibrary(client *Client, payload json.RawMessage, log *logger.Logger, br *bridge.Bridge) error {
+func handleRemoveFromLibrary(client *Client, payload json.RawMessage, requestID string, log *logger.Logger, br *bridge.Bridge) error {
 	if !client.Authenticated {
 		authErr := NewAuthNotAuthenticatedError()
 		SendError(client, authErr)
@@ -493,29 +500,26 @@ func handleRemoveFromLibrary(client *Client, payload json.RawMessage, log *logge
 		return bizErr
 	}
 
-	query := `DELETE FROM user_progress WHERE user_id = ? AND manga_id = ?`
-	result, err := database.DB.Exec(query, client.UserID, req.MangaID)
-	if err != nil {
+	if err := repository.Default().Progress.Remove(context.Background(), client.UserID, req.MangaID); err != nil {
+		if errors.Is(err, repository.ErrNotInLibrary) {
+			bizErr := NewBizNotInLibraryError(req.MangaID)
+			SendError(client, bizErr)
+			return bizErr
+		}
 		dbErr := NewDatabaseQueryError(err)
 		log.Error("database_error_removing_from_library", "error", err.Error(), "manga_id", req.MangaID)
 		SendError(client, dbErr)
 		return dbErr
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		bizErr := NewBizNotInLibraryError(req.MangaID)
-		SendError(client, bizErr)
-		return bizErr
-	}
-
 	log.Info("manga_removed_from_library", "manga_id", req.MangaID)
 
 	if br != nil {
 		br.NotifyLibraryUpdate(bridge.LibraryUpdateEvent{
-			UserID:  client.UserID,
-			MangaID: req.MangaID,
-			Action:  "removed",
+			UserID:    client.UserID,
+			MangaID:   req.MangaID,
+			Action:    "removed",
+			RequestID: requestID,
 		})
 	}
 
@@ -649,22 +653,12 @@ func handleStatusRequest(client *Client, log *logger.Logger, sessionMgr *Session
 
 	// Fallback or override lastSyncInfo from database to reflect most recent user activity
 	// This ensures Last sync is meaningful even if the current session hasn't synced yet.
-	var dbMangaID string
-	var dbMangaTitle string
-	var dbChapter int
-	var dbUpdatedAt string
-	query := `SELECT up.manga_id, COALESCE(m.title, ''), up.current_chapter, up.updated_at
-			  FROM user_progress up
-			  LEFT JOIN manga m ON m.id = up.manga_id
-			  WHERE up.user_id = ?
-			  ORDER BY up.updated_at DESC
-			  LIMIT 1`
-	if err := database.DB.QueryRow(query, client.UserID).Scan(&dbMangaID, &dbMangaTitle, &dbChapter, &dbUpdatedAt); err == nil && dbMangaID != "" {
+	if latest, err := repository.Default().Progress.Latest(context.Background(), client.UserID); err == nil {
 		lastSyncInfo = &LastSyncInfo{
-			MangaID:    dbMangaID,
-			MangaTitle: dbMangaTitle,
-			Chapter:    dbChapter,
-			Timestamp:  dbUpdatedAt,
+			MangaID:    latest.Manga.ID,
+			MangaTitle: latest.Manga.Title,
+			Chapter:    latest.CurrentChapter,
+			Timestamp:  latest.UpdatedAt.Format(time.RFC3339),
 		}
 	}
 
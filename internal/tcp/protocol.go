@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
 )
 
 type Message struct {
-	Type    string          `json:"type"`
-	Payload json.RawMessage `json:"payload"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	RequestID string          `json:"request_id,omitempty"`
 }
 
 type AuthPayload struct {
@@ -93,6 +96,18 @@ type SubscribeUpdatesPayload struct {
 type UnsubscribeUpdatesPayload struct {
 }
 
+// ResumePayload asks the server to replay logged events after an offset
+type ResumePayload struct {
+	AfterOffset int64 `json:"after_offset"`
+	Limit       int   `json:"limit,omitempty"`
+}
+
+// ResumeResponsePayload carries the replayed events and the offset to resume from next time
+type ResumeResponsePayload struct {
+	Events     []bridge.UnifiedEvent `json:"events"`
+	LastOffset int64                 `json:"last_offset"`
+}
+
 // UpdateEventPayload is sent for real-time sync updates
 type UpdateEventPayload struct {
 	Timestamp   string `json:"timestamp"`   // ISO timestamp
@@ -103,6 +118,7 @@ type UpdateEventPayload struct {
 	Chapter     int    `json:"chapter"`
 	Action      string `json:"action"`                 // "updated", "added", "removed"
 	ConflictMsg string `json:"conflict_msg,omitempty"` // If there was a conflict
+	Offset      int64  `json:"offset,omitempty"`       // Event log position to pass to resume
 }
 
 func ParseMessage(data []byte) (*Message, error) {
@@ -5,6 +5,7 @@ import (
 	"sync/atomic"
 
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/ratelimit"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
 )
 
@@ -17,6 +18,7 @@ type Server struct {
 	bridge           *bridge.Bridge
 	sessionManager   *SessionManager
 	heartbeatManager *HeartbeatManager
+	limiter          *ratelimit.Limiter
 }
 
 func NewServer(port string, br *bridge.Bridge) *Server {
@@ -34,6 +36,11 @@ func NewServer(port string, br *bridge.Bridge) *Server {
 	}
 }
 
+// SetLimiter limits how fast clients may send messages; call it before Start
+func (s *Server) SetLimiter(l *ratelimit.Limiter) {
+	s.limiter = l
+}
+
 func (s *Server) Start() error {
 	var err error
 	s.listener, err = net.Listen("tcp", ":"+s.Port)
@@ -63,7 +70,7 @@ func (s *Server) acceptConnections() {
 		client := &Client{Conn: conn, ID: clientID}
 		s.clientManager.Add(client)
 		s.log.Debug("new_client_accepted", "client_id", clientID)
-		go HandleConnection(client, s.clientManager, s.removeClient, s.bridge, s.sessionManager, s.heartbeatManager)
+		go HandleConnection(client, s.clientManager, s.removeClient, s.bridge, s.sessionManager, s.heartbeatManager, s.limiter)
 	}
 }
 
@@ -37,7 +37,7 @@ func (b *Broadcaster) BroadcastToUser(userID string, event bridge.BroadcastEvent
 		return
 	}
 
-	messageBytes := CreateNotificationMessage(userID, event.EventType, event.Data)
+	messageBytes := CreateEventNotificationMessage(userID, event.EventType, event.Offset, event.Data)
 
 	successCount := 0
 	failCount := 0
@@ -73,7 +73,7 @@ func (b *Broadcaster) BroadcastUnifiedEvent(userID string, event bridge.UnifiedE
 		return
 	}
 
-	messageBytes := CreateNotificationMessage(userID, string(event.Type), event.Data)
+	messageBytes := CreateEventNotificationMessage(userID, string(event.Type), event.Offset, event.Data)
 
 	successCount := 0
 	failCount := 0
@@ -110,7 +110,7 @@ func (b *Broadcaster) BroadcastToAll(event bridge.BroadcastEvent) {
 	}
 
 	// Build message once
-	messageBytes := CreateNotificationMessage("", event.EventType, event.Data)
+	messageBytes := CreateEventNotificationMessage("", event.EventType, event.Offset, event.Data)
 
 	successCount := 0
 	failCount := 0
@@ -18,6 +18,7 @@ const (
 	ErrUDPInvalidEventType   ErrorCode = "UDP-008"
 	ErrUDPWriteFailed        ErrorCode = "UDP-009"
 	ErrUDPReadFailed         ErrorCode = "UDP-010"
+	ErrUDPRateLimited        ErrorCode = "UDP-011"
 )
 
 type UDPError struct {
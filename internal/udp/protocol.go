@@ -1,7 +1,11 @@
 package udp
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"time"
 )
 
@@ -11,8 +15,21 @@ type Message struct {
 	UserID    string          `json:"user_id,omitempty"`
 	Data      json.RawMessage `json:"data,omitempty"`
 	Timestamp string          `json:"timestamp"`
+	RequestID string          `json:"request_id,omitempty"`
+	Offset    int64           `json:"offset,omitempty"` // event log position of a notification
+	Signature string          `json:"signature,omitempty"`
 }
 
+// NotificationMaxAge is how far a signed notification's timestamp may be
+// from the receiver's clock before it is treated as a replay
+const NotificationMaxAge = 30 * time.Second
+
+var (
+	ErrNotificationUnsigned  = errors.New("notification is not signed")
+	ErrNotificationSignature = errors.New("notification signature does not match")
+	ErrNotificationStale     = errors.New("notification timestamp is outside the accepted window")
+)
+
 type RegisterPayload struct {
 	Token string `json:"token"`
 }
@@ -88,16 +105,74 @@ func CreateHeartbeatMessage(clientID string) []byte {
 }
 
 func CreateNotificationMessage(userID, eventType string, data interface{}) []byte {
+	return CreateEventNotificationMessage(userID, eventType, 0, data)
+}
+
+// CreateEventNotificationMessage is a notification for a logged event,
+// carrying its offset so clients can ask for what they missed
+func CreateEventNotificationMessage(userID, eventType string, offset int64, data interface{}) []byte {
+	msg := Message{
+		Type:      "notification",
+		EventType: eventType,
+		UserID:    userID,
+		Data:      mustMarshal(data),
+		Timestamp: time.Now().Format(time.RFC3339),
+		Offset:    offset,
+	}
+	return mustMarshal(msg)
+}
+
+// CreateSignedNotificationMessage is a notification for the UDP server to
+// broadcast on the sender's behalf, signed with the secret the two share
+func CreateSignedNotificationMessage(secret, userID, eventType, requestID string, data interface{}) []byte {
 	msg := Message{
 		Type:      "notification",
 		EventType: eventType,
 		UserID:    userID,
 		Data:      mustMarshal(data),
 		Timestamp: time.Now().Format(time.RFC3339),
+		RequestID: requestID,
 	}
+	msg.Signature = signNotification(secret, &msg)
 	return mustMarshal(msg)
 }
 
+// VerifyNotification checks a notification's signature and that its
+// timestamp is within NotificationMaxAge of now
+func VerifyNotification(secret string, msg *Message, now time.Time) error {
+	if msg.Signature == "" {
+		return ErrNotificationUnsigned
+	}
+	if secret == "" {
+		// Anyone can sign with an empty key
+		return ErrNotificationSignature
+	}
+	want := signNotification(secret, msg)
+	if !hmac.Equal([]byte(msg.Signature), []byte(want)) {
+		return ErrNotificationSignature
+	}
+	sent, err := time.Parse(time.RFC3339, msg.Timestamp)
+	if err != nil {
+		return ErrNotificationStale
+	}
+	if age := now.Sub(sent); age > NotificationMaxAge || age < -NotificationMaxAge {
+		return ErrNotificationStale
+	}
+	return nil
+}
+
+// signNotification covers every field the UDP server acts on. The data is
+// signed as the raw bytes on the wire, so it is never re-encoded.
+func signNotification(secret string, msg *Message) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	for _, field := range []string{msg.Type, msg.EventType, msg.UserID, msg.RequestID, msg.Timestamp} {
+		mac.Write([]byte(field))
+		mac.Write([]byte{0})
+	}
+	mac.Write(msg.Data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 func CreateSuccessMessage(message string) []byte {
 	payload := SuccessPayload{Message: message}
 	msg := Message{
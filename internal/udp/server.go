@@ -6,12 +6,17 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"sync/atomic"
 	"time"
 
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/ratelimit"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/sse"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/config"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/metrics"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
 )
 
@@ -23,9 +28,12 @@ type Server struct {
 	broadcaster       *Broadcaster
 	log               *logger.Logger
 	bridge            *bridge.UnifiedBridge
-	sseBroker         *SSEBroker
+	sseBroker         *sse.Broker
 	httpClient        *http.Client
 	apiServerURL      string
+	notifiers         []net.IP
+	notifySecret      string
+	limiter           *ratelimit.Limiter
 }
 
 func NewServer(port string) *Server {
@@ -34,25 +42,62 @@ func NewServer(port string) *Server {
 	if apiURL == "" {
 		apiURL = "http://localhost:8080"
 	}
+	secret, _ := config.LoadJWTSecret()
 	return &Server{
 		Port:              port,
 		subscriberManager: NewSubscriberManager(log),
 		log:               log,
 		bridge:            nil,
-		sseBroker:         NewSSEBroker(),
 		httpClient:        &http.Client{Timeout: 2 * time.Second},
 		apiServerURL:      apiURL,
+		notifiers:         notifierIPs(apiURL),
+		notifySecret:      secret,
 	}
 }
 
+// notifierIPs lists the addresses notifications may come from besides
+// loopback: this host's own, and the API server's
+func notifierIPs(apiURL string) []net.IP {
+	var ips []net.IP
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok {
+				ips = append(ips, ipNet.IP)
+			}
+		}
+	}
+	if u, err := url.Parse(apiURL); err == nil && u.Hostname() != "" {
+		if ip := net.ParseIP(u.Hostname()); ip != nil {
+			ips = append(ips, ip)
+		} else if resolved, err := net.LookupIP(u.Hostname()); err == nil {
+			ips = append(ips, resolved...)
+		}
+	}
+	return ips
+}
+
 func (s *Server) SetBridge(b *bridge.UnifiedBridge) {
 	s.bridge = b
 	if s.broadcaster != nil {
 		s.broadcaster.SetBridge(b)
 	}
+	s.subscriberManager.OnRemove(func(userID string, addr *net.UDPAddr) {
+		b.MarkDisconnected(userID, udpConnID(addr))
+	})
 	s.log.Info("udp_server_bridge_set")
 }
 
+// SetNotificationSecret sets the secret notifications must be signed with.
+// It defaults to the JWT secret, which the API server signs with.
+func (s *Server) SetNotificationSecret(secret string) {
+	s.notifySecret = secret
+}
+
+// SetLimiter limits how fast clients may send packets
+func (s *Server) SetLimiter(l *ratelimit.Limiter) {
+	s.limiter = l
+}
+
 func (s *Server) Start() error {
 	addr, err := net.ResolveUDPAddr("udp", ":"+s.Port)
 	if err != nil {
@@ -128,9 +173,13 @@ func (s *Server) processPacket(data []byte, addr *net.UDPAddr) {
 		return
 	}
 
-	s.log.Debug("received_packet",
+	s.log.WithRequestID(msg.RequestID).Debug("received_packet",
 		"type", msg.Type,
 		"addr", addr.String())
+	metrics.RecordProtocolMessage("udp", msg.Type)
+	if !s.admit(addr, msg) {
+		return
+	}
 
 	switch msg.Type {
 	case "register":
@@ -151,6 +200,57 @@ func (s *Server) processPacket(data []byte, addr *net.UDPAddr) {
 	}
 }
 
+// admit checks a packet against the rate limiter. Heartbeats keep
+// subscriptions alive, so they are not limited. Notifications are broadcast
+// to everyone without a user token, so they must come from the API server:
+// from a trusted address, signed, and recent enough not to be a replay.
+func (s *Server) admit(addr *net.UDPAddr, msg *Message) bool {
+	msgType := msg.Type
+	if msgType == "notification" {
+		if !s.trustedNotifier(addr.IP) {
+			s.log.Warn("notification_from_untrusted_sender", "addr", addr.String())
+			s.sendError(addr, string(ErrUDPAuthFailed), "Notifications are only accepted from the API server")
+			return false
+		}
+		if err := VerifyNotification(s.notifySecret, msg, time.Now()); err != nil {
+			s.log.Warn("notification_rejected", "addr", addr.String(), "error", err.Error())
+			s.sendError(addr, string(ErrUDPAuthFailed), "Notification is not signed by the API server")
+			return false
+		}
+		return true
+	}
+	if s.limiter == nil || msgType == "heartbeat" {
+		return true
+	}
+	userID, _ := s.subscriberManager.GetUserByAddr(addr)
+	d := s.limiter.Check(ratelimit.Message{Scope: "udp", UserID: userID, IP: addr.IP.String()})
+	if d.Allowed {
+		return true
+	}
+	s.log.Warn("packet_rate_limited",
+		"addr", addr.String(),
+		"user_id", userID,
+		"reason", d.Reason,
+		"penalty", d.Penalty.String())
+	s.sendError(addr, string(ErrUDPRateLimited), "Rate limit exceeded: "+d.Reason)
+	if d.Penalty == ratelimit.Disconnect {
+		s.subscriberManager.Unsubscribe(addr)
+	}
+	return false
+}
+
+func (s *Server) trustedNotifier(ip net.IP) bool {
+	if ip.IsLoopback() {
+		return true
+	}
+	for _, trusted := range s.notifiers {
+		if trusted.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Server) handleRegister(addr *net.UDPAddr, payload json.RawMessage) {
 	var regPayload RegisterPayload
 	if err := json.Unmarshal(payload, &regPayload); err != nil {
@@ -173,6 +273,9 @@ func (s *Server) handleRegister(addr *net.UDPAddr, payload json.RawMessage) {
 	}
 
 	s.subscriberManager.Subscribe(claims.UserID, addr, []string{"all"})
+	if s.bridge != nil {
+		s.bridge.MarkConnected(claims.UserID, udpConnID(addr), bridge.ProtocolUDP)
+	}
 
 	s.log.Info("client_registered",
 		"user_id", claims.UserID,
@@ -225,6 +328,9 @@ func (s *Server) handleSubscribe(addr *net.UDPAddr, payload json.RawMessage) {
 	}
 
 	userID, _ := s.subscriberManager.GetUserByAddr(addr)
+	if s.bridge != nil {
+		s.bridge.MarkActive(userID, bridge.ProtocolUDP)
+	}
 	s.log.Info("subscription_updated",
 		"user_id", userID,
 		"addr", addr.String(),
@@ -243,6 +349,10 @@ func (s *Server) handleHeartbeat(addr *net.UDPAddr) {
 	s.conn.WriteToUDP(response, addr)
 }
 
+func udpConnID(addr *net.UDPAddr) string {
+	return "udp/" + addr.String()
+}
+
 func (s *Server) sendSuccess(addr *net.UDPAddr, message string) {
 	response := CreateSuccessMessage(message)
 	_, err := s.conn.WriteToUDP(response, addr)
@@ -264,13 +374,14 @@ func (s *Server) sendError(addr *net.UDPAddr, code, message string) {
 }
 
 func (s *Server) handleNotificationForward(msg *Message) {
+	log := s.log.WithRequestID(msg.RequestID)
 	if msg.EventType == "" {
-		s.log.Warn("invalid_notification_forward", "event_type", msg.EventType)
+		log.Warn("invalid_notification_forward", "event_type", msg.EventType)
 		return
 	}
 
 	if s.broadcaster == nil {
-		s.log.Warn("broadcaster_not_initialized")
+		log.Warn("broadcaster_not_initialized")
 		return
 	}
 
@@ -280,25 +391,26 @@ func (s *Server) handleNotificationForward(msg *Message) {
 	}
 
 	unifiedEvent := bridge.UnifiedEvent{
-		Type: bridge.EventType(msg.EventType),
-		Data: eventData,
+		Type:     bridge.EventType(msg.EventType),
+		Data:     eventData,
+		Metadata: bridge.EventMetadata{RequestID: msg.RequestID},
 	}
 
 	if msg.UserID == "" {
 		s.broadcaster.BroadcastToAll(bridge.BroadcastEvent{EventType: msg.EventType, Data: eventData})
-		s.log.Info("notification_broadcast_all", "event_type", msg.EventType)
+		log.Info("notification_broadcast_all", "event_type", msg.EventType)
 		s.broadcastToSSE("", msg.EventType, eventData)
-		s.forwardToAPIServer("", msg.EventType, eventData)
+		s.forwardToAPIServer("", msg.EventType, msg.RequestID, eventData)
 		return
 	}
 
 	s.broadcaster.BroadcastUnifiedEvent(msg.UserID, unifiedEvent)
-	s.log.Info("notification_forwarded_and_broadcast", "user_id", msg.UserID, "event_type", msg.EventType)
+	log.Info("notification_forwarded_and_broadcast", "user_id", msg.UserID, "event_type", msg.EventType)
 	s.broadcastToSSE(msg.UserID, msg.EventType, eventData)
-	s.forwardToAPIServer(msg.UserID, msg.EventType, eventData)
+	s.forwardToAPIServer(msg.UserID, msg.EventType, msg.RequestID, eventData)
 }
 
-func (s *Server) forwardToAPIServer(userID, eventType string, data map[string]interface{}) {
+func (s *Server) forwardToAPIServer(userID, eventType, requestID string, data map[string]interface{}) {
 	var message string
 	switch eventType {
 	case "manga_created":
@@ -370,21 +482,28 @@ func (s *Server) forwardToAPIServer(userID, eventType string, data map[string]in
 		return
 	}
 
+	log := s.log.WithRequestID(requestID)
 	go func() {
-		resp, err := s.httpClient.Post(
-			s.apiServerURL+"/internal/notify",
-			"application/json",
-			bytes.NewBuffer(jsonData),
-		)
+		req, err := http.NewRequest(http.MethodPost, s.apiServerURL+"/internal/notify", bytes.NewBuffer(jsonData))
+		if err != nil {
+			log.Warn("failed_to_forward_to_api", "error", err.Error())
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if requestID != "" {
+			req.Header.Set(logger.RequestIDHeader, requestID)
+		}
+
+		resp, err := s.httpClient.Do(req)
 		if err != nil {
-			s.log.Warn("failed_to_forward_to_api", "error", err.Error())
+			log.Warn("failed_to_forward_to_api", "error", err.Error())
 			return
 		}
 		defer resp.Body.Close()
 		if resp.StatusCode == 200 {
-			s.log.Info("notification_forwarded_to_api", "event_type", eventType)
+			log.Info("notification_forwarded_to_api", "event_type", eventType)
 		} else {
-			s.log.Warn("api_forward_failed", "status", resp.StatusCode)
+			log.Warn("api_forward_failed", "status", resp.StatusCode)
 		}
 	}()
 }
@@ -469,7 +588,12 @@ func (s *Server) broadcastToSSE(userID, eventType string, data map[string]interf
 	}
 }
 
+// SetSSEBroker sets the SSE broker that receives frontend notifications
+func (s *Server) SetSSEBroker(broker *sse.Broker) {
+	s.sseBroker = broker
+}
+
 // GetSSEBroker returns the SSE broker for HTTP endpoint
-func (s *Server) GetSSEBroker() *SSEBroker {
+func (s *Server) GetSSEBroker() *sse.Broker {
 	return s.sseBroker
 }
@@ -21,6 +21,7 @@ type SubscriberManager struct {
 	addrToUser  map[string]string
 	mu          sync.RWMutex
 	log         *logger.Logger
+	onRemove    func(userID string, addr *net.UDPAddr)
 	stopChan    chan struct{}
 	stopped     bool
 }
@@ -58,13 +59,20 @@ func (sm *SubscriberManager) Subscribe(userID string, addr *net.UDPAddr, eventTy
 		"event_types", eventTypes)
 }
 
-func (sm *SubscriberManager) Unsubscribe(addr *net.UDPAddr) {
+// OnRemove calls fn whenever a subscriber unregisters or goes stale
+func (sm *SubscriberManager) OnRemove(fn func(userID string, addr *net.UDPAddr)) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
+	sm.onRemove = fn
+}
+
+func (sm *SubscriberManager) Unsubscribe(addr *net.UDPAddr) {
+	sm.mu.Lock()
 
 	addrKey := addr.String()
 	userID, exists := sm.addrToUser[addrKey]
 	if !exists {
+		sm.mu.Unlock()
 		return
 	}
 
@@ -87,6 +95,12 @@ func (sm *SubscriberManager) Unsubscribe(addr *net.UDPAddr) {
 	sm.log.Debug("subscriber_unregistered",
 		"user_id", userID,
 		"addr", addrKey)
+	onRemove := sm.onRemove
+	sm.mu.Unlock()
+
+	if onRemove != nil {
+		onRemove(userID, addr)
+	}
 }
 
 func (sm *SubscriberManager) UpdateSubscription(addr *net.UDPAddr, eventTypes []string) bool {
@@ -196,10 +210,10 @@ func (sm *SubscriberManager) cleanupLoop() {
 
 func (sm *SubscriberManager) cleanupStale() {
 	sm.mu.Lock()
-	defer sm.mu.Unlock()
 
 	now := time.Now()
 	timeout := 2 * time.Minute
+	stale := []*Subscriber{}
 
 	for userID, subs := range sm.subscribers {
 		filtered := []*Subscriber{}
@@ -208,6 +222,7 @@ func (sm *SubscriberManager) cleanupStale() {
 				filtered = append(filtered, sub)
 			} else {
 				delete(sm.addrToUser, sub.Addr.String())
+				stale = append(stale, sub)
 				sm.log.Info("removed_stale_subscriber",
 					"user_id", sub.UserID,
 					"addr", sub.Addr.String(),
@@ -221,6 +236,14 @@ func (sm *SubscriberManager) cleanupStale() {
 			delete(sm.subscribers, userID)
 		}
 	}
+	onRemove := sm.onRemove
+	sm.mu.Unlock()
+
+	if onRemove != nil {
+		for _, sub := range stale {
+			onRemove(sub.UserID, sub.Addr)
+		}
+	}
 }
 
 func (sm *SubscriberManager) matchesEventType(sub *Subscriber, eventType string) bool {
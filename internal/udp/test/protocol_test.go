@@ -2,7 +2,9 @@ package udp_test
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/udp"
 )
@@ -210,3 +212,38 @@ func TestCreateErrorMessage(t *testing.T) {
 		t.Errorf("Expected message '%s', got '%s'", message, payload.Message)
 	}
 }
+
+func TestVerifyNotification(t *testing.T) {
+	packet := udp.CreateSignedNotificationMessage("secret", "user-123", "chapter_release", "req-1",
+		map[string]interface{}{"manga_id": "manga-1", "delta": 2})
+	msg, err := udp.ParseMessage(packet)
+	if err != nil {
+		t.Fatalf("Failed to parse notification: %v", err)
+	}
+
+	if err := udp.VerifyNotification("secret", msg, time.Now()); err != nil {
+		t.Errorf("Expected a fresh signed notification to verify, got %v", err)
+	}
+	if err := udp.VerifyNotification("other", msg, time.Now()); !errors.Is(err, udp.ErrNotificationSignature) {
+		t.Errorf("Expected a signature error for the wrong secret, got %v", err)
+	}
+	if err := udp.VerifyNotification("secret", msg, time.Now().Add(udp.NotificationMaxAge+time.Minute)); !errors.Is(err, udp.ErrNotificationStale) {
+		t.Errorf("Expected an old notification to be stale, got %v", err)
+	}
+
+	tampered := *msg
+	tampered.UserID = "user-456"
+	if err := udp.VerifyNotification("secret", &tampered, time.Now()); !errors.Is(err, udp.ErrNotificationSignature) {
+		t.Errorf("Expected a changed user_id to break the signature, got %v", err)
+	}
+	tampered = *msg
+	tampered.Data = []byte(`{"manga_id":"manga-2","delta":2}`)
+	if err := udp.VerifyNotification("secret", &tampered, time.Now()); !errors.Is(err, udp.ErrNotificationSignature) {
+		t.Errorf("Expected changed data to break the signature, got %v", err)
+	}
+
+	unsigned, _ := udp.ParseMessage(udp.CreateNotificationMessage("user-123", "chapter_release", nil))
+	if err := udp.VerifyNotification("secret", unsigned, time.Now()); !errors.Is(err, udp.ErrNotificationUnsigned) {
+		t.Errorf("Expected an unsigned notification to be refused, got %v", err)
+	}
+}
@@ -288,3 +288,73 @@ func TestServerHeartbeat(t *testing.T) {
 		t.Errorf("Expected success response for heartbeat, got '%s'", msg.Type)
 	}
 }
+
+func TestServerNotificationsMustBeSigned(t *testing.T) {
+	logger.Init(logger.ERROR, false, nil)
+
+	server := udp.NewServer("19097")
+	server.SetNotificationSecret("notify-secret")
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	serverAddr := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 19097}
+	subscriber, err := net.DialUDP("udp", nil, serverAddr)
+	if err != nil {
+		t.Fatalf("Failed to dial UDP: %v", err)
+	}
+	defer subscriber.Close()
+	sender, err := net.DialUDP("udp", nil, serverAddr)
+	if err != nil {
+		t.Fatalf("Failed to dial UDP: %v", err)
+	}
+	defer sender.Close()
+
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		jwtSecret = "your-secret-key-change-this-in-production"
+	}
+	token, _ := utils.GenerateJWT("user1", "testuser", "user", jwtSecret)
+	subscriber.Write(udp.CreateRegisterMessage(token))
+
+	buffer := make([]byte, 1024)
+	subscriber.SetReadDeadline(time.Now().Add(2 * time.Second))
+	subscriber.Read(buffer)
+
+	data := map[string]interface{}{"manga_id": "manga-1", "action": "add"}
+	rejected := map[string][]byte{
+		"unsigned":     udp.CreateNotificationMessage("user1", "library_update", data),
+		"wrong secret": udp.CreateSignedNotificationMessage("other-secret", "user1", "library_update", "", data),
+	}
+	for name, packet := range rejected {
+		sender.Write(packet)
+		sender.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, err := sender.Read(buffer)
+		if err != nil {
+			t.Fatalf("%s: no error response: %v", name, err)
+		}
+		msg, _ := udp.ParseMessage(buffer[:n])
+		if msg == nil || msg.Type != "error" {
+			t.Errorf("%s: expected an error response, got %s", name, buffer[:n])
+		}
+
+		subscriber.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		if n, err := subscriber.Read(buffer); err == nil {
+			t.Errorf("%s: notification reached the subscriber: %s", name, buffer[:n])
+		}
+	}
+
+	sender.Write(udp.CreateSignedNotificationMessage("notify-secret", "user1", "library_update", "", data))
+	subscriber.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := subscriber.Read(buffer)
+	if err != nil {
+		t.Fatalf("Signed notification was not delivered: %v", err)
+	}
+	msg, err := udp.ParseMessage(buffer[:n])
+	if err != nil || msg.Type != "notification" || msg.EventType != "library_update" {
+		t.Errorf("Expected the library_update notification, got %s", buffer[:n])
+	}
+}
@@ -3,9 +3,10 @@ package user
 import (
 	"bufio"
 	"context"
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
@@ -15,7 +16,10 @@ import (
 
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
 	manga "github.com/binhbb2204/Manga-Hub-Group13/internal/manga"
-	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/udp"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/config"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
 	"github.com/gin-gonic/gin"
@@ -31,7 +35,7 @@ type Handler struct {
 func NewHandler(br *bridge.Bridge) *Handler {
 	return &Handler{
 		bridge:         br,
-		externalSource: manga.NewMALSource(),
+		externalSource: manga.DefaultRegistry(),
 	}
 }
 
@@ -85,15 +89,18 @@ func (h *Handler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	var user models.User
-	query := `SELECT id, username, email, created_at FROM users WHERE id = ?`
-	err := database.DB.QueryRow(query, userID).Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt)
+	user, err := repository.Default().Users.GetByID(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, gin.H{
+		"id":         user.ID,
+		"username":   user.Username,
+		"email":      user.Email,
+		"created_at": user.CreatedAt,
+	})
 }
 
 // AddToLibrary adds a manga to user's library
@@ -104,11 +111,19 @@ func (h *Handler) AddToLibrary(c *gin.Context) {
 		return
 	}
 
+	requestID := logger.RequestIDFromContext(c)
+	reqLog := logger.ForContext(c).WithContext("user_id", userID)
+
 	var req models.AddToLibraryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	tags, err := repository.NormalizeTags(req.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// DEBUG: Log that we're entering this function
 	log.Printf("[DEBUG] AddToLibrary called for manga ID: %s, user ID: %s", req.MangaID, userID)
@@ -126,7 +141,7 @@ func (h *Handler) AddToLibrary(c *gin.Context) {
 	// MangaDex lookup removed
 
 	// Save or update manga in database with complete metadata (UPSERT)
-	if err := h.saveMangaToDB(mangaData); err != nil {
+	if err := repository.Default().Manga.Upsert(ctx, mangaData); err != nil {
 		log.Printf("[ERROR] Failed to save manga %s to database: %v", req.MangaID, err)
 		// Continue anyway - user progress is more important
 	}
@@ -140,7 +155,7 @@ func (h *Handler) AddToLibrary(c *gin.Context) {
 	udpForwardEnabled := os.Getenv("UDP_FORWARD_ENABLED") == "true"
 
 	// Auto-set status: new manga always starts as "plan_to_read"
-	status := "plan_to_read"
+	status := repository.StatusPlanToRead
 
 	if strictTCPForward {
 		// Strict mode: require TCP server to process the add_to_library operation
@@ -148,19 +163,18 @@ func (h *Handler) AddToLibrary(c *gin.Context) {
 		token := c.GetHeader("Authorization")
 		token = strings.TrimPrefix(token, "Bearer ")
 
-		if err := forwardAddToLibraryToTCP(tcpAddr, token, req.MangaID, status); err != nil {
-			log.Printf("[ERROR] TCP add_to_library required but failed: %v", err)
+		if err := forwardAddToLibraryToTCP(tcpAddr, requestID, token, req.MangaID, status); err != nil {
+			reqLog.Error("tcp_forward_required_failed", "operation", "add_to_library", "error", err.Error())
 			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "TCP forward failed; TCP server unavailable"})
 			return
 		}
 	} else {
 		// Local DB write remains the source of truth (non-strict)
-		query := `INSERT INTO user_progress (user_id, manga_id, current_chapter, status, updated_at)
-				  VALUES (?, ?, 0, ?, ?)
-				  ON CONFLICT(user_id, manga_id) DO UPDATE SET status = ?, updated_at = ?`
-
-		now := time.Now()
-		if _, err = database.DB.Exec(query, userID, req.MangaID, status, now, status, now); err != nil {
+		if _, err := repository.Default().Progress.AddToLibrary(c.Request.Context(), userID, req.MangaID, status); err != nil {
+			if errors.Is(err, repository.ErrMangaNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Manga not found"})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add manga to library"})
 			return
 		}
@@ -171,8 +185,8 @@ func (h *Handler) AddToLibrary(c *gin.Context) {
 			token := c.GetHeader("Authorization")
 			token = strings.TrimPrefix(token, "Bearer ")
 			go func() {
-				if err := forwardAddToLibraryToTCP(tcpAddr, token, req.MangaID, status); err != nil {
-					log.Printf("[WARN] TCP add_to_library forward failed: %v", err)
+				if err := forwardAddToLibraryToTCP(tcpAddr, requestID, token, req.MangaID, status); err != nil {
+					reqLog.Warn("tcp_forward_failed", "operation", "add_to_library", "error", err.Error())
 				}
 			}()
 		}
@@ -182,8 +196,8 @@ func (h *Handler) AddToLibrary(c *gin.Context) {
 	if strictUDPForward {
 		// Strict mode: require UDP server to process the add_to_library notification
 		udpAddr := resolveUDPAddr()
-		if err := forwardAddToLibraryToUDP(udpAddr, userID, req.MangaID, status); err != nil {
-			log.Printf("[ERROR] UDP add_to_library required but failed: %v", err)
+		if err := forwardAddToLibraryToUDP(udpAddr, requestID, userID, req.MangaID, status); err != nil {
+			reqLog.Error("udp_forward_required_failed", "operation", "add_to_library", "error", err.Error())
 			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "UDP forward failed; UDP server unavailable"})
 			return
 		}
@@ -191,54 +205,31 @@ func (h *Handler) AddToLibrary(c *gin.Context) {
 		// Non-strict forward (optional)
 		udpAddr := resolveUDPAddr()
 		go func() {
-			if err := forwardAddToLibraryToUDP(udpAddr, userID, req.MangaID, status); err != nil {
-				log.Printf("[WARN] UDP add_to_library forward failed: %v", err)
+			if err := forwardAddToLibraryToUDP(udpAddr, requestID, userID, req.MangaID, status); err != nil {
+				reqLog.Warn("udp_forward_failed", "operation", "add_to_library", "error", err.Error())
 			}
 		}()
 	}
 
-	// Notify locally (bridge may be no-op in standalone API mode)
-	h.bridge.NotifyLibraryUpdate(bridge.LibraryUpdateEvent{UserID: userID, MangaID: req.MangaID, Action: "added"})
-
-	c.JSON(http.StatusOK, gin.H{"message": "Manga added to library successfully"})
-}
-
-// saveMangaToDB saves or updates manga in database with UPSERT
-func (h *Handler) saveMangaToDB(m *models.Manga) error {
-	genresJSON, err := json.Marshal(m.Genres)
-	if err != nil {
-		return err
+	if req.IsFavorite {
+		if err := repository.Default().Progress.SetFavorite(c.Request.Context(), userID, req.MangaID, true); err != nil {
+			reqLog.Warn("set_favorite_failed", "manga_id", req.MangaID, "error", err.Error())
+		}
+	}
+	if len(tags) > 0 {
+		if _, err := repository.Default().Progress.SetTags(c.Request.Context(), userID, req.MangaID, tags); err != nil {
+			reqLog.Warn("set_tags_failed", "manga_id", req.MangaID, "error", err.Error())
+		}
 	}
 
-	query := `INSERT INTO manga (id, title, author, genres, status, total_chapters, description, cover_url, media_type)
-	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	          ON CONFLICT(id) DO UPDATE SET 
-	              title = excluded.title,
-	              author = excluded.author,
-	              genres = excluded.genres,
-	              status = excluded.status,
-	              total_chapters = excluded.total_chapters,
-	              description = excluded.description,
-	              cover_url = excluded.cover_url,
-	              media_type = excluded.media_type`
-
-	_, err = database.DB.Exec(
-		query,
-		m.ID,
-		m.Title,
-		m.Author,
-		string(genresJSON),
-		m.Status,
-		m.TotalChapters,
-		m.Description,
-		m.CoverURL,
-		m.MediaType,
-	)
+	// Notify locally (bridge may be no-op in standalone API mode)
+	h.bridge.NotifyLibraryUpdate(bridge.LibraryUpdateEvent{UserID: userID, MangaID: req.MangaID, Action: "added", RequestID: requestID})
 
-	return err
+	c.JSON(http.StatusOK, gin.H{"message": "Manga added to library successfully"})
 }
 
-// GetLibrary gets user's manga library
+// GetLibrary gets user's manga library, optionally narrowed to entries
+// carrying ?tag= or to ?favorite=true
 func (h *Handler) GetLibrary(c *gin.Context) {
 	userID := c.GetString("user_id")
 
@@ -247,21 +238,11 @@ func (h *Handler) GetLibrary(c *gin.Context) {
 		return
 	}
 
-	query := `
-		SELECT m.id, m.title, m.author, m.genres, m.status, m.total_chapters, m.description, m.cover_url,
-		       up.current_chapter, up.status, up.user_rating, up.updated_at
-		FROM user_progress up
-		JOIN manga m ON up.manga_id = m.id
-		WHERE up.user_id = ?
-		ORDER BY up.updated_at DESC
-	`
-
-	rows, err := database.DB.Query(query, userID)
+	entries, err := repository.Default().Progress.Library(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
 	}
-	defer rows.Close()
 
 	library := models.UserLibrary{
 		Reading:    []models.MangaProgress{},
@@ -269,42 +250,15 @@ func (h *Handler) GetLibrary(c *gin.Context) {
 		PlanToRead: []models.MangaProgress{},
 	}
 
-	for rows.Next() {
-		var mp models.MangaProgress
-		var genresJSON string
-		var userRating sql.NullFloat64 // Handle NULL values
-
-		err := rows.Scan(
-			&mp.Manga.ID,
-			&mp.Manga.Title,
-			&mp.Manga.Author,
-			&genresJSON,
-			&mp.Manga.Status,
-			&mp.Manga.TotalChapters,
-			&mp.Manga.Description,
-			&mp.Manga.CoverURL,
-			&mp.CurrentChapter,
-			&mp.Status,
-			&userRating, // Scan into sql.NullFloat64
-			&mp.UpdatedAt,
-		)
-		if err != nil {
-			// Log error but continue processing other rows
-			log.Printf("[ERROR] Failed to scan library row: %v", err)
-			continue
-		}
+	tag := strings.ToLower(strings.TrimSpace(c.Query("tag")))
+	favoritesOnly := c.Query("favorite") == "true"
 
-		// Convert user rating (use pointer for explicit null)
-		if userRating.Valid {
-			rating := userRating.Float64
-			mp.UserRating = &rating
-		} else {
-			mp.UserRating = nil // Explicit null in JSON
+	for _, mp := range entries {
+		if favoritesOnly && !mp.IsFavorite {
+			continue
 		}
-
-		// Parse genres JSON
-		if genresJSON != "" {
-			json.Unmarshal([]byte(genresJSON), &mp.Manga.Genres)
+		if tag != "" && !hasTag(mp.Tags, tag) {
+			continue
 		}
 
 		// Categorize by status
@@ -335,57 +289,40 @@ func (h *Handler) UpdateProgress(c *gin.Context) {
 		return
 	}
 
-	// Check if manga exists in user's library
-	var exists bool
-	checkQuery := `SELECT EXISTS(SELECT 1 FROM user_progress WHERE user_id = ? AND manga_id = ?)`
-	err := database.DB.QueryRow(checkQuery, userID, req.MangaID).Scan(&exists)
-	if err != nil || !exists {
+	_, err := h.SaveProgress(c, userID, req)
+	switch {
+	case errors.Is(err, repository.ErrNotInLibrary):
 		c.JSON(http.StatusNotFound, gin.H{"error": "Manga not in library"})
 		return
+	case errors.Is(err, repository.ErrInvalidStatus), errors.Is(err, repository.ErrInvalidRating), errors.Is(err, repository.ErrInvalidChapter):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errTCPForward), errors.Is(err, errUDPForward):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update progress"})
+		return
 	}
 
-	// Get manga total_chapters for auto-status calculation
-	var totalChapters int
-	mangaQuery := `SELECT total_chapters FROM manga WHERE id = ?`
-	database.DB.QueryRow(mangaQuery, req.MangaID).Scan(&totalChapters)
-
-	// Build update query - start with updated_at
-	query := `UPDATE user_progress SET updated_at = ?`
-	args := []interface{}{time.Now()}
-
-	// Auto-calculate status based on chapter progress
-	var autoStatus string
-	if req.CurrentChapter != nil {
-		currentChapter := *req.CurrentChapter
-
-		if currentChapter == 0 {
-			autoStatus = "plan_to_read"
-		} else if totalChapters > 0 && currentChapter >= totalChapters {
-			autoStatus = "completed"
-		} else {
-			autoStatus = "reading"
-		}
-
-		query += `, current_chapter = ?, status = ?`
-		args = append(args, currentChapter, autoStatus)
-	} else if req.Status != "" {
-		// Allow manual status override if no chapter update
-		query += `, status = ?`
-		args = append(args, req.Status)
-	}
+	c.JSON(http.StatusOK, gin.H{"message": "Progress updated successfully"})
+}
 
-	if req.UserRating != nil {
-		query += `, user_rating = ?`
-		args = append(args, *req.UserRating)
-	}
+var (
+	errTCPForward = errors.New("TCP forward failed; TCP server unavailable")
+	errUDPForward = errors.New("UDP forward failed; UDP server unavailable")
+)
 
-	query += ` WHERE user_id = ? AND manga_id = ?`
-	args = append(args, userID, req.MangaID)
+// SaveProgress applies a progress update and announces it: forwarded to the
+// standalone TCP/UDP servers when enabled, then sent through the bridge.
+// Other handlers that move progress use it so clients hear about every change.
+func (h *Handler) SaveProgress(c *gin.Context, userID string, req models.UpdateProgressRequest) (*models.UserProgress, error) {
+	requestID := logger.RequestIDFromContext(c)
+	reqLog := logger.ForContext(c).WithContext("user_id", userID)
 
-	_, err = database.DB.Exec(query, args...)
+	progress, err := repository.Default().Progress.Update(c.Request.Context(), userID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update progress"})
-		return
+		return nil, err
 	}
 
 	// Optional/Required: forward to standalone TCP server when running separately.
@@ -405,31 +342,20 @@ func (h *Handler) UpdateProgress(c *gin.Context) {
 		token := c.GetHeader("Authorization")
 		token = strings.TrimPrefix(token, "Bearer ")
 
-		forwardStatus := autoStatus
-		if forwardStatus == "" && req.Status != "" {
-			forwardStatus = req.Status
-		}
-		if forwardStatus == "" {
-			forwardStatus = "reading"
-		}
-
-		chapterVal := 0
-		if req.CurrentChapter != nil {
-			chapterVal = *req.CurrentChapter
-		}
+		forwardStatus := progress.Status
+		chapterVal := progress.CurrentChapter
 
 		if strictTCPForward {
 			// In strict mode, fail the request if TCP forward fails
-			if err := forwardProgressToTCP(tcpAddr, token, userID, req.MangaID, chapterVal, forwardStatus); err != nil {
-				log.Printf("[ERROR] TCP forward required but failed: %v", err)
-				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "TCP forward failed; TCP server unavailable"})
-				return
+			if err := forwardProgressToTCP(tcpAddr, requestID, token, userID, req.MangaID, chapterVal, forwardStatus); err != nil {
+				reqLog.Error("tcp_forward_required_failed", "operation", "sync_progress", "error", err.Error())
+				return nil, errTCPForward
 			}
 		} else {
 			// Non-strict: fire-and-forget
 			go func() {
-				if err := forwardProgressToTCP(tcpAddr, token, userID, req.MangaID, chapterVal, forwardStatus); err != nil {
-					log.Printf("[WARN] TCP forward failed: %v", err)
+				if err := forwardProgressToTCP(tcpAddr, requestID, token, userID, req.MangaID, chapterVal, forwardStatus); err != nil {
+					reqLog.Warn("tcp_forward_failed", "operation", "sync_progress", "error", err.Error())
 				}
 			}()
 		}
@@ -439,18 +365,8 @@ func (h *Handler) UpdateProgress(c *gin.Context) {
 	if strictUDPForward || udpForwardEnabled {
 		udpAddr := resolveUDPAddr()
 
-		forwardStatus := autoStatus
-		if forwardStatus == "" && req.Status != "" {
-			forwardStatus = req.Status
-		}
-		if forwardStatus == "" {
-			forwardStatus = "reading"
-		}
-
-		chapterVal := 0
-		if req.CurrentChapter != nil {
-			chapterVal = *req.CurrentChapter
-		}
+		forwardStatus := progress.Status
+		chapterVal := progress.CurrentChapter
 
 		userRating := 0.0
 		if req.UserRating != nil {
@@ -459,40 +375,49 @@ func (h *Handler) UpdateProgress(c *gin.Context) {
 
 		if strictUDPForward {
 			// In strict mode, fail the request if UDP forward fails
-			if err := forwardProgressToUDP(udpAddr, userID, req.MangaID, chapterVal, forwardStatus, userRating); err != nil {
-				log.Printf("[ERROR] UDP forward required but failed: %v", err)
-				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "UDP forward failed; UDP server unavailable"})
-				return
+			if err := forwardProgressToUDP(udpAddr, requestID, userID, req.MangaID, chapterVal, forwardStatus, userRating); err != nil {
+				reqLog.Error("udp_forward_required_failed", "operation", "progress_update", "error", err.Error())
+				return nil, errUDPForward
 			}
 		} else {
 			// Non-strict: fire-and-forget
 			go func() {
-				if err := forwardProgressToUDP(udpAddr, userID, req.MangaID, chapterVal, forwardStatus, userRating); err != nil {
-					log.Printf("[WARN] UDP forward failed: %v", err)
+				if err := forwardProgressToUDP(udpAddr, requestID, userID, req.MangaID, chapterVal, forwardStatus, userRating); err != nil {
+					reqLog.Warn("udp_forward_failed", "operation", "progress_update", "error", err.Error())
 				}
 			}()
 		}
 	}
 
 	h.bridge.NotifyProgressUpdate(bridge.ProgressUpdateEvent{
-		UserID:  userID,
-		MangaID: req.MangaID,
-		ChapterID: func() int {
-			if req.CurrentChapter != nil {
-				return *req.CurrentChapter
-			}
-			return 0
-		}(),
-		Status:       autoStatus,
+		UserID:       userID,
+		MangaID:      req.MangaID,
+		ChapterID:    progress.CurrentChapter,
+		Status:       progress.Status,
 		LastReadDate: time.Now(),
+		RequestID:    requestID,
 	})
 
-	c.JSON(http.StatusOK, gin.H{"message": "Progress updated successfully"})
+	return progress, nil
+}
+
+// writeTCPMessage writes one line of the TCP JSON protocol
+func writeTCPMessage(w io.Writer, msgType, requestID string, payload interface{}) error {
+	line, err := json.Marshal(map[string]interface{}{
+		"type":       msgType,
+		"request_id": requestID,
+		"payload":    payload,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
 }
 
 // forwardProgressToTCP opens a short-lived TCP connection to the TCP server and
 // sends auth + sync_progress messages using the existing TCP JSON protocol.
-func forwardProgressToTCP(tcpAddr, jwtToken, userID, mangaID string, chapter int, status string) error {
+func forwardProgressToTCP(tcpAddr, requestID, jwtToken, userID, mangaID string, chapter int, status string) error {
 	conn, err := net.DialTimeout("tcp", tcpAddr, 2*time.Second)
 	if err != nil {
 		return fmt.Errorf("dial tcp: %w", err)
@@ -501,15 +426,16 @@ func forwardProgressToTCP(tcpAddr, jwtToken, userID, mangaID string, chapter int
 
 	w := bufio.NewWriter(conn)
 
-	// auth
-	if _, err := fmt.Fprintf(w, `{"type":"auth","payload":{"token":"%s"}}`+"\n", jwtToken); err != nil {
+	if err := writeTCPMessage(w, "auth", requestID, map[string]interface{}{"token": jwtToken}); err != nil {
 		return fmt.Errorf("write auth: %w", err)
 	}
-
-	// sync_progress
-	if _, err := fmt.Fprintf(w,
-		`{"type":"sync_progress","payload":{"user_id":"%s","manga_id":"%s","current_chapter":%d,"status":"%s"}}`+"\n",
-		userID, mangaID, chapter, status); err != nil {
+	payload := map[string]interface{}{
+		"user_id":         userID,
+		"manga_id":        mangaID,
+		"current_chapter": chapter,
+		"status":          status,
+	}
+	if err := writeTCPMessage(w, "sync_progress", requestID, payload); err != nil {
 		return fmt.Errorf("write sync_progress: %w", err)
 	}
 
@@ -548,7 +474,7 @@ func (h *Handler) ForwardProgressTest(c *gin.Context) {
 		status = "reading"
 	}
 
-	if err := forwardProgressToTCP(tcpAddr, token, userID, body.MangaID, body.Chapter, status); err != nil {
+	if err := forwardProgressToTCP(tcpAddr, logger.RequestIDFromContext(c), token, userID, body.MangaID, body.Chapter, status); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
 		return
 	}
@@ -569,26 +495,9 @@ func (h *Handler) GetProgress(c *gin.Context) {
 		return
 	}
 
-	query := `
-		SELECT current_chapter, status, user_rating, updated_at
-		FROM user_progress
-		WHERE user_id = ? AND manga_id = ?
-	`
-
-	var currentChapter int
-	var status string
-	var userRating sql.NullFloat64
-	var updatedAt time.Time
-
-	err := database.DB.QueryRow(query, userID, mangaID).Scan(
-		&currentChapter,
-		&status,
-		&userRating,
-		&updatedAt,
-	)
-
+	progress, err := repository.Default().Progress.Get(c.Request.Context(), userID, mangaID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, repository.ErrNotInLibrary) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Manga not in library"})
 			return
 		}
@@ -596,21 +505,13 @@ func (h *Handler) GetProgress(c *gin.Context) {
 		return
 	}
 
-	response := gin.H{
+	c.JSON(http.StatusOK, gin.H{
 		"manga_id":        mangaID,
-		"current_chapter": currentChapter,
-		"status":          status,
-		"updated_at":      updatedAt,
-	}
-
-	// Add user_rating if it exists
-	if userRating.Valid {
-		response["user_rating"] = userRating.Float64
-	} else {
-		response["user_rating"] = nil
-	}
-
-	c.JSON(http.StatusOK, response)
+		"current_chapter": progress.CurrentChapter,
+		"status":          progress.Status,
+		"user_rating":     progress.UserRating,
+		"updated_at":      progress.UpdatedAt,
+	})
 }
 
 // RemoveFromLibrary removes manga from user's library
@@ -621,6 +522,9 @@ func (h *Handler) RemoveFromLibrary(c *gin.Context) {
 		return
 	}
 
+	requestID := logger.RequestIDFromContext(c)
+	reqLog := logger.ForContext(c).WithContext("user_id", userID)
+
 	mangaID := c.Param("manga_id")
 	if mangaID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Manga ID is required"})
@@ -638,31 +542,28 @@ func (h *Handler) RemoveFromLibrary(c *gin.Context) {
 		tcpAddr := resolveTCPAddr()
 		token := c.GetHeader("Authorization")
 		token = strings.TrimPrefix(token, "Bearer ")
-		if err := forwardRemoveFromLibraryToTCP(tcpAddr, token, mangaID); err != nil {
-			log.Printf("[ERROR] TCP remove_from_library required but failed: %v", err)
+		if err := forwardRemoveFromLibraryToTCP(tcpAddr, requestID, token, mangaID); err != nil {
+			reqLog.Error("tcp_forward_required_failed", "operation", "remove_from_library", "error", err.Error())
 			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "TCP forward failed; TCP server unavailable"})
 			return
 		}
 	} else {
 		// Local DB removal (non-strict)
-		query := `DELETE FROM user_progress WHERE user_id = ? AND manga_id = ?`
-		result, err := database.DB.Exec(query, userID, mangaID)
-		if err != nil {
+		if err := repository.Default().Progress.Remove(c.Request.Context(), userID, mangaID); err != nil {
+			if errors.Is(err, repository.ErrNotInLibrary) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Manga not in library"})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove manga"})
 			return
 		}
-		rowsAffected, _ := result.RowsAffected()
-		if rowsAffected == 0 {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Manga not in library"})
-			return
-		}
 		if tcpForwardEnabled {
 			tcpAddr := resolveTCPAddr()
 			token := c.GetHeader("Authorization")
 			token = strings.TrimPrefix(token, "Bearer ")
 			go func() {
-				if err := forwardRemoveFromLibraryToTCP(tcpAddr, token, mangaID); err != nil {
-					log.Printf("[WARN] TCP remove_from_library forward failed: %v", err)
+				if err := forwardRemoveFromLibraryToTCP(tcpAddr, requestID, token, mangaID); err != nil {
+					reqLog.Warn("tcp_forward_failed", "operation", "remove_from_library", "error", err.Error())
 				}
 			}()
 		}
@@ -672,8 +573,8 @@ func (h *Handler) RemoveFromLibrary(c *gin.Context) {
 	if strictUDPForward {
 		// Require UDP to remove
 		udpAddr := resolveUDPAddr()
-		if err := forwardRemoveFromLibraryToUDP(udpAddr, userID, mangaID); err != nil {
-			log.Printf("[ERROR] UDP remove_from_library required but failed: %v", err)
+		if err := forwardRemoveFromLibraryToUDP(udpAddr, requestID, userID, mangaID); err != nil {
+			reqLog.Error("udp_forward_required_failed", "operation", "remove_from_library", "error", err.Error())
 			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "UDP forward failed; UDP server unavailable"})
 			return
 		}
@@ -681,33 +582,34 @@ func (h *Handler) RemoveFromLibrary(c *gin.Context) {
 		// Non-strict forward (optional)
 		udpAddr := resolveUDPAddr()
 		go func() {
-			if err := forwardRemoveFromLibraryToUDP(udpAddr, userID, mangaID); err != nil {
-				log.Printf("[WARN] UDP remove_from_library forward failed: %v", err)
+			if err := forwardRemoveFromLibraryToUDP(udpAddr, requestID, userID, mangaID); err != nil {
+				reqLog.Warn("udp_forward_failed", "operation", "remove_from_library", "error", err.Error())
 			}
 		}()
 	}
 
 	h.bridge.NotifyLibraryUpdate(bridge.LibraryUpdateEvent{
-		UserID:  userID,
-		MangaID: mangaID,
-		Action:  "removed",
+		UserID:    userID,
+		MangaID:   mangaID,
+		Action:    "removed",
+		RequestID: requestID,
 	})
 
 	c.JSON(http.StatusOK, gin.H{"message": "Manga removed from library successfully"})
 }
 
 // forwardAddToLibraryToTCP sends auth + add_to_library
-func forwardAddToLibraryToTCP(tcpAddr, jwtToken, mangaID, status string) error {
+func forwardAddToLibraryToTCP(tcpAddr, requestID, jwtToken, mangaID, status string) error {
 	conn, err := net.DialTimeout("tcp", tcpAddr, 2*time.Second)
 	if err != nil {
 		return fmt.Errorf("dial tcp: %w", err)
 	}
 	defer conn.Close()
 	w := bufio.NewWriter(conn)
-	if _, err := fmt.Fprintf(w, `{"type":"auth","payload":{"token":"%s"}}`+"\n", jwtToken); err != nil {
+	if err := writeTCPMessage(w, "auth", requestID, map[string]interface{}{"token": jwtToken}); err != nil {
 		return fmt.Errorf("write auth: %w", err)
 	}
-	if _, err := fmt.Fprintf(w, `{"type":"add_to_library","payload":{"manga_id":"%s","status":"%s"}}`+"\n", mangaID, status); err != nil {
+	if err := writeTCPMessage(w, "add_to_library", requestID, map[string]interface{}{"manga_id": mangaID, "status": status}); err != nil {
 		return fmt.Errorf("write add_to_library: %w", err)
 	}
 	if err := w.Flush(); err != nil {
@@ -717,17 +619,17 @@ func forwardAddToLibraryToTCP(tcpAddr, jwtToken, mangaID, status string) error {
 }
 
 // forwardRemoveFromLibraryToTCP sends auth + remove_from_library
-func forwardRemoveFromLibraryToTCP(tcpAddr, jwtToken, mangaID string) error {
+func forwardRemoveFromLibraryToTCP(tcpAddr, requestID, jwtToken, mangaID string) error {
 	conn, err := net.DialTimeout("tcp", tcpAddr, 2*time.Second)
 	if err != nil {
 		return fmt.Errorf("dial tcp: %w", err)
 	}
 	defer conn.Close()
 	w := bufio.NewWriter(conn)
-	if _, err := fmt.Fprintf(w, `{"type":"auth","payload":{"token":"%s"}}`+"\n", jwtToken); err != nil {
+	if err := writeTCPMessage(w, "auth", requestID, map[string]interface{}{"token": jwtToken}); err != nil {
 		return fmt.Errorf("write auth: %w", err)
 	}
-	if _, err := fmt.Fprintf(w, `{"type":"remove_from_library","payload":{"manga_id":"%s"}}`+"\n", mangaID); err != nil {
+	if err := writeTCPMessage(w, "remove_from_library", requestID, map[string]interface{}{"manga_id": mangaID}); err != nil {
 		return fmt.Errorf("write remove_from_library: %w", err)
 	}
 	if err := w.Flush(); err != nil {
@@ -737,7 +639,7 @@ func forwardRemoveFromLibraryToTCP(tcpAddr, jwtToken, mangaID string) error {
 }
 
 // forwardProgressToUDP sends a UDP notification about progress update
-func forwardProgressToUDP(udpAddr, userID, mangaID string, chapter int, status string, rating float64) error {
+func forwardProgressToUDP(udpAddr, requestID, userID, mangaID string, chapter int, status string, rating float64) error {
 	addr, err := net.ResolveUDPAddr("udp", udpAddr)
 	if err != nil {
 		return fmt.Errorf("resolve udp addr: %w", err)
@@ -761,20 +663,8 @@ func forwardProgressToUDP(udpAddr, userID, mangaID string, chapter int, status s
 		data["rating"] = rating
 	}
 
-	msg := map[string]interface{}{
-		"type":       "notification",
-		"event_type": "progress_update",
-		"user_id":    userID,
-		"data":       data,
-		"timestamp":  time.Now().Format(time.RFC3339),
-	}
-
-	jsonData, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("marshal json: %w", err)
-	}
-
-	_, err = conn.Write(jsonData)
+	secret, _ := config.LoadJWTSecret()
+	_, err = conn.Write(udp.CreateSignedNotificationMessage(secret, userID, "progress_update", requestID, data))
 	if err != nil {
 		return fmt.Errorf("write udp: %w", err)
 	}
@@ -783,7 +673,7 @@ func forwardProgressToUDP(udpAddr, userID, mangaID string, chapter int, status s
 }
 
 // forwardAddToLibraryToUDP sends a UDP notification about add to library
-func forwardAddToLibraryToUDP(udpAddr, userID, mangaID, status string) error {
+func forwardAddToLibraryToUDP(udpAddr, requestID, userID, mangaID, status string) error {
 	addr, err := net.ResolveUDPAddr("udp", udpAddr)
 	if err != nil {
 		return fmt.Errorf("resolve udp addr: %w", err)
@@ -798,24 +688,13 @@ func forwardAddToLibraryToUDP(udpAddr, userID, mangaID, status string) error {
 	conn.SetWriteDeadline(time.Now().Add(2 * time.Second))
 
 	// Create notification message
-	msg := map[string]interface{}{
-		"type":       "notification",
-		"event_type": "library_update",
-		"user_id":    userID,
-		"data": map[string]interface{}{
-			"manga_id": mangaID,
-			"status":   status,
-			"action":   "add",
-		},
-		"timestamp": time.Now().Format(time.RFC3339),
-	}
-
-	jsonData, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("marshal json: %w", err)
+	data := map[string]interface{}{
+		"manga_id": mangaID,
+		"status":   status,
+		"action":   "add",
 	}
-
-	_, err = conn.Write(jsonData)
+	secret, _ := config.LoadJWTSecret()
+	_, err = conn.Write(udp.CreateSignedNotificationMessage(secret, userID, "library_update", requestID, data))
 	if err != nil {
 		return fmt.Errorf("write udp: %w", err)
 	}
@@ -824,7 +703,7 @@ func forwardAddToLibraryToUDP(udpAddr, userID, mangaID, status string) error {
 }
 
 // forwardRemoveFromLibraryToUDP sends a UDP notification about remove from library
-func forwardRemoveFromLibraryToUDP(udpAddr, userID, mangaID string) error {
+func forwardRemoveFromLibraryToUDP(udpAddr, requestID, userID, mangaID string) error {
 	addr, err := net.ResolveUDPAddr("udp", udpAddr)
 	if err != nil {
 		return fmt.Errorf("resolve udp addr: %w", err)
@@ -839,23 +718,12 @@ func forwardRemoveFromLibraryToUDP(udpAddr, userID, mangaID string) error {
 	conn.SetWriteDeadline(time.Now().Add(2 * time.Second))
 
 	// Create notification message
-	msg := map[string]interface{}{
-		"type":       "notification",
-		"event_type": "library_update",
-		"user_id":    userID,
-		"data": map[string]interface{}{
-			"manga_id": mangaID,
-			"action":   "remove",
-		},
-		"timestamp": time.Now().Format(time.RFC3339),
-	}
-
-	jsonData, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("marshal json: %w", err)
+	data := map[string]interface{}{
+		"manga_id": mangaID,
+		"action":   "remove",
 	}
-
-	_, err = conn.Write(jsonData)
+	secret, _ := config.LoadJWTSecret()
+	_, err = conn.Write(udp.CreateSignedNotificationMessage(secret, userID, "library_update", requestID, data))
 	if err != nil {
 		return fmt.Errorf("write udp: %w", err)
 	}
@@ -0,0 +1,78 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+// SetFavorite flags or unflags a library entry as a favorite
+func (h *Handler) SetFavorite(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.SetFavoriteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	mangaID := c.Param("manga_id")
+	err := repository.Default().Progress.SetFavorite(c.Request.Context(), userID, mangaID, req.Favorite)
+	switch {
+	case errors.Is(err, repository.ErrNotInLibrary):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Manga not in library"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update favorite"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"manga_id": mangaID, "is_favorite": req.Favorite})
+}
+
+// SetTags replaces the tags on a library entry
+func (h *Handler) SetTags(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.SetTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	mangaID := c.Param("manga_id")
+	tags, err := repository.Default().Progress.SetTags(c.Request.Context(), userID, mangaID, req.Tags)
+	switch {
+	case errors.Is(err, repository.ErrNotInLibrary):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Manga not in library"})
+		return
+	case errors.Is(err, repository.ErrInvalidTag):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"manga_id": mangaID, "tags": tags})
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package user
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultStatsWeeks = 12
+	maxStatsWeeks     = 52
+)
+
+// GetStats returns aggregate reading statistics for the current user (?weeks=, default 12)
+func (h *Handler) GetStats(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	weeks, err := strconv.Atoi(c.DefaultQuery("weeks", strconv.Itoa(defaultStatsWeeks)))
+	if err != nil || weeks <= 0 {
+		weeks = defaultStatsWeeks
+	}
+	if weeks > maxStatsWeeks {
+		weeks = maxStatsWeeks
+	}
+
+	store := repository.Default()
+	library, err := store.Progress.Library(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	history, err := store.Progress.History(c.Request.Context(), userID, time.Time{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ComputeReadingStats(library, history, time.Now(), weeks))
+}
+
+// ComputeReadingStats aggregates a library and its reading history. Entries
+// with no history (progress saved before history was recorded) count their
+// current chapter as read when they were last updated.
+func ComputeReadingStats(library []models.MangaProgress, history []models.ReadingEvent, now time.Time, weeks int) models.ReadingStats {
+	stats := models.ReadingStats{
+		ByStatus: map[string]int{
+			repository.StatusReading:    0,
+			repository.StatusCompleted:  0,
+			repository.StatusPlanToRead: 0,
+		},
+		RatingDistribution: map[string]int{"1": 0, "2": 0, "3": 0, "4": 0, "5": 0},
+		Genres:             []models.GenreCount{},
+		HistoryAvailable:   len(history) > 0,
+	}
+
+	hasHistory := make(map[string]bool)
+	for _, e := range history {
+		hasHistory[e.MangaID] = true
+	}
+	events := append([]models.ReadingEvent{}, history...)
+
+	genres := make(map[string]int)
+	var ratingSum float64
+	for _, mp := range library {
+		stats.TotalManga++
+		stats.ByStatus[mp.Status]++
+		stats.TotalChaptersRead += mp.CurrentChapter
+		for _, g := range mp.Manga.Genres {
+			genres[g]++
+		}
+		if mp.UserRating != nil {
+			stats.RatedCount++
+			ratingSum += *mp.UserRating
+			stats.RatingDistribution[strconv.Itoa(int(math.Round(*mp.UserRating)))]++
+		}
+		if !hasHistory[mp.Manga.ID] && mp.CurrentChapter > 0 && !mp.UpdatedAt.IsZero() {
+			events = append(events, models.ReadingEvent{
+				MangaID:      mp.Manga.ID,
+				Chapter:      mp.CurrentChapter,
+				ChaptersRead: mp.CurrentChapter,
+				ReadAt:       mp.UpdatedAt,
+			})
+		}
+	}
+
+	if started := stats.TotalManga - stats.ByStatus[repository.StatusPlanToRead]; started > 0 {
+		stats.CompletionRate = round(float64(stats.ByStatus[repository.StatusCompleted])/float64(started), 3)
+	}
+	if stats.RatedCount > 0 {
+		stats.AverageRating = round(ratingSum/float64(stats.RatedCount), 2)
+	}
+
+	for g, n := range genres {
+		stats.Genres = append(stats.Genres, models.GenreCount{Genre: g, Count: n})
+	}
+	sort.Slice(stats.Genres, func(i, j int) bool {
+		if stats.Genres[i].Count != stats.Genres[j].Count {
+			return stats.Genres[i].Count > stats.Genres[j].Count
+		}
+		return stats.Genres[i].Genre < stats.Genres[j].Genre
+	})
+
+	// Weekly buckets, oldest first, ending with the week containing now
+	currentWeek := weekStart(now)
+	stats.ChaptersPerWeek = make([]models.WeeklyChapters, weeks)
+	for i := range stats.ChaptersPerWeek {
+		start := currentWeek.AddDate(0, 0, -7*(weeks-1-i))
+		stats.ChaptersPerWeek[i].WeekStart = start.Format("2006-01-02")
+	}
+	firstWeek := currentWeek.AddDate(0, 0, -7*(weeks-1))
+
+	days := make(map[string]bool)
+	for _, e := range events {
+		readAt := e.ReadAt.UTC()
+		days[readAt.Format("2006-01-02")] = true
+		if stats.LastReadAt == nil || readAt.After(*stats.LastReadAt) {
+			t := readAt
+			stats.LastReadAt = &t
+		}
+		if readAt.Before(firstWeek) || readAt.After(now) {
+			continue
+		}
+		idx := int(weekStart(readAt).Sub(firstWeek).Hours() / (24 * 7))
+		if idx >= 0 && idx < weeks {
+			stats.ChaptersPerWeek[idx].Chapters += e.ChaptersRead
+		}
+	}
+
+	stats.CurrentStreakDays, stats.LongestStreakDays = streaks(days, now)
+	return stats
+}
+
+// streaks returns the run of reading days ending today (or yesterday, so a
+// streak isn't lost before the day is over) and the longest run overall
+func streaks(days map[string]bool, now time.Time) (current, longest int) {
+	if len(days) == 0 {
+		return 0, 0
+	}
+
+	dates := make([]string, 0, len(days))
+	for d := range days {
+		dates = append(dates, d)
+	}
+	sort.Strings(dates)
+
+	run := 0
+	var prev time.Time
+	for _, d := range dates {
+		day, _ := time.Parse("2006-01-02", d)
+		if run > 0 && day.Sub(prev) == 24*time.Hour {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+		prev = day
+	}
+
+	today := now.UTC().Truncate(24 * time.Hour)
+	day := today
+	if !days[day.Format("2006-01-02")] {
+		day = day.AddDate(0, 0, -1)
+	}
+	for days[day.Format("2006-01-02")] {
+		current++
+		day = day.AddDate(0, 0, -1)
+	}
+	return current, longest
+}
+
+// weekStart returns midnight UTC on the Monday of t's week
+func weekStart(t time.Time) time.Time {
+	t = t.UTC().Truncate(24 * time.Hour)
+	offset := (int(t.Weekday()) + 6) % 7
+	return t.AddDate(0, 0, -offset)
+}
+
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
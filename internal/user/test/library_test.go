@@ -0,0 +1,73 @@
+package user_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/user"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestLibraryFavoritesAndTagFilter(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	repository.SetDefault(store)
+	t.Cleanup(func() { repository.SetDefault(nil) })
+
+	for _, id := range []string{"m1", "m2", "m3"} {
+		store.Manga.Create(ctx, &models.Manga{ID: id, Title: id})
+		store.Progress.AddToLibrary(ctx, "u1", id, "")
+	}
+
+	gin.SetMode(gin.TestMode)
+	h := user.NewHandler(nil)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", c.GetHeader("X-Test-User")) })
+	router.GET("/users/library", h.GetLibrary)
+	router.PUT("/users/library/:manga_id/favorite", h.SetFavorite)
+	router.PUT("/users/library/:manga_id/tags", h.SetTags)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", "u1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("PUT", "/users/library/m1/tags", `{"tags": ["Reread", "dark"]}`); w.Code != http.StatusOK {
+		t.Fatalf("set tags status = %d (%s)", w.Code, w.Body.String())
+	}
+	do("PUT", "/users/library/m2/tags", `{"tags": ["dark"]}`)
+	if w := do("PUT", "/users/library/m2/favorite", `{"favorite": true}`); w.Code != http.StatusOK {
+		t.Fatalf("favorite status = %d (%s)", w.Code, w.Body.String())
+	}
+	if w := do("PUT", "/users/library/missing/favorite", `{"favorite": true}`); w.Code != http.StatusNotFound {
+		t.Errorf("favorite outside library status = %d, want 404", w.Code)
+	}
+
+	count := func(query string) int {
+		var library models.UserLibrary
+		json.Unmarshal(do("GET", "/users/library"+query, "").Body.Bytes(), &library)
+		return len(library.Reading) + len(library.Completed) + len(library.PlanToRead)
+	}
+	if n := count(""); n != 3 {
+		t.Errorf("unfiltered library has %d entries, want 3", n)
+	}
+	if n := count("?tag=DARK"); n != 2 {
+		t.Errorf("tag filter returned %d entries, want 2", n)
+	}
+	if n := count("?tag=reread&favorite=true"); n != 0 {
+		t.Errorf("combined filter returned %d entries, want 0", n)
+	}
+	if n := count("?favorite=true"); n != 1 {
+		t.Errorf("favorite filter returned %d entries, want 1", n)
+	}
+}
@@ -0,0 +1,120 @@
+package user_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/user"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+func ratingPtr(v float64) *float64 { return &v }
+
+func TestComputeReadingStats(t *testing.T) {
+	// Wednesday; the current week starts Monday 2026-10-12
+	now := time.Date(2026, 10, 14, 18, 0, 0, 0, time.UTC)
+	day := func(offset int) time.Time { return now.AddDate(0, 0, offset) }
+
+	library := []models.MangaProgress{
+		{Manga: models.Manga{ID: "m1", Genres: []string{"Action", "Drama"}}, CurrentChapter: 20, Status: "reading", UserRating: ratingPtr(4)},
+		{Manga: models.Manga{ID: "m2", Genres: []string{"Action"}}, CurrentChapter: 10, Status: "completed", UserRating: ratingPtr(5)},
+		{Manga: models.Manga{ID: "m3", Genres: []string{"Comedy"}}, Status: "plan_to_read"},
+		// Saved before history was recorded: counted at its update time
+		{Manga: models.Manga{ID: "m4", Genres: []string{"Drama"}}, CurrentChapter: 7, Status: "completed", UpdatedAt: day(-21)},
+	}
+	history := []models.ReadingEvent{
+		{MangaID: "m1", Chapter: 12, ChaptersRead: 12, ReadAt: day(-9)},
+		{MangaID: "m2", Chapter: 10, ChaptersRead: 10, ReadAt: day(-3)},
+		{MangaID: "m1", Chapter: 15, ChaptersRead: 3, ReadAt: day(-2)},
+		{MangaID: "m1", Chapter: 18, ChaptersRead: 3, ReadAt: day(-1)},
+		{MangaID: "m1", Chapter: 20, ChaptersRead: 2, ReadAt: day(0)},
+		{MangaID: "m1", Chapter: 2, ChaptersRead: 2, ReadAt: day(-200)}, // outside the window
+	}
+
+	stats := user.ComputeReadingStats(library, history, now, 4)
+
+	if stats.TotalManga != 4 || stats.ByStatus["completed"] != 2 || stats.ByStatus["plan_to_read"] != 1 {
+		t.Errorf("unexpected counts %+v", stats.ByStatus)
+	}
+	if stats.CompletionRate != 0.667 {
+		t.Errorf("completion rate = %v, want 0.667", stats.CompletionRate)
+	}
+	if stats.TotalChaptersRead != 37 || stats.AverageRating != 4.5 || stats.RatedCount != 2 {
+		t.Errorf("unexpected totals: chapters %d, rating %v over %d", stats.TotalChaptersRead, stats.AverageRating, stats.RatedCount)
+	}
+	if stats.RatingDistribution["5"] != 1 || stats.RatingDistribution["4"] != 1 || stats.RatingDistribution["1"] != 0 {
+		t.Errorf("unexpected distribution %+v", stats.RatingDistribution)
+	}
+	if len(stats.Genres) != 3 || stats.Genres[0].Genre != "Action" || stats.Genres[0].Count != 2 || stats.Genres[1].Genre != "Drama" {
+		t.Errorf("unexpected genres %+v", stats.Genres)
+	}
+
+	want := []models.WeeklyChapters{
+		{WeekStart: "2026-09-21", Chapters: 7},
+		{WeekStart: "2026-09-28", Chapters: 0},
+		{WeekStart: "2026-10-05", Chapters: 12 + 10},
+		{WeekStart: "2026-10-12", Chapters: 3 + 3 + 2},
+	}
+	for i, w := range want {
+		if stats.ChaptersPerWeek[i] != w {
+			t.Errorf("week %d = %+v, want %+v", i, stats.ChaptersPerWeek[i], w)
+		}
+	}
+
+	if stats.CurrentStreakDays != 4 || stats.LongestStreakDays != 4 {
+		t.Errorf("streaks = %d/%d, want 4/4", stats.CurrentStreakDays, stats.LongestStreakDays)
+	}
+	if !stats.HistoryAvailable || stats.LastReadAt == nil || !stats.LastReadAt.Equal(now) {
+		t.Errorf("unexpected history flags %v %v", stats.HistoryAvailable, stats.LastReadAt)
+	}
+
+	// A streak survives until the end of the next day
+	if s := user.ComputeReadingStats(library, history, day(1), 4); s.CurrentStreakDays != 4 {
+		t.Errorf("streak the day after = %d, want 4", s.CurrentStreakDays)
+	}
+	if s := user.ComputeReadingStats(library, history, day(2), 4); s.CurrentStreakDays != 0 {
+		t.Errorf("streak after a missed day = %d, want 0", s.CurrentStreakDays)
+	}
+}
+
+func TestGetStatsRecordsProgress(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	repository.SetDefault(store)
+	t.Cleanup(func() { repository.SetDefault(nil) })
+
+	store.Manga.Create(ctx, &models.Manga{ID: "m1", Title: "Berserk", Genres: []string{"Action"}, TotalChapters: 100})
+	store.Progress.AddToLibrary(ctx, "u1", "m1", "")
+	for _, chapter := range []int{5, 12, 12} {
+		ch := chapter
+		store.Progress.Update(ctx, "u1", models.UpdateProgressRequest{MangaID: "m1", CurrentChapter: &ch})
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", c.GetHeader("X-Test-User")) })
+	router.GET("/users/stats", user.NewHandler(nil).GetStats)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/stats?weeks=2", nil)
+	req.Header.Set("X-Test-User", "u1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s)", w.Code, w.Body.String())
+	}
+
+	var stats models.ReadingStats
+	json.Unmarshal(w.Body.Bytes(), &stats)
+	if len(stats.ChaptersPerWeek) != 2 || stats.ChaptersPerWeek[1].Chapters != 12 {
+		t.Errorf("expected 12 chapters this week, got %+v", stats.ChaptersPerWeek)
+	}
+	if !stats.HistoryAvailable || stats.CurrentStreakDays != 1 || stats.ByStatus["reading"] != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,308 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+const (
+	SignatureHeader = "X-MangaHub-Signature"
+	EventHeader     = "X-MangaHub-Event"
+	DeliveryHeader  = "X-MangaHub-Delivery"
+	TimestampHeader = "X-MangaHub-Timestamp"
+)
+
+// SupportedEventTypes are the bridge events that can be delivered to webhooks
+var SupportedEventTypes = map[string]bool{
+	string(bridge.EventProgressUpdate): true,
+	string(bridge.EventLibraryUpdate):  true,
+	string(bridge.EventChapterRelease): true,
+	string(bridge.EventMangaCreated):   true,
+}
+
+type DispatcherConfig struct {
+	Workers     int
+	QueueSize   int
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	Timeout     time.Duration
+	// AllowedNetworks are internal networks deliveries may still reach;
+	// every other loopback, private or link-local address is refused
+	AllowedNetworks []*net.IPNet
+}
+
+func DefaultDispatcherConfig() DispatcherConfig {
+	return DispatcherConfig{
+		Workers:     4,
+		QueueSize:   500,
+		MaxAttempts: 5,
+		BaseBackoff: time.Second,
+		MaxBackoff:  time.Minute,
+		Timeout:     10 * time.Second,
+
+		AllowedNetworks: AllowedNetworksFromEnv(),
+	}
+}
+
+// Payload is the JSON body posted to webhook endpoints
+type Payload struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	UserID    string                 `json:"user_id,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+type job struct {
+	hook    models.Webhook
+	eventID string
+	typ     string
+	body    []byte
+}
+
+// Dispatcher delivers bridge events to registered webhooks. It is meant to be
+// registered with UnifiedBridge.Subscribe.
+type Dispatcher struct {
+	store    *Store
+	logger   *logger.Logger
+	cfg      DispatcherConfig
+	client   *http.Client
+	jobs     chan job
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+func NewDispatcher(store *Store, log *logger.Logger, cfg DispatcherConfig) *Dispatcher {
+	defaults := DefaultDispatcherConfig()
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaults.Workers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaults.QueueSize
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaults.MaxAttempts
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = defaults.BaseBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaults.MaxBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	return &Dispatcher{
+		store:    store,
+		logger:   log,
+		cfg:      cfg,
+		client:   newDeliveryClient(cfg.Timeout, cfg.AllowedNetworks),
+		jobs:     make(chan job, cfg.QueueSize),
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (d *Dispatcher) Start() {
+	for i := 0; i < d.cfg.Workers; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+	d.logger.Info("webhook_dispatcher_started", "workers", d.cfg.Workers)
+}
+
+func (d *Dispatcher) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stopChan)
+	})
+	d.wg.Wait()
+	d.logger.Info("webhook_dispatcher_stopped")
+}
+
+// HandleEvent matches the event against registered webhooks and queues a
+// delivery for each match. It satisfies bridge.EventHandler.
+func (d *Dispatcher) HandleEvent(event bridge.UnifiedEvent) error {
+	if !SupportedEventTypes[string(event.Type)] {
+		return nil
+	}
+
+	hooks, err := d.store.ListActive()
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(Payload{
+		ID:        event.ID,
+		Type:      string(event.Type),
+		UserID:    event.UserID,
+		Timestamp: event.Timestamp,
+		Data:      event.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	for _, hook := range hooks {
+		if !Matches(hook, event) {
+			continue
+		}
+		select {
+		case d.jobs <- job{hook: hook, eventID: event.ID, typ: string(event.Type), body: body}:
+		default:
+			d.logger.Warn("webhook_queue_full", "webhook_id", hook.ID, "event_id", event.ID)
+			d.deadLetter(job{hook: hook, eventID: event.ID, typ: string(event.Type), body: body}, 0, "delivery queue full")
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the webhook wants the event. Events without a user
+// (chapter releases, new manga) go to every subscriber of that type; user
+// events go to the owner or to admin hooks registered for all users.
+func Matches(hook models.Webhook, event bridge.UnifiedEvent) bool {
+	wanted := false
+	for _, t := range hook.EventTypes {
+		if t == string(event.Type) {
+			wanted = true
+			break
+		}
+	}
+	if !wanted {
+		return false
+	}
+	return event.UserID == "" || hook.AllUsers || hook.UserID == event.UserID
+}
+
+// Sign returns the hex HMAC-SHA256 of the timestamp and body
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *Dispatcher) worker() {
+	defer d.wg.Done()
+	for {
+		select {
+		case j := <-d.jobs:
+			d.deliver(j)
+		case <-d.stopChan:
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(j job) {
+	var lastErr string
+	for attempt := 1; attempt <= d.cfg.MaxAttempts; attempt++ {
+		statusCode, duration, err := d.send(j)
+
+		delivery := models.WebhookDelivery{
+			WebhookID:  j.hook.ID,
+			EventID:    j.eventID,
+			EventType:  j.typ,
+			Attempt:    attempt,
+			StatusCode: statusCode,
+			Success:    err == nil,
+			DurationMs: duration.Milliseconds(),
+		}
+		if err != nil {
+			delivery.Error = err.Error()
+			lastErr = err.Error()
+		}
+		if recErr := d.store.RecordDelivery(delivery); recErr != nil {
+			d.logger.Warn("webhook_delivery_log_failed", "webhook_id", j.hook.ID, "error", recErr.Error())
+		}
+
+		if err == nil {
+			d.logger.Debug("webhook_delivered", "webhook_id", j.hook.ID, "event_id", j.eventID, "attempt", attempt)
+			return
+		}
+
+		d.logger.Warn("webhook_delivery_failed",
+			"webhook_id", j.hook.ID,
+			"event_id", j.eventID,
+			"attempt", attempt,
+			"error", err.Error())
+
+		if attempt == d.cfg.MaxAttempts {
+			break
+		}
+		select {
+		case <-time.After(d.backoff(attempt)):
+		case <-d.stopChan:
+			d.deadLetter(j, attempt, "dispatcher stopped: "+lastErr)
+			return
+		}
+	}
+	d.deadLetter(j, d.cfg.MaxAttempts, lastErr)
+}
+
+func (d *Dispatcher) send(j job) (int, time.Duration, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequest(http.MethodPost, j.hook.URL, bytes.NewReader(j.body))
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "MangaHub-Webhooks/1.0")
+	req.Header.Set(EventHeader, j.typ)
+	req.Header.Set(DeliveryHeader, j.eventID)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(j.hook.Secret, timestamp, j.body))
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	duration := time.Since(start)
+	if err != nil {
+		return 0, duration, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, duration, fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, duration, nil
+}
+
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	wait := d.cfg.BaseBackoff << (attempt - 1)
+	if wait <= 0 || wait > d.cfg.MaxBackoff {
+		wait = d.cfg.MaxBackoff
+	}
+	return wait
+}
+
+func (d *Dispatcher) deadLetter(j job, attempts int, lastErr string) {
+	err := d.store.AddDeadLetter(models.WebhookDeadLetter{
+		WebhookID: j.hook.ID,
+		EventID:   j.eventID,
+		EventType: j.typ,
+		Payload:   string(j.body),
+		LastError: lastErr,
+		Attempts:  attempts,
+	})
+	if err != nil {
+		d.logger.Error("webhook_dead_letter_failed", "webhook_id", j.hook.ID, "error", err.Error())
+		return
+	}
+	d.logger.Warn("webhook_dead_lettered", "webhook_id", j.hook.ID, "event_id", j.eventID, "attempts", attempts)
+}
@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned when a webhook URL points at, or resolves
+// to, an address inside the server's own network
+var ErrBlockedAddress = errors.New("webhook target address is not allowed")
+
+// ParseNetworks reads a comma separated list of CIDRs or bare IPs
+func ParseNetworks(list string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %w", entry, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// AllowedNetworksFromEnv reads WEBHOOK_ALLOWED_NETWORKS, the internal
+// networks webhooks may still be delivered to. Invalid entries are dropped.
+func AllowedNetworksFromEnv() []*net.IPNet {
+	nets, err := ParseNetworks(os.Getenv("WEBHOOK_ALLOWED_NETWORKS"))
+	if err != nil {
+		return nil
+	}
+	return nets
+}
+
+// addressAllowed refuses loopback, private, link-local, multicast and
+// unspecified addresses unless one of the allowed networks contains them
+func addressAllowed(ip net.IP, allowed []*net.IPNet) bool {
+	for _, n := range allowed {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// checkHost resolves a URL host and fails if any of its addresses is blocked
+func checkHost(ctx context.Context, host string, allowed []*net.IPNet) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !addressAllowed(ip, allowed) {
+			return ErrBlockedAddress
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !addressAllowed(addr.IP, allowed) {
+			return ErrBlockedAddress
+		}
+	}
+	return nil
+}
+
+// newDeliveryClient builds the client deliveries go out on. The address is
+// checked after DNS resolution, on the socket about to connect, so a name
+// that changes what it resolves to after registration is still caught.
+// Proxies are bypassed for the same reason, and redirects are not followed;
+// the 3xx response counts as a failed delivery.
+func newDeliveryClient(timeout time.Duration, allowed []*net.IPNet) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !addressAllowed(ip, allowed) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
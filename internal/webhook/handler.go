@@ -0,0 +1,196 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler exposes webhook registration and the delivery log over HTTP
+type Handler struct {
+	store   *Store
+	allowed []*net.IPNet
+}
+
+func NewHandler(store *Store) *Handler {
+	return &Handler{store: store, allowed: AllowedNetworksFromEnv()}
+}
+
+// SetAllowedNetworks replaces the internal networks webhook URLs may point
+// at, which should match the dispatcher's
+func (h *Handler) SetAllowedNetworks(nets []*net.IPNet) {
+	h.allowed = nets
+}
+
+// CreateWebhook registers a new endpoint for the current user
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	parsed, err := url.Parse(req.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url must be an absolute http or https URL"})
+		return
+	}
+	if err := checkHost(c.Request.Context(), parsed.Hostname(), h.allowed); err != nil {
+		if errors.Is(err, ErrBlockedAddress) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "url must not point at an internal address"})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "url host could not be resolved"})
+		}
+		return
+	}
+
+	for _, t := range req.EventTypes {
+		if !SupportedEventTypes[t] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported event type: " + t})
+			return
+		}
+	}
+
+	if req.AllUsers && c.GetString("role") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required for all_users webhooks"})
+		return
+	}
+
+	hook, err := h.store.Create(userID, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, hook)
+}
+
+// ListWebhooks lists the current user's webhooks without their secrets
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	hooks, err := h.store.ListByUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list webhooks"})
+		return
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": hooks, "count": len(hooks)})
+}
+
+// DeleteWebhook removes one of the current user's webhooks
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.store.Delete(c.Param("id"), userID); err != nil {
+		if errors.Is(err, ErrWebhookNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+// GetDeliveries returns the delivery log of a webhook, newest first
+func (h *Handler) GetDeliveries(c *gin.Context) {
+	hook, ok := h.ownedWebhook(c)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.store.ListDeliveries(hook.ID, parseLimit(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries, "count": len(deliveries)})
+}
+
+// GetDeadLetters returns deliveries that exhausted their retries
+func (h *Handler) GetDeadLetters(c *gin.Context) {
+	hook, ok := h.ownedWebhook(c)
+	if !ok {
+		return
+	}
+
+	letters, err := h.store.ListDeadLetters(hook.ID, parseLimit(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load dead letters"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"dead_letters": letters, "count": len(letters)})
+}
+
+// ListAllDeadLetters returns dead letters across all webhooks (admin only)
+func (h *Handler) ListAllDeadLetters(c *gin.Context) {
+	letters, err := h.store.ListDeadLetters("", parseLimit(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load dead letters"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"dead_letters": letters, "count": len(letters)})
+}
+
+// ownedWebhook loads the :id webhook and checks the caller may see it
+func (h *Handler) ownedWebhook(c *gin.Context) (*models.Webhook, bool) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	hook, err := h.store.Get(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, ErrWebhookNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load webhook"})
+		return nil, false
+	}
+
+	if hook.UserID != userID && c.GetString("role") != "admin" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return nil, false
+	}
+	return hook, true
+}
+
+func parseLimit(c *gin.Context) int {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		return 50
+	}
+	if limit > 500 {
+		return 500
+	}
+	return limit
+}
@@ -0,0 +1,187 @@
+package webhook
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// Store persists webhooks, their delivery log and dead letters
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) Create(userID string, req models.CreateWebhookRequest) (*models.Webhook, error) {
+	id, err := utils.GenerateID(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate webhook id: %w", err)
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = utils.GenerateID(32); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+	}
+
+	hook := &models.Webhook{
+		ID:         id,
+		UserID:     userID,
+		URL:        req.URL,
+		Secret:     secret,
+		EventTypes: req.EventTypes,
+		AllUsers:   req.AllUsers,
+		Active:     true,
+		CreatedAt:  time.Now(),
+	}
+
+	_, err = s.db.Exec(`INSERT INTO webhooks (id, user_id, url, secret, event_types, all_users, active, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, 1, ?)`,
+		hook.ID, hook.UserID, hook.URL, hook.Secret, strings.Join(hook.EventTypes, ","), hook.AllUsers, hook.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return hook, nil
+}
+
+func (s *Store) Get(id string) (*models.Webhook, error) {
+	row := s.db.QueryRow(`SELECT id, user_id, url, secret, event_types, all_users, active, created_at
+		FROM webhooks WHERE id = ?`, id)
+	hook, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	return hook, err
+}
+
+func (s *Store) ListByUser(userID string) ([]models.Webhook, error) {
+	return s.query(`SELECT id, user_id, url, secret, event_types, all_users, active, created_at
+		FROM webhooks WHERE user_id = ? ORDER BY created_at DESC`, userID)
+}
+
+// ListActive returns every active webhook; the dispatcher filters them per event
+func (s *Store) ListActive() ([]models.Webhook, error) {
+	return s.query(`SELECT id, user_id, url, secret, event_types, all_users, active, created_at
+		FROM webhooks WHERE active = 1`)
+}
+
+func (s *Store) Delete(id, userID string) error {
+	res, err := s.db.Exec(`DELETE FROM webhooks WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (s *Store) RecordDelivery(d models.WebhookDelivery) error {
+	_, err := s.db.Exec(`INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, attempt, status_code, success, error, duration_ms, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.WebhookID, d.EventID, d.EventType, d.Attempt, d.StatusCode, d.Success, d.Error, d.DurationMs, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record delivery: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) ListDeliveries(webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := s.db.Query(`SELECT id, webhook_id, event_id, event_type, attempt, status_code, success, COALESCE(error, ''), duration_ms, created_at
+		FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ?`, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Attempt, &d.StatusCode, &d.Success, &d.Error, &d.DurationMs, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *Store) AddDeadLetter(dl models.WebhookDeadLetter) error {
+	_, err := s.db.Exec(`INSERT INTO webhook_dead_letters (webhook_id, event_id, event_type, payload, last_error, attempts, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		dl.WebhookID, dl.EventID, dl.EventType, dl.Payload, dl.LastError, dl.Attempts, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to add dead letter: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) ListDeadLetters(webhookID string, limit int) ([]models.WebhookDeadLetter, error) {
+	query := `SELECT id, webhook_id, event_id, event_type, payload, COALESCE(last_error, ''), attempts, created_at
+		FROM webhook_dead_letters`
+	args := []interface{}{}
+	if webhookID != "" {
+		query += ` WHERE webhook_id = ?`
+		args = append(args, webhookID)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	letters := []models.WebhookDeadLetter{}
+	for rows.Next() {
+		var dl models.WebhookDeadLetter
+		if err := rows.Scan(&dl.ID, &dl.WebhookID, &dl.EventID, &dl.EventType, &dl.Payload, &dl.LastError, &dl.Attempts, &dl.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter: %w", err)
+		}
+		letters = append(letters, dl)
+	}
+	return letters, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWebhook(row scanner) (*models.Webhook, error) {
+	var hook models.Webhook
+	var eventTypes string
+	if err := row.Scan(&hook.ID, &hook.UserID, &hook.URL, &hook.Secret, &eventTypes, &hook.AllUsers, &hook.Active, &hook.CreatedAt); err != nil {
+		return nil, err
+	}
+	hook.EventTypes = strings.Split(eventTypes, ",")
+	return &hook, nil
+}
+
+func (s *Store) query(query string, args ...interface{}) ([]models.Webhook, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []models.Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		hooks = append(hooks, *hook)
+	}
+	return hooks, rows.Err()
+}
@@ -0,0 +1,362 @@
+package webhook_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/webhook"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+type receiver struct {
+	server   *httptest.Server
+	mu       sync.Mutex
+	bodies   [][]byte
+	headers  []http.Header
+	calls    atomic.Int32
+	failures int32
+}
+
+// newReceiver returns a local endpoint that fails the first `failures` calls
+func newReceiver(t *testing.T, failures int32) *receiver {
+	r := &receiver{failures: failures}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := r.calls.Add(1)
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.bodies = append(r.bodies, body)
+		r.headers = append(r.headers, req.Header.Clone())
+		r.mu.Unlock()
+		if n <= r.failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func setupStore(t *testing.T) *webhook.Store {
+	if err := database.InitDatabase(t.TempDir() + "/webhooks.db"); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	_, err := database.DB.Exec(`INSERT INTO users (id, username, email, password_hash, role)
+		VALUES ('user-1', 'reader', 'reader@example.com', 'hash', 'user'),
+		       ('admin-1', 'admin', 'admin@example.com', 'hash', 'admin')`)
+	if err != nil {
+		t.Fatalf("failed to insert users: %v", err)
+	}
+	return webhook.NewStore(database.DB)
+}
+
+// loopback lets the dispatcher reach httptest servers
+var loopback, _ = webhook.ParseNetworks("127.0.0.0/8,::1")
+
+func newDispatcher(store *webhook.Store, maxAttempts int) *webhook.Dispatcher {
+	return newDispatcherAllowing(store, maxAttempts, loopback)
+}
+
+func newDispatcherAllowing(store *webhook.Store, maxAttempts int, allowed []*net.IPNet) *webhook.Dispatcher {
+	log := logger.New(logger.DEBUG, false, os.Stdout)
+	return webhook.NewDispatcher(store, log, webhook.DispatcherConfig{
+		Workers:         1,
+		MaxAttempts:     maxAttempts,
+		BaseBackoff:     10 * time.Millisecond,
+		MaxBackoff:      50 * time.Millisecond,
+		Timeout:         time.Second,
+		AllowedNetworks: allowed,
+	})
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDispatcherDeliversSignedPayload(t *testing.T) {
+	store := setupStore(t)
+	recv := newReceiver(t, 0)
+
+	hook, err := store.Create("user-1", models.CreateWebhookRequest{
+		URL:        recv.server.URL,
+		EventTypes: []string{"progress_update"},
+		Secret:     "shh",
+	})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	d := newDispatcher(store, 3)
+	d.Start()
+	defer d.Stop()
+
+	event := bridge.NewUnifiedEvent(bridge.EventProgressUpdate, "user-1", bridge.ProtocolTCP, map[string]interface{}{"manga_id": "m1", "chapter_id": 12})
+	if err := d.HandleEvent(event); err != nil {
+		t.Fatalf("handle event failed: %v", err)
+	}
+
+	waitFor(t, func() bool { return recv.calls.Load() == 1 })
+
+	recv.mu.Lock()
+	body, headers := recv.bodies[0], recv.headers[0]
+	recv.mu.Unlock()
+
+	expected := webhook.Sign("shh", headers.Get(webhook.TimestampHeader), body)
+	if headers.Get(webhook.SignatureHeader) != expected {
+		t.Errorf("signature mismatch: got %s want %s", headers.Get(webhook.SignatureHeader), expected)
+	}
+	if headers.Get(webhook.EventHeader) != "progress_update" {
+		t.Errorf("unexpected event header %q", headers.Get(webhook.EventHeader))
+	}
+
+	var payload webhook.Payload
+	json.Unmarshal(body, &payload)
+	if payload.ID != event.ID || payload.Data["manga_id"] != "m1" {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+
+	waitFor(t, func() bool {
+		deliveries, _ := store.ListDeliveries(hook.ID, 10)
+		return len(deliveries) == 1 && deliveries[0].Success
+	})
+}
+
+func TestDispatcherRetriesWithBackoff(t *testing.T) {
+	store := setupStore(t)
+	recv := newReceiver(t, 2)
+
+	hook, _ := store.Create("user-1", models.CreateWebhookRequest{URL: recv.server.URL, EventTypes: []string{"library_update"}})
+
+	d := newDispatcher(store, 5)
+	d.Start()
+	defer d.Stop()
+
+	d.HandleEvent(bridge.NewUnifiedEvent(bridge.EventLibraryUpdate, "user-1", bridge.ProtocolHTTP, map[string]interface{}{"action": "added"}))
+
+	waitFor(t, func() bool {
+		deliveries, _ := store.ListDeliveries(hook.ID, 10)
+		return len(deliveries) == 3
+	})
+
+	deliveries, _ := store.ListDeliveries(hook.ID, 10)
+	if !deliveries[0].Success || deliveries[0].Attempt != 3 {
+		t.Errorf("expected third attempt to succeed, got %+v", deliveries[0])
+	}
+	if deliveries[2].Success || deliveries[2].StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected first attempt to fail with 500, got %+v", deliveries[2])
+	}
+
+	letters, _ := store.ListDeadLetters(hook.ID, 10)
+	if len(letters) != 0 {
+		t.Errorf("expected no dead letters, got %d", len(letters))
+	}
+}
+
+func TestDispatcherDeadLettersAfterMaxAttempts(t *testing.T) {
+	store := setupStore(t)
+	recv := newReceiver(t, 100)
+
+	hook, _ := store.Create("user-1", models.CreateWebhookRequest{URL: recv.server.URL, EventTypes: []string{"chapter_release"}})
+
+	d := newDispatcher(store, 2)
+	d.Start()
+	defer d.Stop()
+
+	d.HandleEvent(bridge.NewUnifiedEvent(bridge.EventChapterRelease, "", bridge.ProtocolHTTP, map[string]interface{}{"manga_id": "m1"}))
+
+	waitFor(t, func() bool {
+		letters, _ := store.ListDeadLetters(hook.ID, 10)
+		return len(letters) == 1
+	})
+
+	letters, _ := store.ListDeadLetters(hook.ID, 10)
+	if letters[0].Attempts != 2 || letters[0].EventType != "chapter_release" {
+		t.Errorf("unexpected dead letter: %+v", letters[0])
+	}
+	if recv.calls.Load() != 2 {
+		t.Errorf("expected 2 attempts, got %d", recv.calls.Load())
+	}
+}
+
+func TestDispatcherRefusesInternalAddresses(t *testing.T) {
+	store := setupStore(t)
+	recv := newReceiver(t, 0)
+
+	hook, _ := store.Create("user-1", models.CreateWebhookRequest{URL: recv.server.URL, EventTypes: []string{"chapter_release"}})
+
+	d := newDispatcherAllowing(store, 1, nil)
+	d.Start()
+	defer d.Stop()
+
+	d.HandleEvent(bridge.NewUnifiedEvent(bridge.EventChapterRelease, "", bridge.ProtocolHTTP, map[string]interface{}{"manga_id": "m1"}))
+
+	waitFor(t, func() bool {
+		letters, _ := store.ListDeadLetters(hook.ID, 10)
+		return len(letters) == 1
+	})
+
+	letters, _ := store.ListDeadLetters(hook.ID, 10)
+	if !strings.Contains(letters[0].LastError, webhook.ErrBlockedAddress.Error()) {
+		t.Errorf("expected a blocked address error, got %q", letters[0].LastError)
+	}
+	if recv.calls.Load() != 0 {
+		t.Errorf("loopback endpoint was called %d times", recv.calls.Load())
+	}
+}
+
+func TestDispatcherDoesNotFollowRedirects(t *testing.T) {
+	store := setupStore(t)
+	target := newReceiver(t, 0)
+	redirector := httptest.NewServer(http.RedirectHandler(target.server.URL, http.StatusFound))
+	defer redirector.Close()
+
+	hook, _ := store.Create("user-1", models.CreateWebhookRequest{URL: redirector.URL, EventTypes: []string{"chapter_release"}})
+
+	d := newDispatcher(store, 1)
+	d.Start()
+	defer d.Stop()
+
+	d.HandleEvent(bridge.NewUnifiedEvent(bridge.EventChapterRelease, "", bridge.ProtocolHTTP, map[string]interface{}{"manga_id": "m1"}))
+
+	waitFor(t, func() bool {
+		deliveries, _ := store.ListDeliveries(hook.ID, 10)
+		return len(deliveries) == 1
+	})
+
+	deliveries, _ := store.ListDeliveries(hook.ID, 10)
+	if deliveries[0].Success || deliveries[0].StatusCode != http.StatusFound {
+		t.Errorf("expected a failed 302 delivery, got %+v", deliveries[0])
+	}
+	if target.calls.Load() != 0 {
+		t.Errorf("redirect was followed %d times", target.calls.Load())
+	}
+}
+
+func TestWebhookMatches(t *testing.T) {
+	userHook := models.Webhook{UserID: "user-1", EventTypes: []string{"progress_update", "chapter_release"}}
+	adminHook := models.Webhook{UserID: "admin-1", EventTypes: []string{"progress_update"}, AllUsers: true}
+
+	tests := []struct {
+		name  string
+		hook  models.Webhook
+		event bridge.UnifiedEvent
+		want  bool
+	}{
+		{"own event", userHook, bridge.NewUnifiedEvent(bridge.EventProgressUpdate, "user-1", bridge.ProtocolTCP, nil), true},
+		{"other user's event", userHook, bridge.NewUnifiedEvent(bridge.EventProgressUpdate, "user-2", bridge.ProtocolTCP, nil), false},
+		{"filtered type", userHook, bridge.NewUnifiedEvent(bridge.EventLibraryUpdate, "user-1", bridge.ProtocolTCP, nil), false},
+		{"global event", userHook, bridge.NewUnifiedEvent(bridge.EventChapterRelease, "", bridge.ProtocolHTTP, nil), true},
+		{"admin all users", adminHook, bridge.NewUnifiedEvent(bridge.EventProgressUpdate, "user-2", bridge.ProtocolTCP, nil), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := webhook.Matches(tt.hook, tt.event); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatcherSubscribedToBridge(t *testing.T) {
+	store := setupStore(t)
+	recv := newReceiver(t, 0)
+	store.Create("user-1", models.CreateWebhookRequest{URL: recv.server.URL, EventTypes: []string{"manga_created"}})
+
+	d := newDispatcher(store, 1)
+	d.Start()
+	defer d.Stop()
+
+	ub := bridge.NewUnifiedBridge(logger.New(logger.DEBUG, false, os.Stdout))
+	ub.Subscribe(d.HandleEvent)
+	ub.PublishEvent(bridge.NewUnifiedEvent(bridge.EventMangaCreated, "", bridge.ProtocolHTTP, map[string]interface{}{"title": "Berserk"}))
+
+	waitFor(t, func() bool { return recv.calls.Load() == 1 })
+}
+
+func TestCreateWebhookValidation(t *testing.T) {
+	store := setupStore(t)
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		c.Set("role", c.GetHeader("X-Test-Role"))
+	})
+	router.POST("/webhooks", webhook.NewHandler(store).CreateWebhook)
+
+	tests := []struct {
+		name string
+		user string
+		role string
+		body string
+		want int
+	}{
+		{"valid", "user-1", "user", `{"url":"http://93.184.216.34:9000/hook","event_types":["progress_update"]}`, http.StatusCreated},
+		{"bad scheme", "user-1", "user", `{"url":"ftp://example.com","event_types":["progress_update"]}`, http.StatusBadRequest},
+		{"loopback", "user-1", "user", `{"url":"http://127.0.0.1:9000/hook","event_types":["progress_update"]}`, http.StatusBadRequest},
+		{"private", "user-1", "user", `{"url":"http://10.1.2.3/hook","event_types":["progress_update"]}`, http.StatusBadRequest},
+		{"link-local metadata", "user-1", "user", `{"url":"http://169.254.169.254/latest","event_types":["progress_update"]}`, http.StatusBadRequest},
+		{"ipv6 loopback", "user-1", "user", `{"url":"http://[::1]/hook","event_types":["progress_update"]}`, http.StatusBadRequest},
+		{"unspecified", "user-1", "user", `{"url":"http://0.0.0.0/hook","event_types":["progress_update"]}`, http.StatusBadRequest},
+		{"unknown event", "user-1", "user", `{"url":"http://93.184.216.34/hook","event_types":["user_message"]}`, http.StatusBadRequest},
+		{"all users needs admin", "user-1", "user", `{"url":"http://93.184.216.34/hook","event_types":["progress_update"],"all_users":true}`, http.StatusForbidden},
+		{"admin all users", "admin-1", "admin", `{"url":"http://93.184.216.34/hook","event_types":["progress_update"],"all_users":true}`, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Test-User", tt.user)
+			req.Header.Set("X-Test-Role", tt.role)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCreateWebhookAllowedNetworks(t *testing.T) {
+	store := setupStore(t)
+	gin.SetMode(gin.TestMode)
+
+	handler := webhook.NewHandler(store)
+	handler.SetAllowedNetworks(loopback)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", "user-1") })
+	router.POST("/webhooks", handler.CreateWebhook)
+
+	body := `{"url":"http://127.0.0.1:9000/hook","event_types":["progress_update"]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d (%s)", w.Code, http.StatusCreated, w.Body.String())
+	}
+}
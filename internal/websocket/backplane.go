@@ -0,0 +1,297 @@
+package websocket
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/config"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+)
+
+// What an Envelope carries
+const (
+	envelopeRoom       = "room"       // Payload for everyone in Room
+	envelopeSplit      = "split"      // Payload for Readers in Room, Hidden for the rest of it
+	envelopeUser       = "user"       // Payload for every connection UserID has
+	envelopeAll        = "all"        // Payload for every connection
+	envelopeConnect    = "connect"    // ConnID came online as UserID/Username
+	envelopeDisconnect = "disconnect" // ConnID went away
+	envelopeJoin       = "join"       // ConnID joined Room
+	envelopeFocus      = "focus"      // ConnID is looking at Room
+	envelopeKick       = "kick"       // every connection UserID has leaves Room
+	envelopeCloseRoom  = "close_room" // Room was deleted
+	envelopeHello      = "hello"      // a manager started; the others announce their connections
+	envelopeBye        = "bye"        // a manager stopped; forget its connections
+	envelopeAlive      = "alive"      // a manager is still running
+)
+
+const (
+	defaultBackplanePoll = 50 * time.Millisecond
+	backplaneRetention   = time.Minute
+	defaultHeartbeat     = 10 * time.Second
+	defaultRemoteTTL     = 30 * time.Second
+)
+
+// Liveness is how often a manager tells the others it is still running, and
+// how long they go without hearing from it before forgetting its
+// connections. A manager that crashes never says bye.
+type Liveness struct {
+	Heartbeat time.Duration
+	TTL       time.Duration
+}
+
+// LivenessFromEnv reads CHAT_BACKPLANE_HEARTBEAT_MS and CHAT_BACKPLANE_TTL_MS
+func LivenessFromEnv() Liveness {
+	l := Liveness{
+		Heartbeat: time.Duration(config.GetEnvInt("CHAT_BACKPLANE_HEARTBEAT_MS", int(defaultHeartbeat/time.Millisecond))) * time.Millisecond,
+		TTL:       time.Duration(config.GetEnvInt("CHAT_BACKPLANE_TTL_MS", int(defaultRemoteTTL/time.Millisecond))) * time.Millisecond,
+	}
+	if l.Heartbeat <= 0 {
+		l.Heartbeat = defaultHeartbeat
+	}
+	if l.TTL <= l.Heartbeat {
+		l.TTL = 3 * l.Heartbeat
+	}
+	return l
+}
+
+var errBackplaneClosed = errors.New("backplane closed")
+
+// Envelope is one piece of chat traffic or presence on the backplane.
+// Origin identifies the manager that published it.
+type Envelope struct {
+	Origin   string          `json:"origin"`
+	Kind     string          `json:"kind"`
+	Room     string          `json:"room,omitempty"`
+	UserID   string          `json:"user_id,omitempty"`
+	Username string          `json:"username,omitempty"`
+	ConnID   string          `json:"conn_id,omitempty"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+	Hidden   json.RawMessage `json:"hidden,omitempty"`
+	Readers  []string        `json:"readers,omitempty"`
+	Offset   int64           `json:"offset,omitempty"` // event log offset of a user Payload
+}
+
+// Backplane carries chat traffic between managers, so that several chat
+// servers behind a load balancer see each other's users and messages.
+// Every subscriber receives every envelope, its own included, in the order
+// they were published.
+type Backplane interface {
+	Publish(env Envelope) error
+	// Subscribe calls handler for each envelope from a single goroutine
+	// until cancel is called
+	Subscribe(handler func(Envelope)) (cancel func(), err error)
+	Close() error
+}
+
+// BackplaneFromEnv picks the backplane named by CHAT_BACKPLANE: "sqlite"
+// shares chat through the database so several processes can serve it,
+// anything else keeps it in this process
+func BackplaneFromEnv(db *sql.DB) Backplane {
+	if os.Getenv("CHAT_BACKPLANE") == "sqlite" {
+		poll := time.Duration(config.GetEnvInt("CHAT_BACKPLANE_POLL_MS", int(defaultBackplanePoll/time.Millisecond))) * time.Millisecond
+		return NewSQLiteBackplane(db, poll)
+	}
+	return NewMemoryBackplane()
+}
+
+// ---- in-process ----
+
+type memoryBackplane struct {
+	mu          sync.RWMutex
+	subscribers []*memorySubscriber
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+// memorySubscriber queues envelopes so that publishing never waits on a
+// subscriber, even one that publishes from its own handler
+type memorySubscriber struct {
+	mu    sync.Mutex
+	queue []Envelope
+	wake  chan struct{}
+	stop  chan struct{}
+}
+
+// NewMemoryBackplane connects the managers of one process. A manager on
+// its own behaves exactly as if it had no backplane.
+func NewMemoryBackplane() Backplane {
+	return &memoryBackplane{done: make(chan struct{})}
+}
+
+func (b *memoryBackplane) Publish(env Envelope) error {
+	select {
+	case <-b.done:
+		return errBackplaneClosed
+	default:
+	}
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+	for _, sub := range subscribers {
+		sub.mu.Lock()
+		sub.queue = append(sub.queue, env)
+		sub.mu.Unlock()
+		select {
+		case sub.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (b *memoryBackplane) Subscribe(handler func(Envelope)) (func(), error) {
+	select {
+	case <-b.done:
+		return nil, errBackplaneClosed
+	default:
+	}
+	sub := &memorySubscriber{wake: make(chan struct{}, 1), stop: make(chan struct{})}
+	b.mu.Lock()
+	b.subscribers = append(b.subscribers, sub)
+	b.mu.Unlock()
+	go func() {
+		for {
+			select {
+			case <-b.done:
+				return
+			case <-sub.stop:
+				return
+			case <-sub.wake:
+			}
+			sub.mu.Lock()
+			queue := sub.queue
+			sub.queue = nil
+			sub.mu.Unlock()
+			for _, env := range queue {
+				handler(env)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			kept := []*memorySubscriber{}
+			for _, other := range b.subscribers {
+				if other != sub {
+					kept = append(kept, other)
+				}
+			}
+			b.subscribers = kept
+			close(sub.stop)
+		})
+	}, nil
+}
+
+func (b *memoryBackplane) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.done)
+	})
+	return nil
+}
+
+// ---- SQLite ----
+
+type sqliteBackplane struct {
+	db       *sql.DB
+	poll     time.Duration
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSQLiteBackplane shares chat between every process using the same
+// database file. Each subscriber polls the chat_backplane table for rows
+// newer than the last one it saw; rows older than a minute are pruned.
+func NewSQLiteBackplane(db *sql.DB, poll time.Duration) Backplane {
+	if poll <= 0 {
+		poll = defaultBackplanePoll
+	}
+	return &sqliteBackplane{db: db, poll: poll, stopChan: make(chan struct{})}
+}
+
+func (b *sqliteBackplane) Publish(env Envelope) error {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	_, err = b.db.Exec(`INSERT INTO chat_backplane (origin, envelope, created_at) VALUES (?, ?, ?)`,
+		env.Origin, string(data), time.Now().UnixMilli())
+	return err
+}
+
+func (b *sqliteBackplane) Subscribe(handler func(Envelope)) (func(), error) {
+	// Start from now; earlier traffic was for connections that are gone
+	var last sql.NullInt64
+	if err := b.db.QueryRow(`SELECT MAX(id) FROM chat_backplane`).Scan(&last); err != nil {
+		return nil, fmt.Errorf("failed to read backplane position: %w", err)
+	}
+	stop := make(chan struct{})
+	go b.pollLoop(last.Int64, handler, stop)
+
+	var once sync.Once
+	return func() { once.Do(func() { close(stop) }) }, nil
+}
+
+func (b *sqliteBackplane) pollLoop(last int64, handler func(Envelope), stop chan struct{}) {
+	ticker := time.NewTicker(b.poll)
+	defer ticker.Stop()
+	lastPrune := time.Now()
+	for {
+		select {
+		case <-b.stopChan:
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		last = b.deliver(last, handler)
+		if time.Since(lastPrune) > backplaneRetention {
+			lastPrune = time.Now()
+			cutoff := time.Now().Add(-backplaneRetention).UnixMilli()
+			if _, err := b.db.Exec(`DELETE FROM chat_backplane WHERE created_at < ?`, cutoff); err != nil {
+				logger.Warn("Failed to prune chat backplane", map[string]interface{}{"error": err.Error()})
+			}
+		}
+	}
+}
+
+// deliver hands over every envelope after last and returns the new position
+func (b *sqliteBackplane) deliver(last int64, handler func(Envelope)) int64 {
+	rows, err := b.db.Query(`SELECT id, envelope FROM chat_backplane WHERE id > ? ORDER BY id`, last)
+	if err != nil {
+		logger.Warn("Failed to read chat backplane", map[string]interface{}{"error": err.Error()})
+		return last
+	}
+	var envelopes []Envelope
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&last, &data); err != nil {
+			break
+		}
+		var env Envelope
+		if err := json.Unmarshal([]byte(data), &env); err == nil {
+			envelopes = append(envelopes, env)
+		}
+	}
+	rows.Close()
+
+	for _, env := range envelopes {
+		handler(env)
+	}
+	return last
+}
+
+func (b *sqliteBackplane) Close() error {
+	b.stopOnce.Do(func() {
+		close(b.stopChan)
+	})
+	return nil
+}
@@ -69,9 +69,9 @@ func (wb *WSBroadcaster) SendToConnection(connID string, event bridge.UnifiedEve
 	return nil
 }
 
+// BroadcastToUser sends the event to every connection the user has open
 func (wb *WSBroadcaster) BroadcastToUser(userID string, event bridge.UnifiedEvent) {
-	client, ok := wb.manager.GetClient(userID)
-	if !ok {
+	if !wb.manager.isOnline(userID) {
 		wb.logger.Debug("ws_user_not_connected", "user_id", userID)
 		return
 	}
@@ -82,10 +82,9 @@ func (wb *WSBroadcaster) BroadcastToUser(userID string, event bridge.UnifiedEven
 		return
 	}
 
-	select {
-	case client.Send <- messageBytes:
+	if wb.manager.SendToUser(userID, messageBytes) {
 		wb.logger.Debug("ws_event_broadcast", "user_id", userID, "event_type", event.Type)
-	default:
+	} else {
 		wb.logger.Warn("ws_send_channel_full", "user_id", userID)
 	}
 }
@@ -0,0 +1,186 @@
+package websocket
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+var errUserNotFound = errors.New("user not found")
+
+// resolveUser accepts a user ID or a username
+func (h *Handler) resolveUser(ref string) (*models.User, error) {
+	ctx := context.Background()
+	u, err := h.store.Users.GetByID(ctx, ref)
+	if errors.Is(err, repository.ErrNotFound) {
+		u, err = h.store.Users.GetByUsername(ctx, ref)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, errUserNotFound
+	}
+	return u, err
+}
+
+// handleDirectMessage stores a message in the sender and recipient's own
+// conversation and delivers it to every connection either of them has open.
+// It never touches the room the sender happens to be in.
+func (h *Handler) handleDirectMessage(client *Client, msg ClientMessage) error {
+	recipient, err := h.resolveUser(msg.To)
+	if err != nil {
+		h.sendError(client, msg.Room, fmt.Sprintf("Can't message %s: %v", msg.To, err))
+		return nil
+	}
+	if recipient.ID == client.ID {
+		h.sendError(client, msg.Room, "You can't message yourself")
+		return nil
+	}
+
+	ctx := context.Background()
+	conv, err := h.store.Conversations.GetOrCreateDirect(ctx, client.ID, recipient.ID)
+	if err != nil {
+		return err
+	}
+	var reply map[string]interface{}
+	if msg.ReplyTo != "" {
+		if reply, err = h.replyContext(conv.ID, msg.ReplyTo); err != nil {
+			h.sendError(client, msg.Room, "Can't reply: that message isn't in this conversation")
+			return nil
+		}
+	}
+	saved, err := h.store.Messages.SaveReply(ctx, conv.ID, client.ID, msg.Content, msg.ReplyTo)
+	if err != nil {
+		return err
+	}
+	h.trackUnread(client, conv.ID, "", saved.ID)
+
+	serverMsg := ServerMessage{
+		ID:        saved.ID,
+		Type:      MessageTypeText,
+		From:      client.Username,
+		Content:   msg.Content,
+		Timestamp: saved.CreatedAt,
+		Metadata: map[string]interface{}{
+			"direct":          true,
+			"conversation_id": conv.ID,
+			"from_id":         client.ID,
+			"to":              recipient.Username,
+			"to_id":           recipient.ID,
+		},
+	}
+	if reply != nil {
+		serverMsg.Metadata["reply_to"] = reply
+	}
+	data, err := json.Marshal(serverMsg)
+	if err != nil {
+		return err
+	}
+	client.Manager.SendToUser(recipient.ID, data)
+	client.Manager.SendToUser(client.ID, data)
+	return nil
+}
+
+func (h *Handler) sendError(client *Client, room, content string) {
+	id, _ := utils.GenerateID(16)
+	data, err := json.Marshal(ServerMessage{ID: id, Type: MessageTypeError, From: "system", Room: room, Content: content, Timestamp: time.Now()})
+	if err == nil {
+		client.Manager.SendToUser(client.ID, data)
+	}
+}
+
+// DirectInbox lists the user's direct conversations for /dms and the HTTP inbox
+func (h *Handler) DirectInbox(userID string) ([]models.DirectConversation, error) {
+	return h.store.Conversations.ListDirect(context.Background(), userID)
+}
+
+// DirectHistory returns the latest messages between the user and other,
+// oldest first. It is empty when they have never talked.
+func (h *Handler) DirectHistory(userID, otherID string, limit int) ([]Message, error) {
+	conv, err := h.store.Conversations.GetByName(context.Background(), repository.DirectConversationName(userID, otherID))
+	if errors.Is(err, repository.ErrNotFound) {
+		return []Message{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	messages, err := h.GetConversationHistory(conv.ID, limit)
+	if messages == nil && err == nil {
+		messages = []Message{}
+	}
+	return messages, err
+}
+
+// InboxHandler serves direct messages over HTTP for users not connected to chat
+type InboxHandler struct {
+	chat *Handler
+}
+
+func NewInboxHandler(db *sql.DB) *InboxHandler {
+	return &InboxHandler{chat: NewHandler(db, nil)}
+}
+
+// ListConversations handles GET /users/dms
+func (h *InboxHandler) ListConversations(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	inbox, err := h.chat.DirectInbox(userID)
+	if err != nil {
+		logger.Error("Failed to list direct conversations", map[string]interface{}{"error": err.Error(), "user_id": userID})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load direct messages"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"conversations": inbox, "count": len(inbox)})
+}
+
+// GetConversation handles GET /users/dms/:user?limit=50, where :user is a
+// user ID or username
+func (h *InboxHandler) GetConversation(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	other, err := h.chat.resolveUser(c.Param("user"))
+	if errors.Is(err, errUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up user"})
+		return
+	}
+
+	limit := 50
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		limit = n
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	messages, err := h.chat.DirectHistory(userID, other.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load direct messages"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"with":     gin.H{"id": other.ID, "username": other.Username},
+		"messages": messages,
+		"count":    len(messages),
+	})
+}
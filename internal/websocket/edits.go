@@ -0,0 +1,223 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+const (
+	maxEmojiLength     = 32
+	replySnippetLength = 80
+)
+
+var errMessageNotFound = errors.New("message not found")
+
+// validEmoji accepts a short token without spaces: an emoji or a :name:
+func validEmoji(e string) bool {
+	if e == "" || len(e) > maxEmojiLength {
+		return false
+	}
+	for _, r := range e {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// directMembers returns the two user IDs in a direct conversation's name
+func directMembers(conv *models.Conversation) []string {
+	return strings.Split(strings.TrimPrefix(conv.Name, "dm:"), ":")
+}
+
+func isDirectMember(conv *models.Conversation, userID string) bool {
+	for _, id := range directMembers(conv) {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver sends a message to everyone who can see the conversation: both
+// people in a direct conversation, or the room's members otherwise
+func (h *Handler) deliver(client *Client, conv *models.Conversation, msg ServerMessage) error {
+	if conv.Type == "direct" {
+		if msg.Metadata == nil {
+			msg.Metadata = map[string]interface{}{}
+		}
+		msg.Metadata["direct"] = true
+		msg.Metadata["conversation_id"] = conv.ID
+	} else {
+		msg.Room = conv.Name
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if conv.Type == "direct" {
+		for _, id := range directMembers(conv) {
+			client.Manager.SendToUser(id, data)
+		}
+		return nil
+	}
+	client.Manager.broadcastRoom(conv.Name, data)
+	return nil
+}
+
+// replyContext checks replyToID belongs to the conversation and returns the
+// summary shown above the reply
+func (h *Handler) replyContext(conversationID, replyToID string) (map[string]interface{}, error) {
+	parent, err := h.store.Messages.Get(context.Background(), replyToID)
+	if err != nil || parent.ConversationID != conversationID {
+		return nil, errMessageNotFound
+	}
+	snippet := []rune(parent.Content)
+	if len(snippet) > replySnippetLength {
+		snippet = append(snippet[:replySnippetLength-3], []rune("...")...)
+	}
+	summary := map[string]interface{}{
+		"id":      parent.ID,
+		"from":    parent.SenderUsername,
+		"content": string(snippet),
+		"deleted": parent.DeletedAt != nil,
+	}
+	// The reply goes to readers at every chapter, so a spoiler is only
+	// pointed at
+	if parent.SpoilerChapter > 0 {
+		summary["content"] = ""
+		summary["chapter"] = parent.SpoilerChapter
+	}
+	return summary, nil
+}
+
+// loadTarget fetches the message an edit, delete or reaction points at,
+// with its conversation, reporting problems to the client
+func (h *Handler) loadTarget(client *Client, msg ClientMessage) (*models.ChatMessage, *models.Conversation, bool) {
+	ctx := context.Background()
+	target, err := h.store.Messages.Get(ctx, msg.MessageID)
+	if err != nil {
+		h.sendError(client, msg.Room, "Message not found")
+		return nil, nil, false
+	}
+	conv, err := h.store.Conversations.Get(ctx, target.ConversationID)
+	if err != nil || (conv.Type == "direct" && !isDirectMember(conv, client.ID)) {
+		h.sendError(client, msg.Room, "Message not found")
+		return nil, nil, false
+	}
+	if target.DeletedAt != nil {
+		h.sendError(client, msg.Room, "That message was deleted")
+		return nil, nil, false
+	}
+	if err := h.checkBanned(client.ID, conv.ID); err != nil {
+		h.sendError(client, msg.Room, err.Error())
+		return nil, nil, false
+	}
+	return target, conv, true
+}
+
+// handleEdit lets people change their own messages
+func (h *Handler) handleEdit(client *Client, msg ClientMessage) error {
+	target, conv, ok := h.loadTarget(client, msg)
+	if !ok {
+		return nil
+	}
+	if target.SenderID != client.ID {
+		h.sendError(client, msg.Room, "You can only edit your own messages")
+		return nil
+	}
+	if err := h.checkMuted(client.ID, conv.ID); err != nil {
+		h.sendError(client, msg.Room, err.Error())
+		return nil
+	}
+
+	updated, err := h.store.Messages.Edit(context.Background(), target.ID, msg.Content)
+	if err != nil {
+		return err
+	}
+	edit := ServerMessage{
+		ID:        updated.ID,
+		Type:      MessageTypeEdit,
+		From:      client.Username,
+		Content:   updated.Content,
+		Timestamp: time.Now(),
+		Metadata: map[string]interface{}{
+			"message_id": updated.ID,
+			"edited_at":  updated.EditedAt,
+		},
+	}
+	if updated.SpoilerChapter > 0 && conv.Type == "manga" {
+		edit.Room = conv.Name
+		return h.broadcastSpoiler(conv.Name, updated.SpoilerChapter, edit)
+	}
+	return h.deliver(client, conv, edit)
+}
+
+// handleDelete removes a message. Authors can delete their own; room
+// moderators and owners can delete anyone's.
+func (h *Handler) handleDelete(client *Client, msg ClientMessage) error {
+	target, conv, ok := h.loadTarget(client, msg)
+	if !ok {
+		return nil
+	}
+	if target.SenderID != client.ID {
+		if conv.Type == "direct" || repository.RoleRank(h.roomRole(client.ID, client.Role, conv.ID)) < repository.RoleRank(repository.RoleModerator) {
+			h.sendError(client, msg.Room, "You can only delete your own messages")
+			return nil
+		}
+		logger.Info("Room moderation", map[string]interface{}{"room": conv.Name, "action": "delete", "by": client.ID, "message_id": target.ID})
+	}
+
+	if err := h.store.Messages.Delete(context.Background(), target.ID); err != nil {
+		return err
+	}
+	return h.deliver(client, conv, ServerMessage{
+		ID:        target.ID,
+		Type:      MessageTypeDelete,
+		From:      client.Username,
+		Timestamp: time.Now(),
+		Metadata: map[string]interface{}{
+			"message_id": target.ID,
+			"author":     target.SenderUsername,
+		},
+	})
+}
+
+// handleReaction toggles the client's emoji on a message
+func (h *Handler) handleReaction(client *Client, msg ClientMessage) error {
+	target, conv, ok := h.loadTarget(client, msg)
+	if !ok {
+		return nil
+	}
+
+	ctx := context.Background()
+	added, err := h.store.Messages.ToggleReaction(ctx, target.ID, client.ID, msg.Emoji)
+	if err != nil {
+		return err
+	}
+	counts := map[string]int{}
+	if reloaded, err := h.store.Messages.Get(ctx, target.ID); err == nil && reloaded.Reactions != nil {
+		counts = reloaded.Reactions
+	}
+	return h.deliver(client, conv, ServerMessage{
+		ID:        target.ID,
+		Type:      MessageTypeReaction,
+		From:      client.Username,
+		Content:   msg.Emoji,
+		Timestamp: time.Now(),
+		Metadata: map[string]interface{}{
+			"message_id": target.ID,
+			"emoji":      msg.Emoji,
+			"added":      added,
+			"reactions":  counts,
+		},
+	})
+}
@@ -1,24 +1,43 @@
 package websocket
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/ratelimit"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/metrics"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
 )
 
 type Handler struct {
-	db      *sql.DB
+	store   *repository.Store
 	manager *Manager
+	bridge  *bridge.UnifiedBridge // carries chat alerts to other protocols when set
+	limiter *ratelimit.Limiter    // no limits when nil
+
+	slowMu     sync.Mutex
+	lastPosted map[string]time.Time // conversation/user -> last message, for slow mode
+	slowPruned time.Time
 }
 
 func NewHandler(db *sql.DB, manager *Manager) *Handler {
-	return &Handler{db: db, manager: manager}
+	return NewHandlerWithStore(repository.NewSQLiteStore(db), manager)
+}
+
+// NewHandlerWithStore creates a handler backed by the given repositories (for testing)
+func NewHandlerWithStore(store *repository.Store, manager *Manager) *Handler {
+	return &Handler{store: store, manager: manager, lastPosted: make(map[string]time.Time)}
 }
 
 func (h *Handler) HandleClientMessage(client *Client, data []byte) error {
@@ -29,9 +48,21 @@ func (h *Handler) HandleClientMessage(client *Client, data []byte) error {
 	if err := h.validateMessage(&msg); err != nil {
 		return err
 	}
+	if ok, err := h.admit(client, &msg); !ok {
+		return err
+	}
+	metrics.RecordProtocolMessage("websocket", string(msg.Type))
 	switch msg.Type {
-	case MessageTypeText:
+	case MessageTypeText, MessageTypeReply:
 		return h.handleTextMessage(client, msg)
+	case MessageTypeEdit:
+		return h.handleEdit(client, msg)
+	case MessageTypeDelete:
+		return h.handleDelete(client, msg)
+	case MessageTypeReaction:
+		return h.handleReaction(client, msg)
+	case MessageTypeRead:
+		return h.handleRead(client, msg)
 	case MessageTypeTyping:
 		return h.handleTypingIndicator(client, msg)
 	case MessageTypeCommand:
@@ -46,12 +77,30 @@ func (h *Handler) validateMessage(msg *ClientMessage) error {
 	if len(msg.Content) > 4096 {
 		return &ValidationError{Field: "content", Message: "message too long"}
 	}
-	if msg.Type == MessageTypeText && len(msg.Content) == 0 {
-		return &ValidationError{Field: "content", Message: "message content required"}
+	switch msg.Type {
+	case MessageTypeText, MessageTypeReply, MessageTypeEdit:
+		if len(msg.Content) == 0 {
+			return &ValidationError{Field: "content", Message: "message content required"}
+		}
+	}
+	switch msg.Type {
+	case MessageTypeReply:
+		if msg.ReplyTo == "" {
+			return &ValidationError{Field: "reply_to", Message: "message to reply to required"}
+		}
+	case MessageTypeEdit, MessageTypeDelete, MessageTypeReaction:
+		if msg.MessageID == "" {
+			return &ValidationError{Field: "message_id", Message: "message id required"}
+		}
+	}
+	if msg.Type == MessageTypeReaction && !validEmoji(msg.Emoji) {
+		return &ValidationError{Field: "emoji", Message: "a single emoji without spaces required"}
 	}
 	return nil
 }
 
+var errDirectRoomName = &ValidationError{Field: "room", Message: "names starting with dm: are reserved for direct messages"}
+
 type ValidationError struct {
 	Field   string
 	Message string
@@ -60,6 +109,9 @@ type ValidationError struct {
 func (e *ValidationError) Error() string { return e.Field + ": " + e.Message }
 
 func (h *Handler) handleTextMessage(client *Client, msg ClientMessage) error {
+	if msg.To != "" {
+		return h.handleDirectMessage(client, msg)
+	}
 	room := msg.Room
 	if room == "" {
 		room = "global"
@@ -78,29 +130,47 @@ func (h *Handler) handleTextMessage(client *Client, msg ClientMessage) error {
 		return err
 	}
 
+	if err := h.checkPost(client, convID); err != nil {
+		h.sendError(client, room, err.Error())
+		return nil
+	}
+
+	var reply map[string]interface{}
+	if msg.ReplyTo != "" {
+		if reply, err = h.replyContext(convID, msg.ReplyTo); err != nil {
+			h.sendError(client, room, "Can't reply: that message isn't in this room")
+			return nil
+		}
+	}
+
 	// Join user to conversation
 	h.joinConversation(client.ID, convID)
 
 	id, _ := utils.GenerateID(16)
 	serverMsg := ServerMessage{ID: id, Type: MessageTypeText, From: client.Username, Room: room, Content: msg.Content, Timestamp: time.Now()}
-	if msg.To != "" {
-		serverMsg.Metadata = map[string]interface{}{"direct": true}
+	if reply != nil {
+		serverMsg.Metadata = map[string]interface{}{"reply_to": reply}
 	}
 	// Ensure client is a member of the target room before broadcasting
 	client.Manager.joinRoom(client, room)
-	if err := h.saveMessageToConversation(convID, client.ID, msg.Content); err != nil {
+	client.setFocus(room)
+	if saved, err := h.store.Messages.SaveReply(context.Background(), convID, client.ID, msg.Content, msg.ReplyTo); err != nil {
 		logger.Warn("Failed to save message", map[string]interface{}{"error": err.Error()})
+	} else {
+		// Clients need the stored ID to edit, delete or react to it
+		serverMsg.ID = saved.ID
+		h.trackUnread(client, convID, room, saved.ID)
+		saved.SpoilerChapter = h.tagSpoiler(client, room, saved.ID)
+		h.raiseAlerts(client, room, saved)
+		if saved.SpoilerChapter > 0 {
+			return h.broadcastSpoiler(room, saved.SpoilerChapter, serverMsg)
+		}
 	}
 	data, err := json.Marshal(serverMsg)
 	if err != nil {
 		return err
 	}
-	if msg.To != "" {
-		client.Manager.SendToUser(msg.To, data)
-		client.Manager.SendToUser(client.ID, data)
-	} else {
-		client.Manager.broadcastRoom(room, data)
-	}
+	client.Manager.broadcastRoom(room, data)
 	return nil
 }
 
@@ -116,103 +186,73 @@ func (h *Handler) handleTypingIndicator(client *Client, msg ClientMessage) error
 		return err
 	}
 	if msg.To != "" {
-		client.Manager.SendToUser(msg.To, data)
+		if recipient, err := h.resolveUser(msg.To); err == nil {
+			client.Manager.SendToUser(recipient.ID, data)
+		}
 	} else {
+		if h.bannedFromRoom(client.ID, room) {
+			return nil
+		}
 		// Ensure client is a member of the target room before broadcasting typing state
 		client.Manager.joinRoom(client, room)
+		client.setFocus(room)
 		client.Manager.broadcastRoom(room, data)
 	}
 	return nil
 }
 
-func (h *Handler) saveMessage(fromUserID, toUserID, content string) error {
-	id, _ := utils.GenerateID(16)
-	var toUserIDValue interface{}
-	if toUserID == "" {
-		toUserIDValue = nil
-	} else {
-		toUserIDValue = toUserID
-	}
-	query := `INSERT INTO chat_messages (id, from_user_id, to_user_id, content, created_at) 
-              VALUES (?, ?, ?, ?, ?)`
-	_, err := h.db.Exec(query, id, fromUserID, toUserIDValue, content, time.Now())
-	if err != nil {
-		logger.Warn("Failed to save message to DB", map[string]interface{}{"error": err.Error()})
-	}
-	return err
-}
-
-func (h *Handler) saveMessageToConversation(conversationID, senderID, content string) error {
-	id, _ := utils.GenerateID(16)
-	now := time.Now().Format("2006-01-02 15:04:05")
-	query := `INSERT INTO messages (id, conversation_id, sender_id, content, created_at) 
-			  VALUES (?, ?, ?, ?, ?)`
-	_, err := h.db.Exec(query, id, conversationID, senderID, content, now)
-	if err != nil {
-		return err
-	}
-	// Update last_message_at for conversation
-	h.db.Exec(`UPDATE conversations SET last_message_at = ? WHERE id = ?`, now, conversationID)
-	return nil
-}
-
 func (h *Handler) getOrCreateConversation(roomName, userID string) (string, error) {
 	if roomName == "" {
 		roomName = "global"
 	}
+	if strings.HasPrefix(roomName, "dm:") {
+		return "", errDirectRoomName
+	}
+	ctx := context.Background()
 
 	// Determine conversation type and name
-	var convType, convName, mangaID string
+	conv := &models.Conversation{Name: roomName, Type: "custom", CreatedBy: userID}
 	if roomName == "global" {
-		convType = "global"
-		convName = "global"
+		conv.Type = "global"
 	} else if strings.HasPrefix(roomName, "manga-") {
-		convType = "manga"
-		convName = roomName
-		mangaID = strings.TrimPrefix(roomName, "manga-")
+		conv.Type = "manga"
+		conv.MangaID = strings.TrimPrefix(roomName, "manga-")
 		// Validate manga exists
-		var exists bool
-		err := h.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM manga WHERE id = ?)`, mangaID).Scan(&exists)
+		exists, err := h.store.Manga.Exists(ctx, conv.MangaID)
 		if err != nil || !exists {
 			return "", &ValidationError{Field: "manga_id", Message: "manga not found"}
 		}
-	} else {
-		convType = "custom"
-		convName = roomName
 	}
 
 	// Check if conversation exists
-	var convID string
-	err := h.db.QueryRow(`SELECT id FROM conversations WHERE name = ?`, convName).Scan(&convID)
-	if err == sql.ErrNoRows {
-		// Create new conversation
-		convID, _ = utils.GenerateID(16)
-		var mangaIDVal interface{}
-		if mangaID != "" {
-			mangaIDVal = mangaID
-		} else {
-			mangaIDVal = nil
-		}
-		now := time.Now().Format("2006-01-02 15:04:05")
-		_, err = h.db.Exec(`INSERT INTO conversations (id, name, type, manga_id, created_by, created_at, last_message_at) 
-			VALUES (?, ?, ?, ?, ?, ?, ?)`, convID, convName, convType, mangaIDVal, userID, now, now)
-		if err != nil {
-			return "", err
-		}
-		logger.Info("Created new conversation", map[string]interface{}{"id": convID, "name": convName, "type": convType})
+	existing, err := h.store.Conversations.GetByName(ctx, conv.Name)
+	if err == nil {
+		return existing.ID, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return "", err
+	}
 
-		// Set creator as owner for custom rooms
-		if convType == "custom" {
-			h.db.Exec(`INSERT OR IGNORE INTO user_conversation_history (user_id, conversation_id, role, joined_at, unread_count) VALUES (?, ?, 'owner', ?, 0)`, userID, convID, now)
+	if err := h.store.Conversations.Create(ctx, conv); err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			// Lost a race with another client creating the same room
+			if existing, err := h.store.Conversations.GetByName(ctx, conv.Name); err == nil {
+				return existing.ID, nil
+			}
 		}
+		return "", err
 	}
-	return convID, nil
+	logger.Info("Created new conversation", map[string]interface{}{"id": conv.ID, "name": conv.Name, "type": conv.Type})
+
+	// Set creator as owner for custom rooms
+	if conv.Type == "custom" {
+		h.store.Conversations.AddMember(ctx, conv.ID, userID, repository.RoleOwner)
+	}
+	return conv.ID, nil
 }
 
 func (h *Handler) joinConversation(userID, conversationID string) {
-	now := time.Now().Format("2006-01-02 15:04:05")
-	h.db.Exec(`INSERT OR IGNORE INTO user_conversation_history (user_id, conversation_id, joined_at, unread_count)
-		VALUES (?, ?, ?, 0)`, userID, conversationID, now)
+	h.store.Conversations.AddMember(context.Background(), conversationID, userID, repository.RoleMember)
 }
 
 func (h *Handler) handleCommand(client *Client, msg ClientMessage) error {
@@ -240,6 +280,12 @@ func (h *Handler) handleCommand(client *Client, msg ClientMessage) error {
 	case "rooms":
 		// List all available conversations/rooms
 		rooms, _ := h.GetAllRooms()
+		unread := h.unreadByConversation(client.ID)
+		for _, room := range rooms {
+			if n := unread[room["id"].(string)]; n > 0 {
+				room["unread_count"] = n
+			}
+		}
 		responseMsg = ServerMessage{
 			ID:        id,
 			Type:      MessageTypeSystem,
@@ -319,6 +365,9 @@ func (h *Handler) handleCommand(client *Client, msg ClientMessage) error {
 			roomName := strings.Join(args, " ")
 			// Get or create conversation for the room
 			convID, err := h.getOrCreateConversation(roomName, client.ID)
+			if err == nil {
+				err = h.checkBanned(client.ID, convID)
+			}
 			if err != nil {
 				responseMsg = ServerMessage{
 					ID:        id,
@@ -333,6 +382,8 @@ func (h *Handler) handleCommand(client *Client, msg ClientMessage) error {
 				h.joinConversation(client.ID, convID)
 				// Add client to room in WebSocket manager
 				client.Manager.joinRoom(client, roomName)
+				client.setFocus(roomName)
+				h.markLatestRead(client.ID, convID)
 
 				// Send confirmation to client
 				responseMsg = ServerMessage{
@@ -350,6 +401,7 @@ func (h *Handler) handleCommand(client *Client, msg ClientMessage) error {
 
 				// Also send history to the joining client
 				messages, _ := h.GetConversationHistory(convID, 50)
+				messages = h.hideSpoilers(client.ID, convID, messages)
 				historyMsg := ServerMessage{
 					ID:        id,
 					Type:      MessageTypeHistory,
@@ -378,40 +430,149 @@ func (h *Handler) handleCommand(client *Client, msg ClientMessage) error {
 				}
 				userListData, _ := json.Marshal(userListMsg)
 				client.Manager.SendToUser(client.ID, userListData)
+
+				if conv, err := h.store.Conversations.Get(context.Background(), convID); err == nil && conv.Topic != "" {
+					topicMsg := ServerMessage{ID: id, Type: MessageTypeSystem, From: "system", Room: roomName, Content: "Topic: " + conv.Topic, Timestamp: time.Now()}
+					topicData, _ := json.Marshal(topicMsg)
+					client.Manager.SendToUser(client.ID, topicData)
+				}
 				return nil
 			}
 		}
 	case "history":
-		// Default history limit; override if a valid argument is provided
-		limit := 20
-		if len(args) > 0 {
-			if n, err := strconv.Atoi(args[0]); err == nil {
-				// Clamp to sane bounds
-				if n < 1 {
-					n = 1
-				}
-				if n > 500 {
-					n = 500
-				}
-				limit = n
-			}
-		}
+		// /history [limit] [before|after <message-id>]
+		page := parseHistoryArgs(args, 20)
 		room := msg.Room
 		if room == "" {
 			room = "global"
 		}
 		convID, _ := h.getOrCreateConversation(room, client.ID)
-		messages, _ := h.GetConversationHistory(convID, limit)
+		if err := h.checkBanned(client.ID, convID); err != nil {
+			h.sendError(client, room, err.Error())
+			return nil
+		}
+		messages, err := h.ConversationPage(convID, page)
+		if errors.Is(err, repository.ErrNotFound) {
+			h.sendError(client, room, "That message isn't in this room")
+			return nil
+		}
+		messages = h.hideSpoilers(client.ID, convID, messages)
+		metadata := map[string]interface{}{"messages": messages}
+		if page.Before != "" {
+			metadata["before"] = page.Before
+		}
+		if page.After != "" {
+			metadata["after"] = page.After
+		}
 		responseMsg = ServerMessage{
 			ID:        id,
 			Type:      MessageTypeHistory,
 			From:      "system",
 			Room:      room,
 			Timestamp: time.Now(),
+			Metadata:  metadata,
+		}
+	case "search":
+		// Search every conversation the user belongs to: /search <terms>
+		if len(args) == 0 {
+			h.sendError(client, msg.Room, "Usage: /search <words>")
+			return nil
+		}
+		query := strings.Join(args, " ")
+		results, err := h.SearchMessages(client.ID, query, defaultSearchLimit)
+		if err != nil {
+			return err
+		}
+		responseMsg = ServerMessage{
+			ID:        id,
+			Type:      MessageTypeSystem,
+			From:      "system",
+			Room:      msg.Room,
+			Content:   fmt.Sprintf("Messages matching %q", query),
+			Timestamp: time.Now(),
+			Metadata: map[string]interface{}{
+				"results": results,
+				"count":   len(results),
+			},
+		}
+	case "dms":
+		// List the user's direct conversations
+		inbox, _ := h.DirectInbox(client.ID)
+		responseMsg = ServerMessage{
+			ID:        id,
+			Type:      MessageTypeSystem,
+			From:      "system",
+			Room:      msg.Room,
+			Content:   "Direct messages",
+			Timestamp: time.Now(),
+			Metadata: map[string]interface{}{
+				"dms":   inbox,
+				"count": len(inbox),
+			},
+		}
+	case "dm":
+		// Show the conversation with a user: /dm <user> [limit]
+		if len(args) == 0 {
+			responseMsg = ServerMessage{
+				ID:        id,
+				Type:      MessageTypeError,
+				From:      "system",
+				Room:      msg.Room,
+				Content:   "Usage: /dm <user> [limit]",
+				Timestamp: time.Now(),
+			}
+			break
+		}
+		limit := 20
+		if len(args) > 1 {
+			if n, err := strconv.Atoi(args[1]); err == nil && n > 0 && n <= 500 {
+				limit = n
+			}
+		}
+		other, err := h.resolveUser(args[0])
+		var messages []Message
+		if err == nil {
+			messages, err = h.DirectHistory(client.ID, other.ID, limit)
+		}
+		if err == nil {
+			if conv, err := h.store.Conversations.GetByName(context.Background(), repository.DirectConversationName(client.ID, other.ID)); err == nil {
+				h.markLatestRead(client.ID, conv.ID)
+			}
+		}
+		if err != nil {
+			responseMsg = ServerMessage{
+				ID:        id,
+				Type:      MessageTypeError,
+				From:      "system",
+				Room:      msg.Room,
+				Content:   fmt.Sprintf("Can't show messages with %s: %v", args[0], err),
+				Timestamp: time.Now(),
+			}
+			break
+		}
+		responseMsg = ServerMessage{
+			ID:        id,
+			Type:      MessageTypeHistory,
+			From:      "system",
+			Timestamp: time.Now(),
 			Metadata: map[string]interface{}{
 				"messages": messages,
+				"direct":   true,
+				"with":     other.Username,
 			},
 		}
+	case "reveal":
+		// Show a message collapsed as a spoiler: /reveal <message-id>
+		h.reveal(client, msg.Room, args)
+		return nil
+	case "mentions", "alerts", "alert", "unalert":
+		responseMsg = *h.handleAlertCommand(client, cmd, args, msg.Room)
+	case "kick", "ban", "unban", "mute", "unmute", "promote", "demote", "slowmode", "topic", "delete-room":
+		reply := h.handleModeration(client, cmd, args, msg.Room)
+		if reply == nil {
+			return nil
+		}
+		responseMsg = *reply
 	case "status":
 		responseMsg = ServerMessage{
 			ID:        id,
@@ -445,102 +606,51 @@ func (h *Handler) handleCommand(client *Client, msg ClientMessage) error {
 }
 
 func (h *Handler) GetMessageHistory(userID string, limit int) ([]Message, error) {
-	query := `SELECT cm.id, u.username, cm.to_user_id, cm.content, cm.created_at 
-              FROM chat_messages cm
-              JOIN users u ON cm.from_user_id = u.id
-              WHERE cm.from_user_id = ? OR cm.to_user_id = ? OR cm.to_user_id IS NULL
-              ORDER BY cm.created_at DESC LIMIT ?`
-	rows, err := h.db.Query(query, userID, userID, limit)
+	stored, err := h.store.Messages.DirectHistory(context.Background(), userID, limit)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 	var messages []Message
-	for rows.Next() {
-		var msg Message
-		var toUser sql.NullString
-		if err := rows.Scan(&msg.ID, &msg.From, &toUser, &msg.Content, &msg.Timestamp); err != nil {
-			continue
-		}
-		if toUser.Valid {
-			msg.To = toUser.String
-		}
-		messages = append(messages, msg)
+	for _, m := range stored {
+		messages = append(messages, Message{ID: m.ID, From: m.SenderUsername, To: m.RecipientID, Content: m.Content, Timestamp: m.CreatedAt})
 	}
 	return messages, nil
 }
 
 func (h *Handler) GetConversationHistory(conversationID string, limit int) ([]Message, error) {
-	query := `SELECT m.id, u.username, m.content, m.created_at 
-              FROM messages m
-              JOIN users u ON m.sender_id = u.id
-              WHERE m.conversation_id = ?
-              ORDER BY m.created_at DESC LIMIT ?`
-	rows, err := h.db.Query(query, conversationID, limit)
+	stored, err := h.store.Messages.ConversationHistory(context.Background(), conversationID, limit)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 	var messages []Message
-	for rows.Next() {
-		var msg Message
-		if err := rows.Scan(&msg.ID, &msg.From, &msg.Content, &msg.Timestamp); err != nil {
-			continue
-		}
-		messages = append(messages, msg)
-	}
-	// Reverse to get chronological order
-	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
-		messages[i], messages[j] = messages[j], messages[i]
+	for _, m := range stored {
+		messages = append(messages, toMessage(m))
 	}
 	return messages, nil
 }
 
 // GetAllRooms returns all available conversations/rooms
 func (h *Handler) GetAllRooms() ([]map[string]interface{}, error) {
-	query := `
-		SELECT 
-			c.id, 
-			c.name, 
-			c.type,
-			c.created_at,
-			COUNT(DISTINCT uch.user_id) as member_count,
-			MAX(m.created_at) as last_message_at
-		FROM conversations c
-		LEFT JOIN user_conversation_history uch ON c.id = uch.conversation_id
-		LEFT JOIN messages m ON c.id = m.conversation_id
-		GROUP BY c.id, c.name, c.type, c.created_at
-		ORDER BY last_message_at DESC
-	`
-
-	rows, err := h.db.Query(query)
+	summaries, err := h.store.Conversations.List(context.Background())
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
 	rooms := []map[string]interface{}{}
-	for rows.Next() {
-		var id, name, roomType, createdAt string
-		var memberCount int
-		var lastMessageAt sql.NullString
-
-		if err := rows.Scan(&id, &name, &roomType, &createdAt, &memberCount, &lastMessageAt); err != nil {
-			continue
-		}
-
+	for _, s := range summaries {
 		room := map[string]interface{}{
-			"id":           id,
-			"name":         name,
-			"type":         roomType,
-			"created_at":   createdAt,
-			"member_count": memberCount,
+			"id":           s.ID,
+			"name":         s.Name,
+			"type":         s.Type,
+			"created_at":   s.CreatedAt,
+			"member_count": s.MemberCount,
 		}
-
-		if lastMessageAt.Valid {
-			room["last_message_at"] = lastMessageAt.String
+		if s.LastMessageAt != nil {
+			room["last_message_at"] = *s.LastMessageAt
+		}
+		if s.Topic != "" {
+			room["topic"] = s.Topic
 		}
-
 		rooms = append(rooms, room)
 	}
 
@@ -549,44 +659,28 @@ func (h *Handler) GetAllRooms() ([]map[string]interface{}, error) {
 
 // createCustomRoom creates a new custom room with the creator as owner
 func (h *Handler) createCustomRoom(roomName, creatorID string) (string, error) {
-	// Check if room already exists
-	var existingID string
-	err := h.db.QueryRow(`SELECT id FROM conversations WHERE name = ?`, roomName).Scan(&existingID)
-	if err == nil {
-		return "", fmt.Errorf("room '%s' already exists", roomName)
-	}
-	if err != sql.ErrNoRows {
-		return "", err
-	}
-
-	// Create new room
-	convID, _ := utils.GenerateID(16)
-	now := time.Now().Format("2006-01-02 15:04:05")
-
-	_, err = h.db.Exec(`
-		INSERT INTO conversations (id, name, type, created_by, created_at, last_message_at) 
-		VALUES (?, ?, 'custom', ?, ?, ?)
-	`, convID, roomName, creatorID, now, now)
-
-	if err != nil {
+	if strings.HasPrefix(roomName, "dm:") {
+		return "", errDirectRoomName
+	}
+	ctx := context.Background()
+	conv := &models.Conversation{Name: roomName, Type: "custom", CreatedBy: creatorID}
+	if err := h.store.Conversations.Create(ctx, conv); err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return "", fmt.Errorf("room '%s' already exists", roomName)
+		}
 		return "", err
 	}
 
 	// Set creator as owner
-	_, err = h.db.Exec(`
-		INSERT INTO user_conversation_history (user_id, conversation_id, role, joined_at, unread_count) 
-		VALUES (?, ?, 'owner', ?, 0)
-	`, creatorID, convID, now)
-
-	if err != nil {
+	if err := h.store.Conversations.AddMember(ctx, conv.ID, creatorID, repository.RoleOwner); err != nil {
 		return "", err
 	}
 
 	logger.Info("Custom room created", map[string]interface{}{
-		"id":      convID,
+		"id":      conv.ID,
 		"name":    roomName,
 		"creator": creatorID,
 	})
 
-	return convID, nil
+	return conv.ID, nil
 }
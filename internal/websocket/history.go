@@ -0,0 +1,192 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxHistoryLimit    = 500
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+func toMessage(m models.ChatMessage) Message {
+	return Message{ID: m.ID, From: m.SenderUsername, Content: m.Content, Timestamp: m.CreatedAt,
+		ReplyTo: m.ReplyToID, EditedAt: m.EditedAt, Deleted: m.DeletedAt != nil, Reactions: m.Reactions, Chapter: m.SpoilerChapter}
+}
+
+// ConversationPage returns one page of a conversation's history, oldest first
+func (h *Handler) ConversationPage(conversationID string, page repository.MessagePage) ([]Message, error) {
+	stored, err := h.store.Messages.ConversationPage(context.Background(), conversationID, page)
+	if err != nil {
+		return nil, err
+	}
+	messages := []Message{}
+	for _, m := range stored {
+		messages = append(messages, toMessage(m))
+	}
+	return messages, nil
+}
+
+// parseHistoryArgs reads "/history [limit] [before|after <message-id>]"
+func parseHistoryArgs(args []string, defaultLimit int) repository.MessagePage {
+	page := repository.MessagePage{Limit: defaultLimit}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "before", "after":
+			if i+1 < len(args) {
+				if args[i] == "before" {
+					page.Before = args[i+1]
+				} else {
+					page.After = args[i+1]
+				}
+				i++
+			}
+		default:
+			if n, err := strconv.Atoi(args[i]); err == nil {
+				page.Limit = n
+			}
+		}
+	}
+	// Clamp to sane bounds
+	if page.Limit < 1 {
+		page.Limit = 1
+	}
+	if page.Limit > maxHistoryLimit {
+		page.Limit = maxHistoryLimit
+	}
+	return page
+}
+
+// canRead reports whether the user may read a conversation: both people in
+// a direct conversation, and anyone not banned from a room
+func (h *Handler) canRead(userID string, conv *models.Conversation) bool {
+	if conv.Type == "direct" {
+		return isDirectMember(conv, userID)
+	}
+	return h.checkBanned(userID, conv.ID) == nil
+}
+
+// ListMessages handles GET /conversations/:id/messages?before=&after=&limit=50,
+// where :id is a conversation ID or room name. Pass the first message's ID
+// as before to page back through older messages.
+func (h *InboxHandler) ListMessages(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	conv, err := h.chat.store.Conversations.Get(ctx, c.Param("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		conv, err = h.chat.store.Conversations.GetByName(ctx, c.Param("id"))
+	}
+	if err == nil && !h.chat.canRead(userID, conv) {
+		err = repository.ErrNotFound
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load conversation"})
+		return
+	}
+
+	page := repository.MessagePage{Before: c.Query("before"), After: c.Query("after"), Limit: 50}
+	if page.Before != "" && page.After != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use either before or after, not both"})
+		return
+	}
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		page.Limit = n
+	}
+	if page.Limit > maxHistoryLimit {
+		page.Limit = maxHistoryLimit
+	}
+	limit := page.Limit
+
+	// One extra message tells us whether there is another page
+	page.Limit++
+	messages, err := h.chat.ConversationPage(conv.ID, page)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cursor message is not in this conversation"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to load messages", map[string]interface{}{"error": err.Error(), "conversation_id": conv.ID})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load messages"})
+		return
+	}
+	messages = h.chat.hideSpoilers(userID, conv.ID, messages)
+	hasMore := len(messages) > limit
+	if hasMore {
+		if page.After != "" {
+			messages = messages[:limit]
+		} else {
+			messages = messages[1:]
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"conversation": gin.H{"id": conv.ID, "name": conv.Name, "type": conv.Type},
+		"messages":     messages,
+		"count":        len(messages),
+		"has_more":     hasMore,
+	})
+}
+
+// SearchMessages handles GET /conversations/search?q=&limit=20, searching
+// every room and direct conversation the user belongs to
+func (h *InboxHandler) SearchMessages(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query (q) is required"})
+		return
+	}
+	limit := defaultSearchLimit
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		limit = n
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	results, err := h.chat.SearchMessages(userID, query, limit)
+	if err != nil {
+		logger.Error("Failed to search messages", map[string]interface{}{"error": err.Error(), "user_id": userID})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search messages"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"query": query, "results": results, "count": len(results)})
+}
+
+// SearchMessages searches the user's conversations, leaving out rooms they
+// have since been banned from and spoilers they haven't read far enough for
+func (h *Handler) SearchMessages(userID, query string, limit int) ([]models.MessageSearchResult, error) {
+	found, err := h.store.Messages.Search(context.Background(), userID, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	results := []models.MessageSearchResult{}
+	for _, r := range found {
+		if r.ConversationType == "direct" || h.checkBanned(userID, r.ConversationID) == nil {
+			results = append(results, r)
+		}
+	}
+	return h.visibleResults(userID, results), nil
+}
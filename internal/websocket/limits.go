@@ -0,0 +1,74 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/ratelimit"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+)
+
+// errDisconnect tells ReadPump the client has been kicked for abuse
+var errDisconnect = errors.New("disconnected by rate limiter")
+
+// admit checks a message against the rate limiter, masking filtered words in
+// its content. It returns false, after telling the client why, when the
+// message is refused.
+func (h *Handler) admit(client *Client, msg *ClientMessage) (bool, error) {
+	if h.limiter == nil {
+		return true, nil
+	}
+	check := ratelimit.Message{Scope: "websocket", UserID: client.ID, IP: client.ip}
+	switch msg.Type {
+	case MessageTypeText, MessageTypeReply:
+		check.Content = msg.Content
+		if msg.To == "" {
+			check.Room = msg.Room
+			if check.Room == "" {
+				check.Room = "global"
+			}
+		}
+	case MessageTypeEdit:
+		check.Content = msg.Content
+	}
+
+	d := h.limiter.Check(check)
+	if d.Allowed {
+		if check.Content != "" {
+			msg.Content = d.Content
+		}
+		return true, nil
+	}
+
+	metadata := map[string]interface{}{
+		"reason":  d.Reason,
+		"penalty": d.Penalty.String(),
+		"limit":   h.limiter.Config().PerUser,
+	}
+	if !d.Until.IsZero() {
+		metadata["until"] = d.Until
+	}
+	if d.Penalty == ratelimit.Mute || d.Penalty == ratelimit.Disconnect {
+		logger.Warn("Client penalized by rate limiter", map[string]interface{}{
+			"client_id": client.ID,
+			"ip":        client.ip,
+			"reason":    d.Reason,
+			"penalty":   d.Penalty.String(),
+		})
+	}
+	id, _ := utils.GenerateID(8)
+	notice := ServerMessage{ID: id, Type: MessageTypeSystem, From: "system", Room: check.Room, Content: "rate limit exceeded", Timestamp: time.Now(), Metadata: metadata}
+	if data, err := json.Marshal(notice); err == nil {
+		// Never block the read loop on a client that isn't reading
+		select {
+		case client.Send <- data:
+		default:
+		}
+	}
+	if d.Penalty == ratelimit.Disconnect {
+		return false, errDisconnect
+	}
+	return false, nil
+}
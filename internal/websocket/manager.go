@@ -2,6 +2,7 @@ package websocket
 
 import (
 	"encoding/json"
+	"errors"
 	"sync"
 	"time"
 
@@ -15,33 +16,150 @@ import (
 type Client struct {
 	ID          string
 	Username    string
+	Role        string // site role from the token; admins moderate every room
 	Conn        *websocket.Conn
 	Send        chan []byte
 	Manager     *Manager
 	Handler     *Handler
 	LastActive  time.Time
 	ConnectedAt time.Time
-	rateTokens  int
-	rateLast    time.Time
+	ip          string // remote address, for per-IP rate limits
+	noGlobal    bool   // banned from the global room
+	focus       string // room being looked at; see setFocus
+	connID      string // names this connection on the backplane
 	mu          sync.Mutex
+
+	// While a resume replays the event log, per-user deliveries wait in
+	// held; afterwards those the replay already sent are dropped
+	eventMu   sync.Mutex
+	replaying bool
+	held      []heldEvent
+	replayed  int64
+}
+
+type heldEvent struct {
+	offset  int64
+	message []byte
+}
+
+// remoteConn mirrors a connection held by another manager on the backplane
+type remoteConn struct {
+	origin   string
+	userID   string
+	username string
+	rooms    map[string]bool
+	focus    string
 }
 
 type Manager struct {
-	clients    map[string]*Client
+	node       string // names this manager on the backplane
+	backplane  Backplane
+	leave      func()                          // ends the backplane subscription
+	clients    map[string]map[*Client]struct{} // user ID -> that user's connections
 	broadcast  chan []byte
 	register   chan *Client
 	unregister chan *Client
 	mu         sync.RWMutex
 	rooms      map[string]map[*Client]struct{}
+	remote     map[string]*remoteConn // origin/conn ID -> connection on another manager
+	seen       map[string]time.Time   // origin -> when that manager was last heard from
+	expired    map[string]bool        // origins forgotten for going quiet
+	liveness   Liveness
+	done       chan struct{}
+	closeOnce  sync.Once
 }
 
+// NewManager creates a manager that keeps chat within this process
 func NewManager() *Manager {
-	return &Manager{
-		clients:    make(map[string]*Client),
+	return NewManagerWithBackplane(NewMemoryBackplane())
+}
+
+// NewManagerWithBackplane creates a manager that shares rooms, direct
+// messages and presence with every other manager on the backplane
+func NewManagerWithBackplane(bp Backplane) *Manager {
+	node, _ := utils.GenerateID(8)
+	m := &Manager{
+		node:       node,
+		backplane:  bp,
+		clients:    make(map[string]map[*Client]struct{}),
 		broadcast:  make(chan []byte, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		rooms:      make(map[string]map[*Client]struct{}),
+		remote:     make(map[string]*remoteConn),
+		seen:       make(map[string]time.Time),
+		expired:    make(map[string]bool),
+		liveness:   LivenessFromEnv(),
+		done:       make(chan struct{}),
+	}
+	leave, err := bp.Subscribe(m.receive)
+	if err != nil {
+		logger.Error("Failed to subscribe to chat backplane", map[string]interface{}{"error": err.Error()})
+		leave = func() {}
+	}
+	m.leave = leave
+	// Ask the managers already running who is connected to them
+	m.publish(Envelope{Kind: envelopeHello})
+	go m.keepAlive()
+	return m
+}
+
+// Close tells the other managers this one's connections are gone and
+// stops listening to the backplane. The backplane itself stays open for
+// any other managers sharing it.
+func (m *Manager) Close() error {
+	m.closeOnce.Do(func() { close(m.done) })
+	m.publish(Envelope{Kind: envelopeBye})
+	m.leave()
+	return nil
+}
+
+// keepAlive sends a heartbeat every so often and forgets the connections
+// of managers that have gone quiet for longer than the TTL
+func (m *Manager) keepAlive() {
+	ticker := time.NewTicker(m.liveness.Heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.publish(Envelope{Kind: envelopeAlive})
+			m.expireRemote(time.Now().Add(-m.liveness.TTL))
+		}
+	}
+}
+
+// expireRemote drops the connections of every manager not heard from since
+// cutoff
+func (m *Manager) expireRemote(cutoff time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for origin, at := range m.seen {
+		if at.After(cutoff) {
+			continue
+		}
+		delete(m.seen, origin)
+		m.expired[origin] = true
+		for key, rc := range m.remote {
+			if rc.origin == origin {
+				delete(m.remote, key)
+			}
+		}
+		logger.Warn("Chat server stopped answering; forgetting its connections", map[string]interface{}{"node": origin})
+	}
+}
+
+// heardFrom records that origin is running. One that was forgotten for
+// going quiet is asked, with a hello, to announce its connections again.
+func (m *Manager) heardFrom(origin string) {
+	m.mu.Lock()
+	m.seen[origin] = time.Now()
+	returned := m.expired[origin]
+	delete(m.expired, origin)
+	m.mu.Unlock()
+	if returned {
+		m.publish(Envelope{Kind: envelopeHello})
 	}
 }
 
@@ -50,23 +168,28 @@ func (m *Manager) Run() {
 		select {
 		case client := <-m.register:
 			m.mu.Lock()
-			m.clients[client.ID] = client
-			client.rateTokens = 20
-			client.rateLast = time.Now()
-			if _, ok := m.rooms["global"]; !ok {
-				m.rooms["global"] = make(map[*Client]struct{})
+			if client.connID == "" {
+				client.connID, _ = utils.GenerateID(8)
+			}
+			if _, ok := m.clients[client.ID]; !ok {
+				m.clients[client.ID] = make(map[*Client]struct{})
 			}
-			m.rooms["global"][client] = struct{}{}
-			metrics.SetActiveConnections(int64(len(m.clients)))
+			m.clients[client.ID][client] = struct{}{}
+			if !client.noGlobal {
+				if _, ok := m.rooms["global"]; !ok {
+					m.rooms["global"] = make(map[*Client]struct{})
+				}
+				m.rooms["global"][client] = struct{}{}
+			}
+			metrics.SetActiveConnections(int64(m.connectionCount()))
+			rooms := m.roomsOf(client)
 			m.mu.Unlock()
+			m.announce(client, rooms)
 
 		case client := <-m.unregister:
 			m.mu.Lock()
-			if _, ok := m.clients[client.ID]; ok {
-				delete(m.clients, client.ID)
-				close(client.Send)
-			}
-			metrics.SetActiveConnections(int64(len(m.clients)))
+			m.removeClient(client)
+			metrics.SetActiveConnections(int64(m.connectionCount()))
 			affectedRooms := []string{}
 			for room, set := range m.rooms {
 				if _, ok := set[client]; ok {
@@ -78,6 +201,7 @@ func (m *Manager) Run() {
 				}
 			}
 			m.mu.Unlock()
+			m.publish(Envelope{Kind: envelopeDisconnect, ConnID: client.connID})
 
 			// Only broadcast leave message if connection lasted more than 2 seconds
 			// This suppresses spam from quick send commands
@@ -93,20 +217,50 @@ func (m *Manager) Run() {
 			}
 
 		case message := <-m.broadcast:
-			m.mu.RLock()
-			for _, client := range m.clients {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
-					delete(m.clients, client.ID)
-				}
+			m.deliverAll(message)
+		}
+	}
+}
+
+func (m *Manager) deliverAll(message []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, conns := range m.clients {
+		for client := range conns {
+			select {
+			case client.Send <- message:
+			default:
+				m.removeClient(client)
 			}
-			m.mu.RUnlock()
 		}
 	}
 }
 
+// removeClient drops one connection and closes its send channel. The
+// caller holds m.mu.
+func (m *Manager) removeClient(c *Client) {
+	conns, ok := m.clients[c.ID]
+	if !ok {
+		return
+	}
+	if _, ok := conns[c]; !ok {
+		return
+	}
+	delete(conns, c)
+	if len(conns) == 0 {
+		delete(m.clients, c.ID)
+	}
+	close(c.Send)
+}
+
+func (m *Manager) connectionCount() int {
+	n := 0
+	for _, conns := range m.clients {
+		n += len(conns)
+	}
+	return n
+}
+
 func (m *Manager) joinRoom(c *Client, room string) {
 	if room == "" {
 		room = "global"
@@ -124,17 +278,112 @@ func (m *Manager) joinRoom(c *Client, room string) {
 		"total_in_room": len(m.rooms[room]),
 	})
 	m.mu.Unlock()
+	m.publish(Envelope{Kind: envelopeJoin, ConnID: c.connID, Room: room})
+}
+
+// leaveRoom takes every connection the user has, on any manager, out of the room
+func (m *Manager) leaveRoom(userID, room string) {
+	m.removeFromRoom(userID, room)
+	m.publish(Envelope{Kind: envelopeKick, UserID: userID, Room: room})
+}
+
+func (m *Manager) removeFromRoom(userID, room string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	set := m.rooms[room]
+	for c := range set {
+		if c.ID == userID {
+			delete(set, c)
+		}
+	}
+	if len(set) == 0 {
+		delete(m.rooms, room)
+	}
+	for _, rc := range m.remote {
+		if rc.userID == userID {
+			delete(rc.rooms, room)
+		}
+	}
+}
+
+// closeRoom drops a deleted room along with everyone in it
+func (m *Manager) closeRoom(room string) {
+	m.dropRoom(room)
+	m.publish(Envelope{Kind: envelopeCloseRoom, Room: room})
+}
+
+func (m *Manager) dropRoom(room string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.rooms, room)
+	for _, rc := range m.remote {
+		delete(rc.rooms, room)
+	}
 }
 
+// broadcastRoom sends to everyone in the room, whichever manager holds
+// their connection
 func (m *Manager) broadcastRoom(room string, message []byte) {
 	if room == "" {
 		room = "global"
 	}
+	m.deliverRoom(room, message)
+	m.publish(Envelope{Kind: envelopeRoom, Room: room, Payload: message})
+}
+
+func (m *Manager) deliverRoom(room string, message []byte) {
+	m.deliverRoomEach(room, func(*Client) []byte { return message })
+}
+
+// broadcastSplit sends full to the users in the room that sees approves and
+// hidden to everyone else there, whichever manager holds their connection.
+// sees is called without m.mu held, so it may block on the store.
+func (m *Manager) broadcastSplit(room string, full, hidden []byte, sees func(userID string) bool) {
+	readers := []string{}
+	for _, userID := range m.roomUserIDs(room) {
+		if sees(userID) {
+			readers = append(readers, userID)
+		}
+	}
+	m.deliverSplit(room, full, hidden, readers)
+	m.publish(Envelope{Kind: envelopeSplit, Room: room, Payload: full, Hidden: hidden, Readers: readers})
+}
+
+func (m *Manager) deliverSplit(room string, full, hidden []byte, readers []string) {
+	m.deliverRoomEach(room, func(c *Client) []byte {
+		if containsString(readers, c.ID) {
+			return full
+		}
+		return hidden
+	})
+}
+
+// roomUserIDs lists the users with a connection in the room on any manager
+func (m *Manager) roomUserIDs(room string) []string {
 	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids := []string{}
+	for c := range m.rooms[room] {
+		if !containsString(ids, c.ID) {
+			ids = append(ids, c.ID)
+		}
+	}
+	for _, rc := range m.remote {
+		if rc.rooms[room] && !containsString(ids, rc.userID) {
+			ids = append(ids, rc.userID)
+		}
+	}
+	return ids
+}
+
+// deliverRoomEach sends each local connection in the room the message pick
+// chooses for it
+func (m *Manager) deliverRoomEach(room string, pick func(c *Client) []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	set, ok := m.rooms[room]
 	if !ok {
-		logger.Warn("Room not found for broadcast", map[string]interface{}{"room": room})
-		m.mu.RUnlock()
+		logger.Debug("No local connections in room", map[string]interface{}{"room": room})
 		return
 	}
 	logger.Debug("Broadcasting to room", map[string]interface{}{
@@ -143,34 +392,70 @@ func (m *Manager) broadcastRoom(room string, message []byte) {
 	})
 	for c := range set {
 		select {
-		case c.Send <- message:
+		case c.Send <- pick(c):
 			logger.Debug("Message sent", map[string]interface{}{"to": c.Username})
 		default:
 			logger.Warn("Send channel full", map[string]interface{}{"user": c.Username})
-			close(c.Send)
-			delete(m.clients, c.ID)
+			m.removeClient(c)
 			delete(set, c)
 		}
 	}
-	m.mu.RUnlock()
 }
+
+// GetClient returns one of the user's connections to this manager
 func (m *Manager) GetClient(userID string) (*Client, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	client, ok := m.clients[userID]
-	return client, ok
+	for client := range m.clients[userID] {
+		return client, true
+	}
+	return nil, false
+}
+
+// isOnline reports whether the user is connected to any manager
+func (m *Manager) isOnline(userID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.clients[userID]) > 0 {
+		return true
+	}
+	for _, rc := range m.remote {
+		if rc.userID == userID {
+			return true
+		}
+	}
+	return false
 }
 
+// GetActiveUsers lists the users connected to any manager
 func (m *Manager) GetActiveUsers() []string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
+	return m.activeUsers()
+}
+
+func (m *Manager) activeUsers() []string {
 	users := make([]string, 0, len(m.clients))
 	for id := range m.clients {
 		users = append(users, id)
 	}
+	for _, rc := range m.remote {
+		if _, ok := m.clients[rc.userID]; !ok && !containsString(users, rc.userID) {
+			users = append(users, rc.userID)
+		}
+	}
 	return users
 }
 
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *Manager) GetRoomUsers(room string) []map[string]interface{} {
 	if room == "" {
 		room = "global"
@@ -178,11 +463,7 @@ func (m *Manager) GetRoomUsers(room string) []map[string]interface{} {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	roomClients, ok := m.rooms[room]
-	if !ok {
-		return []map[string]interface{}{}
-	}
-
+	roomClients := m.rooms[room]
 	users := make([]map[string]interface{}, 0, len(roomClients))
 	for client := range roomClients {
 		users = append(users, map[string]interface{}{
@@ -191,13 +472,22 @@ func (m *Manager) GetRoomUsers(room string) []map[string]interface{} {
 			"room":     room,
 		})
 	}
+	for _, rc := range m.remote {
+		if rc.rooms[room] {
+			users = append(users, map[string]interface{}{
+				"id":       rc.userID,
+				"username": rc.username,
+				"room":     room,
+			})
+		}
+	}
 	return users
 }
 
 func (m *Manager) GetClientCount() int {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return len(m.clients)
+	return len(m.activeUsers())
 }
 
 func (m *Manager) GetRoomClientCount(room string) int {
@@ -207,42 +497,206 @@ func (m *Manager) GetRoomClientCount(room string) int {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	roomClients, ok := m.rooms[room]
-	if !ok {
-		return 0
+	n := len(m.rooms[room])
+	for _, rc := range m.remote {
+		if rc.rooms[room] {
+			n++
+		}
 	}
-	return len(roomClients)
+	return n
 }
 
 func (m *Manager) BroadcastMessage(message []byte) {
 	m.broadcast <- message
+	m.publish(Envelope{Kind: envelopeAll, Payload: message})
 }
 
+// SendToUser delivers to every connection the user has open on any manager,
+// reporting whether a local connection took the message or the user is
+// connected elsewhere
 func (m *Manager) SendToUser(userID string, message []byte) bool {
+	return m.SendEventToUser(userID, 0, message)
+}
+
+// SendEventToUser is SendToUser for a bridge event at the given event log
+// offset, which a connection still replaying the log must not see twice
+func (m *Manager) SendEventToUser(userID string, offset int64, message []byte) bool {
+	sent := m.deliverUser(userID, offset, message)
+	m.publish(Envelope{Kind: envelopeUser, UserID: userID, Payload: message, Offset: offset})
+	return sent || m.isOnline(userID)
+}
+
+func (m *Manager) deliverUser(userID string, offset int64, message []byte) bool {
 	m.mu.RLock()
-	client, ok := m.clients[userID]
-	m.mu.RUnlock()
+	defer m.mu.RUnlock()
 
-	if !ok {
-		return false
+	sent := false
+	for client := range m.clients[userID] {
+		if client.sendEvent(offset, message) {
+			sent = true
+		}
 	}
+	return sent
+}
+
+// holdEvents starts holding per-user deliveries until releaseEvents; call
+// it before the client is registered so none slip past the replay
+func (c *Client) holdEvents() {
+	c.eventMu.Lock()
+	c.replaying = true
+	c.eventMu.Unlock()
+}
 
+// releaseEvents ends a replay that sent everything up to lastOffset and
+// passes on the held deliveries it did not cover
+func (c *Client) releaseEvents(lastOffset int64) {
+	c.eventMu.Lock()
+	defer c.eventMu.Unlock()
+	c.replaying = false
+	c.replayed = lastOffset
+	for _, ev := range c.held {
+		if ev.offset > 0 && ev.offset <= lastOffset {
+			continue
+		}
+		select {
+		case c.Send <- ev.message:
+		default:
+		}
+	}
+	c.held = nil
+}
+
+func (c *Client) sendEvent(offset int64, message []byte) bool {
+	c.eventMu.Lock()
+	defer c.eventMu.Unlock()
+	if c.replaying {
+		c.held = append(c.held, heldEvent{offset, message})
+		return true
+	}
+	if offset > 0 && offset <= c.replayed {
+		return true
+	}
 	select {
-	case client.Send <- message:
+	case c.Send <- message:
 		return true
 	default:
 		return false
 	}
 }
 
+// publish puts an envelope on the backplane. Callers must not hold m.mu.
+func (m *Manager) publish(env Envelope) {
+	env.Origin = m.node
+	if err := m.backplane.Publish(env); err != nil {
+		logger.Warn("Failed to publish to chat backplane", map[string]interface{}{"error": err.Error(), "kind": env.Kind})
+	}
+}
+
+// roomsOf lists the rooms a local connection is in. The caller holds m.mu.
+func (m *Manager) roomsOf(c *Client) []string {
+	rooms := []string{}
+	for room, set := range m.rooms {
+		if _, ok := set[c]; ok {
+			rooms = append(rooms, room)
+		}
+	}
+	return rooms
+}
+
+// announce tells the other managers about a local connection
+func (m *Manager) announce(c *Client, rooms []string) {
+	m.publish(Envelope{Kind: envelopeConnect, ConnID: c.connID, UserID: c.ID, Username: c.Username})
+	for _, room := range rooms {
+		m.publish(Envelope{Kind: envelopeJoin, ConnID: c.connID, Room: room})
+	}
+	if focus := c.focused(); focus != "" {
+		m.publish(Envelope{Kind: envelopeFocus, ConnID: c.connID, Room: focus})
+	}
+}
+
+// receive handles an envelope from another manager
+func (m *Manager) receive(env Envelope) {
+	if env.Origin == m.node {
+		return
+	}
+	if env.Kind != envelopeBye {
+		m.heardFrom(env.Origin)
+	}
+	switch env.Kind {
+	case envelopeRoom:
+		m.deliverRoom(env.Room, env.Payload)
+	case envelopeSplit:
+		m.deliverSplit(env.Room, env.Payload, env.Hidden, env.Readers)
+	case envelopeUser:
+		m.deliverUser(env.UserID, env.Offset, env.Payload)
+	case envelopeAll:
+		m.deliverAll(env.Payload)
+	case envelopeKick:
+		m.removeFromRoom(env.UserID, env.Room)
+	case envelopeCloseRoom:
+		m.dropRoom(env.Room)
+	case envelopeAlive:
+		// heardFrom has noted it
+	case envelopeHello:
+		type local struct {
+			client *Client
+			rooms  []string
+		}
+		var conns []local
+		m.mu.RLock()
+		for _, set := range m.clients {
+			for c := range set {
+				conns = append(conns, local{c, m.roomsOf(c)})
+			}
+		}
+		m.mu.RUnlock()
+		for _, l := range conns {
+			m.announce(l.client, l.rooms)
+		}
+	default:
+		m.trackRemote(env)
+	}
+}
+
+// trackRemote keeps the mirror of other managers' connections up to date
+func (m *Manager) trackRemote(env Envelope) {
+	key := env.Origin + "/" + env.ConnID
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch env.Kind {
+	case envelopeConnect:
+		m.remote[key] = &remoteConn{origin: env.Origin, userID: env.UserID, username: env.Username, rooms: map[string]bool{}}
+	case envelopeDisconnect:
+		delete(m.remote, key)
+	case envelopeJoin:
+		if rc, ok := m.remote[key]; ok {
+			rc.rooms[env.Room] = true
+		}
+	case envelopeFocus:
+		if rc, ok := m.remote[key]; ok {
+			rc.focus = env.Room
+		}
+	case envelopeBye:
+		delete(m.seen, env.Origin)
+		for k, rc := range m.remote {
+			if rc.origin == env.Origin {
+				delete(m.remote, k)
+			}
+		}
+	}
+}
+
 func (c *Client) ReadPump(connID string, bridge *bridge.UnifiedBridge, broadcaster *WSBroadcaster) {
+	kicked := false
 	defer func() {
 		if bridge != nil && connID != "" {
 			bridge.UnregisterProtocolClient(connID, c.ID)
 			broadcaster.UnregisterConnection(connID)
 		}
 		c.Manager.unregister <- c
-		c.Conn.Close()
+		if !kicked {
+			c.Conn.Close()
+		}
 	}()
 
 	c.Conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -262,24 +716,19 @@ func (c *Client) ReadPump(connID string, bridge *bridge.UnifiedBridge, broadcast
 			break
 		}
 		c.UpdateActivity()
-
-		// rate limiting: 20 messages per 10s window
-		if !c.consumeRateToken() {
-			metrics.IncrementRateLimited()
-			id, _ := utils.GenerateID(8)
-			msg := ServerMessage{ID: id, Type: MessageTypeSystem, From: "system", Room: "global", Content: "rate limit exceeded", Timestamp: time.Now(), Metadata: map[string]interface{}{"limit": 20}}
-			if data, e := json.Marshal(msg); e == nil {
-				select {
-				case c.Send <- data:
-				default:
-				}
-			}
-			continue
+		if bridge != nil && connID != "" {
+			bridge.TouchProtocolClient(connID, c.ID)
 		}
 
 		if c.Handler != nil {
 			metrics.IncrementMessages()
-			if err := c.Handler.HandleClientMessage(c, message); err != nil {
+			err := c.Handler.HandleClientMessage(c, message)
+			if errors.Is(err, errDisconnect) {
+				// Let WritePump deliver the notice before it closes the connection
+				kicked = true
+				break
+			}
+			if err != nil {
 				logger.Error("Failed to handle message", map[string]interface{}{"error": err.Error(), "client_id": c.ID})
 			}
 		} else {
@@ -288,21 +737,6 @@ func (c *Client) ReadPump(connID string, bridge *bridge.UnifiedBridge, broadcast
 	}
 }
 
-func (c *Client) consumeRateToken() bool {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	now := time.Now()
-	if now.Sub(c.rateLast) >= 10*time.Second {
-		c.rateTokens = 20
-		c.rateLast = now
-	}
-	if c.rateTokens <= 0 {
-		return false
-	}
-	c.rateTokens--
-	return true
-}
-
 func (c *Client) UpdateActivity() {
 	c.mu.Lock()
 	c.LastActive = time.Now()
@@ -0,0 +1,234 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultAlertLimit = 20
+	maxAlertLimit     = 100
+)
+
+var mentionPattern = regexp.MustCompile(`@([\p{L}\p{N}_.\-]+)`)
+
+// parseMentions returns the distinct usernames mentioned in a message. A
+// full stop ending the sentence is not part of the name.
+func parseMentions(content string) []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		name := strings.TrimRight(match[1], ".")
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// raiseAlerts records an alert for every user mentioned in a room message
+// and for everyone watching a keyword it contains, then sends each alert
+// through the bridge so it reaches them outside the chat too. The sender and
+// users banned from the room are never alerted, and nobody is alerted twice
+// about the same message.
+func (h *Handler) raiseAlerts(client *Client, room string, msg *models.ChatMessage) {
+	ctx := context.Background()
+	conv, err := h.store.Conversations.Get(ctx, msg.ConversationID)
+	if err != nil || conv.Type == "direct" {
+		return
+	}
+	alerted := map[string]bool{client.ID: true}
+
+	for _, name := range parseMentions(msg.Content) {
+		u, err := h.store.Users.GetByUsername(ctx, name)
+		if err != nil || alerted[u.ID] || !h.canRead(u.ID, conv) {
+			continue
+		}
+		alerted[u.ID] = true
+		h.recordAlert(client, room, msg, &models.ChatAlert{UserID: u.ID, Kind: repository.AlertMention})
+	}
+
+	keywords, err := h.store.Alerts.KeywordsForConversation(ctx, conv.ID)
+	if err != nil {
+		logger.Warn("Failed to load keyword alerts", map[string]interface{}{"error": err.Error(), "conversation_id": conv.ID})
+		return
+	}
+	content := strings.ToLower(msg.Content)
+	for _, k := range keywords {
+		if alerted[k.UserID] || !strings.Contains(content, k.Keyword) || !h.canRead(k.UserID, conv) {
+			continue
+		}
+		// Naming the keyword would tell a reader who is behind what the
+		// spoiler is about
+		if msg.SpoilerChapter > 0 && msg.SpoilerChapter > h.readerChapter(k.UserID, roomManga(room)) {
+			continue
+		}
+		alerted[k.UserID] = true
+		h.recordAlert(client, room, msg, &models.ChatAlert{UserID: k.UserID, Kind: repository.AlertKeyword, Keyword: k.Keyword})
+	}
+}
+
+func (h *Handler) recordAlert(client *Client, room string, msg *models.ChatMessage, alert *models.ChatAlert) {
+	alert.MessageID = msg.ID
+	alert.ConversationID = msg.ConversationID
+	recorded, err := h.store.Alerts.Record(context.Background(), alert)
+	if err != nil {
+		logger.Warn("Failed to record chat alert", map[string]interface{}{"error": err.Error(), "user_id": alert.UserID})
+		return
+	}
+	if !recorded {
+		return
+	}
+
+	eventType := bridge.EventChatMention
+	data := map[string]interface{}{
+		"room":            room,
+		"from":            client.Username,
+		"content":         msg.Content,
+		"message_id":      msg.ID,
+		"conversation_id": msg.ConversationID,
+		"kind":            alert.Kind,
+	}
+	if alert.Kind == repository.AlertKeyword {
+		eventType = bridge.EventChatKeyword
+		data["keyword"] = alert.Keyword
+	}
+	if msg.SpoilerChapter > 0 && msg.SpoilerChapter > h.readerChapter(alert.UserID, roomManga(room)) {
+		data["content"] = ""
+		data["chapter"] = msg.SpoilerChapter
+	}
+	event := bridge.NewUnifiedEvent(eventType, alert.UserID, bridge.ProtocolWebSocket, data)
+	if h.bridge != nil {
+		// Logged first so the push carries the offset a resuming client
+		// checks it against
+		event.Offset = h.bridge.BroadcastEvent(event)
+	}
+	if payload, err := json.Marshal(event); err == nil {
+		client.Manager.SendEventToUser(alert.UserID, event.Offset, payload)
+	}
+}
+
+// handleAlertCommand runs /mentions, /alerts, /alert and /unalert. Keywords
+// watch the current room, or every room when the keyword follows a "*".
+func (h *Handler) handleAlertCommand(client *Client, cmd string, args []string, room string) *ServerMessage {
+	ctx := context.Background()
+	if room == "" {
+		room = "global"
+	}
+	id, _ := utils.GenerateID(16)
+	reply := func(t MessageType, content string, metadata map[string]interface{}) *ServerMessage {
+		return &ServerMessage{ID: id, Type: t, From: "system", Room: room, Content: content, Timestamp: time.Now(), Metadata: metadata}
+	}
+
+	switch cmd {
+	case "mentions":
+		limit := defaultAlertLimit
+		if len(args) > 0 {
+			if n, err := strconv.Atoi(args[0]); err == nil && n > 0 && n <= maxAlertLimit {
+				limit = n
+			}
+		}
+		alerts, err := h.store.Alerts.ListForUser(ctx, client.ID, limit)
+		if err != nil {
+			return reply(MessageTypeError, fmt.Sprintf("Failed to load mentions: %v", err), nil)
+		}
+		alerts = h.hideAlertSpoilers(client.ID, alerts)
+		return reply(MessageTypeSystem, "Recent mentions and alerts", map[string]interface{}{"alerts": alerts, "count": len(alerts)})
+
+	case "alerts":
+		keywords, err := h.store.Alerts.Keywords(ctx, client.ID)
+		if err != nil {
+			return reply(MessageTypeError, fmt.Sprintf("Failed to load keyword alerts: %v", err), nil)
+		}
+		return reply(MessageTypeSystem, "Keyword alerts", map[string]interface{}{"keywords": keywords, "count": len(keywords)})
+	}
+
+	// /alert and /unalert
+	everywhere := len(args) > 0 && args[0] == "*"
+	if everywhere {
+		args = args[1:]
+	}
+	keyword, err := repository.NormalizeKeyword(strings.Join(args, " "))
+	if err != nil {
+		return reply(MessageTypeError, fmt.Sprintf("Usage: /%s [*] <keyword> (%v)", cmd, err), nil)
+	}
+	convID, where := "", "every room"
+	if !everywhere {
+		conv, err := h.store.Conversations.GetByName(ctx, room)
+		if err != nil {
+			return reply(MessageTypeError, fmt.Sprintf("Room '%s' not found", room), nil)
+		}
+		if conv.Type == "direct" {
+			return reply(MessageTypeError, "Keyword alerts are for rooms", nil)
+		}
+		convID, where = conv.ID, "#"+room
+	}
+
+	if cmd == "unalert" {
+		err := h.store.Alerts.RemoveKeyword(ctx, client.ID, convID, keyword)
+		if errors.Is(err, repository.ErrNotFound) {
+			return reply(MessageTypeError, fmt.Sprintf("You aren't watching %q in %s", keyword, where), nil)
+		}
+		if err != nil {
+			return reply(MessageTypeError, fmt.Sprintf("Failed to remove alert: %v", err), nil)
+		}
+		return reply(MessageTypeSystem, fmt.Sprintf("No longer watching %q in %s", keyword, where), nil)
+	}
+
+	existing, err := h.store.Alerts.Keywords(ctx, client.ID)
+	if err != nil {
+		return reply(MessageTypeError, fmt.Sprintf("Failed to add alert: %v", err), nil)
+	}
+	if len(existing) >= repository.MaxKeywordAlerts {
+		return reply(MessageTypeError, fmt.Sprintf("You can watch at most %d keywords; remove one with /unalert", repository.MaxKeywordAlerts), nil)
+	}
+	err = h.store.Alerts.AddKeyword(ctx, client.ID, convID, keyword)
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		return reply(MessageTypeError, fmt.Sprintf("You are already watching %q in %s", keyword, where), nil)
+	}
+	if err != nil {
+		return reply(MessageTypeError, fmt.Sprintf("Failed to add alert: %v", err), nil)
+	}
+	return reply(MessageTypeSystem, fmt.Sprintf("Watching %q in %s", keyword, where), map[string]interface{}{"keyword": keyword})
+}
+
+// ListMentions handles GET /users/mentions?limit=20, the user's latest
+// mentions and keyword alerts, newest first
+func (h *InboxHandler) ListMentions(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	limit := defaultAlertLimit
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		limit = n
+	}
+	if limit > maxAlertLimit {
+		limit = maxAlertLimit
+	}
+
+	alerts, err := h.chat.store.Alerts.ListForUser(c.Request.Context(), userID, limit)
+	if err != nil {
+		logger.Error("Failed to list chat alerts", map[string]interface{}{"error": err.Error(), "user_id": userID})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load mentions"})
+		return
+	}
+	alerts = h.chat.hideAlertSpoilers(userID, alerts)
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts, "count": len(alerts)})
+}
@@ -0,0 +1,390 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+)
+
+const (
+	maxTopicLength  = 200
+	maxSlowModeSecs = 3600
+)
+
+var moderationPastTense = map[string]string{
+	"ban": "banned", "unban": "unbanned", "mute": "muted", "unmute": "unmuted",
+	"promote": "promoted", "demote": "demoted",
+}
+
+// roomRole is the user's role in a room. Site admins moderate every room as
+// if they owned it, which is the only way to moderate global and manga rooms.
+func (h *Handler) roomRole(userID, siteRole, conversationID string) string {
+	if siteRole == "admin" {
+		return repository.RoleOwner
+	}
+	role, err := h.store.Conversations.MemberRole(context.Background(), conversationID, userID)
+	if err != nil {
+		return repository.RoleMember
+	}
+	return role
+}
+
+// sanctionError describes a ban or mute to the user it applies to
+func sanctionError(s *models.RoomSanction) error {
+	what := "banned from"
+	if s.Kind == repository.SanctionMute {
+		what = "muted in"
+	}
+	msg := "You are " + what + " this room"
+	if s.ExpiresAt != nil {
+		msg += " until " + s.ExpiresAt.Local().Format("Jan 02 15:04")
+	}
+	if s.Reason != "" {
+		msg += " (" + s.Reason + ")"
+	}
+	return errors.New(msg)
+}
+
+// errSanctionCheck refuses users whose sanctions couldn't be looked up, so a
+// database failure doesn't let banned or muted users through
+var errSanctionCheck = errors.New("Could not check your access to this room; try again shortly")
+
+// checkBanned returns an error for users banned from the conversation
+func (h *Handler) checkBanned(userID, conversationID string) error {
+	return h.checkSanction(userID, conversationID, repository.SanctionBan)
+}
+
+// checkMuted returns an error for users muted in the conversation
+func (h *Handler) checkMuted(userID, conversationID string) error {
+	return h.checkSanction(userID, conversationID, repository.SanctionMute)
+}
+
+func (h *Handler) checkSanction(userID, conversationID, kind string) error {
+	s, err := h.store.Conversations.ActiveSanction(context.Background(), conversationID, userID, kind)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		logger.Error("Failed to check room sanctions", map[string]interface{}{"error": err.Error(), "user_id": userID, "conversation_id": conversationID})
+		return errSanctionCheck
+	}
+	return sanctionError(s)
+}
+
+// bannedFromRoom looks a room up by name; rooms that don't exist yet have no bans
+func (h *Handler) bannedFromRoom(userID, roomName string) bool {
+	conv, err := h.store.Conversations.GetByName(context.Background(), roomName)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false
+	}
+	return err != nil || h.checkBanned(userID, conv.ID) != nil
+}
+
+// checkPost enforces bans, mutes and slow mode before a message is stored.
+// Moderators and owners are exempt from slow mode.
+func (h *Handler) checkPost(client *Client, conversationID string) error {
+	ctx := context.Background()
+	if err := h.checkBanned(client.ID, conversationID); err != nil {
+		return err
+	}
+	if err := h.checkMuted(client.ID, conversationID); err != nil {
+		return err
+	}
+
+	conv, err := h.store.Conversations.Get(ctx, conversationID)
+	if err != nil || conv.SlowModeSeconds == 0 {
+		return nil
+	}
+	if repository.RoleRank(h.roomRole(client.ID, client.Role, conversationID)) >= repository.RoleRank(repository.RoleModerator) {
+		return nil
+	}
+
+	key := conversationID + "/" + client.ID
+	h.slowMu.Lock()
+	defer h.slowMu.Unlock()
+	h.pruneSlowMode()
+	if last, ok := h.lastPosted[key]; ok {
+		if wait := time.Duration(conv.SlowModeSeconds)*time.Second - time.Since(last); wait > 0 {
+			return fmt.Errorf("Slow mode is on: wait %ds before posting again", int(wait.Seconds())+1)
+		}
+	}
+	h.lastPosted[key] = time.Now()
+	return nil
+}
+
+// pruneSlowMode forgets posts too old to hold anyone up, at most once a
+// minute. The caller holds slowMu.
+func (h *Handler) pruneSlowMode() {
+	now := time.Now()
+	if now.Sub(h.slowPruned) < time.Minute {
+		return
+	}
+	h.slowPruned = now
+	for key, at := range h.lastPosted {
+		if now.Sub(at) >= maxSlowModeSecs*time.Second {
+			delete(h.lastPosted, key)
+		}
+	}
+}
+
+// forgetPosts drops slow mode timestamps for a whole room, or for one user
+// in it when userID is set
+func (h *Handler) forgetPosts(conversationID, userID string) {
+	h.slowMu.Lock()
+	defer h.slowMu.Unlock()
+	for key := range h.lastPosted {
+		if (userID == "" && strings.HasPrefix(key, conversationID+"/")) || key == conversationID+"/"+userID {
+			delete(h.lastPosted, key)
+		}
+	}
+}
+
+// parseModerationDuration accepts Go durations plus whole days, e.g. 10m, 2h, 7d
+func parseModerationDuration(s string) (time.Duration, bool) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days <= 0 {
+			return 0, false
+		}
+		return time.Duration(days) * 24 * time.Hour, true
+	}
+	d, err := time.ParseDuration(s)
+	return d, err == nil && d > 0
+}
+
+// handleModeration runs a room moderation command. It announces successful
+// actions to the room itself and returns the reply for the caller, or nil
+// when the announcement is the only reply needed.
+func (h *Handler) handleModeration(client *Client, cmd string, args []string, room string) *ServerMessage {
+	ctx := context.Background()
+	if room == "" {
+		room = "global"
+	}
+	id, _ := utils.GenerateID(16)
+	reply := func(t MessageType, content string) *ServerMessage {
+		return &ServerMessage{ID: id, Type: t, From: "system", Room: room, Content: content, Timestamp: time.Now()}
+	}
+
+	conv, err := h.store.Conversations.GetByName(ctx, room)
+	if err != nil {
+		return reply(MessageTypeError, fmt.Sprintf("Room '%s' not found", room))
+	}
+	if conv.Type == "direct" {
+		return reply(MessageTypeError, "Direct messages can't be moderated")
+	}
+	role := h.roomRole(client.ID, client.Role, conv.ID)
+	need := func(min string) *ServerMessage {
+		if repository.RoleRank(role) < repository.RoleRank(min) {
+			return reply(MessageTypeError, fmt.Sprintf("/%s needs the %s role in this room", cmd, min))
+		}
+		return nil
+	}
+
+	switch cmd {
+	case "topic":
+		if len(args) == 0 {
+			if conv.Topic == "" {
+				return reply(MessageTypeSystem, "No topic set")
+			}
+			return reply(MessageTypeSystem, "Topic: "+conv.Topic)
+		}
+		if denied := need(repository.RoleModerator); denied != nil {
+			return denied
+		}
+		topic := strings.Join(args, " ")
+		if topic == "clear" {
+			topic = ""
+		}
+		if len([]rune(topic)) > maxTopicLength {
+			return reply(MessageTypeError, fmt.Sprintf("Topics are limited to %d characters", maxTopicLength))
+		}
+		if err := h.store.Conversations.SetTopic(ctx, conv.ID, topic); err != nil {
+			return reply(MessageTypeError, fmt.Sprintf("Failed to set topic: %v", err))
+		}
+		if topic == "" {
+			h.announce(client, room, cmd, "", fmt.Sprintf("%s cleared the topic", client.Username), nil)
+		} else {
+			h.announce(client, room, cmd, "", fmt.Sprintf("%s set the topic: %s", client.Username, topic), nil)
+		}
+		return nil
+
+	case "slowmode":
+		if denied := need(repository.RoleModerator); denied != nil {
+			return denied
+		}
+		if len(args) == 0 {
+			return reply(MessageTypeError, "Usage: /slowmode <seconds> (0 turns it off)")
+		}
+		seconds, err := strconv.Atoi(args[0])
+		if err != nil || seconds < 0 || seconds > maxSlowModeSecs {
+			return reply(MessageTypeError, fmt.Sprintf("Slow mode must be 0-%d seconds", maxSlowModeSecs))
+		}
+		if err := h.store.Conversations.SetSlowMode(ctx, conv.ID, seconds); err != nil {
+			return reply(MessageTypeError, fmt.Sprintf("Failed to set slow mode: %v", err))
+		}
+		content := fmt.Sprintf("%s turned slow mode off", client.Username)
+		if seconds == 0 {
+			h.forgetPosts(conv.ID, "")
+		}
+		if seconds > 0 {
+			content = fmt.Sprintf("%s turned on slow mode: one message every %ds", client.Username, seconds)
+		}
+		h.announce(client, room, cmd, "", content, map[string]interface{}{"seconds": seconds})
+		return nil
+
+	case "delete-room":
+		if denied := need(repository.RoleOwner); denied != nil {
+			return denied
+		}
+		if conv.Type != "custom" {
+			return reply(MessageTypeError, "Only custom rooms can be deleted")
+		}
+		if err := h.store.Conversations.Delete(ctx, conv.ID); err != nil {
+			return reply(MessageTypeError, fmt.Sprintf("Failed to delete room: %v", err))
+		}
+		h.forgetPosts(conv.ID, "")
+		h.announce(client, room, cmd, "", fmt.Sprintf("Room '%s' was deleted by %s", room, client.Username), nil)
+		client.Manager.closeRoom(room)
+		logger.Info("Room deleted", map[string]interface{}{"room": room, "by": client.ID})
+		return nil
+	}
+
+	// The rest act on another user: /<cmd> <user> ...
+	if len(args) == 0 {
+		switch cmd {
+		case "ban", "mute":
+			return reply(MessageTypeError, fmt.Sprintf("Usage: /%s <user> [duration] [reason]", cmd))
+		}
+		return reply(MessageTypeError, fmt.Sprintf("Usage: /%s <user>", cmd))
+	}
+	min := repository.RoleModerator
+	if cmd == "promote" || cmd == "demote" {
+		min = repository.RoleOwner
+	}
+	if denied := need(min); denied != nil {
+		return denied
+	}
+	target, err := h.resolveUser(args[0])
+	if err != nil {
+		return reply(MessageTypeError, fmt.Sprintf("Can't find user %s", args[0]))
+	}
+	if target.ID == client.ID {
+		return reply(MessageTypeError, fmt.Sprintf("You can't /%s yourself", cmd))
+	}
+	targetRole := h.roomRole(target.ID, target.Role, conv.ID)
+	if repository.RoleRank(targetRole) >= repository.RoleRank(role) {
+		return reply(MessageTypeError, fmt.Sprintf("%s is a room %s; you can only act on lower roles", target.Username, targetRole))
+	}
+
+	switch cmd {
+	case "kick":
+		h.store.Conversations.RemoveMember(ctx, conv.ID, target.ID)
+		h.notify(client, target.ID, room, fmt.Sprintf("You were kicked from %s by %s", room, client.Username))
+		client.Manager.leaveRoom(target.ID, room)
+		h.forgetPosts(conv.ID, target.ID)
+		h.announce(client, room, cmd, target.Username, fmt.Sprintf("%s was kicked by %s", target.Username, client.Username), nil)
+
+	case "ban", "mute":
+		kind := repository.SanctionBan
+		if cmd == "mute" {
+			kind = repository.SanctionMute
+		}
+		sanction := &models.RoomSanction{ConversationID: conv.ID, UserID: target.ID, Kind: kind, CreatedBy: client.ID}
+		rest, length := args[1:], ""
+		if len(rest) > 0 {
+			if d, ok := parseModerationDuration(rest[0]); ok {
+				expires := time.Now().Add(d)
+				sanction.ExpiresAt = &expires
+				length, rest = rest[0], rest[1:]
+			}
+		}
+		sanction.Reason = strings.Join(rest, " ")
+		if err := h.store.Conversations.AddSanction(ctx, sanction); err != nil {
+			return reply(MessageTypeError, fmt.Sprintf("Failed to %s %s: %v", cmd, target.Username, err))
+		}
+
+		content := fmt.Sprintf("%s was %s by %s", target.Username, moderationPastTense[cmd], client.Username)
+		if length != "" {
+			content += " for " + length
+		}
+		if sanction.Reason != "" {
+			content += ": " + sanction.Reason
+		}
+		metadata := map[string]interface{}{}
+		if sanction.ExpiresAt != nil {
+			metadata["expires_at"] = *sanction.ExpiresAt
+		}
+		h.notify(client, target.ID, room, sanctionError(sanction).Error())
+		if kind == repository.SanctionBan {
+			h.store.Conversations.RemoveMember(ctx, conv.ID, target.ID)
+			client.Manager.leaveRoom(target.ID, room)
+			h.forgetPosts(conv.ID, target.ID)
+		}
+		h.announce(client, room, cmd, target.Username, content, metadata)
+
+	case "unban", "unmute":
+		kind := repository.SanctionBan
+		if cmd == "unmute" {
+			kind = repository.SanctionMute
+		}
+		if err := h.store.Conversations.RemoveSanction(ctx, conv.ID, target.ID, kind); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return reply(MessageTypeError, fmt.Sprintf("%s isn't %s here", target.Username, moderationPastTense[kind]))
+			}
+			return reply(MessageTypeError, fmt.Sprintf("Failed to %s %s: %v", cmd, target.Username, err))
+		}
+		h.notify(client, target.ID, room, fmt.Sprintf("You were %s in %s by %s", moderationPastTense[cmd], room, client.Username))
+		h.announce(client, room, cmd, target.Username, fmt.Sprintf("%s was %s by %s", target.Username, moderationPastTense[cmd], client.Username), nil)
+
+	case "promote", "demote":
+		newRole := repository.RoleModerator
+		if cmd == "demote" {
+			newRole = repository.RoleMember
+		}
+		if targetRole == newRole {
+			return reply(MessageTypeError, fmt.Sprintf("%s is already a %s", target.Username, newRole))
+		}
+		if err := h.store.Conversations.SetMemberRole(ctx, conv.ID, target.ID, newRole); err != nil {
+			return reply(MessageTypeError, fmt.Sprintf("Failed to %s %s: %v", cmd, target.Username, err))
+		}
+		h.announce(client, room, cmd, target.Username, fmt.Sprintf("%s is now a %s (%s by %s)", target.Username, newRole, moderationPastTense[cmd], client.Username),
+			map[string]interface{}{"role": newRole})
+	}
+	return nil
+}
+
+// announce tells the room about a moderation action
+func (h *Handler) announce(client *Client, room, action, target, content string, metadata map[string]interface{}) {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata["moderation"] = action
+	metadata["by"] = client.Username
+	if target != "" {
+		metadata["target"] = target
+	}
+	id, _ := utils.GenerateID(16)
+	data, err := json.Marshal(ServerMessage{ID: id, Type: MessageTypeSystem, From: "system", Room: room, Content: content, Timestamp: time.Now(), Metadata: metadata})
+	if err != nil {
+		return
+	}
+	client.Manager.broadcastRoom(room, data)
+	logger.Info("Room moderation", map[string]interface{}{"room": room, "action": action, "by": client.ID, "target": target})
+}
+
+// notify sends a system message to every connection of the affected user
+func (h *Handler) notify(client *Client, userID, room, content string) {
+	id, _ := utils.GenerateID(16)
+	if data, err := json.Marshal(ServerMessage{ID: id, Type: MessageTypeSystem, From: "system", Room: room, Content: content, Timestamp: time.Now()}); err == nil {
+		client.Manager.SendToUser(userID, data)
+	}
+}
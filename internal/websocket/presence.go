@@ -0,0 +1,78 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/presence"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+)
+
+// SetPresence announces the presence changes the service sees in the rooms
+// each user is in
+func (s *Server) SetPresence(svc *presence.Service) {
+	svc.Subscribe(s.handler.announcePresence)
+}
+
+// announcePresence tells the user's rooms they went idle, away or came
+// back. Coming online and going offline are already announced as joins and
+// leaves.
+func (h *Handler) announcePresence(p models.UserPresence, previous string) {
+	if p.Status == repository.PresenceOffline || previous == repository.PresenceOffline {
+		return
+	}
+	content := p.Username + " is " + p.Status
+	if p.Status == repository.PresenceOnline {
+		content = p.Username + " is back"
+	}
+	event := PresenceEvent{UserID: p.UserID, Username: p.Username, Status: p.Status, Timestamp: time.Now()}
+
+	for _, room := range h.manager.roomsOfUser(p.UserID) {
+		id, _ := utils.GenerateID(16)
+		msg := ServerMessage{
+			ID:        id,
+			Type:      MessageTypePresence,
+			From:      "system",
+			Room:      room,
+			Content:   content,
+			Timestamp: event.Timestamp,
+			Metadata: map[string]interface{}{
+				"presence": event,
+				"previous": previous,
+			},
+		}
+		if data, err := json.Marshal(msg); err == nil {
+			h.manager.broadcastRoom(room, data)
+		}
+	}
+}
+
+// roomsOfUser lists the rooms any of the user's connections, on any
+// manager, are in
+func (m *Manager) roomsOfUser(userID string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seen := map[string]bool{}
+	for c := range m.clients[userID] {
+		for _, room := range m.roomsOf(c) {
+			seen[room] = true
+		}
+	}
+	for _, rc := range m.remote {
+		if rc.userID == userID {
+			for room := range rc.rooms {
+				seen[room] = true
+			}
+		}
+	}
+	rooms := make([]string, 0, len(seen))
+	for room := range seen {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
@@ -14,6 +14,12 @@ const (
 	MessageTypeHistory  MessageType = "history"
 	MessageTypeWelcome  MessageType = "welcome"
 	MessageTypeError    MessageType = "error"
+	MessageTypeReply    MessageType = "reply"
+	MessageTypeEdit     MessageType = "edit"
+	MessageTypeDelete   MessageType = "delete"
+	MessageTypeReaction MessageType = "reaction"
+	MessageTypeRead     MessageType = "read"
+	MessageTypeReplay   MessageType = "replay" // ends a last_offset catch-up
 )
 
 type Message struct {
@@ -24,14 +30,25 @@ type Message struct {
 	Content   string                 `json:"content"`
 	Timestamp time.Time              `json:"timestamp"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	ReplyTo   string                 `json:"reply_to,omitempty"`
+	EditedAt  *time.Time             `json:"edited_at,omitempty"`
+	Deleted   bool                   `json:"deleted,omitempty"`
+	Reactions map[string]int         `json:"reactions,omitempty"`
+	Chapter   int                    `json:"chapter,omitempty"` // sender's chapter in a manga room
+	Spoiler   bool                   `json:"spoiler,omitempty"` // content hidden until the reader gets to Chapter
 }
 
+// ClientMessage is what clients send. MessageID names the message an edit,
+// delete, reaction or read receipt applies to; ReplyTo the one a reply answers.
 type ClientMessage struct {
-	Type    MessageType `json:"type"`
-	To      string      `json:"to,omitempty"`
-	Room    string      `json:"room,omitempty"`
-	Content string      `json:"content"`
-	Command string      `json:"command,omitempty"`
+	Type      MessageType `json:"type"`
+	To        string      `json:"to,omitempty"`
+	Room      string      `json:"room,omitempty"`
+	Content   string      `json:"content"`
+	Command   string      `json:"command,omitempty"`
+	MessageID string      `json:"message_id,omitempty"`
+	ReplyTo   string      `json:"reply_to,omitempty"`
+	Emoji     string      `json:"emoji,omitempty"`
 }
 
 type ServerMessage struct {
@@ -0,0 +1,191 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+// setFocus records the room this connection is looking at. Messages posted
+// there arrive already read for its user.
+func (c *Client) setFocus(room string) {
+	c.mu.Lock()
+	changed := c.focus != room
+	c.focus = room
+	c.mu.Unlock()
+	if changed && c.Manager != nil {
+		c.Manager.publish(Envelope{Kind: envelopeFocus, ConnID: c.connID, Room: room})
+	}
+}
+
+func (c *Client) focused() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.focus
+}
+
+// focusedUsers returns the users with a connection looking at the room,
+// on this manager or another
+func (m *Manager) focusedUsers(room string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	seen := map[string]bool{}
+	users := []string{}
+	for c := range m.rooms[room] {
+		if !seen[c.ID] && c.focused() == room {
+			seen[c.ID] = true
+			users = append(users, c.ID)
+		}
+	}
+	for _, rc := range m.remote {
+		if !seen[rc.userID] && rc.rooms[room] && rc.focus == room {
+			seen[rc.userID] = true
+			users = append(users, rc.userID)
+		}
+	}
+	return users
+}
+
+// trackUnread counts a new message as unread for every member who is offline
+// or looking elsewhere, and as read for the sender and everyone watching the
+// room. Direct conversations (room "") have no focus, so the recipient always
+// starts with it unread.
+func (h *Handler) trackUnread(client *Client, conversationID, room, messageID string) {
+	readers := []string{client.ID}
+	if room != "" {
+		for _, id := range client.Manager.focusedUsers(room) {
+			if id != client.ID {
+				readers = append(readers, id)
+			}
+		}
+	}
+
+	ctx := context.Background()
+	if err := h.store.Conversations.IncrementUnread(ctx, conversationID, readers); err != nil {
+		logger.Warn("Failed to update unread counts", map[string]interface{}{"error": err.Error(), "conversation_id": conversationID})
+	}
+	for _, id := range readers {
+		if _, err := h.store.Conversations.MarkRead(ctx, conversationID, id, messageID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			logger.Warn("Failed to mark message read", map[string]interface{}{"error": err.Error(), "user_id": id})
+		}
+	}
+}
+
+// markLatestRead moves the user's read marker to the newest message in the
+// conversation. It returns that message's ID when the marker moved.
+func (h *Handler) markLatestRead(userID, conversationID string) (string, bool) {
+	ctx := context.Background()
+	latest, err := h.store.Messages.ConversationHistory(ctx, conversationID, 1)
+	if err != nil || len(latest) == 0 {
+		return "", false
+	}
+	advanced, err := h.store.Conversations.MarkRead(ctx, conversationID, userID, latest[0].ID)
+	if err != nil {
+		return "", false
+	}
+	return latest[0].ID, advanced
+}
+
+// handleRead marks a conversation read up to message_id, or up to its newest
+// message when none is given. The conversation is the message's own, the
+// direct conversation with "to", or the room. Everyone in the conversation is
+// sent a receipt.
+func (h *Handler) handleRead(client *Client, msg ClientMessage) error {
+	ctx := context.Background()
+	var conv *models.Conversation
+	var err error
+	switch {
+	case msg.MessageID != "":
+		var target *models.ChatMessage
+		if target, err = h.store.Messages.Get(ctx, msg.MessageID); err == nil {
+			conv, err = h.store.Conversations.Get(ctx, target.ConversationID)
+		}
+	case msg.To != "":
+		var other *models.User
+		if other, err = h.resolveUser(msg.To); err == nil {
+			conv, err = h.store.Conversations.GetByName(ctx, repository.DirectConversationName(client.ID, other.ID))
+		}
+	default:
+		room := msg.Room
+		if room == "" {
+			room = "global"
+		}
+		conv, err = h.store.Conversations.GetByName(ctx, room)
+	}
+	if err != nil || (conv.Type == "direct" && !isDirectMember(conv, client.ID)) {
+		h.sendError(client, msg.Room, "Conversation not found")
+		return nil
+	}
+	if conv.Type != "direct" {
+		if err := h.checkBanned(client.ID, conv.ID); err != nil {
+			h.sendError(client, msg.Room, err.Error())
+			return nil
+		}
+		client.setFocus(conv.Name)
+	}
+
+	messageID := msg.MessageID
+	advanced := false
+	if messageID == "" {
+		messageID, advanced = h.markLatestRead(client.ID, conv.ID)
+	} else if advanced, err = h.store.Conversations.MarkRead(ctx, conv.ID, client.ID, messageID); errors.Is(err, repository.ErrNotFound) {
+		h.sendError(client, msg.Room, "Message not found")
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !advanced {
+		return nil
+	}
+	return h.deliver(client, conv, ServerMessage{
+		ID:        messageID,
+		Type:      MessageTypeRead,
+		From:      client.Username,
+		Timestamp: time.Now(),
+		Metadata: map[string]interface{}{
+			"message_id": messageID,
+			"user_id":    client.ID,
+		},
+	})
+}
+
+// unreadByConversation maps the user's conversation IDs to unread counts
+func (h *Handler) unreadByConversation(userID string) map[string]int {
+	counts := map[string]int{}
+	list, err := h.store.Conversations.ListForMember(context.Background(), userID)
+	if err != nil {
+		return counts
+	}
+	for _, mc := range list {
+		counts[mc.ID] = mc.UnreadCount
+	}
+	return counts
+}
+
+// ListMemberConversations handles GET /users/conversations: every room and
+// direct conversation the user belongs to, with unread counts
+func (h *InboxHandler) ListMemberConversations(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	list, err := h.chat.store.Conversations.ListForMember(c.Request.Context(), userID)
+	if err != nil {
+		logger.Error("Failed to list conversations", map[string]interface{}{"error": err.Error(), "user_id": userID})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load conversations"})
+		return
+	}
+	total := 0
+	for _, mc := range list {
+		total += mc.UnreadCount
+	}
+	c.JSON(http.StatusOK, gin.H{"conversations": list, "count": len(list), "total_unread": total})
+}
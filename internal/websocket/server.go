@@ -4,9 +4,11 @@ import (
 	"database/sql"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/ratelimit"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
 	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
 	"github.com/gin-gonic/gin"
@@ -38,8 +40,15 @@ type Server struct {
 }
 
 func NewServer(db *sql.DB, jwtSecret string) *Server {
-	manager := NewManager()
+	return NewServerWithBackplane(db, jwtSecret, NewMemoryBackplane())
+}
+
+// NewServerWithBackplane creates a server whose rooms, direct messages and
+// presence are shared with every other server on the backplane
+func NewServerWithBackplane(db *sql.DB, jwtSecret string, bp Backplane) *Server {
+	manager := NewManagerWithBackplane(bp)
 	handler := NewHandler(db, manager)
+	handler.limiter = ratelimit.New(ratelimit.DefaultConfig())
 	broadcaster := NewWSBroadcaster(manager, logger.GetLogger())
 	go manager.Run()
 
@@ -53,12 +62,24 @@ func NewServer(db *sql.DB, jwtSecret string) *Server {
 	}
 }
 
+// Close leaves the backplane so other servers stop listing this one's users
+func (s *Server) Close() error {
+	return s.manager.Close()
+}
+
 func (s *Server) SetBridge(b *bridge.UnifiedBridge) {
 	s.bridge = b
 	s.broadcaster.SetBridge(b)
+	s.handler.bridge = b
 	logger.Info("ws_server_bridge_set")
 }
 
+// SetLimiter replaces the default rate limits, so several servers can share
+// one limiter and its configuration
+func (s *Server) SetLimiter(l *ratelimit.Limiter) {
+	s.handler.limiter = l
+}
+
 func (s *Server) HandleWebSocket(c *gin.Context) {
 	token := c.Query("token")
 	if token == "" {
@@ -79,17 +100,37 @@ func (s *Server) HandleWebSocket(c *gin.Context) {
 	}
 
 	now := time.Now()
+	backplaneID, _ := utils.GenerateID(8)
 	client := &Client{
 		ID:          claims.UserID,
 		Username:    claims.Username,
+		Role:        claims.Role,
 		Conn:        conn,
 		Send:        make(chan []byte, 256),
 		Manager:     s.manager,
 		Handler:     s.handler,
 		LastActive:  now,
 		ConnectedAt: now,
+		connID:      backplaneID,
+		ip:          c.ClientIP(),
+	}
+
+	// Bans outlive the connection they were issued on
+	client.noGlobal = s.handler.bannedFromRoom(client.ID, "global")
+	if !client.noGlobal {
+		client.focus = "global"
 	}
 
+	// Reconnecting clients pass the last offset they saw to catch up on
+	// missed events. Events pushed meanwhile are held until the replay is
+	// sent, so they neither overtake it nor arrive twice.
+	resumeFrom := int64(-1)
+	if lastOffset := c.Query("last_offset"); lastOffset != "" && s.bridge != nil {
+		if after, err := strconv.ParseInt(lastOffset, 10, 64); err == nil && after >= 0 {
+			resumeFrom = after
+			client.holdEvents()
+		}
+	}
 	s.manager.register <- client
 
 	connID := ""
@@ -108,10 +149,73 @@ func (s *Server) HandleWebSocket(c *gin.Context) {
 	// Send welcome message after broadcast
 	go s.sendWelcomeMessage(client)
 
+	if resumeFrom >= 0 {
+		go s.replayMissedEvents(client, resumeFrom)
+	}
+
 	go client.WritePump()
 	go client.ReadPump(connID, s.bridge, s.broadcaster)
 }
 
+// replayMissedEvents sends the logged events after afterOffset a page at a
+// time, then a replay message whose last_offset is where the client is now.
+// has_more is set when the replay stopped early; resume from last_offset.
+func (s *Server) replayMissedEvents(client *Client, afterOffset int64) {
+	last := afterOffset
+	defer func() {
+		if r := recover(); r != nil {
+			// Client disconnected while replaying
+		}
+	}()
+	defer func() { client.releaseEvents(last) }()
+
+	count := 0
+	hasMore := false
+	for {
+		events, err := s.bridge.ReplayEvents(client.ID, last, bridge.DefaultReplayLimit)
+		if err != nil {
+			logger.Warn("ws_event_replay_failed", "user_id", client.ID, "error", err.Error())
+			hasMore = true
+			break
+		}
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			client.Send <- data
+			last = event.Offset
+			count++
+		}
+		if len(events) < bridge.DefaultReplayLimit {
+			break
+		}
+	}
+
+	id, _ := utils.GenerateID(16)
+	done := ServerMessage{
+		ID:        id,
+		Type:      MessageTypeReplay,
+		From:      "system",
+		Content:   "Caught up on missed events",
+		Timestamp: time.Now(),
+		Metadata: map[string]interface{}{
+			"after_offset": afterOffset,
+			"last_offset":  last,
+			"count":        count,
+			"has_more":     hasMore,
+		},
+	}
+	if hasMore {
+		done.Content = "Could not replay every missed event"
+	}
+	if data, err := json.Marshal(done); err == nil {
+		client.Send <- data
+	}
+
+	logger.Info("ws_events_replayed", "user_id", client.ID, "after_offset", afterOffset, "last_offset", last, "count", count)
+}
+
 func (s *Server) sendWelcomeMessage(client *Client) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -0,0 +1,175 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+)
+
+// Manga rooms are reading clubs. Each message there is tagged with the
+// chapter its sender had reached, and readers who are further behind get it
+// collapsed until they catch up or ask for it with /reveal.
+
+// roomManga returns the manga a room discusses, or "" for other rooms
+func roomManga(room string) string {
+	if strings.HasPrefix(room, "manga-") {
+		return strings.TrimPrefix(room, "manga-")
+	}
+	return ""
+}
+
+// readerChapter is how far the user has read the manga; 0 when it isn't in
+// their library
+func (h *Handler) readerChapter(userID, mangaID string) int {
+	p, err := h.store.Progress.Get(context.Background(), userID, mangaID)
+	if err != nil {
+		return 0
+	}
+	return p.CurrentChapter
+}
+
+// tagSpoiler records the sender's chapter on a message posted in a manga
+// room and returns it, or 0 if the message isn't tagged
+func (h *Handler) tagSpoiler(client *Client, room, messageID string) int {
+	mangaID := roomManga(room)
+	if mangaID == "" {
+		return 0
+	}
+	chapter := h.readerChapter(client.ID, mangaID)
+	if chapter <= 0 {
+		return 0
+	}
+	if err := h.store.Messages.TagChapter(context.Background(), messageID, chapter); err != nil {
+		return 0
+	}
+	return chapter
+}
+
+// collapsed is what readers who haven't reached chapter see instead of msg
+func collapsed(msg ServerMessage, chapter int) ServerMessage {
+	msg.Content = ""
+	msg.Metadata = map[string]interface{}{
+		"spoiler":    true,
+		"chapter":    chapter,
+		"message_id": msg.ID,
+	}
+	return msg
+}
+
+// broadcastSpoiler sends a message tagged with chapter to a manga room,
+// collapsed for everyone who hasn't read that far
+func (h *Handler) broadcastSpoiler(room string, chapter int, msg ServerMessage) error {
+	if msg.Metadata == nil {
+		msg.Metadata = map[string]interface{}{}
+	}
+	msg.Metadata["chapter"] = chapter
+	full, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	hidden, err := json.Marshal(collapsed(msg, chapter))
+	if err != nil {
+		return err
+	}
+	mangaID := roomManga(room)
+	h.manager.broadcastSplit(room, full, hidden, func(userID string) bool {
+		return h.readerChapter(userID, mangaID) >= chapter
+	})
+	return nil
+}
+
+// hideSpoilers collapses the messages in a manga room's history that the
+// user hasn't read far enough for
+func (h *Handler) hideSpoilers(userID, conversationID string, messages []Message) []Message {
+	tagged := false
+	for _, m := range messages {
+		tagged = tagged || m.Chapter > 0
+	}
+	if !tagged {
+		return messages
+	}
+	conv, err := h.store.Conversations.Get(context.Background(), conversationID)
+	if err != nil || conv.Type != "manga" {
+		return messages
+	}
+	read := h.readerChapter(userID, conv.MangaID)
+	for i, m := range messages {
+		if m.Chapter > read && !m.Deleted {
+			messages[i].Content = ""
+			messages[i].Spoiler = true
+		}
+	}
+	return messages
+}
+
+// visibleResults drops search hits from manga rooms the user hasn't read far
+// enough for, since even the match would give them away
+func (h *Handler) visibleResults(userID string, results []models.MessageSearchResult) []models.MessageSearchResult {
+	read := map[string]int{}
+	visible := results[:0]
+	for _, r := range results {
+		if mangaID := roomManga(r.ConversationName); r.SpoilerChapter > 0 && r.ConversationType == "manga" {
+			if _, ok := read[mangaID]; !ok {
+				read[mangaID] = h.readerChapter(userID, mangaID)
+			}
+			if r.SpoilerChapter > read[mangaID] {
+				continue
+			}
+		}
+		visible = append(visible, r)
+	}
+	return visible
+}
+
+// hideAlertSpoilers clears the content of alerts about manga room messages
+// the user hasn't read far enough for
+func (h *Handler) hideAlertSpoilers(userID string, alerts []models.ChatAlert) []models.ChatAlert {
+	read := map[string]int{}
+	for i, a := range alerts {
+		mangaID := roomManga(a.ConversationName)
+		if a.SpoilerChapter <= 0 || mangaID == "" {
+			continue
+		}
+		if _, ok := read[mangaID]; !ok {
+			read[mangaID] = h.readerChapter(userID, mangaID)
+		}
+		if a.SpoilerChapter > read[mangaID] {
+			alerts[i].Content = ""
+			alerts[i].Spoiler = true
+		}
+	}
+	return alerts
+}
+
+// reveal sends the client one message's full content, spoiler or not
+func (h *Handler) reveal(client *Client, room string, args []string) {
+	if len(args) == 0 {
+		h.sendError(client, room, "Usage: /reveal <message-id>")
+		return
+	}
+	target, conv, ok := h.loadTarget(client, ClientMessage{MessageID: args[0], Room: room})
+	if !ok {
+		return
+	}
+	msg := ServerMessage{
+		ID:        target.ID,
+		Type:      MessageTypeText,
+		From:      target.SenderUsername,
+		Room:      conv.Name,
+		Content:   target.Content,
+		Timestamp: target.CreatedAt,
+		Metadata:  map[string]interface{}{"revealed": true},
+	}
+	if target.SpoilerChapter > 0 {
+		msg.Metadata["chapter"] = target.SpoilerChapter
+	}
+	if conv.Type == "direct" {
+		msg.Room = ""
+		msg.Metadata["direct"] = true
+	}
+	if data, err := json.Marshal(msg); err == nil {
+		client.Manager.SendToUser(client.ID, data)
+	}
+}
@@ -0,0 +1,193 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/websocket"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+	"github.com/gin-gonic/gin"
+	ws "github.com/gorilla/websocket"
+)
+
+// Two chat servers on one backplane should behave like a single server
+func TestChatAcrossServers(t *testing.T) {
+	// Each returns the backplane for the next server: the memory one is
+	// shared, while SQLite ones only share the database
+	backplanes := map[string]func() func() websocket.Backplane{
+		"memory": func() func() websocket.Backplane {
+			bp := websocket.NewMemoryBackplane()
+			return func() websocket.Backplane { return bp }
+		},
+		"sqlite": func() func() websocket.Backplane {
+			return func() websocket.Backplane { return websocket.NewSQLiteBackplane(database.DB, 10*time.Millisecond) }
+		},
+	}
+	for name, factory := range backplanes {
+		t.Run(name, func(t *testing.T) {
+			setupTestDBAt(t, filepath.Join(t.TempDir(), "chat.db"))
+			defer database.DB.Close()
+			next := factory()
+			server1 := websocket.NewServerWithBackplane(database.DB, "test-secret-key-32-characters!!", next())
+			server2 := websocket.NewServerWithBackplane(database.DB, "test-secret-key-32-characters!!", next())
+			defer server1.Close()
+
+			listen := func(server *websocket.Server) *httptest.Server {
+				router := gin.New()
+				router.GET("/ws/chat", server.HandleWebSocket)
+				return httptest.NewServer(router)
+			}
+			ts1, ts2 := listen(server1), listen(server2)
+			defer ts1.Close()
+			defer ts2.Close()
+
+			dial := func(ts *httptest.Server, userID, username string) *ws.Conn {
+				token, err := utils.GenerateJWT(userID, username, "user", "test-secret-key-32-characters!!")
+				if err != nil {
+					t.Fatalf("Failed to create token: %v", err)
+				}
+				conn, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/chat?token="+token, nil)
+				if err != nil {
+					t.Fatalf("Failed to connect %s: %v", username, err)
+				}
+				return conn
+			}
+			send := func(conn *ws.Conn, payload map[string]interface{}) {
+				b, _ := json.Marshal(payload)
+				if err := conn.WriteMessage(ws.TextMessage, b); err != nil {
+					t.Fatalf("Failed to send %v: %v", payload, err)
+				}
+			}
+			waitFor := func(what string, cond func() bool) {
+				deadline := time.Now().Add(2 * time.Second)
+				for !cond() {
+					if time.Now().After(deadline) {
+						t.Fatalf("timed out waiting for %s", what)
+					}
+					time.Sleep(10 * time.Millisecond)
+				}
+			}
+			online := func(server *websocket.Server, userID string) bool {
+				for _, id := range server.GetActiveUsers() {
+					if id == userID {
+						return true
+					}
+				}
+				return false
+			}
+
+			alice, bob := dial(ts1, "test-user-1", "testuser1"), dial(ts2, "test-user-2", "testuser2")
+			defer alice.Close()
+			defer bob.Close()
+			waitFor("each server to see the other's user", func() bool {
+				return online(server1, "test-user-2") && online(server2, "test-user-1")
+			})
+
+			send(alice, map[string]interface{}{"type": "text", "content": "hello from server 1", "room": "global"})
+			if readMatching(bob, "text", "hello from server 1", 2*time.Second) == nil {
+				t.Fatal("room message did not cross servers")
+			}
+
+			send(bob, map[string]interface{}{"type": "text", "content": "psst", "to": "testuser1"})
+			if dm := readMatching(alice, "text", "psst", 2*time.Second); dm == nil {
+				t.Fatal("direct message did not cross servers")
+			}
+
+			send(alice, map[string]interface{}{"type": "command", "command": "/users", "room": "global"})
+			list := readMatching(alice, "userlist", "", 2*time.Second)
+			if list == nil {
+				t.Fatal("no user list")
+			}
+			if n := list["metadata"].(map[string]interface{})["count"].(float64); n != 2 {
+				t.Errorf("expected both users in global, got %v", list["metadata"])
+			}
+
+			// A server started later learns who is already connected
+			server3 := websocket.NewServerWithBackplane(database.DB, "test-secret-key-32-characters!!", next())
+			defer server3.Close()
+			waitFor("a new server to see existing users", func() bool {
+				return online(server3, "test-user-1") && online(server3, "test-user-2")
+			})
+
+			server2.Close()
+			waitFor("server 1 to forget server 2's user", func() bool {
+				return !online(server1, "test-user-2")
+			})
+		})
+	}
+}
+
+// severable passes envelopes through until cut, like a server that crashed
+// without saying bye
+type severable struct {
+	websocket.Backplane
+	cut atomic.Bool
+}
+
+func (b *severable) Publish(env websocket.Envelope) error {
+	if b.cut.Load() {
+		return nil
+	}
+	return b.Backplane.Publish(env)
+}
+
+func TestSilentServerIsForgotten(t *testing.T) {
+	t.Setenv("CHAT_BACKPLANE_HEARTBEAT_MS", "20")
+	t.Setenv("CHAT_BACKPLANE_TTL_MS", "150")
+	setupTestDB(t)
+
+	shared := websocket.NewMemoryBackplane()
+	crashing := &severable{Backplane: shared}
+	server1 := websocket.NewServerWithBackplane(database.DB, "test-secret-key-32-characters!!", shared)
+	server2 := websocket.NewServerWithBackplane(database.DB, "test-secret-key-32-characters!!", crashing)
+	defer server1.Close()
+	defer server2.Close()
+
+	router := gin.New()
+	router.GET("/ws/chat", server2.HandleWebSocket)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	token, _ := utils.GenerateJWT("test-user-2", "testuser2", "user", "test-secret-key-32-characters!!")
+	conn, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/chat?token="+token, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	online := func() bool {
+		for _, id := range server1.GetActiveUsers() {
+			if id == "test-user-2" {
+				return true
+			}
+		}
+		return false
+	}
+	waitFor := func(what string, cond func() bool) {
+		deadline := time.Now().Add(2 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	waitFor("server 1 to see server 2's user", online)
+	time.Sleep(300 * time.Millisecond)
+	if !online() {
+		t.Fatal("a server that keeps sending heartbeats should not be forgotten")
+	}
+
+	crashing.cut.Store(true)
+	waitFor("server 1 to forget the silent server's user", func() bool { return !online() })
+
+	crashing.cut.Store(false)
+	waitFor("the user to come back with the server", online)
+}
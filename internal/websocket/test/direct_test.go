@@ -0,0 +1,91 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/websocket"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+	"github.com/gin-gonic/gin"
+	ws "github.com/gorilla/websocket"
+)
+
+// readDirect waits for a direct text message, returning nil on timeout
+func readDirect(conn *ws.Conn, timeout time.Duration) map[string]interface{} {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return nil
+		}
+		var msg map[string]interface{}
+		if json.Unmarshal(data, &msg) != nil || msg["type"] != "text" {
+			continue
+		}
+		if meta, _ := msg["metadata"].(map[string]interface{}); meta["direct"] == true {
+			return msg
+		}
+	}
+}
+
+func TestDirectMessageDelivery(t *testing.T) {
+	setupTestDB(t)
+	database.DB.Exec(`INSERT OR IGNORE INTO users (id, username, email, password_hash, created_at)
+		VALUES ('test-user-3', 'testuser3', 'test3@example.com', 'hashedpass3', ?)`, time.Now())
+	server := websocket.NewServer(database.DB, "test-secret-key-32-characters!!")
+
+	router := gin.New()
+	router.GET("/ws/chat", server.HandleWebSocket)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	dial := func(userID, username string) *ws.Conn {
+		token, err := utils.GenerateJWT(userID, username, "user", "test-secret-key-32-characters!!")
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+		conn, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/chat?token="+token, nil)
+		if err != nil {
+			t.Fatalf("Failed to connect %s: %v", username, err)
+		}
+		return conn
+	}
+	sender, senderOtherTab := dial("test-user-1", "testuser1"), dial("test-user-1", "testuser1")
+	recipient, bystander := dial("test-user-2", "testuser2"), dial("test-user-3", "testuser3")
+	for _, c := range []*ws.Conn{sender, senderOtherTab, recipient, bystander} {
+		defer c.Close()
+	}
+	time.Sleep(150 * time.Millisecond)
+
+	b, _ := json.Marshal(map[string]interface{}{"type": "text", "to": "testuser2", "content": "just between us"})
+	if err := sender.WriteMessage(ws.TextMessage, b); err != nil {
+		t.Fatalf("Failed to send direct message: %v", err)
+	}
+
+	for name, conn := range map[string]*ws.Conn{"recipient": recipient, "sender": sender, "sender's other connection": senderOtherTab} {
+		msg := readDirect(conn, time.Second)
+		if msg == nil || msg["content"] != "just between us" {
+			t.Fatalf("%s did not receive the direct message, got %v", name, msg)
+		}
+	}
+	if msg := readDirect(bystander, 500*time.Millisecond); msg != nil {
+		t.Fatalf("bystander received a direct message: %v", msg)
+	}
+
+	var inRooms int
+	database.DB.QueryRow(`SELECT COUNT(*) FROM messages m JOIN conversations c ON c.id = m.conversation_id
+		WHERE c.type != 'direct'`).Scan(&inRooms)
+	if inRooms != 0 {
+		t.Errorf("direct message stored in %d room messages", inRooms)
+	}
+
+	handler := websocket.NewHandler(database.DB, nil)
+	inbox, err := handler.DirectInbox("test-user-2")
+	if err != nil || len(inbox) != 1 || inbox[0].OtherUsername != "testuser1" {
+		t.Errorf("unexpected inbox %+v (err %v)", inbox, err)
+	}
+}
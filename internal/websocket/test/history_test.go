@@ -0,0 +1,89 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/websocket"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestConversationMessagesAndSearchEndpoints(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+	store := repository.NewSQLiteStore(database.DB)
+	club := &models.Conversation{Name: "club", Type: "custom", CreatedBy: "test-user-1"}
+	if err := store.Conversations.Create(ctx, club); err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+	store.Conversations.AddMember(ctx, club.ID, "test-user-1", repository.RoleOwner)
+	for i := 1; i <= 5; i++ {
+		store.Messages.SaveToConversation(ctx, club.ID, "test-user-1", fmt.Sprintf("chapter %d spoilers", i))
+	}
+	dm, _ := store.Conversations.GetOrCreateDirect(ctx, "test-user-1", "test-user-2")
+	store.Messages.SaveToConversation(ctx, dm.ID, "test-user-2", "no spoilers please")
+
+	gin.SetMode(gin.TestMode)
+	inbox := websocket.NewInboxHandler(database.DB)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", c.GetHeader("X-User")) })
+	router.GET("/conversations/search", inbox.SearchMessages)
+	router.GET("/conversations/:id/messages", inbox.ListMessages)
+
+	get := func(user, path string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body
+	}
+	contents := func(body map[string]interface{}) []string {
+		out := []string{}
+		for _, m := range body["messages"].([]interface{}) {
+			out = append(out, m.(map[string]interface{})["content"].(string))
+		}
+		return out
+	}
+
+	code, body := get("test-user-1", "/conversations/club/messages?limit=2")
+	if code != http.StatusOK || body["has_more"] != true {
+		t.Fatalf("first page: %d %v", code, body)
+	}
+	if got := contents(body); len(got) != 2 || got[0] != "chapter 4 spoilers" {
+		t.Errorf("first page = %v", got)
+	}
+	oldest := body["messages"].([]interface{})[0].(map[string]interface{})["id"].(string)
+
+	_, body = get("test-user-1", "/conversations/"+club.ID+"/messages?limit=10&before="+oldest)
+	if got := contents(body); len(got) != 3 || got[0] != "chapter 1 spoilers" || body["has_more"] != false {
+		t.Errorf("older page = %v (has_more %v)", got, body["has_more"])
+	}
+
+	if code, _ := get("test-user-1", "/conversations/club/messages?before=nope"); code != http.StatusBadRequest {
+		t.Errorf("unknown cursor: got %d, want 400", code)
+	}
+	if code, _ := get("someone-else", "/conversations/"+dm.ID+"/messages"); code != http.StatusNotFound {
+		t.Errorf("outsider read a DM: got %d, want 404", code)
+	}
+
+	_, body = get("test-user-1", "/conversations/search?q=spoilers")
+	if body["count"] != 6.0 {
+		t.Errorf("search across room and DM found %v results, want 6", body["count"])
+	}
+	_, body = get("test-user-2", "/conversations/search?q=chapter")
+	if body["count"] != 0.0 {
+		t.Errorf("user outside the room found %v of its messages", body["count"])
+	}
+	if code, _ := get("test-user-1", "/conversations/search"); code != http.StatusBadRequest {
+		t.Errorf("empty search: got %d, want 400", code)
+	}
+}
@@ -0,0 +1,74 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/ratelimit"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/websocket"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/gin-gonic/gin"
+	ws "github.com/gorilla/websocket"
+)
+
+func TestLimiterFiltersWordsAndDisconnectsRepeatOffenders(t *testing.T) {
+	setupTestDB(t)
+	server := websocket.NewServer(database.DB, "test-secret-key-32-characters!!")
+	server.SetLimiter(ratelimit.New(ratelimit.Config{
+		Window:          time.Hour,
+		PerUser:         3,
+		DisconnectAfter: 2,
+		StrikeExpiry:    time.Hour,
+		Words:           []string{"darn"},
+	}))
+	router := gin.New()
+	router.GET("/ws/chat", server.HandleWebSocket)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	conn, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/chat?token="+createTestToken(t), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	send := func(content string) {
+		b, _ := json.Marshal(map[string]interface{}{"type": "text", "content": content, "room": "global"})
+		if err := conn.WriteMessage(ws.TextMessage, b); err != nil {
+			t.Fatalf("send failed: %v", err)
+		}
+	}
+
+	send("darn it")
+	if msg := readMatching(conn, "text", "**** it", 2*time.Second); msg == nil {
+		t.Fatalf("expected the filtered word to be masked")
+	}
+	send("two")
+	send("three")
+
+	send("four")
+	notice := readMatching(conn, "system", "rate limit exceeded", 2*time.Second)
+	if notice == nil {
+		t.Fatalf("expected a rate limit notice")
+	}
+	if metadata, _ := notice["metadata"].(map[string]interface{}); metadata["reason"] != "user_rate" || metadata["penalty"] != "warn" {
+		t.Errorf("expected a warning for the user rate, got %v", notice)
+	}
+
+	send("five")
+	notice = readMatching(conn, "system", "rate limit exceeded", 2*time.Second)
+	if metadata, _ := notice["metadata"].(map[string]interface{}); metadata["penalty"] != "disconnect" {
+		t.Fatalf("expected the second strike to disconnect, got %v", notice)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			if _, closed := err.(*ws.CloseError); !closed {
+				t.Errorf("expected the server to close the connection, got %v", err)
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/websocket"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+	"github.com/gin-gonic/gin"
+	ws "github.com/gorilla/websocket"
+)
+
+func TestMentionsAndKeywordAlerts(t *testing.T) {
+	setupTestDB(t)
+	server := websocket.NewServer(database.DB, "test-secret-key-32-characters!!")
+
+	// Catch what the bridge hands to other protocols
+	ub := bridge.NewUnifiedBridge(logger.GetLogger())
+	ub.Start()
+	defer ub.Stop()
+	published := make(chan bridge.UnifiedEvent, 10)
+	ub.Subscribe(func(e bridge.UnifiedEvent) error {
+		published <- e
+		return nil
+	})
+	server.SetBridge(ub)
+
+	router := gin.New()
+	router.GET("/ws/chat", server.HandleWebSocket)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	dial := func(userID, username string) *ws.Conn {
+		token, err := utils.GenerateJWT(userID, username, "user", "test-secret-key-32-characters!!")
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+		conn, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/chat?token="+token, nil)
+		if err != nil {
+			t.Fatalf("Failed to connect %s: %v", username, err)
+		}
+		return conn
+	}
+	send := func(conn *ws.Conn, payload map[string]interface{}) {
+		b, _ := json.Marshal(payload)
+		if err := conn.WriteMessage(ws.TextMessage, b); err != nil {
+			t.Fatalf("Failed to send %v: %v", payload, err)
+		}
+	}
+	nextEvent := func() bridge.UnifiedEvent {
+		select {
+		case e := <-published:
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("no event reached the bridge")
+			return bridge.UnifiedEvent{}
+		}
+	}
+	alertCount := func(userID string) int {
+		var n int
+		database.DB.QueryRow(`SELECT COUNT(*) FROM chat_alerts WHERE user_id = ?`, userID).Scan(&n)
+		return n
+	}
+
+	author, reader := dial("test-user-1", "testuser1"), dial("test-user-2", "testuser2")
+	defer author.Close()
+	defer reader.Close()
+	time.Sleep(150 * time.Millisecond)
+
+	send(author, map[string]interface{}{"type": "text", "content": "ping @testuser2 and @testuser1 and @nobody.", "room": "global"})
+	e := nextEvent()
+	if e.Type != bridge.EventChatMention || e.UserID != "test-user-2" || e.Data["from"] != "testuser1" || e.Data["room"] != "global" {
+		t.Errorf("unexpected mention event %+v", e)
+	}
+	if readMatching(reader, string(bridge.EventChatMention), "", time.Second) == nil {
+		t.Error("mention was not pushed to the mentioned user's chat connection")
+	}
+	if n := alertCount("test-user-2"); n != 1 {
+		t.Errorf("expected 1 stored mention, got %d", n)
+	}
+	if n := alertCount("test-user-1"); n != 0 {
+		t.Errorf("mentioning yourself should not alert you (%d)", n)
+	}
+
+	// Keyword alerts: the reader watches one room for a manga title
+	send(reader, map[string]interface{}{"type": "command", "command": "/join lounge", "room": "global"})
+	readMatching(reader, "userlist", "", time.Second)
+	send(reader, map[string]interface{}{"type": "command", "command": "/alert One Piece", "room": "lounge"})
+	if readMatching(reader, "system", `Watching "one piece" in #lounge`, time.Second) == nil {
+		t.Fatal("keyword alert was not added")
+	}
+	send(reader, map[string]interface{}{"type": "command", "command": "/alert one  piece", "room": "lounge"})
+	if readMatching(reader, "error", "already watching", time.Second) == nil {
+		t.Error("expected a duplicate keyword to be refused")
+	}
+
+	send(author, map[string]interface{}{"type": "text", "content": "One Piece is great", "room": "global"})
+	send(author, map[string]interface{}{"type": "text", "content": "new ONE PIECE chapter!", "room": "lounge"})
+	e = nextEvent()
+	if e.Type != bridge.EventChatKeyword || e.UserID != "test-user-2" || e.Data["keyword"] != "one piece" || e.Data["room"] != "lounge" {
+		t.Errorf("unexpected keyword event %+v", e)
+	}
+
+	send(reader, map[string]interface{}{"type": "command", "command": "/mentions", "room": "global"})
+	list := readMatching(reader, "system", "Recent mentions", time.Second)
+	if list == nil {
+		t.Fatal("no /mentions reply")
+	}
+	alerts := list["metadata"].(map[string]interface{})["alerts"].([]interface{})
+	if len(alerts) != 2 || alerts[0].(map[string]interface{})["kind"] != "keyword" {
+		t.Errorf("expected the keyword alert then the mention, got %v", alerts)
+	}
+
+	send(reader, map[string]interface{}{"type": "command", "command": "/unalert one piece", "room": "lounge"})
+	if readMatching(reader, "system", "No longer watching", time.Second) == nil {
+		t.Error("keyword alert was not removed")
+	}
+	send(author, map[string]interface{}{"type": "text", "content": "one piece again", "room": "lounge"})
+	time.Sleep(200 * time.Millisecond)
+	if n := alertCount("test-user-2"); n != 2 {
+		t.Errorf("expected no alert after /unalert, got %d alerts", n)
+	}
+}
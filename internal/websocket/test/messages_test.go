@@ -0,0 +1,99 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/websocket"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+	"github.com/gin-gonic/gin"
+	ws "github.com/gorilla/websocket"
+)
+
+func TestMessageEditDeleteReactReply(t *testing.T) {
+	setupTestDB(t)
+	server := websocket.NewServer(database.DB, "test-secret-key-32-characters!!")
+
+	router := gin.New()
+	router.GET("/ws/chat", server.HandleWebSocket)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	dial := func(userID, username string) *ws.Conn {
+		token, err := utils.GenerateJWT(userID, username, "user", "test-secret-key-32-characters!!")
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+		conn, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/chat?token="+token, nil)
+		if err != nil {
+			t.Fatalf("Failed to connect %s: %v", username, err)
+		}
+		return conn
+	}
+	send := func(conn *ws.Conn, payload map[string]interface{}) {
+		payload["room"] = "global"
+		b, _ := json.Marshal(payload)
+		if err := conn.WriteMessage(ws.TextMessage, b); err != nil {
+			t.Fatalf("Failed to send %v: %v", payload, err)
+		}
+	}
+
+	author, reader := dial("test-user-1", "testuser1"), dial("test-user-2", "testuser2")
+	defer author.Close()
+	defer reader.Close()
+	time.Sleep(150 * time.Millisecond)
+
+	send(author, map[string]interface{}{"type": "text", "content": "who read chapter 100?"})
+	original := readMatching(reader, "text", "who read chapter 100?", time.Second)
+	if original == nil {
+		t.Fatal("reader did not get the message")
+	}
+	id, _ := original["id"].(string)
+
+	send(reader, map[string]interface{}{"type": "reply", "reply_to": id, "content": "me!"})
+	reply := readMatching(author, "text", "me!", time.Second)
+	if reply == nil {
+		t.Fatal("author did not get the reply")
+	}
+	replyTo, _ := reply["metadata"].(map[string]interface{})["reply_to"].(map[string]interface{})
+	if replyTo["id"] != id || replyTo["from"] != "testuser1" {
+		t.Errorf("reply context = %v, want message %s by testuser1", replyTo, id)
+	}
+
+	send(reader, map[string]interface{}{"type": "edit", "message_id": id, "content": "hijacked"})
+	if readMatching(reader, "error", "only edit your own", time.Second) == nil {
+		t.Error("reader was allowed to edit someone else's message")
+	}
+	send(author, map[string]interface{}{"type": "edit", "message_id": id, "content": "who read chapter 101?"})
+	if readMatching(reader, "edit", "who read chapter 101?", time.Second) == nil {
+		t.Error("edit was not broadcast")
+	}
+
+	send(reader, map[string]interface{}{"type": "reaction", "message_id": id, "emoji": "🔥"})
+	reaction := readMatching(author, "reaction", "🔥", time.Second)
+	if reaction == nil {
+		t.Fatal("reaction was not broadcast")
+	}
+	if counts, _ := reaction["metadata"].(map[string]interface{})["reactions"].(map[string]interface{}); counts["🔥"] != 1.0 {
+		t.Errorf("reaction counts = %v, want one 🔥", counts)
+	}
+
+	send(reader, map[string]interface{}{"type": "delete", "message_id": id})
+	if readMatching(reader, "error", "only delete your own", time.Second) == nil {
+		t.Error("reader was allowed to delete someone else's message")
+	}
+	send(author, map[string]interface{}{"type": "delete", "message_id": id})
+	if readMatching(reader, "delete", "", time.Second) == nil {
+		t.Fatal("delete was not broadcast")
+	}
+
+	var deleted int
+	database.DB.QueryRow(`SELECT COUNT(*) FROM messages WHERE id = ? AND deleted_at IS NOT NULL AND content = ''`, id).Scan(&deleted)
+	if deleted != 1 {
+		t.Error("deleted message was not left as a tombstone")
+	}
+}
@@ -0,0 +1,143 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/websocket"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+	"github.com/gin-gonic/gin"
+	ws "github.com/gorilla/websocket"
+)
+
+// readMatching waits for a message of the given type whose content contains
+// want, returning it or nil on timeout
+func readMatching(conn *ws.Conn, msgType, want string, timeout time.Duration) map[string]interface{} {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return nil
+		}
+		var msg map[string]interface{}
+		if json.Unmarshal(data, &msg) != nil {
+			continue
+		}
+		if content, _ := msg["content"].(string); msg["type"] == msgType && strings.Contains(content, want) {
+			return msg
+		}
+	}
+}
+
+func TestRoomModerationCommands(t *testing.T) {
+	setupTestDB(t)
+	server := websocket.NewServer(database.DB, "test-secret-key-32-characters!!")
+
+	router := gin.New()
+	router.GET("/ws/chat", server.HandleWebSocket)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	dial := func(userID, username string) *ws.Conn {
+		token, err := utils.GenerateJWT(userID, username, "user", "test-secret-key-32-characters!!")
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+		conn, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/chat?token="+token, nil)
+		if err != nil {
+			t.Fatalf("Failed to connect %s: %v", username, err)
+		}
+		return conn
+	}
+	send := func(conn *ws.Conn, payload map[string]interface{}) {
+		b, _ := json.Marshal(payload)
+		if err := conn.WriteMessage(ws.TextMessage, b); err != nil {
+			t.Fatalf("Failed to send %v: %v", payload, err)
+		}
+	}
+	command := func(conn *ws.Conn, cmd string) {
+		send(conn, map[string]interface{}{"type": "command", "command": cmd, "room": "club"})
+	}
+
+	owner, member := dial("test-user-1", "testuser1"), dial("test-user-2", "testuser2")
+	defer owner.Close()
+	defer member.Close()
+	time.Sleep(150 * time.Millisecond)
+
+	send(owner, map[string]interface{}{"type": "command", "command": "/create club", "room": "global"})
+	if readMatching(owner, "system", "created successfully", time.Second) == nil {
+		t.Fatal("room was not created")
+	}
+	command(owner, "/join club")
+	command(member, "/join club")
+	if readMatching(member, "presence", "testuser2 joined", time.Second) == nil {
+		t.Fatal("member did not join the room")
+	}
+
+	command(member, "/kick testuser1")
+	if readMatching(member, "error", "needs the moderator role", time.Second) == nil {
+		t.Error("member was allowed to kick the owner")
+	}
+
+	command(owner, "/mute testuser2 10m flooding")
+	if readMatching(member, "system", "testuser2 was muted by testuser1 for 10m: flooding", time.Second) == nil {
+		t.Fatal("mute was not announced to the room")
+	}
+	send(member, map[string]interface{}{"type": "text", "content": "can anyone hear me", "room": "club"})
+	if readMatching(member, "error", "You are muted in this room", time.Second) == nil {
+		t.Error("muted member was allowed to post")
+	}
+
+	command(owner, "/ban testuser2")
+	if readMatching(member, "system", "You are banned from this room", time.Second) == nil {
+		t.Fatal("banned member was not told")
+	}
+	command(member, "/join club")
+	if readMatching(member, "error", "banned", time.Second) == nil {
+		t.Error("banned member rejoined the room")
+	}
+
+	command(owner, "/unban testuser2")
+	command(owner, "/topic one-shots only")
+	if readMatching(owner, "system", "set the topic: one-shots only", time.Second) == nil {
+		t.Fatal("topic was not announced")
+	}
+	command(member, "/join club")
+	if readMatching(member, "system", "Topic: one-shots only", time.Second) == nil {
+		t.Error("unbanned member did not rejoin with the topic")
+	}
+}
+
+// brokenSanctions fails every sanction lookup, as a database outage would
+type brokenSanctions struct {
+	repository.ConversationRepository
+}
+
+func (brokenSanctions) ActiveSanction(ctx context.Context, conversationID, userID, kind string) (*models.RoomSanction, error) {
+	return nil, errors.New("database is locked")
+}
+
+func TestPostingFailsClosedWhenSanctionsCantBeChecked(t *testing.T) {
+	store := repository.NewMemoryStore()
+	store.Conversations = brokenSanctions{store.Conversations}
+	manager := websocket.NewManager()
+	go manager.Run()
+	handler := websocket.NewHandlerWithStore(store, manager)
+
+	client := &websocket.Client{ID: "u1", Username: "reader", Send: make(chan []byte, 16), Manager: manager, Handler: handler}
+	data, _ := json.Marshal(map[string]interface{}{"type": "text", "content": "hello", "room": "global"})
+	if err := handler.HandleClientMessage(client, data); err != nil {
+		t.Fatalf("handle message: %v", err)
+	}
+	if history, _ := store.Messages.ConversationHistory(context.Background(), "global", 10); len(history) != 0 {
+		t.Errorf("expected the message to be refused, but it was stored: %+v", history)
+	}
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/presence"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/websocket"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+	"github.com/gin-gonic/gin"
+	ws "github.com/gorilla/websocket"
+)
+
+func TestIdleAndReturnAnnouncedInRooms(t *testing.T) {
+	setupTestDB(t)
+	server := websocket.NewServer(database.DB, "test-secret-key-32-characters!!")
+
+	ub := bridge.NewUnifiedBridge(logger.GetLogger())
+	ub.Start()
+	defer ub.Stop()
+	server.SetBridge(ub)
+
+	svc := presence.NewService(repository.NewSQLiteStore(database.DB), logger.GetLogger(),
+		presence.Config{IdleAfter: 200 * time.Millisecond, AwayAfter: time.Hour, Interval: time.Hour})
+	svc.Start()
+	defer svc.Stop()
+	ub.SetPresenceTracker(svc)
+	server.SetPresence(svc)
+
+	router := gin.New()
+	router.GET("/ws/chat", server.HandleWebSocket)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	dial := func(userID, username string) *ws.Conn {
+		token, err := utils.GenerateJWT(userID, username, "user", "test-secret-key-32-characters!!")
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+		conn, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/chat?token="+token, nil)
+		if err != nil {
+			t.Fatalf("Failed to connect %s: %v", username, err)
+		}
+		return conn
+	}
+	waitFor := func(conn *ws.Conn, content string) map[string]interface{} {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("never received %q: %v", content, err)
+			}
+			var msg map[string]interface{}
+			json.Unmarshal(data, &msg)
+			if msg["type"] == "presence" && msg["content"] == content {
+				return msg
+			}
+		}
+	}
+
+	author, reader := dial("test-user-1", "testuser1"), dial("test-user-2", "testuser2")
+	defer author.Close()
+	defer reader.Close()
+
+	time.Sleep(250 * time.Millisecond)
+	svc.Sweep()
+	msg := waitFor(reader, "testuser1 is idle")
+	metadata, _ := msg["metadata"].(map[string]interface{})
+	event, _ := metadata["presence"].(map[string]interface{})
+	if msg["room"] != "global" || event["user_id"] != "test-user-1" || event["status"] != "idle" || metadata["previous"] != "online" {
+		t.Errorf("unexpected idle announcement %+v", msg)
+	}
+
+	b, _ := json.Marshal(map[string]interface{}{"type": "typing", "room": "global"})
+	author.WriteMessage(ws.TextMessage, b)
+	waitFor(reader, "testuser1 is back")
+}
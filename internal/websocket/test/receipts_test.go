@@ -0,0 +1,112 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/websocket"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+	"github.com/gin-gonic/gin"
+	ws "github.com/gorilla/websocket"
+)
+
+func TestUnreadCountsAndReadReceipts(t *testing.T) {
+	setupTestDB(t)
+	server := websocket.NewServer(database.DB, "test-secret-key-32-characters!!")
+
+	router := gin.New()
+	router.GET("/ws/chat", server.HandleWebSocket)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	dial := func(userID, username string) *ws.Conn {
+		token, err := utils.GenerateJWT(userID, username, "user", "test-secret-key-32-characters!!")
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+		conn, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/chat?token="+token, nil)
+		if err != nil {
+			t.Fatalf("Failed to connect %s: %v", username, err)
+		}
+		return conn
+	}
+	send := func(conn *ws.Conn, payload map[string]interface{}) {
+		b, _ := json.Marshal(payload)
+		if err := conn.WriteMessage(ws.TextMessage, b); err != nil {
+			t.Fatalf("Failed to send %v: %v", payload, err)
+		}
+	}
+	unread := func(userID, conversation string) int {
+		var n int
+		database.DB.QueryRow(`SELECT uch.unread_count FROM user_conversation_history uch
+			JOIN conversations c ON c.id = uch.conversation_id
+			WHERE uch.user_id = ? AND c.name = ?`, userID, conversation).Scan(&n)
+		return n
+	}
+
+	author, reader := dial("test-user-1", "testuser1"), dial("test-user-2", "testuser2")
+	defer author.Close()
+	defer reader.Close()
+	time.Sleep(150 * time.Millisecond)
+
+	send(reader, map[string]interface{}{"type": "text", "content": "hello", "room": "global"})
+	readMatching(author, "text", "hello", time.Second)
+	send(author, map[string]interface{}{"type": "text", "content": "seen right away", "room": "global"})
+	readMatching(reader, "text", "seen right away", time.Second)
+	if n := unread("test-user-2", "global"); n != 0 {
+		t.Errorf("message posted in the room the reader is looking at counted as unread (%d)", n)
+	}
+
+	// The reader moves to another room, so global messages pile up
+	send(reader, map[string]interface{}{"type": "typing", "room": "club"})
+	time.Sleep(100 * time.Millisecond)
+	send(author, map[string]interface{}{"type": "text", "content": "anyone?", "room": "global"})
+	send(author, map[string]interface{}{"type": "text", "content": "hello??", "room": "global"})
+	readMatching(author, "text", "hello??", time.Second)
+	if n := unread("test-user-2", "global"); n != 2 {
+		t.Errorf("expected 2 unread in global, got %d", n)
+	}
+
+	send(reader, map[string]interface{}{"type": "command", "command": "/rooms", "room": "club"})
+	rooms := readMatching(reader, "system", "Available rooms", time.Second)
+	if rooms == nil {
+		t.Fatal("no room list")
+	}
+	badge := 0.0
+	for _, r := range rooms["metadata"].(map[string]interface{})["rooms"].([]interface{}) {
+		if room := r.(map[string]interface{}); room["name"] == "global" {
+			badge, _ = room["unread_count"].(float64)
+		}
+	}
+	if badge != 2 {
+		t.Errorf("/rooms showed %v unread for global, want 2", badge)
+	}
+
+	send(reader, map[string]interface{}{"type": "read", "room": "global"})
+	if receipt := readMatching(author, "read", "", time.Second); receipt == nil || receipt["from"] != "testuser2" {
+		t.Fatalf("author got no read receipt: %v", receipt)
+	}
+	if n := unread("test-user-2", "global"); n != 0 {
+		t.Errorf("expected global to be read, still %d unread", n)
+	}
+
+	send(author, map[string]interface{}{"type": "text", "to": "testuser2", "content": "psst"})
+	dm := readMatching(reader, "text", "psst", time.Second)
+	if dm == nil {
+		t.Fatal("reader did not get the DM")
+	}
+	var dmUnread int
+	database.DB.QueryRow(`SELECT unread_count FROM user_conversation_history WHERE user_id = ? AND conversation_id = ?`,
+		"test-user-2", dm["metadata"].(map[string]interface{})["conversation_id"]).Scan(&dmUnread)
+	if dmUnread != 1 {
+		t.Errorf("expected the DM to be unread, got %d", dmUnread)
+	}
+	send(reader, map[string]interface{}{"type": "read", "message_id": dm["id"]})
+	if readMatching(author, "read", "", time.Second) == nil {
+		t.Error("DM sender got no read receipt")
+	}
+}
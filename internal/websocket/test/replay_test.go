@@ -0,0 +1,120 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/bridge"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/websocket"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+	"github.com/gin-gonic/gin"
+	ws "github.com/gorilla/websocket"
+)
+
+// A resume longer than one replay page is sent in full, ends with a replay
+// message naming the last offset, and a mention pushed while it runs is
+// delivered exactly once
+func TestResumeReplaysEveryPageOnce(t *testing.T) {
+	setupTestDBAt(t, t.TempDir()+"/replay.db")
+	server := websocket.NewServer(database.DB, "test-secret-key-32-characters!!")
+
+	eventLog := bridge.NewEventLog(database.DB, logger.GetLogger(), bridge.DefaultRetentionPolicy())
+	ub := bridge.NewUnifiedBridge(logger.GetLogger())
+	ub.SetEventLog(eventLog)
+	ub.Start()
+	defer ub.Stop()
+	server.SetBridge(ub)
+
+	missed := bridge.DefaultReplayLimit + 100
+	for i := 0; i < missed; i++ {
+		eventLog.Append(bridge.NewUnifiedEvent(bridge.EventProgressUpdate, "test-user-2", bridge.ProtocolHTTP, map[string]interface{}{"chapter": i}))
+	}
+
+	router := gin.New()
+	router.GET("/ws/chat", server.HandleWebSocket)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	dial := func(userID, username, query string) *ws.Conn {
+		token, _ := utils.GenerateJWT(userID, username, "user", "test-secret-key-32-characters!!")
+		conn, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/chat?token="+token+query, nil)
+		if err != nil {
+			t.Fatalf("Failed to connect %s: %v", username, err)
+		}
+		return conn
+	}
+
+	author := dial("test-user-1", "testuser1", "")
+	defer author.Close()
+	time.Sleep(150 * time.Millisecond)
+
+	reader := dial("test-user-2", "testuser2", "&last_offset=0")
+	defer reader.Close()
+	mention, _ := json.Marshal(map[string]interface{}{"type": "text", "content": "hey @testuser2", "room": "global"})
+	author.WriteMessage(ws.TextMessage, mention)
+
+	seen := map[float64]int{}
+	var last, replayedTo float64
+	replayDone, mentioned := false, false
+	reader.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for !replayDone || !mentioned {
+		_, data, err := reader.ReadMessage()
+		if err != nil {
+			t.Fatalf("stopped after %d events (replay done %v, mentioned %v): %v", len(seen), replayDone, mentioned, err)
+		}
+		var msg map[string]interface{}
+		json.Unmarshal(data, &msg)
+		if msg["type"] == "replay" {
+			meta := msg["metadata"].(map[string]interface{})
+			replayedTo = meta["last_offset"].(float64)
+			if meta["has_more"] != false {
+				t.Errorf("replay reported more to come: %v", meta)
+			}
+			replayDone = true
+			continue
+		}
+		offset, ok := msg["offset"].(float64)
+		if !ok {
+			continue
+		}
+		seen[offset]++
+		if offset <= last {
+			t.Fatalf("offset %v arrived after %v", offset, last)
+		}
+		last = offset
+		if msg["type"] == string(bridge.EventChatMention) {
+			mentioned = true
+		}
+	}
+
+	// A second copy of anything would turn up shortly
+	reader.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	for {
+		_, data, err := reader.ReadMessage()
+		if err != nil {
+			break
+		}
+		var msg map[string]interface{}
+		json.Unmarshal(data, &msg)
+		if offset, ok := msg["offset"].(float64); ok {
+			seen[offset]++
+		}
+	}
+
+	if len(seen) != missed+1 {
+		t.Errorf("expected %d distinct events, got %d", missed+1, len(seen))
+	}
+	for offset, n := range seen {
+		if n != 1 {
+			t.Errorf("offset %v delivered %d times", offset, n)
+		}
+	}
+	if replayedTo < float64(missed) {
+		t.Errorf("replay ended at offset %v, want at least %d", replayedTo, missed)
+	}
+}
@@ -22,7 +22,13 @@ func init() {
 }
 
 func setupTestDB(t *testing.T) {
-	if err := database.InitDatabase(":memory:"); err != nil {
+	setupTestDBAt(t, ":memory:")
+}
+
+// setupTestDBAt is setupTestDB for tests that need a database file, which
+// every pooled connection sees the same way
+func setupTestDBAt(t *testing.T, path string) {
+	if err := database.InitDatabase(path); err != nil {
 		t.Fatalf("Failed to init test database: %v", err)
 	}
 
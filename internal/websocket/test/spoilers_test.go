@@ -0,0 +1,206 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/repository"
+	"github.com/binhbb2204/Manga-Hub-Group13/internal/websocket"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/models"
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/utils"
+	"github.com/gin-gonic/gin"
+	ws "github.com/gorilla/websocket"
+)
+
+func TestMangaRoomHidesSpoilersFromReadersBehind(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+	store := repository.NewSQLiteStore(database.DB)
+	if err := store.Manga.Create(ctx, &models.Manga{ID: "one-piece", Title: "One Piece", TotalChapters: 1100}); err != nil {
+		t.Fatalf("create manga: %v", err)
+	}
+	store.Progress.Sync(ctx, "test-user-1", "one-piece", 100, "")
+	store.Progress.Sync(ctx, "test-user-2", "one-piece", 50, "")
+
+	server := websocket.NewServer(database.DB, "test-secret-key-32-characters!!")
+	router := gin.New()
+	router.GET("/ws/chat", server.HandleWebSocket)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	dial := func(userID, username string) *ws.Conn {
+		token, err := utils.GenerateJWT(userID, username, "user", "test-secret-key-32-characters!!")
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+		conn, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/chat?token="+token, nil)
+		if err != nil {
+			t.Fatalf("Failed to connect %s: %v", username, err)
+		}
+		return conn
+	}
+	send := func(conn *ws.Conn, payload map[string]interface{}) {
+		payload["room"] = "manga-one-piece"
+		b, _ := json.Marshal(payload)
+		if err := conn.WriteMessage(ws.TextMessage, b); err != nil {
+			t.Fatalf("Failed to send %v: %v", payload, err)
+		}
+	}
+	// next returns the next message of the given type
+	next := func(conn *ws.Conn, msgType string) map[string]interface{} {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("no %s message: %v", msgType, err)
+			}
+			var msg map[string]interface{}
+			if json.Unmarshal(data, &msg) == nil && msg["type"] == msgType {
+				return msg
+			}
+		}
+	}
+	// historyEntry fetches the room history and returns the message with the given ID
+	historyEntry := func(conn *ws.Conn, id string) map[string]interface{} {
+		send(conn, map[string]interface{}{"type": "command", "command": "/history"})
+		messages, _ := next(conn, "history")["metadata"].(map[string]interface{})["messages"].([]interface{})
+		for _, m := range messages {
+			if entry, _ := m.(map[string]interface{}); entry["id"] == id {
+				return entry
+			}
+		}
+		t.Fatalf("message %s not in history %v", id, messages)
+		return nil
+	}
+
+	author, reader := dial("test-user-1", "testuser1"), dial("test-user-2", "testuser2")
+	defer author.Close()
+	defer reader.Close()
+	for _, conn := range []*ws.Conn{author, reader} {
+		send(conn, map[string]interface{}{"type": "command", "command": "/join manga-one-piece"})
+		next(conn, "history")
+	}
+
+	send(author, map[string]interface{}{"type": "text", "content": "gear 5 changes everything"})
+	full := next(author, "text")
+	id, _ := full["id"].(string)
+	if full["content"] != "gear 5 changes everything" || full["metadata"].(map[string]interface{})["chapter"] != float64(100) {
+		t.Errorf("author should see the tagged message, got %v", full)
+	}
+	hidden := next(reader, "text")
+	metadata, _ := hidden["metadata"].(map[string]interface{})
+	if hidden["id"] != id || hidden["content"] != "" || metadata["spoiler"] != true || metadata["chapter"] != float64(100) {
+		t.Errorf("reader at chapter 50 should get a collapsed message, got %v", hidden)
+	}
+
+	if entry := historyEntry(reader, id); entry["content"] != "" || entry["spoiler"] != true || entry["chapter"] != float64(100) {
+		t.Errorf("history should collapse the spoiler, got %v", entry)
+	}
+	send(reader, map[string]interface{}{"type": "command", "command": "/search gear"})
+	if results := next(reader, "system")["metadata"].(map[string]interface{})["results"].([]interface{}); len(results) != 0 {
+		t.Errorf("search should not find spoilers, got %v", results)
+	}
+
+	send(reader, map[string]interface{}{"type": "command", "command": "/reveal " + id})
+	revealed := next(reader, "text")
+	if revealed["content"] != "gear 5 changes everything" || revealed["metadata"].(map[string]interface{})["revealed"] != true {
+		t.Errorf("reveal should return the full message, got %v", revealed)
+	}
+
+	store.Progress.Sync(ctx, "test-user-2", "one-piece", 100, "")
+	if entry := historyEntry(reader, id); entry["content"] != "gear 5 changes everything" || entry["spoiler"] == true {
+		t.Errorf("a reader who caught up should see the message, got %v", entry)
+	}
+}
+
+func TestAlertsHideSpoilersFromReadersBehind(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+	store := repository.NewSQLiteStore(database.DB)
+	if err := store.Manga.Create(ctx, &models.Manga{ID: "one-piece", Title: "One Piece", TotalChapters: 1100}); err != nil {
+		t.Fatalf("create manga: %v", err)
+	}
+	store.Progress.Sync(ctx, "test-user-1", "one-piece", 100, "")
+	store.Progress.Sync(ctx, "test-user-2", "one-piece", 50, "")
+
+	gin.SetMode(gin.TestMode)
+	server := websocket.NewServer(database.DB, "test-secret-key-32-characters!!")
+	inbox := websocket.NewInboxHandler(database.DB)
+	router := gin.New()
+	router.GET("/ws/chat", server.HandleWebSocket)
+	router.GET("/users/mentions", func(c *gin.Context) { c.Set("user_id", c.GetHeader("X-User")) }, inbox.ListMentions)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	dial := func(userID, username string) *ws.Conn {
+		token, _ := utils.GenerateJWT(userID, username, "user", "test-secret-key-32-characters!!")
+		conn, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/chat?token="+token, nil)
+		if err != nil {
+			t.Fatalf("Failed to connect %s: %v", username, err)
+		}
+		return conn
+	}
+	send := func(conn *ws.Conn, payload map[string]interface{}) {
+		payload["room"] = "manga-one-piece"
+		b, _ := json.Marshal(payload)
+		conn.WriteMessage(ws.TextMessage, b)
+	}
+
+	author, reader := dial("test-user-1", "testuser1"), dial("test-user-2", "testuser2")
+	defer author.Close()
+	defer reader.Close()
+	for _, conn := range []*ws.Conn{author, reader} {
+		send(conn, map[string]interface{}{"type": "command", "command": "/join manga-one-piece"})
+		readMatching(conn, "history", "", 2*time.Second)
+	}
+	send(reader, map[string]interface{}{"type": "command", "command": "/alert gear"})
+	readMatching(reader, "system", "Watching", 2*time.Second)
+
+	send(author, map[string]interface{}{"type": "text", "content": "@testuser2 luffy wins"})
+	send(author, map[string]interface{}{"type": "text", "content": "gear 5 changes everything"})
+	readMatching(author, "text", "gear 5", 2*time.Second)
+	time.Sleep(200 * time.Millisecond)
+
+	// The mention still lands, without its content; the keyword alert would
+	// give the spoiler away, so it is never raised
+	check := func(where string, alerts []interface{}) {
+		if len(alerts) != 1 {
+			t.Fatalf("%s: expected only the mention, got %v", where, alerts)
+		}
+		a := alerts[0].(map[string]interface{})
+		if a["kind"] != "mention" || a["content"] != "" || a["spoiler"] != true || a["spoiler_chapter"] != float64(100) {
+			t.Errorf("%s: mention should be collapsed, got %v", where, a)
+		}
+	}
+
+	send(reader, map[string]interface{}{"type": "command", "command": "/mentions"})
+	list := readMatching(reader, "system", "Recent mentions", 2*time.Second)
+	if list == nil {
+		t.Fatal("no /mentions reply")
+	}
+	check("/mentions", list["metadata"].(map[string]interface{})["alerts"].([]interface{}))
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/users/mentions", nil)
+	req.Header.Set("X-User", "test-user-2")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /users/mentions: %v", err)
+	}
+	defer resp.Body.Close()
+	var body map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	check("GET /users/mentions", body["alerts"].([]interface{}))
+
+	store.Progress.Sync(ctx, "test-user-2", "one-piece", 100, "")
+	send(reader, map[string]interface{}{"type": "command", "command": "/mentions"})
+	list = readMatching(reader, "system", "Recent mentions", 2*time.Second)
+	if a := list["metadata"].(map[string]interface{})["alerts"].([]interface{})[0].(map[string]interface{}); a["content"] != "@testuser2 luffy wins" {
+		t.Errorf("a reader who caught up should see the mention, got %v", a)
+	}
+}
@@ -0,0 +1,19 @@
+package config
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrJWTSecretMissing is returned when JWT_SECRET is not set. There is no
+// built-in fallback: a secret published with the source would let anyone
+// mint tokens the servers accept.
+var ErrJWTSecretMissing = errors.New("JWT_SECRET is not set")
+
+// LoadJWTSecret returns the JWT signing secret from JWT_SECRET
+func LoadJWTSecret() (string, error) {
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		return secret, nil
+	}
+	return "", ErrJWTSecretMissing
+}
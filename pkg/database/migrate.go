@@ -0,0 +1,377 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var ErrNoMigrationsToRollback = errors.New("no applied migrations to roll back")
+
+// Migration is one numbered schema step. SQL steps are loaded from
+// migrations/NNNN_name.up.sql and .down.sql; steps that need to inspect the
+// schema first are registered in Go.
+type Migration struct {
+	Version  int
+	Name     string
+	up       string
+	down     string
+	upFunc   func(tx *sql.Tx) error
+	downFunc func(tx *sql.Tx) error
+
+	// Set by rebuildDirective; see runMigration
+	upRebuilds   bool
+	downRebuilds bool
+}
+
+// rebuildDirective starts a SQL step that drops and recreates a table other
+// tables reference. Dropping it with foreign keys enforced would cascade
+// into those tables, so the step runs with enforcement off and the keys
+// are checked before it commits.
+const rebuildDirective = "-- migrate:rebuild"
+
+// MigrationState reports whether a migration has been applied
+type MigrationState struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// goMigrations are steps that can't be expressed as plain SQL
+var goMigrations = []Migration{
+	{Version: 2, Name: "legacy_columns", upFunc: addLegacyColumns},
+}
+
+// legacyColumns were added to existing tables after release. Databases created
+// before them get the column; ones created by 0001 already have it.
+var legacyColumns = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"manga", "media_type", "TEXT DEFAULT 'manga'"},
+	{"user_progress", "user_rating", "REAL"},
+	{"users", "role", "TEXT DEFAULT 'member'"},
+	{"user_conversation_history", "role", "TEXT DEFAULT 'member'"},
+}
+
+func addLegacyColumns(tx *sql.Tx) error {
+	for _, c := range legacyColumns {
+		exists, err := columnExists(tx, c.table, c.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, c.table, c.column, c.definition)); err != nil {
+			return fmt.Errorf("failed to add %s.%s: %w", c.table, c.column, err)
+		}
+		log.Printf("✓ Added %s column to %s", c.column, c.table)
+	}
+	return nil
+}
+
+func columnExists(tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf(`PRAGMA table_info(%s);`, table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid int
+		var name, ctype string
+		var notnull, pk int
+		var dflt interface{}
+		if err := rows.Scan(&cid, &name, &ctype, &notnull, &dflt, &pk); err != nil {
+			return false, err
+		}
+		if strings.EqualFold(name, column) {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// Migrations returns every known migration ordered by version
+func Migrations() ([]Migration, error) {
+	byVersion := make(map[int]*Migration)
+	for i := range goMigrations {
+		m := goMigrations[i]
+		byVersion[m.Version] = &m
+	}
+
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+	for _, entry := range entries {
+		version, name, direction, err := parseMigrationFilename(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		body, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %04d has conflicting names %q and %q", version, m.Name, name)
+		}
+		rebuilds := strings.HasPrefix(string(body), rebuildDirective)
+		if direction == "up" {
+			m.up, m.upRebuilds = string(body), rebuilds
+		} else {
+			m.down, m.downRebuilds = string(body), rebuilds
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" && m.upFunc == nil {
+			return nil, fmt.Errorf("migration %04d_%s has no up step", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// parseMigrationFilename splits "0003_add_reviews.up.sql" into its parts
+func parseMigrationFilename(filename string) (int, string, string, error) {
+	base := strings.TrimSuffix(filename, ".sql")
+	direction := ""
+	switch {
+	case strings.HasSuffix(base, ".up"):
+		direction = "up"
+	case strings.HasSuffix(base, ".down"):
+		direction = "down"
+	default:
+		return 0, "", "", fmt.Errorf("migration %s must end in .up.sql or .down.sql", filename)
+	}
+	base = strings.TrimSuffix(base, "."+direction)
+
+	parts := strings.SplitN(base, "_", 2)
+	if len(parts) != 2 {
+		return 0, "", "", fmt.Errorf("migration %s must be named NNNN_name", filename)
+	}
+	version, err := strconv.Atoi(parts[0])
+	if err != nil || version <= 0 {
+		return 0, "", "", fmt.Errorf("migration %s has an invalid version", filename)
+	}
+	return version, parts[1], direction, nil
+}
+
+func ensureMigrationsTable(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+        version INTEGER PRIMARY KEY,
+        name TEXT NOT NULL,
+        applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    )`)
+	return err
+}
+
+func appliedVersions(db *sql.DB) (map[int]time.Time, error) {
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// Migrate applies every pending migration in order, each in its own
+// transaction, and returns the ones it applied.
+func Migrate(db *sql.DB) ([]Migration, error) {
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := runMigration(db, m, true); err != nil {
+			return done, err
+		}
+		log.Printf("✓ Applied migration %04d_%s", m.Version, m.Name)
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Rollback reverts the most recently applied migrations, newest first
+func Rollback(db *sql.DB, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	if len(applied) == 0 {
+		return nil, ErrNoMigrationsToRollback
+	}
+
+	var done []Migration
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if err := runMigration(db, m, false); err != nil {
+			return done, err
+		}
+		log.Printf("✓ Rolled back migration %04d_%s", m.Version, m.Name)
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// MigrationStatus lists every known migration and whether it is applied
+func MigrationStatus(db *sql.DB) ([]MigrationState, error) {
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		state := MigrationState{Version: m.Version, Name: m.Name}
+		if at, ok := applied[m.Version]; ok {
+			state.Applied = true
+			state.AppliedAt = &at
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func runMigration(db *sql.DB, m Migration, up bool) error {
+	direction, rebuilds := "down", m.downRebuilds
+	if up {
+		direction, rebuilds = "up", m.upRebuilds
+	}
+
+	// The pragma is per connection and ignored inside a transaction, so pin
+	// one connection and switch enforcement off before beginning
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("migration %04d_%s %s: %w", m.Version, m.Name, direction, err)
+	}
+	defer conn.Close()
+	if rebuilds {
+		if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+			return fmt.Errorf("migration %04d_%s %s: %w", m.Version, m.Name, direction, err)
+		}
+		defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("migration %04d_%s %s: %w", m.Version, m.Name, direction, err)
+	}
+	defer tx.Rollback()
+
+	if up {
+		err = applyStep(tx, m.up, m.upFunc)
+	} else {
+		err = applyStep(tx, m.down, m.downFunc)
+	}
+	if err == nil && rebuilds {
+		err = foreignKeyCheck(tx)
+	}
+	if err != nil {
+		return fmt.Errorf("migration %04d_%s %s: %w", m.Version, m.Name, direction, err)
+	}
+
+	if up {
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.Version, m.Name)
+	} else {
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("migration %04d_%s %s: failed to record: %w", m.Version, m.Name, direction, err)
+	}
+	return tx.Commit()
+}
+
+// foreignKeyCheck fails if any row references one that doesn't exist
+func foreignKeyCheck(tx *sql.Tx) error {
+	rows, err := tx.Query(`PRAGMA foreign_key_check`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		var table, parent string
+		var rowid sql.NullInt64
+		var fkid int
+		if err := rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return err
+		}
+		return fmt.Errorf("foreign key violation: %s row %d references a missing %s", table, rowid.Int64, parent)
+	}
+	return rows.Err()
+}
+
+func applyStep(tx *sql.Tx, script string, fn func(tx *sql.Tx) error) error {
+	if fn != nil {
+		return fn(tx)
+	}
+	if strings.TrimSpace(script) == "" {
+		return nil
+	}
+	_, err := tx.Exec(script)
+	return err
+}
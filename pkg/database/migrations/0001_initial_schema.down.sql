@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS chat_messages;
+DROP TABLE IF EXISTS user_conversation_history;
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS conversations;
+DROP TABLE IF EXISTS user_progress;
+DROP TABLE IF EXISTS manga;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,15 @@
+UPDATE user_progress
+SET user_rating = (
+    SELECT b.user_rating
+    FROM user_progress_ratings_10star b
+    WHERE b.user_id = user_progress.user_id
+        AND b.manga_id = user_progress.manga_id
+)
+WHERE EXISTS (
+    SELECT 1
+    FROM user_progress_ratings_10star b
+    WHERE b.user_id = user_progress.user_id
+        AND b.manga_id = user_progress.manga_id
+);
+
+DROP TABLE user_progress_ratings_10star;
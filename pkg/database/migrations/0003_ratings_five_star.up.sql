@@ -0,0 +1,26 @@
+-- Ratings used to be stored out of 10. If any rating is still above 5 the
+-- whole table is on the old scale and is converted with CEIL(old / 2). The
+-- original values are kept so the step can be rolled back.
+CREATE TABLE user_progress_ratings_10star AS
+SELECT user_id, manga_id, user_rating
+FROM user_progress
+WHERE user_rating IS NOT NULL
+    AND EXISTS (SELECT 1 FROM user_progress WHERE user_rating > 5);
+
+UPDATE user_progress
+SET user_rating = (
+    SELECT CASE
+            WHEN b.user_rating / 2.0 > CAST(b.user_rating / 2.0 AS INTEGER)
+                THEN CAST(b.user_rating / 2.0 AS INTEGER) + 1
+            ELSE CAST(b.user_rating / 2.0 AS INTEGER)
+        END
+    FROM user_progress_ratings_10star b
+    WHERE b.user_id = user_progress.user_id
+        AND b.manga_id = user_progress.manga_id
+)
+WHERE EXISTS (
+    SELECT 1
+    FROM user_progress_ratings_10star b
+    WHERE b.user_id = user_progress.user_id
+        AND b.manga_id = user_progress.manga_id
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS event_log (
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id TEXT NOT NULL,
+    user_id TEXT NOT NULL DEFAULT '',
+    type TEXT NOT NULL,
+    source_protocol TEXT,
+    data TEXT,
+    metadata TEXT,
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_log_user ON event_log(user_id, seq);
+CREATE INDEX IF NOT EXISTS idx_event_log_created ON event_log(created_at);
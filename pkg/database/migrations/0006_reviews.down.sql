@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS chapter_comments;
+DROP TABLE IF EXISTS review_votes;
+DROP TABLE IF EXISTS review_revisions;
+DROP TABLE IF EXISTS reviews;
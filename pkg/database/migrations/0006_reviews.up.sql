@@ -0,0 +1,55 @@
+CREATE TABLE IF NOT EXISTS reviews (
+    id TEXT PRIMARY KEY,
+    manga_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    body TEXT NOT NULL,
+    spoiler INTEGER DEFAULT 0,
+    hidden INTEGER DEFAULT 0,
+    hidden_by TEXT,
+    hidden_reason TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    edited_at TIMESTAMP,
+    UNIQUE(manga_id, user_id),
+    FOREIGN KEY (manga_id) REFERENCES manga(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS review_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    review_id TEXT NOT NULL,
+    body TEXT NOT NULL,
+    spoiler INTEGER DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (review_id) REFERENCES reviews(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS review_votes (
+    review_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (review_id, user_id),
+    FOREIGN KEY (review_id) REFERENCES reviews(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS chapter_comments (
+    id TEXT PRIMARY KEY,
+    manga_id TEXT NOT NULL,
+    chapter INTEGER NOT NULL,
+    user_id TEXT NOT NULL,
+    body TEXT NOT NULL,
+    spoiler INTEGER DEFAULT 0,
+    hidden INTEGER DEFAULT 0,
+    hidden_by TEXT,
+    hidden_reason TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    edited_at TIMESTAMP,
+    FOREIGN KEY (manga_id) REFERENCES manga(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_reviews_manga ON reviews(manga_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_review_revisions_review ON review_revisions(review_id, id);
+CREATE INDEX IF NOT EXISTS idx_chapter_comments_chapter ON chapter_comments(manga_id, chapter, created_at);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS user_list_items;
+DROP TABLE IF EXISTS user_lists;
+DROP TABLE IF EXISTS library_tags;
+ALTER TABLE user_progress DROP COLUMN is_favorite;
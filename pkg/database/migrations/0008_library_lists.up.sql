@@ -0,0 +1,35 @@
+-- Favorites and free-form tags on library entries, and ordered user lists
+ALTER TABLE user_progress ADD COLUMN is_favorite INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS library_tags (
+    user_id TEXT NOT NULL,
+    manga_id TEXT NOT NULL,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (user_id, manga_id, tag),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_library_tags_tag ON library_tags(user_id, tag);
+
+CREATE TABLE IF NOT EXISTS user_lists (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    is_public INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, name),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- position is 1-based and kept contiguous by the list store
+CREATE TABLE IF NOT EXISTS user_list_items (
+    list_id TEXT NOT NULL,
+    manga_id TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    added_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (list_id, manga_id),
+    FOREIGN KEY (list_id) REFERENCES user_lists(id) ON DELETE CASCADE,
+    FOREIGN KEY (manga_id) REFERENCES manga(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS chapter_reads;
+DROP TABLE IF EXISTS chapters;
@@ -0,0 +1,34 @@
+-- Local chapter catalogue, filled from MangaDex. number is kept as published
+-- ("10", "10.5", or empty for oneshots and extras); sort_key is its numeric
+-- value, NULL when it has none.
+CREATE TABLE IF NOT EXISTS chapters (
+    id TEXT PRIMARY KEY,
+    manga_id TEXT NOT NULL,
+    number TEXT NOT NULL DEFAULT '',
+    sort_key REAL,
+    volume TEXT NOT NULL DEFAULT '',
+    title TEXT NOT NULL DEFAULT '',
+    language TEXT NOT NULL DEFAULT '',
+    scanlation_group TEXT NOT NULL DEFAULT '',
+    pages INTEGER NOT NULL DEFAULT 0,
+    published_at TIMESTAMP,
+    fetched_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (manga_id) REFERENCES manga(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_chapters_manga ON chapters(manga_id, language, sort_key);
+
+-- Per-user read marks; read_count goes up each time a chapter is re-read
+CREATE TABLE IF NOT EXISTS chapter_reads (
+    user_id TEXT NOT NULL,
+    chapter_id TEXT NOT NULL,
+    manga_id TEXT NOT NULL,
+    read_count INTEGER NOT NULL DEFAULT 1,
+    first_read_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_read_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, chapter_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chapter_id) REFERENCES chapters(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_chapter_reads_manga ON chapter_reads(user_id, manga_id);
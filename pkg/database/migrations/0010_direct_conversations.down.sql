@@ -0,0 +1,25 @@
+-- migrate:rebuild
+DELETE FROM messages WHERE conversation_id IN (SELECT id FROM conversations WHERE type = 'direct');
+DELETE FROM user_conversation_history WHERE conversation_id IN (SELECT id FROM conversations WHERE type = 'direct');
+
+CREATE TABLE conversations_old (
+    id TEXT PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL,
+    type TEXT CHECK(type IN ('global', 'manga', 'custom')) NOT NULL,
+    manga_id TEXT,
+    created_by TEXT,
+    last_message_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (manga_id) REFERENCES manga(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+INSERT INTO conversations_old (id, name, type, manga_id, created_by, last_message_at, created_at)
+SELECT id, name, type, manga_id, created_by, last_message_at, created_at FROM conversations
+WHERE type != 'direct';
+
+DROP TABLE conversations;
+ALTER TABLE conversations_old RENAME TO conversations;
+
+CREATE INDEX IF NOT EXISTS idx_conversations_type ON conversations(type);
+CREATE INDEX IF NOT EXISTS idx_conversations_manga_id ON conversations(manga_id);
@@ -0,0 +1,24 @@
+-- migrate:rebuild
+-- Direct messages are conversations of type 'direct' between two members,
+-- named dm:<user id>:<user id> with the IDs sorted. SQLite can't change a
+-- CHECK constraint in place, so the table is rebuilt.
+CREATE TABLE conversations_new (
+    id TEXT PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL,
+    type TEXT CHECK(type IN ('global', 'manga', 'custom', 'direct')) NOT NULL,
+    manga_id TEXT,
+    created_by TEXT,
+    last_message_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (manga_id) REFERENCES manga(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+INSERT INTO conversations_new (id, name, type, manga_id, created_by, last_message_at, created_at)
+SELECT id, name, type, manga_id, created_by, last_message_at, created_at FROM conversations;
+
+DROP TABLE conversations;
+ALTER TABLE conversations_new RENAME TO conversations;
+
+CREATE INDEX IF NOT EXISTS idx_conversations_type ON conversations(type);
+CREATE INDEX IF NOT EXISTS idx_conversations_manga_id ON conversations(manga_id);
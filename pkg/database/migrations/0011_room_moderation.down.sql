@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS conversation_sanctions;
+ALTER TABLE conversations DROP COLUMN slow_mode_seconds;
+ALTER TABLE conversations DROP COLUMN topic;
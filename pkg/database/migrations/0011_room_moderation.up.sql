@@ -0,0 +1,18 @@
+-- Room settings changed by /topic and /slowmode
+ALTER TABLE conversations ADD COLUMN topic TEXT;
+ALTER TABLE conversations ADD COLUMN slow_mode_seconds INTEGER NOT NULL DEFAULT 0;
+
+-- Bans and mutes outlive the connection they were issued on. A NULL
+-- expires_at lasts until it is lifted.
+CREATE TABLE IF NOT EXISTS conversation_sanctions (
+    conversation_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    kind TEXT CHECK(kind IN ('ban', 'mute')) NOT NULL,
+    reason TEXT,
+    created_by TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME,
+    PRIMARY KEY (conversation_id, user_id, kind),
+    FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
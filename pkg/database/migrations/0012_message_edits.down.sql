@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS message_reactions;
+ALTER TABLE messages DROP COLUMN deleted_at;
+ALTER TABLE messages DROP COLUMN edited_at;
+ALTER TABLE messages DROP COLUMN reply_to_id;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS keyword_alerts;
+DROP TABLE IF EXISTS chat_alerts;
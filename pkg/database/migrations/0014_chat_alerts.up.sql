@@ -0,0 +1,28 @@
+-- Alerts raised for room messages that @mention someone or match one of
+-- their keywords, so they reach people who aren't in the chat
+CREATE TABLE IF NOT EXISTS chat_alerts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    message_id TEXT NOT NULL,
+    conversation_id TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK(kind IN ('mention', 'keyword')),
+    keyword TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, message_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_alerts_user ON chat_alerts(user_id, created_at DESC);
+
+-- An empty conversation_id watches every room
+CREATE TABLE IF NOT EXISTS keyword_alerts (
+    user_id TEXT NOT NULL,
+    conversation_id TEXT NOT NULL DEFAULT '',
+    keyword TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, conversation_id, keyword),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_keyword_alerts_conversation ON keyword_alerts(conversation_id);
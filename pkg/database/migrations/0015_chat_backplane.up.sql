@@ -0,0 +1,10 @@
+-- Chat traffic shared between chat server processes (CHAT_BACKPLANE=sqlite).
+-- Rows are only kept for a minute; each process reads the ones it hasn't seen.
+CREATE TABLE IF NOT EXISTS chat_backplane (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    origin TEXT NOT NULL,
+    envelope TEXT NOT NULL,
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_backplane_created ON chat_backplane(created_at);
@@ -0,0 +1,9 @@
+-- Each user's presence as last reported by a server, so last-seen survives
+-- restarts and servers in other processes can answer presence queries
+CREATE TABLE IF NOT EXISTS user_presence (
+    user_id TEXT PRIMARY KEY,
+    status TEXT NOT NULL DEFAULT 'offline' CHECK(status IN ('online', 'idle', 'away', 'offline')),
+    last_active DATETIME,
+    last_seen DATETIME NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
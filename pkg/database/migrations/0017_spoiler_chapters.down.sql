@@ -0,0 +1 @@
+ALTER TABLE messages DROP COLUMN spoiler_chapter;
@@ -0,0 +1,3 @@
+-- Messages in manga rooms remember the chapter their sender had reached, so
+-- readers who are behind can have them hidden as spoilers. 0 means untagged.
+ALTER TABLE messages ADD COLUMN spoiler_chapter INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,56 @@
+-- migrate:rebuild
+-- Messages get an explicit sequence number. The implicit rowid of a table
+-- with a TEXT primary key can be renumbered by VACUUM, which would silently
+-- desync the search index and break read positions that compare rowids.
+-- id stays unique, so everything referencing messages(id) still does.
+DROP TRIGGER IF EXISTS messages_fts_update;
+DROP TRIGGER IF EXISTS messages_fts_delete;
+DROP TRIGGER IF EXISTS messages_fts_insert;
+DROP TABLE IF EXISTS messages_fts;
+
+CREATE TABLE messages_new (
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,
+    id TEXT UNIQUE NOT NULL,
+    conversation_id TEXT NOT NULL,
+    sender_id TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    reply_to_id TEXT,
+    edited_at DATETIME,
+    deleted_at DATETIME,
+    spoiler_chapter INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+    FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- rowid still follows insertion order here, so it seeds the sequence
+INSERT INTO messages_new (seq, id, conversation_id, sender_id, content, created_at, reply_to_id, edited_at, deleted_at, spoiler_chapter)
+SELECT rowid, id, conversation_id, sender_id, content, created_at, reply_to_id, edited_at, deleted_at, spoiler_chapter
+FROM messages ORDER BY rowid;
+
+DROP TABLE messages;
+ALTER TABLE messages_new RENAME TO messages;
+
+CREATE INDEX IF NOT EXISTS idx_messages_conversation ON messages(conversation_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_messages_sender ON messages(sender_id);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
+    content,
+    content = 'messages',
+    content_rowid = 'seq'
+);
+
+CREATE TRIGGER IF NOT EXISTS messages_fts_insert AFTER INSERT ON messages BEGIN
+    INSERT INTO messages_fts (rowid, content) VALUES (new.seq, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS messages_fts_delete AFTER DELETE ON messages BEGIN
+    INSERT INTO messages_fts (messages_fts, rowid, content) VALUES ('delete', old.seq, old.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS messages_fts_update AFTER UPDATE OF content ON messages BEGIN
+    INSERT INTO messages_fts (messages_fts, rowid, content) VALUES ('delete', old.seq, old.content);
+    INSERT INTO messages_fts (rowid, content) VALUES (new.seq, new.content);
+END;
+
+INSERT INTO messages_fts (messages_fts) VALUES ('rebuild');
@@ -0,0 +1,205 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/database"
+)
+
+func TestInitDatabaseAppliesAllMigrations(t *testing.T) {
+	if err := database.InitDatabase(t.TempDir() + "/fresh.db"); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	defer database.Close()
+
+	states, err := database.MigrationStatus(database.DB)
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if len(states) == 0 {
+		t.Fatal("expected embedded migrations")
+	}
+	for _, s := range states {
+		if !s.Applied || s.AppliedAt == nil {
+			t.Errorf("expected migration %04d_%s to be applied", s.Version, s.Name)
+		}
+	}
+
+	applied, err := database.Migrate(database.DB)
+	if err != nil || len(applied) != 0 {
+		t.Errorf("expected second migrate to be a no-op, got %d applied (%v)", len(applied), err)
+	}
+}
+
+func TestRollbackAndReapply(t *testing.T) {
+	if err := database.InitDatabase(t.TempDir() + "/rollback.db"); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	defer database.Close()
+
+	states, _ := database.MigrationStatus(database.DB)
+	latest := states[len(states)-1]
+
+	reverted, err := database.Rollback(database.DB, 1)
+	if err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if len(reverted) != 1 || reverted[0].Version != latest.Version {
+		t.Fatalf("expected to roll back %04d, got %+v", latest.Version, reverted)
+	}
+
+	states, _ = database.MigrationStatus(database.DB)
+	if states[len(states)-1].Applied {
+		t.Error("expected latest migration to be pending after rollback")
+	}
+
+	applied, err := database.Migrate(database.DB)
+	if err != nil || len(applied) != 1 {
+		t.Fatalf("expected one migration re-applied, got %d (%v)", len(applied), err)
+	}
+}
+
+func TestMigrateUpgradesLegacyDatabase(t *testing.T) {
+	path := t.TempDir() + "/legacy.db"
+	db, err := database.Open(path)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	// Schema as created by releases before media_type, role and user_rating existed
+	_, err = db.Exec(`
+    CREATE TABLE users (id TEXT PRIMARY KEY, username TEXT UNIQUE NOT NULL, email TEXT UNIQUE, password_hash TEXT NOT NULL, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP);
+    CREATE TABLE manga (id TEXT PRIMARY KEY, title TEXT NOT NULL, author TEXT, genres TEXT, status TEXT, total_chapters INTEGER DEFAULT 0, description TEXT, cover_url TEXT);
+    CREATE TABLE user_progress (user_id TEXT NOT NULL, manga_id TEXT NOT NULL, current_chapter INTEGER DEFAULT 0, status TEXT DEFAULT 'plan_to_read', updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (user_id, manga_id));
+    CREATE TABLE user_conversation_history (user_id TEXT NOT NULL, conversation_id TEXT NOT NULL, last_read_message_id TEXT, unread_count INTEGER DEFAULT 0, joined_at DATETIME DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (user_id, conversation_id));
+    INSERT INTO users (id, username, password_hash) VALUES ('u1', 'reader', 'hash');
+    INSERT INTO manga (id, title) VALUES ('m1', 'Berserk'), ('m2', 'Vagabond'), ('m3', 'Monster');
+    INSERT INTO user_progress (user_id, manga_id) VALUES ('u1', 'm1'), ('u1', 'm2'), ('u1', 'm3');`)
+	if err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+
+	if _, err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate failed on legacy database: %v", err)
+	}
+
+	// Ratings written on the old 10-point scale before the rating step runs
+	// again must be converted after a rollback and re-apply.
+	migrations, _ := database.Migrations()
+	stepsToRatings := 0
+	for _, m := range migrations {
+		if m.Version >= 3 {
+			stepsToRatings++
+		}
+	}
+	if _, err := database.Rollback(db, stepsToRatings); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	db.Exec(`UPDATE user_progress SET user_rating = CASE manga_id WHEN 'm1' THEN 10 WHEN 'm2' THEN 7 ELSE 8.5 END`)
+	if _, err := database.Migrate(db); err != nil {
+		t.Fatalf("re-migrate failed: %v", err)
+	}
+
+	want := map[string]float64{"m1": 5, "m2": 4, "m3": 5}
+	for mangaID, rating := range want {
+		var got float64
+		db.QueryRow(`SELECT user_rating FROM user_progress WHERE manga_id = ?`, mangaID).Scan(&got)
+		if got != rating {
+			t.Errorf("rating for %s = %v, want %v", mangaID, got, rating)
+		}
+	}
+
+	var role, mediaType string
+	if err := db.QueryRow(`SELECT role FROM users WHERE id = 'u1'`).Scan(&role); err != nil || role != "member" {
+		t.Errorf("expected users.role backfilled to member, got %q (%v)", role, err)
+	}
+	if err := db.QueryRow(`SELECT media_type FROM manga WHERE id = 'm1'`).Scan(&mediaType); err != nil || mediaType != "manga" {
+		t.Errorf("expected manga.media_type backfilled to manga, got %q (%v)", mediaType, err)
+	}
+	if _, err := db.Exec(`INSERT INTO user_conversation_history (user_id, conversation_id, role) VALUES ('u1', 'global', 'admin')`); err != nil {
+		t.Errorf("expected user_conversation_history.role to exist: %v", err)
+	}
+
+	// Databases converted by hand with the old script are left alone
+	if _, err := database.Rollback(db, stepsToRatings); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	db.Exec(`UPDATE user_progress SET user_rating = 4`)
+	if _, err := database.Migrate(db); err != nil {
+		t.Fatalf("re-migrate failed: %v", err)
+	}
+	var rating float64
+	db.QueryRow(`SELECT user_rating FROM user_progress WHERE manga_id = 'm2'`).Scan(&rating)
+	if rating != 4 {
+		t.Errorf("expected 5-star rating to stay 4, got %v", rating)
+	}
+}
+
+func TestRebuildingConversationsKeepsChatHistory(t *testing.T) {
+	if err := database.InitDatabase(t.TempDir() + "/upgrade.db"); err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	defer database.Close()
+	db := database.DB
+
+	// Back to before 0010 rebuilt the conversations table
+	states, _ := database.MigrationStatus(db)
+	steps := 0
+	for _, s := range states {
+		if s.Version >= 10 {
+			steps++
+		}
+	}
+	if _, err := database.Rollback(db, steps); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	_, err := db.Exec(`
+    INSERT INTO users (id, username, password_hash) VALUES ('u1', 'reader', 'hash');
+    INSERT INTO messages (id, conversation_id, sender_id, content) VALUES ('msg1', 'global', 'u1', 'hello');
+    INSERT INTO user_conversation_history (user_id, conversation_id) VALUES ('u1', 'global');`)
+	if err != nil {
+		t.Fatalf("failed to seed chat: %v", err)
+	}
+
+	if _, err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	var messages, members int
+	db.QueryRow(`SELECT COUNT(*) FROM messages`).Scan(&messages)
+	db.QueryRow(`SELECT COUNT(*) FROM user_conversation_history`).Scan(&members)
+	if messages != 1 || members != 1 {
+		t.Errorf("expected chat history to survive the upgrade, got %d messages and %d members", messages, members)
+	}
+
+	// Foreign keys are enforced again once the rebuild is done
+	if _, err := db.Exec(`DELETE FROM users WHERE id = 'u1'`); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+	db.QueryRow(`SELECT COUNT(*) FROM messages`).Scan(&messages)
+	if messages != 0 {
+		t.Errorf("expected deleting the sender to cascade, %d messages left", messages)
+	}
+}
+
+func TestEveryConnectionEnforcesForeignKeys(t *testing.T) {
+	db, err := database.Open(t.TempDir() + "/pool.db")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	defer db.Close()
+
+	// Hold several connections at once so the pool has to open new ones
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatalf("conn failed: %v", err)
+		}
+		defer conn.Close()
+		var enabled int
+		if err := conn.QueryRowContext(ctx, `PRAGMA foreign_keys`).Scan(&enabled); err != nil || enabled != 1 {
+			t.Errorf("connection %d: expected foreign keys on, got %d (%v)", i, enabled, err)
+		}
+	}
+}
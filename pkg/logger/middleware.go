@@ -0,0 +1,27 @@
+package logger
+
+import (
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validRequestID bounds client-supplied IDs so they can't bloat the logs or
+// smuggle quotes and control characters into the messages that carry them
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// GinMiddleware accepts an X-Request-ID from the client or generates one,
+// stores it on the gin and request contexts and echoes it in the response.
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = NewRequestID()
+		}
+
+		c.Set(RequestIDKey, requestID)
+		c.Request = c.Request.WithContext(ContextWithRequestID(c.Request.Context(), requestID))
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// RequestIDHeader is the HTTP header used to accept and echo request IDs
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDKey is the log context key and gin context key for request IDs
+	RequestIDKey = "request_id"
+)
+
+type requestIDKey struct{}
+
+func NewRequestID() string {
+	return uuid.NewString()
+}
+
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	if requestID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx. A *gin.Context
+// works too since the middleware also stores the ID under RequestIDKey.
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	if id, ok := ctx.Value(RequestIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// WithRequestID adds the request ID to the log context; empty IDs are ignored
+func (l *Logger) WithRequestID(requestID string) *Logger {
+	if requestID == "" {
+		return l
+	}
+	return l.WithContext(RequestIDKey, requestID)
+}
+
+// ForContext returns a logger that tags every entry with the request ID in ctx
+func (l *Logger) ForContext(ctx context.Context) *Logger {
+	return l.WithRequestID(RequestIDFromContext(ctx))
+}
+
+func ForContext(ctx context.Context) *Logger {
+	return GetLogger().ForContext(ctx)
+}
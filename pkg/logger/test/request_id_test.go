@@ -0,0 +1,96 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGinMiddlewareAcceptsRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var seen, fromRequest string
+	router := gin.New()
+	router.Use(logger.GinMiddleware())
+	router.GET("/ping", func(c *gin.Context) {
+		seen = logger.RequestIDFromContext(c)
+		fromRequest = logger.RequestIDFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(logger.RequestIDHeader, "trace-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if seen != "trace-123" || fromRequest != "trace-123" {
+		t.Errorf("expected request ID trace-123 on both contexts, got %q and %q", seen, fromRequest)
+	}
+	if got := w.Header().Get(logger.RequestIDHeader); got != "trace-123" {
+		t.Errorf("expected response header trace-123, got %q", got)
+	}
+}
+
+func TestGinMiddlewareGeneratesRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(logger.GinMiddleware())
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	first := httptest.NewRecorder()
+	router.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	second := httptest.NewRecorder()
+	router.ServeHTTP(second, httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	a, b := first.Header().Get(logger.RequestIDHeader), second.Header().Get(logger.RequestIDHeader)
+	if a == "" || b == "" || a == b {
+		t.Errorf("expected distinct generated request IDs, got %q and %q", a, b)
+	}
+}
+
+func TestLoggerForContextIncludesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(logger.INFO, true, &buf)
+
+	ctx := logger.ContextWithRequestID(context.Background(), "req-42")
+	log.ForContext(ctx).Info("progress_forwarded")
+
+	var entry logger.LogEntry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	if entry.Context[logger.RequestIDKey] != "req-42" {
+		t.Errorf("Expected request_id req-42 in context, got %v", entry.Context)
+	}
+
+	buf.Reset()
+	log.ForContext(context.Background()).Info("no_request")
+	if bytes.Contains(buf.Bytes(), []byte(logger.RequestIDKey)) {
+		t.Errorf("Expected no request_id without one in context, got %s", buf.String())
+	}
+}
+
+func TestGinMiddlewareReplacesUnsafeRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(logger.GinMiddleware())
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, id := range []string{`x","type":"remove_from_library`, "has space", string(make([]byte, 129))} {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		req.Header.Set(logger.RequestIDHeader, id)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if got := w.Header().Get(logger.RequestIDHeader); got == id || got == "" {
+			t.Errorf("expected %q to be replaced with a generated ID, got %q", id, got)
+		}
+	}
+}
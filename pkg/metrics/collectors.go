@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxMessageTypes caps the distinct message type labels per protocol, since
+// the type comes straight from client input.
+const maxMessageTypes = 64
+
+var (
+	protocolConnections = NewGaugeVec("mangahub_protocol_connections",
+		"Open client connections by protocol.", "protocol")
+	protocolMessages = NewCounterVec("mangahub_protocol_messages_total",
+		"Messages received from clients by protocol and message type.", "protocol", "type")
+	httpRequests = NewCounterVec("mangahub_http_requests_total",
+		"HTTP requests by method, route and status.", "method", "route", "status")
+	httpRequestDuration = NewHistogramVec("mangahub_http_request_duration_seconds",
+		"HTTP request latency by method, route and status.", nil, "method", "route", "status")
+	bridgeQueueDepth = NewGaugeVec("mangahub_bridge_queue_depth",
+		"Events waiting in a bridge queue.", "bridge")
+	grpcRequestDuration = NewHistogramVec("mangahub_grpc_request_duration_seconds",
+		"gRPC unary call latency by method and status code.", nil, "method", "code")
+	externalAPIDuration = NewHistogramVec("mangahub_external_api_request_duration_seconds",
+		"Latency of requests to external manga APIs by source, method and status.", nil, "source", "method", "status")
+
+	messageTypesMu sync.Mutex
+	messageTypes   = make(map[string]map[string]struct{})
+)
+
+func init() {
+	NewCounterFunc("mangahub_broadcasts_total", "Events delivered to clients.", func() float64 { return float64(GetBroadcasts()) })
+	NewCounterFunc("mangahub_broadcast_failures_total", "Events that failed to reach a client.", func() float64 { return float64(GetBroadcastFails()) })
+	NewGaugeFunc("mangahub_active_connections", "Active connections reported by the chat and TCP bridges.", func() float64 { return float64(GetActiveConnections()) })
+	NewCounterFunc("mangahub_chat_messages_total", "Chat messages accepted from WebSocket clients.", func() float64 { return float64(GetMessages()) })
+	NewCounterFunc("mangahub_rate_limited_total", "Messages rejected by rate limiting.", func() float64 { return float64(GetRateLimited()) })
+	NewCounterFunc("mangahub_events_processed_total", "Events processed by the sync pipeline.", func() float64 { return float64(systemMetrics.EventsProcessed.Load()) })
+	NewCounterFunc("mangahub_events_failed_total", "Events that failed in the sync pipeline.", func() float64 { return float64(systemMetrics.EventsFailed.Load()) })
+	NewCounterFunc("mangahub_bytes_transferred_total", "Bytes transferred by the sync pipeline.", func() float64 { return float64(systemMetrics.TotalBytesTransferred.Load()) })
+	NewGaugeFunc("mangahub_uptime_seconds", "Seconds since metrics were last reset.", func() float64 { return GetUptime().Seconds() })
+}
+
+// RecordProtocolMessage counts a message received over the given protocol
+func RecordProtocolMessage(protocol, msgType string) {
+	protocolMessages.Inc(protocol, boundedMessageType(protocol, msgType))
+}
+
+func boundedMessageType(protocol, msgType string) string {
+	if msgType == "" {
+		return "unknown"
+	}
+	messageTypesMu.Lock()
+	defer messageTypesMu.Unlock()
+
+	seen, ok := messageTypes[protocol]
+	if !ok {
+		seen = make(map[string]struct{})
+		messageTypes[protocol] = seen
+	}
+	if _, ok := seen[msgType]; ok {
+		return msgType
+	}
+	if len(seen) >= maxMessageTypes {
+		return "other"
+	}
+	seen[msgType] = struct{}{}
+	return msgType
+}
+
+// SetBridgeQueueDepth records how many events are waiting in a bridge queue
+func SetBridgeQueueDepth(bridge string, depth int) {
+	bridgeQueueDepth.Set(float64(depth), bridge)
+}
+
+// ObserveGRPCRequest records the latency of a unary gRPC call
+func ObserveGRPCRequest(method, code string, d time.Duration) {
+	grpcRequestDuration.Observe(d.Seconds(), method, code)
+}
+
+// ObserveExternalAPI records the latency of a call to an external API
+func ObserveExternalAPI(source, method, status string, d time.Duration) {
+	externalAPIDuration.Observe(d.Seconds(), source, method, status)
+}
+
+// GinMiddleware records request counts and latency by route and status.
+// Unmatched routes are grouped under "unmatched" to bound cardinality.
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		httpRequests.Inc(c.Request.Method, route, status)
+		httpRequestDuration.Observe(time.Since(start).Seconds(), c.Request.Method, route, status)
+	}
+}
+
+// InstrumentTransport wraps rt so every request records its latency under source
+func InstrumentTransport(source string, rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &instrumentedTransport{source: source, next: rt}
+}
+
+type instrumentedTransport struct {
+	source string
+	next   http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	ObserveExternalAPI(t.source, req.Method, status, time.Since(start))
+	return resp, err
+}
@@ -19,5 +19,19 @@ func (h *Handler) Metrics(c *gin.Context) {
 		"active_connections":    GetActiveConnections(),
 		"messages_total":        GetMessages(),
 		"rate_limited_total":    GetRateLimited(),
+		"protocol_connections": gin.H{
+			"tcp":       GetTCPConnections(),
+			"udp":       GetUDPConnections(),
+			"websocket": GetWebSocketConnections(),
+			"grpc":      GetGRPCConnections(),
+		},
+		"system": GetSystemMetrics(),
 	})
 }
+
+// Prometheus serves every registered metric in the Prometheus text format
+func (h *Handler) Prometheus(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	DefaultRegistry.WriteText(c.Writer)
+}
@@ -14,26 +14,26 @@ var (
 func IncrementConnectionCount(protocol string) {
 	switch protocol {
 	case "tcp":
-		atomic.AddInt64(&tcpConnections, 1)
+		protocolConnections.Set(float64(atomic.AddInt64(&tcpConnections, 1)), protocol)
 	case "udp":
-		atomic.AddInt64(&udpConnections, 1)
+		protocolConnections.Set(float64(atomic.AddInt64(&udpConnections, 1)), protocol)
 	case "websocket":
-		atomic.AddInt64(&websocketConnections, 1)
+		protocolConnections.Set(float64(atomic.AddInt64(&websocketConnections, 1)), protocol)
 	case "grpc":
-		atomic.AddInt64(&grpcConnections, 1)
+		protocolConnections.Set(float64(atomic.AddInt64(&grpcConnections, 1)), protocol)
 	}
 }
 
 func DecrementConnectionCount(protocol string) {
 	switch protocol {
 	case "tcp":
-		atomic.AddInt64(&tcpConnections, -1)
+		protocolConnections.Set(float64(atomic.AddInt64(&tcpConnections, -1)), protocol)
 	case "udp":
-		atomic.AddInt64(&udpConnections, -1)
+		protocolConnections.Set(float64(atomic.AddInt64(&udpConnections, -1)), protocol)
 	case "websocket":
-		atomic.AddInt64(&websocketConnections, -1)
+		protocolConnections.Set(float64(atomic.AddInt64(&websocketConnections, -1)), protocol)
 	case "grpc":
-		atomic.AddInt64(&grpcConnections, -1)
+		protocolConnections.Set(float64(atomic.AddInt64(&grpcConnections, -1)), protocol)
 	}
 }
 
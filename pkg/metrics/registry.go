@@ -0,0 +1,325 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are latency buckets in seconds, matching the Prometheus client defaults
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type collector interface {
+	write(w io.Writer)
+}
+
+// Registry holds metric families and renders them in the Prometheus text
+// exposition format (version 0.0.4).
+type Registry struct {
+	mu       sync.RWMutex
+	families map[string]collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]collector)}
+}
+
+// DefaultRegistry is the registry served by Handler.Prometheus
+var DefaultRegistry = NewRegistry()
+
+func (r *Registry) register(name string, c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.families[name]; exists {
+		panic("metrics: duplicate registration of " + name)
+	}
+	r.families[name] = c
+}
+
+// WriteText writes every registered family sorted by name
+func (r *Registry) WriteText(w io.Writer) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	families := r.families
+	r.mu.RUnlock()
+
+	sort.Strings(names)
+	for _, name := range names {
+		families[name].write(w)
+	}
+}
+
+// series holds the values of one family keyed by the joined label values
+type series struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+	mu         sync.RWMutex
+	keys       map[string][]string
+}
+
+func newSeries(name, help, kind string, labelNames []string) series {
+	return series{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		keys:       make(map[string][]string),
+	}
+}
+
+func (s *series) key(labelValues []string) string {
+	if len(labelValues) != len(s.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", s.name, len(s.labelNames), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+func (s *series) sortedKeys() []string {
+	keys := make([]string, 0, len(s.keys))
+	for k := range s.keys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *series) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", s.name, escapeHelp(s.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", s.name, s.kind)
+}
+
+// CounterVec is a monotonically increasing value partitioned by labels
+type CounterVec struct {
+	series
+	values map[string]float64
+}
+
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{series: newSeries(name, help, "counter", labelNames), values: make(map[string]float64)}
+	DefaultRegistry.register(name, c)
+	return c
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	k := c.key(labelValues)
+	c.mu.Lock()
+	c.values[k] += v
+	c.keys[k] = labelValues
+	c.mu.Unlock()
+}
+
+// Value returns the current value for the given labels
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.values[c.key(labelValues)]
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	c.header(w)
+	for _, k := range c.sortedKeys() {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labelNames, c.keys[k], "", ""), formatValue(c.values[k]))
+	}
+}
+
+// GaugeVec is a value that can go up and down, partitioned by labels
+type GaugeVec struct {
+	series
+	values map[string]float64
+}
+
+func NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	g := &GaugeVec{series: newSeries(name, help, "gauge", labelNames), values: make(map[string]float64)}
+	DefaultRegistry.register(name, g)
+	return g
+}
+
+func (g *GaugeVec) Set(v float64, labelValues ...string) {
+	k := g.key(labelValues)
+	g.mu.Lock()
+	g.values[k] = v
+	g.keys[k] = labelValues
+	g.mu.Unlock()
+}
+
+func (g *GaugeVec) Add(v float64, labelValues ...string) {
+	k := g.key(labelValues)
+	g.mu.Lock()
+	g.values[k] += v
+	g.keys[k] = labelValues
+	g.mu.Unlock()
+}
+
+func (g *GaugeVec) Inc(labelValues ...string) { g.Add(1, labelValues...) }
+
+func (g *GaugeVec) Dec(labelValues ...string) { g.Add(-1, labelValues...) }
+
+// Value returns the current value for the given labels
+func (g *GaugeVec) Value(labelValues ...string) float64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.values[g.key(labelValues)]
+}
+
+func (g *GaugeVec) write(w io.Writer) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	g.header(w)
+	for _, k := range g.sortedKeys() {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, formatLabels(g.labelNames, g.keys[k], "", ""), formatValue(g.values[k]))
+	}
+}
+
+type histogramValue struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// HistogramVec tracks the distribution of observations in cumulative buckets
+type HistogramVec struct {
+	series
+	buckets []float64
+	values  map[string]*histogramValue
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	h := &HistogramVec{
+		series:  newSeries(name, help, "histogram", labelNames),
+		buckets: sorted,
+		values:  make(map[string]*histogramValue),
+	}
+	DefaultRegistry.register(name, h)
+	return h
+}
+
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	k := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hv, ok := h.values[k]
+	if !ok {
+		hv = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[k] = hv
+		h.keys[k] = labelValues
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			hv.counts[i]++
+		}
+	}
+	hv.sum += v
+	hv.count++
+}
+
+// Count returns the number of observations for the given labels
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if hv, ok := h.values[h.key(labelValues)]; ok {
+		return hv.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	h.header(w)
+	for _, k := range h.sortedKeys() {
+		hv := h.values[k]
+		labels := h.keys[k]
+		for i, upper := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labelNames, labels, "le", formatValue(upper)), hv.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labelNames, labels, "le", "+Inf"), hv.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labelNames, labels, "", ""), formatValue(hv.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labelNames, labels, "", ""), hv.count)
+	}
+}
+
+// valueFunc exposes a value read at scrape time, e.g. an existing atomic
+type valueFunc struct {
+	name string
+	help string
+	kind string
+	fn   func() float64
+}
+
+// NewCounterFunc registers a counter whose value is read from fn on every scrape
+func NewCounterFunc(name, help string, fn func() float64) {
+	DefaultRegistry.register(name, &valueFunc{name: name, help: help, kind: "counter", fn: fn})
+}
+
+// NewGaugeFunc registers a gauge whose value is read from fn on every scrape
+func NewGaugeFunc(name, help string, fn func() float64) {
+	DefaultRegistry.register(name, &valueFunc{name: name, help: help, kind: "gauge", fn: fn})
+}
+
+func (f *valueFunc) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+	fmt.Fprintf(w, "%s %s\n", f.name, formatValue(f.fn()))
+}
+
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	parts := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		parts = append(parts, name+`="`+escapeLabel(values[i])+`"`)
+	}
+	if extraName != "" {
+		parts = append(parts, extraName+`="`+extraValue+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeHelp(v string) string {
+	return helpEscaper.Replace(v)
+}
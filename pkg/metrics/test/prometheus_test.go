@@ -0,0 +1,88 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/binhbb2204/Manga-Hub-Group13/pkg/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+func scrape(t *testing.T, router *gin.Engine) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	return w.Body.String()
+}
+
+func TestPrometheusExposition(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	metrics.Reset()
+
+	router := gin.New()
+	router.Use(metrics.GinMiddleware())
+	router.GET("/metrics", metrics.NewHandler().Prometheus)
+	router.GET("/manga/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/manga/one-piece", nil))
+	metrics.RecordProtocolMessage("tcp", "sync_progress")
+	metrics.IncrementConnectionCount("websocket")
+	defer metrics.DecrementConnectionCount("websocket")
+	metrics.SetBridgeQueueDepth("unified", 3)
+
+	body := scrape(t, router)
+
+	expected := []string{
+		"# TYPE mangahub_http_requests_total counter",
+		`mangahub_http_requests_total{method="GET",route="/manga/:id",status="204"} 1`,
+		"# TYPE mangahub_http_request_duration_seconds histogram",
+		`mangahub_http_request_duration_seconds_bucket{method="GET",route="/manga/:id",status="204",le="+Inf"} 1`,
+		`mangahub_protocol_messages_total{protocol="tcp",type="sync_progress"}`,
+		`mangahub_protocol_connections{protocol="websocket"} 1`,
+		`mangahub_bridge_queue_depth{bridge="unified"} 3`,
+		"# TYPE mangahub_uptime_seconds gauge",
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("exposition missing %q", line)
+		}
+	}
+}
+
+func TestProtocolMessageTypeCardinality(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/metrics", metrics.NewHandler().Prometheus)
+
+	for i := 0; i < 200; i++ {
+		metrics.RecordProtocolMessage("cardinality-test", strings.Repeat("x", i+1))
+	}
+
+	body := scrape(t, router)
+	if !strings.Contains(body, `mangahub_protocol_messages_total{protocol="cardinality-test",type="other"}`) {
+		t.Error("expected overflow message types to be grouped under \"other\"")
+	}
+	if n := strings.Count(body, `protocol="cardinality-test"`); n > 65 {
+		t.Errorf("expected bounded label set, got %d series", n)
+	}
+}
+
+func TestJSONMetricsIncludesProtocolConnections(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/metrics/json", metrics.NewHandler().Metrics)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics/json", nil))
+	if !strings.Contains(w.Body.String(), `"protocol_connections"`) {
+		t.Errorf("expected protocol_connections in JSON metrics, got %s", w.Body.String())
+	}
+}
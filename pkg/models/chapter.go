@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Chapter is one release in the local chapter catalogue
+type Chapter struct {
+	ID              string     `json:"id" db:"id"` // MangaDex chapter ID
+	MangaID         string     `json:"manga_id" db:"manga_id"`
+	Number          string     `json:"number" db:"number"` // as published: "10", "10.5", or "" for oneshots and extras
+	Volume          string     `json:"volume" db:"volume"`
+	Title           string     `json:"title" db:"title"`
+	Language        string     `json:"language" db:"language"`
+	ScanlationGroup string     `json:"scanlation_group" db:"scanlation_group"`
+	Pages           int        `json:"pages" db:"pages"`
+	PublishedAt     *time.Time `json:"published_at,omitempty" db:"published_at"`
+}
+
+// ChapterReadState is a chapter with the current user's read mark
+type ChapterReadState struct {
+	Chapter
+	Read       bool       `json:"read"`
+	ReadCount  int        `json:"read_count"` // above 1 for re-reads
+	LastReadAt *time.Time `json:"last_read_at,omitempty"`
+}
+
+// ChapterProgress is a user's read marks for one manga. CurrentChapter is
+// the library's current_chapter, which read marks move forward.
+type ChapterProgress struct {
+	MangaID        string             `json:"manga_id"`
+	CurrentChapter int                `json:"current_chapter"`
+	ReadChapters   int                `json:"read_chapters"`
+	TotalChapters  int                `json:"total_chapters"`
+	Chapters       []ChapterReadState `json:"chapters"`
+}
+
+// MarkChaptersReadRequest marks the listed chapters, and/or every chapter up
+// to and including Through (a chapter number such as "10.5") in Language
+type MarkChaptersReadRequest struct {
+	ChapterIDs []string `json:"chapter_ids"`
+	Through    string   `json:"through"`
+	Language   string   `json:"language"`
+}
@@ -0,0 +1,102 @@
+package models
+
+import "time"
+
+type Conversation struct {
+	ID              string     `json:"id" db:"id"`
+	Name            string     `json:"name" db:"name"`
+	Type            string     `json:"type" db:"type"` // global, manga, custom or direct
+	MangaID         string     `json:"manga_id,omitempty" db:"manga_id"`
+	CreatedBy       string     `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	LastMessageAt   *time.Time `json:"last_message_at,omitempty" db:"last_message_at"`
+	Topic           string     `json:"topic,omitempty" db:"topic"`
+	SlowModeSeconds int        `json:"slow_mode_seconds,omitempty" db:"slow_mode_seconds"` // wait between messages, 0 is off
+}
+
+type ConversationSummary struct {
+	Conversation
+	MemberCount int `json:"member_count"`
+}
+
+// DirectConversation is one entry in a user's direct message inbox
+type DirectConversation struct {
+	Conversation
+	OtherUserID   string       `json:"other_user_id"`
+	OtherUsername string       `json:"other_username"`
+	LastMessage   *ChatMessage `json:"last_message,omitempty"`
+	UnreadCount   int          `json:"unread_count"`
+}
+
+// MemberConversation is a conversation as one of its members sees it
+type MemberConversation struct {
+	Conversation
+	Role              string `json:"role"`
+	UnreadCount       int    `json:"unread_count"`
+	LastReadMessageID string `json:"last_read_message_id,omitempty"`
+	OtherUsername     string `json:"other_username,omitempty"` // direct conversations only
+}
+
+// ChatMessage is a room message (ConversationID set) or a direct chat
+// message (RecipientID set, or neither for a broadcast).
+type ChatMessage struct {
+	ID             string         `json:"id" db:"id"`
+	ConversationID string         `json:"conversation_id,omitempty" db:"conversation_id"`
+	SenderID       string         `json:"sender_id" db:"sender_id"`
+	SenderUsername string         `json:"sender_username" db:"-"`
+	RecipientID    string         `json:"recipient_id,omitempty" db:"to_user_id"`
+	Content        string         `json:"content" db:"content"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	ReplyToID      string         `json:"reply_to_id,omitempty" db:"reply_to_id"`
+	EditedAt       *time.Time     `json:"edited_at,omitempty" db:"edited_at"`
+	DeletedAt      *time.Time     `json:"deleted_at,omitempty" db:"deleted_at"`           // content is cleared
+	Reactions      map[string]int `json:"reactions,omitempty" db:"-"`                     // emoji -> count
+	SpoilerChapter int            `json:"spoiler_chapter,omitempty" db:"spoiler_chapter"` // sender's chapter in a manga room
+}
+
+// MessageSearchResult is a message found by search, with the conversation
+// it was posted in
+type MessageSearchResult struct {
+	ChatMessage
+	ConversationName string `json:"conversation_name"`
+	ConversationType string `json:"conversation_type"`
+}
+
+// ChatAlert tells a user about a room message that mentioned them or
+// matched one of their keywords
+type ChatAlert struct {
+	ID               int64     `json:"id" db:"id"`
+	UserID           string    `json:"user_id" db:"user_id"`
+	MessageID        string    `json:"message_id" db:"message_id"`
+	ConversationID   string    `json:"conversation_id" db:"conversation_id"`
+	ConversationName string    `json:"room" db:"-"`
+	SenderUsername   string    `json:"from" db:"-"`
+	Content          string    `json:"content" db:"-"`
+	Kind             string    `json:"kind" db:"kind"` // mention or keyword
+	Keyword          string    `json:"keyword,omitempty" db:"keyword"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	SpoilerChapter   int       `json:"spoiler_chapter,omitempty" db:"-"` // the message's, in a manga room
+	Spoiler          bool      `json:"spoiler,omitempty" db:"-"`         // content hidden until the reader gets to SpoilerChapter
+}
+
+// KeywordAlert is a word or phrase a user wants to hear about. An empty
+// ConversationID watches every room.
+type KeywordAlert struct {
+	UserID           string    `json:"user_id" db:"user_id"`
+	ConversationID   string    `json:"conversation_id,omitempty" db:"conversation_id"`
+	ConversationName string    `json:"room,omitempty" db:"-"`
+	Keyword          string    `json:"keyword" db:"keyword"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+}
+
+// RoomSanction is a ban or mute on one user in one room. A nil ExpiresAt
+// lasts until a moderator lifts it.
+type RoomSanction struct {
+	ConversationID string     `json:"conversation_id" db:"conversation_id"`
+	UserID         string     `json:"user_id" db:"user_id"`
+	Kind           string     `json:"kind" db:"kind"` // ban or mute
+	Reason         string     `json:"reason,omitempty" db:"reason"`
+	CreatedBy      string     `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// UserList is a user-defined, ordered collection of manga
+type UserList struct {
+	ID          string     `json:"id" db:"id"`
+	UserID      string     `json:"user_id" db:"user_id"`
+	Username    string     `json:"username"`
+	Name        string     `json:"name" db:"name"`
+	Description string     `json:"description" db:"description"`
+	IsPublic    bool       `json:"is_public" db:"is_public"`
+	ItemCount   int        `json:"item_count"`
+	Items       []ListItem `json:"items,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+type ListItem struct {
+	Position int       `json:"position" db:"position"` // 1-based
+	Manga    Manga     `json:"manga"`
+	AddedAt  time.Time `json:"added_at" db:"added_at"`
+}
+
+type CreateListRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	IsPublic    bool   `json:"is_public"`
+}
+
+// UpdateListRequest changes only the fields that are set
+type UpdateListRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	IsPublic    *bool   `json:"is_public"`
+}
+
+// AddListItemRequest inserts at Position, or appends when it is 0
+type AddListItemRequest struct {
+	MangaID  string `json:"manga_id" binding:"required"`
+	Position int    `json:"position" binding:"min=0"`
+}
+
+type MoveListItemRequest struct {
+	Position int `json:"position" binding:"required,min=1"`
+}
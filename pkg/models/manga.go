@@ -10,6 +10,7 @@ type Manga struct {
 	Description       string                   `json:"description" db:"description"`
 	CoverURL          string                   `json:"cover_url" db:"cover_url"`
 	MangaDexID        string                   `json:"mangadex_id,omitempty" db:"-"`
+	ExternalIDs       map[string]string        `json:"external_ids,omitempty" db:"-"`
 	AlternativeTitles map[string]interface{}   `json:"alternative_titles,omitempty"`
 	StartDate         string                   `json:"start_date,omitempty"`
 	EndDate           string                   `json:"end_date,omitempty"`
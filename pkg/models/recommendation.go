@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Recommendation is a manga suggested to a user with the reasons behind it
+type Recommendation struct {
+	Manga   Manga    `json:"manga"`
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+type RecommendationsResponse struct {
+	Recommendations []Recommendation `json:"recommendations"`
+	Count           int              `json:"count"`
+	ComputedAt      *time.Time       `json:"computed_at"`
+}
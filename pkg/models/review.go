@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// Review is a written review of a manga. Rating is the author's star rating
+// from their library, if they gave one.
+type Review struct {
+	ID           string     `json:"id" db:"id"`
+	MangaID      string     `json:"manga_id" db:"manga_id"`
+	UserID       string     `json:"user_id" db:"user_id"`
+	Username     string     `json:"username" db:"-"`
+	Rating       *float64   `json:"rating,omitempty" db:"-"`
+	Body         string     `json:"body" db:"body"`
+	Spoiler      bool       `json:"spoiler" db:"spoiler"`
+	HelpfulCount int        `json:"helpful_count" db:"-"`
+	Hidden       bool       `json:"hidden,omitempty" db:"hidden"`
+	HiddenBy     string     `json:"hidden_by,omitempty" db:"hidden_by"`
+	HiddenReason string     `json:"hidden_reason,omitempty" db:"hidden_reason"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+	EditedAt     *time.Time `json:"edited_at,omitempty" db:"edited_at"`
+}
+
+// ReviewRevision is an earlier version of a review, saved when it was edited
+type ReviewRevision struct {
+	ID        int64     `json:"id" db:"id"`
+	ReviewID  string    `json:"review_id" db:"review_id"`
+	Body      string    `json:"body" db:"body"`
+	Spoiler   bool      `json:"spoiler" db:"spoiler"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type ChapterComment struct {
+	ID           string     `json:"id" db:"id"`
+	MangaID      string     `json:"manga_id" db:"manga_id"`
+	Chapter      int        `json:"chapter" db:"chapter"`
+	UserID       string     `json:"user_id" db:"user_id"`
+	Username     string     `json:"username" db:"-"`
+	Body         string     `json:"body" db:"body"`
+	Spoiler      bool       `json:"spoiler" db:"spoiler"`
+	Hidden       bool       `json:"hidden,omitempty" db:"hidden"`
+	HiddenBy     string     `json:"hidden_by,omitempty" db:"hidden_by"`
+	HiddenReason string     `json:"hidden_reason,omitempty" db:"hidden_reason"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+	EditedAt     *time.Time `json:"edited_at,omitempty" db:"edited_at"`
+}
+
+// WriteReviewRequest creates or edits a review or chapter comment
+type WriteReviewRequest struct {
+	Body    string `json:"body" binding:"required"`
+	Spoiler bool   `json:"spoiler"`
+}
+
+// ModerationRequest hides or restores a review or comment (admin only)
+type ModerationRequest struct {
+	Hidden bool   `json:"hidden"`
+	Reason string `json:"reason"`
+}
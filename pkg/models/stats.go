@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// ReadingEvent records chapters read when a user's progress moved forward
+type ReadingEvent struct {
+	MangaID      string    `json:"manga_id" db:"manga_id"`
+	Chapter      int       `json:"chapter" db:"chapter"`
+	ChaptersRead int       `json:"chapters_read" db:"chapters_read"`
+	ReadAt       time.Time `json:"read_at" db:"read_at"`
+}
+
+type WeeklyChapters struct {
+	WeekStart string `json:"week_start"` // Monday, YYYY-MM-DD
+	Chapters  int    `json:"chapters"`
+}
+
+type GenreCount struct {
+	Genre string `json:"genre"`
+	Count int    `json:"count"`
+}
+
+// ReadingStats is the aggregate view served at GET /users/stats
+type ReadingStats struct {
+	TotalManga         int              `json:"total_manga"`
+	ByStatus           map[string]int   `json:"by_status"`
+	CompletionRate     float64          `json:"completion_rate"` // completed / started, 0..1
+	TotalChaptersRead  int              `json:"total_chapters_read"`
+	AverageRating      float64          `json:"average_rating"`
+	RatedCount         int              `json:"rated_count"`
+	RatingDistribution map[string]int   `json:"rating_distribution"`
+	Genres             []GenreCount     `json:"genres"`
+	ChaptersPerWeek    []WeeklyChapters `json:"chapters_per_week"`
+	CurrentStreakDays  int              `json:"current_streak_days"`
+	LongestStreakDays  int              `json:"longest_streak_days"`
+	LastReadAt         *time.Time       `json:"last_read_at,omitempty"`
+	HistoryAvailable   bool             `json:"history_available"` // false when series are estimated from the library alone
+}
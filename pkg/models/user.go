@@ -11,6 +11,16 @@ type User struct {
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 }
 
+// UserPresence is whether a user is around, and when they last were
+type UserPresence struct {
+	UserID     string     `json:"user_id" db:"user_id"`
+	Username   string     `json:"username" db:"-"`
+	Status     string     `json:"status" db:"status"` // online, idle, away or offline
+	LastActive *time.Time `json:"last_active,omitempty" db:"last_active"`
+	LastSeen   *time.Time `json:"last_seen,omitempty" db:"last_seen"`
+	Protocols  []string   `json:"protocols,omitempty" db:"-"` // how they are connected right now
+}
+
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required"`
 	Email    string `json:"email" binding:"required"`
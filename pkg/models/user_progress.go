@@ -7,11 +7,24 @@ type UserProgress struct {
 	MangaID        string    `json:"manga_id" db:"manga_id"`
 	CurrentChapter int       `json:"current_chapter" db:"current_chapter"`
 	Status         string    `json:"status" db:"status"`
+	UserRating     *float64  `json:"user_rating" db:"user_rating"`
+	IsFavorite     bool      `json:"is_favorite" db:"is_favorite"`
 	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type AddToLibraryRequest struct {
-	MangaID string `json:"manga_id" binding:"required"`
+	MangaID    string   `json:"manga_id" binding:"required"`
+	IsFavorite bool     `json:"is_favorite"`
+	Tags       []string `json:"tags"`
+}
+
+type SetFavoriteRequest struct {
+	Favorite bool `json:"favorite"`
+}
+
+// SetTagsRequest replaces an entry's tags; an empty list clears them
+type SetTagsRequest struct {
+	Tags []string `json:"tags"`
 }
 
 type UpdateProgressRequest struct {
@@ -26,6 +39,8 @@ type MangaProgress struct {
 	CurrentChapter int       `json:"current_chapter"`
 	Status         string    `json:"status"`
 	UserRating     *float64  `json:"user_rating"` // Pointer so null is explicit
+	IsFavorite     bool      `json:"is_favorite"`
+	Tags           []string  `json:"tags"`
 	UpdatedAt      time.Time `json:"updated_at"`
 }
 
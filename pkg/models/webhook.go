@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+type Webhook struct {
+	ID         string    `json:"id" db:"id"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	URL        string    `json:"url" db:"url"`
+	Secret     string    `json:"secret,omitempty" db:"secret"`
+	EventTypes []string  `json:"event_types" db:"event_types"`
+	AllUsers   bool      `json:"all_users" db:"all_users"`
+	Active     bool      `json:"active" db:"active"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required"`
+	EventTypes []string `json:"event_types" binding:"required,min=1"`
+	Secret     string   `json:"secret"`
+	AllUsers   bool     `json:"all_users"` // Admin only: receive events for every user
+}
+
+type WebhookDelivery struct {
+	ID         int64     `json:"id" db:"id"`
+	WebhookID  string    `json:"webhook_id" db:"webhook_id"`
+	EventID    string    `json:"event_id" db:"event_id"`
+	EventType  string    `json:"event_type" db:"event_type"`
+	Attempt    int       `json:"attempt" db:"attempt"`
+	StatusCode int       `json:"status_code" db:"status_code"`
+	Success    bool      `json:"success" db:"success"`
+	Error      string    `json:"error,omitempty" db:"error"`
+	DurationMs int64     `json:"duration_ms" db:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+type WebhookDeadLetter struct {
+	ID        int64     `json:"id" db:"id"`
+	WebhookID string    `json:"webhook_id" db:"webhook_id"`
+	EventID   string    `json:"event_id" db:"event_id"`
+	EventType string    `json:"event_type" db:"event_type"`
+	Payload   string    `json:"payload" db:"payload"`
+	LastError string    `json:"last_error" db:"last_error"`
+	Attempts  int       `json:"attempts" db:"attempts"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -613,6 +613,150 @@ func (x *AddMangaResponse) GetMediaType() string {
 	return ""
 }
 
+type StreamEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	AfterOffset   int64                  `protobuf:"varint,2,opt,name=after_offset,json=afterOffset,proto3" json:"after_offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEventsRequest) Reset() {
+	*x = StreamEventsRequest{}
+	mi := &file_proto_manga_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEventsRequest) ProtoMessage() {}
+
+func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEventsRequest.ProtoReflect.Descriptor instead.
+func (*StreamEventsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *StreamEventsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *StreamEventsRequest) GetAfterOffset() int64 {
+	if x != nil {
+		return x.AfterOffset
+	}
+	return 0
+}
+
+type Event struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Offset         int64                  `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	Id             string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Type           string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	UserId         string                 `protobuf:"bytes,4,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	SourceProtocol string                 `protobuf:"bytes,5,opt,name=source_protocol,json=sourceProtocol,proto3" json:"source_protocol,omitempty"`
+	Timestamp      string                 `protobuf:"bytes,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	DataJson       string                 `protobuf:"bytes,7,opt,name=data_json,json=dataJson,proto3" json:"data_json,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_proto_manga_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Event) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *Event) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Event) GetSourceProtocol() string {
+	if x != nil {
+		return x.SourceProtocol
+	}
+	return ""
+}
+
+func (x *Event) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *Event) GetDataJson() string {
+	if x != nil {
+		return x.DataJson
+	}
+	return ""
+}
+
 var File_proto_manga_proto protoreflect.FileDescriptor
 
 const file_proto_manga_proto_rawDesc = "" +
@@ -666,12 +810,24 @@ const file_proto_manga_proto_rawDesc = "" +
 	"\vdescription\x18\a \x01(\tR\vdescription\x12\x1b\n" +
 	"\tcover_url\x18\b \x01(\tR\bcoverUrl\x12\x1d\n" +
 	"\n" +
-	"media_type\x18\t \x01(\tR\tmediaType2\x84\x02\n" +
+	"media_type\x18\t \x01(\tR\tmediaType\"Q\n" +
+	"\x13StreamEventsRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12!\n" +
+	"\fafter_offset\x18\x02 \x01(\x03R\vafterOffset\"\xc0\x01\n" +
+	"\x05Event\x12\x16\n" +
+	"\x06offset\x18\x01 \x01(\x03R\x06offset\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x17\n" +
+	"\auser_id\x18\x04 \x01(\tR\x06userId\x12'\n" +
+	"\x0fsource_protocol\x18\x05 \x01(\tR\x0esourceProtocol\x12\x1c\n" +
+	"\ttimestamp\x18\x06 \x01(\tR\ttimestamp\x12\x1b\n" +
+	"\tdata_json\x18\a \x01(\tR\bdataJson2\xc0\x02\n" +
 	"\fMangaService\x128\n" +
 	"\bGetManga\x12\x16.manga.GetMangaRequest\x1a\x14.manga.MangaResponse\x12:\n" +
 	"\vSearchManga\x12\x14.manga.SearchRequest\x1a\x15.manga.SearchResponse\x12A\n" +
 	"\x0eUpdateProgress\x12\x16.manga.ProgressRequest\x1a\x17.manga.ProgressResponse\x12;\n" +
-	"\bAddManga\x12\x16.manga.AddMangaRequest\x1a\x17.manga.AddMangaResponseB5Z3github.com/binhbb2204/Manga-Hub-Group13/proto/mangab\x06proto3"
+	"\bAddManga\x12\x16.manga.AddMangaRequest\x1a\x17.manga.AddMangaResponse\x12:\n" +
+	"\fStreamEvents\x12\x1a.manga.StreamEventsRequest\x1a\f.manga.Event0\x01B5Z3github.com/binhbb2204/Manga-Hub-Group13/proto/mangab\x06proto3"
 
 var (
 	file_proto_manga_proto_rawDescOnce sync.Once
@@ -685,16 +841,18 @@ func file_proto_manga_proto_rawDescGZIP() []byte {
 	return file_proto_manga_proto_rawDescData
 }
 
-var file_proto_manga_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_proto_manga_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_proto_manga_proto_goTypes = []any{
-	(*GetMangaRequest)(nil),  // 0: manga.GetMangaRequest
-	(*MangaResponse)(nil),    // 1: manga.MangaResponse
-	(*SearchRequest)(nil),    // 2: manga.SearchRequest
-	(*SearchResponse)(nil),   // 3: manga.SearchResponse
-	(*ProgressRequest)(nil),  // 4: manga.ProgressRequest
-	(*ProgressResponse)(nil), // 5: manga.ProgressResponse
-	(*AddMangaRequest)(nil),  // 6: manga.AddMangaRequest
-	(*AddMangaResponse)(nil), // 7: manga.AddMangaResponse
+	(*GetMangaRequest)(nil),     // 0: manga.GetMangaRequest
+	(*MangaResponse)(nil),       // 1: manga.MangaResponse
+	(*SearchRequest)(nil),       // 2: manga.SearchRequest
+	(*SearchResponse)(nil),      // 3: manga.SearchResponse
+	(*ProgressRequest)(nil),     // 4: manga.ProgressRequest
+	(*ProgressResponse)(nil),    // 5: manga.ProgressResponse
+	(*AddMangaRequest)(nil),     // 6: manga.AddMangaRequest
+	(*AddMangaResponse)(nil),    // 7: manga.AddMangaResponse
+	(*StreamEventsRequest)(nil), // 8: manga.StreamEventsRequest
+	(*Event)(nil),               // 9: manga.Event
 }
 var file_proto_manga_proto_depIdxs = []int32{
 	1, // 0: manga.SearchResponse.mangas:type_name -> manga.MangaResponse
@@ -702,12 +860,14 @@ var file_proto_manga_proto_depIdxs = []int32{
 	2, // 2: manga.MangaService.SearchManga:input_type -> manga.SearchRequest
 	4, // 3: manga.MangaService.UpdateProgress:input_type -> manga.ProgressRequest
 	6, // 4: manga.MangaService.AddManga:input_type -> manga.AddMangaRequest
-	1, // 5: manga.MangaService.GetManga:output_type -> manga.MangaResponse
-	3, // 6: manga.MangaService.SearchManga:output_type -> manga.SearchResponse
-	5, // 7: manga.MangaService.UpdateProgress:output_type -> manga.ProgressResponse
-	7, // 8: manga.MangaService.AddManga:output_type -> manga.AddMangaResponse
-	5, // [5:9] is the sub-list for method output_type
-	1, // [1:5] is the sub-list for method input_type
+	8, // 5: manga.MangaService.StreamEvents:input_type -> manga.StreamEventsRequest
+	1, // 6: manga.MangaService.GetManga:output_type -> manga.MangaResponse
+	3, // 7: manga.MangaService.SearchManga:output_type -> manga.SearchResponse
+	5, // 8: manga.MangaService.UpdateProgress:output_type -> manga.ProgressResponse
+	7, // 9: manga.MangaService.AddManga:output_type -> manga.AddMangaResponse
+	9, // 10: manga.MangaService.StreamEvents:output_type -> manga.Event
+	6, // [6:11] is the sub-list for method output_type
+	1, // [1:6] is the sub-list for method input_type
 	1, // [1:1] is the sub-list for extension type_name
 	1, // [1:1] is the sub-list for extension extendee
 	0, // [0:1] is the sub-list for field type_name
@@ -724,7 +884,7 @@ func file_proto_manga_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_manga_proto_rawDesc), len(file_proto_manga_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   8,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -23,6 +23,7 @@ const (
 	MangaService_SearchManga_FullMethodName    = "/manga.MangaService/SearchManga"
 	MangaService_UpdateProgress_FullMethodName = "/manga.MangaService/UpdateProgress"
 	MangaService_AddManga_FullMethodName       = "/manga.MangaService/AddManga"
+	MangaService_StreamEvents_FullMethodName   = "/manga.MangaService/StreamEvents"
 )
 
 // MangaServiceClient is the client API for MangaService service.
@@ -33,6 +34,7 @@ type MangaServiceClient interface {
 	SearchManga(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
 	UpdateProgress(ctx context.Context, in *ProgressRequest, opts ...grpc.CallOption) (*ProgressResponse, error)
 	AddManga(ctx context.Context, in *AddMangaRequest, opts ...grpc.CallOption) (*AddMangaResponse, error)
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
 }
 
 type mangaServiceClient struct {
@@ -83,6 +85,25 @@ func (c *mangaServiceClient) AddManga(ctx context.Context, in *AddMangaRequest,
 	return out, nil
 }
 
+func (c *mangaServiceClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MangaService_ServiceDesc.Streams[0], MangaService_StreamEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MangaService_StreamEventsClient = grpc.ServerStreamingClient[Event]
+
 // MangaServiceServer is the server API for MangaService service.
 // All implementations must embed UnimplementedMangaServiceServer
 // for forward compatibility.
@@ -91,6 +112,7 @@ type MangaServiceServer interface {
 	SearchManga(context.Context, *SearchRequest) (*SearchResponse, error)
 	UpdateProgress(context.Context, *ProgressRequest) (*ProgressResponse, error)
 	AddManga(context.Context, *AddMangaRequest) (*AddMangaResponse, error)
+	StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error
 	mustEmbedUnimplementedMangaServiceServer()
 }
 
@@ -113,6 +135,9 @@ func (UnimplementedMangaServiceServer) UpdateProgress(context.Context, *Progress
 func (UnimplementedMangaServiceServer) AddManga(context.Context, *AddMangaRequest) (*AddMangaResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AddManga not implemented")
 }
+func (UnimplementedMangaServiceServer) StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
 func (UnimplementedMangaServiceServer) mustEmbedUnimplementedMangaServiceServer() {}
 func (UnimplementedMangaServiceServer) testEmbeddedByValue()                      {}
 
@@ -206,6 +231,17 @@ func _MangaService_AddManga_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MangaService_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MangaServiceServer).StreamEvents(m, &grpc.GenericServerStream[StreamEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MangaService_StreamEventsServer = grpc.ServerStreamingServer[Event]
+
 // MangaService_ServiceDesc is the grpc.ServiceDesc for MangaService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -230,6 +266,12 @@ var MangaService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _MangaService_AddManga_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _MangaService_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "proto/manga.proto",
 }